@@ -0,0 +1,1134 @@
+// Package main is the entry point for the Food Delivery API server.
+// Architecture: Modular Monolith following Clean Architecture principles.
+// Layers: Handlers (Delivery) -> Usecases -> Repositories
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+
+	"fooddelivery/internal/config"
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/handlers"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/attestation"
+	"fooddelivery/pkg/buildinfo"
+	"fooddelivery/pkg/calls"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/email"
+	"fooddelivery/pkg/errreport"
+	"fooddelivery/pkg/events"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/googleauth"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/netguard"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/routing"
+	"fooddelivery/pkg/secrets"
+	"fooddelivery/pkg/slack"
+	"fooddelivery/pkg/sms"
+	"fooddelivery/pkg/storage"
+	"fooddelivery/pkg/throttle"
+	"fooddelivery/pkg/weather"
+	"fooddelivery/pkg/worker"
+)
+
+func main() {
+	// Initialize Logger
+	// Every log line carries the release so incidents map to a deployment
+	logger.Init("version", buildinfo.Release())
+	log := logger.NewLogger()
+	build := buildinfo.Get()
+	log.Info("Starting Food Delivery API Server...",
+		"commit", build.Commit,
+		"build_time", build.BuildTime,
+		"go_version", build.GoVersion,
+		"modified", build.Modified,
+	)
+
+	// Credentials come from Vault / AWS Secrets Manager when configured,
+	// otherwise (or if it is unreachable outside production) from env vars
+	secretsCfg, err := config.LoadSecretsConfig()
+	if err != nil {
+		log.Fatal("Invalid secrets manager configuration", "error", err)
+	}
+	var secretsManager *secrets.Manager
+	var secretLookup config.SecretLookup
+	if source := secretsCfg.Source(); source != nil {
+		secretsManager = secrets.NewManager(source)
+		if err := secretsManager.Load(context.Background()); err != nil {
+			if secretsCfg.Required {
+				log.Fatal("Failed to load secrets", "error", err)
+			}
+			log.Warn("Secrets manager unavailable, falling back to environment variables", "error", err)
+			secretsManager = nil
+		} else {
+			secretLookup = secretsManager.Lookup
+			log.Info("Secrets loaded", "source", secretsManager.Source())
+		}
+	}
+
+	// Load configuration from environment variables
+	cfg, err := config.LoadWithSecrets(secretLookup)
+	if err != nil {
+		log.Fatal("Failed to load configuration", "error", err)
+	}
+	log.Info("Configuration loaded", "port", cfg.Port)
+
+	// Initialize PostgreSQL connection pool with auto-reconnect
+	// Using singleton pattern to ensure single connection pool across the app
+	dbPool, err := database.NewPostgresPool(context.Background(), cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal("Failed to connect to PostgreSQL", "error", err)
+	}
+	defer dbPool.Close()
+
+	// Initialize Redis client for caching and session management
+	redisClient, err := redis.NewClient(cfg.RedisURL, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", "error", err)
+	}
+	defer redisClient.Close()
+	redisClient.SetNamespace(cfg.RedisNamespace)
+	redisClient.SetCompression(cfg.RedisCompressMinBytes)
+
+	// Pool statistics on /metrics
+	dbPool.RegisterMetrics(metrics.Default)
+	redisClient.RegisterMetrics(metrics.Default)
+
+	// Initialize repositories (Data Access Layer)
+	userRepo := repository.NewUserRepository(dbPool)
+	menuRepo := repository.NewMenuRepository(dbPool)
+	orderRepo := repository.NewOrderRepository(dbPool)
+	paymentEventRepo := repository.NewPaymentEventRepository(dbPool)
+	refundRepo := repository.NewRefundRepository(dbPool)
+	webhookLogRepo := repository.NewWebhookLogRepository(dbPool)
+	addressRepo := repository.NewAddressRepository(dbPool)
+	apiKeyRepo := repository.NewAPIKeyRepository(dbPool)
+	batchRepo := repository.NewDeliveryBatchRepository(dbPool)
+	capacityRepo := repository.NewCapacityRepository(dbPool)
+	surveyRepo := repository.NewSurveyRepository(dbPool)
+	analyticsRepo := repository.NewAnalyticsRepository(dbPool)
+	settingsRepo := repository.NewSettingsRepository(dbPool)
+	auditRepo := repository.NewAuditRepository(dbPool)
+	templateRepo := repository.NewTemplateRepository(dbPool)
+	deviceTokenRepo := repository.NewDeviceTokenRepository(dbPool)
+
+	// Phone numbers and emails are encrypted at rest when keys are configured
+	piiProtector, err := cfg.PIIProtector()
+	if err != nil {
+		log.Fatal("Invalid PII encryption keys", "error", err)
+	}
+	if piiProtector != nil {
+		userRepo.SetPIIProtector(piiProtector)
+	} else {
+		log.Warn("PII encryption keys not set, storing phone numbers and emails in plaintext")
+	}
+
+	// Initialize usecases (Business Logic Layer)
+	// Staff actions feed the admin activity dashboard
+	auditUsecase := usecase.NewAuditUsecase(auditRepo, log)
+
+	// Runtime-editable knobs; environment values are the defaults
+	settingsUsecase := usecase.NewSettingsUsecase(settingsRepo, redisClient, settingDefinitions(cfg), log)
+	settingsUsecase.SetAuditLog(auditUsecase)
+
+	// Minimum app versions, enforced on every API request
+	for name, version := range map[string]string{
+		"APP_MIN_VERSION_ANDROID": cfg.AppMinVersionAndroid,
+		"APP_MIN_VERSION_IOS":     cfg.AppMinVersionIOS,
+	} {
+		if err := usecase.ValidateAppVersion(version); err != nil {
+			log.Fatal("Invalid "+name, "error", err)
+		}
+	}
+	appVersionUsecase := usecase.NewAppVersionUsecase(settingsUsecase, usecase.AppVersionConfig{
+		UpdateURLs: map[string]string{
+			usecase.AppPlatformAndroid: cfg.AppUpdateURLAndroid,
+			usecase.AppPlatformIOS:     cfg.AppUpdateURLIOS,
+		},
+	}, log)
+
+	menuUsecase := usecase.NewMenuUsecase(menuRepo, redisClient, log)
+	menuUsecase.SetAuditLog(auditUsecase)
+	menuUsecase.SetSectionRepository(repository.NewMenuSectionRepository(dbPool))
+
+	// Orders from staff numbers or through sandbox keys are tagged as tests
+	if err := usecase.ValidateStaffPhones(cfg.TestOrderStaffPhones); err != nil {
+		log.Fatal("Invalid TEST_ORDER_STAFF_PHONES", "error", err)
+	}
+	paymentUsecase := usecase.NewPaymentUsecase(orderRepo, menuRepo, paymentEventRepo, refundRepo, webhookLogRepo, cfg.Razorpay, log)
+	paymentUsecase.SetRedisClient(redisClient) // Set redis for idempotency
+	paymentUsecase.SetRetryPolicy(cfg.RetryPolicy(config.IntegrationPayment))
+	paymentUsecase.SetSettings(settingsUsecase) // One MGET for idempotency + settings at checkout
+	paymentUsecase.SetUnitOfWork(dbPool)        // Slot booking + order insert in one transaction
+	paymentUsecase.SetPriceConfirmThreshold(cfg.PriceConfirmThreshold)
+
+	// Delivery fee from road distance; falls back to a straight-line estimate
+	// when no router is configured or it is unreachable
+	kitchen := geo.Point{Latitude: cfg.KitchenLatitude, Longitude: cfg.KitchenLongitude}
+	var router routing.Provider = routing.NewHaversineProvider(cfg.RiderSpeedKmph)
+	if cfg.RoutingURL != "" {
+		osrm := routing.NewOSRMProvider(cfg.RoutingURL, time.Duration(cfg.RoutingTimeoutMs)*time.Millisecond)
+		osrm.SetRetryPolicy(cfg.RetryPolicy(config.IntegrationRouting))
+		router = routing.NewFallbackProvider(
+			osrm,
+			router,
+			func(err error) { log.Warn("Routing service unavailable, using straight-line distance", "error", err) },
+		)
+	}
+	deliveryFeeUsecase := usecase.NewDeliveryFeeUsecase(router, redisClient, usecase.DeliveryFeeConfig{
+		Origin:             kitchen,
+		BaseFee:            cfg.DeliveryBaseFee,
+		BaseDistanceMeters: cfg.DeliveryBaseDistanceMeters,
+		PerKmFee:           cfg.DeliveryPerKmFee,
+		MaxDistanceMeters:  cfg.DeliveryMaxDistanceMeters,
+	}, log)
+	deliveryFeeUsecase.SetSettings(settingsUsecase)
+	paymentUsecase.SetDeliveryFeeUsecase(deliveryFeeUsecase)
+
+	// Packaging charge and GST on top of the items
+	pricingUsecase := usecase.NewPricingUsecase(usecase.PricingConfig{
+		PackagingCharge:    cfg.PackagingCharge,
+		GSTRateBasisPoints: cfg.GSTRateBasisPoints,
+	})
+	pricingUsecase.SetSettings(settingsUsecase)
+	paymentUsecase.SetPricing(pricingUsecase)
+
+	// Stock taken at checkout, given back on cancellation or failed payment
+	inventoryUsecase := usecase.NewInventoryUsecase(repository.NewInventoryRepository(dbPool), menuUsecase, auditUsecase, log)
+	inventoryUsecase.SetSettings(settingsUsecase)
+	paymentUsecase.SetInventory(inventoryUsecase)
+
+	// Promised delivery times, pushed out automatically in bad weather
+	etaUsecase := usecase.NewETAUsecase(usecase.ETAConfig{
+		Origin:         kitchen,
+		PrepTime:       time.Duration(cfg.KitchenPrepMinutes) * time.Minute,
+		RiderSpeedKmph: cfg.RiderSpeedKmph,
+	}, redisClient, log)
+	etaUsecase.SetSettings(settingsUsecase)
+	if cfg.WeatherEnabled {
+		rules, err := weather.ParseAdjustments(cfg.WeatherETARules)
+		if err != nil {
+			log.Fatal("Invalid WEATHER_ETA_RULES", "error", err)
+		}
+		weatherProvider := weather.NewOpenMeteoProvider(cfg.WeatherAPIURL, 2*time.Second)
+		weatherProvider.SetRetryPolicy(cfg.RetryPolicy(config.IntegrationWeather))
+		etaUsecase.SetWeather(weatherProvider, rules)
+	}
+	paymentUsecase.SetETAUsecase(etaUsecase)
+
+	// Kitchen throughput per 15-minute slot; checkout books a slot
+	capacityUsecase := usecase.NewCapacityUsecase(capacityRepo, cfg.KitchenSlotCapacity, cfg.Location, log)
+	capacityUsecase.SetAuditLog(auditUsecase)
+	capacityUsecase.SetSettings(settingsUsecase)
+	paymentUsecase.SetCapacityUsecase(capacityUsecase)
+
+	// 30-minute delivery windows customers book at checkout
+	deliverySlotRepo := repository.NewDeliverySlotRepository(dbPool)
+	deliverySlotUsecase := usecase.NewDeliverySlotUsecase(deliverySlotRepo, cfg.DeliverySlotCapacity,
+		time.Duration(cfg.DeliverySlotHoldMinutes)*time.Minute, cfg.Location, log)
+	deliverySlotUsecase.SetSettings(settingsUsecase)
+	deliverySlotUsecase.SetETAUsecase(etaUsecase)
+	paymentUsecase.SetDeliverySlots(deliverySlotUsecase)
+
+	// Users by ID, cached per request and briefly in Redis. Redis copies
+	// keep phone numbers and emails encrypted, so they need the PII keys.
+	userCache := usecase.NewUserCache(userRepo, redisClient, log)
+	userCache.SetPIIProtector(piiProtector)
+
+	orderUsecase := usecase.NewOrderUsecase(orderRepo, userRepo, paymentUsecase, log)
+	orderUsecase.SetUserCache(userCache)
+	paymentUsecase.SetUserCache(userCache)
+	orderUsecase.SetAuditLog(auditUsecase)
+	orderUsecase.SetRedisClient(redisClient) // Set redis for pickup attempt limiting
+	orderUsecase.SetPickupSigningKey([]byte("pickup:" + cfg.JWTSecret))
+
+	// Accepted orders are split into tickets for the prep stations that
+	// make each menu category
+	stationUsecase := usecase.NewPrepStationUsecase(repository.NewPrepStationRepository(dbPool), orderRepo, log)
+	stationUsecase.SetAuditLog(auditUsecase)
+	orderUsecase.SetStations(stationUsecase)
+
+	// Saved delivery addresses; checkout can deliver to one by ID
+	addressUsecase := usecase.NewAddressUsecase(addressRepo, log)
+	paymentUsecase.SetAddresses(addressUsecase)
+
+	// Object storage for delivery proof photos
+	store, err := storage.NewLocalStore(cfg.StorageDir)
+	if err != nil {
+		log.Fatal("Failed to initialize storage", "error", err)
+	}
+	orderUsecase.SetStorage(store)
+
+	// Background workers are supervised: restarted with backoff if they fail
+	// or panic, and stopped before the server shuts down
+	workers := worker.NewSupervisor(log)
+	workers.Add("db-health", dbPool.RunHealthChecker)
+
+	// Warm the menu caches before traffic arrives; retried if the database
+	// or Redis is not ready yet
+	workers.Add("menu-cache-warm", menuUsecase.WarmCache)
+
+	// Pool exhaustion diagnostics
+	watchdogCfg := database.WatchdogConfig{
+		Interval:     5 * time.Second,
+		Threshold:    time.Duration(cfg.DBWatchdogThresholdMs) * time.Millisecond,
+		DumpCooldown: time.Duration(cfg.DBWatchdogDumpMinutes) * time.Minute,
+	}
+	workers.Add("db-pool-watchdog", worker.Forever(func(ctx context.Context) {
+		dbPool.RunWatchdog(ctx, watchdogCfg)
+	}))
+
+	// Message copy lives in templates: embedded defaults, admin overrides
+	// Each attempt, retries included, waits for a token from the provider's
+	// rate limit; order updates and alerts go before surveys
+	pushThrottle := throttle.New(config.IntegrationPush, cfg.Throttle[config.IntegrationPush])
+	workers.Add("push-throttle", worker.Forever(pushThrottle.Run))
+	pushProvider, err := newPushProvider(cfg, deviceTokenRepo, log)
+	if err != nil {
+		log.Fatal("Invalid push provider configuration", "error", err)
+	}
+	pushSender := push.NewRetryingSender(
+		push.NewThrottledSender(pushProvider, pushThrottle),
+		cfg.RetryPolicy(config.IntegrationPush),
+	)
+	smsThrottle := throttle.New(config.IntegrationSMS, cfg.Throttle[config.IntegrationSMS])
+	workers.Add("sms-throttle", worker.Forever(smsThrottle.Run))
+	smsSender := sms.NewRetryingSender(
+		sms.NewThrottledSender(newSMSProvider(cfg, log), smsThrottle),
+		cfg.RetryPolicy(config.IntegrationSMS),
+	)
+	templateUsecase, err := usecase.NewTemplateUsecase(templateRepo, redisClient, log)
+	if err != nil {
+		log.Fatal("Invalid message templates", "error", err)
+	}
+	if err := templateUsecase.SetFallbackLocale(cfg.RestaurantLocale); err != nil {
+		log.Fatal("Invalid RESTAURANT_LOCALE", "error", err)
+	}
+	templateUsecase.SetUserCache(userCache)
+	templateUsecase.SetPushSender(pushSender)
+	templateUsecase.SetAuditLog(auditUsecase)
+
+	// Receipts to the payer; delivery updates to the payer or gift recipient
+	notificationUsecase := usecase.NewNotificationUsecase(templateUsecase, pushSender, userRepo, log)
+	notificationUsecase.SetUserCache(userCache)
+	notificationUsecase.SetSMSSender(smsSender)
+	paymentUsecase.SetNotifications(notificationUsecase)
+	orderUsecase.SetNotifications(notificationUsecase)
+
+	// Order confirmation and receipt emails are queued in the outbox and
+	// sent by a worker, retried with backoff
+	emailSender := newEmailProvider(cfg, log)
+	templateUsecase.SetEmailSender(emailSender)
+	emailUsecase := usecase.NewEmailUsecase(repository.NewEmailOutboxRepository(dbPool), orderRepo, userCache, templateUsecase, emailSender, cfg.Location, log)
+	emailUsecase.SetRetryPolicy(cfg.RetryPolicy(config.IntegrationEmail))
+	notificationUsecase.SetEmails(emailUsecase)
+	workers.Add("email-outbox", worker.Forever(func(ctx context.Context) {
+		emailUsecase.Run(ctx, 10*time.Second)
+	}))
+
+	// Alerts to admins; critical ones go out at once, the rest in digests
+	digestRules, err := usecase.ParseDigestRules(cfg.AdminAlertDigests)
+	if err != nil {
+		log.Fatal("Invalid ADMIN_ALERT_DIGESTS", "error", err)
+	}
+	alertUsecase := usecase.NewAdminAlertUsecase(userRepo, pushSender, redisClient, usecase.AdminAlertConfig{
+		Frequencies: digestRules,
+		DailyHour:   cfg.AdminAlertDigestHour,
+		Location:    cfg.Location,
+	}, log)
+	if cfg.AdminAlertSlackURL != "" {
+		slackWebhook := slack.NewWebhook(cfg.AdminAlertSlackURL, 5*time.Second)
+		slackWebhook.SetRetryPolicy(cfg.RetryPolicy(config.IntegrationWebhook))
+		alertUsecase.SetSlack(slackWebhook)
+	}
+	paymentUsecase.SetAlerts(alertUsecase)
+	paymentUsecase.SetAuditLog(auditUsecase)
+	workers.Add("admin-alert-digests", worker.Forever(func(ctx context.Context) {
+		alertUsecase.RunDigests(ctx, time.Minute)
+	}))
+
+	// Items running low or selling out alert admins, and stock goes in the
+	// daily digest; checkout checks at once, the worker catches the rest
+	inventoryUsecase.SetAlerts(alertUsecase)
+	alertUsecase.AddDailyReport("stock", inventoryUsecase.StockReport)
+	workers.Add("stock-checks", worker.Forever(func(ctx context.Context) {
+		inventoryUsecase.RunStockChecks(ctx, time.Minute)
+	}))
+
+	// Webhooks whose processing failed are replayed with backoff; admins are
+	// alerted about any that never succeed
+	paymentUsecase.SetWebhookRetryPolicy(cfg.RetryPolicy(config.IntegrationWebhookReplay))
+	workers.Add("webhook-retries", worker.Forever(func(ctx context.Context) {
+		paymentUsecase.RunWebhookRetries(ctx, 30*time.Second)
+	}))
+
+	// Items out of stock after payment: the customer takes a partial refund
+	// or cancels; unanswered shortages are accepted by the expiry worker
+	shortageRepo := repository.NewItemShortageRepository(dbPool)
+	itemShortageUsecase := usecase.NewItemShortageUsecase(shortageRepo, orderRepo, paymentUsecase, notificationUsecase, log)
+	itemShortageUsecase.SetResponseWindow(time.Duration(cfg.ItemShortageResponseMinutes) * time.Minute)
+	itemShortageUsecase.SetUnitOfWork(dbPool)
+	itemShortageUsecase.SetAlerts(alertUsecase)
+	itemShortageUsecase.SetAuditLog(auditUsecase)
+	workers.Add("item-shortage-expiry", worker.Forever(func(ctx context.Context) {
+		itemShortageUsecase.RunExpiry(ctx, time.Minute)
+	}))
+
+	// Problems reported after delivery: bounded refunds applied by the
+	// compensation rules, the rest escalated to staff
+	orderIssueRepo := repository.NewOrderIssueRepository(dbPool)
+	orderIssueUsecase := usecase.NewOrderIssueUsecase(orderIssueRepo, orderRepo, paymentUsecase, settingsUsecase, log)
+	orderIssueUsecase.SetAlerts(alertUsecase)
+	orderIssueUsecase.SetAuditLog(auditUsecase)
+
+	// Unpaid orders give back their delivery slot after the hold period
+	workers.Add("delivery-slot-expiry", worker.Forever(func(ctx context.Context) {
+		deliverySlotUsecase.RunExpiry(ctx, time.Minute)
+	}))
+
+	// Post-delivery NPS surveys, pushed by a background dispatcher
+	surveyUsecase := usecase.NewSurveyUsecase(surveyRepo, pushSender, templateUsecase, usecase.SurveyConfig{
+		Delay:          time.Duration(cfg.SurveyDelayMinutes) * time.Minute,
+		AlertThreshold: cfg.SurveyAlertThreshold,
+	}, log)
+	surveyUsecase.SetSettings(settingsUsecase)
+	surveyUsecase.SetAlerts(alertUsecase)
+	orderUsecase.SetSurveyUsecase(surveyUsecase)
+	workers.Add("survey-dispatcher", worker.Forever(func(ctx context.Context) {
+		surveyUsecase.RunDispatcher(ctx, time.Minute)
+	}))
+
+	// Chat between an order's customer, its rider and support, with phone
+	// numbers masked
+	orderMessageRepo := repository.NewOrderMessageRepository(dbPool)
+	orderChatUsecase := usecase.NewOrderChatUsecase(orderMessageRepo, orderRepo, notificationUsecase, log)
+
+	// Riders call customers through the call provider without either
+	// seeing the other's number
+	maskedCallUsecase := usecase.NewMaskedCallUsecase(repository.NewOrderCallRepository(dbPool), orderRepo, userCache,
+		newCallProvider(cfg, log), usecase.MaskedCallConfig{
+			Provider:       cfg.CallProvider,
+			MaxDuration:    time.Duration(cfg.CallMaxMinutes) * time.Minute,
+			AfterDelivery:  time.Duration(cfg.CallAfterDeliveryMinutes) * time.Minute,
+			CallbackURL:    cfg.CallStatusCallbackURL,
+			CallbackSecret: cfg.CallCallbackSecret,
+		}, log)
+	maskedCallUsecase.SetSettings(settingsUsecase)
+
+	// Order status changes are written to an outbox with the change and
+	// relayed to the configured sink by one instance at a time
+	orderEventRepo := repository.NewOrderEventRepository(dbPool)
+	orderEventRelay := usecase.NewOrderEventRelay(orderEventRepo, newOrderEventPublisher(cfg, redisClient, log), redisClient,
+		cfg.RetryPolicy(config.IntegrationOrderEvents), log)
+	orderEventRelay.SetAlerts(alertUsecase)
+	workers.Add("order-events", worker.Forever(func(ctx context.Context) {
+		orderEventRelay.Run(ctx, time.Duration(cfg.OrderEventsIntervalMs)*time.Millisecond)
+	}))
+
+	// Relayed events also go out on a Redis channel; every instance's hub
+	// pushes them to the customers and admins connected over WebSocket
+	orderEventRelay.SetLive(events.NewRedisChannelPublisher(redisClient, redis.OrderEventsLiveChannel))
+	orderLiveHub := usecase.NewOrderLiveHub(redisClient, log)
+	workers.Add("order-live-hub", worker.Forever(orderLiveHub.Run))
+
+	// Expired sessions and OTPs, old order chats and published order events,
+	// purged by one instance at a time
+	cleanupUsecase := usecase.NewCleanupUsecase(userRepo, redisClient, usecase.CleanupConfig{
+		SessionRetention: time.Duration(cfg.SessionRetentionDays) * 24 * time.Hour,
+		OTPRetention:     24 * time.Hour,
+		ChatRetention:    time.Duration(cfg.ChatRetentionDays) * 24 * time.Hour,
+		EventRetention:   time.Duration(cfg.OrderEventsRetentionDays) * 24 * time.Hour,
+	}, log)
+	cleanupUsecase.SetOrderMessages(orderMessageRepo)
+	cleanupUsecase.SetOrderEvents(orderEventRepo)
+	workers.Add("cleanup", worker.Forever(func(ctx context.Context) {
+		cleanupUsecase.Run(ctx, time.Duration(cfg.CleanupIntervalMinutes)*time.Minute)
+	}))
+
+	// Multi-order rider batches with nearest-neighbor routes from the kitchen
+	batchUsecase := usecase.NewBatchUsecase(orderRepo, batchRepo, orderUsecase, usecase.BatchConfig{
+		Origin:         kitchen,
+		RadiusMeters:   float64(cfg.BatchRadiusMeters),
+		MaxOrders:      cfg.BatchMaxOrders,
+		RiderSpeedKmph: cfg.RiderSpeedKmph,
+		StopDwell:      time.Duration(cfg.StopDwellMinutes) * time.Minute,
+	}, log)
+
+	// Rider apps and kitchen screens heartbeat; batches suggest free online
+	// riders and admins hear when the open kitchen has no screen
+	if err := usecase.ValidateOpenHours(cfg.KitchenOpenHours); err != nil {
+		log.Fatal("Invalid KITCHEN_OPEN_HOURS", "error", err)
+	}
+	presenceUsecase := usecase.NewPresenceUsecase(redisClient, time.Duration(cfg.PresenceTTLSeconds)*time.Second, cfg.Location, log)
+	presenceUsecase.SetUserCache(userCache)
+	presenceUsecase.SetSettings(settingsUsecase)
+	presenceUsecase.SetAlerts(alertUsecase)
+	batchUsecase.SetPresence(presenceUsecase)
+	workers.Add("kitchen-screen-watch", worker.Forever(func(ctx context.Context) {
+		presenceUsecase.RunKitchenWatch(ctx, time.Minute)
+	}))
+
+	analyticsUsecase := usecase.NewAnalyticsUsecase(analyticsRepo, menuRepo, redisClient, log)
+	analyticsUsecase.SetLocation(cfg.Location)
+	importUsecase := usecase.NewOrderImportUsecase(orderRepo, menuRepo, userRepo, log)
+	userUsecase := usecase.NewUserUsecase(userRepo, log)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(apiKeyRepo, redisClient, log)
+	userUsecase.SetRedisClient(redisClient) // Set redis for auth rate limiting
+	userUsecase.SetUserCache(userCache)
+	userUsecase.SetAuditLog(auditUsecase)
+	userUsecase.SetReactivationWindow(time.Duration(cfg.AccountReactivationDays) * 24 * time.Hour)
+	userUsecase.SetSettings(settingsUsecase) // Churn thresholds
+	announcementUsecase := usecase.NewAnnouncementUsecase(repository.NewAnnouncementRepository(dbPool), userUsecase, auditUsecase, redisClient, cfg.Location, log)
+
+	// Saved carts; guests keep one under a device token until they sign up
+	cartUsecase := usecase.NewCartUsecase(redisClient, repository.NewCartRepository(dbPool, redisClient), menuRepo, log)
+	userUsecase.SetCarts(cartUsecase)
+	userUsecase.SetOTPSender(smsSender, templateUsecase)
+
+	// Set JWT configuration for user usecase
+	userUsecase.SetJWTConfig(cfg.JWTSecret,
+		time.Duration(cfg.JWTAccessMinutes)*time.Minute,
+		time.Duration(cfg.JWTRefreshHours)*time.Hour)
+	userUsecase.SetMFAConfig(cfg.AdminMFARequired, cfg.TOTPIssuer)
+
+	// Rotated credentials are swapped in without a restart. Database, Redis
+	// and PII keys are read at startup only.
+	if secretsManager != nil {
+		razorpayRotated := func(string) {
+			keyID, _ := secretsManager.Lookup("RAZORPAY_KEY_ID")
+			keySecret, _ := secretsManager.Lookup("RAZORPAY_KEY_SECRET")
+			paymentUsecase.SetRazorpayCredentials(keyID, keySecret)
+		}
+		secretsManager.OnChange("RAZORPAY_KEY_ID", razorpayRotated)
+		secretsManager.OnChange("RAZORPAY_KEY_SECRET", razorpayRotated)
+		secretsManager.OnChange("RAZORPAY_WEBHOOK_SECRET", paymentUsecase.SetWebhookSecret)
+		secretsManager.OnChange("JWT_SECRET", userUsecase.RotateJWTSecret)
+
+		if secretsCfg.RefreshInterval > 0 {
+			onRefresh := func(changed []string, err error) {
+				if err != nil {
+					log.Warn("Secrets refresh failed, keeping current values", "error", err)
+					return
+				}
+				if len(changed) > 0 {
+					log.Info("Secrets rotated", "keys", changed)
+				}
+			}
+			workers.Add("secrets-refresh", worker.Forever(func(ctx context.Context) {
+				secretsManager.Run(ctx, secretsCfg.RefreshInterval, onRefresh)
+			}))
+		}
+	}
+
+	// Google Sign-In is optional; only enabled when client IDs are configured
+	if len(cfg.GoogleClientIDs) > 0 {
+		userUsecase.SetGoogleVerifier(googleauth.NewVerifier(cfg.GoogleClientIDs))
+		log.Info("Google Sign-In enabled", "client_ids", len(cfg.GoogleClientIDs))
+	}
+
+	// App attestation guards order creation and OTP requests against bots
+	attestationMode, err := attestation.ParseMode(cfg.AttestationMode)
+	if err != nil {
+		log.Fatal("Invalid attestation configuration", "error", err)
+	}
+	var attestationVerifier attestation.Verifier
+	if attestationMode != attestation.ModeOff {
+		attestationVerifier = attestation.NewAppCheckVerifier(cfg.FirebaseProjectNumber, cfg.AttestationAppIDs)
+		log.Info("App attestation enabled", "mode", attestationMode)
+	}
+	attestationUsecase := usecase.NewAttestationUsecase(attestationVerifier, attestationMode, redisClient, log)
+
+	requestLimits := handlers.RequestLimits{
+		Timeout:        time.Duration(cfg.RequestTimeoutMs) * time.Millisecond,
+		UploadTimeout:  time.Duration(cfg.UploadTimeoutMs) * time.Millisecond,
+		MaxBodyBytes:   cfg.MaxBodyBytes,
+		MaxUploadBytes: cfg.MaxUploadBytes,
+	}
+
+	// Initialize Fiber with optimized settings for low-latency
+	app := fiber.New(fiber.Config{
+		// Prefork enables multiple Go processes to handle requests
+		// Disabled for easier debugging; enable in production for max throughput
+		Prefork: false,
+
+		// Strict routing distinguishes between /foo and /foo/
+		StrictRouting: true,
+
+		// Case sensitive routing
+		CaseSensitive: true,
+
+		// Read timeout prevents slow client attacks
+		ReadTimeout: 10 * time.Second,
+
+		// Write timeout for response
+		WriteTimeout: 10 * time.Second,
+
+		// Idle timeout for keep-alive connections
+		IdleTimeout: 120 * time.Second,
+
+		// Hard cap on request bodies; per-request limits are enforced by
+		// RequestLimitsMiddleware
+		BodyLimit: requestLimits.ServerBodyLimit(),
+
+		// Custom error handler with structured logging
+		ErrorHandler: handlers.CustomErrorHandler(log),
+	})
+
+	// Global middleware stack
+	// Order matters: Recovery -> CORS -> Compression -> Request Logging -> Error Reporting -> Limits -> Routes
+
+	// Recovery middleware catches panics and converts to 500 errors
+	// Prevents server crash from unhandled panics
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace: true,
+	}))
+
+	// CORS middleware for Flutter web/mobile clients
+	allowCredentials := cfg.AllowedOrigins != "*"
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.AllowedOrigins,
+		AllowMethods:     "GET,POST,PUT,DELETE,PATCH",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID,X-API-Key,X-Firebase-AppCheck,X-App-Platform,X-App-Version,X-Guest-Token",
+		AllowCredentials: allowCredentials,
+		MaxAge:           3600,
+	}))
+
+	// gzip/brotli/deflate by Accept-Encoding; streamed exports are
+	// compressed as they are written
+	app.Use(compress.New(compress.Config{
+		Level: compress.LevelBestSpeed,
+	}))
+
+	// Custom request logging middleware with Request-ID generation
+	app.Use(logger.FiberMiddleware(log))
+
+	// Panics and 5xx responses go to the error tracker, tagged with the release
+	var reporter errreport.Reporter = errreport.Nop{}
+	if cfg.SentryDSN != "" {
+		transport, err := errreport.NewSentryTransport(cfg.SentryDSN, 5*time.Second)
+		if err != nil {
+			log.Fatal("Invalid SENTRY_DSN", "error", err)
+		}
+		reporter = errreport.NewClient(transport, errreport.Options{
+			Release:     buildinfo.Release(),
+			Environment: cfg.Environment,
+			SampleRate:  cfg.ErrorReportSampleRate,
+		}, log)
+		log.Info("Error reporting enabled", "release", buildinfo.Release(), "sample_rate", cfg.ErrorReportSampleRate)
+	}
+	app.Use(handlers.ErrorReportingMiddleware(reporter))
+
+	// Deadline propagated to DB/Redis calls, and body size limits
+	app.Use(handlers.RequestLimitsMiddleware(requestLimits))
+
+	// Setup routes
+	guards, err := buildRouteGuards(cfg, log)
+	if err != nil {
+		log.Fatal("Invalid transport security configuration", "error", err)
+	}
+	setupRoutes(app, handlers.NewHandlers(
+		menuUsecase,
+		orderUsecase,
+		paymentUsecase,
+		userUsecase,
+		apiKeyUsecase,
+		attestationUsecase,
+		batchUsecase,
+		capacityUsecase,
+		surveyUsecase,
+		analyticsUsecase,
+		importUsecase,
+		settingsUsecase,
+		auditUsecase,
+		templateUsecase,
+		itemShortageUsecase,
+		appVersionUsecase,
+		cartUsecase,
+		presenceUsecase,
+		addressUsecase,
+		deliverySlotUsecase,
+		orderChatUsecase,
+		maskedCallUsecase,
+		inventoryUsecase,
+		announcementUsecase,
+		orderIssueUsecase,
+		stationUsecase,
+		orderLiveHub,
+		usecase.NewDeviceUsecase(deviceTokenRepo, log),
+		cfg.Location,
+		log,
+	), guards)
+
+	// TLS termination is optional; behind a load balancer it usually ends there
+	var tlsConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		tlsConfig, err = netguard.TLSConfig{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+			ClientAuth:   cfg.TLSClientAuth,
+		}.ServerConfig()
+		if err != nil {
+			log.Fatal("Invalid TLS configuration", "error", err)
+		}
+	}
+
+	// Graceful shutdown handling
+	// Captures SIGINT/SIGTERM and cleanly closes connections
+	shutdownChan := make(chan os.Signal, 1)
+	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
+
+	workers.Start(context.Background())
+
+	// Start server in goroutine
+	go func() {
+		addr := fmt.Sprintf(":%d", cfg.Port)
+		if tlsConfig == nil {
+			log.Info("Server listening", "address", addr)
+			if err := app.Listen(addr); err != nil {
+				log.Fatal("Server failed to start", "error", err)
+			}
+			return
+		}
+
+		ln, err := tls.Listen("tcp", addr, tlsConfig)
+		if err != nil {
+			log.Fatal("Server failed to start", "error", err)
+		}
+		log.Info("Server listening with TLS", "address", addr, "client_auth", cfg.TLSClientAuth)
+		if err := app.Listener(ln); err != nil {
+			log.Fatal("Server failed to start", "error", err)
+		}
+	}()
+
+	// Wait for shutdown signal
+	<-shutdownChan
+	log.Info("Shutdown signal received, gracefully stopping server...")
+
+	// Graceful shutdown with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := workers.Stop(ctx); err != nil {
+		log.Warn("Background workers did not stop in time", "error", err)
+	}
+
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		log.Error("Server forced to shutdown", "error", err)
+	}
+	if err := reporter.Close(ctx); err != nil {
+		log.Warn("Pending error reports not sent", "error", err)
+	}
+
+	log.Info("Server stopped gracefully")
+}
+
+// settingDefinitions lists the settings admins can change at runtime, with
+// the environment values as defaults
+func settingDefinitions(cfg *config.Config) []usecase.SettingDefinition {
+	return []usecase.SettingDefinition{
+		usecase.IntSetting(usecase.SettingDeliveryBaseFee, "Delivery fee up to the base distance (paisa)", int(cfg.DeliveryBaseFee), 0, 100000),
+		usecase.IntSetting(usecase.SettingDeliveryBaseDistance, "Distance covered by the base fee (meters)", cfg.DeliveryBaseDistanceMeters, 0, 50000),
+		usecase.IntSetting(usecase.SettingDeliveryPerKmFee, "Fee per started km beyond the base distance (paisa)", int(cfg.DeliveryPerKmFee), 0, 50000),
+		usecase.IntSetting(usecase.SettingDeliveryMaxDistance, "Furthest road distance delivered to (meters, 0 = no limit)", cfg.DeliveryMaxDistanceMeters, 0, 100000),
+		usecase.IntSetting(usecase.SettingKitchenPrepMinutes, "Kitchen preparation time used for ETAs (minutes)", cfg.KitchenPrepMinutes, 1, 240),
+		usecase.IntSetting(usecase.SettingKitchenSlotCapacity, "Orders per 15-minute slot outside capacity rules (0 = unlimited)", cfg.KitchenSlotCapacity, 0, 1000),
+		usecase.IntSetting(usecase.SettingDeliverySlotCapacity, "Orders delivered per 30-minute delivery slot (0 = unlimited)", cfg.DeliverySlotCapacity, 0, 1000),
+		usecase.IntSetting(usecase.SettingSurveyAlertThreshold, "Survey scores at or below this alert admins", cfg.SurveyAlertThreshold, 0, 10),
+		usecase.IntSetting(usecase.SettingCallsAfterDeliveryMinutes, "Minutes after delivery riders can still call the customer (0 = only while out for delivery)", cfg.CallAfterDeliveryMinutes, 0, 240),
+		usecase.IntSetting(usecase.SettingCheckoutPriceConfirmThreshold, "Price increase over the displayed total charged without confirmation (paisa)", int(cfg.PriceConfirmThreshold), 0, 100000),
+		usecase.IntSetting(usecase.SettingPricingPackagingCharge, "Packaging charge per order (paisa)", int(cfg.PackagingCharge), 0, 100000),
+		usecase.IntSetting(usecase.SettingPricingGSTRate, "GST on items and packaging (basis points, 500 = 5%)", cfg.GSTRateBasisPoints, 0, 2800),
+		usecase.IntSetting(usecase.SettingChurnMinOrders, "Paid orders that make a customer a regular for churn flags", cfg.ChurnMinOrders, 1, 100),
+		usecase.IntSetting(usecase.SettingChurnQuietDays, "Days without an order before a regular is flagged as a churn risk", cfg.ChurnQuietDays, 1, 365),
+		usecase.IntSetting(usecase.SettingIssueWindowHours, "Hours after delivery customers can report a problem with an order", cfg.IssueWindowHours, 1, 168),
+		usecase.IntSetting(usecase.SettingIssueMaxAutoRefund, "Largest refund for a reported problem applied without staff (paisa, 0 = escalate all)", int(cfg.IssueMaxAutoRefund), 0, 1000000),
+		usecase.IntSetting(usecase.SettingIssueColdFoodPercent, "Share of the subtotal refunded for cold food (percent)", cfg.IssueColdFoodPercent, 0, 100),
+		usecase.IntSetting(usecase.SettingIssueAutoLimit, "Problems per customer resolved without staff in 30 days", cfg.IssueAutoLimit, 0, 50),
+		usecase.IntSetting(usecase.SettingInventoryLowStockThreshold, "Stock at or below which admins are alerted, for items without their own threshold", cfg.LowStockThreshold, 0, 100000),
+		usecase.BoolSetting(usecase.SettingInventoryDisableSoldOut, "Switch items off the menu when they sell out, back on when restocked", cfg.DisableSoldOutItems),
+		usecase.StringSetting(usecase.SettingAppMinVersionAndroid, "Oldest Android app version served, e.g. 2.4.0 (empty = no minimum)", cfg.AppMinVersionAndroid, usecase.ValidateAppVersion),
+		usecase.StringSetting(usecase.SettingAppMinVersionIOS, "Oldest iOS app version served, e.g. 2.4.0 (empty = no minimum)", cfg.AppMinVersionIOS, usecase.ValidateAppVersion),
+		usecase.StringSetting(usecase.SettingKitchenOpenHours, "Kitchen opening hours, e.g. 11:00-23:00 local; alerts when no kitchen screen is online (empty = no check)", cfg.KitchenOpenHours, usecase.ValidateOpenHours),
+		usecase.StringSetting(usecase.SettingTestOrderStaffPhones, "Comma-separated staff phone numbers whose orders are tagged as tests", cfg.TestOrderStaffPhones, usecase.ValidateStaffPhones),
+	}
+}
+
+// newPushProvider returns the configured push provider, or a sender that
+// only logs when none is set
+func newPushProvider(cfg *config.Config, tokens push.TokenStore, log *logger.Logger) (push.Sender, error) {
+	if cfg.PushProvider == "fcm" {
+		return push.NewFCMSender(cfg.FCM, tokens, time.Duration(cfg.PushTimeoutMs)*time.Millisecond)
+	}
+	log.Warn("PUSH_PROVIDER not set, push notifications are logged instead of sent")
+	return push.NewLogSender(log), nil
+}
+
+// newEmailProvider returns the configured email provider, or a sender that
+// only logs when none is set
+func newEmailProvider(cfg *config.Config, log *logger.Logger) email.Sender {
+	timeout := time.Duration(cfg.EmailTimeoutMs) * time.Millisecond
+	switch cfg.EmailProvider {
+	case "smtp":
+		return email.NewSMTPSender(cfg.SMTP, timeout)
+	case "sendgrid":
+		return email.NewSendGridSender(cfg.SendGrid, timeout)
+	}
+	log.Warn("EMAIL_PROVIDER not set, order emails are logged instead of sent")
+	return email.NewLogSender(log)
+}
+
+// newSMSProvider returns the configured SMS provider, or a sender that only
+// logs when none is set
+func newSMSProvider(cfg *config.Config, log *logger.Logger) sms.Sender {
+	timeout := time.Duration(cfg.SMSTimeoutMs) * time.Millisecond
+	switch cfg.SMSProvider {
+	case "twilio":
+		return sms.NewTwilioSender(cfg.Twilio, timeout)
+	case "msg91":
+		return sms.NewMSG91Sender(cfg.MSG91, timeout)
+	}
+	log.Warn("SMS_PROVIDER not set, texts (including OTPs) are logged instead of sent")
+	return sms.NewLogSender(log)
+}
+
+// newOrderEventPublisher returns the configured order events sink
+func newOrderEventPublisher(cfg *config.Config, redisClient *redis.Client, log *logger.Logger) events.Publisher {
+	switch cfg.OrderEventsSink {
+	case "redis":
+		return events.NewRedisStreamPublisher(redisClient, cfg.OrderEventsStream, int64(cfg.OrderEventsStreamMaxLen))
+	case "kafka":
+		return events.NewKafkaPublisher(cfg.Kafka, time.Duration(cfg.OrderEventsTimeoutMs)*time.Millisecond)
+	}
+	return events.NewLogPublisher(log)
+}
+
+// newCallProvider returns the configured masked call provider, or a bridger
+// that only logs when none is set
+func newCallProvider(cfg *config.Config, log *logger.Logger) calls.Bridger {
+	timeout := time.Duration(cfg.CallTimeoutMs) * time.Millisecond
+	switch cfg.CallProvider {
+	case "exotel":
+		return calls.NewExotelBridger(cfg.Exotel, timeout)
+	case "twilio":
+		return calls.NewTwilioBridger(cfg.TwilioVoice, timeout)
+	}
+	log.Warn("CALL_PROVIDER not set, rider calls are logged instead of placed")
+	return calls.NewLogBridger(log)
+}
+
+// routeGuards are transport-level checks run before a route group's own
+// middleware; empty slices leave the group unguarded
+type routeGuards struct {
+	Admin   []fiber.Handler
+	Webhook []fiber.Handler
+	Metrics []fiber.Handler
+}
+
+// buildRouteGuards assembles the allowlist and client certificate checks
+// enabled in config
+func buildRouteGuards(cfg *config.Config, log *logger.Logger) (routeGuards, error) {
+	var guards routeGuards
+
+	trustedProxies, err := netguard.ParseIPList(cfg.TrustedProxies)
+	if err != nil {
+		return guards, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	adminIPs, err := netguard.ParseIPList(cfg.AdminAllowedIPs)
+	if err != nil {
+		return guards, fmt.Errorf("ADMIN_ALLOWED_IPS: %w", err)
+	}
+	if adminIPs.Len() > 0 {
+		guards.Admin = append(guards.Admin, handlers.IPAllowlistMiddleware("admin", adminIPs, trustedProxies, log))
+		log.Info("Admin IP allowlist enabled", "entries", adminIPs.Len())
+	}
+	if cfg.AdminRequireClientCert {
+		guards.Admin = append(guards.Admin, handlers.ClientCertMiddleware("admin", log))
+		log.Info("Admin client certificates required")
+	}
+
+	webhookIPs, err := netguard.ParseIPList(cfg.WebhookAllowedIPs)
+	if err != nil {
+		return guards, fmt.Errorf("RAZORPAY_WEBHOOK_ALLOWED_IPS: %w", err)
+	}
+	if webhookIPs.Len() > 0 {
+		guards.Webhook = append(guards.Webhook, handlers.IPAllowlistMiddleware("razorpay_webhook", webhookIPs, trustedProxies, log))
+		log.Info("Razorpay webhook source validation enabled", "entries", webhookIPs.Len())
+	}
+
+	// Metrics are never public: without their own list they get the admin
+	// list, and without either only local scrapers are let in
+	metricsAllowed := cfg.MetricsAllowedIPs
+	if len(metricsAllowed) == 0 {
+		metricsAllowed = cfg.AdminAllowedIPs
+	}
+	if len(metricsAllowed) == 0 {
+		metricsAllowed = []string{"127.0.0.1", "::1"}
+	}
+	metricsIPs, err := netguard.ParseIPList(metricsAllowed)
+	if err != nil {
+		return guards, fmt.Errorf("METRICS_ALLOWED_IPS: %w", err)
+	}
+	guards.Metrics = append(guards.Metrics, handlers.IPAllowlistMiddleware("metrics", metricsIPs, trustedProxies, log))
+
+	return guards, nil
+}
+
+// setupRoutes configures all API routes following RESTful conventions
+func setupRoutes(app *fiber.App, h *handlers.Handlers, guards routeGuards) {
+	// Health check endpoint for load balancer/k8s probes
+	app.Get("/health", h.HealthCheck)
+
+	// Build version, for correlating incidents with deployments
+	app.Get("/version", h.Version)
+
+	// Prometheus scrape endpoint, restricted to the metrics allowlist
+	app.Get("/metrics", append(guards.Metrics, h.Metrics)...)
+
+	// API v1 routes
+	// Outdated app builds get 426 before anything else runs
+	api := app.Group("/api/v1", h.AppVersionMiddleware)
+
+	// Authentication routes (no auth required)
+	auth := api.Group("/auth")
+	auth.Post("/register", h.Register)                                                                  // Email/password registration
+	auth.Post("/login/email", h.EmailLogin)                                                             // Email/password login
+	auth.Post("/login/phone", h.AttestationMiddleware(handlers.AttestationActionOTPRequest), h.SendOTP) // Phone-based OTP login (send OTP)
+	auth.Post("/login/google", h.GoogleLogin)                                                           // Google Sign-In (ID token)
+	auth.Post("/verify-otp", h.VerifyOTP)                                                               // Verify OTP and get token
+	auth.Post("/reactivate", h.ReactivateAccount)                                                       // Reactivation OTP after logging in to a deactivated account
+	auth.Post("/refresh", h.RefreshToken)                                                               // New access and refresh token for a refresh token
+	auth.Post("/logout", h.AuthMiddleware, h.Logout)                                                    // Revokes the access token and refresh token
+
+	// Guest checkout: OTP signs the guest up (or in) and carries their cart over
+	auth.Post("/guest/send-otp", h.AttestationMiddleware(handlers.AttestationActionOTPRequest), h.SendGuestOTP)
+	auth.Post("/guest/verify", h.ConvertGuest) // Also takes guest_token to merge the cart
+
+	// Two-factor authentication (TOTP) for admin accounts
+	// Only needs a valid login token; the verified token unlocks admin routes
+	mfa := auth.Group("/2fa", h.AuthMiddleware)
+	mfa.Get("/", h.GetTOTPStatus)
+	mfa.Post("/enroll", h.BeginTOTPEnrollment)
+	mfa.Post("/enroll/confirm", h.ConfirmTOTPEnrollment)
+	mfa.Post("/verify", h.VerifyMFA)
+	mfa.Post("/recovery-codes", h.RegenerateRecoveryCodes)
+
+	// The caller's own account
+	account := api.Group("/account", h.AuthMiddleware)
+	account.Post("/deactivate", h.DeactivateAccount)
+	account.Get("/locale", h.GetAccountLocale)
+	account.Put("/locale", h.SetAccountLocale)
+
+	// The caller's app installs, for push notifications
+	devices := api.Group("/devices", h.AuthMiddleware)
+	devices.Post("/", h.RegisterDevice)     // {"token": "...", "platform": "android|ios|web"}
+	devices.Delete("/", h.UnregisterDevice) // {"token": "..."}
+
+	// Saved delivery addresses
+	addresses := api.Group("/addresses", h.AuthMiddleware)
+	addresses.Get("/", h.GetAddresses)
+	addresses.Post("/", h.CreateAddress)
+	addresses.Put("/:id", h.UpdateAddress)
+	addresses.Delete("/:id", h.DeleteAddress)
+	addresses.Post("/:id/default", h.SetDefaultAddress)
+
+	// Menu routes (public read, admin write)
+	// Register directly on API group without creating a subgroup
+	api.Get("/menu", h.GetMenu)
+	api.Get("/menu/:id", h.GetMenuItem)
+
+	// In-app banners for the caller's segment; guests get theirs signed out
+	api.Get("/announcements", h.OptionalAuthMiddleware, h.GetAnnouncements)
+	api.Post("/announcements/track", h.TrackAnnouncements) // {"impressions": [ids], "clicks": [ids]}
+
+	// Guest carts, keyed by the X-Guest-Token from /guest/session
+	guest := api.Group("/guest")
+	guest.Post("/session", h.StartGuestSession)
+	guest.Get("/cart", h.GetGuestCart)
+	guest.Put("/cart", h.SaveGuestCart)
+
+	// The signed-in user's saved cart
+	cart := api.Group("/cart", h.AuthMiddleware)
+	cart.Get("/", h.GetCart)
+	cart.Put("/", h.SaveCart)
+	cart.Delete("/", h.ClearCart)
+	cart.Post("/items", h.AddCartItem)
+	cart.Put("/items/:menu_item_id", h.UpdateCartItem)
+	cart.Delete("/items/:menu_item_id", h.RemoveCartItem)
+	cart.Post("/merge", h.MergeGuestCart) // Carry a guest cart over after logging in
+
+	// Protected routes (require authentication)
+	// Using JWT middleware for authentication
+	// Use specific paths instead of "/" to avoid catching public routes
+	orders := api.Group("/orders", h.AuthMiddleware)
+	orders.Post("/create", h.AttestationMiddleware(handlers.AttestationActionOrderCreate), h.CreateOrder)
+	orders.Post("/quote", h.QuoteOrder) // Same body as /create; nothing is created
+	orders.Get("/", h.GetUserOrders)
+	orders.Get("/slots", h.GetKitchenSlots) // Registered before /:id
+	orders.Get("/delivery-slots", h.GetDeliverySlots)
+	orders.Get("/:id", h.GetOrder)
+	orders.Get("/:id/pickup-code", h.GetPickupCode)
+	orders.Get("/:id/delivery-otp", h.GetDeliveryOTP)
+	orders.Post("/:id/feedback", h.SubmitOrderFeedback) // NPS survey answer
+	orders.Get("/:id/shortages", h.GetMyOrderShortages)
+	orders.Post("/:id/shortage/reply", h.ReplyToShortage) // {"reply": "accept"|"cancel"}
+	orders.Get("/:id/issues", h.GetOrderIssueOptions)
+	orders.Post("/:id/issues", h.ReportOrderIssue) // Auto-resolved within the compensation rules, else escalated
+	orders.Post("/:id/cancel", h.CancelOrder)      // Until the kitchen accepts it; paid orders are refunded
+	orders.Get("/:id/proof-of-delivery/photo", h.GetDeliveryProofPhoto)
+	orders.Get("/:id/chat", h.GetOrderChat) // ?after=RFC3339 returns only newer messages
+	orders.Post("/:id/chat", h.SendOrderChat)
+	orders.Get("/:id/events", h.OrderEvents) // Server-Sent Events; ends once the order is delivered or cancelled
+	orders.Post("/verify", h.VerifyPayment)
+
+	// Post-delivery surveys awaiting an answer
+	api.Get("/surveys", h.AuthMiddleware, h.GetOpenSurveys)
+
+	// Rider app routes (require rider role)
+	rider := api.Group("/rider", h.AuthMiddleware, h.RiderMiddleware)
+	rider.Get("/orders", h.GetRiderOrders)
+	rider.Post("/orders/:id/deliver", h.ConfirmDelivery)   // Requires the customer's OTP
+	rider.Post("/orders/:id/proof", h.UploadDeliveryProof) // Multipart "photo" field
+	rider.Get("/route", h.GetRiderRoute)                   // Active batch with stop order and ETAs
+	rider.Post("/heartbeat", h.RiderHeartbeat)             // Every ~30s while on shift; optional location
+	rider.Post("/offline", h.RiderOffline)
+	rider.Get("/orders/:id/chat", h.GetRiderOrderChat)
+	rider.Post("/orders/:id/chat", h.SendRiderOrderChat)
+	rider.Post("/orders/:id/call", h.CallCustomer) // Masked call; rings the rider, then the customer
+
+	// Live order updates over WebSocket; the JWT goes in the Authorization
+	// header or ?access_token=
+	live := api.Group("/live", h.LiveUpgradeMiddleware)
+	live.Get("/orders", h.AuthMiddleware, websocket.New(h.OrderFeed)) // The caller's orders
+	live.Get("/admin/orders", append(guards.Admin, h.AuthMiddleware, h.AdminMiddleware, h.AdminMFAMiddleware, websocket.New(h.AdminOrderFeed))...)
+
+	// Admin routes (require admin role and a TOTP-verified session)
+	admin := api.Group("/admin", append(guards.Admin, h.AuthMiddleware, h.AdminMiddleware, h.AdminMFAMiddleware)...)
+	admin.Post("/menu", h.CreateMenuItem)
+	admin.Put("/menu/order", h.ReorderMenu)
+	admin.Put("/menu/availability", h.SetMenuAvailability) // {"item_ids": [...], "is_available": false}
+	admin.Put("/menu/sections/:section", h.SetMenuSection)
+	admin.Put("/menu/:id", h.UpdateMenuItem)
+	admin.Put("/menu/:id/stock", h.SetMenuItemStock)
+	admin.Put("/menu/:id/stock-threshold", h.SetMenuItemStockThreshold) // null uses the default
+	admin.Get("/inventory/stock", h.GetStockSummary)
+	admin.Put("/menu/:id/options", h.SetMenuItemOptions)
+	admin.Get("/menu/as-of", h.GetMenuAsOf)
+	admin.Get("/menu/:id/price-history", h.GetMenuPriceHistory)
+	admin.Delete("/menu/:id", h.DeleteMenuItem)
+	admin.Post("/menu/invalidate-cache", h.InvalidateMenuCache)
+	admin.Get("/orders", h.GetAllOrders)
+	admin.Get("/orders/export", h.ExportOrders)  // Streamed JSON, up to 92 days per request
+	admin.Post("/orders/import", h.ImportOrders) // Historical orders from the legacy platform
+	admin.Put("/orders/:id/status", h.UpdateOrderStatus)
+	admin.Post("/orders/:id/cancel", h.AdminCancelOrder)        // Audited; same rules and refund as customer cancellation
+	admin.Post("/orders/pickup/verify", h.VerifyPickup)         // Counter staff scan/enter pickup code
+	admin.Get("/orders/:id/payment-events", h.GetPaymentEvents) // Payment audit trail
+	admin.Get("/orders/:id/webhooks", h.GetOrderWebhookLogs)    // Raw Razorpay webhooks for the order
+	admin.Get("/orders/payment-reviews", h.GetPaymentReviews)   // Captured payments that didn't match their order
+	admin.Post("/orders/:id/payment-review/resolve", h.ResolvePaymentReview)
+	admin.Post("/orders/:id/unavailable-items", h.ReportItemShortage) // Kitchen ran out after payment
+	admin.Get("/orders/:id/shortages", h.GetOrderShortages)
+	admin.Get("/order-issues", h.GetOrderIssues) // ?status=ESCALATED for the queue
+	admin.Post("/order-issues/:id/resolve", h.ResolveOrderIssue)
+	admin.Post("/orders/:id/refunds", h.InitiateRefund) // {"amount": paisa, "reason": ...}; no amount refunds the rest
+	admin.Get("/orders/:id/refunds", h.GetOrderRefunds)
+	admin.Get("/orders/:id/chat", h.GetAdminOrderChat) // Full transcript, for support cases
+	admin.Post("/orders/:id/chat", h.SendSupportOrderChat)
+	admin.Get("/orders/:id/calls", h.GetOrderCalls)
+	admin.Get("/refunds/:id", h.GetRefundStatus)                  // Asks Razorpay while pending
+	admin.Get("/webhooks", h.GetWebhookLogs)                      // ?order_id=&status=&event=&since=&until=
+	admin.Get("/webhooks/:id", h.GetWebhookLog)                   // With the raw payload
+	admin.Post("/webhooks/:id/replay", h.ReplayWebhook)           // Re-runs the handler, e.g. for a stuck payment
+	admin.Post("/orders/:id/tags", h.UpdateOrderTags)             // {"add": [...], "remove": [...]}
+	admin.Post("/orders/:id/attributes", h.UpdateOrderAttributes) // {"set": {...}, "remove": [...]}
+	admin.Put("/orders/:id/test", h.SetTestOrder)                 // {"test": true|false}; overrides detection
+	admin.Get("/order-attributes", h.GetOrderAttributeDefinitions)
+	admin.Put("/order-attributes/:key", h.DefineOrderAttribute)
+	admin.Post("/orders/:id/dispatch", h.DispatchOrder)
+	admin.Post("/orders/:id/deliver-override", h.OverrideDelivery)
+	admin.Put("/users/:id/rider", h.SetRiderStatus)
+	admin.Get("/presence", h.GetPresence)                      // Online riders and kitchen screens
+	admin.Post("/kitchen/heartbeat", h.KitchenScreenHeartbeat) // Sent by kitchen displays
+	admin.Post("/users/:id/deactivate", h.AdminDeactivateUser) // Reason required; the user can't undo it
+	admin.Post("/users/:id/reactivate", h.AdminReactivateUser)
+	admin.Get("/users/segment", h.GetUserSegment) // ?churn_risk=&min_orders=&min_spend=&inactive_days=&active_days=
+	admin.Get("/users/:id", h.GetUserDetail)      // Profile with order stats and churn flag
+	admin.Get("/delivery-batches/suggestions", h.SuggestDeliveryBatches)
+	admin.Post("/delivery-batches", h.CreateDeliveryBatch)
+	admin.Get("/analytics/nps", h.GetNPS)
+	admin.Get("/analytics/timeseries", h.GetOrderTimeSeries)
+	admin.Get("/activity", h.GetAdminActivity)
+	admin.Get("/analytics/menu-items/:id/prices", h.GetItemPriceReport)
+	admin.Get("/analytics/sustainability", h.GetSustainabilityReport) // Monthly cutlery and packaging choices
+	admin.Get("/surveys/alerts", h.GetSurveyAlerts)
+	admin.Post("/surveys/:id/acknowledge", h.AcknowledgeSurveyAlert)
+	admin.Get("/settings", h.GetSettings)
+	admin.Get("/settings/:key", h.GetSetting)
+	admin.Put("/settings/:key", h.UpdateSetting)
+	admin.Delete("/settings/:key", h.ResetSetting)
+	admin.Get("/settings/:key/history", h.GetSettingHistory)
+	admin.Get("/templates", h.GetTemplates)
+	admin.Get("/templates/:key/:locale", h.GetTemplate)
+	admin.Put("/templates/:key/:locale", h.UpdateTemplate)
+	admin.Delete("/templates/:key/:locale", h.ResetTemplate)
+	admin.Post("/templates/:key/preview", h.PreviewTemplate)
+	admin.Post("/templates/:key/test-send", h.TestSendTemplate)
+	admin.Get("/capacity", h.GetCapacityRules)
+	admin.Put("/capacity", h.ReplaceCapacityRules)
+
+	// Kitchen prep stations: category routing, per-station KDS queues and
+	// the expo view of order readiness
+	admin.Get("/kitchen/stations", h.GetPrepStations)
+	admin.Post("/kitchen/stations", h.CreatePrepStation)
+	admin.Put("/kitchen/stations/:id", h.UpdatePrepStation)
+	admin.Delete("/kitchen/stations/:id", h.DeletePrepStation)
+	admin.Get("/kitchen/stations/:id/queue", h.GetStationQueue)
+	admin.Post("/kitchen/tickets/:id/start", h.StartStationTicket)
+	admin.Post("/kitchen/tickets/:id/ready", h.ReadyStationTicket) // Returns the order's readiness
+	admin.Get("/kitchen/expo", h.GetKitchenExpo)
+	admin.Get("/orders/:id/stations", h.GetOrderStations)
+	admin.Post("/orders/:id/route", h.RouteOrderToStations) // Accepted before stations were set up
+
+	// In-app banners
+	admin.Get("/announcements", h.GetAdminAnnouncements) // With impressions, clicks and click rate; ?since=
+	admin.Post("/announcements", h.CreateAnnouncement)
+	admin.Put("/announcements/:id", h.UpdateAnnouncement)
+	admin.Delete("/announcements/:id", h.DeleteAnnouncement)
+
+	// Partner API key management
+	admin.Post("/api-keys", h.IssueAPIKey)
+	admin.Get("/api-keys", h.ListAPIKeys)
+	admin.Post("/api-keys/:id/rotate", h.RotateAPIKey)
+	admin.Delete("/api-keys/:id", h.RevokeAPIKey)
+	admin.Get("/api-keys/:id/usage", h.GetAPIKeyUsage)
+
+	// Partner routes (server-to-server, X-API-Key auth with per-key scopes and quotas)
+	partner := app.Group("/partner/v1")
+	partner.Get("/menu", h.APIKeyMiddleware(domain.APIKeyScopeMenuRead), h.GetMenu)
+	partner.Get("/orders", h.APIKeyMiddleware(domain.APIKeyScopeOrdersRead), h.GetAllOrders)
+
+	// Webhook routes (Razorpay and call provider callbacks)
+	// These bypass normal auth but use signature verification, optionally
+	// restricted to the gateway's published source IPs
+	webhooks := app.Group("/webhooks")
+	webhooks.Post("/razorpay", append(guards.Webhook, h.RazorpayWebhook)...)
+	webhooks.Post("/calls/:id", h.CallStatusCallback) // Signed token per call in the query string
+}
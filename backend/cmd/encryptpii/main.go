@@ -0,0 +1,70 @@
+// Package main encrypts phone numbers and emails that are still stored in
+// plaintext, and re-encrypts values written under a retired key after a key
+// rotation. Safe to re-run and to run while the API is serving traffic.
+//
+// Usage:
+//
+//	DATABASE_URL=... PII_ENCRYPTION_KEYS=... PII_BLIND_INDEX_KEY=... encryptpii
+package main
+
+import (
+	"context"
+	"os"
+
+	"fooddelivery/internal/config"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/logger"
+)
+
+func main() {
+	logger.Init()
+	log := logger.NewLogger()
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	cfg := &config.Config{
+		PIIEncryptionKeys: os.Getenv("PII_ENCRYPTION_KEYS"),
+		PIIBlindIndexKey:  os.Getenv("PII_BLIND_INDEX_KEY"),
+	}
+	protector, err := cfg.PIIProtector()
+	if err != nil {
+		log.Fatal("Invalid PII encryption keys", "error", err)
+	}
+	if protector == nil {
+		log.Fatal("PII_ENCRYPTION_KEYS and PII_BLIND_INDEX_KEY are required")
+	}
+
+	ctx := context.Background()
+	dbPool, err := database.NewPostgresPool(ctx, databaseURL, log)
+	if err != nil {
+		log.Fatal("Failed to connect to PostgreSQL", "error", err)
+	}
+	defer dbPool.Close()
+
+	userRepo := repository.NewUserRepository(dbPool)
+	userRepo.SetPIIProtector(protector)
+
+	total := 0
+	for {
+		n, err := userRepo.EncryptPendingPII(ctx)
+		if err != nil {
+			log.Fatal("Failed to encrypt users", "error", err, "encrypted", total)
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+		log.Info("Encrypted user batch", "batch", n, "total", total)
+	}
+
+	purged, err := userRepo.PurgeExpiredPlaintextOTPs(ctx)
+	if err != nil {
+		log.Fatal("Failed to purge plaintext OTPs", "error", err)
+	}
+
+	log.Info("PII encryption complete", "users", total, "otps_purged", purged, "key_id", protector.KeyID())
+}
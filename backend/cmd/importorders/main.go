@@ -0,0 +1,97 @@
+// Package main is a one-off tool that imports historical orders exported
+// from the legacy platform. It shares the import logic behind
+// POST /admin/orders/import but has no upload size limit beyond
+// usecase.MaxImportOrders per run.
+//
+// Usage:
+//
+//	DATABASE_URL=... importorders -file orders.csv -source legacy-2023 [-aliases aliases.json] [-dry-run]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/logger"
+)
+
+func main() {
+	filePath := flag.String("file", "", "legacy export (.json or .csv)")
+	source := flag.String("source", "", "label for this export, e.g. legacy-2023")
+	aliasesPath := flag.String("aliases", "", "optional JSON object mapping legacy item names to menu item names")
+	dryRun := flag.Bool("dry-run", false, "validate and map without writing")
+	flag.Parse()
+
+	logger.Init()
+	log := logger.NewLogger()
+
+	if *filePath == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	opts := usecase.ImportOptions{Source: *source, DryRun: *dryRun}
+	if *aliasesPath != "" {
+		raw, err := os.ReadFile(*aliasesPath)
+		if err != nil {
+			log.Fatal("Failed to read aliases", "error", err)
+		}
+		if err := json.Unmarshal(raw, &opts.Aliases); err != nil {
+			log.Fatal("Aliases must be a JSON object of legacy name to menu item name", "error", err)
+		}
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		log.Fatal("Failed to open export", "error", err)
+	}
+	defer file.Close()
+
+	var orders []usecase.LegacyOrder
+	switch strings.ToLower(filepath.Ext(*filePath)) {
+	case ".json":
+		orders, err = usecase.ParseLegacyJSON(file)
+	case ".csv":
+		orders, err = usecase.ParseLegacyCSV(file)
+	default:
+		log.Fatal("Export must be a .json or .csv file")
+	}
+	if err != nil {
+		log.Fatal("Failed to parse export", "error", err)
+	}
+
+	ctx := context.Background()
+	dbPool, err := database.NewPostgresPool(ctx, databaseURL, log)
+	if err != nil {
+		log.Fatal("Failed to connect to PostgreSQL", "error", err)
+	}
+	defer dbPool.Close()
+
+	importUsecase := usecase.NewOrderImportUsecase(
+		repository.NewOrderRepository(dbPool),
+		repository.NewMenuRepository(dbPool),
+		repository.NewUserRepository(dbPool),
+		log,
+	)
+
+	result, err := importUsecase.Import(ctx, orders, opts)
+	if err != nil {
+		log.Fatal("Import failed", "error", err)
+	}
+
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+}
@@ -0,0 +1,55 @@
+// Package main moves the app's Redis keys into the namespace set by
+// REDIS_NAMESPACE, for an environment that starts using one on a Redis
+// instance it already wrote to. Run it once right after the first deploy
+// with the namespace; until it finishes, sessions and caches written before
+// the deploy are not found. Safe to re-run.
+//
+// Only migrate the environment that owns the existing keys (usually
+// production); others sharing the instance start with an empty namespace.
+//
+// Usage:
+//
+//	REDIS_URL=... REDIS_NAMESPACE=production redisnamespace [-pattern 'app:*'] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+func main() {
+	pattern := flag.String("pattern", "app:*", "keys to move, as a SCAN MATCH pattern")
+	dryRun := flag.Bool("dry-run", false, "count matching keys without moving them")
+	flag.Parse()
+
+	logger.Init()
+	log := logger.NewLogger()
+
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		log.Fatal("REDIS_URL environment variable is required")
+	}
+	namespace := os.Getenv("REDIS_NAMESPACE")
+	if namespace == "" {
+		log.Fatal("REDIS_NAMESPACE environment variable is required")
+	}
+
+	client, err := redis.NewClient(redisURL, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", "error", err)
+	}
+	defer client.Close()
+	client.SetNamespace(namespace)
+
+	moved, skipped, err := client.MigrateNamespace(context.Background(), *pattern, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to migrate keys", "error", err, "moved", moved)
+	}
+
+	log.Info("Redis namespace migration complete",
+		"namespace", client.Namespace(), "moved", moved, "skipped", skipped, "dry_run", *dryRun)
+}
@@ -0,0 +1,395 @@
+// Package main runs a scripted end-to-end order against a deployed
+// environment for post-deploy checks: register a throwaway customer, read
+// the menu, place a pickup order through the sandbox gateway, deliver a
+// signed payment.captured webhook, accept the order and hand it over as an
+// admin, then deactivate the customer. Each step is verified; any failure
+// exits non-zero.
+//
+// The target must use Razorpay test keys, allow this host through any
+// webhook IP allow-list, and not enforce app attestation on order creation.
+//
+// Usage:
+//
+//	RAZORPAY_WEBHOOK_SECRET=... SMOKE_ADMIN_PASSWORD=... [SMOKE_ADMIN_TOTP_SECRET=...] \
+//	  smoke -base-url https://api.staging.example.com -admin-email ops@example.com
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/totp"
+)
+
+func main() {
+	baseURL := flag.String("base-url", os.Getenv("SMOKE_BASE_URL"), "API base URL, e.g. https://api.staging.example.com")
+	adminEmail := flag.String("admin-email", os.Getenv("SMOKE_ADMIN_EMAIL"), "admin account that accepts and hands over the order")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	flag.Parse()
+
+	logger.Init()
+	log := logger.NewLogger()
+
+	webhookSecret := os.Getenv("RAZORPAY_WEBHOOK_SECRET")
+	adminPassword := os.Getenv("SMOKE_ADMIN_PASSWORD")
+	if *baseURL == "" || *adminEmail == "" || webhookSecret == "" || adminPassword == "" {
+		flag.Usage()
+		log.Fatal("base URL, admin email, RAZORPAY_WEBHOOK_SECRET and SMOKE_ADMIN_PASSWORD are required")
+	}
+
+	s := &smoke{
+		api:           &apiClient{baseURL: strings.TrimRight(*baseURL, "/"), http: &http.Client{Timeout: *timeout}},
+		webhookSecret: webhookSecret,
+		adminEmail:    *adminEmail,
+		adminPassword: adminPassword,
+		adminTOTP:     os.Getenv("SMOKE_ADMIN_TOTP_SECRET"),
+	}
+
+	if err := s.run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "smoke test failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("smoke test passed")
+}
+
+// smoke holds the state passed between steps
+type smoke struct {
+	api           *apiClient
+	webhookSecret string
+	adminEmail    string
+	adminPassword string
+	adminTOTP     string
+
+	userToken  string
+	adminToken string
+	item       domain.MenuItem
+	variantID  *uuid.UUID // Set when the item can only be ordered as a variant
+	order      usecase.InitiateOrderResponse
+}
+
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// run executes the steps in order, stopping at the first failure. The
+// customer is deactivated even when a later step fails.
+func (s *smoke) run(ctx context.Context) error {
+	steps := []step{
+		{"health", s.health},
+		{"register customer", s.register},
+		{"fetch menu", s.fetchMenu},
+		{"place pickup order", s.placeOrder},
+		{"deliver payment webhook", s.payByWebhook},
+		{"order is paid", s.expectStatus(domain.OrderStatusPaid)},
+		{"admin login", s.adminLogin},
+		{"accept order", s.accept},
+		{"hand over with pickup code", s.handOver},
+		{"order is delivered", s.expectStatus(domain.OrderStatusDelivered)},
+	}
+
+	var failed error
+	for _, st := range steps {
+		start := time.Now()
+		if err := st.fn(ctx); err != nil {
+			fmt.Printf("FAIL %-28s %v\n", st.name, err)
+			failed = fmt.Errorf("%s: %w", st.name, err)
+			break
+		}
+		fmt.Printf("ok   %-28s %s\n", st.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if s.userToken != "" {
+		if err := s.deactivate(ctx); err != nil {
+			fmt.Printf("FAIL %-28s %v\n", "deactivate customer", err)
+			failed = errors.Join(failed, fmt.Errorf("deactivate customer: %w", err))
+		} else {
+			fmt.Printf("ok   %-28s\n", "deactivate customer")
+		}
+	}
+	return failed
+}
+
+func (s *smoke) health(ctx context.Context) error {
+	return s.api.do(ctx, http.MethodGet, "/health", "", nil, nil)
+}
+
+func (s *smoke) register(ctx context.Context) error {
+	suffix := time.Now().UnixNano() % 1e8
+	req := usecase.RegisterRequest{
+		PhoneNumber: fmt.Sprintf("+9190%08d", suffix),
+		Name:        "Smoke Test",
+		Email:       fmt.Sprintf("smoke+%d@example.com", time.Now().UnixNano()),
+		Password:    randomHex(16),
+	}
+
+	var resp usecase.RegisterResponse
+	if err := s.api.do(ctx, http.MethodPost, "/api/v1/auth/register", "", req, &resp); err != nil {
+		return err
+	}
+	if resp.Token == "" {
+		return errors.New("no token in registration response")
+	}
+	s.userToken = resp.Token
+	return nil
+}
+
+func (s *smoke) fetchMenu(ctx context.Context) error {
+	var menu usecase.MenuResponse
+	if err := s.api.do(ctx, http.MethodGet, "/api/v1/menu", "", nil, &menu); err != nil {
+		return err
+	}
+
+	// Prefer a plain item; failing that, order the first variant of one
+	var withVariants *domain.MenuItem
+	for i := range menu.Items {
+		item := &menu.Items[i]
+		switch {
+		case !item.IsAvailable:
+		case item.HasVariants():
+			if withVariants == nil {
+				withVariants = item
+			}
+		case item.Price > 0:
+			s.item = *item
+			return nil
+		}
+	}
+	if withVariants != nil {
+		for _, v := range withVariants.Variants {
+			if v.IsAvailable {
+				s.item, s.variantID = *withVariants, &v.ID
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no available items among %d on the menu", len(menu.Items))
+}
+
+func (s *smoke) placeOrder(ctx context.Context) error {
+	req := map[string]any{
+		"items":            []domain.CartItem{{MenuItemID: s.item.ID, Quantity: 1, VariantID: s.variantID}},
+		"fulfillment_type": domain.FulfillmentPickup,
+	}
+
+	// The charged amount includes packaging and GST, so check it against
+	// the quote for the same cart rather than the item price
+	var quote usecase.OrderQuote
+	if err := s.api.do(ctx, http.MethodPost, "/api/v1/orders/quote", s.userToken, req, &quote); err != nil {
+		return fmt.Errorf("quote: %w", err)
+	}
+
+	if err := s.api.do(ctx, http.MethodPost, "/api/v1/orders/create", s.userToken, req, &s.order); err != nil {
+		return err
+	}
+	if s.order.RazorpayOrderID == "" {
+		return errors.New("no Razorpay order ID in response")
+	}
+	if s.order.Amount != quote.Total {
+		return fmt.Errorf("amount %d, expected the quoted total %d", s.order.Amount, quote.Total)
+	}
+	return nil
+}
+
+// payByWebhook sends the payment.captured event Razorpay would send once
+// the customer pays, signed with the webhook secret
+func (s *smoke) payByWebhook(ctx context.Context) error {
+	var payment usecase.PaymentEntity
+	entity := &payment.Payment.Entity
+	entity.ID = "pay_smoke" + randomHex(6)
+	entity.Amount = s.order.Amount
+	entity.Currency = s.order.Currency
+	entity.Status = "captured"
+	entity.OrderID = s.order.RazorpayOrderID
+	entity.Method = "upi"
+	entity.Captured = true
+
+	inner, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(usecase.WebhookPayload{
+		Entity:    "event",
+		Event:     "payment.captured",
+		Contains:  []string{"payment"},
+		Payload:   inner,
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.api.baseURL+"/webhooks/razorpay", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Razorpay-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return s.api.send(req, nil)
+}
+
+// expectStatus checks the customer's view of the order
+func (s *smoke) expectStatus(want domain.OrderStatus) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var order domain.Order
+		if err := s.api.do(ctx, http.MethodGet, "/api/v1/orders/"+s.order.ID.String(), s.userToken, nil, &order); err != nil {
+			return err
+		}
+		if order.Status != want {
+			return fmt.Errorf("status %s, expected %s", order.Status, want)
+		}
+		return nil
+	}
+}
+
+// adminLogin logs in and, when the account needs it, completes TOTP so
+// admin routes unlock
+func (s *smoke) adminLogin(ctx context.Context) error {
+	var login usecase.LoginResponse
+	req := usecase.EmailLoginRequest{Email: s.adminEmail, Password: s.adminPassword}
+	if err := s.api.do(ctx, http.MethodPost, "/api/v1/auth/login/email", "", req, &login); err != nil {
+		return err
+	}
+	s.adminToken = login.Token
+	if !login.MFARequired {
+		return nil
+	}
+
+	if s.adminTOTP == "" {
+		return errors.New("admin needs two-factor verification but SMOKE_ADMIN_TOTP_SECRET is not set")
+	}
+	code, err := totp.Code(s.adminTOTP, totp.Step(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to generate TOTP code: %w", err)
+	}
+
+	var verified usecase.MFATokenResponse
+	if err := s.api.do(ctx, http.MethodPost, "/api/v1/auth/2fa/verify", s.adminToken, usecase.VerifyMFARequest{Code: code}, &verified); err != nil {
+		return err
+	}
+	s.adminToken = verified.Token
+	return nil
+}
+
+func (s *smoke) accept(ctx context.Context) error {
+	req := map[string]string{"status": string(domain.OrderStatusAccepted)}
+	return s.api.do(ctx, http.MethodPut, "/api/v1/admin/orders/"+s.order.ID.String()+"/status", s.adminToken, req, nil)
+}
+
+// handOver reads the code as the customer and enters it as counter staff
+func (s *smoke) handOver(ctx context.Context) error {
+	var pickup usecase.PickupCodeResponse
+	if err := s.api.do(ctx, http.MethodGet, "/api/v1/orders/"+s.order.ID.String()+"/pickup-code", s.userToken, nil, &pickup); err != nil {
+		return err
+	}
+
+	req := usecase.VerifyPickupRequest{OrderID: s.order.ID, Code: pickup.Code}
+	return s.api.do(ctx, http.MethodPost, "/api/v1/admin/orders/pickup/verify", s.adminToken, req, nil)
+}
+
+// deactivate retires the throwaway customer so smoke runs don't pile up
+// active accounts
+func (s *smoke) deactivate(ctx context.Context) error {
+	req := map[string]string{"reason": "smoke test"}
+	return s.api.do(ctx, http.MethodPost, "/api/v1/account/deactivate", s.userToken, req, nil)
+}
+
+// apiClient calls the API and unwraps its success envelope
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// do sends body as JSON and decodes the response's data into out
+func (c *apiClient) do(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.send(req, out)
+}
+
+// send performs req, failing on any non-2xx status with the error and
+// request ID the API returned
+func (c *apiClient) send(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		}
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s %s: %d %s (request_id %s)", req.Method, req.URL.Path, resp.StatusCode, apiErr.Error, apiErr.RequestID)
+		}
+		return fmt.Errorf("%s %s: %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", req.Method, req.URL.Path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("%s %s: unexpected data: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -3,9 +3,21 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/calls"
+	"fooddelivery/pkg/email"
+	"fooddelivery/pkg/events"
+	"fooddelivery/pkg/pii"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/retry"
+	"fooddelivery/pkg/sms"
+	"fooddelivery/pkg/throttle"
 )
 
 // Config holds all application configuration
@@ -15,64 +27,713 @@ type Config struct {
 	Environment    string
 	AllowedOrigins string
 
+	// Per-request deadline and body size limits; multipart uploads get
+	// their own, larger limits
+	RequestTimeoutMs int
+	UploadTimeoutMs  int
+	MaxBodyBytes     int
+	MaxUploadBytes   int
+
 	// Database
 	DatabaseURL string
 
+	// Pool saturation watchdog: warn (with a goroutine dump) when the mean
+	// connection acquire wait over an interval exceeds the threshold
+	DBWatchdogThresholdMs int // 0 disables the watchdog
+	DBWatchdogDumpMinutes int // minimum gap between goroutine dumps
+
 	// Redis
-	RedisURL string
+	RedisURL              string
+	RedisNamespace        string // Prefix for every key, for environments sharing an instance
+	RedisCompressMinBytes int    // zstd-compress cached JSON of at least this size; 0 disables
 
 	// Razorpay credentials
 	Razorpay RazorpayConfig
 
 	// JWT settings
-	JWTSecret     string
-	JWTExpiration int // hours
+	JWTSecret        string
+	JWTAccessMinutes int // Access token lifetime
+	JWTRefreshHours  int // Refresh token lifetime
+
+	// Google Sign-In OAuth client IDs (web, Android, iOS)
+	GoogleClientIDs []string
+
+	// Admin two-factor authentication
+	AdminMFARequired bool
+	TOTPIssuer       string
+
+	// Mobile app attestation (Firebase App Check over Play Integrity / App Attest)
+	AttestationMode       string // off, monitor or enforce
+	FirebaseProjectNumber string
+	AttestationAppIDs     []string
+
+	// Days a user can undo deactivating their own account by logging in
+	AccountReactivationDays int
+
+	// Business time zone for operating hours, report days and date filters
+	BusinessTimezone string
+	Location         *time.Location
+
+	// Language for messages to users who haven't chosen or been detected
+	// with one, and to non-users such as gift recipients
+	RestaurantLocale string
+
+	// Kitchen location and rider batching
+	KitchenLatitude   float64
+	KitchenLongitude  float64
+	BatchRadiusMeters int
+	BatchMaxOrders    int
+	RiderSpeedKmph    float64
+	StopDwellMinutes  int
+
+	// Rider apps and kitchen screens are online while they heartbeat within
+	// PresenceTTLSeconds; an open kitchen with no screen online alerts admins
+	PresenceTTLSeconds int
+	KitchenOpenHours   string // "HH:MM-HH:MM" local; default for kitchen.open_hours, empty = no check
+
+	// Distance-based delivery fee (amounts in paisa)
+	RoutingURL                 string
+	RoutingTimeoutMs           int
+	DeliveryBaseFee            int64
+	DeliveryBaseDistanceMeters int
+	DeliveryPerKmFee           int64
+	DeliveryMaxDistanceMeters  int
+
+	// Checkout charges up to this much (paisa) over the total the app
+	// displayed without asking the customer to confirm
+	PriceConfirmThreshold int64
+
+	// Order charges on top of the items: packaging per order (paisa) and
+	// GST on items and packaging (basis points, 500 = 5%)
+	PackagingCharge    int64
+	GSTRateBasisPoints int
+
+	// Mobile app builds below these versions get 426 Upgrade Required
+	// (defaults for the app.min_version.* settings; empty = no minimum)
+	AppMinVersionAndroid string
+	AppMinVersionIOS     string
+	AppUpdateURLAndroid  string
+	AppUpdateURLIOS      string
+
+	// Orders from these phone numbers (comma-separated) are tagged as tests
+	// (default for the test_orders.staff_phones setting)
+	TestOrderStaffPhones string
+
+	// Delivery ETA and weather adjustments
+	KitchenPrepMinutes  int
+	KitchenSlotCapacity int // Default orders per 15-minute slot; 0 = unlimited
+	WeatherEnabled      bool
+	WeatherAPIURL       string
+	WeatherETARules     string // e.g. "rain=10,heavy_rain=20"
+
+	// Delivery windows customers book at checkout
+	DeliverySlotCapacity    int // Default orders per 30-minute slot; 0 = unlimited
+	DeliverySlotHoldMinutes int // Unpaid orders give back their slot after this
+
+	// Items the kitchen runs out of after payment
+	ItemShortageResponseMinutes int // Customer's time to accept or cancel before it's accepted for them
+
+	// Post-delivery NPS surveys
+	SurveyDelayMinutes   int
+	SurveyAlertThreshold int
+
+	// Churn flags on customer order stats
+	ChurnMinOrders int // Orders that make a customer a regular
+	ChurnQuietDays int // Days without an order before a regular is at risk
+
+	// Self-service order issues - remedies applied without staff, within limits
+	IssueWindowHours     int   // Hours after delivery a problem can be reported
+	IssueMaxAutoRefund   int64 // Largest refund applied automatically (paisa)
+	IssueColdFoodPercent int   // Share of the subtotal refunded for cold food
+	IssueAutoLimit       int   // Automatic remedies per customer in 30 days
+
+	// Admin alerts - non-critical kinds are batched into digests
+	AdminAlertDigests    string // e.g. "survey.low_score=daily"; unlisted kinds are hourly
+	AdminAlertDigestHour int    // Local hour daily digests are sent
+	AdminAlertSlackURL   string // Slack incoming webhook also sent alerts and digests (empty = push only)
+
+	// Stock alerts on tracked menu items
+	LowStockThreshold   int  // Stock at or below which admins are alerted, unless the item sets its own
+	DisableSoldOutItems bool // Switch items off when they sell out, back on when restocked
+
+	// Expired sessions and OTPs, and old order chats, are purged every
+	// CleanupIntervalMinutes
+	CleanupIntervalMinutes int
+	SessionRetentionDays   int
+	ChatRetentionDays      int
+
+	// Order events outbox, relayed to a sink: log, redis (a stream) or kafka
+	// (through a REST Proxy). Published events are purged after
+	// OrderEventsRetentionDays.
+	OrderEventsSink          string
+	OrderEventsIntervalMs    int
+	OrderEventsTimeoutMs     int
+	OrderEventsRetentionDays int
+	OrderEventsStream        string // Redis stream key
+	OrderEventsStreamMaxLen  int    // Approximate cap on the stream; 0 keeps everything
+	Kafka                    events.KafkaConfig
+
+	// Retry policies per external integration, keyed by Integration* name
+	Retry map[string]RetryConfig
+
+	// Send rate limits per notification provider (push, SMS), keyed by
+	// Integration* name
+	Throttle map[string]throttle.Config
+
+	// Push provider for order updates and alerts: fcm, or empty to log
+	// notifications instead of sending them
+	PushProvider  string
+	PushTimeoutMs int
+	FCM           push.FCMConfig
+
+	// Email provider for order confirmations and receipts: smtp, sendgrid,
+	// or empty to log emails instead of sending them
+	EmailProvider  string
+	EmailFrom      email.Address
+	EmailTimeoutMs int
+	SMTP           email.SMTPConfig
+	SendGrid       email.SendGridConfig
+
+	// SMS provider for OTPs and gift order updates: twilio, msg91, or empty
+	// to log texts instead of sending them
+	SMSProvider           string
+	SMSDefaultCountryCode string // Added to 10-digit numbers, e.g. "91"
+	SMSTimeoutMs          int
+	Twilio                sms.TwilioConfig
+	MSG91                 sms.MSG91Config
+
+	// Masked rider-to-customer calls: exotel, twilio, or empty to log calls
+	// instead of placing them. Status callbacks need a public URL.
+	CallProvider             string
+	CallTimeoutMs            int
+	CallMaxMinutes           int
+	CallAfterDeliveryMinutes int    // Riders can still call this long after hand-over
+	CallStatusCallbackURL    string // e.g. https://api.example.com/webhooks/calls
+	CallCallbackSecret       string
+	Exotel                   calls.ExotelConfig
+	TwilioVoice              calls.TwilioConfig
+
+	// Object storage root for uploaded files (delivery proof photos)
+	StorageDir string
+
+	// PII encryption - "id:base64key,..." (first key encrypts) and the
+	// blind index key (base64); both 32 bytes
+	PIIEncryptionKeys string
+	PIIBlindIndexKey  string
+
+	// Transport hardening. Allowlists hold IPs and CIDR ranges; empty
+	// disables the check, except for metrics, which fall back to the admin
+	// list and then to loopback. Client IPs come from X-Forwarded-For only
+	// when the peer is a trusted proxy.
+	TrustedProxies         []string
+	AdminAllowedIPs        []string
+	WebhookAllowedIPs      []string
+	MetricsAllowedIPs      []string
+	TLSCertFile            string
+	TLSKeyFile             string
+	TLSClientCAFile        string
+	TLSClientAuth          string // none, optional or require
+	AdminRequireClientCert bool
+
+	// Error reporting (Sentry); disabled when the DSN is empty
+	SentryDSN             string
+	ErrorReportSampleRate float64 // fraction of 5xx errors reported; panics always are
+}
+
+// Integrations with their own retry policy
+const (
+	IntegrationPayment = "payment"
+	IntegrationRouting = "routing"
+	IntegrationWeather = "weather"
+	IntegrationPush    = "push"
+	IntegrationSMS     = "sms"
+	IntegrationEmail   = "email"
+	IntegrationWebhook = "webhook"
+
+	// Replays of incoming Razorpay webhooks whose processing failed
+	IntegrationWebhookReplay = "webhook_replay"
+
+	// Publishing the order events outbox to its sink
+	IntegrationOrderEvents = "order_events"
+)
+
+// RetryConfig is the retry behavior of one integration
+type RetryConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64 // 0..1
+}
+
+// Policy converts the config to a retry policy with the default classifier
+func (r RetryConfig) Policy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:    r.MaxAttempts,
+		InitialBackoff: r.InitialBackoff,
+		MaxBackoff:     r.MaxBackoff,
+		Jitter:         r.Jitter,
+	}
+}
+
+// defaultRetry is tuned per integration: user-facing calls (payment at
+// checkout, routing, weather) retry briefly, asynchronous deliveries
+// (push, SMS, email, outgoing webhooks) retry longer
+var defaultRetry = map[string]RetryConfig{
+	IntegrationPayment: {MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second, Jitter: 0.2},
+	IntegrationRouting: {MaxAttempts: 2, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, Jitter: 0.2},
+	IntegrationWeather: {MaxAttempts: 2, InitialBackoff: 200 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.2},
+	IntegrationPush:    {MaxAttempts: 4, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, Jitter: 0.5},
+	IntegrationSMS:     {MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Jitter: 0.5},
+	IntegrationEmail:   {MaxAttempts: 5, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute, Jitter: 0.5},
+	IntegrationWebhook: {MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second, Jitter: 0.5},
+
+	// Replayed by a background worker, so waits are long: 1m doubling, about
+	// 8 hours before giving up
+	IntegrationWebhookReplay: {MaxAttempts: 10, InitialBackoff: time.Minute, MaxBackoff: 6 * time.Hour, Jitter: 0.2},
+
+	// The relay never gives up on an event; MaxAttempts failed batches in a
+	// row (about 5 minutes) alert admins, then it keeps retrying every minute
+	IntegrationOrderEvents: {MaxAttempts: 8, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute, Jitter: 0.2},
+}
+
+// defaultThrottle stays under the providers' published quotas with room
+// for other clients of the same account
+var defaultThrottle = map[string]throttle.Config{
+	IntegrationPush: {Rate: 100, Burst: 20, QueueSize: 5000},
+	IntegrationSMS:  {Rate: 10, Burst: 5, QueueSize: 1000},
+}
+
+// RetryPolicy returns the retry policy for an integration
+func (c *Config) RetryPolicy(integration string) retry.Policy {
+	if rc, ok := c.Retry[integration]; ok {
+		return rc.Policy()
+	}
+	return retry.NoRetry
+}
+
+// PIIProtector builds the PII encryptor from config. Returns nil when no
+// keys are configured, in which case PII is stored in plaintext.
+func (c *Config) PIIProtector() (*pii.Protector, error) {
+	if c.PIIEncryptionKeys == "" && c.PIIBlindIndexKey == "" {
+		return nil, nil
+	}
+
+	keys, err := pii.ParseKeys(c.PIIEncryptionKeys)
+	if err != nil {
+		return nil, err
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(c.PIIBlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PII_BLIND_INDEX_KEY is not base64", pii.ErrInvalidKey)
+	}
+
+	return pii.NewProtector(keys, indexKey)
 }
 
 // RazorpayConfig holds Razorpay API credentials
 type RazorpayConfig struct {
-	KeyID        string
-	KeySecret    string
+	KeyID         string
+	KeySecret     string
 	WebhookSecret string
 }
 
+// SecretLookup resolves a credential by its environment variable name; ok
+// is false when the secrets manager doesn't hold it
+type SecretLookup func(key string) (value string, ok bool)
+
 // Load reads configuration from environment variables.
 // Returns error if required variables are missing.
 func Load() (*Config, error) {
+	return LoadWithSecrets(nil)
+}
+
+// LoadWithSecrets reads configuration like Load, taking credentials from
+// lookup first and falling back to environment variables
+func LoadWithSecrets(lookup SecretLookup) (*Config, error) {
 	cfg := &Config{}
 
+	getSecret := func(key string) string {
+		if lookup != nil {
+			if value, ok := lookup(key); ok && value != "" {
+				return value
+			}
+		}
+		return os.Getenv(key)
+	}
+
 	// Server settings with defaults
 	cfg.Port = getEnvInt("PORT", 8080)
 	cfg.Environment = getEnv("ENVIRONMENT", "development")
 	cfg.AllowedOrigins = getEnv("ALLOWED_ORIGINS", "*")
+	cfg.RequestTimeoutMs = getEnvInt("REQUEST_TIMEOUT_MS", 10000)
+	cfg.UploadTimeoutMs = getEnvInt("UPLOAD_TIMEOUT_MS", 60000)
+	cfg.MaxBodyBytes = getEnvInt("MAX_BODY_BYTES", 1<<20)
+	cfg.MaxUploadBytes = getEnvInt("MAX_UPLOAD_BYTES", 10<<20)
 
 	// Database - required
-	cfg.DatabaseURL = os.Getenv("DATABASE_URL")
+	cfg.DatabaseURL = getSecret("DATABASE_URL")
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
 
+	cfg.DBWatchdogThresholdMs = getEnvInt("DB_POOL_WAIT_WARN_MS", 100)
+	cfg.DBWatchdogDumpMinutes = getEnvInt("DB_POOL_DUMP_COOLDOWN_MINUTES", 5)
+
 	// Redis - required
-	cfg.RedisURL = os.Getenv("REDIS_URL")
+	cfg.RedisURL = getSecret("REDIS_URL")
 	if cfg.RedisURL == "" {
 		return nil, fmt.Errorf("REDIS_URL environment variable is required")
 	}
+	cfg.RedisNamespace = getEnv("REDIS_NAMESPACE", "")
+	cfg.RedisCompressMinBytes = getEnvInt("REDIS_COMPRESS_MIN_BYTES", 0)
+	if cfg.RedisCompressMinBytes < 0 {
+		return nil, fmt.Errorf("REDIS_COMPRESS_MIN_BYTES must not be negative")
+	}
 
 	// Razorpay - required for payment processing
-	cfg.Razorpay.KeyID = os.Getenv("RAZORPAY_KEY_ID")
-	cfg.Razorpay.KeySecret = os.Getenv("RAZORPAY_KEY_SECRET")
-	cfg.Razorpay.WebhookSecret = os.Getenv("RAZORPAY_WEBHOOK_SECRET")
+	cfg.Razorpay.KeyID = getSecret("RAZORPAY_KEY_ID")
+	cfg.Razorpay.KeySecret = getSecret("RAZORPAY_KEY_SECRET")
+	cfg.Razorpay.WebhookSecret = getSecret("RAZORPAY_WEBHOOK_SECRET")
 
 	if cfg.Razorpay.KeyID == "" || cfg.Razorpay.KeySecret == "" {
 		return nil, fmt.Errorf("RAZORPAY_KEY_ID and RAZORPAY_KEY_SECRET are required")
 	}
 
 	// JWT settings
-	cfg.JWTSecret = os.Getenv("JWT_SECRET")
+	cfg.JWTSecret = getSecret("JWT_SECRET")
 	if cfg.JWTSecret == "" {
 		return nil, fmt.Errorf("JWT_SECRET environment variable is required")
 	}
-	cfg.JWTExpiration = getEnvInt("JWT_EXPIRATION_HOURS", 24)
+	cfg.JWTAccessMinutes = getEnvInt("JWT_ACCESS_TOKEN_MINUTES", 15)
+	cfg.JWTRefreshHours = getEnvInt("JWT_REFRESH_TOKEN_HOURS", 30*24)
+
+	// Google Sign-In - optional, social login is disabled when unset
+	cfg.GoogleClientIDs = getEnvList("GOOGLE_CLIENT_IDS")
+
+	// Admin 2FA - enforced by default, disable only for local development
+	cfg.AdminMFARequired = getEnvBool("ADMIN_2FA_REQUIRED", true)
+	cfg.TOTPIssuer = getEnv("TOTP_ISSUER", "Crave Delivery")
+
+	// App attestation - off by default; roll out via monitor before enforce
+	cfg.AttestationMode = getEnv("ATTESTATION_MODE", "off")
+	cfg.FirebaseProjectNumber = os.Getenv("FIREBASE_PROJECT_NUMBER")
+	cfg.AttestationAppIDs = getEnvList("ATTESTATION_APP_IDS")
+	if cfg.AttestationMode != "off" && cfg.FirebaseProjectNumber == "" {
+		return nil, fmt.Errorf("FIREBASE_PROJECT_NUMBER is required when ATTESTATION_MODE is %s", cfg.AttestationMode)
+	}
+
+	cfg.StorageDir = getEnv("STORAGE_DIR", "./data/uploads")
+
+	// PII encryption - required in production, plaintext is tolerated locally
+	cfg.PIIEncryptionKeys = getSecret("PII_ENCRYPTION_KEYS")
+	cfg.PIIBlindIndexKey = getSecret("PII_BLIND_INDEX_KEY")
+	if cfg.Environment == "production" && (cfg.PIIEncryptionKeys == "" || cfg.PIIBlindIndexKey == "") {
+		return nil, fmt.Errorf("PII_ENCRYPTION_KEYS and PII_BLIND_INDEX_KEY are required in production")
+	}
+
+	// Transport hardening - all optional; TLS is usually terminated at the
+	// load balancer, set TLS_CERT_FILE to terminate (and verify clients) here
+	cfg.TrustedProxies = getEnvList("TRUSTED_PROXIES")
+	cfg.AdminAllowedIPs = getEnvList("ADMIN_ALLOWED_IPS")
+	cfg.WebhookAllowedIPs = getEnvList("RAZORPAY_WEBHOOK_ALLOWED_IPS")
+	cfg.MetricsAllowedIPs = getEnvList("METRICS_ALLOWED_IPS")
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.TLSClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	cfg.TLSClientAuth = getEnv("TLS_CLIENT_AUTH", "none")
+	cfg.AdminRequireClientCert = getEnvBool("ADMIN_REQUIRE_CLIENT_CERT", false)
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.AdminRequireClientCert && (cfg.TLSCertFile == "" || cfg.TLSClientAuth == "none") {
+		return nil, fmt.Errorf("ADMIN_REQUIRE_CLIENT_CERT needs TLS_CERT_FILE and TLS_CLIENT_AUTH=optional or require")
+	}
+
+	// Push - optional; without a provider notifications are only logged
+	cfg.PushProvider = strings.ToLower(os.Getenv("PUSH_PROVIDER"))
+	cfg.PushTimeoutMs = getEnvInt("PUSH_TIMEOUT_MS", 5000)
+	switch cfg.PushProvider {
+	case "fcm":
+		cfg.FCM = push.FCMConfig{
+			CredentialsFile: os.Getenv("FCM_CREDENTIALS_FILE"),
+			ProjectID:       os.Getenv("FCM_PROJECT_ID"),
+		}
+		if cfg.FCM.CredentialsFile == "" {
+			return nil, fmt.Errorf("FCM_CREDENTIALS_FILE is required when PUSH_PROVIDER is fcm")
+		}
+	case "":
+	default:
+		return nil, fmt.Errorf("PUSH_PROVIDER must be fcm, got %q", cfg.PushProvider)
+	}
+
+	// Email - optional; without a provider emails are only logged
+	cfg.EmailProvider = strings.ToLower(os.Getenv("EMAIL_PROVIDER"))
+	cfg.EmailFrom = email.Address{Email: os.Getenv("EMAIL_FROM"), Name: os.Getenv("EMAIL_FROM_NAME")}
+	cfg.EmailTimeoutMs = getEnvInt("EMAIL_TIMEOUT_MS", 10000)
+	if cfg.EmailProvider != "" && cfg.EmailFrom.Email == "" {
+		return nil, fmt.Errorf("EMAIL_FROM is required when EMAIL_PROVIDER is set")
+	}
+	switch cfg.EmailProvider {
+	case "smtp":
+		cfg.SMTP = email.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: getSecret("SMTP_PASSWORD"),
+			From:     cfg.EmailFrom,
+		}
+		if cfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when EMAIL_PROVIDER is smtp")
+		}
+	case "sendgrid":
+		cfg.SendGrid = email.SendGridConfig{
+			APIKey: getSecret("SENDGRID_API_KEY"),
+			From:   cfg.EmailFrom,
+		}
+		if cfg.SendGrid.APIKey == "" {
+			return nil, fmt.Errorf("SENDGRID_API_KEY is required when EMAIL_PROVIDER is sendgrid")
+		}
+	case "":
+	default:
+		return nil, fmt.Errorf("EMAIL_PROVIDER must be smtp or sendgrid, got %q", cfg.EmailProvider)
+	}
+
+	// SMS - optional; without a provider OTPs are not delivered, so
+	// production requires one
+	cfg.SMSProvider = strings.ToLower(os.Getenv("SMS_PROVIDER"))
+	cfg.SMSDefaultCountryCode = getEnv("SMS_DEFAULT_COUNTRY_CODE", "91")
+	cfg.SMSTimeoutMs = getEnvInt("SMS_TIMEOUT_MS", 5000)
+	switch cfg.SMSProvider {
+	case "twilio":
+		cfg.Twilio = sms.TwilioConfig{
+			AccountSID:         os.Getenv("TWILIO_ACCOUNT_SID"),
+			AuthToken:          getSecret("TWILIO_AUTH_TOKEN"),
+			From:               os.Getenv("TWILIO_FROM"),
+			DefaultCountryCode: cfg.SMSDefaultCountryCode,
+		}
+		if cfg.Twilio.AccountSID == "" || cfg.Twilio.AuthToken == "" || cfg.Twilio.From == "" {
+			return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM are required when SMS_PROVIDER is twilio")
+		}
+	case "msg91":
+		cfg.MSG91 = sms.MSG91Config{
+			AuthKey:            getSecret("MSG91_AUTH_KEY"),
+			SenderID:           os.Getenv("MSG91_SENDER_ID"),
+			DLTTemplateID:      os.Getenv("MSG91_DLT_TEMPLATE_ID"),
+			Route:              os.Getenv("MSG91_ROUTE"),
+			DefaultCountryCode: cfg.SMSDefaultCountryCode,
+		}
+		if cfg.MSG91.AuthKey == "" || cfg.MSG91.SenderID == "" {
+			return nil, fmt.Errorf("MSG91_AUTH_KEY and MSG91_SENDER_ID are required when SMS_PROVIDER is msg91")
+		}
+	case "":
+		if cfg.Environment == "production" {
+			return nil, fmt.Errorf("SMS_PROVIDER is required in production")
+		}
+	default:
+		return nil, fmt.Errorf("SMS_PROVIDER must be twilio or msg91, got %q", cfg.SMSProvider)
+	}
+
+	// Masked calls - optional; without a provider calls are only logged
+	cfg.CallProvider = strings.ToLower(os.Getenv("CALL_PROVIDER"))
+	cfg.CallTimeoutMs = getEnvInt("CALL_TIMEOUT_MS", 10000)
+	cfg.CallMaxMinutes = getEnvInt("CALL_MAX_MINUTES", 10)
+	cfg.CallAfterDeliveryMinutes = getEnvInt("CALL_AFTER_DELIVERY_MINUTES", 0)
+	cfg.CallStatusCallbackURL = strings.TrimRight(os.Getenv("CALL_STATUS_CALLBACK_URL"), "/")
+	cfg.CallCallbackSecret = getSecret("CALL_CALLBACK_SECRET")
+	if cfg.CallMaxMinutes < 1 {
+		return nil, fmt.Errorf("CALL_MAX_MINUTES must be at least 1")
+	}
+	if cfg.CallStatusCallbackURL != "" && cfg.CallCallbackSecret == "" {
+		return nil, fmt.Errorf("CALL_CALLBACK_SECRET is required when CALL_STATUS_CALLBACK_URL is set")
+	}
+	switch cfg.CallProvider {
+	case "exotel":
+		cfg.Exotel = calls.ExotelConfig{
+			AccountSID:         os.Getenv("EXOTEL_ACCOUNT_SID"),
+			APIKey:             os.Getenv("EXOTEL_API_KEY"),
+			APIToken:           getSecret("EXOTEL_API_TOKEN"),
+			CallerID:           os.Getenv("EXOTEL_CALLER_ID"),
+			DefaultCountryCode: cfg.SMSDefaultCountryCode,
+			BaseURL:            os.Getenv("EXOTEL_BASE_URL"),
+		}
+		if cfg.Exotel.AccountSID == "" || cfg.Exotel.APIKey == "" || cfg.Exotel.APIToken == "" || cfg.Exotel.CallerID == "" {
+			return nil, fmt.Errorf("EXOTEL_ACCOUNT_SID, EXOTEL_API_KEY, EXOTEL_API_TOKEN and EXOTEL_CALLER_ID are required when CALL_PROVIDER is exotel")
+		}
+	case "twilio":
+		cfg.TwilioVoice = calls.TwilioConfig{
+			AccountSID:         os.Getenv("TWILIO_ACCOUNT_SID"),
+			AuthToken:          getSecret("TWILIO_AUTH_TOKEN"),
+			CallerID:           os.Getenv("TWILIO_CALLER_ID"),
+			DefaultCountryCode: cfg.SMSDefaultCountryCode,
+		}
+		if cfg.TwilioVoice.AccountSID == "" || cfg.TwilioVoice.AuthToken == "" || cfg.TwilioVoice.CallerID == "" {
+			return nil, fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_CALLER_ID are required when CALL_PROVIDER is twilio")
+		}
+	case "":
+	default:
+		return nil, fmt.Errorf("CALL_PROVIDER must be exotel or twilio, got %q", cfg.CallProvider)
+	}
+
+	// Error reporting - optional
+	cfg.SentryDSN = getSecret("SENTRY_DSN")
+	cfg.ErrorReportSampleRate = getEnvFloat("ERROR_REPORT_SAMPLE_RATE", 1)
+	if cfg.ErrorReportSampleRate < 0 || cfg.ErrorReportSampleRate > 1 {
+		return nil, fmt.Errorf("ERROR_REPORT_SAMPLE_RATE must be between 0 and 1")
+	}
+
+	// Retry policies - RETRY_<INTEGRATION>_* overrides the built-in defaults
+	cfg.Retry = make(map[string]RetryConfig, len(defaultRetry))
+	for name, def := range defaultRetry {
+		cfg.Retry[name] = loadRetryConfig(name, def)
+	}
+
+	// Send rate limits - THROTTLE_<PROVIDER>_* overrides the built-in defaults
+	cfg.Throttle = make(map[string]throttle.Config, len(defaultThrottle))
+	for name, def := range defaultThrottle {
+		cfg.Throttle[name] = loadThrottleConfig(name, def)
+	}
+
+	// Item shortages - unanswered ones are accepted (partial refund) after this
+	cfg.ItemShortageResponseMinutes = getEnvInt("ITEM_SHORTAGE_RESPONSE_MINUTES", 10)
+
+	// Surveys - ask a while after delivery; scores at or below the threshold alert admins
+	cfg.SurveyDelayMinutes = getEnvInt("SURVEY_DELAY_MINUTES", 30)
+	cfg.SurveyAlertThreshold = getEnvInt("SURVEY_ALERT_THRESHOLD", 3)
+
+	// Churn - a regular who goes quiet is flagged for win-back
+	cfg.ChurnMinOrders = getEnvInt("CHURN_MIN_ORDERS", 3)
+	cfg.ChurnQuietDays = getEnvInt("CHURN_QUIET_DAYS", 30)
+
+	// Order issues - bounded refunds for missing items and cold food, the rest goes to staff
+	cfg.IssueWindowHours = getEnvInt("ISSUE_WINDOW_HOURS", 24)
+	cfg.IssueMaxAutoRefund = int64(getEnvInt("ISSUE_MAX_AUTO_REFUND", 30000))
+	cfg.IssueColdFoodPercent = getEnvInt("ISSUE_COLD_FOOD_PERCENT", 20)
+	cfg.IssueAutoLimit = getEnvInt("ISSUE_AUTO_RESOLVE_LIMIT", 2)
+
+	// Admin alerts - critical alerts always go out immediately
+	cfg.AdminAlertDigests = getEnv("ADMIN_ALERT_DIGESTS", "survey.low_score=hourly,inventory.low_stock=immediate,inventory.sold_out=immediate")
+	cfg.AdminAlertDigestHour = getEnvInt("ADMIN_ALERT_DIGEST_HOUR", 9)
+	if cfg.AdminAlertDigestHour < 0 || cfg.AdminAlertDigestHour > 23 {
+		return nil, fmt.Errorf("ADMIN_ALERT_DIGEST_HOUR must be between 0 and 23")
+	}
+	cfg.AdminAlertSlackURL = getEnv("ADMIN_ALERT_SLACK_WEBHOOK_URL", "")
+
+	// Stock alerts - low and sold out items alert admins; sold out items come off the menu
+	cfg.LowStockThreshold = getEnvInt("LOW_STOCK_THRESHOLD", 5)
+	cfg.DisableSoldOutItems = getEnvBool("DISABLE_SOLD_OUT_ITEMS", true)
+
+	// Cleanup - expired sessions are kept a while for login history
+	cfg.CleanupIntervalMinutes = getEnvInt("CLEANUP_INTERVAL_MINUTES", 60)
+	cfg.SessionRetentionDays = getEnvInt("SESSION_RETENTION_DAYS", 30)
+	cfg.ChatRetentionDays = getEnvInt("CHAT_RETENTION_DAYS", 90)
+	if cfg.CleanupIntervalMinutes < 1 {
+		return nil, fmt.Errorf("CLEANUP_INTERVAL_MINUTES must be at least 1")
+	}
+	if cfg.ChatRetentionDays < 1 {
+		return nil, fmt.Errorf("CHAT_RETENTION_DAYS must be at least 1")
+	}
+
+	// Order events - the outbox is relayed to a sink; without one events are
+	// only logged
+	cfg.OrderEventsSink = strings.ToLower(getEnv("ORDER_EVENTS_SINK", "log"))
+	cfg.OrderEventsIntervalMs = getEnvInt("ORDER_EVENTS_INTERVAL_MS", 1000)
+	cfg.OrderEventsTimeoutMs = getEnvInt("ORDER_EVENTS_TIMEOUT_MS", 5000)
+	cfg.OrderEventsRetentionDays = getEnvInt("ORDER_EVENTS_RETENTION_DAYS", 7)
+	if cfg.OrderEventsIntervalMs < 100 {
+		return nil, fmt.Errorf("ORDER_EVENTS_INTERVAL_MS must be at least 100")
+	}
+	if cfg.OrderEventsRetentionDays < 1 {
+		return nil, fmt.Errorf("ORDER_EVENTS_RETENTION_DAYS must be at least 1")
+	}
+	switch cfg.OrderEventsSink {
+	case "log":
+	case "redis":
+		cfg.OrderEventsStream = getEnv("ORDER_EVENTS_STREAM", "app:events:orders")
+		cfg.OrderEventsStreamMaxLen = getEnvInt("ORDER_EVENTS_STREAM_MAXLEN", 100000)
+		if cfg.OrderEventsStreamMaxLen < 0 {
+			return nil, fmt.Errorf("ORDER_EVENTS_STREAM_MAXLEN must not be negative")
+		}
+	case "kafka":
+		cfg.Kafka = events.KafkaConfig{
+			RESTURL:  os.Getenv("KAFKA_REST_URL"),
+			Topic:    getEnv("ORDER_EVENTS_TOPIC", "order-events"),
+			Username: os.Getenv("KAFKA_REST_USERNAME"),
+			Password: getSecret("KAFKA_REST_PASSWORD"),
+		}
+		if cfg.Kafka.RESTURL == "" {
+			return nil, fmt.Errorf("KAFKA_REST_URL is required when ORDER_EVENTS_SINK is kafka")
+		}
+	default:
+		return nil, fmt.Errorf("ORDER_EVENTS_SINK must be log, redis or kafka, got %q", cfg.OrderEventsSink)
+	}
+
+	// Accounts - self-deactivated users can reactivate by logging in for this long
+	cfg.AccountReactivationDays = getEnvInt("ACCOUNT_REACTIVATION_DAYS", 30)
+
+	// Business time zone - an IANA name, since Postgres converts with it too
+	cfg.BusinessTimezone = getEnv("BUSINESS_TIMEZONE", "Asia/Kolkata")
+	if cfg.BusinessTimezone == "" || cfg.BusinessTimezone == "Local" {
+		return nil, fmt.Errorf("BUSINESS_TIMEZONE must be an IANA time zone name")
+	}
+	loc, err := time.LoadLocation(cfg.BusinessTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BUSINESS_TIMEZONE: %w", err)
+	}
+	cfg.Location = loc
+
+	// Messages fall back to this locale, then to English
+	cfg.RestaurantLocale = getEnv("RESTAURANT_LOCALE", "en")
+
+	// Delivery routing - routes start at the kitchen
+	cfg.KitchenLatitude = getEnvFloat("KITCHEN_LATITUDE", 0)
+	cfg.KitchenLongitude = getEnvFloat("KITCHEN_LONGITUDE", 0)
+	cfg.BatchRadiusMeters = getEnvInt("BATCH_RADIUS_METERS", 2000)
+	cfg.BatchMaxOrders = getEnvInt("BATCH_MAX_ORDERS", 3)
+	cfg.RiderSpeedKmph = getEnvFloat("RIDER_SPEED_KMPH", 20)
+	cfg.StopDwellMinutes = getEnvInt("STOP_DWELL_MINUTES", 4)
+
+	// Presence - apps heartbeat about every 30 seconds
+	cfg.PresenceTTLSeconds = getEnvInt("PRESENCE_TTL_SECONDS", 90)
+	cfg.KitchenOpenHours = getEnv("KITCHEN_OPEN_HOURS", "")
+
+	// Delivery fee - road distance from an OSRM-compatible router; straight-line
+	// estimate when ROUTING_URL is unset or the router is down
+	cfg.RoutingURL = getEnv("ROUTING_URL", "")
+	cfg.RoutingTimeoutMs = getEnvInt("ROUTING_TIMEOUT_MS", 2000)
+	cfg.DeliveryBaseFee = int64(getEnvInt("DELIVERY_BASE_FEE", 2000))
+	cfg.DeliveryBaseDistanceMeters = getEnvInt("DELIVERY_BASE_DISTANCE_METERS", 2000)
+	cfg.DeliveryPerKmFee = int64(getEnvInt("DELIVERY_PER_KM_FEE", 800))
+	cfg.DeliveryMaxDistanceMeters = getEnvInt("DELIVERY_MAX_DISTANCE_METERS", 10000)
+
+	// Checkout - price increases since the app priced the cart need consent above this
+	cfg.PriceConfirmThreshold = int64(getEnvInt("PRICE_CONFIRM_THRESHOLD", 0))
+
+	// Order charges - restaurant GST is 5% on food and packaging
+	cfg.PackagingCharge = int64(getEnvInt("PACKAGING_CHARGE", 0))
+	cfg.GSTRateBasisPoints = getEnvInt("GST_RATE_BASIS_POINTS", 500)
+	if cfg.PackagingCharge < 0 || cfg.GSTRateBasisPoints < 0 || cfg.GSTRateBasisPoints > 2800 {
+		return nil, fmt.Errorf("PACKAGING_CHARGE must not be negative and GST_RATE_BASIS_POINTS must be 0-2800")
+	}
+
+	// App versions - builds below the minimum are told to update
+	cfg.AppMinVersionAndroid = getEnv("APP_MIN_VERSION_ANDROID", "")
+	cfg.AppMinVersionIOS = getEnv("APP_MIN_VERSION_IOS", "")
+	cfg.AppUpdateURLAndroid = getEnv("APP_UPDATE_URL_ANDROID", "")
+	cfg.AppUpdateURLIOS = getEnv("APP_UPDATE_URL_IOS", "")
+
+	// Test orders - staff numbers whose orders stay out of analytics
+	cfg.TestOrderStaffPhones = getEnv("TEST_ORDER_STAFF_PHONES", "")
+
+	// ETA - weather adjustments are opt-in
+	cfg.KitchenPrepMinutes = getEnvInt("KITCHEN_PREP_MINUTES", 20)
+	cfg.KitchenSlotCapacity = getEnvInt("KITCHEN_SLOT_CAPACITY", 0)
+	cfg.WeatherEnabled = getEnvBool("WEATHER_ENABLED", false)
+	cfg.WeatherAPIURL = getEnv("WEATHER_API_URL", "https://api.open-meteo.com")
+	cfg.WeatherETARules = getEnv("WEATHER_ETA_RULES", "rain=10,heavy_rain=20,storm=30,snow=20")
+
+	// Delivery slots
+	cfg.DeliverySlotCapacity = getEnvInt("DELIVERY_SLOT_CAPACITY", 10)
+	cfg.DeliverySlotHoldMinutes = getEnvInt("DELIVERY_SLOT_HOLD_MINUTES", 15)
 
 	return cfg, nil
 }
@@ -94,3 +755,57 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// loadRetryConfig reads RETRY_<NAME>_MAX_ATTEMPTS, _INITIAL_BACKOFF_MS,
+// _MAX_BACKOFF_MS and _JITTER for an integration
+func loadRetryConfig(name string, def RetryConfig) RetryConfig {
+	prefix := "RETRY_" + strings.ToUpper(name) + "_"
+	return RetryConfig{
+		MaxAttempts:    getEnvInt(prefix+"MAX_ATTEMPTS", def.MaxAttempts),
+		InitialBackoff: time.Duration(getEnvInt(prefix+"INITIAL_BACKOFF_MS", int(def.InitialBackoff.Milliseconds()))) * time.Millisecond,
+		MaxBackoff:     time.Duration(getEnvInt(prefix+"MAX_BACKOFF_MS", int(def.MaxBackoff.Milliseconds()))) * time.Millisecond,
+		Jitter:         getEnvFloat(prefix+"JITTER", def.Jitter),
+	}
+}
+
+// loadThrottleConfig reads THROTTLE_<NAME>_RATE, _BURST and _QUEUE_SIZE for
+// a notification provider
+func loadThrottleConfig(name string, def throttle.Config) throttle.Config {
+	prefix := "THROTTLE_" + strings.ToUpper(name) + "_"
+	return throttle.Config{
+		Rate:      getEnvFloat(prefix+"RATE", def.Rate),
+		Burst:     getEnvInt(prefix+"BURST", def.Burst),
+		QueueSize: getEnvInt(prefix+"QUEUE_SIZE", def.QueueSize),
+	}
+}
+
+// getEnvFloat returns environment variable as float64 or default
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool returns environment variable as bool or default
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList returns a comma-separated environment variable as a slice
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fooddelivery/pkg/secrets"
+)
+
+// Secrets manager providers
+const (
+	SecretsProviderNone  = ""
+	SecretsProviderVault = "vault"
+	SecretsProviderAWS   = "aws"
+)
+
+// SecretsConfig selects where credentials come from. It is read from the
+// environment before everything else, since the rest of the config may
+// depend on it.
+type SecretsConfig struct {
+	Provider        string
+	RefreshInterval time.Duration // 0 disables refresh
+
+	// Required in production when a provider is set; otherwise failing to
+	// reach the secrets manager falls back to environment variables
+	Required bool
+
+	VaultAddr      string
+	VaultToken     string
+	VaultNamespace string
+	VaultPath      string // KV v2 API path, e.g. "secret/data/crave-delivery"
+
+	AWSRegion   string
+	AWSSecretID string
+}
+
+// LoadSecretsConfig reads the secrets manager settings from the environment
+func LoadSecretsConfig() (SecretsConfig, error) {
+	sc := SecretsConfig{
+		Provider:        getEnv("SECRETS_PROVIDER", SecretsProviderNone),
+		RefreshInterval: time.Duration(getEnvInt("SECRETS_REFRESH_MINUTES", 5)) * time.Minute,
+		Required:        getEnv("ENVIRONMENT", "development") == "production",
+		VaultAddr:       getEnv("VAULT_ADDR", "http://127.0.0.1:8200"),
+		VaultToken:      os.Getenv("VAULT_TOKEN"),
+		VaultNamespace:  os.Getenv("VAULT_NAMESPACE"),
+		VaultPath:       getEnv("VAULT_SECRET_PATH", "secret/data/crave-delivery"),
+		AWSRegion:       getEnv("AWS_REGION", "ap-south-1"),
+		AWSSecretID:     os.Getenv("AWS_SECRET_ID"),
+	}
+
+	switch sc.Provider {
+	case SecretsProviderNone:
+	case SecretsProviderVault:
+		if sc.VaultToken == "" {
+			return sc, fmt.Errorf("VAULT_TOKEN is required when SECRETS_PROVIDER is vault")
+		}
+	case SecretsProviderAWS:
+		if sc.AWSSecretID == "" || os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+			return sc, fmt.Errorf("AWS_SECRET_ID, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when SECRETS_PROVIDER is aws")
+		}
+	default:
+		return sc, fmt.Errorf("unknown SECRETS_PROVIDER %q (use vault or aws)", sc.Provider)
+	}
+
+	return sc, nil
+}
+
+// Source builds the configured secrets source, or nil when credentials come
+// from environment variables only
+func (sc SecretsConfig) Source() secrets.Source {
+	switch sc.Provider {
+	case SecretsProviderVault:
+		return secrets.NewVaultSource(sc.VaultAddr, sc.VaultToken, sc.VaultNamespace, sc.VaultPath, 5*time.Second)
+	case SecretsProviderAWS:
+		return secrets.NewAWSSource(sc.AWSRegion, sc.AWSSecretID, secrets.AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, 5*time.Second)
+	}
+	return nil
+}
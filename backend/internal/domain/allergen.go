@@ -0,0 +1,143 @@
+package domain
+
+import "sort"
+
+// Allergen is a food allergen declared on a menu item's ingredients
+type Allergen string
+
+const (
+	AllergenPeanuts   Allergen = "peanuts"
+	AllergenTreeNuts  Allergen = "tree_nuts"
+	AllergenShellfish Allergen = "shellfish"
+	AllergenFish      Allergen = "fish"
+	AllergenSesame    Allergen = "sesame"
+	AllergenGluten    Allergen = "gluten"
+	AllergenDairy     Allergen = "dairy"
+	AllergenEgg       Allergen = "egg"
+	AllergenSoy       Allergen = "soy"
+	AllergenMustard   Allergen = "mustard"
+)
+
+// AllergenRule is how an allergen is shown to customers. Severe allergens
+// can cause anaphylaxis, so checkout makes the customer acknowledge them.
+type AllergenRule struct {
+	Label  string // As in "Contains nuts"
+	Severe bool
+}
+
+// AllergenRules lists every allergen ingredients may declare
+var AllergenRules = map[Allergen]AllergenRule{
+	AllergenPeanuts:   {Label: "peanuts", Severe: true},
+	AllergenTreeNuts:  {Label: "nuts", Severe: true},
+	AllergenShellfish: {Label: "shellfish", Severe: true},
+	AllergenFish:      {Label: "fish", Severe: true},
+	AllergenSesame:    {Label: "sesame", Severe: true},
+	AllergenGluten:    {Label: "gluten"},
+	AllergenDairy:     {Label: "milk"},
+	AllergenEgg:       {Label: "egg"},
+	AllergenSoy:       {Label: "soy"},
+	AllergenMustard:   {Label: "mustard"},
+}
+
+// IsValid reports whether a is a known allergen
+func (a Allergen) IsValid() bool {
+	_, ok := AllergenRules[a]
+	return ok
+}
+
+// Ingredient is one ingredient of a menu item. MayContain lists allergens
+// it can pick up from shared kitchen equipment or the supplier's facility.
+type Ingredient struct {
+	Name       string     `json:"name"`
+	Allergens  []Allergen `json:"allergens,omitempty"`
+	MayContain []Allergen `json:"may_contain,omitempty"`
+}
+
+// AllergenPresence says whether an allergen is an ingredient or a
+// cross-contamination risk
+type AllergenPresence string
+
+const (
+	AllergenContains   AllergenPresence = "contains"
+	AllergenMayContain AllergenPresence = "may_contain"
+)
+
+// AllergenWarning is shown on a menu item or a cart, e.g. "Contains nuts".
+// Items names the cart items it comes from and is empty on menu items.
+type AllergenWarning struct {
+	Allergen Allergen         `json:"allergen"`
+	Presence AllergenPresence `json:"presence"`
+	Severe   bool             `json:"severe"`
+	Message  string           `json:"message"`
+	Items    []string         `json:"items,omitempty"`
+}
+
+// ComputeAllergenWarnings computes the item's warnings from its
+// ingredients. An allergen any ingredient contains is not also reported as
+// may contain.
+func (m *MenuItem) ComputeAllergenWarnings() []AllergenWarning {
+	presence := make(map[Allergen]AllergenPresence)
+	for _, ing := range m.Ingredients {
+		for _, a := range ing.MayContain {
+			if _, seen := presence[a]; !seen {
+				presence[a] = AllergenMayContain
+			}
+		}
+		for _, a := range ing.Allergens {
+			presence[a] = AllergenContains
+		}
+	}
+	return allergenWarnings(presence, nil)
+}
+
+// CartAllergenWarnings merges the warnings of a cart's items, naming the
+// items each comes from. An allergen one item contains and another may
+// contain is reported as contained.
+func CartAllergenWarnings(items []MenuItem) []AllergenWarning {
+	presence := make(map[Allergen]AllergenPresence)
+	sources := make(map[Allergen][]string)
+	for i := range items {
+		for _, w := range items[i].ComputeAllergenWarnings() {
+			if presence[w.Allergen] != AllergenContains {
+				presence[w.Allergen] = w.Presence
+			}
+			sources[w.Allergen] = append(sources[w.Allergen], items[i].Name)
+		}
+	}
+	return allergenWarnings(presence, sources)
+}
+
+// allergenWarnings applies AllergenRules to the allergens found, severe
+// ones first. Allergens without a rule are ignored.
+func allergenWarnings(presence map[Allergen]AllergenPresence, sources map[Allergen][]string) []AllergenWarning {
+	warnings := make([]AllergenWarning, 0, len(presence))
+	for a, p := range presence {
+		rule, ok := AllergenRules[a]
+		if !ok {
+			continue
+		}
+		message := "Contains " + rule.Label
+		if p == AllergenMayContain {
+			message = "May contain " + rule.Label
+		}
+		warnings = append(warnings, AllergenWarning{
+			Allergen: a,
+			Presence: p,
+			Severe:   rule.Severe,
+			Message:  message,
+			Items:    sources[a],
+		})
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		a, b := warnings[i], warnings[j]
+		if a.Severe != b.Severe {
+			return a.Severe
+		}
+		if a.Presence != b.Presence {
+			return a.Presence == AllergenContains
+		}
+		return a.Allergen < b.Allergen
+	})
+	return warnings
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnnouncementAudience is who an in-app banner is shown to
+type AnnouncementAudience string
+
+const (
+	AudienceAll                AnnouncementAudience = "ALL"
+	AudienceGuests             AnnouncementAudience = "GUESTS"              // Not signed in
+	AudienceNewCustomers       AnnouncementAudience = "NEW_CUSTOMERS"       // Signed in, never paid for an order
+	AudienceReturningCustomers AnnouncementAudience = "RETURNING_CUSTOMERS" // Paid for an order and not lapsed
+	AudienceLapsedCustomers    AnnouncementAudience = "LAPSED_CUSTOMERS"    // Regulars flagged as a churn risk
+)
+
+// IsValid reports whether a is a known audience
+func (a AnnouncementAudience) IsValid() bool {
+	switch a {
+	case AudienceAll, AudienceGuests, AudienceNewCustomers, AudienceReturningCustomers, AudienceLapsedCustomers:
+		return true
+	}
+	return false
+}
+
+// Announcement is an in-app banner
+type Announcement struct {
+	ID        uuid.UUID            `json:"id"`
+	Title     string               `json:"title"`
+	Body      string               `json:"body,omitempty"`
+	ImageURL  string               `json:"image_url,omitempty"`
+	DeepLink  string               `json:"deep_link,omitempty"` // Opened on tap
+	Audience  AnnouncementAudience `json:"audience"`
+	Priority  int                  `json:"priority"` // Higher shows first
+	StartsAt  time.Time            `json:"starts_at"`
+	EndsAt    *time.Time           `json:"ends_at,omitempty"`
+	IsActive  bool                 `json:"is_active"`
+	CreatedBy *uuid.UUID           `json:"created_by,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// LiveAt reports whether the banner is shown at t
+func (a *Announcement) LiveAt(t time.Time) bool {
+	return a.IsActive && !t.Before(a.StartsAt) && (a.EndsAt == nil || t.Before(*a.EndsAt))
+}
+
+// AnnouncementWithStats is a banner with how it performed, for admins
+type AnnouncementWithStats struct {
+	Announcement
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	ClickRate   float64 `json:"click_rate"` // Clicks per impression
+}
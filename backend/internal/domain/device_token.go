@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DevicePlatform is the app a push token was issued to
+type DevicePlatform string
+
+const (
+	DevicePlatformAndroid DevicePlatform = "android"
+	DevicePlatformIOS     DevicePlatform = "ios"
+	DevicePlatformWeb     DevicePlatform = "web"
+)
+
+// IsValid reports whether p is a known platform
+func (p DevicePlatform) IsValid() bool {
+	return p == DevicePlatformAndroid || p == DevicePlatformIOS || p == DevicePlatformWeb
+}
+
+// DeviceToken is an app install's FCM registration token
+type DeviceToken struct {
+	Token      string         `json:"token"`
+	UserID     uuid.UUID      `json:"user_id"`
+	Platform   DevicePlatform `json:"platform"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastSeenAt time.Time      `json:"last_seen_at"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueuedEmail is an email owed to a user for an order, waiting in the
+// outbox for the email worker
+type QueuedEmail struct {
+	ID          int64     `json:"id"`
+	TemplateKey string    `json:"template_key"`
+	OrderID     uuid.UUID `json:"order_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Attempts    int       `json:"attempts"` // Including the one in progress
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MenuItemVariant is a size or portion of a menu item with its own price.
+// An item with available variants is ordered as one of them.
+type MenuItemVariant struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Price       int64     `json:"price"` // Unit price in paisa, replacing the item's
+	IsAvailable bool      `json:"is_available"`
+}
+
+// MenuItemModifier is an add-on chosen on top of a menu item, at most once
+// per line
+type MenuItemModifier struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Price       int64     `json:"price"` // Paisa added to the unit price; may be 0
+	IsAvailable bool      `json:"is_available"`
+}
+
+// OrderItemModifier is a modifier as it was named and priced when ordered
+type OrderItemModifier struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Price int64     `json:"price"` // Paisa
+}
+
+// HasVariants reports whether the item must be ordered as one of its
+// variants
+func (m *MenuItem) HasVariants() bool {
+	for _, v := range m.Variants {
+		if v.IsAvailable {
+			return true
+		}
+	}
+	return false
+}
+
+// Variant returns the item's variant with the given ID, or nil
+func (m *MenuItem) Variant(id uuid.UUID) *MenuItemVariant {
+	for i := range m.Variants {
+		if m.Variants[i].ID == id {
+			return &m.Variants[i]
+		}
+	}
+	return nil
+}
+
+// Modifier returns the item's modifier with the given ID, or nil
+func (m *MenuItem) Modifier(id uuid.UUID) *MenuItemModifier {
+	for i := range m.Modifiers {
+		if m.Modifiers[i].ID == id {
+			return &m.Modifiers[i]
+		}
+	}
+	return nil
+}
+
+// LineKey identifies a cart line: the same item with a different variant
+// or modifiers is a separate line. Modifier order doesn't matter.
+func (c CartItem) LineKey() string {
+	var sb strings.Builder
+	sb.WriteString(c.MenuItemID.String())
+	if c.VariantID != nil {
+		sb.WriteString("/" + c.VariantID.String())
+	}
+	if len(c.ModifierIDs) > 0 {
+		ids := make([]string, len(c.ModifierIDs))
+		for i, id := range c.ModifierIDs {
+			ids[i] = id.String()
+		}
+		sort.Strings(ids)
+		sb.WriteString("+" + strings.Join(ids, "+"))
+	}
+	return sb.String()
+}
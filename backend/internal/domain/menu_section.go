@@ -0,0 +1,37 @@
+package domain
+
+// MenuSectionKey names a curated section shown above the menu's categories
+type MenuSectionKey string
+
+const (
+	MenuSectionChefsSpecials MenuSectionKey = "CHEFS_SPECIALS"
+	MenuSectionBestsellers   MenuSectionKey = "BESTSELLERS"
+)
+
+// MenuSectionKeys lists the sections in the order the menu shows them
+var MenuSectionKeys = []MenuSectionKey{MenuSectionChefsSpecials, MenuSectionBestsellers}
+
+// IsValid reports whether k is a known section
+func (k MenuSectionKey) IsValid() bool {
+	return k == MenuSectionChefsSpecials || k == MenuSectionBestsellers
+}
+
+// Title is the section's heading in the app
+func (k MenuSectionKey) Title() string {
+	switch k {
+	case MenuSectionChefsSpecials:
+		return "Chef's specials"
+	case MenuSectionBestsellers:
+		return "Bestsellers"
+	}
+	return string(k)
+}
+
+// MenuSection is a curated row of menu items. Automatic sections were
+// filled from sales because nothing was pinned.
+type MenuSection struct {
+	Key       MenuSectionKey `json:"key"`
+	Title     string         `json:"title"`
+	Items     []MenuItem     `json:"items"`
+	Automatic bool           `json:"automatic"`
+}
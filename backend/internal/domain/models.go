@@ -3,35 +3,93 @@
 package domain
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 // OrderStatus represents the state machine for order lifecycle.
-// State transitions: PENDING -> AWAITING_PAYMENT -> PAID/PAYMENT_FAILED -> ACCEPTED -> DELIVERED
+// State transitions: PENDING -> AWAITING_PAYMENT -> PAID/PAYMENT_FAILED -> ACCEPTED -> OUT_FOR_DELIVERY -> DELIVERED
+// Pickup orders skip OUT_FOR_DELIVERY and are handed over at the counter.
+// PAID/ACCEPTED orders the customer cancels (after an item shortage) end
+// CANCELLED and are refunded.
 type OrderStatus string
 
 const (
-	OrderStatusPending        OrderStatus = "PENDING"
+	OrderStatusPending         OrderStatus = "PENDING"
 	OrderStatusAwaitingPayment OrderStatus = "AWAITING_PAYMENT"
-	OrderStatusPaymentFailed  OrderStatus = "PAYMENT_FAILED"
-	OrderStatusPaid           OrderStatus = "PAID"
-	OrderStatusAccepted       OrderStatus = "ACCEPTED"
-	OrderStatusDelivered      OrderStatus = "DELIVERED"
+	OrderStatusPaymentFailed   OrderStatus = "PAYMENT_FAILED"
+	OrderStatusPaid            OrderStatus = "PAID"
+	OrderStatusAccepted        OrderStatus = "ACCEPTED"
+	OrderStatusOutForDelivery  OrderStatus = "OUT_FOR_DELIVERY"
+	OrderStatusDelivered       OrderStatus = "DELIVERED"
+	OrderStatusCancelled       OrderStatus = "CANCELLED"
+)
+
+// IsFinal reports whether an order in status s will not change again
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusDelivered || s == OrderStatusCancelled
+}
+
+// FulfillmentType distinguishes doorstep delivery from counter pickup
+type FulfillmentType string
+
+const (
+	FulfillmentDelivery FulfillmentType = "DELIVERY"
+	FulfillmentPickup   FulfillmentType = "PICKUP"
+)
+
+// IsValid reports whether f is a known fulfillment type
+func (f FulfillmentType) IsValid() bool {
+	return f == FulfillmentDelivery || f == FulfillmentPickup
+}
+
+// DeliveryConfirmation records how a hand-over was confirmed
+type DeliveryConfirmation string
+
+const (
+	DeliveryConfirmedByOTP        DeliveryConfirmation = "OTP"
+	DeliveryConfirmedByPickupCode DeliveryConfirmation = "PICKUP_CODE"
+	DeliveryConfirmedByOverride   DeliveryConfirmation = "ADMIN_OVERRIDE"
 )
 
 // User represents a registered user in the system
 type User struct {
-	ID            uuid.UUID  `json:"id"`
-	PhoneNumber   string     `json:"phone_number"`
-	Name          string     `json:"name"`
-	Email         string     `json:"email"`
-	PasswordHash  string     `json:"-"` // Never expose password hash in JSON
-	EmailVerified bool       `json:"email_verified"`
-	IsAdmin       bool       `json:"is_admin"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	PhoneNumber   string    `json:"phone_number"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	PasswordHash  string    `json:"-"` // Never expose password hash in JSON
+	EmailVerified bool      `json:"email_verified"`
+	IsAdmin       bool      `json:"is_admin"`
+	IsRider       bool      `json:"is_rider"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Set while the account is deactivated; its data is kept
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty"`
+	DeactivatedBy      *uuid.UUID `json:"deactivated_by,omitempty"` // Admin; nil when the user deactivated it
+	DeactivationReason string     `json:"deactivation_reason,omitempty"`
+
+	// Language for SMS, push and email templates
+	Locale         string `json:"locale,omitempty"`          // Chosen by the user
+	DetectedLocale string `json:"detected_locale,omitempty"` // From the app's Accept-Language at login
+}
+
+// IsDeactivated reports whether the account is deactivated
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
+}
+
+// PreferredLocale is the locale to message the user in: their own choice,
+// else the one detected from their app, else empty
+func (u *User) PreferredLocale() string {
+	if u.Locale != "" {
+		return u.Locale
+	}
+	return u.DetectedLocale
 }
 
 // OTPPurpose represents the purpose of an OTP
@@ -42,21 +100,22 @@ const (
 	OTPPurposeSignup        OTPPurpose = "signup"
 	OTPPurposePasswordReset OTPPurpose = "password_reset"
 	OTPPurposeEmailVerify   OTPPurpose = "email_verify"
+	OTPPurposeReactivate    OTPPurpose = "reactivate"
 )
 
 // OTP represents a one-time password for verification
 type OTP struct {
-	ID           uuid.UUID   `json:"id"`
-	UserID       *uuid.UUID  `json:"user_id,omitempty"`
-	PhoneNumber  *string     `json:"phone_number,omitempty"`
-	Email        *string     `json:"email,omitempty"`
-	OTPCode      string      `json:"-"` // Never expose OTP in JSON
-	Purpose      OTPPurpose  `json:"purpose"`
-	ExpiresAt    time.Time   `json:"expires_at"`
-	IsVerified   bool        `json:"is_verified"`
-	VerifiedAt   *time.Time  `json:"verified_at,omitempty"`
-	Attempts     int         `json:"attempts"`
-	CreatedAt    time.Time   `json:"created_at"`
+	ID          uuid.UUID  `json:"id"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	PhoneNumber *string    `json:"phone_number,omitempty"`
+	Email       *string    `json:"email,omitempty"`
+	OTPCode     string     `json:"-"` // Never expose OTP in JSON
+	Purpose     OTPPurpose `json:"purpose"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	IsVerified  bool       `json:"is_verified"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 // Session represents an active user session
@@ -74,6 +133,75 @@ type Session struct {
 	CreatedAt      time.Time  `json:"created_at"`
 }
 
+// UserTOTP holds a user's authenticator enrollment for two-factor auth
+type UserTOTP struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"` // Shared secret, never exposed after enrollment
+	IsEnabled    bool       `json:"is_enabled"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IdentityProvider identifies an external login provider
+type IdentityProvider string
+
+const (
+	IdentityProviderGoogle IdentityProvider = "google"
+)
+
+// UserIdentity links an external provider account to a local user
+type UserIdentity struct {
+	ID              uuid.UUID        `json:"id"`
+	UserID          uuid.UUID        `json:"user_id"`
+	Provider        IdentityProvider `json:"provider"`
+	ProviderSubject string           `json:"-"` // Provider-side user ID, internal only
+	Email           *string          `json:"email,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	LastLoginAt     time.Time        `json:"last_login_at"`
+}
+
+// APIKeyScope is a permission granted to a partner API key
+type APIKeyScope string
+
+const (
+	APIKeyScopeMenuRead   APIKeyScope = "menu:read"
+	APIKeyScopeOrdersRead APIKeyScope = "orders:read"
+)
+
+// ValidAPIKeyScopes lists all scopes that can be granted
+var ValidAPIKeyScopes = map[APIKeyScope]bool{
+	APIKeyScopeMenuRead:   true,
+	APIKeyScopeOrdersRead: true,
+}
+
+// APIKey represents a server-to-server partner credential
+type APIKey struct {
+	ID                 uuid.UUID     `json:"id"`
+	Name               string        `json:"name"`
+	KeyPrefix          string        `json:"key_prefix"`
+	KeyHash            string        `json:"-"` // Never expose the hash
+	Scopes             []APIKeyScope `json:"scopes"`
+	RateLimitPerMinute int           `json:"rate_limit_per_minute"`
+	IsActive           bool          `json:"is_active"`
+	CreatedBy          *uuid.UUID    `json:"created_by,omitempty"`
+	RotatedFrom        *uuid.UUID    `json:"rotated_from,omitempty"`
+	LastUsedAt         *time.Time    `json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time    `json:"revoked_at,omitempty"`
+	CreatedAt          time.Time     `json:"created_at"`
+}
+
+// HasScope reports whether the key grants the given scope
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // MenuItem represents a food item available for ordering.
 // Price is stored in paisa (1/100 of rupee) to avoid floating point errors.
 type MenuItem struct {
@@ -84,8 +212,29 @@ type MenuItem struct {
 	Category    string    `json:"category"`
 	ImageURL    string    `json:"image_url,omitempty"`
 	IsAvailable bool      `json:"is_available"`
+	Version     int       `json:"version"` // For optimistic locking
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Ingredients with their allergens, and the warnings computed from them
+	// for customers (filled when the menu is read)
+	Ingredients      []Ingredient      `json:"ingredients,omitempty"`
+	AllergenWarnings []AllergenWarning `json:"allergen_warnings,omitempty"`
+
+	// Units left to sell; nil when the item isn't stock-tracked. At 0 the
+	// item is sold out: still listed, unlike an unavailable item, but it
+	// can't be ordered until restocked.
+	StockQuantity *int `json:"stock_quantity,omitempty"`
+	SoldOut       bool `json:"sold_out"`
+
+	// Sizes and add-ons, in display order (filled when the menu is read)
+	Variants  []MenuItemVariant  `json:"variants,omitempty"`
+	Modifiers []MenuItemModifier `json:"modifiers,omitempty"`
+}
+
+// InStock reports whether quantity units can be ordered
+func (m *MenuItem) InStock(quantity int) bool {
+	return m.StockQuantity == nil || *m.StockQuantity >= quantity
 }
 
 // PriceInRupees returns the price formatted in rupees for display
@@ -104,8 +253,217 @@ type Order struct {
 	RazorpayPaymentID string      `json:"razorpay_payment_id,omitempty"`
 	Version           int         `json:"version"` // For optimistic locking
 	Items             []OrderItem `json:"items"`
-	CreatedAt         time.Time   `json:"created_at"`
-	UpdatedAt         time.Time   `json:"updated_at"`
+
+	// Where a delivery order goes; nil for pickup orders
+	DeliveryLocation *DeliveryLocation `json:"delivery_location,omitempty"`
+
+	// Copy of the saved address chosen at checkout, if one was
+	DeliveryAddress *Address `json:"delivery_address,omitempty"`
+
+	// Set when the order is delivered to someone other than the payer
+	Recipient *OrderRecipient `json:"recipient,omitempty"`
+
+	// How the customer wants it packed; shown to the kitchen
+	Preferences OrderPreferences `json:"preferences"`
+
+	// Severe allergens in the cart the customer acknowledged at checkout
+	AcknowledgedAllergens []Allergen `json:"acknowledged_allergens,omitempty"`
+
+	// Fee charged for delivery (included in TotalAmount) and the road
+	// distance from the kitchen it was priced on
+	DeliveryFee            int64 `json:"delivery_fee"` // Amount in paisa
+	DeliveryDistanceMeters *int  `json:"delivery_distance_meters,omitempty"`
+
+	// The rest of TotalAmount, in paisa: the items, the packaging charge
+	// and GST on both at the rate in force at checkout
+	Subtotal           int64 `json:"subtotal"`
+	PackagingCharge    int64 `json:"packaging_charge"`
+	TaxAmount          int64 `json:"tax_amount"`
+	TaxRateBasisPoints int   `json:"tax_rate_basis_points"` // 500 = 5%
+
+	// Promised arrival computed at checkout; the weather share is kept
+	// separately so analytics can tell kitchen/traffic delays from weather
+	EstimatedDeliveryAt  *time.Time `json:"estimated_delivery_at,omitempty"`
+	ETAAdjustmentMinutes int        `json:"eta_adjustment_minutes"`
+	ETAWeatherCondition  string     `json:"eta_weather_condition,omitempty"`
+
+	// Kitchen slot the order was booked into; scheduled orders were placed
+	// for a later slot than the one current at checkout
+	KitchenSlotAt *time.Time `json:"kitchen_slot_at,omitempty"`
+	IsScheduled   bool       `json:"is_scheduled"`
+
+	// Delivery slot (a 30-minute window) the customer chose, if any
+	DeliverySlotAt *time.Time `json:"delivery_slot_at,omitempty"`
+
+	// Pickup orders are handed over at the counter against a code only the
+	// customer can see, so the code is never serialized with the order
+	FulfillmentType  FulfillmentType `json:"fulfillment_type"`
+	PickupCode       string          `json:"-"`
+	PickupVerifiedAt *time.Time      `json:"pickup_verified_at,omitempty"`
+	PickupVerifiedBy *uuid.UUID      `json:"pickup_verified_by,omitempty"`
+
+	// Doorstep hand-over requires the OTP shown to the customer in-app;
+	// admins can override when the customer can't produce it
+	RiderID                *uuid.UUID           `json:"rider_id,omitempty"`
+	DispatchedAt           *time.Time           `json:"dispatched_at,omitempty"`
+	DeliveryOTP            string               `json:"-"`
+	DeliveryOTPAttempts    int                  `json:"delivery_otp_attempts,omitempty"`
+	DeliveredAt            *time.Time           `json:"delivered_at,omitempty"`
+	DeliveryConfirmation   DeliveryConfirmation `json:"delivery_confirmation,omitempty"`
+	DeliveryOverrideBy     *uuid.UUID           `json:"delivery_override_by,omitempty"`
+	DeliveryOverrideReason string               `json:"delivery_override_reason,omitempty"`
+	ProofOfDelivery        *DeliveryProof       `json:"proof_of_delivery,omitempty"`
+
+	// Set on orders brought over from the legacy platform. Imported orders
+	// are history only and are kept out of every operational flow.
+	ImportedAt    *time.Time `json:"imported_at,omitempty"`
+	ImportSource  string     `json:"import_source,omitempty"`
+	LegacyOrderID string     `json:"legacy_order_id,omitempty"`
+
+	// Set when the gateway captured a payment that doesn't match the order;
+	// the order stays unpaid until an admin reviews it
+	PaymentReview *PaymentReview `json:"payment_review,omitempty"`
+
+	// Set once the order is cancelled
+	Cancellation *OrderCancellation `json:"cancellation,omitempty"`
+
+	// Labels and custom attributes set by admins and automation rules;
+	// orders tagged OrderTagTest are left out of analytics
+	Tags       []string               `json:"tags,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrderTagTest marks an order placed for testing rather than by a customer
+const OrderTagTest = "test-order"
+
+// OrderAttributeType is the value type of a custom order attribute
+type OrderAttributeType string
+
+const (
+	OrderAttributeString  OrderAttributeType = "string"
+	OrderAttributeNumber  OrderAttributeType = "number"
+	OrderAttributeBoolean OrderAttributeType = "boolean"
+)
+
+// OrderAttributeDefinition declares a custom attribute that can be set on
+// orders. Its type is fixed once defined.
+type OrderAttributeDefinition struct {
+	Key         string             `json:"key"`
+	Type        OrderAttributeType `json:"type"`
+	Description string             `json:"description"`
+	CreatedBy   *uuid.UUID         `json:"created_by,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// DeliveryLocation is the drop-off point for a delivery order
+type DeliveryLocation struct {
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Address is a delivery address saved in a customer's address book
+type Address struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"-"`
+	Label        string    `json:"label"` // e.g. "Home", "Work"
+	Line1        string    `json:"line1"`
+	Line2        string    `json:"line2,omitempty"`
+	Landmark     string    `json:"landmark,omitempty"`
+	City         string    `json:"city,omitempty"`
+	PostalCode   string    `json:"postal_code,omitempty"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	Instructions string    `json:"instructions,omitempty"` // For the rider
+	IsDefault    bool      `json:"is_default"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Location returns the address as an order's drop-off point, written out
+// on one line for the rider
+func (a *Address) Location() *DeliveryLocation {
+	parts := []string{a.Line1}
+	for _, part := range []string{a.Line2, a.Landmark, a.City, a.PostalCode} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return &DeliveryLocation{
+		Address:   strings.Join(parts, ", "),
+		Latitude:  a.Latitude,
+		Longitude: a.Longitude,
+	}
+}
+
+// Packaging is how an order is packed
+type Packaging string
+
+const (
+	PackagingStandard    Packaging = "STANDARD"
+	PackagingMinimal     Packaging = "MINIMAL"      // No extra bags, napkins or sachets
+	PackagingPlasticFree Packaging = "PLASTIC_FREE" // Paper and compostable containers only
+)
+
+// IsValid reports whether p is a known packaging option
+func (p Packaging) IsValid() bool {
+	return p == PackagingStandard || p == PackagingMinimal || p == PackagingPlasticFree
+}
+
+// OrderPreferences are the customer's packing choices for an order
+type OrderPreferences struct {
+	NoCutlery bool      `json:"no_cutlery"`
+	Packaging Packaging `json:"packaging"`
+}
+
+// OrderRecipient is the person a gift order is delivered to. The rider
+// contacts them and delivery updates go to their phone.
+type OrderRecipient struct {
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// IsGift reports whether the order goes to someone other than the payer
+func (o *Order) IsGift() bool {
+	return o.Recipient != nil
+}
+
+// DeliveryBatch groups orders carried together by one rider, in visit order
+type DeliveryBatch struct {
+	ID                  uuid.UUID           `json:"id"`
+	RiderID             uuid.UUID           `json:"rider_id"`
+	TotalDistanceMeters int                 `json:"total_distance_meters"`
+	Stops               []DeliveryBatchStop `json:"stops"`
+	CreatedBy           *uuid.UUID          `json:"created_by,omitempty"`
+	CreatedAt           time.Time           `json:"created_at"`
+}
+
+// DeliveryBatchStop is one drop-off in a batch route
+type DeliveryBatchStop struct {
+	OrderID            uuid.UUID        `json:"order_id"`
+	Sequence           int              `json:"sequence"`
+	Location           DeliveryLocation `json:"location"`
+	LegDistanceMeters  int              `json:"leg_distance_meters"` // From the previous stop (or kitchen)
+	EstimatedArrivalAt time.Time        `json:"estimated_arrival_at"`
+	Status             OrderStatus      `json:"status"`
+}
+
+// DeliveryProof is the doorstep photo a rider uploads at hand-over.
+// The image itself lives in the storage service and is served through an
+// authenticated endpoint, never a public URL.
+type DeliveryProof struct {
+	ID          uuid.UUID  `json:"id"`
+	OrderID     uuid.UUID  `json:"order_id"`
+	RiderID     *uuid.UUID `json:"rider_id,omitempty"`
+	StorageKey  string     `json:"-"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int        `json:"size_bytes"`
+	PhotoURL    string     `json:"photo_url,omitempty"` // Set by the API layer
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 // TotalInRupees returns the total amount formatted in rupees
@@ -113,15 +471,361 @@ func (o *Order) TotalInRupees() float64 {
 	return float64(o.TotalAmount) / 100.0
 }
 
+// TaxOn returns the GST the order charged on amount, e.g. to refund it
+// with items that couldn't be made
+func (o *Order) TaxOn(amount int64) int64 {
+	return TaxAmount(amount, o.TaxRateBasisPoints)
+}
+
+// TaxAmount is rateBasisPoints (1/100 of a percent) of amount, rounded to
+// the nearest paisa
+func TaxAmount(amount int64, rateBasisPoints int) int64 {
+	return (amount*int64(rateBasisPoints) + 5000) / 10000
+}
+
+// IsImported reports whether the order came from a historical import
+func (o *Order) IsImported() bool {
+	return o.ImportedAt != nil
+}
+
+// PaymentEventSource identifies what drove a payment transition
+type PaymentEventSource string
+
+const (
+	PaymentSourceCheckout       PaymentEventSource = "CHECKOUT"
+	PaymentSourceWebhook        PaymentEventSource = "WEBHOOK"
+	PaymentSourceClientVerify   PaymentEventSource = "CLIENT_VERIFY"
+	PaymentSourceReconciliation PaymentEventSource = "RECONCILIATION"
+	PaymentSourceRefund         PaymentEventSource = "REFUND"
+)
+
+// PaymentEventOutcome records whether a transition attempt changed the order
+type PaymentEventOutcome string
+
+const (
+	PaymentOutcomeApplied  PaymentEventOutcome = "APPLIED"
+	PaymentOutcomeNoChange PaymentEventOutcome = "NO_CHANGE"
+	PaymentOutcomeRejected PaymentEventOutcome = "REJECTED"
+	PaymentOutcomeError    PaymentEventOutcome = "ERROR"
+)
+
+// PaymentEvent is one entry in an order's payment audit trail
+type PaymentEvent struct {
+	ID                uuid.UUID           `json:"id"`
+	OrderID           *uuid.UUID          `json:"order_id,omitempty"`
+	RazorpayOrderID   string              `json:"razorpay_order_id,omitempty"`
+	RazorpayPaymentID string              `json:"razorpay_payment_id,omitempty"`
+	Source            PaymentEventSource  `json:"source"`
+	EventType         string              `json:"event_type"`
+	PreviousStatus    *OrderStatus        `json:"previous_status,omitempty"`
+	NewStatus         *OrderStatus        `json:"new_status,omitempty"`
+	Outcome           PaymentEventOutcome `json:"outcome"`
+	Amount            *int64              `json:"amount,omitempty"` // Paisa
+	Detail            string              `json:"detail,omitempty"`
+	WebhookLogID      *uuid.UUID          `json:"webhook_log_id,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
+}
+
+// WebhookLog is one webhook as received, with how processing it went. A
+// failed one is replayed at NextAttemptAt until it succeeds or FailedAt is
+// set.
+type WebhookLog struct {
+	ID              uuid.UUID       `json:"id"`
+	Source          string          `json:"source"`
+	EventType       string          `json:"event_type"`
+	Payload         json.RawMessage `json:"payload"`
+	SignatureValid  bool            `json:"signature_valid"`
+	Processed       bool            `json:"processed"`
+	ProcessingError *string         `json:"processing_error,omitempty"`
+	OrderID         *uuid.UUID      `json:"order_id,omitempty"`
+	Attempts        int             `json:"attempts"`
+	NextAttemptAt   *time.Time      `json:"next_attempt_at,omitempty"`
+	FailedAt        *time.Time      `json:"failed_at,omitempty"` // Replays ran out
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       *time.Time      `json:"updated_at,omitempty"`
+}
+
+// WebhookLogStatus filters webhook logs by how processing went
+type WebhookLogStatus string
+
+const (
+	WebhookStatusProcessed   WebhookLogStatus = "processed"
+	WebhookStatusUnprocessed WebhookLogStatus = "unprocessed" // Any outcome but success
+	WebhookStatusRetrying    WebhookLogStatus = "retrying"    // Replay scheduled
+	WebhookStatusFailed      WebhookLogStatus = "failed"      // Replays ran out
+)
+
+// IsValid reports whether s is a known webhook log status
+func (s WebhookLogStatus) IsValid() bool {
+	switch s {
+	case WebhookStatusProcessed, WebhookStatusUnprocessed, WebhookStatusRetrying, WebhookStatusFailed:
+		return true
+	}
+	return false
+}
+
+// OrderEventType names an event in the order events outbox
+type OrderEventType string
+
+const (
+	OrderEventCreated       OrderEventType = "order.created"
+	OrderEventStatusChanged OrderEventType = "order.status_changed"
+)
+
+// OrderEvent is an order change waiting in the outbox to be published, or
+// already published. Payload is what the sink receives.
+type OrderEvent struct {
+	ID          int64           `json:"id"`
+	OrderID     uuid.UUID       `json:"order_id"`
+	Type        OrderEventType  `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty"`
+	Attempts    int             `json:"attempts"`
+	LastError   *string         `json:"last_error,omitempty"`
+}
+
+// RefundStatus tracks a refund through Razorpay
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "PENDING"   // Issued, or being issued; not yet settled
+	RefundProcessed RefundStatus = "PROCESSED" // Money is on its way back to the payer
+	RefundFailed    RefundStatus = "FAILED"    // Rejected or failed; refund by hand
+)
+
+// Refund returns all or part of an order's captured payment. InitiatedBy is
+// nil for automatic refunds.
+type Refund struct {
+	ID                uuid.UUID    `json:"id"`
+	OrderID           uuid.UUID    `json:"order_id"`
+	RazorpayPaymentID string       `json:"razorpay_payment_id"`
+	RazorpayRefundID  string       `json:"razorpay_refund_id,omitempty"`
+	Amount            int64        `json:"amount"` // Paisa
+	Status            RefundStatus `json:"status"`
+	Reason            string       `json:"reason"`
+	FailureReason     string       `json:"failure_reason,omitempty"`
+	InitiatedBy       *uuid.UUID   `json:"initiated_by,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+}
+
+// KitchenCapacityRule sets how many orders the kitchen takes per 15-minute
+// slot within a daily window (kitchen local time, "HH:MM", end exclusive)
+type KitchenCapacityRule struct {
+	ID            uuid.UUID  `json:"id"`
+	DayOfWeek     *int       `json:"day_of_week,omitempty"` // 0 = Sunday; nil = every day
+	StartTime     string     `json:"start_time"`
+	EndTime       string     `json:"end_time"`
+	OrdersPerSlot int        `json:"orders_per_slot"` // 0 closes the window
+	UpdatedBy     *uuid.UUID `json:"updated_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// OrderSurvey is the post-delivery NPS survey for an order
+type OrderSurvey struct {
+	ID                  uuid.UUID  `json:"id"`
+	OrderID             uuid.UUID  `json:"order_id"`
+	UserID              uuid.UUID  `json:"user_id"`
+	SendAfter           time.Time  `json:"send_after"`
+	SentAt              *time.Time `json:"sent_at,omitempty"`
+	Score               *int       `json:"score,omitempty"` // 0-10
+	Comment             string     `json:"comment,omitempty"`
+	RespondedAt         *time.Time `json:"responded_at,omitempty"`
+	AlertAcknowledgedAt *time.Time `json:"alert_acknowledged_at,omitempty"`
+	AlertAcknowledgedBy *uuid.UUID `json:"alert_acknowledged_by,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// NPSSummary is the Net Promoter Score over a window of responses.
+// Promoters score 9-10, passives 7-8, detractors 0-6.
+type NPSSummary struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Responses  int       `json:"responses"`
+	Promoters  int       `json:"promoters"`
+	Passives   int       `json:"passives"`
+	Detractors int       `json:"detractors"`
+	Score      float64   `json:"score"` // -100 to 100
+}
+
+// SettingType is the value type of a runtime setting
+type SettingType string
+
+const (
+	SettingTypeInt    SettingType = "int"
+	SettingTypeFloat  SettingType = "float"
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeString SettingType = "string"
+)
+
+// Setting is a runtime-editable knob. Value is the admin override when one
+// exists, otherwise the default taken from the environment.
+type Setting struct {
+	Key          string          `json:"key"`
+	Type         SettingType     `json:"type"`
+	Description  string          `json:"description"`
+	Value        json.RawMessage `json:"value"`
+	Default      json.RawMessage `json:"default"`
+	Min          *float64        `json:"min,omitempty"`
+	Max          *float64        `json:"max,omitempty"`
+	IsOverridden bool            `json:"is_overridden"`
+	UpdatedBy    *uuid.UUID      `json:"updated_by,omitempty"`
+	UpdatedAt    *time.Time      `json:"updated_at,omitempty"`
+}
+
+// SettingChange is one entry in a setting's history. A nil OldValue means
+// an override was created; a nil NewValue means it was reset to default.
+type SettingChange struct {
+	ID        uuid.UUID       `json:"id"`
+	Key       string          `json:"key"`
+	OldValue  json.RawMessage `json:"old_value"`
+	NewValue  json.RawMessage `json:"new_value"`
+	ChangedBy *uuid.UUID      `json:"changed_by,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// TemplateChannel is how a templated message is delivered
+type TemplateChannel string
+
+const (
+	TemplateChannelSMS     TemplateChannel = "sms"
+	TemplateChannelPush    TemplateChannel = "push"
+	TemplateChannelEmail   TemplateChannel = "email"
+	TemplateChannelInvoice TemplateChannel = "invoice"
+)
+
+// MessageTemplate is the text of one message in one locale. Subject is the
+// push title or email subject and is empty for SMS. IsDefault marks the
+// template embedded in the binary, as opposed to an admin override.
+type MessageTemplate struct {
+	Key       string          `json:"key"`
+	Channel   TemplateChannel `json:"channel"`
+	Locale    string          `json:"locale"`
+	Subject   string          `json:"subject,omitempty"`
+	Body      string          `json:"body"`
+	IsDefault bool            `json:"is_default"`
+	UpdatedBy *uuid.UUID      `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+// OrderCancellation records who cancelled an order and why. CancelledBy is
+// nil when the system cancelled it.
+type OrderCancellation struct {
+	CancelledAt time.Time  `json:"cancelled_at"`
+	CancelledBy *uuid.UUID `json:"cancelled_by,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+// PaymentReview flags an order whose captured payment didn't match it
+type PaymentReview struct {
+	Reason     string     `json:"reason"`
+	FlaggedAt  time.Time  `json:"flagged_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *uuid.UUID `json:"resolved_by,omitempty"`
+}
+
+// AuditAction names an admin action in the audit log
+type AuditAction string
+
+const (
+	AuditMenuItemCreated       AuditAction = "menu_item.created"
+	AuditMenuItemUpdated       AuditAction = "menu_item.updated"
+	AuditMenuItemDeleted       AuditAction = "menu_item.deleted"
+	AuditOrderStatusChanged    AuditAction = "order.status_changed"
+	AuditOrderDeliveryOverride AuditAction = "order.delivery_overridden"
+	AuditSettingUpdated        AuditAction = "setting.updated"
+	AuditSettingReset          AuditAction = "setting.reset"
+	AuditCapacityRulesReplaced AuditAction = "capacity.rules_replaced"
+	AuditTemplateUpdated       AuditAction = "template.updated"
+	AuditTemplateReset         AuditAction = "template.reset"
+	AuditUserDeactivated       AuditAction = "user.deactivated"
+	AuditUserReactivated       AuditAction = "user.reactivated"
+	AuditPaymentReviewResolved AuditAction = "order.payment_review_resolved"
+	AuditItemShortageReported  AuditAction = "order.items_unavailable"
+	AuditOrderTagsUpdated      AuditAction = "order.tags_updated"
+	AuditOrderAttributesSet    AuditAction = "order.attributes_updated"
+	AuditOrderAttributeDefined AuditAction = "order_attribute.defined"
+	AuditOrderCancelled        AuditAction = "order.cancelled"
+	AuditRefundInitiated       AuditAction = "order.refunded"
+	AuditMenuSectionUpdated    AuditAction = "menu_section.updated"
+	AuditMenuReordered         AuditAction = "menu.reordered"
+	AuditMenuAvailability      AuditAction = "menu.availability_updated"
+	AuditMenuStockUpdated      AuditAction = "menu_item.stock_updated"
+	AuditMenuStockThreshold    AuditAction = "menu_item.stock_threshold_updated"
+	AuditMenuOptionsUpdated    AuditAction = "menu_item.options_updated"
+	AuditWebhookReplayed       AuditAction = "webhook.replayed"
+	AuditAnnouncementCreated   AuditAction = "announcement.created"
+	AuditAnnouncementUpdated   AuditAction = "announcement.updated"
+	AuditAnnouncementDeleted   AuditAction = "announcement.deleted"
+	AuditOrderIssueResolved    AuditAction = "order.issue_resolved"
+	AuditPrepStationCreated    AuditAction = "prep_station.created"
+	AuditPrepStationUpdated    AuditAction = "prep_station.updated"
+	AuditPrepStationDeleted    AuditAction = "prep_station.deleted"
+)
+
+// AuditEntry is one admin action. EntityID is a UUID or, for settings, the
+// setting key.
+type AuditEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	ActorID    *uuid.UUID      `json:"actor_id,omitempty"`
+	ActorName  string          `json:"actor_name,omitempty"`
+	Action     AuditAction     `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// MenuPriceChange is one entry in a menu item's price timeline. A nil
+// OldPrice marks the price the item was created with.
+type MenuPriceChange struct {
+	ID         uuid.UUID  `json:"id"`
+	MenuItemID uuid.UUID  `json:"menu_item_id"`
+	OldPrice   *int64     `json:"old_price"`
+	NewPrice   int64      `json:"new_price"`
+	ChangedBy  *uuid.UUID `json:"changed_by,omitempty"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+// MenuSnapshot is the menu as it stood at a point in time
+type MenuSnapshot struct {
+	AsOf  time.Time      `json:"as_of"`
+	Items []MenuItemAsOf `json:"items"`
+}
+
+// MenuItemAsOf is a menu item as it stood at a point in time.
+// DetailsApproximate is set when no change history reaches back that far:
+// the price is still historical, but the other fields are current values.
+type MenuItemAsOf struct {
+	ID                 uuid.UUID `json:"id"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	Price              int64     `json:"price"` // Price in paisa
+	PriceSince         time.Time `json:"price_since"`
+	Category           string    `json:"category"`
+	ImageURL           string    `json:"image_url,omitempty"`
+	IsAvailable        bool      `json:"is_available"`
+	DetailsApproximate bool      `json:"details_approximate,omitempty"`
+}
+
 // OrderItem represents a line item in an order
 type OrderItem struct {
 	ID         uuid.UUID `json:"id"`
 	OrderID    uuid.UUID `json:"order_id"`
-	MenuItemID uuid.UUID `json:"menu_item_id"`
+	MenuItemID uuid.UUID `json:"menu_item_id"` // uuid.Nil for unmatched imported items
 	Name       string    `json:"name"`
-	Price      int64     `json:"price"`    // Price at time of order (in paisa)
+	Price      int64     `json:"price"` // Price at time of order (in paisa)
 	Quantity   int       `json:"quantity"`
+	Category   string    `json:"category,omitempty"`  // Menu category at time of order
+	ImageURL   string    `json:"image_url,omitempty"` // Menu image at time of order
 	CreatedAt  time.Time `json:"created_at"`
+
+	// The variant and modifiers chosen, as named and priced at checkout.
+	// Price already includes them.
+	VariantID   *uuid.UUID          `json:"variant_id,omitempty"`
+	VariantName string              `json:"variant_name,omitempty"`
+	Modifiers   []OrderItemModifier `json:"modifiers,omitempty"`
 }
 
 // Subtotal returns the line item subtotal in paisa
@@ -129,14 +833,89 @@ func (oi *OrderItem) Subtotal() int64 {
 	return oi.Price * int64(oi.Quantity)
 }
 
+// ItemShortageStatus tracks the customer's answer to an item shortage
+type ItemShortageStatus string
+
+const (
+	ItemShortagePending   ItemShortageStatus = "PENDING"
+	ItemShortageAccepted  ItemShortageStatus = "ACCEPTED"  // Refund for the missing items, rest of the order goes ahead
+	ItemShortageCancelled ItemShortageStatus = "CANCELLED" // Order cancelled and refunded in full
+)
+
+// ItemShortage is a set of items the kitchen ran out of after an order was
+// paid for. ResolvedBy is nil when it was resolved automatically.
+type ItemShortage struct {
+	ID               uuid.UUID          `json:"id"`
+	OrderID          uuid.UUID          `json:"order_id"`
+	Status           ItemShortageStatus `json:"status"`
+	Items            []ShortItem        `json:"items"`
+	RefundAmount     int64              `json:"refund_amount"` // Paisa
+	Note             string             `json:"note,omitempty"`
+	ReportedBy       *uuid.UUID         `json:"reported_by,omitempty"`
+	RespondBy        time.Time          `json:"respond_by"`
+	ResolvedAt       *time.Time         `json:"resolved_at,omitempty"`
+	ResolvedBy       *uuid.UUID         `json:"resolved_by,omitempty"`
+	RazorpayRefundID string             `json:"razorpay_refund_id,omitempty"`
+	RefundError      string             `json:"refund_error,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+}
+
+// ShortItem is the missing quantity of one order line and its refund
+type ShortItem struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Name        string    `json:"name"`
+	Quantity    int       `json:"quantity"`
+	Amount      int64     `json:"amount"` // Paisa
+}
+
+// ChatRole is who sent an order chat message
+type ChatRole string
+
+const (
+	ChatRoleCustomer ChatRole = "CUSTOMER"
+	ChatRoleRider    ChatRole = "RIDER"
+	ChatRoleSupport  ChatRole = "SUPPORT"
+)
+
+// OrderMessage is one message in an order's chat. The sender is shown by
+// role only; phone numbers in the body are masked before it is stored.
+type OrderMessage struct {
+	ID         uuid.UUID `json:"id"`
+	OrderID    uuid.UUID `json:"order_id"`
+	SenderID   uuid.UUID `json:"-"`
+	SenderRole ChatRole  `json:"sender_role"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OrderCall is a masked call a rider placed to the customer about an
+// order. Status is one of the calls package's statuses.
+type OrderCall struct {
+	ID              uuid.UUID  `json:"id"`
+	OrderID         uuid.UUID  `json:"order_id"`
+	RiderID         uuid.UUID  `json:"rider_id"`
+	Provider        string     `json:"provider"`
+	ProviderCallID  string     `json:"provider_call_id,omitempty"`
+	Status          string     `json:"status"`
+	DurationSeconds int        `json:"duration_seconds"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+}
+
 // CartItem represents an item in the user's cart (before order creation)
 type CartItem struct {
-	MenuItemID uuid.UUID `json:"menu_item_id"`
-	Quantity   int       `json:"quantity"`
+	MenuItemID     uuid.UUID `json:"menu_item_id"`
+	Quantity       int       `json:"quantity"`
+	DisplayedPrice *int64    `json:"displayed_price,omitempty"` // Unit price the app showed, checked at checkout
+
+	// The variant, required when the item has any, and add-ons chosen
+	VariantID   *uuid.UUID  `json:"variant_id,omitempty"`
+	ModifierIDs []uuid.UUID `json:"modifier_ids,omitempty"`
 }
 
 // Cart represents the user's shopping cart
 type Cart struct {
 	UserID uuid.UUID  `json:"user_id"`
 	Items  []CartItem `json:"items"`
-}
\ No newline at end of file
+}
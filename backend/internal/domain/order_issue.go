@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderIssueType is a problem a customer reports with a delivered order
+type OrderIssueType string
+
+const (
+	IssueMissingItems OrderIssueType = "MISSING_ITEMS" // Items paid for but not in the bag
+	IssueWrongItems   OrderIssueType = "WRONG_ITEMS"   // Something else came instead
+	IssueColdFood     OrderIssueType = "COLD_FOOD"
+	IssueOther        OrderIssueType = "OTHER" // Always goes to staff
+)
+
+// IsValid reports whether t is a known issue type
+func (t OrderIssueType) IsValid() bool {
+	switch t {
+	case IssueMissingItems, IssueWrongItems, IssueColdFood, IssueOther:
+		return true
+	}
+	return false
+}
+
+// NeedsItems reports whether the customer has to say which items the issue
+// is about
+func (t OrderIssueType) NeedsItems() bool {
+	return t == IssueMissingItems || t == IssueWrongItems
+}
+
+// OrderIssueStatus tracks an issue from report to resolution
+type OrderIssueStatus string
+
+const (
+	IssueAutoResolved OrderIssueStatus = "AUTO_RESOLVED" // Remedy applied by the rules
+	IssueEscalated    OrderIssueStatus = "ESCALATED"     // Waiting for staff
+	IssueResolved     OrderIssueStatus = "RESOLVED"      // Closed by staff
+)
+
+// IsValid reports whether s is a known issue status
+func (s OrderIssueStatus) IsValid() bool {
+	switch s {
+	case IssueAutoResolved, IssueEscalated, IssueResolved:
+		return true
+	}
+	return false
+}
+
+// OrderIssue is a problem reported with a delivered order and how it was
+// made good. RemedyAmount is what the compensation rules work out;
+// RefundAmount is what was actually refunded.
+type OrderIssue struct {
+	ID               uuid.UUID        `json:"id"`
+	OrderID          uuid.UUID        `json:"order_id"`
+	UserID           uuid.UUID        `json:"user_id"`
+	Type             OrderIssueType   `json:"type"`
+	Status           OrderIssueStatus `json:"status"`
+	Items            []ShortItem      `json:"items"`
+	Description      string           `json:"description,omitempty"`
+	RemedyAmount     int64            `json:"remedy_amount"` // Paisa
+	RefundAmount     int64            `json:"refund_amount"` // Paisa
+	RazorpayRefundID string           `json:"razorpay_refund_id,omitempty"`
+	RefundError      string           `json:"refund_error,omitempty"`
+	EscalationReason string           `json:"escalation_reason,omitempty"`
+	ResolvedAt       *time.Time       `json:"resolved_at,omitempty"`
+	ResolvedBy       *uuid.UUID       `json:"resolved_by,omitempty"`
+	ResolutionNote   string           `json:"resolution_note,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
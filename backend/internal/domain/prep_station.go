@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PrepStation is a kitchen station (grill, fryer, beverages) that prepares
+// the menu categories mapped to it. The default station takes items in
+// categories no station claims.
+type PrepStation struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Categories []string  `json:"categories"`
+	IsDefault  bool      `json:"is_default"`
+	SortOrder  int       `json:"sort_order"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// StationTicketStatus tracks a station's part of an order
+type StationTicketStatus string
+
+const (
+	TicketQueued    StationTicketStatus = "QUEUED"
+	TicketPreparing StationTicketStatus = "PREPARING"
+	TicketReady     StationTicketStatus = "READY"
+)
+
+// StationItem is an order line as a station sees it
+type StationItem struct {
+	OrderItemID uuid.UUID           `json:"order_item_id"`
+	Name        string              `json:"name"`
+	VariantName string              `json:"variant_name,omitempty"`
+	Modifiers   []OrderItemModifier `json:"modifiers,omitempty"`
+	Quantity    int                 `json:"quantity"`
+}
+
+// StationTicket is the items of one accepted order a station prepares
+type StationTicket struct {
+	ID          uuid.UUID           `json:"id"`
+	OrderID     uuid.UUID           `json:"order_id"`
+	StationID   uuid.UUID           `json:"station_id"`
+	StationName string              `json:"station_name,omitempty"`
+	Status      StationTicketStatus `json:"status"`
+	Items       []StationItem       `json:"items"`
+	CreatedAt   time.Time           `json:"created_at"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	ReadyAt     *time.Time          `json:"ready_at,omitempty"`
+	ReadyBy     *uuid.UUID          `json:"ready_by,omitempty"`
+}
+
+// OrderReadiness is an accepted order's progress across stations. Ready
+// once every ticket is; ReadyAt is when the last one was.
+type OrderReadiness struct {
+	OrderID uuid.UUID       `json:"order_id"`
+	Tickets []StationTicket `json:"tickets"`
+	Ready   bool            `json:"ready"`
+	ReadyAt *time.Time      `json:"ready_at,omitempty"`
+}
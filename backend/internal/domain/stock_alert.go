@@ -0,0 +1,46 @@
+package domain
+
+import "github.com/google/uuid"
+
+// StockLevel is how a tracked item's stock compares with its low-stock
+// threshold
+type StockLevel string
+
+const (
+	StockLevelLow StockLevel = "LOW"
+	StockLevelOut StockLevel = "OUT"
+)
+
+// StockAlert is a change in an item's stock level found by the stock
+// check. Previous and Level are empty while stock is above the threshold.
+type StockAlert struct {
+	MenuItemID    uuid.UUID  `json:"menu_item_id"`
+	Name          string     `json:"name"`
+	Category      string     `json:"category"`
+	StockQuantity *int       `json:"stock_quantity"`
+	Threshold     int        `json:"low_stock_threshold"`
+	Previous      StockLevel `json:"previous_level,omitempty"`
+	Level         StockLevel `json:"level,omitempty"`
+	Disabled      bool       `json:"disabled"`  // Switched off because it sold out
+	Reenabled     bool       `json:"reenabled"` // Switched back on after a restock
+}
+
+// StockItem is a tracked item in the stock summary
+type StockItem struct {
+	MenuItemID    uuid.UUID  `json:"menu_item_id"`
+	Name          string     `json:"name"`
+	Category      string     `json:"category"`
+	StockQuantity int        `json:"stock_quantity"`
+	Threshold     int        `json:"low_stock_threshold"`
+	Level         StockLevel `json:"level,omitempty"`
+	Disabled      bool       `json:"disabled"` // Off the menu until restocked
+}
+
+// StockSummary counts the tracked items on the menu and lists the ones
+// at or below their threshold, lowest stock first
+type StockSummary struct {
+	Tracked int         `json:"tracked"`
+	Low     int         `json:"low"`
+	Out     int         `json:"out"`
+	Items   []StockItem `json:"items"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserOrderStats summarises a customer's paid orders. Cancelled and test
+// orders don't count, and TotalSpend is net of processed refunds.
+type UserOrderStats struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	OrderCount   int        `json:"order_count"`
+	TotalSpend   int64      `json:"total_spend"` // Paisa
+	FirstOrderAt *time.Time `json:"first_order_at,omitempty"`
+	LastOrderAt  *time.Time `json:"last_order_at,omitempty"`
+
+	// Derived when read
+	DaysSinceLastOrder *int `json:"days_since_last_order,omitempty"`
+	ChurnRisk          bool `json:"churn_risk"` // A regular customer who stopped ordering
+}
+
+// UserWithStats is a customer with their order stats, as admins see them
+type UserWithStats struct {
+	User  User           `json:"user"`
+	Stats UserOrderStats `json:"stats"`
+}
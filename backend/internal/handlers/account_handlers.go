@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// DeactivateAccountRequest optionally says why the user is leaving
+type DeactivateAccountRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DeactivateAccount handles POST /account/deactivate
+func (h *Handlers) DeactivateAccount(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeactivateAccountRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	if err := h.userUsecase.DeactivateAccount(c.UserContext(), userID, req.Reason); err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Account deactivation failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to deactivate account")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Account deactivated",
+	})
+}
+
+// AccountLocaleRequest sets the language of the user's messages
+type AccountLocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+// GetAccountLocale handles GET /account/locale
+func (h *Handlers) GetAccountLocale(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.userUsecase.GetLocale(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Failed to get locale", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get language")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// SetAccountLocale handles PUT /account/locale
+// An empty locale goes back to the language detected from the app.
+func (h *Handlers) SetAccountLocale(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req AccountLocaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	settings, err := h.userUsecase.SetLocale(c.UserContext(), userID, req.Locale)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidLocale):
+			return fiber.NewError(fiber.StatusBadRequest, "Locale must look like \"en\" or \"hi-IN\"")
+		case errors.Is(err, usecase.ErrUserNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Failed to set locale", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set language")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// ReactivateAccount handles POST /auth/reactivate with the OTP sent when a
+// deactivated user logged in
+func (h *Handlers) ReactivateAccount(c *fiber.Ctx) error {
+	var req usecase.ReactivateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.PhoneNumber == "" || req.OTP == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Phone number and OTP are required")
+	}
+
+	resp, err := h.userUsecase.Reactivate(c.UserContext(), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidOTP) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired OTP")
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Account reactivation failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Reactivation failed")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+		Message: "Account reactivated",
+	})
+}
+
+// AdminDeactivateUserRequest carries the reason recorded in the audit log
+type AdminDeactivateUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminDeactivateUser handles POST /admin/users/:id/deactivate
+func (h *Handlers) AdminDeactivateUser(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req AdminDeactivateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := h.userUsecase.AdminDeactivateUser(c.UserContext(), adminID, userID, req.Reason)
+	if err != nil {
+		if errors.Is(err, usecase.ErrDeactivationReason) {
+			return fiber.NewError(fiber.StatusBadRequest, "A reason is required")
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Admin deactivation failed", "error", err, "user_id", userID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to deactivate account")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    user,
+	})
+}
+
+// AdminReactivateUser handles POST /admin/users/:id/reactivate
+func (h *Handlers) AdminReactivateUser(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := h.userUsecase.AdminReactivateUser(c.UserContext(), adminID, userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Admin reactivation failed", "error", err, "user_id", userID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reactivate account")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    user,
+	})
+}
+
+// reactivationRequired answers a login by a self-deactivated account. The
+// app asks for the OTP just sent and posts it to /auth/reactivate.
+func reactivationRequired(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":                 "Account is deactivated, enter the OTP sent to your phone to reactivate it",
+		"reactivation_required": true,
+		"request_id":            logger.GetRequestID(c),
+	})
+}
+
+// mapAccountError converts deactivation and OTP rate limit errors to HTTP
+// errors, returning nil for other errors
+func mapAccountError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, usecase.ErrAccountDeactivated):
+		return fiber.NewError(fiber.StatusForbidden, "Account is deactivated")
+	case errors.Is(err, usecase.ErrOTPRateLimited):
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// AddressRequest is the body for saving or editing an address. IsDefault
+// only applies when creating; use POST /addresses/:id/default afterwards.
+type AddressRequest struct {
+	Label        string  `json:"label"`
+	Line1        string  `json:"line1"`
+	Line2        string  `json:"line2"`
+	Landmark     string  `json:"landmark"`
+	City         string  `json:"city"`
+	PostalCode   string  `json:"postal_code"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Instructions string  `json:"instructions"`
+	IsDefault    bool    `json:"is_default"`
+}
+
+func (r *AddressRequest) address() *domain.Address {
+	return &domain.Address{
+		Label:        r.Label,
+		Line1:        r.Line1,
+		Line2:        r.Line2,
+		Landmark:     r.Landmark,
+		City:         r.City,
+		PostalCode:   r.PostalCode,
+		Latitude:     r.Latitude,
+		Longitude:    r.Longitude,
+		Instructions: r.Instructions,
+		IsDefault:    r.IsDefault,
+	}
+}
+
+// GetAddresses handles GET /addresses
+func (h *Handlers) GetAddresses(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := h.addressUsecase.List(c.UserContext(), userID)
+	if err != nil {
+		h.log.Error("Failed to fetch addresses", "error", err, "user_id", userID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch addresses")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    addresses,
+	})
+}
+
+// CreateAddress handles POST /addresses
+func (h *Handlers) CreateAddress(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req AddressRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	address, err := h.addressUsecase.Create(c.UserContext(), userID, req.address())
+	if err != nil {
+		if fiberErr := mapAddressError(err); fiberErr != nil {
+			return fiberErr
+		}
+		h.log.Error("Failed to save address", "error", err, "user_id", userID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save address")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    address,
+	})
+}
+
+// UpdateAddress handles PUT /addresses/:id
+func (h *Handlers) UpdateAddress(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	addressID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid address ID")
+	}
+
+	var req AddressRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	address, err := h.addressUsecase.Update(c.UserContext(), userID, addressID, req.address())
+	if err != nil {
+		if fiberErr := mapAddressError(err); fiberErr != nil {
+			return fiberErr
+		}
+		h.log.Error("Failed to update address", "error", err, "address_id", addressID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update address")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    address,
+	})
+}
+
+// SetDefaultAddress handles POST /addresses/:id/default
+func (h *Handlers) SetDefaultAddress(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	addressID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid address ID")
+	}
+
+	if err := h.addressUsecase.SetDefault(c.UserContext(), userID, addressID); err != nil {
+		if fiberErr := mapAddressError(err); fiberErr != nil {
+			return fiberErr
+		}
+		h.log.Error("Failed to set default address", "error", err, "address_id", addressID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set default address")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Default address updated",
+	})
+}
+
+// DeleteAddress handles DELETE /addresses/:id
+func (h *Handlers) DeleteAddress(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	addressID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid address ID")
+	}
+
+	if err := h.addressUsecase.Delete(c.UserContext(), userID, addressID); err != nil {
+		if fiberErr := mapAddressError(err); fiberErr != nil {
+			return fiberErr
+		}
+		h.log.Error("Failed to delete address", "error", err, "address_id", addressID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete address")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Address deleted",
+	})
+}
+
+// mapAddressError converts address validation errors, returning nil for
+// unexpected ones
+func mapAddressError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, usecase.ErrAddressNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Address not found")
+	case errors.Is(err, usecase.ErrAddressLimitFull):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, usecase.ErrAddressLabel),
+		errors.Is(err, usecase.ErrAddressLine),
+		errors.Is(err, usecase.ErrAddressDetail),
+		errors.Is(err, usecase.ErrAddressPostal),
+		errors.Is(err, usecase.ErrAddressLocation):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
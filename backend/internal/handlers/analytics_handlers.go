@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetOrderTimeSeries handles GET /admin/analytics/timeseries?hours=24
+// Orders per 15 min, revenue per hour and payment failure rate per hour.
+func (h *Handlers) GetOrderTimeSeries(c *fiber.Ctx) error {
+	series, err := h.analyticsUsecase.GetOrderTimeSeries(c.UserContext(), c.QueryInt("hours", 24))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidWindow) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to build order time series", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load metrics")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    series,
+	})
+}
+
+// GetItemPriceReport handles GET /admin/analytics/menu-items/:id/prices?days=30
+// Daily list price, units, revenue and the impact of price changes.
+func (h *Handlers) GetItemPriceReport(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+
+	report, err := h.analyticsUsecase.GetItemPriceReport(c.UserContext(), id, c.QueryInt("days", 30))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidReportDays):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		h.log.Error("Failed to build item price report", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load price report")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// GetSustainabilityReport handles GET /admin/analytics/sustainability?month=2024-03
+// Cutlery and packaging choices on the month's paid orders; defaults to
+// the current month.
+func (h *Handlers) GetSustainabilityReport(c *fiber.Ctx) error {
+	report, err := h.analyticsUsecase.GetSustainabilityReport(c.UserContext(), c.Query("month"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidReportMonth) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to build sustainability report", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load sustainability report")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    report,
+	})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// TrackAnnouncementsRequest reports banners the app showed and tapped
+type TrackAnnouncementsRequest struct {
+	Impressions []uuid.UUID `json:"impressions"`
+	Clicks      []uuid.UUID `json:"clicks"`
+}
+
+// announcementError maps announcement usecase errors to HTTP errors
+func announcementError(err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrAnnouncementNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, usecase.ErrInvalidAnnouncement),
+		errors.Is(err, usecase.ErrInvalidAnnouncementURL),
+		errors.Is(err, usecase.ErrInvalidAnnouncementTime),
+		errors.Is(err, usecase.ErrInvalidAudience),
+		errors.Is(err, usecase.ErrTooManyAnnouncementIDs):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// GetAnnouncements handles GET /announcements
+// Banners live now for the caller's segment, highest priority first.
+// Works signed out, showing the banners meant for guests.
+func (h *Handlers) GetAnnouncements(c *fiber.Ctx) error {
+	var userID *uuid.UUID
+	if id, err := getUserID(c); err == nil {
+		userID = &id
+	}
+
+	announcements, err := h.announcements.ActiveAnnouncements(c.UserContext(), userID)
+	if err != nil {
+		h.log.Error("Failed to get announcements", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get announcements")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    announcements,
+	})
+}
+
+// TrackAnnouncements handles POST /announcements/track
+// Body: {"impressions": [ids], "clicks": [ids]}
+func (h *Handlers) TrackAnnouncements(c *fiber.Ctx) error {
+	var req TrackAnnouncementsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.announcements.TrackAnnouncements(c.UserContext(), req.Impressions, req.Clicks); err != nil {
+		if mapped := announcementError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to track announcements", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to track announcements")
+	}
+
+	return c.JSON(SuccessResponse{Success: true})
+}
+
+// GetAdminAnnouncements handles GET /admin/announcements?since=
+// Every banner with impressions, clicks and click rate counted from since
+// (a date or RFC 3339 time; all time when omitted), newest first.
+func (h *Handlers) GetAdminAnnouncements(c *fiber.Ctx) error {
+	since, err := parseExportTime(c.Query("since"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "since must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+
+	announcements, err := h.announcements.ListAnnouncements(c.UserContext(), since)
+	if err != nil {
+		h.log.Error("Failed to list announcements", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list announcements")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    announcements,
+	})
+}
+
+// CreateAnnouncement handles POST /admin/announcements
+func (h *Handlers) CreateAnnouncement(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.AnnouncementInput
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	announcement, err := h.announcements.CreateAnnouncement(c.UserContext(), adminID, req)
+	if err != nil {
+		if mapped := announcementError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to create announcement", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create announcement")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    announcement,
+	})
+}
+
+// UpdateAnnouncement handles PUT /admin/announcements/:id
+func (h *Handlers) UpdateAnnouncement(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid announcement ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.AnnouncementInput
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	announcement, err := h.announcements.UpdateAnnouncement(c.UserContext(), adminID, id, req)
+	if err != nil {
+		if mapped := announcementError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to update announcement", "error", err, "announcement_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update announcement")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    announcement,
+	})
+}
+
+// DeleteAnnouncement handles DELETE /admin/announcements/:id
+func (h *Handlers) DeleteAnnouncement(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid announcement ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.announcements.DeleteAnnouncement(c.UserContext(), adminID, id); err != nil {
+		if mapped := announcementError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to delete announcement", "error", err, "announcement_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete announcement")
+	}
+
+	return c.JSON(SuccessResponse{Success: true})
+}
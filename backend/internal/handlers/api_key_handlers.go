@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// APIKeyHeader carries partner API keys
+const APIKeyHeader = "X-API-Key"
+
+// ContextKeyAPIKey is the key for storing the authenticated partner key
+const ContextKeyAPIKey = "api_key"
+
+// APIKeyMiddleware authenticates a partner key, enforces the scope and the
+// per-endpoint rate limit, and meters the request once it completes
+func (h *Handlers) APIKeyMiddleware(scope domain.APIKeyScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Route path (not the raw URL) keeps metering keys bounded
+		endpoint := c.Method() + " " + c.Route().Path
+
+		key, limit, err := h.apiKeyUsecase.Authenticate(c.UserContext(), c.Get(APIKeyHeader), endpoint)
+		if err != nil && !errors.Is(err, usecase.ErrAPIKeyRateLimited) {
+			if errors.Is(err, usecase.ErrInvalidAPIKey) {
+				return fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+			}
+			h.log.Error("API key authentication failed", "error", err, "request_id", logger.GetRequestID(c))
+			return fiber.NewError(fiber.StatusInternalServerError, "Authentication failed")
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(limit.ResetAt.Unix(), 10))
+		if errors.Is(err, usecase.ErrAPIKeyRateLimited) {
+			h.apiKeyUsecase.RecordUsage(c.UserContext(), key.ID, endpoint, fiber.StatusTooManyRequests)
+			return fiber.NewError(fiber.StatusTooManyRequests, "Rate limit exceeded")
+		}
+
+		if !key.HasScope(scope) {
+			return fiber.NewError(fiber.StatusForbidden, "API key lacks scope "+string(scope))
+		}
+
+		c.Locals(ContextKeyAPIKey, key)
+
+		err = c.Next()
+
+		status := c.Response().StatusCode()
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			status = fe.Code
+		}
+		h.apiKeyUsecase.RecordUsage(c.UserContext(), key.ID, endpoint, status)
+
+		return err
+	}
+}
+
+// IssueAPIKey handles POST /admin/api-keys
+func (h *Handlers) IssueAPIKey(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.IssueAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Name == "" || len(req.Scopes) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Name and at least one scope are required")
+	}
+
+	key, err := h.apiKeyUsecase.IssueKey(c.UserContext(), req, userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidScope) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to issue API key", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to issue API key")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    key,
+		Message: "Store this key securely; it will not be shown again",
+	})
+}
+
+// ListAPIKeys handles GET /admin/api-keys
+func (h *Handlers) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.apiKeyUsecase.ListKeys(c.UserContext())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch API keys")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    keys,
+	})
+}
+
+// RotateAPIKey handles POST /admin/api-keys/:id/rotate
+func (h *Handlers) RotateAPIKey(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	key, err := h.apiKeyUsecase.RotateKey(c.UserContext(), id, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Active API key not found")
+		}
+		h.log.Error("Failed to rotate API key", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to rotate API key")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    key,
+		Message: "Previous key revoked. Store this key securely; it will not be shown again",
+	})
+}
+
+// RevokeAPIKey handles DELETE /admin/api-keys/:id
+func (h *Handlers) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	if err := h.apiKeyUsecase.RevokeKey(c.UserContext(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Active API key not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to revoke API key")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "API key revoked",
+	})
+}
+
+// GetAPIKeyUsage handles GET /admin/api-keys/:id/usage?days=7
+func (h *Handlers) GetAPIKeyUsage(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	usage, err := h.apiKeyUsecase.GetUsage(c.UserContext(), id, c.QueryInt("days", 7))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "API key not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch API key usage")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    usage,
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// Headers the mobile apps identify their build with
+const (
+	HeaderAppPlatform = "X-App-Platform" // android or ios
+	HeaderAppVersion  = "X-App-Version"  // e.g. 2.4.1
+)
+
+// AppVersionMiddleware answers 426 to app builds older than their platform's
+// minimum version, telling the app where to update. Requests that don't
+// send the headers pass.
+func (h *Handlers) AppVersionMiddleware(c *fiber.Ctx) error {
+	err := h.appVersionUsecase.Check(c.UserContext(), c.Get(HeaderAppPlatform), c.Get(HeaderAppVersion))
+	if err == nil {
+		return c.Next()
+	}
+
+	var outdated *usecase.AppOutdatedError
+	if !errors.As(err, &outdated) {
+		return err
+	}
+
+	// The app shows a blocking update screen linking to update_url
+	return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+		"error":           "This version of the app is no longer supported, please update",
+		"update_required": true,
+		"platform":        outdated.Platform,
+		"current_version": outdated.Version,
+		"min_version":     outdated.MinVersion,
+		"update_url":      outdated.UpdateURL,
+		"request_id":      logger.GetRequestID(c),
+	})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/attestation"
+)
+
+// Protected actions used as attestation metric labels
+const (
+	AttestationActionOrderCreate = "order_create"
+	AttestationActionOTPRequest  = "otp_request"
+)
+
+// AttestationMiddleware requires a genuine-app attestation token for the
+// given action. Behaviour (off/monitor/enforce) is decided by the usecase.
+func (h *Handlers) AttestationMiddleware(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := h.attestationUsecase.Check(c.UserContext(), action, c.Get(attestation.HeaderName)); err != nil {
+			if errors.Is(err, usecase.ErrAttestationFailed) {
+				return fiber.NewError(fiber.StatusForbidden, "App attestation required")
+			}
+			return err
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetAdminActivity handles GET /admin/activity?since=&actor_id=&action=&limit=&offset=
+// Recent staff actions, newest first, with per-action counts for the window.
+// action takes a comma-separated list; since defaults to the last 24 hours.
+func (h *Handlers) GetAdminActivity(c *fiber.Ctx) error {
+	since, err := parseExportTime(c.Query("since"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "since must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+
+	q := usecase.ActivityQuery{
+		Since:  since,
+		Limit:  c.QueryInt("limit"),
+		Offset: c.QueryInt("offset"),
+	}
+
+	if raw := c.Query("actor_id"); raw != "" {
+		actorID, err := uuid.Parse(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid actor ID")
+		}
+		q.ActorID = &actorID
+	}
+
+	for _, action := range strings.Split(c.Query("action"), ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			q.Actions = append(q.Actions, domain.AuditAction(action))
+		}
+	}
+
+	activity, err := h.auditUsecase.Activity(c.UserContext(), q)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidActivityWindow) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to load admin activity", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load admin activity")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    activity,
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// SuggestDeliveryBatches handles GET /admin/delivery-batches/suggestions
+func (h *Handlers) SuggestDeliveryBatches(c *fiber.Ctx) error {
+	suggestions, err := h.batchUsecase.SuggestBatches(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to suggest delivery batches", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to suggest batches")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    suggestions,
+	})
+}
+
+// CreateDeliveryBatchRequest assigns several orders to one rider
+type CreateDeliveryBatchRequest struct {
+	RiderID  uuid.UUID   `json:"rider_id"`
+	OrderIDs []uuid.UUID `json:"order_ids"`
+}
+
+// CreateDeliveryBatch handles POST /admin/delivery-batches
+func (h *Handlers) CreateDeliveryBatch(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateDeliveryBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.RiderID == uuid.Nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Rider ID is required")
+	}
+
+	batch, err := h.batchUsecase.CreateBatch(c.UserContext(), req.RiderID, req.OrderIDs, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrEmptyBatch),
+			errors.Is(err, usecase.ErrBatchTooLarge),
+			errors.Is(err, usecase.ErrOrderNotBatchable):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to create delivery batch", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create batch")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    batch,
+	})
+}
+
+// GetRiderRoute handles GET /rider/route
+func (h *Handlers) GetRiderRoute(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	batch, err := h.batchUsecase.GetRiderRoute(c.UserContext(), riderID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrNoActiveBatch) {
+			return fiber.NewError(fiber.StatusNotFound, "No active delivery batch")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch route")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    batch,
+	})
+}
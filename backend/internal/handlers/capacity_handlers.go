@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// maxSlotsListed caps GET /orders/slots to one day of 15-minute slots
+const maxSlotsListed = 96
+
+// GetKitchenSlots handles GET /orders/slots?from=RFC3339&count=N
+// Lists upcoming kitchen slots so customers can pick a scheduled time.
+func (h *Handlers) GetKitchenSlots(c *fiber.Ctx) error {
+	from := time.Now()
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "from must be an RFC3339 timestamp")
+		}
+		if parsed.After(from) {
+			from = parsed
+		}
+	}
+
+	count := c.QueryInt("count", 8)
+	if count < 1 || count > maxSlotsListed {
+		return fiber.NewError(fiber.StatusBadRequest, "count must be between 1 and 96")
+	}
+
+	slots, err := h.capacityUsecase.GetAvailability(c.UserContext(), from, count)
+	if err != nil {
+		h.log.Error("Failed to list kitchen slots", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list slots")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    slots,
+	})
+}
+
+// GetCapacityRules handles GET /admin/capacity
+func (h *Handlers) GetCapacityRules(c *fiber.Ctx) error {
+	rules, err := h.capacityUsecase.GetRules(c.UserContext())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch capacity rules")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    rules,
+	})
+}
+
+// ReplaceCapacityRulesRequest is the full rule set to apply
+type ReplaceCapacityRulesRequest struct {
+	Rules []domain.KitchenCapacityRule `json:"rules"`
+}
+
+// ReplaceCapacityRules handles PUT /admin/capacity
+func (h *Handlers) ReplaceCapacityRules(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req ReplaceCapacityRulesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	rules, err := h.capacityUsecase.ReplaceRules(c.UserContext(), req.Rules, adminID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCapacityRule) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to update capacity rules", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update capacity rules")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    rules,
+	})
+}
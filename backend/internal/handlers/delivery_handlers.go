@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetDeliveryOTP handles GET /orders/:id/delivery-otp
+func (h *Handlers) GetDeliveryOTP(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	resp, err := h.orderUsecase.GetDeliveryOTP(c.UserContext(), orderID, userID)
+	if err != nil {
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch delivery OTP")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// GetRiderOrders handles GET /rider/orders
+func (h *Handlers) GetRiderOrders(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.orderUsecase.GetRiderOrders(c.UserContext(), riderID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch orders")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    orders,
+	})
+}
+
+// ConfirmDeliveryRequest carries the OTP read out by the customer
+type ConfirmDeliveryRequest struct {
+	OTP string `json:"otp"`
+}
+
+// ConfirmDelivery handles POST /rider/orders/:id/deliver
+func (h *Handlers) ConfirmDelivery(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req ConfirmDeliveryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.OTP == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "OTP is required")
+	}
+
+	order, err := h.orderUsecase.ConfirmDelivery(c.UserContext(), orderID, riderID, req.OTP)
+	if err != nil {
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to confirm delivery", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to confirm delivery")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    order,
+		Message: "Order delivered",
+	})
+}
+
+// DispatchOrderRequest assigns a rider
+type DispatchOrderRequest struct {
+	RiderID uuid.UUID `json:"rider_id"`
+}
+
+// DispatchOrder handles POST /admin/orders/:id/dispatch
+func (h *Handlers) DispatchOrder(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req DispatchOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.RiderID == uuid.Nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Rider ID is required")
+	}
+
+	order, err := h.orderUsecase.DispatchOrder(c.UserContext(), orderID, req.RiderID)
+	if err != nil {
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    order,
+		Message: "Order dispatched",
+	})
+}
+
+// OverrideDeliveryRequest explains why the OTP was bypassed
+type OverrideDeliveryRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OverrideDelivery handles POST /admin/orders/:id/deliver-override
+func (h *Handlers) OverrideDelivery(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req OverrideDeliveryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	order, err := h.orderUsecase.OverrideDelivery(c.UserContext(), orderID, adminID, req.Reason)
+	if err != nil {
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to override delivery", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to override delivery")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    order,
+		Message: "Delivery confirmed by override",
+	})
+}
+
+// SetRiderStatusRequest toggles the rider role
+type SetRiderStatusRequest struct {
+	IsRider bool `json:"is_rider"`
+}
+
+// SetRiderStatus handles PUT /admin/users/:id/rider
+func (h *Handlers) SetRiderStatus(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req SetRiderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := h.userUsecase.SetRiderStatus(c.UserContext(), userID, req.IsRider)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update rider status")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    user,
+	})
+}
+
+// mapDeliveryError converts known delivery errors to HTTP errors, returning
+// nil for unexpected ones so callers can log them
+func mapDeliveryError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return fiber.NewError(fiber.StatusConflict, "Order was updated concurrently, please retry")
+	case errors.Is(err, usecase.ErrNotRider):
+		return fiber.NewError(fiber.StatusBadRequest, "User is not a rider")
+	case errors.Is(err, usecase.ErrNotDeliveryOrder):
+		return fiber.NewError(fiber.StatusBadRequest, "Order is not a delivery order")
+	case errors.Is(err, usecase.ErrNotOutForDelivery):
+		return fiber.NewError(fiber.StatusConflict, "Order is not out for delivery")
+	case errors.Is(err, usecase.ErrNotAssignedRider):
+		return fiber.NewError(fiber.StatusForbidden, "Order is assigned to another rider")
+	case errors.Is(err, usecase.ErrInvalidDeliveryOTP):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Invalid OTP")
+	case errors.Is(err, usecase.ErrDeliveryOTPLocked):
+		return fiber.NewError(fiber.StatusLocked, "Too many wrong OTPs, contact support to complete this delivery")
+	case errors.Is(err, usecase.ErrOverrideReason):
+		return fiber.NewError(fiber.StatusBadRequest, "Reason is required")
+	default:
+		return nil
+	}
+}
+
+// proofPhotoURL is the authenticated path serving an order's proof photo
+func proofPhotoURL(orderID uuid.UUID) string {
+	return "/api/v1/orders/" + orderID.String() + "/proof-of-delivery/photo"
+}
+
+// UploadDeliveryProof handles POST /rider/orders/:id/proof (multipart "photo")
+func (h *Handlers) UploadDeliveryProof(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Photo is required")
+	}
+	if fileHeader.Size > usecase.MaxProofPhotoBytes {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Photo is too large")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid photo upload")
+	}
+	defer file.Close()
+
+	photo, err := io.ReadAll(io.LimitReader(file, usecase.MaxProofPhotoBytes+1))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid photo upload")
+	}
+
+	proof, err := h.orderUsecase.UploadDeliveryProof(c.UserContext(), orderID, riderID, photo)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidProofPhoto):
+			return fiber.NewError(fiber.StatusUnsupportedMediaType, "Photo must be a JPEG, PNG or WebP image")
+		case errors.Is(err, usecase.ErrProofPhotoTooLarge):
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Photo is too large")
+		case errors.Is(err, usecase.ErrProofNotAllowed):
+			return fiber.NewError(fiber.StatusConflict, "Proof can only be uploaded for orders out for delivery or delivered")
+		}
+		if fe := mapDeliveryError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to upload delivery proof", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to upload photo")
+	}
+
+	proof.PhotoURL = proofPhotoURL(orderID)
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    proof,
+	})
+}
+
+// GetDeliveryProofPhoto handles GET /orders/:id/proof-of-delivery/photo
+func (h *Handlers) GetDeliveryProofPhoto(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	order, err := h.orderUsecase.GetOrder(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch order")
+	}
+
+	// Customer, the delivering rider, or support/admin
+	isAdmin, _ := c.Locals(ContextKeyIsAdmin).(bool)
+	isDeliveringRider := order.RiderID != nil && *order.RiderID == userID
+	if order.UserID != userID && !isAdmin && !isDeliveringRider {
+		return fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	body, proof, err := h.orderUsecase.OpenDeliveryProof(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "No proof of delivery for this order")
+		}
+		h.log.Error("Failed to open delivery proof", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch photo")
+	}
+
+	c.Set(fiber.HeaderContentType, proof.ContentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return c.SendStream(body, proof.SizeBytes)
+}
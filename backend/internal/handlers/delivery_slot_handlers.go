@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetDeliverySlots handles GET /orders/delivery-slots?date=2006-01-02
+// Lists the day's 30-minute delivery windows (today by default) and which
+// can still be booked.
+func (h *Handlers) GetDeliverySlots(c *fiber.Ctx) error {
+	day := time.Now()
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, raw, h.location)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "date must be a date (2006-01-02)")
+		}
+		day = parsed
+	}
+
+	slots, err := h.deliverySlots.GetAvailability(c.UserContext(), day)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidDeliveryDate) {
+			return fiber.NewError(fiber.StatusBadRequest, "Date must be today or within the next 7 days")
+		}
+		h.log.Error("Failed to list delivery slots", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list delivery slots")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    slots,
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// DeviceRequest identifies an app install by its FCM token
+type DeviceRequest struct {
+	Token    string                `json:"token"`
+	Platform domain.DevicePlatform `json:"platform"`
+}
+
+// RegisterDevice handles POST /devices
+// Body: {"token": "<FCM registration token>", "platform": "android"}
+func (h *Handlers) RegisterDevice(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	device, err := h.devices.RegisterDevice(c.UserContext(), userID, req.Token, req.Platform)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidDeviceToken) || errors.Is(err, usecase.ErrInvalidPlatform) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to register device", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to register device")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    device,
+	})
+}
+
+// UnregisterDevice handles DELETE /devices
+// Body: {"token": "<FCM registration token>"}
+func (h *Handlers) UnregisterDevice(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.devices.UnregisterDevice(c.UserContext(), userID, req.Token); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Device not registered")
+		}
+		h.log.Error("Failed to unregister device", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to unregister device")
+	}
+
+	return c.JSON(SuccessResponse{Success: true})
+}
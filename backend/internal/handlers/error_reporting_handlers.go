@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/errreport"
+	"fooddelivery/pkg/logger"
+)
+
+// ErrorReportingMiddleware reports panics and 5xx responses with their
+// request context. Must run after logger.FiberMiddleware: panics are
+// re-raised so the request logger still logs them and answers 500.
+func ErrorReportingMiddleware(reporter errreport.Reporter) fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				reporter.Report(errreport.Event{
+					Level:     errreport.LevelFatal,
+					Message:   fmt.Sprint(r),
+					ErrorType: "panic",
+					Stack:     string(debug.Stack()),
+					Request:   reportRequest(c, fiber.StatusInternalServerError),
+					UserID:    reportUserID(c),
+					Tags:      map[string]string{"route": c.Route().Path},
+				})
+				panic(r)
+			}
+		}()
+
+		err = c.Next()
+
+		status := c.Response().StatusCode()
+		message := ""
+		errorType := ""
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+			message = err.Error()
+			errorType = fmt.Sprintf("%T", err)
+		}
+		if status < fiber.StatusInternalServerError {
+			return err
+		}
+		if message == "" {
+			message = fmt.Sprintf("%d response from %s %s", status, c.Method(), c.Route().Path)
+		}
+
+		reporter.Report(errreport.Event{
+			Level:     errreport.LevelError,
+			Message:   message,
+			ErrorType: errorType,
+			Request:   reportRequest(c, status),
+			UserID:    reportUserID(c),
+			Tags:      map[string]string{"route": c.Route().Path},
+		})
+		return err
+	}
+}
+
+// reportRequest captures request context for an error report. The query
+// string is left out since it may carry tokens.
+func reportRequest(c *fiber.Ctx, status int) *errreport.Request {
+	return &errreport.Request{
+		Method:    c.Method(),
+		URL:       c.BaseURL() + c.Path(),
+		RequestID: logger.GetRequestID(c),
+		ClientIP:  c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Status:    status,
+	}
+}
+
+func reportUserID(c *fiber.Ctx) string {
+	if id, ok := c.Locals(ContextKeyUserID).(uuid.UUID); ok {
+		return id.String()
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// exportTimeout bounds a streamed export. The stream is written after the
+// handler returns, outside the request deadline.
+const exportTimeout = 5 * time.Minute
+
+// exportWriteTimeout bounds each write of a streamed export, replacing the
+// server's write timeout, which would otherwise cover the whole stream
+const exportWriteTimeout = 10 * time.Second
+
+// ExportOrders handles GET /admin/orders/export?from=2024-03-01&to=2024-04-01&status=DELIVERED
+// Streams the matching orders with their items as they are read, newest
+// first. from/to accept a date or RFC3339 time; to is exclusive.
+func (h *Handlers) ExportOrders(c *fiber.Ctx) error {
+	from, err := parseExportTime(c.Query("from"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "from must be a date (2006-01-02) or RFC3339 time")
+	}
+	to, err := parseExportTime(c.Query("to"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "to must be a date (2006-01-02) or RFC3339 time")
+	}
+
+	filter := repository.OrderExportFilter{
+		From:   from,
+		To:     to,
+		Status: domain.OrderStatus(c.Query("status")),
+	}
+	if err := h.orderUsecase.ValidateExport(filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	requestID := logger.GetRequestID(c)
+	return streamJSONArray(c, func(ctx context.Context, emit func(v any) error) error {
+		err := h.orderUsecase.ExportOrders(ctx, filter, func(order *domain.Order) error {
+			return emit(order)
+		})
+		if err != nil {
+			h.log.Error("Order export failed", "error", err, "request_id", requestID)
+		}
+		return err
+	})
+}
+
+// streamJSONArray writes {"success":true,"data":[...]} with array elements
+// encoded as produce emits them, so large result sets are never held in
+// memory. Headers are sent before produce runs: if it fails part-way the
+// array is closed and an "error" field added, so clients must check for it.
+func streamJSONArray(c *fiber.Ctx, produce func(ctx context.Context, emit func(v any) error) error) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+
+	// The server's write timeout is set once for the whole response, so the
+	// stream extends the deadline before each write instead
+	conn := c.Context().Conn()
+	extendDeadline := func() error {
+		if conn == nil {
+			return nil
+		}
+		return conn.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		_ = extendDeadline()
+		_, _ = w.WriteString(`{"success":true,"data":[`)
+
+		first := true
+		enc := json.NewEncoder(w)
+		err := produce(ctx, func(v any) error {
+			if err := extendDeadline(); err != nil {
+				return err
+			}
+			if !first {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			first = false
+			// Encode fails once the client has gone away and the buffer
+			// can't be flushed, which stops the export
+			return enc.Encode(v)
+		})
+
+		_ = extendDeadline()
+		if err != nil {
+			_, _ = w.WriteString(`],"error":"Export interrupted"}`)
+		} else {
+			_, _ = w.WriteString(`]}`)
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+// parseExportTime accepts a date or an RFC3339 time; dates are midnight in loc
+func parseExportTime(raw string, loc *time.Location) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// A streamed export must outlive the server's write timeout, which
+// fasthttp sets once for the whole response
+func TestStreamJSONArrayOutlivesWriteTimeout(t *testing.T) {
+	const (
+		writeTimeout = 200 * time.Millisecond
+		rows         = 6
+		rowInterval  = 100 * time.Millisecond // rows*rowInterval > writeTimeout
+	)
+
+	app := fiber.New(fiber.Config{WriteTimeout: writeTimeout, DisableStartupMessage: true})
+	app.Get("/export", func(c *fiber.Ctx) error {
+		return streamJSONArray(c, func(ctx context.Context, emit func(v any) error) error {
+			for i := 0; i < rows; i++ {
+				time.Sleep(rowInterval)
+				if err := emit(map[string]int{"row": i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	defer func() { _ = app.Shutdown() }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/export")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body after %d bytes: %v", len(body), err)
+	}
+
+	var result struct {
+		Success bool             `json:"success"`
+		Data    []map[string]int `json:"data"`
+		Error   string           `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("truncated response %q: %v", body, err)
+	}
+	if result.Error != "" {
+		t.Errorf("error = %q, want none", result.Error)
+	}
+	if len(result.Data) != rows {
+		t.Errorf("got %d rows, want %d", len(result.Data), rows)
+	}
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// HeaderGuestToken carries the token from POST /guest/session
+const HeaderGuestToken = "X-Guest-Token"
+
+// CartRequest replaces a saved cart
+type CartRequest struct {
+	Items []domain.CartItem `json:"items"`
+}
+
+// CartQuantityRequest sets the quantity of a cart line
+type CartQuantityRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// MergeCartRequest names the guest cart to carry into the user's
+type MergeCartRequest struct {
+	GuestToken string `json:"guest_token"`
+}
+
+// StartGuestSession handles POST /guest/session
+func (h *Handlers) StartGuestSession(c *fiber.Ctx) error {
+	session, err := h.cartUsecase.StartGuestSession(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to start guest session", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start guest session")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// GetGuestCart handles GET /guest/cart
+func (h *Handlers) GetGuestCart(c *fiber.Ctx) error {
+	cart, err := h.cartUsecase.GetGuestCart(c.UserContext(), c.Get(HeaderGuestToken))
+	if err != nil {
+		if ferr := mapGuestCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to fetch guest cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// SaveGuestCart handles PUT /guest/cart
+func (h *Handlers) SaveGuestCart(c *fiber.Ctx) error {
+	var req CartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	cart, err := h.cartUsecase.SaveGuestCart(c.UserContext(), c.Get(HeaderGuestToken), req.Items)
+	if err != nil {
+		if ferr := mapGuestCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to save guest cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// GetCart handles GET /cart
+func (h *Handlers) GetCart(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.cartUsecase.GetCart(c.UserContext(), userID)
+	if err != nil {
+		h.log.Error("Failed to fetch cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// SaveCart handles PUT /cart
+func (h *Handlers) SaveCart(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req CartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	cart, err := h.cartUsecase.SaveCart(c.UserContext(), userID, req.Items)
+	if err != nil {
+		if ferr := mapCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to save cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// AddCartItem handles POST /cart/items
+// Adds to the quantity when the item is already in the cart.
+func (h *Handlers) AddCartItem(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req domain.CartItem
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	cart, err := h.cartUsecase.AddItem(c.UserContext(), userID, req)
+	if err != nil {
+		if ferr := mapCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to add cart item", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add item to cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// UpdateCartItem handles PUT /cart/items/:menu_item_id
+// A quantity of 0 removes the line. Lines with a variant or modifiers are
+// picked with the variant_id and modifier_ids query parameters.
+func (h *Handlers) UpdateCartItem(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	line, err := cartLine(c)
+	if err != nil {
+		return err
+	}
+
+	var req CartQuantityRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	cart, err := h.cartUsecase.UpdateQuantity(c.UserContext(), userID, line, req.Quantity)
+	if err != nil {
+		if errors.Is(err, usecase.ErrCartItemNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Item is not in the cart")
+		}
+		if ferr := mapCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to update cart item", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// RemoveCartItem handles DELETE /cart/items/:menu_item_id
+// Takes the same variant_id and modifier_ids query parameters as
+// UpdateCartItem.
+func (h *Handlers) RemoveCartItem(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	line, err := cartLine(c)
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.cartUsecase.RemoveItem(c.UserContext(), userID, line)
+	if err != nil {
+		h.log.Error("Failed to remove cart item", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// ClearCart handles DELETE /cart
+func (h *Handlers) ClearCart(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.cartUsecase.Clear(c.UserContext(), userID); err != nil {
+		h.log.Error("Failed to clear cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to clear cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Cart cleared",
+	})
+}
+
+// MergeGuestCart handles POST /cart/merge
+// For logins that didn't go through guest checkout, or to retry a merge.
+func (h *Handlers) MergeGuestCart(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req MergeCartRequest
+	if err := c.BodyParser(&req); err != nil || req.GuestToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "guest_token is required")
+	}
+
+	cart, err := h.cartUsecase.MergeGuestCart(c.UserContext(), userID, req.GuestToken)
+	if err != nil {
+		h.log.Error("Failed to merge guest cart", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to merge cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    cart,
+	})
+}
+
+// SendGuestOTP handles POST /auth/guest/send-otp
+func (h *Handlers) SendGuestOTP(c *fiber.Ctx) error {
+	var req usecase.PhoneLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	resp, err := h.userUsecase.SendGuestOTP(c.UserContext(), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidPhone) {
+			return fiber.NewError(fiber.StatusBadRequest, "Enter a valid phone number")
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Send guest OTP failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send OTP")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// ConvertGuest handles POST /auth/guest/verify
+func (h *Handlers) ConvertGuest(c *fiber.Ctx) error {
+	var req usecase.ConvertGuestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.PhoneNumber == "" || req.OTP == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Phone number and OTP are required")
+	}
+
+	resp, err := h.userUsecase.ConvertGuest(c.UserContext(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidOTP):
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired OTP")
+		case errors.Is(err, usecase.ErrNameRequired):
+			return fiber.NewError(fiber.StatusBadRequest, "Name is required")
+		case errors.Is(err, usecase.ErrInvalidEmail):
+			return fiber.NewError(fiber.StatusBadRequest, "Enter a valid email address")
+		case errors.Is(err, usecase.ErrUserExists):
+			return fiber.NewError(fiber.StatusConflict, "An account with this email already exists, log in with it instead")
+		case errors.Is(err, usecase.ErrReactivationRequired):
+			return reactivationRequired(c)
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Guest conversion failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Verification failed")
+	}
+
+	h.userUsecase.DetectLocale(c.UserContext(), resp.UserID, c.Get(fiber.HeaderAcceptLanguage))
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// mapGuestCartError converts guest cart errors to HTTP errors, returning
+// nil for unexpected ones so callers can log them
+func mapGuestCartError(err error) *fiber.Error {
+	if errors.Is(err, usecase.ErrGuestSessionExpired) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Guest session expired, start a new one")
+	}
+	return mapCartError(err)
+}
+
+// cartLine reads which cart line a request is about: the menu item from
+// the path, and its variant and comma-separated modifiers from the query
+func cartLine(c *fiber.Ctx) (domain.CartItem, error) {
+	var line domain.CartItem
+	var err error
+	if line.MenuItemID, err = uuid.Parse(c.Params("menu_item_id")); err != nil {
+		return line, fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+	if v := c.Query("variant_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return line, fiber.NewError(fiber.StatusBadRequest, "Invalid variant ID")
+		}
+		line.VariantID = &id
+	}
+	if m := c.Query("modifier_ids"); m != "" {
+		for _, v := range strings.Split(m, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(v))
+			if err != nil {
+				return line, fiber.NewError(fiber.StatusBadRequest, "Invalid modifier ID")
+			}
+			line.ModifierIDs = append(line.ModifierIDs, id)
+		}
+	}
+	return line, nil
+}
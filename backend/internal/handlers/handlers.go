@@ -13,16 +13,43 @@ import (
 	"fooddelivery/internal/domain"
 	"fooddelivery/internal/repository"
 	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/buildinfo"
 	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
 )
 
 // Handlers aggregates all HTTP handlers
 type Handlers struct {
-	menuUsecase    *usecase.MenuUsecase
-	orderUsecase   *usecase.OrderUsecase
-	paymentUsecase *usecase.PaymentUsecase
-	userUsecase    *usecase.UserUsecase
-	log            *logger.Logger
+	menuUsecase        *usecase.MenuUsecase
+	orderUsecase       *usecase.OrderUsecase
+	paymentUsecase     *usecase.PaymentUsecase
+	userUsecase        *usecase.UserUsecase
+	apiKeyUsecase      *usecase.APIKeyUsecase
+	attestationUsecase *usecase.AttestationUsecase
+	batchUsecase       *usecase.BatchUsecase
+	capacityUsecase    *usecase.CapacityUsecase
+	surveyUsecase      *usecase.SurveyUsecase
+	analyticsUsecase   *usecase.AnalyticsUsecase
+	importUsecase      *usecase.OrderImportUsecase
+	settingsUsecase    *usecase.SettingsUsecase
+	auditUsecase       *usecase.AuditUsecase
+	templateUsecase    *usecase.TemplateUsecase
+	shortageUsecase    *usecase.ItemShortageUsecase
+	appVersionUsecase  *usecase.AppVersionUsecase
+	cartUsecase        *usecase.CartUsecase
+	presenceUsecase    *usecase.PresenceUsecase
+	addressUsecase     *usecase.AddressUsecase
+	deliverySlots      *usecase.DeliverySlotUsecase
+	chatUsecase        *usecase.OrderChatUsecase
+	callUsecase        *usecase.MaskedCallUsecase
+	inventoryUsecase   *usecase.InventoryUsecase
+	announcements      *usecase.AnnouncementUsecase
+	issues             *usecase.OrderIssueUsecase
+	stations           *usecase.PrepStationUsecase
+	live               *usecase.OrderLiveHub
+	devices            *usecase.DeviceUsecase
+	location           *time.Location
+	log                *logger.Logger
 }
 
 // NewHandlers creates a new handlers instance
@@ -31,20 +58,71 @@ func NewHandlers(
 	orderUsecase *usecase.OrderUsecase,
 	paymentUsecase *usecase.PaymentUsecase,
 	userUsecase *usecase.UserUsecase,
+	apiKeyUsecase *usecase.APIKeyUsecase,
+	attestationUsecase *usecase.AttestationUsecase,
+	batchUsecase *usecase.BatchUsecase,
+	capacityUsecase *usecase.CapacityUsecase,
+	surveyUsecase *usecase.SurveyUsecase,
+	analyticsUsecase *usecase.AnalyticsUsecase,
+	importUsecase *usecase.OrderImportUsecase,
+	settingsUsecase *usecase.SettingsUsecase,
+	auditUsecase *usecase.AuditUsecase,
+	templateUsecase *usecase.TemplateUsecase,
+	shortageUsecase *usecase.ItemShortageUsecase,
+	appVersionUsecase *usecase.AppVersionUsecase,
+	cartUsecase *usecase.CartUsecase,
+	presenceUsecase *usecase.PresenceUsecase,
+	addressUsecase *usecase.AddressUsecase,
+	deliverySlotUsecase *usecase.DeliverySlotUsecase,
+	chatUsecase *usecase.OrderChatUsecase,
+	callUsecase *usecase.MaskedCallUsecase,
+	inventoryUsecase *usecase.InventoryUsecase,
+	announcements *usecase.AnnouncementUsecase,
+	issues *usecase.OrderIssueUsecase,
+	stations *usecase.PrepStationUsecase,
+	live *usecase.OrderLiveHub,
+	devices *usecase.DeviceUsecase,
+	location *time.Location,
 	log *logger.Logger,
 ) *Handlers {
 	return &Handlers{
-		menuUsecase:    menuUsecase,
-		orderUsecase:   orderUsecase,
-		paymentUsecase: paymentUsecase,
-		userUsecase:    userUsecase,
-		log:            log,
+		menuUsecase:        menuUsecase,
+		orderUsecase:       orderUsecase,
+		paymentUsecase:     paymentUsecase,
+		userUsecase:        userUsecase,
+		apiKeyUsecase:      apiKeyUsecase,
+		attestationUsecase: attestationUsecase,
+		batchUsecase:       batchUsecase,
+		capacityUsecase:    capacityUsecase,
+		surveyUsecase:      surveyUsecase,
+		analyticsUsecase:   analyticsUsecase,
+		importUsecase:      importUsecase,
+		settingsUsecase:    settingsUsecase,
+		auditUsecase:       auditUsecase,
+		templateUsecase:    templateUsecase,
+		shortageUsecase:    shortageUsecase,
+		appVersionUsecase:  appVersionUsecase,
+		cartUsecase:        cartUsecase,
+		presenceUsecase:    presenceUsecase,
+		addressUsecase:     addressUsecase,
+		deliverySlots:      deliverySlotUsecase,
+		chatUsecase:        chatUsecase,
+		callUsecase:        callUsecase,
+		inventoryUsecase:   inventoryUsecase,
+		announcements:      announcements,
+		issues:             issues,
+		stations:           stations,
+		live:               live,
+		devices:            devices,
+		location:           location,
+		log:                log,
 	}
 }
 
 // ContextKeyUserID is the key for storing user ID in Fiber context
 const ContextKeyUserID = "user_id"
 const ContextKeyIsAdmin = "is_admin"
+const ContextKeyIsRider = "is_rider"
 
 // Response helpers
 type ErrorResponse struct {
@@ -56,6 +134,15 @@ type SuccessResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Message string      `json:"message,omitempty"`
+	Page    *PageInfo   `json:"page,omitempty"` // Set when Data is one page of a list
+}
+
+// PageInfo describes the page of a paginated list
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page
 }
 
 // CustomErrorHandler returns a custom error handler for Fiber
@@ -83,6 +170,19 @@ func CustomErrorHandler(log *logger.Logger) fiber.ErrorHandler {
 	}
 }
 
+// Version handles GET /version
+func (h *Handlers) Version(c *fiber.Ctx) error {
+	return c.JSON(buildinfo.Get())
+}
+
+// Metrics handles GET /metrics, exposing process metrics in the
+// Prometheus text format
+func (h *Handlers) Metrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	_, err := metrics.Default.WriteTo(c)
+	return err
+}
+
 // HealthCheck handles GET /health
 func (h *Handlers) HealthCheck(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
@@ -108,13 +208,31 @@ func (h *Handlers) AuthMiddleware(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
 	}
+	if h.userUsecase.IsTokenRevoked(c.UserContext(), claims) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
 
+	c.Locals(ContextKeyClaims, claims)
 	c.Locals(ContextKeyUserID, claims.UserID)
 	c.Locals(ContextKeyIsAdmin, claims.IsAdmin)
+	c.Locals(ContextKeyIsRider, claims.IsRider)
+	c.Locals(ContextKeyMFA, claims.MFA)
+
+	// User lookups made while serving the request share one read
+	c.SetUserContext(usecase.WithUserCache(c.UserContext()))
 
 	return c.Next()
 }
 
+// OptionalAuthMiddleware authenticates the request like AuthMiddleware
+// when it carries a token and lets it through as a guest when it doesn't
+func (h *Handlers) OptionalAuthMiddleware(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" {
+		return c.Next()
+	}
+	return h.AuthMiddleware(c)
+}
+
 // AdminMiddleware checks if user is admin
 func (h *Handlers) AdminMiddleware(c *fiber.Ctx) error {
 	isAdmin, ok := c.Locals(ContextKeyIsAdmin).(bool)
@@ -124,6 +242,15 @@ func (h *Handlers) AdminMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// RiderMiddleware checks if user is a rider
+func (h *Handlers) RiderMiddleware(c *fiber.Ctx) error {
+	isRider, ok := c.Locals(ContextKeyIsRider).(bool)
+	if !ok || !isRider {
+		return fiber.NewError(fiber.StatusForbidden, "Rider access required")
+	}
+	return c.Next()
+}
+
 // getUserID extracts user ID from context
 func getUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	userID, ok := c.Locals(ContextKeyUserID).(uuid.UUID)
@@ -145,7 +272,7 @@ func (h *Handlers) Register(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Email, password, name, and phone number are required")
 	}
 
-	resp, err := h.userUsecase.Register(c.Context(), req)
+	resp, err := h.userUsecase.Register(c.UserContext(), req)
 	if err != nil {
 		if errors.Is(err, usecase.ErrUserExists) {
 			return fiber.NewError(fiber.StatusConflict, "User already exists")
@@ -174,7 +301,7 @@ func (h *Handlers) EmailLogin(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Email and password are required")
 	}
 
-	resp, err := h.userUsecase.EmailLogin(c.Context(), req)
+	resp, err := h.userUsecase.EmailLogin(c.UserContext(), req)
 	if err != nil {
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "User not found")
@@ -182,10 +309,18 @@ func (h *Handlers) EmailLogin(c *fiber.Ctx) error {
 		if errors.Is(err, usecase.ErrInvalidPassword) {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid password")
 		}
+		if errors.Is(err, usecase.ErrReactivationRequired) {
+			return reactivationRequired(c)
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
 		h.log.Error("Login failed", "error", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Login failed")
 	}
 
+	h.userUsecase.DetectLocale(c.UserContext(), resp.UserID, c.Get(fiber.HeaderAcceptLanguage))
+
 	return c.JSON(SuccessResponse{
 		Success: true,
 		Data:    resp,
@@ -203,11 +338,14 @@ func (h *Handlers) SendOTP(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Phone number is required")
 	}
 
-	resp, err := h.userUsecase.SendOTP(c.Context(), req)
+	resp, err := h.userUsecase.SendOTP(c.UserContext(), req)
 	if err != nil {
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "User not found")
 		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
 		h.log.Error("Send OTP failed", "error", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send OTP")
 	}
@@ -229,7 +367,7 @@ func (h *Handlers) VerifyOTP(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Phone number and OTP are required")
 	}
 
-	resp, err := h.userUsecase.VerifyOTP(c.Context(), req)
+	resp, err := h.userUsecase.VerifyOTP(c.UserContext(), req)
 	if err != nil {
 		if errors.Is(err, usecase.ErrInvalidOTP) {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired OTP")
@@ -237,20 +375,87 @@ func (h *Handlers) VerifyOTP(c *fiber.Ctx) error {
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "User not found")
 		}
+		if errors.Is(err, usecase.ErrReactivationRequired) {
+			return reactivationRequired(c)
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
 		h.log.Error("OTP verification failed", "error", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Verification failed")
 	}
 
+	h.userUsecase.DetectLocale(c.UserContext(), resp.UserID, c.Get(fiber.HeaderAcceptLanguage))
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// GoogleLogin handles POST /auth/login/google (Google Sign-In)
+func (h *Handlers) GoogleLogin(c *fiber.Ctx) error {
+	var req usecase.GoogleLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.IDToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "ID token is required")
+	}
+
+	resp, err := h.userUsecase.GoogleLogin(c.UserContext(), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrSocialLoginDisabled) {
+			return fiber.NewError(fiber.StatusNotImplemented, "Google Sign-In is not enabled")
+		}
+		if errors.Is(err, usecase.ErrInvalidIDToken) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid Google token")
+		}
+		if errors.Is(err, usecase.ErrEmailUnverified) {
+			return fiber.NewError(fiber.StatusForbidden, "Google account email is not verified")
+		}
+		if errors.Is(err, usecase.ErrPhoneRequired) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "Phone number is required to create an account")
+		}
+		if errors.Is(err, usecase.ErrUserExists) {
+			return fiber.NewError(fiber.StatusConflict, "Account is already linked to another Google account or phone is in use")
+		}
+		if errors.Is(err, usecase.ErrReactivationRequired) {
+			return reactivationRequired(c)
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Google login failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Login failed")
+	}
+
+	h.userUsecase.DetectLocale(c.UserContext(), resp.UserID, c.Get(fiber.HeaderAcceptLanguage))
+
 	return c.JSON(SuccessResponse{
 		Success: true,
 		Data:    resp,
 	})
 }
 
-// GetMenu handles GET /menu
+// GetMenu handles GET /menu, optionally filtered with ?category=
 func (h *Handlers) GetMenu(c *fiber.Ctx) error {
 	h.log.Info("GetMenu request received", "request_id", logger.GetRequestID(c))
-	menu, err := h.menuUsecase.GetMenu(c.Context())
+
+	if category := c.Query("category"); category != "" {
+		items, err := h.menuUsecase.GetMenuByCategory(c.UserContext(), category)
+		if err != nil {
+			h.log.Error("Failed to fetch menu category", "error", err, "category", category, "request_id", logger.GetRequestID(c))
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch menu")
+		}
+		return c.JSON(SuccessResponse{
+			Success: true,
+			Data:    usecase.MenuResponse{Items: items, Categories: []string{category}},
+		})
+	}
+
+	menu, err := h.menuUsecase.GetMenu(c.UserContext())
 	if err != nil {
 		h.log.Error("Failed to fetch menu", "error", err, "request_id", logger.GetRequestID(c))
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch menu")
@@ -270,7 +475,7 @@ func (h *Handlers) GetMenuItem(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
 	}
 
-	item, err := h.menuUsecase.GetMenuItem(c.Context(), id)
+	item, err := h.menuUsecase.GetMenuItem(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
@@ -295,11 +500,19 @@ func (h *Handlers) CreateMenuItem(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Name, price, and category are required")
 	}
 
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
 	item.CreatedAt = time.Now()
 	item.UpdatedAt = time.Now()
 	item.IsAvailable = true
 
-	if err := h.menuUsecase.CreateMenuItem(c.Context(), &item); err != nil {
+	if err := h.menuUsecase.CreateMenuItem(c.UserContext(), &item, adminID); err != nil {
+		if errors.Is(err, usecase.ErrInvalidIngredients) || errors.Is(err, usecase.ErrInvalidStock) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create menu item")
 	}
 
@@ -321,12 +534,21 @@ func (h *Handlers) UpdateMenuItem(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
+	// The version the admin loaded; a stale one means someone else saved first
+	if item.Version < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Version is required")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
 	item.ID = id
-	item.UpdatedAt = time.Now()
 
-	if err := h.menuUsecase.UpdateMenuItem(c.Context(), &item); err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+	if err := h.menuUsecase.UpdateMenuItem(c.UserContext(), &item, adminID); err != nil {
+		if fe := mapMenuWriteError(err); fe != nil {
+			return fe
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update menu item")
 	}
@@ -344,9 +566,19 @@ func (h *Handlers) DeleteMenuItem(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
 	}
 
-	if err := h.menuUsecase.DeleteMenuItem(c.Context(), id); err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+	version := c.QueryInt("version")
+	if version < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Version is required")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.menuUsecase.DeleteMenuItem(c.UserContext(), id, version, adminID); err != nil {
+		if fe := mapMenuWriteError(err); fe != nil {
+			return fe
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete menu item")
 	}
@@ -357,9 +589,119 @@ func (h *Handlers) DeleteMenuItem(c *fiber.Ctx) error {
 	})
 }
 
+// GetMenuPriceHistory handles GET /admin/menu/:id/price-history
+func (h *Handlers) GetMenuPriceHistory(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+
+	history, err := h.menuUsecase.GetPriceHistory(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load price history")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    history,
+	})
+}
+
+// GetMenuAsOf handles GET /admin/menu/as-of?date=
+// date is a day in the business time zone (the menu at the end of it) or
+// an RFC3339 instant
+func (h *Handlers) GetMenuAsOf(c *fiber.Ctx) error {
+	raw := c.Query("date")
+	at, err := parseExportTime(raw, h.location)
+	if err != nil || raw == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "date must be YYYY-MM-DD or RFC3339")
+	}
+	if _, dayErr := time.ParseInLocation(time.DateOnly, raw, h.location); dayErr == nil {
+		at = at.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if now := time.Now(); at.After(now) && raw == now.In(h.location).Format(time.DateOnly) {
+		at = now // Today so far
+	}
+
+	snapshot, err := h.menuUsecase.GetMenuAsOf(c.UserContext(), at)
+	if err != nil {
+		if errors.Is(err, usecase.ErrMenuAsOfFuture) {
+			return fiber.NewError(fiber.StatusBadRequest, "date is in the future")
+		}
+		h.log.Error("Failed to reconstruct menu", "error", err, "date", raw, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load menu")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    snapshot,
+	})
+}
+
+// mapMenuWriteError converts errors from versioned menu writes to HTTP
+// errors, returning nil for unexpected ones
+func mapMenuWriteError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return fiber.NewError(fiber.StatusConflict, "Menu item was changed by someone else, reload and retry")
+	case errors.Is(err, usecase.ErrInvalidIngredients),
+		errors.Is(err, usecase.ErrInvalidStock):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// MenuAvailabilityRequest switches many menu items on or off
+type MenuAvailabilityRequest struct {
+	ItemIDs     []uuid.UUID `json:"item_ids"`
+	IsAvailable *bool       `json:"is_available"`
+}
+
+// SetMenuAvailability handles PUT /admin/menu/availability
+// Body: {"item_ids": ["uuid", ...], "is_available": false}
+func (h *Handlers) SetMenuAvailability(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req MenuAvailabilityRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.IsAvailable == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "is_available is required")
+	}
+
+	changed, err := h.menuUsecase.SetAvailability(c.UserContext(), req.ItemIDs, *req.IsAvailable, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrAvailabilityItems):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		h.log.Error("Failed to set menu availability", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set menu availability")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"is_available": *req.IsAvailable,
+			"changed":      changed,
+		},
+	})
+}
+
 // InvalidateMenuCache handles POST /admin/menu/invalidate-cache
 func (h *Handlers) InvalidateMenuCache(c *fiber.Ctx) error {
-	if err := h.menuUsecase.InvalidateMenuCache(c.Context()); err != nil {
+	if err := h.menuUsecase.InvalidateMenuCache(c.UserContext()); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to invalidate cache")
 	}
 
@@ -371,7 +713,22 @@ func (h *Handlers) InvalidateMenuCache(c *fiber.Ctx) error {
 
 // CreateOrderRequest for order creation
 type CreateOrderRequest struct {
-	Items []domain.CartItem `json:"items"`
+	Items            []domain.CartItem        `json:"items"`
+	FulfillmentType  domain.FulfillmentType   `json:"fulfillment_type"`  // DELIVERY (default) or PICKUP
+	DeliveryLocation *domain.DeliveryLocation `json:"delivery_location"` // Required for DELIVERY
+	AddressID        *uuid.UUID               `json:"address_id"`        // Saved address instead of delivery_location
+	Recipient        *domain.OrderRecipient   `json:"recipient"`         // Optional gift recipient (DELIVERY only)
+	NoCutlery        bool                     `json:"no_cutlery"`        // Leave out cutlery
+	Packaging        domain.Packaging         `json:"packaging"`         // STANDARD (default), MINIMAL or PLASTIC_FREE
+	ScheduledFor     *time.Time               `json:"scheduled_for"`     // Optional later kitchen slot
+	DisplayedTotal   *int64                   `json:"displayed_total"`   // Optional; with items' displayed_price, checked against current prices
+	ConfirmedTotal   *int64                   `json:"confirmed_total"`   // Accepts a changed total from a price_adjustment
+
+	// Severe allergens from allergen_warnings the customer accepted
+	AcknowledgedAllergens []domain.Allergen `json:"acknowledged_allergens"`
+
+	// Optional delivery window from GET /orders/delivery-slots (DELIVERY only)
+	DeliverySlot *time.Time `json:"delivery_slot"`
 }
 
 // CreateOrder handles POST /orders/create
@@ -391,17 +748,71 @@ func (h *Handlers) CreateOrder(c *fiber.Ctx) error {
 	}
 
 	paymentReq := usecase.InitiateOrderRequest{
-		UserID: userID,
-		Items:  req.Items,
+		UserID:           userID,
+		Items:            req.Items,
+		FulfillmentType:  req.FulfillmentType,
+		DeliveryLocation: req.DeliveryLocation,
+		AddressID:        req.AddressID,
+		Recipient:        req.Recipient,
+		NoCutlery:        req.NoCutlery,
+		Packaging:        req.Packaging,
+		ScheduledFor:     req.ScheduledFor,
+		DisplayedTotal:   req.DisplayedTotal,
+		ConfirmedTotal:   req.ConfirmedTotal,
+		Allergens:        req.AcknowledgedAllergens,
+		DeliverySlot:     req.DeliverySlot,
 	}
 
-	resp, err := h.paymentUsecase.InitiateOrder(c.Context(), paymentReq)
+	resp, err := h.paymentUsecase.InitiateOrder(c.UserContext(), paymentReq)
 	if err != nil {
-		if errors.Is(err, usecase.ErrInvalidCart) {
-			return fiber.NewError(fiber.StatusBadRequest, "Invalid cart")
+		var changed *usecase.PriceChangedError
+		if errors.As(err, &changed) {
+			// The app shows the report and resubmits with confirmed_total
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":            "Prices have changed since your cart was priced",
+				"price_adjustment": changed.Adjustment,
+				"request_id":       logger.GetRequestID(c),
+			})
+		}
+		var allergens *usecase.AllergenAckRequiredError
+		if errors.As(err, &allergens) {
+			// The app shows the warnings and resubmits with acknowledged_allergens
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":             "Your cart contains severe allergens; please acknowledge them",
+				"allergen_warnings": allergens.Warnings,
+				"request_id":        logger.GetRequestID(c),
+			})
+		}
+		var full *usecase.SlotUnavailableError
+		if errors.As(err, &full) {
+			// Offer the next open slots so the app can switch to scheduled ordering
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":          "The kitchen is fully booked for this time",
+				"requested_slot": full.Requested,
+				"next_available": full.NextAvailable,
+				"request_id":     logger.GetRequestID(c),
+			})
+		}
+		var slotFull *usecase.DeliverySlotFullError
+		if errors.As(err, &slotFull) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":          "This delivery slot is fully booked",
+				"requested_slot": slotFull.Requested,
+				"next_available": slotFull.NextAvailable,
+				"request_id":     logger.GetRequestID(c),
+			})
+		}
+		if errors.Is(err, usecase.ErrInvalidSlot) {
+			return fiber.NewError(fiber.StatusBadRequest, "Scheduled time must be within the next 7 days")
+		}
+		if errors.Is(err, usecase.ErrInvalidDeliverySlot) {
+			return fiber.NewError(fiber.StatusBadRequest, "This delivery slot can no longer be booked")
+		}
+		if ferr := mapCartError(err); ferr != nil {
+			return ferr
 		}
-		if errors.Is(err, usecase.ErrItemNotAvailable) {
-			return fiber.NewError(fiber.StatusBadRequest, "One or more items are not available")
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
 		}
 		h.log.Error("Failed to create order", "error", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create order")
@@ -413,21 +824,196 @@ func (h *Handlers) CreateOrder(c *fiber.Ctx) error {
 	})
 }
 
-// GetUserOrders handles GET /orders
+// QuoteOrder handles POST /orders/quote
+// Prices a cart exactly as POST /orders/create would, without creating an
+// order or holding a kitchen slot. Takes the same body.
+func (h *Handlers) QuoteOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if len(req.Items) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Cart is empty")
+	}
+
+	quote, err := h.paymentUsecase.Quote(c.UserContext(), usecase.InitiateOrderRequest{
+		UserID:           userID,
+		Items:            req.Items,
+		FulfillmentType:  req.FulfillmentType,
+		DeliveryLocation: req.DeliveryLocation,
+		AddressID:        req.AddressID,
+		Recipient:        req.Recipient,
+		NoCutlery:        req.NoCutlery,
+		Packaging:        req.Packaging,
+		ScheduledFor:     req.ScheduledFor,
+		DeliverySlot:     req.DeliverySlot,
+	})
+	if err != nil {
+		if ferr := mapCartError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to quote order", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to price cart")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    quote,
+	})
+}
+
+// mapCartError converts cart validation and pricing errors shared by
+// checkout and quotes, returning nil for unexpected ones
+func mapCartError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCart):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid cart")
+	case errors.Is(err, usecase.ErrItemNotAvailable):
+		return fiber.NewError(fiber.StatusBadRequest, "One or more items are not available")
+	case errors.Is(err, usecase.ErrItemSoldOut):
+		return fiber.NewError(fiber.StatusConflict, "One or more items are sold out")
+	case errors.Is(err, usecase.ErrInvalidItemOptions):
+		return fiber.NewError(fiber.StatusBadRequest, "Choose one of the item's sizes and only its add-ons, each once")
+	case errors.Is(err, usecase.ErrInvalidDeliveryLocation):
+		return fiber.NewError(fiber.StatusBadRequest, "Delivery address and coordinates are required")
+	case errors.Is(err, usecase.ErrAddressNotFound):
+		return fiber.NewError(fiber.StatusBadRequest, "Saved address not found")
+	case errors.Is(err, usecase.ErrOutOfDeliveryRange):
+		return fiber.NewError(fiber.StatusBadRequest, "We don't deliver to this address yet")
+	case errors.Is(err, usecase.ErrInvalidFulfillment):
+		return fiber.NewError(fiber.StatusBadRequest, "Fulfillment type must be DELIVERY or PICKUP")
+	case errors.Is(err, usecase.ErrInvalidRecipient):
+		return fiber.NewError(fiber.StatusBadRequest, "Gift orders need delivery and the recipient's name and phone number")
+	case errors.Is(err, usecase.ErrInvalidPackaging):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrDeliverySlotPickup):
+		return fiber.NewError(fiber.StatusBadRequest, "Delivery slots apply to delivery orders only")
+	}
+	return nil
+}
+
+// GetPaymentEvents handles GET /admin/orders/:id/payment-events
+// Every payment transition attempt for the order, oldest first.
+func (h *Handlers) GetPaymentEvents(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	events, err := h.paymentUsecase.GetPaymentEvents(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		h.log.Error("Failed to fetch payment events", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch payment events")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    events,
+	})
+}
+
+// GetPaymentReviews handles GET /admin/orders/payment-reviews
+func (h *Handlers) GetPaymentReviews(c *fiber.Ctx) error {
+	orders, err := h.paymentUsecase.GetOpenPaymentReviews(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to fetch payment reviews", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch payment reviews")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    orders,
+	})
+}
+
+// ResolvePaymentReviewRequest records what the admin did about the mismatch
+type ResolvePaymentReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ResolvePaymentReview handles POST /admin/orders/:id/payment-review/resolve
+func (h *Handlers) ResolvePaymentReview(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req ResolvePaymentReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	order, err := h.paymentUsecase.ResolvePaymentReview(c.UserContext(), adminID, orderID, req.Note)
+	if err != nil {
+		if errors.Is(err, usecase.ErrResolutionNote) {
+			return fiber.NewError(fiber.StatusBadRequest, "A note is required")
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "No open payment review for this order")
+		}
+		h.log.Error("Failed to resolve payment review", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to resolve payment review")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    order,
+	})
+}
+
+// GetUserOrders handles GET /orders?limit=20&offset=0&status=&from=&to=
+// from/to accept a date or RFC3339 time; to is exclusive.
 func (h *Handlers) GetUserOrders(c *fiber.Ctx) error {
 	userID, err := getUserID(c)
 	if err != nil {
 		return err
 	}
 
-	orders, err := h.orderUsecase.GetUserOrders(c.Context(), userID)
+	from, err := parseExportTime(c.Query("from"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "from must be a date (2006-01-02) or RFC3339 time")
+	}
+	to, err := parseExportTime(c.Query("to"), h.location)
 	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "to must be a date (2006-01-02) or RFC3339 time")
+	}
+	filter := repository.OrderListFilter{
+		Status: domain.OrderStatus(c.Query("status")),
+		From:   from,
+		To:     to,
+	}
+
+	page, err := h.orderUsecase.GetUserOrders(c.UserContext(), userID, filter, c.QueryInt("limit"), c.QueryInt("offset"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidOrderFilter) {
+			return fiber.NewError(fiber.StatusBadRequest, "Unknown status or empty date range")
+		}
+		h.log.Error("Failed to fetch user orders", "error", err, "request_id", logger.GetRequestID(c))
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch orders")
 	}
 
+	for i := range page.Orders {
+		hideStaffLabels(&page.Orders[i])
+	}
+
 	return c.JSON(SuccessResponse{
 		Success: true,
-		Data:    orders,
+		Data:    page.Orders,
+		Page:    &PageInfo{Limit: page.Limit, Offset: page.Offset, HasMore: page.HasMore},
 	})
 }
 
@@ -443,7 +1029,7 @@ func (h *Handlers) GetOrder(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
 	}
 
-	order, err := h.orderUsecase.GetOrder(c.Context(), orderID)
+	order, err := h.orderUsecase.GetOrder(c.UserContext(), orderID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Order not found")
@@ -456,6 +1042,13 @@ func (h *Handlers) GetOrder(c *fiber.Ctx) error {
 	if order.UserID != userID && !isAdmin {
 		return fiber.NewError(fiber.StatusForbidden, "Access denied")
 	}
+	if !isAdmin {
+		hideStaffLabels(order)
+	}
+
+	if order.ProofOfDelivery != nil {
+		order.ProofOfDelivery.PhotoURL = proofPhotoURL(order.ID)
+	}
 
 	return c.JSON(SuccessResponse{
 		Success: true,
@@ -470,11 +1063,17 @@ func (h *Handlers) VerifyPayment(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	resp, err := h.paymentUsecase.VerifyPayment(c.Context(), req)
+	resp, err := h.paymentUsecase.VerifyPayment(c.UserContext(), req)
 	if err != nil {
 		if errors.Is(err, usecase.ErrInvalidSignature) {
 			return fiber.NewError(fiber.StatusBadRequest, "Invalid payment signature")
 		}
+		if errors.Is(err, usecase.ErrPaymentMismatch) {
+			return fiber.NewError(fiber.StatusConflict, "Payment does not match this order")
+		}
+		if errors.Is(err, usecase.ErrOrderCancelled) {
+			return fiber.NewError(fiber.StatusConflict, "Order was cancelled; any payment will be refunded")
+		}
 		if errors.Is(err, repository.ErrNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Order not found")
 		}
@@ -487,19 +1086,44 @@ func (h *Handlers) VerifyPayment(c *fiber.Ctx) error {
 	})
 }
 
-// GetAllOrders handles GET /admin/orders
+// GetAllOrders handles GET /admin/orders?limit=50&cursor=&tag=vip,influencer&attr.channel=instagram
+// Follow page.next_cursor for further pages; offset still works but gets
+// slow deep into the list. Orders must carry every tag and attribute asked for.
 func (h *Handlers) GetAllOrders(c *fiber.Ctx) error {
 	limit := c.QueryInt("limit", 50)
 	offset := c.QueryInt("offset", 0)
 
-	orders, err := h.orderUsecase.GetAllOrders(c.Context(), limit, offset)
+	search := usecase.OrderSearch{Attributes: map[string]string{}}
+	if tags := c.Query("tag"); tags != "" {
+		search.Tags = strings.Split(tags, ",")
+	}
+	for key, value := range c.Queries() {
+		if attr, ok := strings.CutPrefix(key, "attr."); ok {
+			search.Attributes[attr] = value
+		}
+	}
+
+	page, err := h.orderUsecase.GetAllOrders(c.UserContext(), search, limit, offset, c.Query("cursor"))
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidCursor) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		if ferr := mapOrderTagError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to fetch orders", "error", err, "request_id", logger.GetRequestID(c))
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch orders")
 	}
 
 	return c.JSON(SuccessResponse{
 		Success: true,
-		Data:    orders,
+		Data:    page.Orders,
+		Page: &PageInfo{
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+			HasMore:    page.HasMore,
+			NextCursor: page.NextCursor,
+		},
 	})
 }
 
@@ -520,8 +1144,13 @@ func (h *Handlers) UpdateOrderStatus(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
 	status := domain.OrderStatus(req.Status)
-	if err := h.orderUsecase.UpdateOrderStatus(c.Context(), orderID, status); err != nil {
+	if err := h.orderUsecase.UpdateOrderStatus(c.UserContext(), orderID, status, adminID); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Order not found")
 		}
@@ -552,7 +1181,7 @@ func (h *Handlers) RazorpayWebhook(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.paymentUsecase.HandleWebhook(c.Context(), body, signature); err != nil {
+	if err := h.paymentUsecase.HandleWebhook(c.UserContext(), body, signature); err != nil {
 		if errors.Is(err, usecase.ErrInvalidSignature) {
 			h.log.Warn("Webhook invalid signature", "signature", signature)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -566,4 +1195,4 @@ func (h *Handlers) RazorpayWebhook(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "ok"})
-}
\ No newline at end of file
+}
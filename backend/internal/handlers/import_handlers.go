@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// ImportOrders handles POST /admin/orders/import
+// Multipart form: file (a .json or .csv legacy export), source, optional
+// aliases (JSON object of legacy name -> menu item name) and dry_run.
+func (h *Handlers) ImportOrders(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Export file is required")
+	}
+
+	opts := usecase.ImportOptions{
+		Source: c.FormValue("source"),
+		DryRun: c.FormValue("dry_run") == "true",
+	}
+	if raw := c.FormValue("aliases"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Aliases); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "aliases must be a JSON object of legacy name to menu item name")
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid file upload")
+	}
+	defer file.Close()
+
+	var orders []usecase.LegacyOrder
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".json":
+		orders, err = usecase.ParseLegacyJSON(file)
+	case ".csv":
+		orders, err = usecase.ParseLegacyCSV(file)
+	default:
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Export must be a .json or .csv file")
+	}
+	if err != nil {
+		return h.mapImportError(c, err)
+	}
+
+	result, err := h.importUsecase.Import(c.UserContext(), orders, opts)
+	if err != nil {
+		return h.mapImportError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// mapImportError converts import usecase errors to HTTP errors
+func (h *Handlers) mapImportError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidImport), errors.Is(err, usecase.ErrInvalidImportSource):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrImportTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	}
+	h.log.Error("Failed to import orders", "error", err, "request_id", logger.GetRequestID(c))
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to import orders")
+}
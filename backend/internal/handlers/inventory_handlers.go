@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// SetStockRequest sets a menu item's stock level; null stops tracking it
+type SetStockRequest struct {
+	StockQuantity *int `json:"stock_quantity"`
+}
+
+// SetMenuItemStock handles PUT /admin/menu/:id/stock
+func (h *Handlers) SetMenuItemStock(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetStockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	item, err := h.inventoryUsecase.SetStock(c.UserContext(), id, req.StockQuantity, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidStock):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		h.log.Error("Failed to set stock", "error", err, "menu_item_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set stock")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    item,
+	})
+}
+
+// SetStockThresholdRequest sets the stock level an item alerts at; null
+// uses the default
+type SetStockThresholdRequest struct {
+	LowStockThreshold *int `json:"low_stock_threshold"`
+}
+
+// SetMenuItemStockThreshold handles PUT /admin/menu/:id/stock-threshold
+func (h *Handlers) SetMenuItemStockThreshold(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetStockThresholdRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.inventoryUsecase.SetLowStockThreshold(c.UserContext(), id, req.LowStockThreshold, adminID); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidStockThreshold):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		h.log.Error("Failed to set low stock threshold", "error", err, "menu_item_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set low stock threshold")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"menu_item_id":        id,
+			"low_stock_threshold": req.LowStockThreshold,
+		},
+	})
+}
+
+// GetStockSummary handles GET /admin/inventory/stock
+// Counts of tracked, low and sold out items, with the low and sold out
+// ones listed lowest first.
+func (h *Handlers) GetStockSummary(c *fiber.Ctx) error {
+	summary, err := h.inventoryUsecase.StockSummary(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to get stock summary", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get stock summary")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// ReportItemShortage handles POST /admin/orders/:id/unavailable-items
+func (h *Handlers) ReportItemShortage(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req usecase.ReportShortageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	shortage, err := h.shortageUsecase.ReportShortage(c.UserContext(), adminID, orderID, req)
+	if err != nil {
+		if fe := mapShortageError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to report item shortage", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to report unavailable items")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    shortage,
+	})
+}
+
+// GetOrderShortages handles GET /admin/orders/:id/shortages
+func (h *Handlers) GetOrderShortages(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	return h.listShortages(c, orderID, nil)
+}
+
+// GetMyOrderShortages handles GET /orders/:id/shortages
+func (h *Handlers) GetMyOrderShortages(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	return h.listShortages(c, orderID, &userID)
+}
+
+func (h *Handlers) listShortages(c *fiber.Ctx, orderID uuid.UUID, userID *uuid.UUID) error {
+	shortages, err := h.shortageUsecase.GetShortages(c.UserContext(), orderID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		h.log.Error("Failed to fetch item shortages", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch unavailable items")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    shortages,
+	})
+}
+
+// ShortageReplyRequest is the customer's answer to missing items
+type ShortageReplyRequest struct {
+	Reply usecase.ShortageReply `json:"reply"`
+}
+
+// ReplyToShortage handles POST /orders/:id/shortage/reply
+func (h *Handlers) ReplyToShortage(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req ShortageReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	shortage, err := h.shortageUsecase.Reply(c.UserContext(), userID, orderID, req.Reply)
+	if err != nil {
+		if fe := mapShortageError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to answer item shortage", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to answer unavailable items")
+	}
+
+	message := "Refund for the unavailable items is on its way"
+	if req.Reply == usecase.ShortageReplyCancel {
+		message = "Order cancelled, a full refund is on its way"
+	}
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    shortage,
+		Message: message,
+	})
+}
+
+// mapShortageError converts known item shortage errors to HTTP errors,
+// returning nil for unexpected ones so callers can log them
+func mapShortageError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrInvalidShortage):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrInvalidShortageReply):
+		return fiber.NewError(fiber.StatusBadRequest, "Reply must be accept or cancel")
+	case errors.Is(err, usecase.ErrShortageNotAllowed):
+		return fiber.NewError(fiber.StatusConflict, "Items can only be reported unavailable on paid orders still in the kitchen")
+	case errors.Is(err, usecase.ErrShortagePending):
+		return fiber.NewError(fiber.StatusConflict, "Order already has unavailable items waiting for the customer")
+	case errors.Is(err, usecase.ErrNoPendingShortage):
+		return fiber.NewError(fiber.StatusConflict, "Order has no unavailable items waiting for an answer")
+	default:
+		return nil
+	}
+}
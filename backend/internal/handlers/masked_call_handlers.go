@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/calls"
+	"fooddelivery/pkg/logger"
+)
+
+// CallCustomer handles POST /rider/orders/:id/call. The provider rings the
+// rider's phone and connects them to the customer.
+func (h *Handlers) CallCustomer(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	call, err := h.callUsecase.CallCustomer(c.UserContext(), orderID, riderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		case errors.Is(err, usecase.ErrNotAssignedRider):
+			return fiber.NewError(fiber.StatusForbidden, "Order is assigned to another rider")
+		case errors.Is(err, usecase.ErrCallUnavailable):
+			return fiber.NewError(fiber.StatusConflict, "Calls are only available while the order is being delivered")
+		case errors.Is(err, usecase.ErrCallInProgress):
+			return fiber.NewError(fiber.StatusConflict, "A call about this order is already in progress")
+		case errors.Is(err, usecase.ErrNoPhoneNumber):
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "No phone number on file to call")
+		case errors.Is(err, usecase.ErrCallFailed):
+			return fiber.NewError(fiber.StatusBadGateway, "Call could not be placed, please try again")
+		}
+		h.log.Error("Failed to call customer", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to place call")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    call,
+		Message: "Your phone will ring shortly",
+	})
+}
+
+// GetOrderCalls handles GET /admin/orders/:id/calls
+func (h *Handlers) GetOrderCalls(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	orderCalls, err := h.callUsecase.GetOrderCalls(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		h.log.Error("Failed to fetch order calls", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch calls")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    orderCalls,
+	})
+}
+
+// CallStatusCallback handles POST /webhooks/calls/:id, posted by the call
+// provider when a call ends. The token in the query string was signed for
+// the call when it was placed.
+func (h *Handlers) CallStatusCallback(c *fiber.Ctx) error {
+	callID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid call ID")
+	}
+
+	form := url.Values{}
+	c.Request().PostArgs().VisitAll(func(key, value []byte) {
+		form.Add(string(key), string(value))
+	})
+	if multipart, err := c.MultipartForm(); err == nil {
+		for key, values := range multipart.Value {
+			form[key] = append(form[key], values...)
+		}
+	}
+
+	err = h.callUsecase.HandleStatusCallback(c.UserContext(), callID, c.Query("token"), form)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidCallToken):
+			h.log.Warn("Call status callback with invalid token", "call_id", callID.String(), "request_id", logger.GetRequestID(c))
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
+		case errors.Is(err, calls.ErrInvalidCallback):
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid callback")
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Call not found")
+		}
+		h.log.Error("Failed to record call status", "error", err, "call_id", callID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record call status")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// MenuOptionsRequest replaces a menu item's variants and modifiers. Entries
+// with an id update that entry; entries without one are added.
+type MenuOptionsRequest struct {
+	Variants  []domain.MenuItemVariant  `json:"variants"`
+	Modifiers []domain.MenuItemModifier `json:"modifiers"`
+}
+
+// SetMenuItemOptions handles PUT /admin/menu/:id/options
+// Variants and modifiers left out of the request are removed.
+func (h *Handlers) SetMenuItemOptions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid menu item ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req MenuOptionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	item, err := h.menuUsecase.SetOptions(c.UserContext(), id, req.Variants, req.Modifiers, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMenuOptions):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item or option not found")
+		}
+		h.log.Error("Failed to set menu item options", "error", err, "menu_item_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to set menu item options")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    item,
+	})
+}
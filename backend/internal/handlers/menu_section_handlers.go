@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// MenuItemIDsRequest lists menu items in the order an admin wants them
+type MenuItemIDsRequest struct {
+	ItemIDs []uuid.UUID `json:"item_ids"`
+}
+
+// SetMenuSection handles PUT /admin/menu/sections/:section
+func (h *Handlers) SetMenuSection(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	section := domain.MenuSectionKey(strings.ToUpper(c.Params("section")))
+
+	var req MenuItemIDsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.menuUsecase.SetSectionItems(c.UserContext(), section, req.ItemIDs, adminID); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMenuSection):
+			return fiber.NewError(fiber.StatusNotFound, "Menu section not found")
+		case errors.Is(err, usecase.ErrMenuSectionItems),
+			errors.Is(err, usecase.ErrMenuSectionUnavailable):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to update menu section", "error", err, "section", string(section), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update menu section")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Menu section updated",
+	})
+}
+
+// ReorderMenu handles PUT /admin/menu/order
+func (h *Handlers) ReorderMenu(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req MenuItemIDsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.menuUsecase.ReorderMenu(c.UserContext(), req.ItemIDs, adminID); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrMenuOrderItems):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Menu item not found")
+		}
+		h.log.Error("Failed to reorder menu", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reorder menu")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Menu order updated",
+	})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// ContextKeyMFA marks a token whose session completed the second factor
+const ContextKeyMFA = "mfa_verified"
+
+// AdminMFAMiddleware blocks admin routes until the session has passed TOTP.
+// Must run after AuthMiddleware and AdminMiddleware.
+func (h *Handlers) AdminMFAMiddleware(c *fiber.Ctx) error {
+	if !h.userUsecase.MFARequired() {
+		return c.Next()
+	}
+
+	verified, _ := c.Locals(ContextKeyMFA).(bool)
+	if !verified {
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor authentication required")
+	}
+	return c.Next()
+}
+
+// GetTOTPStatus handles GET /auth/2fa
+func (h *Handlers) GetTOTPStatus(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	status, err := h.userUsecase.GetTOTPStatus(c.UserContext(), userID)
+	if err != nil {
+		return h.mapMFAError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// BeginTOTPEnrollment handles POST /auth/2fa/enroll
+func (h *Handlers) BeginTOTPEnrollment(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.userUsecase.BeginTOTPEnrollment(c.UserContext(), userID)
+	if err != nil {
+		return h.mapMFAError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// TOTPCodeRequest carries an authenticator code
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// ConfirmTOTPEnrollment handles POST /auth/2fa/enroll/confirm
+func (h *Handlers) ConfirmTOTPEnrollment(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Code is required")
+	}
+
+	resp, err := h.userUsecase.ConfirmTOTPEnrollment(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return h.mapMFAError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// VerifyMFA handles POST /auth/2fa/verify
+func (h *Handlers) VerifyMFA(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.VerifyMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Code or recovery code is required")
+	}
+
+	resp, err := h.userUsecase.VerifyMFA(c.UserContext(), userID, req)
+	if err != nil {
+		return h.mapMFAError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// RegenerateRecoveryCodes handles POST /auth/2fa/recovery-codes
+// Requires an MFA-verified session so a stolen password alone can't mint codes.
+func (h *Handlers) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if verified, _ := c.Locals(ContextKeyMFA).(bool); !verified {
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor authentication required")
+	}
+
+	codes, err := h.userUsecase.RegenerateRecoveryCodes(c.UserContext(), userID)
+	if err != nil {
+		return h.mapMFAError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"recovery_codes": codes},
+	})
+}
+
+// mapMFAError translates two-factor usecase errors to HTTP errors
+func (h *Handlers) mapMFAError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrUserNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	case errors.Is(err, usecase.ErrMFANotAllowed):
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor enrollment is only available for admins")
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled):
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	case errors.Is(err, usecase.ErrMFANotEnrolled):
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication is not enrolled")
+	case errors.Is(err, usecase.ErrInvalidMFACode):
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid code")
+	case errors.Is(err, usecase.ErrTooManyAttempts):
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many attempts, try again later")
+	}
+
+	h.log.Error("Two-factor operation failed", "error", err, "request_id", logger.GetRequestID(c))
+	return fiber.NewError(fiber.StatusInternalServerError, "Two-factor operation failed")
+}
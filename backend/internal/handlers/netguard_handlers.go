@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/netip"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/netguard"
+)
+
+// IPAllowlistMiddleware rejects requests whose client IP is not in allowed.
+// The client IP is resolved through trustedProxies (see netguard.ClientIP).
+// name identifies the guarded route group in logs.
+func IPAllowlistMiddleware(name string, allowed, trustedProxies *netguard.IPList, log *logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		remote, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+		client := netguard.ClientIP(remote, c.Get(fiber.HeaderXForwardedFor), trustedProxies)
+
+		if !allowed.Contains(client) {
+			log.Warn("Request blocked by IP allowlist",
+				"guard", name,
+				"client_ip", client.String(),
+				"path", c.Path(),
+				"request_id", logger.GetRequestID(c),
+			)
+			return fiber.NewError(fiber.StatusForbidden, "Access denied")
+		}
+		return c.Next()
+	}
+}
+
+// ClientCertMiddleware requires a client certificate verified against the
+// configured CA. Only meaningful when the server terminates TLS itself.
+func ClientCertMiddleware(name string, log *logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := netguard.VerifiedClient(c.Context().TLSConnectionState()); !ok {
+			log.Warn("Request blocked without client certificate",
+				"guard", name,
+				"path", c.Path(),
+				"request_id", logger.GetRequestID(c),
+			)
+			return fiber.NewError(fiber.StatusForbidden, "Client certificate required")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// CancelOrderRequest optionally says why an order is cancelled
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CancelOrder handles POST /orders/:id/cancel
+func (h *Handlers) CancelOrder(c *fiber.Ctx) error {
+	return h.cancelOrder(c, false)
+}
+
+// AdminCancelOrder handles POST /admin/orders/:id/cancel
+func (h *Handlers) AdminCancelOrder(c *fiber.Ctx) error {
+	return h.cancelOrder(c, true)
+}
+
+func (h *Handlers) cancelOrder(c *fiber.Ctx, asAdmin bool) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req CancelOrderRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	result, err := h.orderUsecase.CancelOrder(c.UserContext(), userID, orderID, asAdmin, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		case errors.Is(err, usecase.ErrOrderNotCancellable), errors.Is(err, repository.ErrVersionConflict):
+			return fiber.NewError(fiber.StatusConflict, "Order can no longer be cancelled")
+		case errors.Is(err, usecase.ErrCancellationReason):
+			return fiber.NewError(fiber.StatusBadRequest, "Reason must be at most 500 characters")
+		}
+		h.log.Error("Failed to cancel order", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to cancel order")
+	}
+
+	if !asAdmin {
+		hideStaffLabels(result.Order)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    result,
+	})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetOrderChat handles GET /orders/:id/chat
+func (h *Handlers) GetOrderChat(c *fiber.Ctx) error {
+	return h.listOrderChat(c, domain.ChatRoleCustomer)
+}
+
+// SendOrderChat handles POST /orders/:id/chat
+func (h *Handlers) SendOrderChat(c *fiber.Ctx) error {
+	return h.sendOrderChat(c, domain.ChatRoleCustomer)
+}
+
+// GetRiderOrderChat handles GET /rider/orders/:id/chat
+func (h *Handlers) GetRiderOrderChat(c *fiber.Ctx) error {
+	return h.listOrderChat(c, domain.ChatRoleRider)
+}
+
+// SendRiderOrderChat handles POST /rider/orders/:id/chat
+func (h *Handlers) SendRiderOrderChat(c *fiber.Ctx) error {
+	return h.sendOrderChat(c, domain.ChatRoleRider)
+}
+
+// GetAdminOrderChat handles GET /admin/orders/:id/chat
+func (h *Handlers) GetAdminOrderChat(c *fiber.Ctx) error {
+	return h.listOrderChat(c, domain.ChatRoleSupport)
+}
+
+// SendSupportOrderChat handles POST /admin/orders/:id/chat
+func (h *Handlers) SendSupportOrderChat(c *fiber.Ctx) error {
+	return h.sendOrderChat(c, domain.ChatRoleSupport)
+}
+
+func (h *Handlers) listOrderChat(c *fiber.Ctx, role domain.ChatRole) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var after *time.Time
+	if raw := c.Query("after"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "after must be an RFC 3339 timestamp")
+		}
+		after = &t
+	}
+
+	messages, err := h.chatUsecase.List(c.UserContext(), orderID, userID, role, after)
+	if err != nil {
+		if fe := mapOrderChatError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to fetch order chat", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    messages,
+	})
+}
+
+func (h *Handlers) sendOrderChat(c *fiber.Ctx, role domain.ChatRole) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req usecase.SendChatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	message, err := h.chatUsecase.Send(c.UserContext(), orderID, userID, role, req)
+	if err != nil {
+		if fe := mapOrderChatError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to send order chat message", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send message")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    message,
+	})
+}
+
+// mapOrderChatError maps order chat errors to HTTP errors, or nil
+func mapOrderChatError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrNotAssignedRider):
+		return fiber.NewError(fiber.StatusForbidden, "Order is assigned to another rider")
+	case errors.Is(err, usecase.ErrChatClosed):
+		return fiber.NewError(fiber.StatusConflict, "Chat is closed for this order")
+	case errors.Is(err, usecase.ErrInvalidChatMessage):
+		return fiber.NewError(fiber.StatusBadRequest, "Message must be 1-1000 characters")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetOrderIssueOptions handles GET /orders/:id/issues
+// Whether a problem can still be reported, until when, the issue types
+// and anything already reported.
+func (h *Handlers) GetOrderIssueOptions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	options, err := h.issues.GetOptions(c.UserContext(), userID, orderID)
+	if err != nil {
+		if fe := mapIssueError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to get order issue options", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order issues")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    options,
+	})
+}
+
+// ReportOrderIssue handles POST /orders/:id/issues
+// Body: {"type": "MISSING_ITEMS", "items": [{"order_item_id", "quantity"}], "description": ""}
+func (h *Handlers) ReportOrderIssue(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req usecase.ReportIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	issue, err := h.issues.ReportIssue(c.UserContext(), userID, orderID, req)
+	if err != nil {
+		if fe := mapIssueError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to report order issue", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to report the problem")
+	}
+
+	message := "Our team will look into this and get back to you"
+	if issue.Status == domain.IssueAutoResolved {
+		message = "Sorry about that, a refund is on its way"
+	}
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    issue,
+		Message: message,
+	})
+}
+
+// GetOrderIssues handles GET /admin/order-issues?status=&limit=&offset=
+// Newest first; ?status=ESCALATED is the queue waiting for staff.
+func (h *Handlers) GetOrderIssues(c *fiber.Ctx) error {
+	status := domain.OrderIssueStatus(c.Query("status"))
+
+	issues, err := h.issues.ListIssues(c.UserContext(), status, c.QueryInt("limit"), c.QueryInt("offset"))
+	if err != nil {
+		if fe := mapIssueError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to list order issues", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list order issues")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    issues,
+	})
+}
+
+// ResolveOrderIssue handles POST /admin/order-issues/:id/resolve
+// Body: {"refund_amount": 15000, "note": "..."}
+func (h *Handlers) ResolveOrderIssue(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	issueID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid issue ID")
+	}
+
+	var req usecase.ResolveIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	issue, err := h.issues.ResolveIssue(c.UserContext(), adminID, issueID, req)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Issue not found")
+		}
+		if fe := mapIssueError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to resolve order issue", "error", err, "issue_id", issueID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to resolve order issue")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    issue,
+	})
+}
+
+// mapIssueError converts known order issue errors to HTTP errors,
+// returning nil for unexpected ones so callers can log them
+func mapIssueError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrInvalidIssueType),
+		errors.Is(err, usecase.ErrInvalidIssueItems),
+		errors.Is(err, usecase.ErrIssueDescription),
+		errors.Is(err, usecase.ErrInvalidIssueRefund),
+		errors.Is(err, usecase.ErrInvalidIssueStatus),
+		errors.Is(err, usecase.ErrIssueResolutionNote):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrIssueNotAllowed),
+		errors.Is(err, usecase.ErrIssueWindowClosed),
+		errors.Is(err, usecase.ErrIssueAlreadyFiled),
+		errors.Is(err, usecase.ErrIssueNotEscalated),
+		errors.Is(err, usecase.ErrRefundNotAllowed):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/events"
+)
+
+// Live feed connection timing
+const (
+	livePingInterval     = 30 * time.Second
+	liveWriteTimeout     = 10 * time.Second
+	sseHeartbeatInterval = 15 * time.Second
+)
+
+// LiveUpgradeMiddleware admits WebSocket upgrade requests only. Browsers
+// can't set headers on a WebSocket, so the JWT may come as ?access_token=
+// instead of the Authorization header; AuthMiddleware checks it as usual.
+func (h *Handlers) LiveUpgradeMiddleware(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	if c.Get("Authorization") == "" {
+		if token := c.Query("access_token"); token != "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return c.Next()
+}
+
+// OrderFeed handles GET /live/orders (WebSocket)
+// Pushes status changes of the caller's orders as they happen.
+func (h *Handlers) OrderFeed(conn *websocket.Conn) {
+	h.liveFeed(conn, false)
+}
+
+// AdminOrderFeed handles GET /live/admin/orders (WebSocket)
+// Pushes every order's status changes.
+func (h *Handlers) AdminOrderFeed(conn *websocket.Conn) {
+	h.liveFeed(conn, true)
+}
+
+// liveFeed writes the subscription's events to the socket until the client
+// goes away, falls behind, or its token expires. Clients only send pings
+// and the close frame; anything else they send is ignored.
+func (h *Handlers) liveFeed(conn *websocket.Conn, admin bool) {
+	userID, _ := conn.Locals(ContextKeyUserID).(uuid.UUID)
+	sub := h.live.Subscribe(userID, admin)
+	defer h.live.Unsubscribe(sub)
+
+	expiry := make(<-chan time.Time)
+	if claims, ok := conn.Locals(ContextKeyClaims).(*usecase.JWTClaims); ok && claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expiry = timer.C
+	}
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-expiry:
+			h.closeLive(conn, websocket.ClosePolicyViolation, "token expired")
+			return
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				h.closeLive(conn, websocket.CloseTryAgainLater, "too far behind, reconnect")
+				return
+			}
+			if err := conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout)); err != nil {
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// closeLive sends a close frame with a reason before the socket is closed
+func (h *Handlers) closeLive(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(liveWriteTimeout)); err != nil {
+		h.log.Debug("Failed to send live feed close frame", "error", err)
+	}
+}
+
+// OrderEvents handles GET /orders/:id/events (Server-Sent Events)
+// The fallback for clients that can't use the WebSocket feed: streams one
+// order's current status, then each change as it happens, with a comment
+// line every 15s as a heartbeat. The stream ends once the order is
+// delivered or cancelled, or when the caller's token expires.
+func (h *Handlers) OrderEvents(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	// Subscribe before reading the order so no change falls between the two
+	sub := h.live.SubscribeOrder(orderID)
+	order, err := h.orderUsecase.GetOrder(c.UserContext(), orderID)
+	if err != nil {
+		h.live.Unsubscribe(sub)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch order")
+	}
+
+	isAdmin, _ := c.Locals(ContextKeyIsAdmin).(bool)
+	if order.UserID != userID && !isAdmin {
+		h.live.Unsubscribe(sub)
+		return fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	var expiresAt time.Time
+	if claims, ok := c.Locals(ContextKeyClaims).(*usecase.JWTClaims); ok && claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no") // Don't let nginx hold events back
+
+	// The server's write timeout covers the whole response, so the stream
+	// extends the deadline before each write instead
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.live.Unsubscribe(sub)
+		h.streamOrderEvents(&sseStream{conn: conn, w: w}, sub, order, expiresAt)
+	})
+	return nil
+}
+
+// streamOrderEvents writes the order's status and then its events until
+// the order is final, the subscription is dropped, the token expires or a
+// write fails because the client has gone
+func (h *Handlers) streamOrderEvents(stream *sseStream, sub *usecase.LiveSubscription, order *domain.Order, expiresAt time.Time) {
+	current, _ := json.Marshal(map[string]interface{}{
+		"order_id": order.ID,
+		"status":   order.Status,
+		"version":  order.Version,
+	})
+	if err := stream.send("", "order.status", current); err != nil || order.Status.IsFinal() {
+		return
+	}
+
+	expiry := make(<-chan time.Time)
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expiry = timer.C
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-expiry:
+			_ = stream.send("", "close", []byte(`{"reason":"token expired"}`))
+			return
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				_ = stream.send("", "close", []byte(`{"reason":"too far behind, reconnect"}`))
+				return
+			}
+			var event events.Event
+			if err := json.Unmarshal(msg, &event); err != nil {
+				continue
+			}
+			if err := stream.send(event.ID, event.Type, event.Payload); err != nil {
+				return
+			}
+			var payload struct {
+				Status domain.OrderStatus `json:"status"`
+			}
+			if json.Unmarshal(event.Payload, &payload) == nil && payload.Status.IsFinal() {
+				return
+			}
+		case <-heartbeat.C:
+			if err := stream.write(": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseStream writes Server-Sent Events to a streamed response body
+type sseStream struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// send writes one event; data must be a single line, as compact JSON is
+func (s *sseStream) send(id, event string, data []byte) error {
+	msg := fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+	if id != "" {
+		msg = "id: " + id + "\n" + msg
+	}
+	return s.write(msg)
+}
+
+// write writes and flushes raw stream text. A failed flush means the
+// client has gone away.
+func (s *sseStream) write(text string) error {
+	if s.conn != nil {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout)); err != nil {
+			return err
+		}
+	}
+	if _, err := s.w.WriteString(text); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
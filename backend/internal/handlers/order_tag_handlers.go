@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// OrderAttributeDefinitionRequest defines an order attribute
+type OrderAttributeDefinitionRequest struct {
+	Type        domain.OrderAttributeType `json:"type"`
+	Description string                    `json:"description"`
+}
+
+// UpdateOrderTags handles POST /admin/orders/:id/tags
+func (h *Handlers) UpdateOrderTags(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req usecase.OrderTagsUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	tags, err := h.orderUsecase.UpdateOrderTags(c.UserContext(), adminID, orderID, req)
+	if err != nil {
+		if ferr := mapOrderTagError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to update order tags", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update tags")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"order_id": orderID, "tags": tags},
+	})
+}
+
+// UpdateOrderAttributes handles POST /admin/orders/:id/attributes
+func (h *Handlers) UpdateOrderAttributes(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req usecase.OrderAttributesUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	attributes, err := h.orderUsecase.SetOrderAttributes(c.UserContext(), adminID, orderID, req)
+	if err != nil {
+		if ferr := mapOrderTagError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to update order attributes", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update attributes")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"order_id": orderID, "attributes": attributes},
+	})
+}
+
+// GetOrderAttributeDefinitions handles GET /admin/order-attributes
+func (h *Handlers) GetOrderAttributeDefinitions(c *fiber.Ctx) error {
+	definitions, err := h.orderUsecase.GetOrderAttributeDefinitions(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to fetch order attribute definitions", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch attributes")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    definitions,
+	})
+}
+
+// DefineOrderAttribute handles PUT /admin/order-attributes/:key
+func (h *Handlers) DefineOrderAttribute(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req OrderAttributeDefinitionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	definition, err := h.orderUsecase.DefineOrderAttribute(c.UserContext(), adminID, c.Params("key"), req.Type, req.Description)
+	if err != nil {
+		if errors.Is(err, usecase.ErrAttributeTypeConflict) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		if ferr := mapOrderTagError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to define order attribute", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to define attribute")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    definition,
+	})
+}
+
+// mapOrderTagError converts tag and attribute errors to HTTP errors,
+// returning nil for unexpected ones so callers can log them
+func mapOrderTagError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrInvalidOrderTag),
+		errors.Is(err, usecase.ErrTooManyOrderTags),
+		errors.Is(err, usecase.ErrUnknownOrderAttribute),
+		errors.Is(err, usecase.ErrInvalidOrderAttribute),
+		errors.Is(err, usecase.ErrInvalidAttributeKey),
+		errors.Is(err, usecase.ErrInvalidAttributeType),
+		errors.Is(err, usecase.ErrNoOrderLabelChanges):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// hideStaffLabels strips tags and attributes from an order shown to its
+// customer; they are for staff only
+func hideStaffLabels(order *domain.Order) {
+	order.Tags = nil
+	order.Attributes = nil
+}
+
+// TestOrderRequest reclassifies an order
+type TestOrderRequest struct {
+	Test *bool `json:"test"`
+}
+
+// SetTestOrder handles PUT /admin/orders/:id/test
+func (h *Handlers) SetTestOrder(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req TestOrderRequest
+	if err := c.BodyParser(&req); err != nil || req.Test == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "test (true or false) is required")
+	}
+
+	tags, err := h.orderUsecase.SetTestOrder(c.UserContext(), adminID, orderID, *req.Test)
+	if err != nil {
+		if ferr := mapOrderTagError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Failed to reclassify order", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reclassify order")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"order_id": orderID, "test": *req.Test, "tags": tags},
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetPickupCode handles GET /orders/:id/pickup-code
+func (h *Handlers) GetPickupCode(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	resp, err := h.orderUsecase.GetPickupCode(c.UserContext(), orderID, userID)
+	if err != nil {
+		if fe := mapPickupError(err); fe != nil {
+			return fe
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch pickup code")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// VerifyPickup handles POST /admin/orders/pickup/verify
+func (h *Handlers) VerifyPickup(c *fiber.Ctx) error {
+	staffID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.VerifyPickupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.QRPayload == "" && (req.OrderID == uuid.Nil || req.Code == "") {
+		return fiber.NewError(fiber.StatusBadRequest, "Provide qr_payload or order_id and code")
+	}
+
+	order, err := h.orderUsecase.VerifyPickup(c.UserContext(), req, staffID)
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return fiber.NewError(fiber.StatusConflict, "Order was updated concurrently, please retry")
+		}
+		if fe := mapPickupError(err); fe != nil {
+			return fe
+		}
+		h.log.Error("Failed to verify pickup", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to verify pickup")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    order,
+		Message: "Pickup verified, order handed over",
+	})
+}
+
+// mapPickupError converts known pickup errors to HTTP errors, returning nil
+// for unexpected ones so callers can log them
+func mapPickupError(err error) *fiber.Error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrNotPickupOrder):
+		return fiber.NewError(fiber.StatusBadRequest, "Order is not a pickup order")
+	case errors.Is(err, usecase.ErrPickupNotReady):
+		return fiber.NewError(fiber.StatusConflict, "Order is not ready for pickup")
+	case errors.Is(err, usecase.ErrInvalidPickupCode):
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Invalid pickup code")
+	case errors.Is(err, usecase.ErrTooManyAttempts):
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many attempts, try again later")
+	default:
+		return nil
+	}
+}
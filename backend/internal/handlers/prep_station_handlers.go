@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// stationError maps prep station usecase errors to HTTP errors
+func stationError(err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrStationNotFound),
+		errors.Is(err, usecase.ErrTicketNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	case errors.Is(err, usecase.ErrInvalidStation),
+		errors.Is(err, usecase.ErrInvalidStationCat):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrStationNameTaken),
+		errors.Is(err, usecase.ErrStationCategoryTaken),
+		errors.Is(err, usecase.ErrStationBusy),
+		errors.Is(err, usecase.ErrTicketNotQueued),
+		errors.Is(err, usecase.ErrTicketAlreadyReady),
+		errors.Is(err, usecase.ErrOrderNotInKitchen):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return nil
+}
+
+// GetPrepStations handles GET /admin/kitchen/stations
+func (h *Handlers) GetPrepStations(c *fiber.Ctx) error {
+	stations, err := h.stations.ListStations(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to list prep stations", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list prep stations")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    stations,
+	})
+}
+
+// CreatePrepStation handles POST /admin/kitchen/stations
+// Body: {"name": "Grill", "categories": ["Burgers", "Kebabs"], "is_default": false, "sort_order": 1}
+func (h *Handlers) CreatePrepStation(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.StationInput
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	station, err := h.stations.CreateStation(c.UserContext(), adminID, req)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to create prep station", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create prep station")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    station,
+	})
+}
+
+// UpdatePrepStation handles PUT /admin/kitchen/stations/:id
+func (h *Handlers) UpdatePrepStation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.StationInput
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	station, err := h.stations.UpdateStation(c.UserContext(), adminID, id, req)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to update prep station", "error", err, "station_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update prep station")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    station,
+	})
+}
+
+// DeletePrepStation handles DELETE /admin/kitchen/stations/:id
+func (h *Handlers) DeletePrepStation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.stations.DeleteStation(c.UserContext(), adminID, id); err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to delete prep station", "error", err, "station_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete prep station")
+	}
+
+	return c.JSON(SuccessResponse{Success: true})
+}
+
+// GetStationQueue handles GET /admin/kitchen/stations/:id/queue
+// The station's KDS view: its open tickets, oldest first.
+func (h *Handlers) GetStationQueue(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station ID")
+	}
+
+	tickets, err := h.stations.StationQueue(c.UserContext(), id)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to get station queue", "error", err, "station_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get station queue")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    tickets,
+	})
+}
+
+// StartStationTicket handles POST /admin/kitchen/tickets/:id/start
+func (h *Handlers) StartStationTicket(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid ticket ID")
+	}
+
+	ticket, err := h.stations.StartTicket(c.UserContext(), id)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to start station ticket", "error", err, "ticket_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start ticket")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    ticket,
+	})
+}
+
+// ReadyStationTicket handles POST /admin/kitchen/tickets/:id/ready
+// Returns the order's readiness across stations.
+func (h *Handlers) ReadyStationTicket(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid ticket ID")
+	}
+
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	readiness, err := h.stations.ReadyTicket(c.UserContext(), adminID, id)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to mark station ticket ready", "error", err, "ticket_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to mark ticket ready")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    readiness,
+	})
+}
+
+// GetKitchenExpo handles GET /admin/kitchen/expo
+// Every order in the kitchen with its station tickets and whether all are
+// ready, oldest first.
+func (h *Handlers) GetKitchenExpo(c *fiber.Ctx) error {
+	orders, err := h.stations.KitchenReadiness(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to get kitchen readiness", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get kitchen readiness")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    orders,
+	})
+}
+
+// GetOrderStations handles GET /admin/orders/:id/stations
+func (h *Handlers) GetOrderStations(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	readiness, err := h.stations.OrderReadiness(c.UserContext(), orderID)
+	if err != nil {
+		h.log.Error("Failed to get order readiness", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order readiness")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    readiness,
+	})
+}
+
+// RouteOrderToStations handles POST /admin/orders/:id/route
+// Routes an accepted order again; stations that have its ticket keep it.
+func (h *Handlers) RouteOrderToStations(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	readiness, err := h.stations.RouteOrder(c.UserContext(), orderID)
+	if err != nil {
+		if mapped := stationError(err); mapped != nil {
+			return mapped
+		}
+		h.log.Error("Failed to route order to stations", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to route order")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    readiness,
+	})
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+)
+
+// RiderHeartbeatRequest optionally carries the rider's position
+type RiderHeartbeatRequest struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// KitchenHeartbeatRequest identifies a kitchen display
+type KitchenHeartbeatRequest struct {
+	ScreenID string `json:"screen_id"`
+	Name     string `json:"name"`
+}
+
+// RiderHeartbeat handles POST /rider/heartbeat
+func (h *Handlers) RiderHeartbeat(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req RiderHeartbeatRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	var location *geo.Point
+	if req.Latitude != nil || req.Longitude != nil {
+		if req.Latitude == nil || req.Longitude == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "latitude and longitude go together")
+		}
+		location = &geo.Point{Latitude: *req.Latitude, Longitude: *req.Longitude}
+	}
+
+	if err := h.presenceUsecase.RiderHeartbeat(c.UserContext(), riderID, location); err != nil {
+		if errors.Is(err, usecase.ErrInvalidLocation) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to record rider heartbeat", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record heartbeat")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RiderOffline handles POST /rider/offline
+func (h *Handlers) RiderOffline(c *fiber.Ctx) error {
+	riderID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.presenceUsecase.GoOffline(c.UserContext(), usecase.PresenceRider, riderID.String()); err != nil {
+		h.log.Error("Failed to mark rider offline", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to go offline")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// KitchenScreenHeartbeat handles POST /admin/kitchen/heartbeat
+func (h *Handlers) KitchenScreenHeartbeat(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req KitchenHeartbeatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.presenceUsecase.KitchenScreenHeartbeat(c.UserContext(), userID, req.ScreenID, req.Name); err != nil {
+		if errors.Is(err, usecase.ErrInvalidScreenID) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to record kitchen screen heartbeat", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record heartbeat")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPresence handles GET /admin/presence
+func (h *Handlers) GetPresence(c *fiber.Ctx) error {
+	riders, err := h.presenceUsecase.Online(c.UserContext(), usecase.PresenceRider)
+	if err != nil {
+		h.log.Error("Failed to list online riders", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load presence")
+	}
+	screens, err := h.presenceUsecase.Online(c.UserContext(), usecase.PresenceKitchenScreen)
+	if err != nil {
+		h.log.Error("Failed to list online kitchen screens", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load presence")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"riders":          riders,
+			"kitchen_screens": screens,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// RefundRequest refunds part of an order, or what's left of it when
+// amount is omitted
+type RefundRequest struct {
+	Amount int64  `json:"amount"` // Paisa
+	Reason string `json:"reason"`
+}
+
+// InitiateRefund handles POST /admin/orders/:id/refunds
+func (h *Handlers) InitiateRefund(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req RefundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	refund, err := h.paymentUsecase.InitiateRefund(c.UserContext(), adminID, orderID, req.Amount, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		case errors.Is(err, usecase.ErrRefundReason), errors.Is(err, usecase.ErrInvalidRefundAmount):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, usecase.ErrRefundNotAllowed), errors.Is(err, usecase.ErrNothingToRefund):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, repository.ErrRefundExceedsPayment):
+			return fiber.NewError(fiber.StatusConflict, "Refund is more than is left of the payment")
+		case errors.Is(err, usecase.ErrRefundRejected):
+			h.log.Warn("Refund rejected by gateway", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+			return fiber.NewError(fiber.StatusBadGateway, "Razorpay rejected the refund; see the order's refunds for the reason")
+		}
+		h.log.Error("Failed to refund order", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to refund order")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{
+		Success: true,
+		Data:    refund,
+	})
+}
+
+// GetOrderRefunds handles GET /admin/orders/:id/refunds
+func (h *Handlers) GetOrderRefunds(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	refunds, err := h.paymentUsecase.ListRefunds(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		h.log.Error("Failed to fetch refunds", "error", err, "order_id", orderID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch refunds")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    refunds,
+	})
+}
+
+// GetRefundStatus handles GET /admin/refunds/:id
+func (h *Handlers) GetRefundStatus(c *fiber.Ctx) error {
+	refundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid refund ID")
+	}
+
+	refund, err := h.paymentUsecase.GetRefundStatus(c.UserContext(), refundID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Refund not found")
+		}
+		h.log.Error("Failed to fetch refund", "error", err, "refund_id", refundID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch refund")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    refund,
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestLimits bounds how long a request may run and how large its body
+// may be. Multipart uploads (delivery proof photos, order imports) get their
+// own, larger limits.
+type RequestLimits struct {
+	Timeout        time.Duration
+	UploadTimeout  time.Duration
+	MaxBodyBytes   int
+	MaxUploadBytes int
+}
+
+// ServerBodyLimit is the hard cap for the server config; requests above it
+// are rejected before any handler runs
+func (l RequestLimits) ServerBodyLimit() int {
+	return max(l.MaxBodyBytes, l.MaxUploadBytes)
+}
+
+// RequestLimitsMiddleware rejects oversized bodies with 413 and gives each
+// request a deadline through its user context, so database and Redis calls
+// made with c.UserContext() are cancelled when it passes. A request that
+// runs out of time answers 408.
+func RequestLimitsMiddleware(limits RequestLimits) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout, maxBytes := limits.Timeout, limits.MaxBodyBytes
+		if isUpload(c) {
+			timeout, maxBytes = limits.UploadTimeout, limits.MaxUploadBytes
+		}
+
+		if maxBytes > 0 && len(c.Request().Body()) > maxBytes {
+			return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Request body is too large")
+		}
+
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// A handler that finished cleanly just before the deadline keeps its
+		// response; anything that failed after it is reported as a timeout
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError) {
+			c.Response().ResetBody()
+			return fiber.NewError(fiber.StatusRequestTimeout, "Request timed out")
+		}
+		return err
+	}
+}
+
+func isUpload(c *fiber.Ctx) bool {
+	return strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// ContextKeyClaims holds the access token's claims, for logout
+const ContextKeyClaims = "token_claims"
+
+// RefreshToken handles POST /auth/refresh
+// Exchanges a refresh token for a new access token and refresh token.
+func (h *Handlers) RefreshToken(c *fiber.Ctx) error {
+	var req usecase.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.RefreshToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Refresh token is required")
+	}
+
+	tokens, err := h.userUsecase.Refresh(c.UserContext(), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRefreshToken) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired refresh token")
+		}
+		if ferr := mapAccountError(err); ferr != nil {
+			return ferr
+		}
+		h.log.Error("Token refresh failed", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to refresh token")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    tokens,
+	})
+}
+
+// Logout handles POST /auth/logout
+// Revokes the access token in the Authorization header and the refresh
+// token in the body, if any.
+func (h *Handlers) Logout(c *fiber.Ctx) error {
+	claims, ok := c.Locals(ContextKeyClaims).(*usecase.JWTClaims)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req usecase.RefreshRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	if err := h.userUsecase.Logout(c.UserContext(), claims, req.RefreshToken); err != nil {
+		h.log.Error("Logout failed", "error", err, "user_id", claims.UserID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to log out")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Logged out",
+	})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetSettings handles GET /admin/settings
+func (h *Handlers) GetSettings(c *fiber.Ctx) error {
+	settings, err := h.settingsUsecase.GetAll(c.UserContext())
+	if err != nil {
+		h.log.Error("Failed to load settings", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load settings")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// GetSetting handles GET /admin/settings/:key
+func (h *Handlers) GetSetting(c *fiber.Ctx) error {
+	setting, err := h.settingsUsecase.Get(c.UserContext(), c.Params("key"))
+	if err != nil {
+		return h.mapSettingsError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    setting,
+	})
+}
+
+// UpdateSettingRequest carries the new value, typed as the setting is
+type UpdateSettingRequest struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// UpdateSetting handles PUT /admin/settings/:key
+func (h *Handlers) UpdateSetting(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateSettingRequest
+	if err := c.BodyParser(&req); err != nil || len(req.Value) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "value is required")
+	}
+
+	setting, err := h.settingsUsecase.Update(c.UserContext(), c.Params("key"), req.Value, adminID)
+	if err != nil {
+		return h.mapSettingsError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    setting,
+	})
+}
+
+// ResetSetting handles DELETE /admin/settings/:key
+// Removes the override so the environment default applies again.
+func (h *Handlers) ResetSetting(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	setting, err := h.settingsUsecase.Reset(c.UserContext(), c.Params("key"), adminID)
+	if err != nil {
+		return h.mapSettingsError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    setting,
+	})
+}
+
+// GetSettingHistory handles GET /admin/settings/:key/history?limit=50
+func (h *Handlers) GetSettingHistory(c *fiber.Ctx) error {
+	changes, err := h.settingsUsecase.History(c.UserContext(), c.Params("key"), c.QueryInt("limit", 50))
+	if err != nil {
+		return h.mapSettingsError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    changes,
+	})
+}
+
+// mapSettingsError converts settings usecase errors to HTTP errors
+func (h *Handlers) mapSettingsError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrUnknownSetting):
+		return fiber.NewError(fiber.StatusNotFound, "Setting not found")
+	case errors.Is(err, usecase.ErrInvalidSettingValue):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrSettingNotOverridden):
+		return fiber.NewError(fiber.StatusConflict, "Setting is already at its default")
+	}
+	h.log.Error("Settings request failed", "error", err, "request_id", logger.GetRequestID(c))
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to process setting")
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetOpenSurveys handles GET /surveys
+// Returns the caller's unanswered post-delivery surveys.
+func (h *Handlers) GetOpenSurveys(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	surveys, err := h.surveyUsecase.GetOpenSurveys(c.UserContext(), userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch surveys")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    surveys,
+	})
+}
+
+// SubmitFeedbackRequest is a survey answer
+type SubmitFeedbackRequest struct {
+	Score   *int   `json:"score"` // 0-10
+	Comment string `json:"comment"`
+}
+
+// SubmitOrderFeedback handles POST /orders/:id/feedback
+func (h *Handlers) SubmitOrderFeedback(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req SubmitFeedbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Score == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Score is required")
+	}
+
+	survey, err := h.surveyUsecase.Respond(c.UserContext(), orderID, userID, *req.Score, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrSurveyNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "No feedback request for this order")
+		case errors.Is(err, usecase.ErrSurveyAnswered):
+			return fiber.NewError(fiber.StatusConflict, "Feedback already submitted")
+		case errors.Is(err, usecase.ErrInvalidSurveyScore), errors.Is(err, usecase.ErrSurveyCommentLength):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		h.log.Error("Failed to save feedback", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save feedback")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    survey,
+		Message: "Thanks for your feedback",
+	})
+}
+
+// GetNPS handles GET /admin/analytics/nps?days=30
+func (h *Handlers) GetNPS(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 365 {
+		return fiber.NewError(fiber.StatusBadRequest, "days must be between 1 and 365")
+	}
+
+	summary, err := h.surveyUsecase.GetNPS(c.UserContext(), days)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to compute NPS")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+// GetSurveyAlerts handles GET /admin/surveys/alerts
+// Low-score responses linked to their orders; ?all=true includes acknowledged ones.
+func (h *Handlers) GetSurveyAlerts(c *fiber.Ctx) error {
+	alerts, err := h.surveyUsecase.ListAlerts(c.UserContext(), c.QueryBool("all", false), 100)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch survey alerts")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    alerts,
+	})
+}
+
+// AcknowledgeSurveyAlert handles POST /admin/surveys/:id/acknowledge
+func (h *Handlers) AcknowledgeSurveyAlert(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	surveyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid survey ID")
+	}
+
+	if err := h.surveyUsecase.AcknowledgeAlert(c.UserContext(), surveyID, adminID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Survey response not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to acknowledge alert")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Message: "Alert acknowledged",
+	})
+}
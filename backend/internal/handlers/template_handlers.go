@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetTemplates handles GET /admin/templates
+func (h *Handlers) GetTemplates(c *fiber.Ctx) error {
+	templates, err := h.templateUsecase.List(c.UserContext())
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    templates,
+	})
+}
+
+// GetTemplate handles GET /admin/templates/:key/:locale
+// Returns the template that would be used, after locale fallback.
+func (h *Handlers) GetTemplate(c *fiber.Ctx) error {
+	template, err := h.templateUsecase.Get(c.UserContext(), c.Params("key"), c.Params("locale"))
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// UpdateTemplate handles PUT /admin/templates/:key/:locale
+func (h *Handlers) UpdateTemplate(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req usecase.TemplateText
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	template, err := h.templateUsecase.Update(c.UserContext(), c.Params("key"), c.Params("locale"), req, adminID)
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// ResetTemplate handles DELETE /admin/templates/:key/:locale
+// Removes the override so the embedded default applies again.
+func (h *Handlers) ResetTemplate(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	template, err := h.templateUsecase.Reset(c.UserContext(), c.Params("key"), c.Params("locale"), adminID)
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// PreviewTemplateRequest renders a stored template, or the draft subject and
+// body when given. Variables override the template's sample values.
+type PreviewTemplateRequest struct {
+	Locale    string         `json:"locale"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Variables map[string]any `json:"variables"`
+}
+
+func (r PreviewTemplateRequest) draft() *usecase.TemplateText {
+	if r.Body == "" {
+		return nil
+	}
+	return &usecase.TemplateText{Subject: r.Subject, Body: r.Body}
+}
+
+// PreviewTemplate handles POST /admin/templates/:key/preview
+func (h *Handlers) PreviewTemplate(c *fiber.Ctx) error {
+	var req PreviewTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	msg, err := h.templateUsecase.Preview(c.UserContext(), c.Params("key"), req.Locale, req.draft(), req.Variables)
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    msg,
+	})
+}
+
+// TestSendTemplate handles POST /admin/templates/:key/test-send
+// Sends the rendered template to the calling admin's own devices.
+func (h *Handlers) TestSendTemplate(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req PreviewTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	msg, err := h.templateUsecase.TestSend(c.UserContext(), c.Params("key"), req.Locale, req.draft(), req.Variables, adminID)
+	if err != nil {
+		return h.mapTemplateError(c, err)
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    msg,
+		Message: "Test message sent",
+	})
+}
+
+// mapTemplateError converts template usecase errors to HTTP errors
+func (h *Handlers) mapTemplateError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrUnknownTemplate):
+		return fiber.NewError(fiber.StatusNotFound, "Template not found")
+	case errors.Is(err, usecase.ErrInvalidTemplate),
+		errors.Is(err, usecase.ErrInvalidLocale),
+		errors.Is(err, usecase.ErrNoTemplateSender):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrTemplateNotOverridden):
+		return fiber.NewError(fiber.StatusConflict, "Template is already at its default")
+	}
+	h.log.Error("Template request failed", "error", err, "request_id", logger.GetRequestID(c))
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to process template")
+}
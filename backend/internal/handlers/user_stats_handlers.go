@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetUserDetail handles GET /admin/users/:id
+// The user's profile with their order count, net spend, days since their
+// last order and churn flag.
+func (h *Handlers) GetUserDetail(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	detail, err := h.userUsecase.GetUserDetail(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		h.log.Error("Failed to get user detail", "error", err, "user_id", userID.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get user")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    detail,
+	})
+}
+
+// GetUserSegment handles GET /admin/users/segment?churn_risk=&min_orders=&min_spend=&inactive_days=&active_days=&limit=&offset=
+// Customers matching every given filter, most recently ordered first.
+// churn_risk=true selects regulars who have gone quiet.
+func (h *Handlers) GetUserSegment(c *fiber.Ctx) error {
+	seg := usecase.UserSegment{
+		ChurnRisk:        c.QueryBool("churn_risk"),
+		MinOrders:        c.QueryInt("min_orders"),
+		MinSpend:         int64(c.QueryInt("min_spend")),
+		InactiveDays:     c.QueryInt("inactive_days"),
+		ActiveWithinDays: c.QueryInt("active_days"),
+		Limit:            c.QueryInt("limit"),
+		Offset:           c.QueryInt("offset"),
+	}
+	if seg.MinOrders < 0 || seg.MinSpend < 0 || seg.InactiveDays < 0 || seg.ActiveWithinDays < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Segment filters can't be negative")
+	}
+
+	members, err := h.userUsecase.ListUserSegment(c.UserContext(), seg)
+	if err != nil {
+		h.log.Error("Failed to list user segment", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list users")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    members,
+	})
+}
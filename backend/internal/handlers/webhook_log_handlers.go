@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/internal/usecase"
+	"fooddelivery/pkg/logger"
+)
+
+// GetWebhookLogs handles GET /admin/webhooks?order_id=&status=&event=&since=&until=&limit=&offset=
+// Logged webhooks, newest first. status is processed, unprocessed, retrying
+// or failed; since and until take a date (YYYY-MM-DD) or RFC 3339 time.
+func (h *Handlers) GetWebhookLogs(c *fiber.Ctx) error {
+	filter := repository.WebhookLogFilter{
+		Status:    domain.WebhookLogStatus(c.Query("status")),
+		EventType: c.Query("event"),
+		Limit:     c.QueryInt("limit"),
+		Offset:    c.QueryInt("offset"),
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return fiber.NewError(fiber.StatusBadRequest, "status must be processed, unprocessed, retrying or failed")
+	}
+
+	if raw := c.Query("order_id"); raw != "" {
+		orderID, err := uuid.Parse(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+		}
+		filter.OrderID = &orderID
+	}
+
+	since, err := parseExportTime(c.Query("since"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "since must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if !since.IsZero() {
+		filter.Since = &since
+	}
+	until, err := parseExportTime(c.Query("until"), h.location)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "until must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if !until.IsZero() {
+		filter.Until = &until
+	}
+
+	logs, err := h.paymentUsecase.ListWebhookLogs(c.UserContext(), filter)
+	if err != nil {
+		h.log.Error("Failed to list webhook logs", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list webhook logs")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    logs,
+	})
+}
+
+// GetWebhookLog handles GET /admin/webhooks/:id
+func (h *Handlers) GetWebhookLog(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook log ID")
+	}
+
+	wl, err := h.paymentUsecase.GetWebhookLog(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrWebhookLogNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Webhook log not found")
+		}
+		h.log.Error("Failed to get webhook log", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get webhook log")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    wl,
+	})
+}
+
+// GetOrderWebhookLogs handles GET /admin/orders/:id/webhooks
+func (h *Handlers) GetOrderWebhookLogs(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	logs, err := h.paymentUsecase.GetOrderWebhookLogs(c.UserContext(), orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		h.log.Error("Failed to list order webhook logs", "error", err, "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list webhook logs")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    logs,
+	})
+}
+
+// ReplayWebhook handles POST /admin/webhooks/:id/replay
+// Runs the webhook through its handler again and returns the log with the
+// outcome; a failed replay is a 200 with processing_error set.
+func (h *Handlers) ReplayWebhook(c *fiber.Ctx) error {
+	adminID, err := getUserID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook log ID")
+	}
+
+	wl, err := h.paymentUsecase.ReplayWebhook(c.UserContext(), adminID, id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrWebhookLogNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Webhook log not found")
+		}
+		if errors.Is(err, usecase.ErrWebhookNotReplayable) {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		h.log.Error("Failed to replay webhook", "error", err, "webhook_log_id", id.String(), "request_id", logger.GetRequestID(c))
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to replay webhook")
+	}
+
+	return c.JSON(SuccessResponse{
+		Success: true,
+		Data:    wl,
+	})
+}
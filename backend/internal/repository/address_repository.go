@@ -0,0 +1,256 @@
+// Package repository implements saved address data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// ErrAddressLimit is returned when a user already has the most saved
+// addresses allowed
+var ErrAddressLimit = errors.New("address book is full")
+
+// AddressRepository handles saved address persistence. Every lookup is
+// scoped to the owning user, so another user's address reads as not found.
+type AddressRepository struct {
+	db *database.Pool
+}
+
+// NewAddressRepository creates a new address repository
+func NewAddressRepository(db *database.Pool) *AddressRepository {
+	return &AddressRepository{db: db}
+}
+
+// addressColumns is the column list matching scanAddress
+const addressColumns = `id, user_id, label, line1, COALESCE(line2, ''), COALESCE(landmark, ''),
+		COALESCE(city, ''), COALESCE(postal_code, ''), latitude, longitude,
+		COALESCE(instructions, ''), is_default, created_at, updated_at`
+
+// scanAddress scans a row selected with addressColumns
+func scanAddress(row pgx.Row) (*domain.Address, error) {
+	address := &domain.Address{}
+	err := row.Scan(
+		&address.ID,
+		&address.UserID,
+		&address.Label,
+		&address.Line1,
+		&address.Line2,
+		&address.Landmark,
+		&address.City,
+		&address.PostalCode,
+		&address.Latitude,
+		&address.Longitude,
+		&address.Instructions,
+		&address.IsDefault,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// Create saves a new address. A user's first address becomes their
+// default; a new default replaces the old one. Returns ErrAddressLimit if
+// the user already has limit addresses. Creates for one user are
+// serialized on the user row so two can't both pass the limit.
+func (r *AddressRepository) Create(ctx context.Context, address *domain.Address, limit int) error {
+	address.ID = uuid.New()
+	address.CreatedAt = time.Now()
+	address.UpdatedAt = address.CreatedAt
+
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var locked int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, address.UserID).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to lock user for address: %w", err)
+		}
+
+		var count int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM user_addresses WHERE user_id = $1`, address.UserID).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count addresses: %w", err)
+		}
+		if count >= limit {
+			return ErrAddressLimit
+		}
+		if count == 0 {
+			address.IsDefault = true
+		}
+
+		if address.IsDefault {
+			if err := clearDefaultAddress(ctx, tx, address.UserID); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_addresses (id, user_id, label, line1, line2, landmark, city, postal_code,
+				latitude, longitude, instructions, is_default, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		`,
+			address.ID,
+			address.UserID,
+			address.Label,
+			address.Line1,
+			nullableString(address.Line2),
+			nullableString(address.Landmark),
+			nullableString(address.City),
+			nullableString(address.PostalCode),
+			address.Latitude,
+			address.Longitude,
+			nullableString(address.Instructions),
+			address.IsDefault,
+			address.CreatedAt,
+			address.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create address: %w", mapPgError(err))
+		}
+		return nil
+	})
+}
+
+// Update changes an address's details. Which address is the default is
+// changed with SetDefault.
+func (r *AddressRepository) Update(ctx context.Context, address *domain.Address) error {
+	query := `
+		UPDATE user_addresses
+		SET label = $3, line1 = $4, line2 = $5, landmark = $6, city = $7, postal_code = $8,
+			latitude = $9, longitude = $10, instructions = $11
+		WHERE id = $1 AND user_id = $2
+		RETURNING is_default, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		address.ID,
+		address.UserID,
+		address.Label,
+		address.Line1,
+		nullableString(address.Line2),
+		nullableString(address.Landmark),
+		nullableString(address.City),
+		nullableString(address.PostalCode),
+		address.Latitude,
+		address.Longitude,
+		nullableString(address.Instructions),
+	).Scan(&address.IsDefault, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update address: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// SetDefault makes an address the user's default
+func (r *AddressRepository) SetDefault(ctx context.Context, userID, id uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		if err := clearDefaultAddress(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `UPDATE user_addresses SET is_default = TRUE WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to set default address: %w", mapPgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// Delete removes an address. If it was the default, the most recently
+// added remaining address becomes the default.
+func (r *AddressRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var wasDefault bool
+		err := tx.QueryRow(ctx, `
+			DELETE FROM user_addresses WHERE id = $1 AND user_id = $2
+			RETURNING is_default
+		`, id, userID).Scan(&wasDefault)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to delete address: %w", err)
+		}
+		if !wasDefault {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE user_addresses SET is_default = TRUE
+			WHERE id = (
+				SELECT id FROM user_addresses WHERE user_id = $1
+				ORDER BY created_at DESC, id LIMIT 1
+			)
+		`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to promote default address: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByID retrieves one of a user's addresses
+func (r *AddressRepository) GetByID(ctx context.Context, userID, id uuid.UUID) (*domain.Address, error) {
+	address, err := scanAddress(r.db.QueryRow(ctx, `
+		SELECT `+addressColumns+` FROM user_addresses WHERE id = $1 AND user_id = $2
+	`, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	return address, nil
+}
+
+// ListByUser returns a user's addresses, the default first and then newest
+// first
+func (r *AddressRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+addressColumns+`
+		FROM user_addresses
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := []domain.Address{}
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		addresses = append(addresses, *address)
+	}
+
+	return addresses, rows.Err()
+}
+
+// clearDefaultAddress unsets the user's default address, if any, so
+// another can take its place under the one-default index
+func clearDefaultAddress(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `UPDATE user_addresses SET is_default = FALSE WHERE user_id = $1 AND is_default`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear default address: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package repository implements aggregate queries for ops analytics
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// OrderBucket aggregates the orders created in one time bucket
+type OrderBucket struct {
+	Start   time.Time
+	Orders  int
+	Revenue int64 // Paisa from orders that were paid
+	Failed  int   // Orders whose payment failed
+}
+
+// AnalyticsRepository runs read-only aggregate queries
+type AnalyticsRepository struct {
+	db *database.Pool
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *database.Pool) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// GetOrderBuckets groups orders created in [from, to) into buckets of the
+// given size, aligned to from so buckets follow the caller's local clock
+// (e.g. hours in a half-hour offset zone). Empty buckets are not returned.
+// Imported orders and orders tagged as tests are left out.
+func (r *AnalyticsRepository) GetOrderBuckets(ctx context.Context, from, to time.Time, bucket time.Duration) ([]OrderBucket, error) {
+	query := `
+		SELECT
+			$1::timestamptz + (floor(extract(epoch FROM created_at - $1::timestamptz) / $3) * $3)::float8 * interval '1 second' AS bucket,
+			COUNT(*),
+			COALESCE(SUM(total_amount) FILTER (WHERE status::text = ANY($4)), 0),
+			COUNT(*) FILTER (WHERE status::text = $5)
+		FROM orders
+		WHERE created_at >= $1 AND created_at < $2 AND imported_at IS NULL
+			AND NOT ($6 = ANY(tags))
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := r.db.Query(ctx, query, from, to, int64(bucket.Seconds()), paidStatuses(), string(domain.OrderStatusPaymentFailed), domain.OrderTagTest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []OrderBucket
+	for rows.Next() {
+		var b OrderBucket
+		if err := rows.Scan(&b.Start, &b.Orders, &b.Revenue, &b.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan order bucket: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// ItemSalesBucket aggregates the paid sales of one menu item in a time bucket
+type ItemSalesBucket struct {
+	Start   time.Time
+	Units   int
+	Revenue int64 // Paisa charged, at the prices snapshotted on the orders
+}
+
+// GetItemDailySales groups paid sales of a menu item from orders created
+// in [from, to) by calendar day in loc. Start is local midnight. Empty days
+// are not returned. Test orders are left out.
+func (r *AnalyticsRepository) GetItemDailySales(ctx context.Context, itemID uuid.UUID, from, to time.Time, loc *time.Location) ([]ItemSalesBucket, error) {
+	query := `
+		SELECT
+			date_trunc('day', o.created_at AT TIME ZONE $4) AT TIME ZONE $4 AS bucket,
+			SUM(oi.quantity),
+			SUM(oi.quantity::BIGINT * oi.price)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.menu_item_id = $1
+		  AND o.created_at >= $2 AND o.created_at < $3
+		  AND o.imported_at IS NULL
+		  AND NOT ($6 = ANY(o.tags))
+		  AND o.status::text = ANY($5)
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := r.db.Query(ctx, query, itemID, from, to, loc.String(), paidStatuses(), domain.OrderTagTest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query item sales: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []ItemSalesBucket
+	for rows.Next() {
+		var b ItemSalesBucket
+		if err := rows.Scan(&b.Start, &b.Units, &b.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan item sales bucket: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// PackagingCount counts the paid orders packed one way
+type PackagingCount struct {
+	Packaging domain.Packaging
+	Orders    int
+	NoCutlery int // Of those, how many asked for no cutlery
+}
+
+// GetPackagingCounts counts paid orders created in [from, to) by packaging
+// and cutlery choice. Imported orders and orders tagged as tests are left
+// out.
+func (r *AnalyticsRepository) GetPackagingCounts(ctx context.Context, from, to time.Time) ([]PackagingCount, error) {
+	query := `
+		SELECT packaging, COUNT(*), COUNT(*) FILTER (WHERE no_cutlery)
+		FROM orders
+		WHERE created_at >= $1 AND created_at < $2 AND imported_at IS NULL
+			AND NOT ($4 = ANY(tags))
+			AND status::text = ANY($3)
+		GROUP BY packaging
+	`
+
+	rows, err := r.db.Query(ctx, query, from, to, paidStatuses(), domain.OrderTagTest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packaging counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []PackagingCount
+	for rows.Next() {
+		var c PackagingCount
+		if err := rows.Scan(&c.Packaging, &c.Orders, &c.NoCutlery); err != nil {
+			return nil, fmt.Errorf("failed to scan packaging count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+// paidStatuses lists the statuses of orders that were paid for
+func paidStatuses() []string {
+	return []string{
+		string(domain.OrderStatusPaid),
+		string(domain.OrderStatusAccepted),
+		string(domain.OrderStatusOutForDelivery),
+		string(domain.OrderStatusDelivered),
+	}
+}
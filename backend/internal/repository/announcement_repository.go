@@ -0,0 +1,208 @@
+// Package repository implements in-app announcement data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// AnnouncementRepository handles in-app banners and their stats
+type AnnouncementRepository struct {
+	db *database.Pool
+}
+
+// NewAnnouncementRepository creates a new announcement repository
+func NewAnnouncementRepository(db *database.Pool) *AnnouncementRepository {
+	return &AnnouncementRepository{db: db}
+}
+
+// announcementColumns is the column list matching scanAnnouncement
+const announcementColumns = `id, title, COALESCE(body, ''), COALESCE(image_url, ''), COALESCE(deep_link, ''),
+	audience, priority, starts_at, ends_at, is_active, created_by, created_at, updated_at`
+
+// scanAnnouncement scans a row selected with announcementColumns, followed
+// by any extra destinations
+func scanAnnouncement(row pgx.Row, extra ...any) (*domain.Announcement, error) {
+	a := &domain.Announcement{}
+	dest := append([]any{
+		&a.ID,
+		&a.Title,
+		&a.Body,
+		&a.ImageURL,
+		&a.DeepLink,
+		&a.Audience,
+		&a.Priority,
+		&a.StartsAt,
+		&a.EndsAt,
+		&a.IsActive,
+		&a.CreatedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Create inserts a banner
+func (r *AnnouncementRepository) Create(ctx context.Context, a *domain.Announcement) error {
+	query := `
+		INSERT INTO announcements (id, title, body, image_url, deep_link, audience, priority, starts_at, ends_at, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING created_at, updated_at
+	`
+
+	a.ID = uuid.New()
+	err := r.db.QueryRow(ctx, query, a.ID, a.Title, nullableString(a.Body), nullableString(a.ImageURL), nullableString(a.DeepLink),
+		a.Audience, a.Priority, a.StartsAt, a.EndsAt, a.IsActive, a.CreatedBy,
+	).Scan(&a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create announcement: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// Update replaces a banner's content, audience and window
+func (r *AnnouncementRepository) Update(ctx context.Context, a *domain.Announcement) error {
+	query := `
+		UPDATE announcements
+		SET title = $2, body = $3, image_url = $4, deep_link = $5, audience = $6, priority = $7,
+			starts_at = $8, ends_at = $9, is_active = $10
+		WHERE id = $1
+		RETURNING created_by, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, a.ID, a.Title, nullableString(a.Body), nullableString(a.ImageURL), nullableString(a.DeepLink),
+		a.Audience, a.Priority, a.StartsAt, a.EndsAt, a.IsActive,
+	).Scan(&a.CreatedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update announcement: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// Delete removes a banner and its stats
+func (r *AnnouncementRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete announcement: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByID retrieves a banner
+func (r *AnnouncementRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Announcement, error) {
+	a, err := scanAnnouncement(r.db.QueryRow(ctx, `SELECT `+announcementColumns+` FROM announcements WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get announcement: %w", err)
+	}
+	return a, nil
+}
+
+// ListCurrent returns the active banners that haven't ended by now,
+// including ones that start later, highest priority first
+func (r *AnnouncementRepository) ListCurrent(ctx context.Context, now time.Time) ([]domain.Announcement, error) {
+	query := `
+		SELECT ` + announcementColumns + `
+		FROM announcements
+		WHERE is_active AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY priority DESC, starts_at DESC, id
+	`
+
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+	defer rows.Close()
+
+	announcements := []domain.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan announcement: %w", err)
+		}
+		announcements = append(announcements, *a)
+	}
+
+	return announcements, rows.Err()
+}
+
+// ListWithStats returns every banner with its impressions and clicks since
+// the given day, newest first
+func (r *AnnouncementRepository) ListWithStats(ctx context.Context, since time.Time) ([]domain.AnnouncementWithStats, error) {
+	query := `
+		SELECT ` + announcementColumns + `, COALESCE(s.impressions, 0), COALESCE(s.clicks, 0)
+		FROM announcements
+		LEFT JOIN (
+			SELECT announcement_id, SUM(impressions) AS impressions, SUM(clicks) AS clicks
+			FROM announcement_stats
+			WHERE day >= $1
+			GROUP BY announcement_id
+		) s ON s.announcement_id = announcements.id
+		ORDER BY created_at DESC, id
+	`
+
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+	defer rows.Close()
+
+	list := []domain.AnnouncementWithStats{}
+	for rows.Next() {
+		var s domain.AnnouncementWithStats
+		a, err := scanAnnouncement(rows, &s.Impressions, &s.Clicks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan announcement: %w", err)
+		}
+		s.Announcement = *a
+		if s.Impressions > 0 {
+			s.ClickRate = float64(s.Clicks) / float64(s.Impressions)
+		}
+		list = append(list, s)
+	}
+
+	return list, rows.Err()
+}
+
+// RecordEvents adds impressions and clicks to day's counts. Each ID counts
+// once per time it's listed; unknown banners are ignored.
+func (r *AnnouncementRepository) RecordEvents(ctx context.Context, day time.Time, impressions, clicks []uuid.UUID) error {
+	query := `
+		INSERT INTO announcement_stats (announcement_id, day, impressions, clicks)
+		SELECT e.id, $1, COUNT(*) FILTER (WHERE e.kind = 'impression'), COUNT(*) FILTER (WHERE e.kind = 'click')
+		FROM (
+			SELECT unnest($2::uuid[]) AS id, 'impression' AS kind
+			UNION ALL
+			SELECT unnest($3::uuid[]), 'click'
+		) e
+		JOIN announcements a ON a.id = e.id
+		GROUP BY e.id
+		ON CONFLICT (announcement_id, day) DO UPDATE
+		SET impressions = announcement_stats.impressions + EXCLUDED.impressions,
+			clicks = announcement_stats.clicks + EXCLUDED.clicks
+	`
+
+	if _, err := r.db.Exec(ctx, query, day, impressions, clicks); err != nil {
+		return fmt.Errorf("failed to record announcement events: %w", err)
+	}
+	return nil
+}
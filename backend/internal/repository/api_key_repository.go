@@ -0,0 +1,208 @@
+// Package repository implements partner API key data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// APIKeyRepository handles API key persistence
+type APIKeyRepository struct {
+	db *database.Pool
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *database.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, key_prefix, key_hash, scopes, rate_limit_per_minute, is_active,
+		created_by, rotated_from, last_used_at, revoked_at, created_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	var scopes []string
+
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.KeyPrefix,
+		&key.KeyHash,
+		&scopes,
+		&key.RateLimitPerMinute,
+		&key.IsActive,
+		&key.CreatedBy,
+		&key.RotatedFrom,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]domain.APIKeyScope, len(scopes))
+	for i, s := range scopes {
+		key.Scopes[i] = domain.APIKeyScope(s)
+	}
+
+	return key, nil
+}
+
+// scopeStrings converts scopes for storage in a TEXT[] column
+func scopeStrings(scopes []domain.APIKeyScope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// Create inserts a new API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	return insertAPIKey(ctx, r.db, key)
+}
+
+// insertAPIKey inserts a key using either the pool or a transaction
+func insertAPIKey(ctx context.Context, q database.Querier, key *domain.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, name, key_prefix, key_hash, scopes, rate_limit_per_minute, is_active, created_by, rotated_from, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	key.ID = uuid.New()
+	_, err := q.Exec(ctx, query,
+		key.ID,
+		key.Name,
+		key.KeyPrefix,
+		key.KeyHash,
+		scopeStrings(key.Scopes),
+		key.RateLimitPerMinute,
+		key.IsActive,
+		key.CreatedBy,
+		key.RotatedFrom,
+		key.CreatedAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByID retrieves an API key by ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetActiveByHash retrieves an active API key by its hash (authentication path)
+func (r *APIKeyRepository) GetActiveByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND is_active = TRUE`
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key by hash: %w", err)
+	}
+
+	return key, nil
+}
+
+// List retrieves all API keys, newest first
+func (r *APIKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke deactivates an API key
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE api_keys
+		SET is_active = FALSE, revoked_at = NOW()
+		WHERE id = $1 AND is_active = TRUE
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Rotate revokes the old key and inserts its replacement atomically,
+// so a partner never ends up with zero or two valid keys for one integration
+func (r *APIKeyRepository) Rotate(ctx context.Context, oldID uuid.UUID, replacement *domain.APIKey) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			UPDATE api_keys
+			SET is_active = FALSE, revoked_at = NOW()
+			WHERE id = $1 AND is_active = TRUE
+		`
+
+		result, err := tx.Exec(ctx, query, oldID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		replacement.RotatedFrom = &oldID
+		return insertAPIKey(ctx, tx, replacement)
+	})
+}
+
+// TouchLastUsed records when a key was last used
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update API key last used: %w", mapPgError(err))
+	}
+	return nil
+}
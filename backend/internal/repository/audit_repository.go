@@ -0,0 +1,117 @@
+// Package repository implements the admin audit log
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// AuditFilter narrows the admin activity feed. Zero values match everything.
+type AuditFilter struct {
+	Since   time.Time
+	ActorID *uuid.UUID
+	Actions []domain.AuditAction
+	Limit   int
+	Offset  int
+}
+
+// AuditRepository handles audit log persistence
+type AuditRepository struct {
+	db *database.Pool
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *database.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record appends an entry to the audit log
+func (r *AuditRepository) Record(ctx context.Context, entry *domain.AuditEntry) error {
+	entry.ID = uuid.New()
+	entry.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO admin_audit_log (id, actor_id, action, entity_type, entity_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entry.ID, entry.ActorID, entry.Action, entry.EntityType, entry.EntityID, entry.Details, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// List returns matching entries newest first, with the actor's name
+func (r *AuditRepository) List(ctx context.Context, f AuditFilter) ([]domain.AuditEntry, error) {
+	query := `
+		SELECT a.id, a.actor_id, COALESCE(u.name, ''), a.action, a.entity_type, a.entity_id, a.details, a.created_at
+		FROM admin_audit_log a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE a.created_at >= $1
+		  AND ($2::uuid IS NULL OR a.actor_id = $2)
+		  AND (cardinality($3::text[]) = 0 OR a.action = ANY($3))
+		ORDER BY a.created_at DESC, a.id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(ctx, query, f.Since, f.ActorID, auditActions(f.Actions), f.Limit, f.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var e domain.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.EntityType, &e.EntityID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// CountByAction counts matching entries per action, ignoring Limit and Offset
+func (r *AuditRepository) CountByAction(ctx context.Context, f AuditFilter) (map[domain.AuditAction]int, error) {
+	query := `
+		SELECT action, COUNT(*)
+		FROM admin_audit_log
+		WHERE created_at >= $1
+		  AND ($2::uuid IS NULL OR actor_id = $2)
+		  AND (cardinality($3::text[]) = 0 OR action = ANY($3))
+		GROUP BY action
+	`
+
+	rows, err := r.db.Query(ctx, query, f.Since, f.ActorID, auditActions(f.Actions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.AuditAction]int)
+	for rows.Next() {
+		var action domain.AuditAction
+		var n int
+		if err := rows.Scan(&action, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan audit count: %w", err)
+		}
+		counts[action] = n
+	}
+
+	return counts, rows.Err()
+}
+
+func auditActions(actions []domain.AuditAction) []string {
+	out := make([]string, len(actions))
+	for i, a := range actions {
+		out[i] = string(a)
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+// Package repository implements kitchen capacity data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// CapacityRepository handles kitchen capacity rules and slot bookings
+type CapacityRepository struct {
+	db *database.Pool
+}
+
+// NewCapacityRepository creates a new capacity repository
+func NewCapacityRepository(db *database.Pool) *CapacityRepository {
+	return &CapacityRepository{db: db}
+}
+
+// ListRules returns all capacity rules
+func (r *CapacityRepository) ListRules(ctx context.Context) ([]domain.KitchenCapacityRule, error) {
+	query := `
+		SELECT id, day_of_week, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+			orders_per_slot, updated_by, created_at
+		FROM kitchen_capacity_rules
+		ORDER BY day_of_week NULLS FIRST, start_time
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capacity rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []domain.KitchenCapacityRule
+	for rows.Next() {
+		var rule domain.KitchenCapacityRule
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.DayOfWeek,
+			&rule.StartTime,
+			&rule.EndTime,
+			&rule.OrdersPerSlot,
+			&rule.UpdatedBy,
+			&rule.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan capacity rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// ReplaceRules swaps the whole rule set in one transaction
+func (r *CapacityRepository) ReplaceRules(ctx context.Context, rules []domain.KitchenCapacityRule) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM kitchen_capacity_rules`); err != nil {
+			return fmt.Errorf("failed to clear capacity rules: %w", err)
+		}
+
+		query := `
+			INSERT INTO kitchen_capacity_rules (id, day_of_week, start_time, end_time, orders_per_slot, updated_by, created_at)
+			VALUES ($1, $2, $3::time, $4::time, $5, $6, $7)
+		`
+
+		now := time.Now()
+		for i := range rules {
+			rules[i].ID = uuid.New()
+			rules[i].CreatedAt = now
+
+			if _, err := tx.Exec(ctx, query,
+				rules[i].ID,
+				rules[i].DayOfWeek,
+				rules[i].StartTime,
+				rules[i].EndTime,
+				rules[i].OrdersPerSlot,
+				rules[i].UpdatedBy,
+				rules[i].CreatedAt,
+			); err != nil {
+				return fmt.Errorf("failed to insert capacity rule: %w", mapPgError(err))
+			}
+		}
+
+		return nil
+	})
+}
+
+// BookSlot takes one order from the slot if fewer than capacity are booked.
+// The conditional upsert keeps concurrent checkouts from overbooking.
+func (r *CapacityRepository) BookSlot(ctx context.Context, slotStart time.Time, capacity int) (bool, error) {
+	query := `
+		INSERT INTO kitchen_slot_bookings (slot_start, booked)
+		SELECT $1, 1
+		WHERE $2 > 0
+		ON CONFLICT (slot_start) DO UPDATE
+		SET booked = kitchen_slot_bookings.booked + 1
+		WHERE kitchen_slot_bookings.booked < $2
+		RETURNING booked
+	`
+
+	var booked int
+	err := r.db.QueryRow(ctx, query, slotStart, capacity).Scan(&booked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to book kitchen slot: %w", mapPgError(err))
+	}
+
+	return true, nil
+}
+
+// ReleaseSlot gives back a booking, e.g. when payment setup fails
+func (r *CapacityRepository) ReleaseSlot(ctx context.Context, slotStart time.Time) error {
+	query := `
+		UPDATE kitchen_slot_bookings
+		SET booked = booked - 1
+		WHERE slot_start = $1 AND booked > 0
+	`
+
+	if _, err := r.db.Exec(ctx, query, slotStart); err != nil {
+		return fmt.Errorf("failed to release kitchen slot: %w", err)
+	}
+
+	return nil
+}
+
+// GetBookings returns booked counts for slots in [from, to)
+func (r *CapacityRepository) GetBookings(ctx context.Context, from, to time.Time) (map[time.Time]int, error) {
+	query := `
+		SELECT slot_start, booked
+		FROM kitchen_slot_bookings
+		WHERE slot_start >= $1 AND slot_start < $2
+	`
+
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slot bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := make(map[time.Time]int)
+	for rows.Next() {
+		var slot time.Time
+		var booked int
+		if err := rows.Scan(&slot, &booked); err != nil {
+			return nil, fmt.Errorf("failed to scan slot booking: %w", err)
+		}
+		bookings[slot.UTC()] = booked
+	}
+
+	return bookings, rows.Err()
+}
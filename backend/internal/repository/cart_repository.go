@@ -0,0 +1,167 @@
+// Package repository implements saved cart data access
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/redis"
+)
+
+// CartRepository keeps signed-in users' carts in Postgres with Redis as a
+// read-through cache. Writes go to Postgres first, so a Redis outage or
+// eviction never loses a cart; reads fall back to Postgres on a cache miss
+// or Redis error.
+type CartRepository struct {
+	db    *database.Pool
+	cache *redis.Client // Optional
+}
+
+// NewCartRepository creates a new cart repository
+func NewCartRepository(db *database.Pool, cache *redis.Client) *CartRepository {
+	return &CartRepository{db: db, cache: cache}
+}
+
+// cachedCart is the Redis value of a user's cart
+type cachedCart struct {
+	Items     []domain.CartItem `json:"items"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Get returns the user's cart lines, empty if they have no cart
+func (r *CartRepository) Get(ctx context.Context, userID uuid.UUID) ([]domain.CartItem, error) {
+	if items, ok := r.cached(ctx, userID); ok {
+		return items, nil
+	}
+
+	var raw []byte
+	var updatedAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT items, updated_at FROM user_carts WHERE user_id = $1`, userID).Scan(&raw, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []domain.CartItem{}, nil
+		}
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+	items, err := decodeCartItems(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, userID, items, updatedAt)
+	return items, nil
+}
+
+// Update changes the user's cart with fn under a row lock, so concurrent
+// changes from several devices apply one after the other. fn gets the
+// current lines and returns the new ones; an error from fn aborts the
+// change and is returned as is.
+func (r *CartRepository) Update(ctx context.Context, userID uuid.UUID, fn func([]domain.CartItem) ([]domain.CartItem, error)) ([]domain.CartItem, error) {
+	var items []domain.CartItem
+	var updatedAt time.Time
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `INSERT INTO user_carts (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to create cart: %w", mapPgError(err))
+		}
+
+		var current []domain.CartItem
+		if tag.RowsAffected() == 1 {
+			// Carts saved before they were kept in Postgres are only in Redis
+			current, _ = r.cached(ctx, userID)
+		} else {
+			var raw []byte
+			if err := tx.QueryRow(ctx, `SELECT items FROM user_carts WHERE user_id = $1 FOR UPDATE`, userID).Scan(&raw); err != nil {
+				return fmt.Errorf("failed to lock cart: %w", err)
+			}
+			if current, err = decodeCartItems(raw); err != nil {
+				return err
+			}
+		}
+		if current == nil {
+			current = []domain.CartItem{}
+		}
+
+		if items, err = fn(current); err != nil {
+			return err
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			return fmt.Errorf("failed to encode cart: %w", err)
+		}
+		err = tx.QueryRow(ctx, `
+			UPDATE user_carts SET items = $2, updated_at = NOW()
+			WHERE user_id = $1
+			RETURNING updated_at
+		`, userID, data).Scan(&updatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save cart: %w", mapPgError(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(ctx, userID, items, updatedAt)
+	return items, nil
+}
+
+// Delete removes the user's cart
+func (r *CartRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM user_carts WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+	if r.cache != nil {
+		if err := r.cache.DeleteKey(ctx, redis.UserCartPrefix+userID.String()); err != nil {
+			return fmt.Errorf("failed to delete cached cart: %w", err)
+		}
+	}
+	return nil
+}
+
+// cached reads the user's cart from Redis. Misses and Redis errors both
+// report false, so callers go to Postgres.
+func (r *CartRepository) cached(ctx context.Context, userID uuid.UUID) ([]domain.CartItem, bool) {
+	if r.cache == nil {
+		return nil, false
+	}
+	var cart cachedCart
+	found, err := r.cache.GetJSON(ctx, redis.UserCartPrefix+userID.String(), &cart)
+	if err != nil || !found {
+		return nil, false
+	}
+	if cart.Items == nil {
+		cart.Items = []domain.CartItem{}
+	}
+	return cart.Items, true
+}
+
+// store caches the user's cart. If the cart can't be cached the stale copy
+// is dropped instead, so reads go to Postgres.
+func (r *CartRepository) store(ctx context.Context, userID uuid.UUID, items []domain.CartItem, updatedAt time.Time) {
+	if r.cache == nil {
+		return
+	}
+	key := redis.UserCartPrefix + userID.String()
+	if err := r.cache.SetJSON(ctx, key, cachedCart{Items: items, UpdatedAt: updatedAt}, redis.UserCartTTL); err != nil {
+		_ = r.cache.DeleteKey(ctx, key)
+	}
+}
+
+// decodeCartItems decodes the items column
+func decodeCartItems(raw []byte) ([]domain.CartItem, error) {
+	items := []domain.CartItem{}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode cart: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,168 @@
+// Package repository implements delivery batch persistence
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// DeliveryBatchRepository handles multi-order rider batches
+type DeliveryBatchRepository struct {
+	db *database.Pool
+}
+
+// NewDeliveryBatchRepository creates a new delivery batch repository
+func NewDeliveryBatchRepository(db *database.Pool) *DeliveryBatchRepository {
+	return &DeliveryBatchRepository{db: db}
+}
+
+// Create inserts a batch with its stops in a transaction
+func (r *DeliveryBatchRepository) Create(ctx context.Context, batch *domain.DeliveryBatch) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		batch.ID = uuid.New()
+		batch.CreatedAt = time.Now()
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO delivery_batches (id, rider_id, total_distance_meters, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, batch.ID, batch.RiderID, batch.TotalDistanceMeters, batch.CreatedBy, batch.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert delivery batch: %w", mapPgError(err))
+		}
+
+		stopQuery := `
+			INSERT INTO delivery_batch_stops (batch_id, order_id, sequence, leg_distance_meters, estimated_arrival_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		for _, stop := range batch.Stops {
+			_, err := tx.Exec(ctx, stopQuery,
+				batch.ID,
+				stop.OrderID,
+				stop.Sequence,
+				stop.LegDistanceMeters,
+				stop.EstimatedArrivalAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert batch stop: %w", mapPgError(err))
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetActiveByRider returns the rider's most recent batch that still has an
+// order out for delivery
+func (r *DeliveryBatchRepository) GetActiveByRider(ctx context.Context, riderID uuid.UUID) (*domain.DeliveryBatch, error) {
+	query := `
+		SELECT b.id, b.rider_id, b.total_distance_meters, b.created_by, b.created_at
+		FROM delivery_batches b
+		WHERE b.rider_id = $1
+			AND EXISTS (
+				SELECT 1 FROM delivery_batch_stops s
+				JOIN orders o ON o.id = s.order_id
+				WHERE s.batch_id = b.id AND o.status = $2
+			)
+		ORDER BY b.created_at DESC
+		LIMIT 1
+	`
+
+	batch := &domain.DeliveryBatch{}
+	err := r.db.QueryRow(ctx, query, riderID, domain.OrderStatusOutForDelivery).Scan(
+		&batch.ID,
+		&batch.RiderID,
+		&batch.TotalDistanceMeters,
+		&batch.CreatedBy,
+		&batch.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get active batch: %w", err)
+	}
+
+	stops, err := r.getStops(ctx, batch.ID)
+	if err != nil {
+		return nil, err
+	}
+	batch.Stops = stops
+
+	return batch, nil
+}
+
+// GetBusyRiders returns the riders with an order still out for delivery
+func (r *DeliveryBatchRepository) GetBusyRiders(ctx context.Context) (map[uuid.UUID]bool, error) {
+	query := `
+		SELECT DISTINCT b.rider_id
+		FROM delivery_batches b
+		JOIN delivery_batch_stops s ON s.batch_id = b.id
+		JOIN orders o ON o.id = s.order_id
+		WHERE o.status = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.OrderStatusOutForDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query busy riders: %w", err)
+	}
+	defer rows.Close()
+
+	busy := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var riderID uuid.UUID
+		if err := rows.Scan(&riderID); err != nil {
+			return nil, fmt.Errorf("failed to scan rider: %w", err)
+		}
+		busy[riderID] = true
+	}
+
+	return busy, rows.Err()
+}
+
+// getStops loads a batch's stops in visit order with each order's drop-off
+// point and current status
+func (r *DeliveryBatchRepository) getStops(ctx context.Context, batchID uuid.UUID) ([]domain.DeliveryBatchStop, error) {
+	query := `
+		SELECT s.order_id, s.sequence, s.leg_distance_meters, s.estimated_arrival_at,
+			o.status, COALESCE(o.delivery_address, ''), o.delivery_latitude, o.delivery_longitude
+		FROM delivery_batch_stops s
+		JOIN orders o ON o.id = s.order_id
+		WHERE s.batch_id = $1
+		ORDER BY s.sequence
+	`
+
+	rows, err := r.db.Query(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch stops: %w", err)
+	}
+	defer rows.Close()
+
+	var stops []domain.DeliveryBatchStop
+	for rows.Next() {
+		var stop domain.DeliveryBatchStop
+		err := rows.Scan(
+			&stop.OrderID,
+			&stop.Sequence,
+			&stop.LegDistanceMeters,
+			&stop.EstimatedArrivalAt,
+			&stop.Status,
+			&stop.Location.Address,
+			&stop.Location.Latitude,
+			&stop.Location.Longitude,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch stop: %w", err)
+		}
+		stops = append(stops, stop)
+	}
+
+	return stops, nil
+}
@@ -0,0 +1,153 @@
+// Package repository implements delivery slot booking data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// DeliverySlotRepository handles delivery slot bookings
+type DeliverySlotRepository struct {
+	db *database.Pool
+}
+
+// NewDeliverySlotRepository creates a new delivery slot repository
+func NewDeliverySlotRepository(db *database.Pool) *DeliverySlotRepository {
+	return &DeliverySlotRepository{db: db}
+}
+
+// BookSlot takes one order from the slot if fewer than capacity are booked.
+// The conditional upsert keeps concurrent checkouts from overbooking.
+func (r *DeliverySlotRepository) BookSlot(ctx context.Context, slotStart time.Time, capacity int) (bool, error) {
+	query := `
+		INSERT INTO delivery_slot_bookings (slot_start, booked)
+		SELECT $1, 1
+		WHERE $2 > 0
+		ON CONFLICT (slot_start) DO UPDATE
+		SET booked = delivery_slot_bookings.booked + 1
+		WHERE delivery_slot_bookings.booked < $2
+		RETURNING booked
+	`
+
+	var booked int
+	err := r.db.QueryRow(ctx, query, slotStart, capacity).Scan(&booked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to book delivery slot: %w", mapPgError(err))
+	}
+
+	return true, nil
+}
+
+// ReleaseSlot takes one booking off a slot, for bookings whose order was
+// never saved
+func (r *DeliverySlotRepository) ReleaseSlot(ctx context.Context, slotStart time.Time) error {
+	query := `
+		UPDATE delivery_slot_bookings
+		SET booked = GREATEST(booked - 1, 0)
+		WHERE slot_start = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, slotStart); err != nil {
+		return fmt.Errorf("failed to release delivery slot: %w", err)
+	}
+	return nil
+}
+
+// releaseHolds marks the orders picked by the where clause as released and
+// takes them off their slots' counts in one statement, so it is atomic
+// inside or outside a unit of work. Returns the number of orders released.
+func (r *DeliverySlotRepository) releaseHolds(ctx context.Context, where string, args ...interface{}) (int, error) {
+	query := `
+		WITH released AS (
+			UPDATE orders SET delivery_slot_released_at = NOW()
+			WHERE ` + where + `
+			RETURNING delivery_slot_at
+		), counts AS (
+			SELECT delivery_slot_at, COUNT(*) AS n FROM released GROUP BY delivery_slot_at
+		), freed AS (
+			UPDATE delivery_slot_bookings b
+			SET booked = GREATEST(b.booked - c.n, 0)
+			FROM counts c
+			WHERE b.slot_start = c.delivery_slot_at
+		)
+		SELECT COUNT(*) FROM released
+	`
+
+	var released int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&released); err != nil {
+		return 0, fmt.Errorf("failed to release delivery slots: %w", err)
+	}
+	return released, nil
+}
+
+// ReleaseOrderSlot gives back an order's delivery slot booking. Returns
+// false if the order has no slot or it was already released.
+func (r *DeliverySlotRepository) ReleaseOrderSlot(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	released, err := r.releaseHolds(ctx, `
+		id = $1 AND delivery_slot_at IS NOT NULL AND delivery_slot_released_at IS NULL`,
+		orderID)
+	return released > 0, err
+}
+
+// ReleaseExpiredHolds gives back the slots of up to limit orders created
+// before cutoff that are still unpaid, returning how many were released
+func (r *DeliverySlotRepository) ReleaseExpiredHolds(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	return r.releaseHolds(ctx, `
+		id IN (
+			SELECT id FROM orders
+			WHERE delivery_slot_at IS NOT NULL AND delivery_slot_released_at IS NULL
+			  AND created_at < $1
+			  AND status::text = ANY($2)
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)`,
+		cutoff, unpaidStatuses(), limit)
+}
+
+// GetBookings returns booked counts for slots in [from, to)
+func (r *DeliverySlotRepository) GetBookings(ctx context.Context, from, to time.Time) (map[time.Time]int, error) {
+	query := `
+		SELECT slot_start, booked
+		FROM delivery_slot_bookings
+		WHERE slot_start >= $1 AND slot_start < $2
+	`
+
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delivery slot bookings: %w", err)
+	}
+	defer rows.Close()
+
+	bookings := make(map[time.Time]int)
+	for rows.Next() {
+		var slot time.Time
+		var booked int
+		if err := rows.Scan(&slot, &booked); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery slot booking: %w", err)
+		}
+		bookings[slot.UTC()] = booked
+	}
+
+	return bookings, rows.Err()
+}
+
+// unpaidStatuses lists the statuses of orders still waiting to be paid
+func unpaidStatuses() []string {
+	return []string{
+		string(domain.OrderStatusPending),
+		string(domain.OrderStatusAwaitingPayment),
+		string(domain.OrderStatusPaymentFailed),
+	}
+}
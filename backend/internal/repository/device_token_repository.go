@@ -0,0 +1,101 @@
+// Package repository implements push device token data access
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// DeviceTokenRepository stores the FCM tokens of users' devices; it is the
+// push.TokenStore the FCM sender reads
+type DeviceTokenRepository struct {
+	db *database.Pool
+}
+
+// NewDeviceTokenRepository creates a new device token repository
+func NewDeviceTokenRepository(db *database.Pool) *DeviceTokenRepository {
+	return &DeviceTokenRepository{db: db}
+}
+
+// Register saves a device's token for the user, taking it over from any
+// other user, and keeps only the user's keep most recently seen devices
+func (r *DeviceTokenRepository) Register(ctx context.Context, token *domain.DeviceToken, keep int) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO device_tokens (token, user_id, platform)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (token) DO UPDATE
+			SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW(),
+			    created_at = CASE WHEN device_tokens.user_id = EXCLUDED.user_id THEN device_tokens.created_at ELSE NOW() END
+			RETURNING created_at, last_seen_at
+		`, token.Token, token.UserID, token.Platform).Scan(&token.CreatedAt, &token.LastSeenAt)
+		if err != nil {
+			return fmt.Errorf("failed to register device token: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM device_tokens
+			WHERE user_id = $1 AND token NOT IN (
+				SELECT token FROM device_tokens
+				WHERE user_id = $1
+				ORDER BY last_seen_at DESC
+				LIMIT $2
+			)
+		`, token.UserID, keep)
+		if err != nil {
+			return fmt.Errorf("failed to trim device tokens: %w", err)
+		}
+		return nil
+	})
+}
+
+// Unregister deletes one of the user's device tokens. Returns ErrNotFound
+// if the user has no such token.
+func (r *DeviceTokenRepository) Unregister(ctx context.Context, userID uuid.UUID, token string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE token = $1 AND user_id = $2`, token, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unregister device token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeviceTokens returns the user's tokens, most recently seen first
+func (r *DeviceTokenRepository) DeviceTokens(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT token FROM device_tokens
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, fmt.Errorf("failed to scan device token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RemoveDeviceToken deletes a token whoever it belongs to, for tokens FCM
+// no longer delivers to
+func (r *DeviceTokenRepository) RemoveDeviceToken(ctx context.Context, token string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE token = $1`, token); err != nil {
+		return fmt.Errorf("failed to remove device token: %w", err)
+	}
+	return nil
+}
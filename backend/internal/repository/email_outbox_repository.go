@@ -0,0 +1,106 @@
+// Package repository implements the transactional email outbox
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// EmailOutboxRepository queues order emails for the email worker
+type EmailOutboxRepository struct {
+	db *database.Pool
+}
+
+// NewEmailOutboxRepository creates a new email outbox repository
+func NewEmailOutboxRepository(db *database.Pool) *EmailOutboxRepository {
+	return &EmailOutboxRepository{db: db}
+}
+
+// Enqueue queues the email templateKey for an order, due now. An email the
+// order already has queued or sent is not queued again.
+func (r *EmailOutboxRepository) Enqueue(ctx context.Context, templateKey string, orderID, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO email_outbox (template_key, order_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (order_id, template_key) DO NOTHING
+	`, templateKey, orderID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit due emails, oldest first, and counts the
+// attempt. Each is pushed lease into the future so another instance
+// doesn't send it meanwhile; recording the outcome clears it.
+func (r *EmailOutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.QueuedEmail, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template_key, order_id, user_id, attempts, created_at
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []domain.QueuedEmail{}
+	for rows.Next() {
+		var e domain.QueuedEmail
+		if err := rows.Scan(&e.ID, &e.TemplateKey, &e.OrderID, &e.UserID, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queued email: %w", err)
+		}
+		emails = append(emails, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim emails: %w", err)
+	}
+	return emails, nil
+}
+
+// MarkSent records that an email went out, or that there was nothing to
+// send (no verified address)
+func (r *EmailOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE email_outbox SET next_attempt_at = NULL, sent_at = NOW(), last_error = NULL WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+	return nil
+}
+
+// ScheduleRetry schedules a failed email's next attempt
+func (r *EmailOutboxRepository) ScheduleRetry(ctx context.Context, id int64, at time.Time, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE email_outbox SET next_attempt_at = $2, last_error = $3 WHERE id = $1
+	`, id, at, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to schedule email retry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed gives up on an email
+func (r *EmailOutboxRepository) MarkFailed(ctx context.Context, id int64, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE email_outbox SET next_attempt_at = NULL, failed_at = NOW(), last_error = $2 WHERE id = $1
+	`, id, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"fooddelivery/pkg/database"
+)
+
+// Common repository errors
+var (
+	ErrNotFound        = errors.New("record not found")
+	ErrDuplicateKey    = errors.New("duplicate key violation")
+	ErrVersionConflict = errors.New("version conflict - record was modified")
+
+	// ErrForeignKeyViolation means a referenced row does not exist, or a
+	// row being removed is still referenced
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrCheckViolation means a value broke a CHECK constraint
+	ErrCheckViolation = errors.New("check constraint violation")
+	// ErrSerializationFailure means the transaction lost a race with a
+	// concurrent one and can be retried as a whole
+	ErrSerializationFailure = errors.New("serialization failure - retry the transaction")
+)
+
+// PostgreSQL SQLSTATE codes translated by mapPgError
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// ConstraintError is a Postgres error translated into one of the
+// repository errors above. errors.Is matches both Kind and the underlying
+// *pgconn.PgError, and Constraint tells apart two indexes on one table.
+type ConstraintError struct {
+	Kind       error
+	Table      string
+	Constraint string
+	Err        *pgconn.PgError
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Constraint == "" {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%s on %s (%s)", e.Kind, e.Table, e.Constraint)
+}
+
+// Unwrap exposes both the repository error and the driver error
+func (e *ConstraintError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// mapPgError translates Postgres constraint and concurrency errors into a
+// *ConstraintError. Other errors, including nil, are returned unchanged.
+func mapPgError(err error) error {
+	var ce *ConstraintError
+	if errors.As(err, &ce) {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var kind error
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		kind = ErrDuplicateKey
+	case pgForeignKeyViolation:
+		kind = ErrForeignKeyViolation
+	case pgCheckViolation:
+		kind = ErrCheckViolation
+	case pgSerializationFailure, pgDeadlockDetected:
+		// Postgres aborts one side of a deadlock, so it's retried the same way
+		kind = ErrSerializationFailure
+	default:
+		return err
+	}
+
+	return &ConstraintError{
+		Kind:       kind,
+		Table:      pgErr.TableName,
+		Constraint: pgErr.ConstraintName,
+		Err:        pgErr,
+	}
+}
+
+// execTx runs fn in db's serializable transaction, translating errors from
+// fn or the commit so callers can retry on ErrSerializationFailure
+func execTx(ctx context.Context, db *database.Pool, fn func(tx pgx.Tx) error) error {
+	return mapPgError(db.ExecTx(ctx, fn))
+}
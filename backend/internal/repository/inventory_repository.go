@@ -0,0 +1,335 @@
+// Package repository implements menu item stock data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// ErrInsufficientStock is returned when an order wants more of an item than
+// is left
+var ErrInsufficientStock = errors.New("not enough stock")
+
+// InventoryRepository handles stock levels of menu items. Items with a NULL
+// stock_quantity aren't tracked and are never touched.
+type InventoryRepository struct {
+	db *database.Pool
+}
+
+// NewInventoryRepository creates a new inventory repository
+func NewInventoryRepository(db *database.Pool) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// SetStock sets an item's stock level; nil stops tracking it. Stock isn't
+// an edit of the item, so its version is left alone. Returns ErrNotFound
+// if the item doesn't exist.
+func (r *InventoryRepository) SetStock(ctx context.Context, itemID uuid.UUID, quantity *int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE menu_items SET stock_quantity = $2 WHERE id = $1`, itemID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to set stock: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetLowStockThreshold sets the stock level at which admins are alerted
+// about an item; nil uses the default. Returns ErrNotFound if the item
+// doesn't exist.
+func (r *InventoryRepository) SetLowStockThreshold(ctx context.Context, itemID uuid.UUID, threshold *int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE menu_items SET low_stock_threshold = $2 WHERE id = $1`, itemID, threshold)
+	if err != nil {
+		return fmt.Errorf("failed to set low stock threshold: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CheckStockLevels compares each tracked item's stock with its threshold
+// (defaultThreshold when it has none), records the level and returns the
+// items whose level changed. With disableSoldOut an item that sold out is
+// switched off; an item switched off that way is switched back on once
+// restocked either way. Items deleted from the menu are left alone. The
+// rows are locked, so concurrent checks report each change once.
+func (r *InventoryRepository) CheckStockLevels(ctx context.Context, defaultThreshold int, disableSoldOut bool) ([]domain.StockAlert, error) {
+	var changed []domain.StockAlert
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			WITH levels AS (
+				SELECT id, stock_alert AS previous, is_available, stock_disabled,
+					CASE
+						WHEN stock_quantity = 0 THEN 'OUT'
+						WHEN stock_quantity <= COALESCE(low_stock_threshold, $1) THEN 'LOW'
+					END AS level
+				FROM menu_items
+				WHERE (is_available OR stock_disabled)
+				  AND (stock_quantity IS NOT NULL OR stock_alert IS NOT NULL)
+				FOR UPDATE
+			), changes AS (
+				SELECT id, previous, level,
+					level IS NOT DISTINCT FROM 'OUT' AND $2::boolean AND is_available AS disable,
+					level IS DISTINCT FROM 'OUT' AND stock_disabled AS reenable
+				FROM levels
+			)
+			UPDATE menu_items m
+			SET stock_alert = c.level,
+			    is_available = CASE WHEN c.disable THEN FALSE WHEN c.reenable THEN TRUE ELSE m.is_available END,
+			    stock_disabled = (m.stock_disabled OR c.disable) AND NOT c.reenable,
+			    version = CASE WHEN c.disable OR c.reenable THEN m.version + 1 ELSE m.version END,
+			    updated_at = CASE WHEN c.disable OR c.reenable THEN NOW() ELSE m.updated_at END
+			FROM changes c
+			WHERE m.id = c.id
+			  AND (c.level IS DISTINCT FROM c.previous OR c.disable OR c.reenable)
+			RETURNING m.id, m.name, m.category, m.stock_quantity, COALESCE(m.low_stock_threshold, $1),
+				COALESCE(c.previous, ''), COALESCE(c.level, ''), c.disable, c.reenable
+		`, defaultThreshold, disableSoldOut)
+		if err != nil {
+			return fmt.Errorf("failed to check stock levels: %w", err)
+		}
+		for rows.Next() {
+			var a domain.StockAlert
+			if err := rows.Scan(&a.MenuItemID, &a.Name, &a.Category, &a.StockQuantity, &a.Threshold,
+				&a.Previous, &a.Level, &a.Disabled, &a.Reenabled); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan stock level: %w", err)
+			}
+			changed = append(changed, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read stock levels: %w", err)
+		}
+
+		// Availability switched by the check shows in the menu history
+		// like an admin's change, without an author
+		now := time.Now()
+		for _, a := range changed {
+			if a.Disabled || a.Reenabled {
+				if err := recordMenuSnapshot(ctx, tx, a.MenuItemID, nil, now); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// StockSummary counts the tracked items on the menu, including those
+// switched off because they sold out, and lists the ones at or below
+// their threshold (defaultThreshold when they have none)
+func (r *InventoryRepository) StockSummary(ctx context.Context, defaultThreshold int) (*domain.StockSummary, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, name, category, stock_quantity, COALESCE(low_stock_threshold, $1), stock_disabled
+		FROM menu_items
+		WHERE stock_quantity IS NOT NULL AND (is_available OR stock_disabled)
+		ORDER BY stock_quantity, name
+	`, defaultThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &domain.StockSummary{Items: []domain.StockItem{}}
+	for rows.Next() {
+		var item domain.StockItem
+		if err := rows.Scan(&item.MenuItemID, &item.Name, &item.Category, &item.StockQuantity, &item.Threshold, &item.Disabled); err != nil {
+			return nil, fmt.Errorf("failed to scan stock summary: %w", err)
+		}
+		summary.Tracked++
+		switch {
+		case item.StockQuantity == 0:
+			item.Level = domain.StockLevelOut
+			summary.Out++
+		case item.StockQuantity <= item.Threshold:
+			item.Level = domain.StockLevelLow
+			summary.Low++
+		default:
+			continue
+		}
+		summary.Items = append(summary.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stock summary: %w", err)
+	}
+	return summary, nil
+}
+
+// Decrement takes an order's items out of stock and marks the order as
+// holding stock, all or nothing. Returns ErrInsufficientStock, changing
+// nothing, if any tracked item has fewer units left than ordered, and
+// otherwise the items the order sold out. Joins the unit of work in ctx so
+// the stock goes with the order insert.
+func (r *InventoryRepository) Decrement(ctx context.Context, orderID uuid.UUID, items []domain.OrderItem) ([]uuid.UUID, error) {
+	wanted := make(map[uuid.UUID]int, len(items))
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		if _, ok := wanted[item.MenuItemID]; !ok {
+			ids = append(ids, item.MenuItemID)
+		}
+		wanted[item.MenuItemID] += item.Quantity
+	}
+
+	var soldOut []uuid.UUID
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		// Lock in a fixed order so concurrent checkouts can't deadlock
+		rows, err := r.db.Query(ctx, `
+			SELECT id, stock_quantity FROM menu_items
+			WHERE id = ANY($1) AND stock_quantity IS NOT NULL
+			ORDER BY id
+			FOR UPDATE
+		`, ids)
+		if err != nil {
+			return fmt.Errorf("failed to lock stock: %w", err)
+		}
+		stock := make(map[uuid.UUID]int)
+		for rows.Next() {
+			var id uuid.UUID
+			var quantity int
+			if err := rows.Scan(&id, &quantity); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan stock: %w", err)
+			}
+			stock[id] = quantity
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read stock: %w", err)
+		}
+		if len(stock) == 0 {
+			return nil // Nothing in the order is tracked
+		}
+
+		tracked := make([]uuid.UUID, 0, len(stock))
+		quantities := make([]int32, 0, len(stock))
+		for id, left := range stock {
+			if left < wanted[id] {
+				return ErrInsufficientStock
+			}
+			if left == wanted[id] {
+				soldOut = append(soldOut, id)
+			}
+			tracked = append(tracked, id)
+			quantities = append(quantities, int32(wanted[id]))
+		}
+
+		_, err = r.db.Exec(ctx, `
+			UPDATE menu_items m
+			SET stock_quantity = m.stock_quantity - w.quantity
+			FROM unnest($1::uuid[], $2::int[]) AS w(id, quantity)
+			WHERE m.id = w.id
+		`, tracked, quantities)
+		if err != nil {
+			return fmt.Errorf("failed to decrement stock: %w", err)
+		}
+
+		if _, err := r.db.Exec(ctx, `UPDATE orders SET stock_status = 'RESERVED' WHERE id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to mark order stock: %w", mapPgError(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return soldOut, nil
+}
+
+// Restore gives an order's items back to stock if Decrement took them and
+// they haven't been given back yet, so calling it again is harmless.
+// Returns the items that were sold out before.
+func (r *InventoryRepository) Restore(ctx context.Context, orderID uuid.UUID) ([]uuid.UUID, error) {
+	var restocked []uuid.UUID
+	err := r.moveOrderStock(ctx, orderID, "RESERVED", "RELEASED", `
+		UPDATE menu_items m
+		SET stock_quantity = m.stock_quantity + l.quantity
+		FROM order_lines l
+		WHERE m.id = l.menu_item_id AND m.stock_quantity IS NOT NULL
+		RETURNING m.id, m.stock_quantity = l.quantity
+	`, func(id uuid.UUID, changed bool) {
+		if changed {
+			restocked = append(restocked, id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restocked, nil
+}
+
+// Retake takes an order's items out of stock again after Restore gave them
+// back, for an order paid on a retry after a failed attempt. The money is
+// already taken, so stock that ran out meanwhile stops at 0 instead of
+// refusing. Returns the items left sold out. A no-op unless Restore ran.
+func (r *InventoryRepository) Retake(ctx context.Context, orderID uuid.UUID) ([]uuid.UUID, error) {
+	var soldOut []uuid.UUID
+	err := r.moveOrderStock(ctx, orderID, "RELEASED", "RESERVED", `
+		UPDATE menu_items m
+		SET stock_quantity = GREATEST(m.stock_quantity - l.quantity, 0)
+		FROM order_lines l
+		WHERE m.id = l.menu_item_id AND m.stock_quantity IS NOT NULL
+		RETURNING m.id, m.stock_quantity = 0
+	`, func(id uuid.UUID, empty bool) {
+		if empty {
+			soldOut = append(soldOut, id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return soldOut, nil
+}
+
+// moveOrderStock switches an order's stock_status from one state to the
+// other and, if it was in the first, runs update over its lines (available
+// as order_lines), passing each returned (id, flag) row to each
+func (r *InventoryRepository) moveOrderStock(ctx context.Context, orderID uuid.UUID, from, to, update string, each func(id uuid.UUID, flag bool)) error {
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `
+			UPDATE orders SET stock_status = $3
+			WHERE id = $1 AND stock_status = $2
+		`, orderID, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to update order stock status: %w", mapPgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		rows, err := r.db.Query(ctx, `
+			WITH order_lines AS (
+				SELECT menu_item_id, SUM(quantity)::int AS quantity
+				FROM order_items
+				WHERE order_id = $1 AND menu_item_id IS NOT NULL
+				GROUP BY menu_item_id
+			)
+		`+update, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to move order stock: %w", mapPgError(err))
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id uuid.UUID
+			var flag bool
+			if err := rows.Scan(&id, &flag); err != nil {
+				return fmt.Errorf("failed to scan stock: %w", err)
+			}
+			each(id, flag)
+		}
+		return rows.Err()
+	})
+}
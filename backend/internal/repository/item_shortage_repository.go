@@ -0,0 +1,188 @@
+// Package repository implements item shortage data access
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// ItemShortageRepository handles item shortage persistence
+type ItemShortageRepository struct {
+	db *database.Pool
+}
+
+// NewItemShortageRepository creates a new item shortage repository
+func NewItemShortageRepository(db *database.Pool) *ItemShortageRepository {
+	return &ItemShortageRepository{db: db}
+}
+
+// shortageColumns is the column list matching scanShortage
+const shortageColumns = `id, order_id, status, items, refund_amount, COALESCE(note, ''),
+		reported_by, respond_by, resolved_at, resolved_by,
+		COALESCE(razorpay_refund_id, ''), COALESCE(refund_error, ''), created_at`
+
+// scanShortage scans a row selected with shortageColumns
+func scanShortage(row pgx.Row) (*domain.ItemShortage, error) {
+	s := &domain.ItemShortage{}
+	var items []byte
+	err := row.Scan(
+		&s.ID,
+		&s.OrderID,
+		&s.Status,
+		&items,
+		&s.RefundAmount,
+		&s.Note,
+		&s.ReportedBy,
+		&s.RespondBy,
+		&s.ResolvedAt,
+		&s.ResolvedBy,
+		&s.RazorpayRefundID,
+		&s.RefundError,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(items, &s.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode shortage items: %w", err)
+	}
+	return s, nil
+}
+
+// Create stores a new pending shortage. Returns ErrDuplicateKey if the
+// order already has one pending.
+func (r *ItemShortageRepository) Create(ctx context.Context, s *domain.ItemShortage) error {
+	items, err := json.Marshal(s.Items)
+	if err != nil {
+		return fmt.Errorf("failed to encode shortage items: %w", err)
+	}
+
+	s.ID = uuid.New()
+	s.Status = domain.ItemShortagePending
+	s.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO order_item_shortages (id, order_id, status, items, refund_amount, note, reported_by, respond_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = r.db.Exec(ctx, query,
+		s.ID,
+		s.OrderID,
+		s.Status,
+		items,
+		s.RefundAmount,
+		nullableString(s.Note),
+		s.ReportedBy,
+		s.RespondBy,
+		s.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create item shortage: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetPendingByOrder returns the order's pending shortage
+func (r *ItemShortageRepository) GetPendingByOrder(ctx context.Context, orderID uuid.UUID) (*domain.ItemShortage, error) {
+	query := `
+		SELECT ` + shortageColumns + `
+		FROM order_item_shortages
+		WHERE order_id = $1 AND status = $2
+	`
+
+	s, err := scanShortage(r.db.QueryRow(ctx, query, orderID, domain.ItemShortagePending))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get item shortage: %w", err)
+	}
+	return s, nil
+}
+
+// ListByOrder returns every shortage reported on an order, oldest first
+func (r *ItemShortageRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]domain.ItemShortage, error) {
+	query := `
+		SELECT ` + shortageColumns + `
+		FROM order_item_shortages
+		WHERE order_id = $1
+		ORDER BY created_at
+	`
+	return r.list(ctx, query, orderID)
+}
+
+// GetDue returns pending shortages whose response deadline has passed
+func (r *ItemShortageRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]domain.ItemShortage, error) {
+	query := `
+		SELECT ` + shortageColumns + `
+		FROM order_item_shortages
+		WHERE status = $1 AND respond_by <= $2
+		ORDER BY respond_by
+		LIMIT $3
+	`
+	return r.list(ctx, query, domain.ItemShortagePending, now, limit)
+}
+
+func (r *ItemShortageRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.ItemShortage, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query item shortages: %w", err)
+	}
+	defer rows.Close()
+
+	shortages := []domain.ItemShortage{}
+	for rows.Next() {
+		s, err := scanShortage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan item shortage: %w", err)
+		}
+		shortages = append(shortages, *s)
+	}
+
+	return shortages, rows.Err()
+}
+
+// Resolve records the answer to a pending shortage. Returns
+// ErrVersionConflict if it was already resolved.
+func (r *ItemShortageRepository) Resolve(ctx context.Context, id uuid.UUID, status domain.ItemShortageStatus, resolvedBy *uuid.UUID) error {
+	query := `
+		UPDATE order_item_shortages
+		SET status = $2, resolved_at = NOW(), resolved_by = $3
+		WHERE id = $1 AND status = $4
+	`
+
+	result, err := r.db.Exec(ctx, query, id, status, resolvedBy, domain.ItemShortagePending)
+	if err != nil {
+		return fmt.Errorf("failed to resolve item shortage: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// SetRefund records the refund issued for a resolved shortage, or why it
+// failed
+func (r *ItemShortageRepository) SetRefund(ctx context.Context, id uuid.UUID, refundID, refundError string) error {
+	query := `
+		UPDATE order_item_shortages
+		SET razorpay_refund_id = $2, refund_error = $3
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, nullableString(refundID), nullableString(refundError)); err != nil {
+		return fmt.Errorf("failed to record shortage refund: %w", mapPgError(err))
+	}
+	return nil
+}
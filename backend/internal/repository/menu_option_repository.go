@@ -0,0 +1,133 @@
+// Package repository implements menu item variant and modifier data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+)
+
+// attachOptions fills in the variants and modifiers of items, in display
+// order
+func (r *MenuRepository) attachOptions(ctx context.Context, items []domain.MenuItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	index := make(map[uuid.UUID]int, len(items))
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		index[item.ID] = i
+		ids[i] = item.ID
+	}
+
+	err := r.loadOptions(ctx, "menu_item_variants", ids, func(itemID uuid.UUID, v domain.MenuItemVariant) {
+		item := &items[index[itemID]]
+		item.Variants = append(item.Variants, v)
+	})
+	if err != nil {
+		return err
+	}
+	return r.loadOptions(ctx, "menu_item_modifiers", ids, func(itemID uuid.UUID, v domain.MenuItemVariant) {
+		item := &items[index[itemID]]
+		item.Modifiers = append(item.Modifiers, domain.MenuItemModifier(v))
+	})
+}
+
+// loadOptions reads the rows of an options table for the given items.
+// Variants and modifiers have the same shape, so both scan as variants.
+func (r *MenuRepository) loadOptions(ctx context.Context, table string, itemIDs []uuid.UUID, each func(itemID uuid.UUID, v domain.MenuItemVariant)) error {
+	rows, err := r.db.Query(ctx, `
+		SELECT menu_item_id, id, name, price, is_available
+		FROM `+table+`
+		WHERE menu_item_id = ANY($1)
+		ORDER BY menu_item_id, sort_order, name
+	`, itemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID uuid.UUID
+		var v domain.MenuItemVariant
+		if err := rows.Scan(&itemID, &v.ID, &v.Name, &v.Price, &v.IsAvailable); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		each(itemID, v)
+	}
+	return rows.Err()
+}
+
+// SetOptions replaces an item's variants and modifiers with the given
+// lists, in that display order. Entries with an ID keep it, so carts that
+// picked them stay valid; entries without one are added with a new ID set
+// on them, and entries not listed are removed. Returns ErrNotFound,
+// changing nothing, if the item doesn't exist or an ID belongs to another
+// item. Options aren't an edit of the item, so its version is left alone.
+func (r *MenuRepository) SetOptions(ctx context.Context, itemID uuid.UUID, variants []domain.MenuItemVariant, modifiers []domain.MenuItemModifier) error {
+	return r.db.InTx(ctx, func(ctx context.Context) error {
+		// Serializes concurrent edits of the same item
+		var locked uuid.UUID
+		err := r.db.QueryRow(ctx, `SELECT id FROM menu_items WHERE id = $1 FOR UPDATE`, itemID).Scan(&locked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to lock menu item: %w", err)
+		}
+
+		if err := r.replaceOptions(ctx, "menu_item_variants", itemID, variants); err != nil {
+			return err
+		}
+		asVariants := make([]domain.MenuItemVariant, len(modifiers))
+		for i, m := range modifiers {
+			asVariants[i] = domain.MenuItemVariant(m)
+		}
+		if err := r.replaceOptions(ctx, "menu_item_modifiers", itemID, asVariants); err != nil {
+			return err
+		}
+		for i := range modifiers {
+			modifiers[i].ID = asVariants[i].ID
+		}
+		return nil
+	})
+}
+
+// replaceOptions makes an options table hold exactly opts for the item
+func (r *MenuRepository) replaceOptions(ctx context.Context, table string, itemID uuid.UUID, opts []domain.MenuItemVariant) error {
+	keep := make([]uuid.UUID, 0, len(opts))
+	for i := range opts {
+		if opts[i].ID == uuid.Nil {
+			opts[i].ID = uuid.New()
+		}
+		keep = append(keep, opts[i].ID)
+	}
+
+	_, err := r.db.Exec(ctx, `DELETE FROM `+table+` WHERE menu_item_id = $1 AND NOT (id = ANY($2))`, itemID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", table, err)
+	}
+
+	for i, opt := range opts {
+		tag, err := r.db.Exec(ctx, `
+			INSERT INTO `+table+` (id, menu_item_id, name, price, is_available, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name, price = EXCLUDED.price,
+				is_available = EXCLUDED.is_available, sort_order = EXCLUDED.sort_order
+			WHERE `+table+`.menu_item_id = EXCLUDED.menu_item_id
+		`, opt.ID, itemID, opt.Name, opt.Price, opt.IsAvailable, i)
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", table, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -26,10 +27,10 @@ func NewMenuRepository(db *database.Pool) *MenuRepository {
 // GetAll retrieves all available menu items
 func (r *MenuRepository) GetAll(ctx context.Context) ([]domain.MenuItem, error) {
 	query := `
-		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at
+		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at, version, ingredients, stock_quantity
 		FROM menu_items
 		WHERE is_available = TRUE
-		ORDER BY category, name
+		ORDER BY category, sort_order, name
 	`
 
 	rows, err := r.db.Query(ctx, query)
@@ -53,6 +54,9 @@ func (r *MenuRepository) GetAll(ctx context.Context) ([]domain.MenuItem, error)
 			&item.IsAvailable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.Ingredients,
+			&item.StockQuantity,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan menu item: %w", err)
@@ -61,6 +65,7 @@ func (r *MenuRepository) GetAll(ctx context.Context) ([]domain.MenuItem, error)
 		if imageURL != nil {
 			item.ImageURL = *imageURL
 		}
+		item.SoldOut = !item.InStock(1)
 
 		items = append(items, item)
 	}
@@ -69,15 +74,18 @@ func (r *MenuRepository) GetAll(ctx context.Context) ([]domain.MenuItem, error)
 		return nil, fmt.Errorf("error iterating menu items: %w", err)
 	}
 
+	if err := r.attachOptions(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
 // GetAllIncludingUnavailable retrieves all menu items (admin view)
 func (r *MenuRepository) GetAllIncludingUnavailable(ctx context.Context) ([]domain.MenuItem, error) {
 	query := `
-		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at
+		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at, version, ingredients, stock_quantity
 		FROM menu_items
-		ORDER BY category, name
+		ORDER BY category, sort_order, name
 	`
 
 	rows, err := r.db.Query(ctx, query)
@@ -101,6 +109,9 @@ func (r *MenuRepository) GetAllIncludingUnavailable(ctx context.Context) ([]doma
 			&item.IsAvailable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.Ingredients,
+			&item.StockQuantity,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan menu item: %w", err)
@@ -109,17 +120,21 @@ func (r *MenuRepository) GetAllIncludingUnavailable(ctx context.Context) ([]doma
 		if imageURL != nil {
 			item.ImageURL = *imageURL
 		}
+		item.SoldOut = !item.InStock(1)
 
 		items = append(items, item)
 	}
 
+	if err := r.attachOptions(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
 // GetByID retrieves a menu item by UUID
 func (r *MenuRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.MenuItem, error) {
 	query := `
-		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at
+		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at, version, ingredients, stock_quantity
 		FROM menu_items
 		WHERE id = $1
 	`
@@ -137,6 +152,9 @@ func (r *MenuRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Men
 		&item.IsAvailable,
 		&item.CreatedAt,
 		&item.UpdatedAt,
+		&item.Version,
+		&item.Ingredients,
+		&item.StockQuantity,
 	)
 
 	if err != nil {
@@ -149,8 +167,13 @@ func (r *MenuRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Men
 	if imageURL != nil {
 		item.ImageURL = *imageURL
 	}
+	item.SoldOut = !item.InStock(1)
 
-	return item, nil
+	items := []domain.MenuItem{*item}
+	if err := r.attachOptions(ctx, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
 }
 
 // GetByIDs retrieves multiple menu items by their UUIDs
@@ -161,7 +184,7 @@ func (r *MenuRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]domai
 	}
 
 	query := `
-		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at
+		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at, version, ingredients, stock_quantity
 		FROM menu_items
 		WHERE id = ANY($1) AND is_available = TRUE
 	`
@@ -187,6 +210,9 @@ func (r *MenuRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]domai
 			&item.IsAvailable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.Ingredients,
+			&item.StockQuantity,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan menu item: %w", err)
@@ -195,97 +221,149 @@ func (r *MenuRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]domai
 		if imageURL != nil {
 			item.ImageURL = *imageURL
 		}
+		item.SoldOut = !item.InStock(1)
 
 		items = append(items, item)
 	}
 
+	if err := r.attachOptions(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
-// Create inserts a new menu item
-func (r *MenuRepository) Create(ctx context.Context, item *domain.MenuItem) error {
+// Create inserts a new menu item and starts its price history
+func (r *MenuRepository) Create(ctx context.Context, item *domain.MenuItem, createdBy uuid.UUID) error {
 	query := `
-		INSERT INTO menu_items (id, name, description, price, category, image_url, is_available, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO menu_items (id, name, description, price, category, image_url, is_available, created_at, updated_at, ingredients, stock_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, '[]'::jsonb), $11)
 	`
 
 	item.ID = uuid.New()
-	_, err := r.db.Exec(ctx, query,
-		item.ID,
-		item.Name,
-		item.Description,
-		item.Price,
-		item.Category,
-		item.ImageURL,
-		item.IsAvailable,
-		item.CreatedAt,
-		item.UpdatedAt,
-	)
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query,
+			item.ID,
+			item.Name,
+			item.Description,
+			item.Price,
+			item.Category,
+			item.ImageURL,
+			item.IsAvailable,
+			item.CreatedAt,
+			item.UpdatedAt,
+			item.Ingredients,
+			item.StockQuantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create menu item: %w", mapPgError(err))
+		}
 
+		if err := recordPriceChange(ctx, tx, item.ID, nil, item.Price, createdBy, item.CreatedAt); err != nil {
+			return err
+		}
+		return recordMenuSnapshot(ctx, tx, item.ID, &createdBy, item.CreatedAt)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create menu item: %w", err)
+		return err
 	}
 
+	item.Version = 1
+	item.SoldOut = !item.InStock(1)
 	return nil
 }
 
-// Update modifies an existing menu item
-func (r *MenuRepository) Update(ctx context.Context, item *domain.MenuItem) error {
+// Update modifies an existing menu item with optimistic locking: item.Version
+// must match the stored version. On success item carries the new version.
+// Returns ErrVersionConflict if another admin changed the item first. A
+// price change is recorded in the item's price history. The availability
+// set here is the admin's, so the stock check won't switch the item back on.
+func (r *MenuRepository) Update(ctx context.Context, item *domain.MenuItem, changedBy uuid.UUID) error {
 	query := `
 		UPDATE menu_items
-		SET name = $2, description = $3, price = $4, category = $5, 
-		    image_url = $6, is_available = $7, updated_at = NOW()
-		WHERE id = $1
+		SET name = $2, description = $3, price = $4, category = $5,
+		    image_url = $6, is_available = $7, ingredients = COALESCE($9, '[]'::jsonb),
+		    stock_disabled = FALSE, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $8
+		RETURNING version, created_at, updated_at, stock_quantity
 	`
 
-	result, err := r.db.Exec(ctx, query,
-		item.ID,
-		item.Name,
-		item.Description,
-		item.Price,
-		item.Category,
-		item.ImageURL,
-		item.IsAvailable,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to update menu item: %w", err)
-	}
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var oldPrice int64
+		err := tx.QueryRow(ctx, `SELECT price FROM menu_items WHERE id = $1 FOR UPDATE`, item.ID).Scan(&oldPrice)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to lock menu item: %w", err)
+		}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
-	}
+		err = tx.QueryRow(ctx, query,
+			item.ID,
+			item.Name,
+			item.Description,
+			item.Price,
+			item.Category,
+			item.ImageURL,
+			item.IsAvailable,
+			item.Version,
+			item.Ingredients,
+		).Scan(&item.Version, &item.CreatedAt, &item.UpdatedAt, &item.StockQuantity)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrVersionConflict
+			}
+			return fmt.Errorf("failed to update menu item: %w", mapPgError(err))
+		}
+		item.SoldOut = !item.InStock(1)
 
-	return nil
+		if oldPrice != item.Price {
+			if err := recordPriceChange(ctx, tx, item.ID, &oldPrice, item.Price, changedBy, item.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		return recordMenuSnapshot(ctx, tx, item.ID, &changedBy, item.UpdatedAt)
+	})
 }
 
 // Delete removes a menu item (soft delete by setting is_available = false)
-func (r *MenuRepository) Delete(ctx context.Context, id uuid.UUID) error {
+// if it is still at expectedVersion
+func (r *MenuRepository) Delete(ctx context.Context, id uuid.UUID, expectedVersion int, deletedBy uuid.UUID) error {
 	query := `
 		UPDATE menu_items
-		SET is_available = FALSE, updated_at = NOW()
-		WHERE id = $1
+		SET is_available = FALSE, stock_disabled = FALSE, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2
+		RETURNING updated_at
 	`
 
-	result, err := r.db.Exec(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete menu item: %w", err)
-	}
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var deletedAt time.Time
+		if err := tx.QueryRow(ctx, query, id, expectedVersion).Scan(&deletedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return r.lockFailure(ctx, id)
+			}
+			return fmt.Errorf("failed to delete menu item: %w", err)
+		}
+		return recordMenuSnapshot(ctx, tx, id, &deletedBy, deletedAt)
+	})
+}
 
-	if result.RowsAffected() == 0 {
+// lockFailure explains a versioned write that matched no row: the item is
+// either gone or was modified concurrently
+func (r *MenuRepository) lockFailure(ctx context.Context, id uuid.UUID) error {
+	_, err := r.GetByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
 		return ErrNotFound
 	}
-
-	return nil
+	return ErrVersionConflict
 }
 
 // GetByCategory retrieves menu items by category
 func (r *MenuRepository) GetByCategory(ctx context.Context, category string) ([]domain.MenuItem, error) {
 	query := `
-		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at
+		SELECT id, name, description, price, category, image_url, is_available, created_at, updated_at, version, ingredients, stock_quantity
 		FROM menu_items
 		WHERE category = $1 AND is_available = TRUE
-		ORDER BY name
+		ORDER BY sort_order, name
 	`
 
 	rows, err := r.db.Query(ctx, query, category)
@@ -309,6 +387,9 @@ func (r *MenuRepository) GetByCategory(ctx context.Context, category string) ([]
 			&item.IsAvailable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.Ingredients,
+			&item.StockQuantity,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan menu item: %w", err)
@@ -317,9 +398,221 @@ func (r *MenuRepository) GetByCategory(ctx context.Context, category string) ([]
 		if imageURL != nil {
 			item.ImageURL = *imageURL
 		}
+		item.SoldOut = !item.InStock(1)
 
 		items = append(items, item)
 	}
 
+	if err := r.attachOptions(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
+
+// SetSortOrder sets the display position of each item to its position in
+// itemIDs. Items not listed keep theirs. Returns ErrNotFound, changing
+// nothing, if any ID is not a menu item. Display order isn't an edit of the
+// item, so versions are left alone.
+func (r *MenuRepository) SetSortOrder(ctx context.Context, itemIDs []uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE menu_items m
+			SET sort_order = o.position
+			FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, position)
+			WHERE m.id = o.id
+		`, itemIDs)
+		if err != nil {
+			return fmt.Errorf("failed to reorder menu items: %w", err)
+		}
+		if tag.RowsAffected() != int64(len(itemIDs)) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// SetAvailability switches every item in itemIDs on or off in one
+// transaction and returns the IDs of the items that changed; items already
+// in that state, and not switched off for selling out, are left alone.
+// Returns ErrNotFound, changing nothing, if any ID is not a menu item. An
+// admin's choice overrides the sold-out switch, as an edit does.
+func (r *MenuRepository) SetAvailability(ctx context.Context, itemIDs []uuid.UUID, available bool, changedBy uuid.UUID) ([]uuid.UUID, error) {
+	changed := []uuid.UUID{}
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, is_available, stock_disabled FROM menu_items
+			WHERE id = ANY($1)
+			ORDER BY id
+			FOR UPDATE
+		`, itemIDs)
+		if err != nil {
+			return fmt.Errorf("failed to lock menu items: %w", err)
+		}
+		var found int
+		var toChange []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			var isAvailable, stockDisabled bool
+			if err := rows.Scan(&id, &isAvailable, &stockDisabled); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan menu item: %w", err)
+			}
+			found++
+			if isAvailable != available || stockDisabled {
+				toChange = append(toChange, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to lock menu items: %w", err)
+		}
+		if found != len(itemIDs) {
+			return ErrNotFound
+		}
+		if len(toChange) == 0 {
+			return nil
+		}
+
+		var changedAt time.Time
+		err = tx.QueryRow(ctx, `
+			WITH updated AS (
+				UPDATE menu_items
+				SET is_available = $2, stock_disabled = FALSE, version = version + 1, updated_at = NOW()
+				WHERE id = ANY($1)
+				RETURNING updated_at
+			)
+			SELECT MAX(updated_at) FROM updated
+		`, toChange, available).Scan(&changedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update menu availability: %w", err)
+		}
+		for _, id := range toChange {
+			if err := recordMenuSnapshot(ctx, tx, id, &changedBy, changedAt); err != nil {
+				return err
+			}
+		}
+		changed = toChange
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// GetPriceHistory returns an item's price timeline, oldest first
+func (r *MenuRepository) GetPriceHistory(ctx context.Context, itemID uuid.UUID) ([]domain.MenuPriceChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, menu_item_id, old_price, new_price, changed_by, changed_at
+		FROM menu_price_history
+		WHERE menu_item_id = $1
+		ORDER BY changed_at, id
+	`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []domain.MenuPriceChange{}
+	for rows.Next() {
+		var c domain.MenuPriceChange
+		if err := rows.Scan(&c.ID, &c.MenuItemID, &c.OldPrice, &c.NewPrice, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// recordMenuSnapshot copies a menu item as written in tx into its history
+func recordMenuSnapshot(ctx context.Context, tx pgx.Tx, itemID uuid.UUID, changedBy *uuid.UUID, at time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO menu_item_history (menu_item_id, name, description, price, category, image_url, is_available, changed_by, changed_at)
+		SELECT id, name, description, price, category, image_url, is_available, $2, $3
+		FROM menu_items
+		WHERE id = $1
+	`, itemID, changedBy, at)
+	if err != nil {
+		return fmt.Errorf("failed to record menu item snapshot: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// GetMenuAsOf reconstructs the menu at a point in time: every item created
+// by then, at the price it had and, where history reaches back, with the
+// name, category and availability it had. Items are ordered by category
+// and name.
+func (r *MenuRepository) GetMenuAsOf(ctx context.Context, at time.Time) ([]domain.MenuItemAsOf, error) {
+	query := `
+		SELECT m.id,
+			CASE WHEN h.id IS NULL THEN m.name ELSE h.name END,
+			CASE WHEN h.id IS NULL THEN m.description ELSE h.description END,
+			CASE WHEN h.id IS NULL THEN m.category ELSE h.category END,
+			CASE WHEN h.id IS NULL THEN m.image_url ELSE h.image_url END,
+			CASE WHEN h.id IS NULL THEN m.is_available ELSE h.is_available END,
+			p.new_price, p.changed_at,
+			h.id IS NULL
+		FROM menu_items m
+		JOIN LATERAL (
+			SELECT new_price, changed_at FROM menu_price_history
+			WHERE menu_item_id = m.id AND changed_at <= $1
+			ORDER BY changed_at DESC, id DESC
+			LIMIT 1
+		) p ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT id, name, description, category, image_url, is_available FROM menu_item_history
+			WHERE menu_item_id = m.id AND changed_at <= $1
+			ORDER BY changed_at DESC, id DESC
+			LIMIT 1
+		) h ON TRUE
+		WHERE m.created_at <= $1
+		ORDER BY 4, 2, m.id
+	`
+
+	rows, err := r.db.Query(ctx, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query menu as of %s: %w", at.Format(time.RFC3339), err)
+	}
+	defer rows.Close()
+
+	items := []domain.MenuItemAsOf{}
+	for rows.Next() {
+		var item domain.MenuItemAsOf
+		var description, imageURL *string
+		err := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&description,
+			&item.Category,
+			&imageURL,
+			&item.IsAvailable,
+			&item.Price,
+			&item.PriceSince,
+			&item.DetailsApproximate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan menu item: %w", err)
+		}
+		if description != nil {
+			item.Description = *description
+		}
+		if imageURL != nil {
+			item.ImageURL = *imageURL
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func recordPriceChange(ctx context.Context, tx pgx.Tx, itemID uuid.UUID, oldPrice *int64, newPrice int64, changedBy uuid.UUID, at time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO menu_price_history (id, menu_item_id, old_price, new_price, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, uuid.New(), itemID, oldPrice, newPrice, changedBy, at)
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", mapPgError(err))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package repository implements curated menu section data access
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// MenuSectionRepository handles the items pinned to curated menu sections
+type MenuSectionRepository struct {
+	db *database.Pool
+}
+
+// NewMenuSectionRepository creates a new menu section repository
+func NewMenuSectionRepository(db *database.Pool) *MenuSectionRepository {
+	return &MenuSectionRepository{db: db}
+}
+
+// GetPinned returns each section's pinned item IDs in display order.
+// Sections with nothing pinned are absent.
+func (r *MenuSectionRepository) GetPinned(ctx context.Context) (map[domain.MenuSectionKey][]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT section, menu_item_id
+		FROM menu_section_items
+		ORDER BY section, position
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query menu sections: %w", err)
+	}
+	defer rows.Close()
+
+	pinned := make(map[domain.MenuSectionKey][]uuid.UUID)
+	for rows.Next() {
+		var section string
+		var itemID uuid.UUID
+		if err := rows.Scan(&section, &itemID); err != nil {
+			return nil, fmt.Errorf("failed to scan menu section item: %w", err)
+		}
+		key := domain.MenuSectionKey(section)
+		pinned[key] = append(pinned[key], itemID)
+	}
+
+	return pinned, rows.Err()
+}
+
+// SetPinned replaces a section's pinned items with itemIDs, in that order.
+// An empty list unpins everything.
+func (r *MenuSectionRepository) SetPinned(ctx context.Context, section domain.MenuSectionKey, itemIDs []uuid.UUID, pinnedBy uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM menu_section_items WHERE section = $1`, string(section)); err != nil {
+			return fmt.Errorf("failed to clear menu section: %w", err)
+		}
+
+		for i, itemID := range itemIDs {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO menu_section_items (section, menu_item_id, position, pinned_by, created_at)
+				VALUES ($1, $2, $3, $4, NOW())
+			`, string(section), itemID, i+1, pinnedBy)
+			if err != nil {
+				return fmt.Errorf("failed to pin menu item: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetTopSellers returns the IDs of the available menu items with the most
+// units sold on paid orders created since the given time, best first. Test
+// and imported orders are left out.
+func (r *MenuSectionRepository) GetTopSellers(ctx context.Context, since time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT oi.menu_item_id
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN menu_items m ON m.id = oi.menu_item_id
+		WHERE o.created_at >= $1
+		  AND o.imported_at IS NULL
+		  AND NOT ($3 = ANY(o.tags))
+		  AND o.status::text = ANY($2)
+		  AND m.is_available = TRUE
+		GROUP BY oi.menu_item_id
+		ORDER BY SUM(oi.quantity) DESC, oi.menu_item_id
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, since, paidStatuses(), domain.OrderTagTest, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top sellers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan top seller: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
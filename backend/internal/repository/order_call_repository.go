@@ -0,0 +1,122 @@
+// Package repository implements masked call log data access
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// OrderCallRepository handles the log of calls placed about orders
+type OrderCallRepository struct {
+	db *database.Pool
+}
+
+// NewOrderCallRepository creates a new order call repository
+func NewOrderCallRepository(db *database.Pool) *OrderCallRepository {
+	return &OrderCallRepository{db: db}
+}
+
+// orderCallColumns is the column list matching scanOrderCall
+const orderCallColumns = `id, order_id, rider_id, provider, COALESCE(provider_call_id, ''), status,
+		duration_seconds, COALESCE(error, ''), created_at, ended_at`
+
+// Create logs a call about to be placed
+func (r *OrderCallRepository) Create(ctx context.Context, call *domain.OrderCall) error {
+	call.ID = uuid.New()
+	call.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO order_calls (id, order_id, rider_id, provider, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, call.ID, call.OrderID, call.RiderID, call.Provider, call.Status, call.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order call: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// UpdateStatus records a call's provider ID, status and, once it has ended,
+// its connected duration. errMsg is kept for calls the provider refused.
+func (r *OrderCallRepository) UpdateStatus(ctx context.Context, id uuid.UUID, providerCallID, status string, duration time.Duration, ended bool, errMsg string) error {
+	query := `
+		UPDATE order_calls
+		SET provider_call_id = COALESCE($2, provider_call_id),
+			status = $3,
+			duration_seconds = $4,
+			error = COALESCE($5, error),
+			ended_at = CASE WHEN $6 THEN COALESCE(ended_at, NOW()) END
+		WHERE id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, nullableString(providerCallID), status, int(duration.Seconds()), nullableString(errMsg), ended)
+	if err != nil {
+		return fmt.Errorf("failed to update order call: %w", mapPgError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasOpenCall reports whether a call about the order started after since is
+// still ringing or connected
+func (r *OrderCallRepository) HasOpenCall(ctx context.Context, orderID uuid.UUID, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM order_calls
+			WHERE order_id = $1 AND created_at > $2
+			  AND status IN ('INITIATED', 'RINGING', 'IN_PROGRESS')
+		)
+	`
+
+	var open bool
+	if err := r.db.QueryRow(ctx, query, orderID, since).Scan(&open); err != nil {
+		return false, fmt.Errorf("failed to check open order calls: %w", err)
+	}
+	return open, nil
+}
+
+// ListByOrder returns the calls placed about an order, oldest first
+func (r *OrderCallRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]domain.OrderCall, error) {
+	query := `
+		SELECT ` + orderCallColumns + `
+		FROM order_calls
+		WHERE order_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order calls: %w", err)
+	}
+	defer rows.Close()
+
+	calls := []domain.OrderCall{}
+	for rows.Next() {
+		var call domain.OrderCall
+		err := rows.Scan(
+			&call.ID,
+			&call.OrderID,
+			&call.RiderID,
+			&call.Provider,
+			&call.ProviderCallID,
+			&call.Status,
+			&call.DurationSeconds,
+			&call.Error,
+			&call.CreatedAt,
+			&call.EndedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order call: %w", err)
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, rows.Err()
+}
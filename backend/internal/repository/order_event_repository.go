@@ -0,0 +1,127 @@
+// Package repository implements the order events outbox
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// OrderEventRepository reads and settles the order events outbox. Events
+// are written by OrderRepository in the transaction that changes the order.
+type OrderEventRepository struct {
+	db *database.Pool
+}
+
+// NewOrderEventRepository creates a new order event repository
+func NewOrderEventRepository(db *database.Pool) *OrderEventRepository {
+	return &OrderEventRepository{db: db}
+}
+
+// recordOrderEvent adds an event with the order's state after a change to
+// the outbox, in the transaction that made it. previous is the status
+// before the change, empty for a new order; nothing is recorded if the
+// status didn't change.
+func recordOrderEvent(ctx context.Context, q database.Querier, orderID uuid.UUID, eventType domain.OrderEventType, previous domain.OrderStatus) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO order_events (order_id, event_type, payload)
+		SELECT id, $2, jsonb_build_object(
+			'event_type', $2::text,
+			'order_id', id,
+			'user_id', user_id,
+			'status', status,
+			'previous_status', NULLIF($3::text, ''),
+			'version', version,
+			'total_amount', total_amount,
+			'fulfillment_type', fulfillment_type,
+			'rider_id', rider_id,
+			'occurred_at', NOW()
+		)
+		FROM orders
+		WHERE id = $1 AND status::text IS DISTINCT FROM NULLIF($3::text, '')
+	`, orderID, eventType, string(previous))
+	if err != nil {
+		return fmt.Errorf("failed to record order event: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// ListUnpublished returns up to limit events still to publish, oldest
+// first
+func (r *OrderEventRepository) ListUnpublished(ctx context.Context, limit int) ([]domain.OrderEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, event_type, payload, created_at, published_at, attempts, last_error
+		FROM order_events
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.OrderEvent{}
+	for rows.Next() {
+		var e domain.OrderEvent
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.Type, &e.Payload, &e.CreatedAt, &e.PublishedAt, &e.Attempts, &e.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan order event: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkPublished stamps events as delivered to the sink
+func (r *OrderEventRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE order_events
+		SET published_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE id = ANY($1)
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to mark order events published: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt to publish events
+func (r *OrderEventRepository) RecordFailure(ctx context.Context, ids []int64, publishErr string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE order_events
+		SET attempts = attempts + 1, last_error = $2
+		WHERE id = ANY($1)
+	`, ids, publishErr)
+	if err != nil {
+		return fmt.Errorf("failed to record order event failure: %w", err)
+	}
+	return nil
+}
+
+// CountUnpublished returns how many events are waiting to be published
+func (r *OrderEventRepository) CountUnpublished(ctx context.Context) (int64, error) {
+	var n int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM order_events WHERE published_at IS NULL`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count order events: %w", err)
+	}
+	return n, nil
+}
+
+// DeletePublishedBefore deletes up to limit events published before
+// cutoff, returning how many were removed
+func (r *OrderEventRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM order_events
+		WHERE id IN (SELECT id FROM order_events WHERE published_at < $1 LIMIT $2)
+	`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published order events: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
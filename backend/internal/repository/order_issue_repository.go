@@ -0,0 +1,229 @@
+// Package repository implements order issue data access
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// OrderIssueRepository handles order issue persistence
+type OrderIssueRepository struct {
+	db *database.Pool
+}
+
+// NewOrderIssueRepository creates a new order issue repository
+func NewOrderIssueRepository(db *database.Pool) *OrderIssueRepository {
+	return &OrderIssueRepository{db: db}
+}
+
+// issueColumns is the column list matching scanIssue
+const issueColumns = `id, order_id, user_id, issue_type, status, items, COALESCE(description, ''),
+		remedy_amount, refund_amount, COALESCE(razorpay_refund_id, ''), COALESCE(refund_error, ''),
+		COALESCE(escalation_reason, ''), resolved_at, resolved_by, COALESCE(resolution_note, ''), created_at`
+
+// scanIssue scans a row selected with issueColumns
+func scanIssue(row pgx.Row) (*domain.OrderIssue, error) {
+	i := &domain.OrderIssue{}
+	var items []byte
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.UserID,
+		&i.Type,
+		&i.Status,
+		&items,
+		&i.Description,
+		&i.RemedyAmount,
+		&i.RefundAmount,
+		&i.RazorpayRefundID,
+		&i.RefundError,
+		&i.EscalationReason,
+		&i.ResolvedAt,
+		&i.ResolvedBy,
+		&i.ResolutionNote,
+		&i.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(items, &i.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode issue items: %w", err)
+	}
+	return i, nil
+}
+
+// Create stores a new issue with the status already decided; an
+// AUTO_RESOLVED issue is stamped resolved. Returns ErrDuplicateKey if the
+// order already has an issue.
+func (r *OrderIssueRepository) Create(ctx context.Context, i *domain.OrderIssue) error {
+	if i.Items == nil {
+		i.Items = []domain.ShortItem{}
+	}
+	items, err := json.Marshal(i.Items)
+	if err != nil {
+		return fmt.Errorf("failed to encode issue items: %w", err)
+	}
+
+	i.ID = uuid.New()
+	i.CreatedAt = time.Now()
+	if i.Status == domain.IssueAutoResolved {
+		i.ResolvedAt = &i.CreatedAt
+	}
+
+	query := `
+		INSERT INTO order_issues (id, order_id, user_id, issue_type, status, items, description,
+			remedy_amount, refund_amount, escalation_reason, resolved_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err = r.db.Exec(ctx, query,
+		i.ID,
+		i.OrderID,
+		i.UserID,
+		i.Type,
+		i.Status,
+		items,
+		nullableString(i.Description),
+		i.RemedyAmount,
+		i.RefundAmount,
+		nullableString(i.EscalationReason),
+		i.ResolvedAt,
+		i.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create order issue: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByID retrieves an issue by ID
+func (r *OrderIssueRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.OrderIssue, error) {
+	query := `SELECT ` + issueColumns + ` FROM order_issues WHERE id = $1`
+
+	i, err := scanIssue(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get order issue: %w", err)
+	}
+	return i, nil
+}
+
+// ListByOrder returns the issues reported on an order
+func (r *OrderIssueRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]domain.OrderIssue, error) {
+	query := `
+		SELECT ` + issueColumns + `
+		FROM order_issues
+		WHERE order_id = $1
+		ORDER BY created_at
+	`
+	return r.list(ctx, query, orderID)
+}
+
+// List returns issues newest first, only those with status when it isn't
+// empty
+func (r *OrderIssueRepository) List(ctx context.Context, status domain.OrderIssueStatus, limit, offset int) ([]domain.OrderIssue, error) {
+	query := `
+		SELECT ` + issueColumns + `
+		FROM order_issues
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	return r.list(ctx, query, string(status), limit, offset)
+}
+
+func (r *OrderIssueRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.OrderIssue, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := []domain.OrderIssue{}
+	for rows.Next() {
+		i, err := scanIssue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order issue: %w", err)
+		}
+		issues = append(issues, *i)
+	}
+
+	return issues, rows.Err()
+}
+
+// CountAutoResolvedSince counts the customer's issues resolved
+// automatically since the given time
+func (r *OrderIssueRepository) CountAutoResolvedSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM order_issues
+		WHERE user_id = $1 AND status = $2 AND created_at >= $3
+	`, userID, domain.IssueAutoResolved, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count order issues: %w", err)
+	}
+	return n, nil
+}
+
+// SetRefund records the refund issued for an issue, or why it failed
+func (r *OrderIssueRepository) SetRefund(ctx context.Context, id uuid.UUID, amount int64, refundID, refundError string) error {
+	query := `
+		UPDATE order_issues
+		SET refund_amount = $2, razorpay_refund_id = $3, refund_error = $4
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, amount, nullableString(refundID), nullableString(refundError)); err != nil {
+		return fmt.Errorf("failed to record issue refund: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// Escalate hands an automatically resolved issue whose refund failed over
+// to staff
+func (r *OrderIssueRepository) Escalate(ctx context.Context, id uuid.UUID, reason, refundError string) error {
+	query := `
+		UPDATE order_issues
+		SET status = $2, escalation_reason = $3, refund_error = $4, refund_amount = 0, resolved_at = NULL
+		WHERE id = $1 AND status = $5
+	`
+
+	result, err := r.db.Exec(ctx, query, id, domain.IssueEscalated, reason, nullableString(refundError), domain.IssueAutoResolved)
+	if err != nil {
+		return fmt.Errorf("failed to escalate order issue: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Resolve closes an escalated issue. Returns ErrVersionConflict if it
+// isn't escalated any more.
+func (r *OrderIssueRepository) Resolve(ctx context.Context, id, resolvedBy uuid.UUID, note string) error {
+	query := `
+		UPDATE order_issues
+		SET status = $2, resolution_note = $3, resolved_at = NOW(), resolved_by = $4
+		WHERE id = $1 AND status = $5
+	`
+
+	result, err := r.db.Exec(ctx, query, id, domain.IssueResolved, nullableString(note), resolvedBy, domain.IssueEscalated)
+	if err != nil {
+		return fmt.Errorf("failed to resolve order issue: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package repository implements order chat data access
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// OrderMessageRepository handles order chat messages
+type OrderMessageRepository struct {
+	db *database.Pool
+}
+
+// NewOrderMessageRepository creates a new order message repository
+func NewOrderMessageRepository(db *database.Pool) *OrderMessageRepository {
+	return &OrderMessageRepository{db: db}
+}
+
+// Create saves a new message
+func (r *OrderMessageRepository) Create(ctx context.Context, message *domain.OrderMessage) error {
+	message.ID = uuid.New()
+	message.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO order_messages (id, order_id, sender_id, sender_role, body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, message.ID, message.OrderID, message.SenderID, message.SenderRole, message.Body, message.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order message: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// List returns up to limit of an order's messages, oldest first. With after
+// set, only messages sent after it are returned, for polling.
+func (r *OrderMessageRepository) List(ctx context.Context, orderID uuid.UUID, after *time.Time, limit int) ([]domain.OrderMessage, error) {
+	query := `
+		SELECT id, order_id, sender_id, sender_role, body, created_at
+		FROM order_messages
+		WHERE order_id = $1 AND ($2::timestamptz IS NULL OR created_at > $2)
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []domain.OrderMessage{}
+	for rows.Next() {
+		var m domain.OrderMessage
+		if err := rows.Scan(&m.ID, &m.OrderID, &m.SenderID, &m.SenderRole, &m.Body, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// DeleteOlderThan deletes up to limit messages sent before cutoff,
+// returning how many were removed
+func (r *OrderMessageRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM order_messages
+		WHERE id IN (SELECT id FROM order_messages WHERE created_at < $1 LIMIT $2)
+	`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old order messages: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
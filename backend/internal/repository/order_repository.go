@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -14,6 +15,220 @@ import (
 	"fooddelivery/pkg/database"
 )
 
+// orderColumns is the column list matching scanOrder
+const orderColumns = `id, user_id, status, total_amount, razorpay_order_id, razorpay_payment_id, version,
+		fulfillment_type, pickup_code, pickup_verified_at, pickup_verified_by,
+		rider_id, dispatched_at, delivery_otp, delivery_otp_attempts, delivered_at,
+		delivery_confirmation, delivery_override_by, delivery_override_reason,
+		delivery_address, delivery_latitude, delivery_longitude, delivery_address_snapshot,
+		recipient_name, recipient_phone, no_cutlery, packaging, acknowledged_allergens, delivery_fee, delivery_distance_meters,
+		subtotal, packaging_charge, tax_amount, tax_rate_basis_points,
+		estimated_delivery_at, eta_adjustment_minutes, eta_weather_condition,
+		kitchen_slot_at, is_scheduled, delivery_slot_at,
+		imported_at, import_source, legacy_order_id,
+		payment_review_reason, payment_review_flagged_at, payment_review_resolved_at, payment_review_resolved_by,
+		cancelled_at, cancelled_by, cancellation_reason,
+		tags, attributes,
+		created_at, updated_at`
+
+// scanOrder scans a row selected with orderColumns
+func scanOrder(row pgx.Row) (*domain.Order, error) {
+	order := &domain.Order{}
+	var razorpayOrderID, razorpayPaymentID, pickupCode *string
+	var deliveryOTP, deliveryConfirmation, overrideReason *string
+	var deliveryAddress, etaWeather *string
+	var recipientName, recipientPhone *string
+	var importSource, legacyOrderID *string
+	var reviewReason *string
+	var reviewFlaggedAt, reviewResolvedAt *time.Time
+	var reviewResolvedBy *uuid.UUID
+	var cancelledAt *time.Time
+	var cancelledBy *uuid.UUID
+	var cancellationReason *string
+	var deliveryLat, deliveryLng *float64
+	var acknowledgedAllergens []string
+
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.Status,
+		&order.TotalAmount,
+		&razorpayOrderID,
+		&razorpayPaymentID,
+		&order.Version,
+		&order.FulfillmentType,
+		&pickupCode,
+		&order.PickupVerifiedAt,
+		&order.PickupVerifiedBy,
+		&order.RiderID,
+		&order.DispatchedAt,
+		&deliveryOTP,
+		&order.DeliveryOTPAttempts,
+		&order.DeliveredAt,
+		&deliveryConfirmation,
+		&order.DeliveryOverrideBy,
+		&overrideReason,
+		&deliveryAddress,
+		&deliveryLat,
+		&deliveryLng,
+		&order.DeliveryAddress,
+		&recipientName,
+		&recipientPhone,
+		&order.Preferences.NoCutlery,
+		&order.Preferences.Packaging,
+		&acknowledgedAllergens,
+		&order.DeliveryFee,
+		&order.DeliveryDistanceMeters,
+		&order.Subtotal,
+		&order.PackagingCharge,
+		&order.TaxAmount,
+		&order.TaxRateBasisPoints,
+		&order.EstimatedDeliveryAt,
+		&order.ETAAdjustmentMinutes,
+		&etaWeather,
+		&order.KitchenSlotAt,
+		&order.IsScheduled,
+		&order.DeliverySlotAt,
+		&order.ImportedAt,
+		&importSource,
+		&legacyOrderID,
+		&reviewReason,
+		&reviewFlaggedAt,
+		&reviewResolvedAt,
+		&reviewResolvedBy,
+		&cancelledAt,
+		&cancelledBy,
+		&cancellationReason,
+		&order.Tags,
+		&order.Attributes,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if razorpayOrderID != nil {
+		order.RazorpayOrderID = *razorpayOrderID
+	}
+	if razorpayPaymentID != nil {
+		order.RazorpayPaymentID = *razorpayPaymentID
+	}
+	if pickupCode != nil {
+		order.PickupCode = *pickupCode
+	}
+	if deliveryOTP != nil {
+		order.DeliveryOTP = *deliveryOTP
+	}
+	if deliveryConfirmation != nil {
+		order.DeliveryConfirmation = domain.DeliveryConfirmation(*deliveryConfirmation)
+	}
+	if overrideReason != nil {
+		order.DeliveryOverrideReason = *overrideReason
+	}
+	if etaWeather != nil {
+		order.ETAWeatherCondition = *etaWeather
+	}
+	if importSource != nil {
+		order.ImportSource = *importSource
+	}
+	if legacyOrderID != nil {
+		order.LegacyOrderID = *legacyOrderID
+	}
+	if reviewFlaggedAt != nil {
+		order.PaymentReview = &domain.PaymentReview{
+			FlaggedAt:  *reviewFlaggedAt,
+			ResolvedAt: reviewResolvedAt,
+			ResolvedBy: reviewResolvedBy,
+		}
+		if reviewReason != nil {
+			order.PaymentReview.Reason = *reviewReason
+		}
+	}
+	if cancelledAt != nil {
+		order.Cancellation = &domain.OrderCancellation{CancelledAt: *cancelledAt, CancelledBy: cancelledBy}
+		if cancellationReason != nil {
+			order.Cancellation.Reason = *cancellationReason
+		}
+	}
+	if deliveryLat != nil && deliveryLng != nil {
+		order.DeliveryLocation = &domain.DeliveryLocation{
+			Latitude:  *deliveryLat,
+			Longitude: *deliveryLng,
+		}
+		if deliveryAddress != nil {
+			order.DeliveryLocation.Address = *deliveryAddress
+		}
+	}
+	if recipientName != nil && recipientPhone != nil {
+		order.Recipient = &domain.OrderRecipient{Name: *recipientName, PhoneNumber: *recipientPhone}
+	}
+	for _, a := range acknowledgedAllergens {
+		order.AcknowledgedAllergens = append(order.AcknowledgedAllergens, domain.Allergen(a))
+	}
+
+	return order, nil
+}
+
+// orderItemColumns is the column list matching scanOrderItem
+const orderItemColumns = `id, order_id, menu_item_id, name, price, quantity, category, image_url, created_at, variant_id, variant_name, modifiers`
+
+// scanOrderItem scans a row selected with orderItemColumns
+func scanOrderItem(row pgx.Row) (*domain.OrderItem, error) {
+	item := &domain.OrderItem{}
+	var menuItemID *uuid.UUID
+	var category, imageURL, variantName *string
+
+	err := row.Scan(
+		&item.ID,
+		&item.OrderID,
+		&menuItemID,
+		&item.Name,
+		&item.Price,
+		&item.Quantity,
+		&category,
+		&imageURL,
+		&item.CreatedAt,
+		&item.VariantID,
+		&variantName,
+		&item.Modifiers,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if menuItemID != nil {
+		item.MenuItemID = *menuItemID
+	}
+	if category != nil {
+		item.Category = *category
+	}
+	if imageURL != nil {
+		item.ImageURL = *imageURL
+	}
+	if variantName != nil {
+		item.VariantName = *variantName
+	}
+
+	return item, nil
+}
+
+// nullableString maps empty strings to NULL so unique constraints ignore them
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nullableTime maps the zero time to NULL
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 // OrderRepository handles order data persistence
 type OrderRepository struct {
 	db *database.Pool
@@ -26,11 +241,17 @@ func NewOrderRepository(db *database.Pool) *OrderRepository {
 
 // Create inserts a new order with its items in a transaction
 func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error {
-	return r.db.ExecTx(ctx, func(tx pgx.Tx) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
 		// Insert order
 		orderQuery := `
-			INSERT INTO orders (id, user_id, status, total_amount, razorpay_order_id, version, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			INSERT INTO orders (id, user_id, status, total_amount, razorpay_order_id, version,
+				fulfillment_type, pickup_code, delivery_address, delivery_latitude, delivery_longitude,
+				delivery_fee, delivery_distance_meters, estimated_delivery_at, eta_adjustment_minutes,
+				eta_weather_condition, kitchen_slot_at, is_scheduled, recipient_name, recipient_phone,
+				created_at, updated_at, tags, delivery_address_snapshot, no_cutlery, packaging,
+				acknowledged_allergens, delivery_slot_at, subtotal, packaging_charge, tax_amount, tax_rate_basis_points)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+				$21, $22, COALESCE($23::text[], '{}'), $24, $25, $26, $27, $28, $29, $30, $31, $32)
 		`
 
 		order.ID = uuid.New()
@@ -39,72 +260,191 @@ func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error
 		order.CreatedAt = now
 		order.UpdatedAt = now
 
+		var deliveryAddress *string
+		var deliveryLat, deliveryLng *float64
+		if loc := order.DeliveryLocation; loc != nil {
+			deliveryAddress = &loc.Address
+			deliveryLat = &loc.Latitude
+			deliveryLng = &loc.Longitude
+		}
+
+		var acknowledgedAllergens []string
+		for _, a := range order.AcknowledgedAllergens {
+			acknowledgedAllergens = append(acknowledgedAllergens, string(a))
+		}
+
+		var recipientName, recipientPhone *string
+		if rcpt := order.Recipient; rcpt != nil {
+			recipientName = &rcpt.Name
+			recipientPhone = &rcpt.PhoneNumber
+		}
+
 		_, err := tx.Exec(ctx, orderQuery,
 			order.ID,
 			order.UserID,
 			order.Status,
 			order.TotalAmount,
-			order.RazorpayOrderID,
+			nullableString(order.RazorpayOrderID),
 			order.Version,
+			order.FulfillmentType,
+			nullableString(order.PickupCode),
+			deliveryAddress,
+			deliveryLat,
+			deliveryLng,
+			order.DeliveryFee,
+			order.DeliveryDistanceMeters,
+			order.EstimatedDeliveryAt,
+			order.ETAAdjustmentMinutes,
+			nullableString(order.ETAWeatherCondition),
+			order.KitchenSlotAt,
+			order.IsScheduled,
+			recipientName,
+			recipientPhone,
 			order.CreatedAt,
 			order.UpdatedAt,
+			order.Tags,
+			order.DeliveryAddress,
+			order.Preferences.NoCutlery,
+			order.Preferences.Packaging,
+			acknowledgedAllergens,
+			order.DeliverySlotAt,
+			order.Subtotal,
+			order.PackagingCharge,
+			order.TaxAmount,
+			order.TaxRateBasisPoints,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert order: %w", err)
+			return fmt.Errorf("failed to insert order: %w", mapPgError(err))
 		}
 
-		// Insert order items
-		itemQuery := `
-			INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`
-
+		// Insert order items in one round trip
 		for i := range order.Items {
 			order.Items[i].ID = uuid.New()
 			order.Items[i].OrderID = order.ID
 			order.Items[i].CreatedAt = now
+		}
+		if err := copyOrderItems(ctx, tx, order.Items); err != nil {
+			return fmt.Errorf("failed to insert order items: %w", mapPgError(err))
+		}
 
-			_, err := tx.Exec(ctx, itemQuery,
-				order.Items[i].ID,
-				order.Items[i].OrderID,
-				order.Items[i].MenuItemID,
-				order.Items[i].Name,
-				order.Items[i].Price,
-				order.Items[i].Quantity,
-				order.Items[i].CreatedAt,
-			)
-			if err != nil {
-				return fmt.Errorf("failed to insert order item: %w", err)
+		return recordOrderEvent(ctx, tx, order.ID, domain.OrderEventCreated, "")
+	})
+}
+
+// copyOrderItems inserts an order's line items with COPY, a single round
+// trip however large the cart. Items without a menu item (imported ones
+// whose dish is gone) are stored with a NULL menu_item_id.
+func copyOrderItems(ctx context.Context, tx pgx.Tx, items []domain.OrderItem) error {
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"order_items"},
+		[]string{"id", "order_id", "menu_item_id", "name", "price", "quantity", "category", "image_url", "created_at",
+			"variant_id", "variant_name", "modifiers"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			item := &items[i]
+			var menuItemID *uuid.UUID
+			if item.MenuItemID != uuid.Nil {
+				menuItemID = &item.MenuItemID
+			}
+			modifiers := item.Modifiers
+			if modifiers == nil {
+				modifiers = []domain.OrderItemModifier{} // The column is NOT NULL
 			}
+			return []any{
+				item.ID,
+				item.OrderID,
+				menuItemID,
+				item.Name,
+				item.Price,
+				item.Quantity,
+				nullableString(item.Category),
+				nullableString(item.ImageURL),
+				item.CreatedAt,
+				item.VariantID,
+				nullableString(item.VariantName),
+				modifiers,
+			}, nil
+		}),
+	)
+	return err
+}
+
+// Import inserts a historical order with its items, keeping the caller's
+// timestamps. Returns false without writing anything if the same legacy
+// order was already imported from that source. Imported orders are history,
+// so no order event is recorded.
+func (r *OrderRepository) Import(ctx context.Context, order *domain.Order) (bool, error) {
+	inserted := false
+
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		orderQuery := `
+			INSERT INTO orders (id, user_id, status, total_amount, version,
+				fulfillment_type, delivery_address, delivery_fee, delivered_at,
+				imported_at, import_source, legacy_order_id, created_at, updated_at, subtotal)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (import_source, legacy_order_id) WHERE legacy_order_id IS NOT NULL
+			DO NOTHING
+		`
+
+		order.ID = uuid.New()
+		order.Version = 1
+
+		var deliveryAddress *string
+		if order.DeliveryLocation != nil {
+			deliveryAddress = nullableString(order.DeliveryLocation.Address)
 		}
 
-		return nil
+		tag, err := tx.Exec(ctx, orderQuery,
+			order.ID,
+			order.UserID,
+			order.Status,
+			order.TotalAmount,
+			order.Version,
+			order.FulfillmentType,
+			deliveryAddress,
+			order.DeliveryFee,
+			order.DeliveredAt,
+			order.ImportedAt,
+			order.ImportSource,
+			order.LegacyOrderID,
+			order.CreatedAt,
+			order.UpdatedAt,
+			order.Subtotal,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert imported order: %w", mapPgError(err))
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+		inserted = true
+
+		for i := range order.Items {
+			order.Items[i].ID = uuid.New()
+			order.Items[i].OrderID = order.ID
+			order.Items[i].CreatedAt = order.CreatedAt
+		}
+		if err := copyOrderItems(ctx, tx, order.Items); err != nil {
+			return fmt.Errorf("failed to insert imported order items: %w", mapPgError(err))
+		}
+
+		return addOrderToStats(ctx, tx, order.ID)
 	})
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, nil
 }
 
 // GetByID retrieves an order with its items
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
 	orderQuery := `
-		SELECT id, user_id, status, total_amount, razorpay_order_id, razorpay_payment_id, version, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders
 		WHERE id = $1
 	`
 
-	order := &domain.Order{}
-	var razorpayOrderID, razorpayPaymentID *string
-
-	err := r.db.QueryRow(ctx, orderQuery, id).Scan(
-		&order.ID,
-		&order.UserID,
-		&order.Status,
-		&order.TotalAmount,
-		&razorpayOrderID,
-		&razorpayPaymentID,
-		&order.Version,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
-
+	order, err := scanOrder(r.db.QueryRow(ctx, orderQuery, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -112,13 +452,6 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Or
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	if razorpayOrderID != nil {
-		order.RazorpayOrderID = *razorpayOrderID
-	}
-	if razorpayPaymentID != nil {
-		order.RazorpayPaymentID = *razorpayPaymentID
-	}
-
 	// Fetch order items
 	items, err := r.getOrderItems(ctx, order.ID)
 	if err != nil {
@@ -126,6 +459,12 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Or
 	}
 	order.Items = items
 
+	proof, err := r.GetDeliveryProof(ctx, order.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	order.ProofOfDelivery = proof
+
 	return order, nil
 }
 
@@ -133,26 +472,12 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Or
 // Used by webhook handler to find the order for payment updates
 func (r *OrderRepository) GetByRazorpayOrderID(ctx context.Context, razorpayOrderID string) (*domain.Order, error) {
 	orderQuery := `
-		SELECT id, user_id, status, total_amount, razorpay_order_id, razorpay_payment_id, version, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders
 		WHERE razorpay_order_id = $1
 	`
 
-	order := &domain.Order{}
-	var rpOrderID, rpPaymentID *string
-
-	err := r.db.QueryRow(ctx, orderQuery, razorpayOrderID).Scan(
-		&order.ID,
-		&order.UserID,
-		&order.Status,
-		&order.TotalAmount,
-		&rpOrderID,
-		&rpPaymentID,
-		&order.Version,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
-
+	order, err := scanOrder(r.db.QueryRow(ctx, orderQuery, razorpayOrderID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -160,99 +485,170 @@ func (r *OrderRepository) GetByRazorpayOrderID(ctx context.Context, razorpayOrde
 		return nil, fmt.Errorf("failed to get order by razorpay ID: %w", err)
 	}
 
-	if rpOrderID != nil {
-		order.RazorpayOrderID = *rpOrderID
-	}
-	if rpPaymentID != nil {
-		order.RazorpayPaymentID = *rpPaymentID
-	}
-
 	return order, nil
 }
 
-// GetByUserID retrieves all orders for a user
-func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Order, error) {
+// OrderListFilter narrows a user's order history. Zero times leave the
+// range open; an empty Status matches every status.
+type OrderListFilter struct {
+	Status domain.OrderStatus
+	From   time.Time
+	To     time.Time
+}
+
+// GetByUserID retrieves one page of a user's orders, newest first
+func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, filter OrderListFilter, limit, offset int) ([]domain.Order, error) {
 	query := `
-		SELECT id, user_id, status, total_amount, razorpay_order_id, razorpay_payment_id, version, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders
 		WHERE user_id = $1
-		ORDER BY created_at DESC
+			AND ($2 = '' OR status::text = $2)
+			AND ($3::timestamptz IS NULL OR created_at >= $3)
+			AND ($4::timestamptz IS NULL OR created_at < $4)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5 OFFSET $6
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query,
+		userID,
+		string(filter.Status),
+		nullableTime(filter.From),
+		nullableTime(filter.To),
+		limit,
+		offset,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user orders: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []domain.Order
+	orders := []domain.Order{}
 	for rows.Next() {
-		var order domain.Order
-		var razorpayOrderID, razorpayPaymentID *string
-
-		err := rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.Status,
-			&order.TotalAmount,
-			&razorpayOrderID,
-			&razorpayPaymentID,
-			&order.Version,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
-
-		if razorpayOrderID != nil {
-			order.RazorpayOrderID = *razorpayOrderID
-		}
-		if razorpayPaymentID != nil {
-			order.RazorpayPaymentID = *razorpayPaymentID
-		}
-
-		orders = append(orders, order)
+		orders = append(orders, *order)
 	}
 
-	return orders, nil
+	return orders, rows.Err()
 }
 
 // UpdateStatus updates order status with optimistic locking
 // This is critical for payment processing to prevent race conditions
 func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID uuid.UUID, newStatus domain.OrderStatus, expectedVersion int) error {
-	// OPTIMISTIC LOCKING: Only update if version matches expected version
-	// This prevents race conditions where two concurrent requests try to update the same order
-	// If version doesn't match, another request already modified the order
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		// OPTIMISTIC LOCKING: Only update if version matches expected version
+		// This prevents race conditions where two concurrent requests try to update the same order
+		// If version doesn't match, another request already modified the order
+		query := `
+			WITH previous AS (
+				SELECT id AS order_id, status AS previous_status FROM orders WHERE id = $1 FOR UPDATE
+			)
+			UPDATE orders
+			SET status = $2, version = version + 1, updated_at = NOW()
+			FROM previous
+			WHERE orders.id = previous.order_id AND version = $3
+			RETURNING previous_status
+		`
+
+		var previous domain.OrderStatus
+		err := tx.QueryRow(ctx, query, orderID, newStatus, expectedVersion).Scan(&previous)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("failed to update order status: %w", mapPgError(err))
+			}
+			// No row updated: either order doesn't exist or version mismatch
+			if _, err := r.GetByID(ctx, orderID); errors.Is(err, ErrNotFound) {
+				return ErrNotFound
+			}
+			// Order exists but version mismatch - concurrent modification
+			return ErrVersionConflict
+		}
+
+		return recordOrderEvent(ctx, tx, orderID, domain.OrderEventStatusChanged, previous)
+	})
+}
+
+// changeStatus runs an UPDATE that moves an order out of previous, guarded
+// by version and status, and records the change in the order events outbox
+// in the same transaction. Returns ErrVersionConflict if no row matched.
+func (r *OrderRepository) changeStatus(ctx context.Context, orderID uuid.UUID, previous domain.OrderStatus, action, query string, args ...any) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", action, err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrVersionConflict
+		}
+		return recordOrderEvent(ctx, tx, orderID, domain.OrderEventStatusChanged, previous)
+	})
+}
+
+// Cancel moves an order to CANCELLED, recording who cancelled it (nil for
+// the system) and why. A paid order stops counting toward its customer's
+// order stats. Returns ErrVersionConflict if the order changed since
+// expectedVersion was read.
+func (r *OrderRepository) Cancel(ctx context.Context, orderID uuid.UUID, expectedVersion int, cancelledBy *uuid.UUID, reason string) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			WITH previous AS (
+				SELECT id AS order_id, status AS previous_status FROM orders WHERE id = $1 FOR UPDATE
+			)
+			UPDATE orders
+			SET status = $2, cancelled_at = NOW(), cancelled_by = $4, cancellation_reason = $5,
+				version = version + 1, updated_at = NOW()
+			FROM previous
+			WHERE orders.id = previous.order_id AND version = $3
+			RETURNING previous_status, previous_status::text = ANY($6)
+		`
+
+		var previous domain.OrderStatus
+		var wasPaid bool
+		err := tx.QueryRow(ctx, query, orderID, domain.OrderStatusCancelled, expectedVersion, cancelledBy,
+			nullableString(reason), paidStatuses()).Scan(&previous, &wasPaid)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("failed to cancel order: %w", mapPgError(err))
+			}
+			if _, err := r.GetByID(ctx, orderID); errors.Is(err, ErrNotFound) {
+				return ErrNotFound
+			}
+			return ErrVersionConflict
+		}
+
+		if err := recordOrderEvent(ctx, tx, orderID, domain.OrderEventStatusChanged, previous); err != nil {
+			return err
+		}
+		if !wasPaid {
+			return nil
+		}
+		return removeOrderFromStats(ctx, tx, orderID)
+	})
+}
+
+// ClaimLateCapture records a payment captured after its order was
+// cancelled, so it is refunded once. Returns false if the order isn't
+// cancelled or a payment was already recorded on it.
+func (r *OrderRepository) ClaimLateCapture(ctx context.Context, orderID uuid.UUID, paymentID string) (bool, error) {
 	query := `
 		UPDATE orders
-		SET status = $2, version = version + 1, updated_at = NOW()
-		WHERE id = $1 AND version = $3
+		SET razorpay_payment_id = $2, updated_at = NOW()
+		WHERE id = $1 AND status = $3 AND razorpay_payment_id IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, orderID, newStatus, expectedVersion)
+	result, err := r.db.Exec(ctx, query, orderID, paymentID, domain.OrderStatusCancelled)
 	if err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+		return false, fmt.Errorf("failed to record late capture: %w", mapPgError(err))
 	}
-
-	// If no rows affected, either order doesn't exist or version mismatch
-	if result.RowsAffected() == 0 {
-		// Check if order exists
-		_, err := r.GetByID(ctx, orderID)
-		if errors.Is(err, ErrNotFound) {
-			return ErrNotFound
-		}
-		// Order exists but version mismatch - concurrent modification
-		return ErrVersionConflict
-	}
-
-	return nil
+	return result.RowsAffected() == 1, nil
 }
 
 // UpdatePaymentStatus updates order with payment information atomically
 // Uses SERIALIZABLE isolation to ensure payment is recorded exactly once
 func (r *OrderRepository) UpdatePaymentStatus(ctx context.Context, orderID uuid.UUID, status domain.OrderStatus, paymentID string, expectedVersion int) error {
-	return r.db.ExecTxWithIsolation(ctx, pgx.Serializable, func(tx pgx.Tx) error {
+	err := r.db.ExecTxWithIsolation(ctx, pgx.Serializable, func(tx pgx.Tx) error {
 		// First, check current status to prevent double processing
 		var currentStatus domain.OrderStatus
 		var currentVersion int
@@ -273,8 +669,11 @@ func (r *OrderRepository) UpdatePaymentStatus(ctx context.Context, orderID uuid.
 			return ErrVersionConflict
 		}
 
-		// Prevent processing if already in a terminal state
-		if currentStatus == domain.OrderStatusPaid || currentStatus == domain.OrderStatusDelivered {
+		// Prevent processing if already in a terminal state (a late webhook
+		// must not revive a cancelled order)
+		if currentStatus == domain.OrderStatusPaid || currentStatus == domain.OrderStatusAccepted ||
+			currentStatus == domain.OrderStatusOutForDelivery || currentStatus == domain.OrderStatusDelivered ||
+			currentStatus == domain.OrderStatusCancelled {
 			// Already processed, idempotent success
 			return nil
 		}
@@ -288,37 +687,339 @@ func (r *OrderRepository) UpdatePaymentStatus(ctx context.Context, orderID uuid.
 
 		_, err = tx.Exec(ctx, updateQuery, orderID, status, paymentID)
 		if err != nil {
-			return fmt.Errorf("failed to update payment status: %w", err)
+			return fmt.Errorf("failed to update payment status: %w", mapPgError(err))
+		}
+		if err := recordOrderEvent(ctx, tx, orderID, domain.OrderEventStatusChanged, currentStatus); err != nil {
+			return err
 		}
 
-		return nil
+		// Count the order toward its customer's stats once it is paid
+		return addOrderToStats(ctx, tx, orderID)
 	})
+	return mapPgError(err)
 }
 
 // SetRazorpayOrderID updates the Razorpay order ID for an order
 func (r *OrderRepository) SetRazorpayOrderID(ctx context.Context, orderID uuid.UUID, razorpayOrderID string, expectedVersion int) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			WITH previous AS (
+				SELECT id AS order_id, status AS previous_status FROM orders WHERE id = $1 FOR UPDATE
+			)
+			UPDATE orders
+			SET razorpay_order_id = $2, status = $3, version = version + 1, updated_at = NOW()
+			FROM previous
+			WHERE orders.id = previous.order_id AND version = $4
+			RETURNING previous_status
+		`
+
+		var previous domain.OrderStatus
+		err := tx.QueryRow(ctx, query, orderID, razorpayOrderID, domain.OrderStatusAwaitingPayment, expectedVersion).Scan(&previous)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrVersionConflict
+			}
+			return fmt.Errorf("failed to set razorpay order ID: %w", mapPgError(err))
+		}
+
+		return recordOrderEvent(ctx, tx, orderID, domain.OrderEventStatusChanged, previous)
+	})
+}
+
+// FlagPaymentReview flags an order for payment review, reopening a resolved
+// review. The version bump makes a concurrent attempt to mark the order paid
+// fail its optimistic lock.
+func (r *OrderRepository) FlagPaymentReview(ctx context.Context, orderID uuid.UUID, reason string) error {
 	query := `
 		UPDATE orders
-		SET razorpay_order_id = $2, status = $3, version = version + 1, updated_at = NOW()
-		WHERE id = $1 AND version = $4
+		SET payment_review_reason = $2, payment_review_flagged_at = NOW(),
+			payment_review_resolved_at = NULL, payment_review_resolved_by = NULL,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(ctx, query, orderID, razorpayOrderID, domain.OrderStatusAwaitingPayment, expectedVersion)
+	result, err := r.db.Exec(ctx, query, orderID, reason)
 	if err != nil {
-		return fmt.Errorf("failed to set razorpay order ID: %w", err)
+		return fmt.Errorf("failed to flag order for payment review: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
 	}
 
+	return nil
+}
+
+// ResolvePaymentReview closes an open payment review. Returns ErrNotFound if
+// the order has no open review.
+func (r *OrderRepository) ResolvePaymentReview(ctx context.Context, orderID, resolvedBy uuid.UUID) error {
+	query := `
+		UPDATE orders
+		SET payment_review_resolved_at = NOW(), payment_review_resolved_by = $2,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND payment_review_flagged_at IS NOT NULL AND payment_review_resolved_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, orderID, resolvedBy)
+	if err != nil {
+		return fmt.Errorf("failed to resolve payment review: %w", mapPgError(err))
+	}
 	if result.RowsAffected() == 0 {
-		return ErrVersionConflict
+		return ErrNotFound
 	}
 
 	return nil
 }
 
+// GetOpenPaymentReviews returns orders awaiting payment review, oldest
+// flag first
+func (r *OrderRepository) GetOpenPaymentReviews(ctx context.Context, limit int) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE payment_review_flagged_at IS NOT NULL AND payment_review_resolved_at IS NULL
+		ORDER BY payment_review_flagged_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment reviews: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []domain.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, rows.Err()
+}
+
+// CompletePickup marks a pickup order as handed over after its code was verified.
+// The status and fulfillment guards make a replayed code a no-op conflict.
+func (r *OrderRepository) CompletePickup(ctx context.Context, orderID, verifiedBy uuid.UUID, expectedVersion int) error {
+	query := `
+		UPDATE orders
+		SET status = $2, pickup_verified_at = NOW(), pickup_verified_by = $3,
+			delivered_at = NOW(), delivery_confirmation = $7,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $4 AND status = $5 AND fulfillment_type = $6
+	`
+
+	return r.changeStatus(ctx, orderID, domain.OrderStatusAccepted, "complete pickup", query,
+		orderID,
+		domain.OrderStatusDelivered,
+		verifiedBy,
+		expectedVersion,
+		domain.OrderStatusAccepted,
+		domain.FulfillmentPickup,
+		domain.DeliveryConfirmedByPickupCode,
+	)
+}
+
+// Dispatch hands an accepted delivery order to a rider and stores the
+// hand-over OTP, resetting any previous attempt count
+func (r *OrderRepository) Dispatch(ctx context.Context, orderID, riderID uuid.UUID, otp string, expectedVersion int) error {
+	query := `
+		UPDATE orders
+		SET status = $2, rider_id = $3, delivery_otp = $4, delivery_otp_attempts = 0,
+			dispatched_at = NOW(), version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $5 AND status = $6 AND fulfillment_type = $7
+	`
+
+	return r.changeStatus(ctx, orderID, domain.OrderStatusAccepted, "dispatch order", query,
+		orderID,
+		domain.OrderStatusOutForDelivery,
+		riderID,
+		otp,
+		expectedVersion,
+		domain.OrderStatusAccepted,
+		domain.FulfillmentDelivery,
+	)
+}
+
+// IncrementDeliveryOTPAttempts records a wrong OTP and returns the new count.
+// Attempts are persisted (not versioned) so the lockout survives restarts.
+func (r *OrderRepository) IncrementDeliveryOTPAttempts(ctx context.Context, orderID uuid.UUID) (int, error) {
+	query := `
+		UPDATE orders
+		SET delivery_otp_attempts = delivery_otp_attempts + 1, updated_at = NOW()
+		WHERE id = $1
+		RETURNING delivery_otp_attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRow(ctx, query, orderID).Scan(&attempts); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to record delivery OTP attempt: %w", mapPgError(err))
+	}
+
+	return attempts, nil
+}
+
+// CompleteDelivery marks an out-for-delivery order as DELIVERED.
+// overrideBy and reason are only set for admin overrides.
+func (r *OrderRepository) CompleteDelivery(ctx context.Context, orderID uuid.UUID, confirmation domain.DeliveryConfirmation, overrideBy *uuid.UUID, reason string, expectedVersion int) error {
+	query := `
+		UPDATE orders
+		SET status = $2, delivered_at = NOW(), delivery_confirmation = $3,
+			delivery_override_by = $4, delivery_override_reason = $5,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $6 AND status = $7
+	`
+
+	return r.changeStatus(ctx, orderID, domain.OrderStatusOutForDelivery, "complete delivery", query,
+		orderID,
+		domain.OrderStatusDelivered,
+		confirmation,
+		overrideBy,
+		nullableString(reason),
+		expectedVersion,
+		domain.OrderStatusOutForDelivery,
+	)
+}
+
+// GetReadyForDispatch retrieves accepted delivery orders waiting for a rider,
+// oldest first, excluding orders already placed in a batch
+func (r *OrderRepository) GetReadyForDispatch(ctx context.Context, limit int) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders o
+		WHERE status = $1 AND fulfillment_type = $2
+			AND delivery_latitude IS NOT NULL
+			AND imported_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM delivery_batch_stops s WHERE s.order_id = o.id)
+		ORDER BY created_at
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, domain.OrderStatusAccepted, domain.FulfillmentDelivery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders ready for dispatch: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, nil
+}
+
+// GetActiveByRider retrieves orders currently out for delivery with a rider
+func (r *OrderRepository) GetActiveByRider(ctx context.Context, riderID uuid.UUID) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE rider_id = $1 AND status = $2 AND imported_at IS NULL
+		ORDER BY dispatched_at
+	`
+
+	rows, err := r.db.Query(ctx, query, riderID, domain.OrderStatusOutForDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rider orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, nil
+}
+
+// SaveDeliveryProof records the proof photo for an order, replacing any
+// earlier upload. Returns the storage key of the replaced photo, if any.
+func (r *OrderRepository) SaveDeliveryProof(ctx context.Context, proof *domain.DeliveryProof) (string, error) {
+	var previousKey string
+
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`SELECT storage_key FROM delivery_proofs WHERE order_id = $1 FOR UPDATE`,
+			proof.OrderID,
+		).Scan(&previousKey)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to check existing proof: %w", err)
+		}
+
+		query := `
+			INSERT INTO delivery_proofs (id, order_id, rider_id, storage_key, content_type, size_bytes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (order_id) DO UPDATE
+			SET id = EXCLUDED.id, rider_id = EXCLUDED.rider_id, storage_key = EXCLUDED.storage_key,
+				content_type = EXCLUDED.content_type, size_bytes = EXCLUDED.size_bytes,
+				created_at = EXCLUDED.created_at
+		`
+
+		proof.ID = uuid.New()
+		proof.CreatedAt = time.Now()
+
+		_, err = tx.Exec(ctx, query,
+			proof.ID,
+			proof.OrderID,
+			proof.RiderID,
+			proof.StorageKey,
+			proof.ContentType,
+			proof.SizeBytes,
+			proof.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save delivery proof: %w", mapPgError(err))
+		}
+		return nil
+	})
+
+	return previousKey, err
+}
+
+// GetDeliveryProof retrieves the proof photo metadata for an order
+func (r *OrderRepository) GetDeliveryProof(ctx context.Context, orderID uuid.UUID) (*domain.DeliveryProof, error) {
+	query := `
+		SELECT id, order_id, rider_id, storage_key, content_type, size_bytes, created_at
+		FROM delivery_proofs
+		WHERE order_id = $1
+	`
+
+	proof := &domain.DeliveryProof{}
+	err := r.db.QueryRow(ctx, query, orderID).Scan(
+		&proof.ID,
+		&proof.OrderID,
+		&proof.RiderID,
+		&proof.StorageKey,
+		&proof.ContentType,
+		&proof.SizeBytes,
+		&proof.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get delivery proof: %w", err)
+	}
+
+	return proof, nil
+}
+
 // getOrderItems retrieves all items for an order
 func (r *OrderRepository) getOrderItems(ctx context.Context, orderID uuid.UUID) ([]domain.OrderItem, error) {
 	query := `
-		SELECT id, order_id, menu_item_id, name, price, quantity, created_at
+		SELECT ` + orderItemColumns + `
 		FROM order_items
 		WHERE order_id = $1
 	`
@@ -331,97 +1032,182 @@ func (r *OrderRepository) getOrderItems(ctx context.Context, orderID uuid.UUID)
 
 	var items []domain.OrderItem
 	for rows.Next() {
-		var item domain.OrderItem
-		err := rows.Scan(
-			&item.ID,
-			&item.OrderID,
-			&item.MenuItemID,
-			&item.Name,
-			&item.Price,
-			&item.Quantity,
-			&item.CreatedAt,
-		)
+		item, err := scanOrderItem(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order item: %w", err)
 		}
-		items = append(items, item)
+		items = append(items, *item)
 	}
 
 	return items, nil
 }
 
-// GetAllOrders retrieves all orders (admin only)
-func (r *OrderRepository) GetAllOrders(ctx context.Context, limit, offset int) ([]domain.Order, error) {
+// OrderCursor is the position after the last order of a page, in
+// (created_at, id) order
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// AdminOrderFilter narrows the admin order listing to orders carrying all
+// of Tags and all of Attributes. A zero filter matches every order.
+type AdminOrderFilter struct {
+	Tags       []string
+	Attributes map[string]interface{}
+}
+
+// GetAllOrders retrieves a page of all orders, newest first (admin only).
+// With a cursor the page starts after it (keyset, so deep pages stay
+// cheap); offset is kept for callers that still page by position.
+func (r *OrderRepository) GetAllOrders(ctx context.Context, filter AdminOrderFilter, limit, offset int, after *OrderCursor) ([]domain.Order, error) {
+	var afterTime time.Time
+	var afterID uuid.UUID
+	if after != nil {
+		afterTime, afterID = after.CreatedAt, after.ID
+	}
+
+	attributes := "{}"
+	if len(filter.Attributes) > 0 {
+		data, err := json.Marshal(filter.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode attribute filter: %w", err)
+		}
+		attributes = string(data)
+	}
+
 	query := `
-		SELECT id, user_id, status, total_amount, razorpay_order_id, razorpay_payment_id, version, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		WHERE ($1::timestamptz IS NULL OR (created_at, id) < ($1, $2))
+			AND ($5::text[] IS NULL OR tags @> $5)
+			AND attributes @> $6::jsonb
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
 	`
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, nullableTime(afterTime), afterID, limit, offset, filter.Tags, attributes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query all orders: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []domain.Order
+	orders := []domain.Order{}
 	for rows.Next() {
-		var order domain.Order
-		var razorpayOrderID, razorpayPaymentID *string
-
-		err := rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.Status,
-			&order.TotalAmount,
-			&razorpayOrderID,
-			&razorpayPaymentID,
-			&order.Version,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
+		orders = append(orders, *order)
+	}
 
-		if razorpayOrderID != nil {
-			order.RazorpayOrderID = *razorpayOrderID
-		}
-		if razorpayPaymentID != nil {
-			order.RazorpayPaymentID = *razorpayPaymentID
-		}
+	return orders, rows.Err()
+}
 
-		orders = append(orders, order)
-	}
+// exportPageSize is how many orders StreamOrders loads per query
+const exportPageSize = 500
 
-	return orders, nil
+// OrderExportFilter selects orders for StreamOrders. Zero times leave the
+// range open; an empty Status matches every status.
+type OrderExportFilter struct {
+	From   time.Time
+	To     time.Time
+	Status domain.OrderStatus
 }
 
-// LogWebhook stores webhook attempt for audit trail
-func (r *OrderRepository) LogWebhook(ctx context.Context, source, eventType string, payload []byte, signatureValid bool, orderID *uuid.UUID, processingError string) error {
-	query := `
-		INSERT INTO webhook_logs (id, source, event_type, payload, signature_valid, processed, processing_error, order_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
+// StreamOrders calls fn for each matching order with its items, newest
+// first. Orders are loaded a page at a time (keyset on created_at, id) so
+// memory stays flat however many orders match. An error from fn stops the
+// scan and is returned.
+func (r *OrderRepository) StreamOrders(ctx context.Context, filter OrderExportFilter, fn func(*domain.Order) error) error {
+	var cursorTime time.Time
+	var cursorID uuid.UUID
 
-	processed := processingError == ""
+	for {
+		query := `
+			SELECT ` + orderColumns + `
+			FROM orders
+			WHERE ($1::timestamptz IS NULL OR created_at >= $1)
+				AND ($2::timestamptz IS NULL OR created_at < $2)
+				AND ($3 = '' OR status::text = $3)
+				AND ($4::timestamptz IS NULL OR (created_at, id) < ($4, $5))
+			ORDER BY created_at DESC, id DESC
+			LIMIT $6
+		`
 
-	_, err := r.db.Exec(ctx, query,
-		uuid.New(),
-		source,
-		eventType,
-		payload,
-		signatureValid,
-		processed,
-		processingError,
-		orderID,
-		time.Now(),
-	)
+		rows, err := r.db.Query(ctx, query,
+			nullableTime(filter.From),
+			nullableTime(filter.To),
+			string(filter.Status),
+			nullableTime(cursorTime),
+			cursorID,
+			exportPageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to query orders for export: %w", err)
+		}
+
+		var page []*domain.Order
+		for rows.Next() {
+			order, err := scanOrder(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan order: %w", err)
+			}
+			page = append(page, order)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read orders for export: %w", err)
+		}
+		if len(page) == 0 {
+			return nil
+		}
 
+		if err := r.attachItems(ctx, page); err != nil {
+			return err
+		}
+		for _, order := range page {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < exportPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		cursorTime, cursorID = last.CreatedAt, last.ID
+	}
+}
+
+// attachItems loads the items of several orders in one query
+func (r *OrderRepository) attachItems(ctx context.Context, orders []*domain.Order) error {
+	ids := make([]uuid.UUID, len(orders))
+	byID := make(map[uuid.UUID]*domain.Order, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		byID[order.ID] = order
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+orderItemColumns+`
+		FROM order_items
+		WHERE order_id = ANY($1)
+	`, ids)
 	if err != nil {
-		return fmt.Errorf("failed to log webhook: %w", err)
+		return fmt.Errorf("failed to query order items: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		item, err := scanOrderItem(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		if order, ok := byID[item.OrderID]; ok {
+			order.Items = append(order.Items, *item)
+		}
+	}
+
+	return rows.Err()
 }
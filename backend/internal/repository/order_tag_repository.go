@@ -0,0 +1,137 @@
+// Package repository implements order tags and custom attributes
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+)
+
+// UpdateTags adds and removes tags on an order in one statement, keeping the
+// result sorted and free of duplicates. The order is left untouched when the
+// result would exceed maxTags; applied reports whether it was updated.
+func (r *OrderRepository) UpdateTags(ctx context.Context, orderID uuid.UUID, add, remove []string, maxTags int) (tags []string, applied bool, err error) {
+	query := `
+		WITH next AS (
+			SELECT id, ARRAY(
+				SELECT DISTINCT t FROM unnest(array_cat(tags, $2::text[])) AS t
+				WHERE t <> ALL($3::text[])
+				ORDER BY t
+			) AS tags
+			FROM orders
+			WHERE id = $1
+			FOR UPDATE
+		), updated AS (
+			UPDATE orders o SET tags = next.tags
+			FROM next
+			WHERE o.id = next.id AND cardinality(next.tags) <= $4
+			RETURNING o.id
+		)
+		SELECT next.tags, EXISTS (SELECT 1 FROM updated) FROM next
+	`
+
+	if add == nil {
+		add = []string{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+
+	err = r.db.QueryRow(ctx, query, orderID, add, remove, maxTags).Scan(&tags, &applied)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, ErrNotFound
+		}
+		return nil, false, fmt.Errorf("failed to update order tags: %w", err)
+	}
+
+	return tags, applied, nil
+}
+
+// UpdateAttributes sets and removes custom attributes on an order, leaving
+// the others as they are, and returns the order's attributes
+func (r *OrderRepository) UpdateAttributes(ctx context.Context, orderID uuid.UUID, set map[string]interface{}, remove []string) (map[string]interface{}, error) {
+	if set == nil {
+		set = map[string]interface{}{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order attributes: %w", err)
+	}
+
+	query := `
+		UPDATE orders
+		SET attributes = (attributes || $2::jsonb) - $3::text[]
+		WHERE id = $1
+		RETURNING attributes
+	`
+
+	var attributes map[string]interface{}
+	if err := r.db.QueryRow(ctx, query, orderID, string(data), remove).Scan(&attributes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update order attributes: %w", mapPgError(err))
+	}
+
+	return attributes, nil
+}
+
+// GetAttributeDefinitions returns every defined order attribute by key
+func (r *OrderRepository) GetAttributeDefinitions(ctx context.Context) ([]domain.OrderAttributeDefinition, error) {
+	query := `
+		SELECT key, value_type, description, created_by, created_at, updated_at
+		FROM order_attribute_definitions
+		ORDER BY key
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order attribute definitions: %w", err)
+	}
+	defer rows.Close()
+
+	definitions := []domain.OrderAttributeDefinition{}
+	for rows.Next() {
+		var d domain.OrderAttributeDefinition
+		if err := rows.Scan(&d.Key, &d.Type, &d.Description, &d.CreatedBy, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order attribute definition: %w", err)
+		}
+		definitions = append(definitions, d)
+	}
+
+	return definitions, rows.Err()
+}
+
+// SaveAttributeDefinition defines an order attribute, or updates the
+// description of an existing one. Returns ErrDuplicateKey if the key is
+// already defined with a different type.
+func (r *OrderRepository) SaveAttributeDefinition(ctx context.Context, d *domain.OrderAttributeDefinition) error {
+	query := `
+		INSERT INTO order_attribute_definitions (key, value_type, description, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET description = EXCLUDED.description, updated_at = NOW()
+		WHERE order_attribute_definitions.value_type = EXCLUDED.value_type
+		RETURNING created_by, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, d.Key, d.Type, d.Description, d.CreatedBy).Scan(&d.CreatedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDuplicateKey
+		}
+		return fmt.Errorf("failed to save order attribute definition: %w", mapPgError(err))
+	}
+
+	return nil
+}
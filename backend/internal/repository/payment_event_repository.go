@@ -0,0 +1,109 @@
+// Package repository implements the payment audit trail
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// PaymentEventRepository handles payment event persistence
+type PaymentEventRepository struct {
+	db *database.Pool
+}
+
+// NewPaymentEventRepository creates a new payment event repository
+func NewPaymentEventRepository(db *database.Pool) *PaymentEventRepository {
+	return &PaymentEventRepository{db: db}
+}
+
+// Record appends an event to the audit trail
+func (r *PaymentEventRepository) Record(ctx context.Context, event *domain.PaymentEvent) error {
+	query := `
+		INSERT INTO payment_events (id, order_id, razorpay_order_id, razorpay_payment_id, source, event_type,
+			previous_status, new_status, outcome, amount, detail, webhook_log_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(ctx, query,
+		event.ID,
+		event.OrderID,
+		nullableString(event.RazorpayOrderID),
+		nullableString(event.RazorpayPaymentID),
+		event.Source,
+		event.EventType,
+		event.PreviousStatus,
+		event.NewStatus,
+		event.Outcome,
+		event.Amount,
+		nullableString(event.Detail),
+		event.WebhookLogID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record payment event: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// ListByOrder returns an order's payment events oldest first, including
+// gateway events that only matched its Razorpay order ID
+func (r *PaymentEventRepository) ListByOrder(ctx context.Context, orderID uuid.UUID, razorpayOrderID string) ([]domain.PaymentEvent, error) {
+	query := `
+		SELECT id, order_id, razorpay_order_id, razorpay_payment_id, source, event_type,
+			previous_status, new_status, outcome, amount, detail, webhook_log_id, created_at
+		FROM payment_events
+		WHERE order_id = $1 OR ($2 <> '' AND razorpay_order_id = $2)
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID, razorpayOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.PaymentEvent
+	for rows.Next() {
+		var e domain.PaymentEvent
+		var razorpayOrder, razorpayPayment, detail *string
+		if err := rows.Scan(
+			&e.ID,
+			&e.OrderID,
+			&razorpayOrder,
+			&razorpayPayment,
+			&e.Source,
+			&e.EventType,
+			&e.PreviousStatus,
+			&e.NewStatus,
+			&e.Outcome,
+			&e.Amount,
+			&detail,
+			&e.WebhookLogID,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan payment event: %w", err)
+		}
+		if razorpayOrder != nil {
+			e.RazorpayOrderID = *razorpayOrder
+		}
+		if razorpayPayment != nil {
+			e.RazorpayPaymentID = *razorpayPayment
+		}
+		if detail != nil {
+			e.Detail = *detail
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
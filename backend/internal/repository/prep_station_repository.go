@@ -0,0 +1,334 @@
+// Package repository implements kitchen prep station data access
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// Prep station errors
+var (
+	// ErrStationCategoryTaken is returned when a category is already
+	// mapped to another station
+	ErrStationCategoryTaken = errors.New("category is already prepared at another station")
+	// ErrStationBusy is returned when deleting a station with open tickets
+	ErrStationBusy = errors.New("station has open tickets")
+)
+
+// PrepStationRepository handles prep stations and their tickets
+type PrepStationRepository struct {
+	db *database.Pool
+}
+
+// NewPrepStationRepository creates a new prep station repository
+func NewPrepStationRepository(db *database.Pool) *PrepStationRepository {
+	return &PrepStationRepository{db: db}
+}
+
+// stationColumns is the column list matching scanStation
+const stationColumns = `s.id, s.name, s.is_default, s.sort_order, s.created_at, s.updated_at,
+	COALESCE((SELECT array_agg(c.category ORDER BY c.category) FROM prep_station_categories c WHERE c.station_id = s.id), '{}')`
+
+// scanStation scans a row selected with stationColumns
+func scanStation(row pgx.Row) (*domain.PrepStation, error) {
+	s := &domain.PrepStation{}
+	err := row.Scan(&s.ID, &s.Name, &s.IsDefault, &s.SortOrder, &s.CreatedAt, &s.UpdatedAt, &s.Categories)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListStations returns every station with its categories, in display order
+func (r *PrepStationRepository) ListStations(ctx context.Context) ([]domain.PrepStation, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+stationColumns+` FROM prep_stations s ORDER BY s.sort_order, s.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prep stations: %w", err)
+	}
+	defer rows.Close()
+
+	stations := []domain.PrepStation{}
+	for rows.Next() {
+		s, err := scanStation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prep station: %w", err)
+		}
+		stations = append(stations, *s)
+	}
+
+	return stations, rows.Err()
+}
+
+// GetStation retrieves a station by ID
+func (r *PrepStationRepository) GetStation(ctx context.Context, id uuid.UUID) (*domain.PrepStation, error) {
+	s, err := scanStation(r.db.QueryRow(ctx, `SELECT `+stationColumns+` FROM prep_stations s WHERE s.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get prep station: %w", err)
+	}
+	return s, nil
+}
+
+// CreateStation inserts a station and its categories. Returns
+// ErrDuplicateKey if the name or default flag is taken and
+// ErrStationCategoryTaken if a category is.
+func (r *PrepStationRepository) CreateStation(ctx context.Context, s *domain.PrepStation) error {
+	s.ID = uuid.New()
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = s.CreatedAt
+
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO prep_stations (id, name, is_default, sort_order, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+		`, s.ID, s.Name, s.IsDefault, s.SortOrder, s.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return setStationCategories(ctx, tx, s.ID, s.Categories)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create prep station: %w", stationError(err))
+	}
+	return nil
+}
+
+// UpdateStation replaces a station's settings and categories. Errors as
+// CreateStation, and ErrNotFound.
+func (r *PrepStationRepository) UpdateStation(ctx context.Context, s *domain.PrepStation) error {
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE prep_stations
+			SET name = $2, is_default = $3, sort_order = $4, updated_at = NOW()
+			WHERE id = $1
+			RETURNING created_at, updated_at
+		`, s.ID, s.Name, s.IsDefault, s.SortOrder).Scan(&s.CreatedAt, &s.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM prep_station_categories WHERE station_id = $1`, s.ID); err != nil {
+			return err
+		}
+		return setStationCategories(ctx, tx, s.ID, s.Categories)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update prep station: %w", stationError(err))
+	}
+	return nil
+}
+
+// setStationCategories maps categories to a station
+func setStationCategories(ctx context.Context, q database.Querier, stationID uuid.UUID, categories []string) error {
+	if len(categories) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, `
+		INSERT INTO prep_station_categories (category, station_id)
+		SELECT unnest($2::text[]), $1
+	`, stationID, categories)
+	return err
+}
+
+// stationError tells a taken category apart from other unique violations
+func stationError(err error) error {
+	var ce *ConstraintError
+	if errors.As(err, &ce) && errors.Is(ce.Kind, ErrDuplicateKey) && ce.Table == "prep_station_categories" {
+		return ErrStationCategoryTaken
+	}
+	return err
+}
+
+// DeleteStation removes a station. Returns ErrStationBusy while it has
+// open tickets for accepted orders.
+func (r *PrepStationRepository) DeleteStation(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM prep_stations s
+		WHERE s.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM station_tickets t JOIN orders o ON o.id = t.order_id
+			WHERE t.station_id = s.id AND t.status <> $2 AND o.status = $3
+		)
+	`, id, domain.TicketReady, domain.OrderStatusAccepted)
+	if err != nil {
+		return fmt.Errorf("failed to delete prep station: %w", mapPgError(err))
+	}
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+
+	if _, err := r.GetStation(ctx, id); err != nil {
+		return err
+	}
+	return ErrStationBusy
+}
+
+// ticketColumns is the column list matching scanTicket
+const ticketColumns = `t.id, t.order_id, t.station_id, s.name, t.status, t.items,
+	t.created_at, t.started_at, t.ready_at, t.ready_by`
+
+// scanTicket scans a row selected with ticketColumns
+func scanTicket(row pgx.Row) (*domain.StationTicket, error) {
+	t := &domain.StationTicket{}
+	var items []byte
+	err := row.Scan(&t.ID, &t.OrderID, &t.StationID, &t.StationName, &t.Status, &items,
+		&t.CreatedAt, &t.StartedAt, &t.ReadyAt, &t.ReadyBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(items, &t.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket items: %w", err)
+	}
+	return t, nil
+}
+
+// CreateTickets stores an order's tickets. A station that already has a
+// ticket for the order keeps it, so routing an order twice is harmless.
+func (r *PrepStationRepository) CreateTickets(ctx context.Context, tickets []domain.StationTicket) error {
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		for i := range tickets {
+			t := &tickets[i]
+			items, err := json.Marshal(t.Items)
+			if err != nil {
+				return fmt.Errorf("failed to encode ticket items: %w", err)
+			}
+			t.ID = uuid.New()
+			t.Status = domain.TicketQueued
+			t.CreatedAt = time.Now()
+			_, err = tx.Exec(ctx, `
+				INSERT INTO station_tickets (id, order_id, station_id, status, items, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (order_id, station_id) DO NOTHING
+			`, t.ID, t.OrderID, t.StationID, t.Status, items, t.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create station tickets: %w", err)
+	}
+	return nil
+}
+
+// GetTicket retrieves a ticket by ID
+func (r *PrepStationRepository) GetTicket(ctx context.Context, id uuid.UUID) (*domain.StationTicket, error) {
+	query := `
+		SELECT ` + ticketColumns + `
+		FROM station_tickets t JOIN prep_stations s ON s.id = t.station_id
+		WHERE t.id = $1
+	`
+	t, err := scanTicket(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get station ticket: %w", err)
+	}
+	return t, nil
+}
+
+// ListOpenTickets returns a station's tickets not yet ready, for orders
+// still in the kitchen, oldest first
+func (r *PrepStationRepository) ListOpenTickets(ctx context.Context, stationID uuid.UUID) ([]domain.StationTicket, error) {
+	query := `
+		SELECT ` + ticketColumns + `
+		FROM station_tickets t
+		JOIN prep_stations s ON s.id = t.station_id
+		JOIN orders o ON o.id = t.order_id
+		WHERE t.station_id = $1 AND t.status <> $2 AND o.status = $3
+		ORDER BY t.created_at
+	`
+	return r.listTickets(ctx, query, stationID, domain.TicketReady, domain.OrderStatusAccepted)
+}
+
+// ListKitchenTickets returns every ticket of orders still in the kitchen,
+// grouped by order, oldest order first
+func (r *PrepStationRepository) ListKitchenTickets(ctx context.Context) ([]domain.StationTicket, error) {
+	query := `
+		SELECT ` + ticketColumns + `
+		FROM station_tickets t
+		JOIN prep_stations s ON s.id = t.station_id
+		JOIN orders o ON o.id = t.order_id
+		WHERE o.status = $1
+		ORDER BY o.created_at, t.order_id, s.sort_order, s.name
+	`
+	return r.listTickets(ctx, query, domain.OrderStatusAccepted)
+}
+
+// ListOrderTickets returns an order's tickets in station display order
+func (r *PrepStationRepository) ListOrderTickets(ctx context.Context, orderID uuid.UUID) ([]domain.StationTicket, error) {
+	query := `
+		SELECT ` + ticketColumns + `
+		FROM station_tickets t JOIN prep_stations s ON s.id = t.station_id
+		WHERE t.order_id = $1
+		ORDER BY s.sort_order, s.name
+	`
+	return r.listTickets(ctx, query, orderID)
+}
+
+func (r *PrepStationRepository) listTickets(ctx context.Context, query string, args ...interface{}) ([]domain.StationTicket, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query station tickets: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []domain.StationTicket{}
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan station ticket: %w", err)
+		}
+		tickets = append(tickets, *t)
+	}
+
+	return tickets, rows.Err()
+}
+
+// StartTicket moves a queued ticket to PREPARING. Returns
+// ErrVersionConflict if it isn't queued.
+func (r *PrepStationRepository) StartTicket(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE station_tickets
+		SET status = $2, started_at = NOW()
+		WHERE id = $1 AND status = $3
+	`, id, domain.TicketPreparing, domain.TicketQueued)
+	if err != nil {
+		return fmt.Errorf("failed to start station ticket: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// ReadyTicket marks a ticket READY. Returns ErrVersionConflict if it
+// already is.
+func (r *PrepStationRepository) ReadyTicket(ctx context.Context, id, readyBy uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE station_tickets
+		SET status = $2, started_at = COALESCE(started_at, NOW()), ready_at = NOW(), ready_by = $3
+		WHERE id = $1 AND status <> $2
+	`, id, domain.TicketReady, readyBy)
+	if err != nil {
+		return fmt.Errorf("failed to mark station ticket ready: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
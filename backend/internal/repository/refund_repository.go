@@ -0,0 +1,226 @@
+// Package repository implements refund data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// ErrRefundExceedsPayment is returned when a refund would take the refunds
+// of a payment past the amount captured
+var ErrRefundExceedsPayment = errors.New("refunds would exceed the captured amount")
+
+// RefundRepository handles refund persistence
+type RefundRepository struct {
+	db *database.Pool
+}
+
+// NewRefundRepository creates a new refund repository
+func NewRefundRepository(db *database.Pool) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+// refundColumns is the column list matching scanRefund
+const refundColumns = `id, order_id, razorpay_payment_id, COALESCE(razorpay_refund_id, ''), amount, status,
+		reason, COALESCE(failure_reason, ''), initiated_by, created_at, updated_at`
+
+// scanRefund scans a row selected with refundColumns
+func scanRefund(row pgx.Row) (*domain.Refund, error) {
+	refund := &domain.Refund{}
+	err := row.Scan(
+		&refund.ID,
+		&refund.OrderID,
+		&refund.RazorpayPaymentID,
+		&refund.RazorpayRefundID,
+		&refund.Amount,
+		&refund.Status,
+		&refund.Reason,
+		&refund.FailureReason,
+		&refund.InitiatedBy,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// Create stores a pending refund before it is sent to Razorpay. Returns
+// ErrRefundExceedsPayment if, with the payment's other refunds that haven't
+// failed, it would return more than captured. Refunds of one order are
+// serialized on the order row so two can't both pass the check.
+func (r *RefundRepository) Create(ctx context.Context, refund *domain.Refund, captured int64) error {
+	refund.ID = uuid.New()
+	refund.Status = domain.RefundPending
+	refund.CreatedAt = time.Now()
+	refund.UpdatedAt = refund.CreatedAt
+
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var locked int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM orders WHERE id = $1 FOR UPDATE`, refund.OrderID).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to lock order for refund: %w", err)
+		}
+
+		var refunded int64
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(amount), 0) FROM refunds
+			WHERE razorpay_payment_id = $1 AND status <> 'FAILED'
+		`, refund.RazorpayPaymentID).Scan(&refunded)
+		if err != nil {
+			return fmt.Errorf("failed to sum refunds: %w", err)
+		}
+		if refunded+refund.Amount > captured {
+			return ErrRefundExceedsPayment
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO refunds (id, order_id, razorpay_payment_id, amount, status, reason, initiated_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`,
+			refund.ID,
+			refund.OrderID,
+			refund.RazorpayPaymentID,
+			refund.Amount,
+			refund.Status,
+			refund.Reason,
+			refund.InitiatedBy,
+			refund.CreatedAt,
+			refund.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create refund: %w", mapPgError(err))
+		}
+		return nil
+	})
+}
+
+// Settle records Razorpay's refund ID and a refund's outcome. Only a
+// pending refund changes status; the refund ID is kept once known, so a
+// webhook arriving before the gateway call returns is handled either way.
+// A processed refund comes off the customer's order stats. Returns the
+// refund and whether its status changed.
+func (r *RefundRepository) Settle(ctx context.Context, id uuid.UUID, razorpayRefundID string, status domain.RefundStatus, failureReason string) (*domain.Refund, bool, error) {
+	query := `
+		WITH previous AS (
+			SELECT id AS refund_id, status AS previous_status FROM refunds WHERE id = $1 FOR UPDATE
+		)
+		UPDATE refunds
+		SET razorpay_refund_id = COALESCE(razorpay_refund_id, $2),
+			status = CASE WHEN status = 'PENDING' THEN $3 ELSE status END,
+			failure_reason = CASE WHEN status = 'PENDING' THEN $4 ELSE failure_reason END,
+			updated_at = NOW()
+		FROM previous
+		WHERE refunds.id = previous.refund_id
+		RETURNING ` + refundColumns + `, previous_status <> status
+	`
+
+	refund := &domain.Refund{}
+	var changed bool
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, id, nullableString(razorpayRefundID), status, nullableString(failureReason)).Scan(
+			&refund.ID,
+			&refund.OrderID,
+			&refund.RazorpayPaymentID,
+			&refund.RazorpayRefundID,
+			&refund.Amount,
+			&refund.Status,
+			&refund.Reason,
+			&refund.FailureReason,
+			&refund.InitiatedBy,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+			&changed,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to settle refund: %w", err)
+		}
+
+		// Money returned no longer counts toward the customer's spend
+		if changed && refund.Status == domain.RefundProcessed {
+			return subtractRefundFromStats(ctx, tx, refund.OrderID, refund.Amount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return refund, changed, nil
+}
+
+// GetByID retrieves a refund
+func (r *RefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Refund, error) {
+	refund, err := scanRefund(r.db.QueryRow(ctx, `SELECT `+refundColumns+` FROM refunds WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return refund, nil
+}
+
+// GetByRazorpayRefundID retrieves a refund by Razorpay's ID for it
+func (r *RefundRepository) GetByRazorpayRefundID(ctx context.Context, razorpayRefundID string) (*domain.Refund, error) {
+	refund, err := scanRefund(r.db.QueryRow(ctx, `SELECT `+refundColumns+` FROM refunds WHERE razorpay_refund_id = $1`, razorpayRefundID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return refund, nil
+}
+
+// ListByOrder returns an order's refunds oldest first
+func (r *RefundRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]domain.Refund, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+refundColumns+`
+		FROM refunds
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := []domain.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, *refund)
+	}
+
+	return refunds, rows.Err()
+}
+
+// RefundedAmount totals the refunds of a payment that haven't failed
+func (r *RefundRepository) RefundedAmount(ctx context.Context, razorpayPaymentID string) (int64, error) {
+	var refunded int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM refunds
+		WHERE razorpay_payment_id = $1 AND status <> 'FAILED'
+	`, razorpayPaymentID).Scan(&refunded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+	return refunded, nil
+}
@@ -0,0 +1,124 @@
+// Package repository implements runtime setting overrides and their history
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// SettingsRepository handles setting persistence
+type SettingsRepository struct {
+	db *database.Pool
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(db *database.Pool) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// GetOverrides returns every stored override keyed by setting key. Only Key,
+// Value, UpdatedBy and UpdatedAt are populated.
+func (r *SettingsRepository) GetOverrides(ctx context.Context) (map[string]domain.Setting, error) {
+	rows, err := r.db.Query(ctx, `SELECT key, value, updated_by, updated_at FROM settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settings: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]domain.Setting)
+	for rows.Next() {
+		var s domain.Setting
+		if err := rows.Scan(&s.Key, &s.Value, &s.UpdatedBy, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		overrides[s.Key] = s
+	}
+
+	return overrides, rows.Err()
+}
+
+// Set stores an override and records the change in one transaction
+func (r *SettingsRepository) Set(ctx context.Context, key string, value json.RawMessage, changedBy uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var old json.RawMessage
+		err := tx.QueryRow(ctx, `SELECT value FROM settings WHERE key = $1 FOR UPDATE`, key).Scan(&old)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to lock setting: %w", err)
+		}
+
+		now := time.Now()
+		_, err = tx.Exec(ctx, `
+			INSERT INTO settings (key, value, updated_by, updated_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (key) DO UPDATE SET value = $2, updated_by = $3, updated_at = $4
+		`, key, value, changedBy, now)
+		if err != nil {
+			return fmt.Errorf("failed to save setting: %w", mapPgError(err))
+		}
+
+		return recordSettingChange(ctx, tx, key, old, value, changedBy, now)
+	})
+}
+
+// Delete removes an override so the default applies again. Returns
+// ErrNotFound if the setting was not overridden.
+func (r *SettingsRepository) Delete(ctx context.Context, key string, changedBy uuid.UUID) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		var old json.RawMessage
+		err := tx.QueryRow(ctx, `DELETE FROM settings WHERE key = $1 RETURNING value`, key).Scan(&old)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("failed to delete setting: %w", err)
+		}
+
+		return recordSettingChange(ctx, tx, key, old, nil, changedBy, time.Now())
+	})
+}
+
+// ListChanges returns a setting's history, newest first
+func (r *SettingsRepository) ListChanges(ctx context.Context, key string, limit int) ([]domain.SettingChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, key, old_value, new_value, changed_by, changed_at
+		FROM setting_changes
+		WHERE key = $1
+		ORDER BY changed_at DESC
+		LIMIT $2
+	`, key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query setting changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []domain.SettingChange{}
+	for rows.Next() {
+		var c domain.SettingChange
+		if err := rows.Scan(&c.ID, &c.Key, &c.OldValue, &c.NewValue, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+func recordSettingChange(ctx context.Context, tx pgx.Tx, key string, old, value json.RawMessage, changedBy uuid.UUID, at time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO setting_changes (id, key, old_value, new_value, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, uuid.New(), key, old, value, changedBy, at)
+	if err != nil {
+		return fmt.Errorf("failed to record setting change: %w", mapPgError(err))
+	}
+	return nil
+}
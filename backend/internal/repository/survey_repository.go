@@ -0,0 +1,218 @@
+// Package repository implements post-delivery survey data access
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// SurveyRepository handles order survey persistence
+type SurveyRepository struct {
+	db *database.Pool
+}
+
+// NewSurveyRepository creates a new survey repository
+func NewSurveyRepository(db *database.Pool) *SurveyRepository {
+	return &SurveyRepository{db: db}
+}
+
+const surveyColumns = `id, order_id, user_id, send_after, sent_at, score, comment, responded_at,
+		alert_acknowledged_at, alert_acknowledged_by, created_at`
+
+// scanSurvey scans a row selected with surveyColumns
+func scanSurvey(row pgx.Row) (*domain.OrderSurvey, error) {
+	survey := &domain.OrderSurvey{}
+	var comment *string
+
+	err := row.Scan(
+		&survey.ID,
+		&survey.OrderID,
+		&survey.UserID,
+		&survey.SendAfter,
+		&survey.SentAt,
+		&survey.Score,
+		&comment,
+		&survey.RespondedAt,
+		&survey.AlertAcknowledgedAt,
+		&survey.AlertAcknowledgedBy,
+		&survey.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment != nil {
+		survey.Comment = *comment
+	}
+
+	return survey, nil
+}
+
+// querySurveys runs a query selecting surveyColumns
+func (r *SurveyRepository) querySurveys(ctx context.Context, query string, args ...interface{}) ([]domain.OrderSurvey, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query surveys: %w", err)
+	}
+	defer rows.Close()
+
+	var surveys []domain.OrderSurvey
+	for rows.Next() {
+		survey, err := scanSurvey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey: %w", err)
+		}
+		surveys = append(surveys, *survey)
+	}
+
+	return surveys, rows.Err()
+}
+
+// Create schedules a survey. An order only ever gets one survey, so a
+// repeat call for the same order is a no-op.
+func (r *SurveyRepository) Create(ctx context.Context, survey *domain.OrderSurvey) error {
+	query := `
+		INSERT INTO order_surveys (id, order_id, user_id, send_after, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+
+	survey.ID = uuid.New()
+	survey.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(ctx, query, survey.ID, survey.OrderID, survey.UserID, survey.SendAfter, survey.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create survey: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetByOrderID returns the survey for an order
+func (r *SurveyRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) (*domain.OrderSurvey, error) {
+	query := `SELECT ` + surveyColumns + ` FROM order_surveys WHERE order_id = $1`
+
+	survey, err := scanSurvey(r.db.QueryRow(ctx, query, orderID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+
+	return survey, nil
+}
+
+// GetDue returns unsent, unanswered surveys whose send time has passed
+func (r *SurveyRepository) GetDue(ctx context.Context, limit int) ([]domain.OrderSurvey, error) {
+	query := `
+		SELECT ` + surveyColumns + `
+		FROM order_surveys
+		WHERE sent_at IS NULL AND responded_at IS NULL AND send_after <= NOW()
+		ORDER BY send_after
+		LIMIT $1
+	`
+	return r.querySurveys(ctx, query, limit)
+}
+
+// MarkSent records that the survey push went out
+func (r *SurveyRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE order_surveys SET sent_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark survey sent: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// GetOpenByUser returns the user's unanswered surveys created since
+func (r *SurveyRepository) GetOpenByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]domain.OrderSurvey, error) {
+	query := `
+		SELECT ` + surveyColumns + `
+		FROM order_surveys
+		WHERE user_id = $1 AND responded_at IS NULL AND created_at >= $2
+		ORDER BY created_at DESC
+	`
+	return r.querySurveys(ctx, query, userID, since)
+}
+
+// Respond stores the customer's answer. Returns false if already answered.
+func (r *SurveyRepository) Respond(ctx context.Context, id uuid.UUID, score int, comment string) (bool, error) {
+	query := `
+		UPDATE order_surveys
+		SET score = $2, comment = $3, responded_at = NOW()
+		WHERE id = $1 AND responded_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, score, nullableString(comment))
+	if err != nil {
+		return false, fmt.Errorf("failed to save survey response: %w", mapPgError(err))
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// GetNPS aggregates responses received in [from, to)
+func (r *SurveyRepository) GetNPS(ctx context.Context, from, to time.Time) (*domain.NPSSummary, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE score >= 9),
+			COUNT(*) FILTER (WHERE score BETWEEN 7 AND 8),
+			COUNT(*) FILTER (WHERE score <= 6)
+		FROM order_surveys
+		WHERE responded_at >= $1 AND responded_at < $2
+	`
+
+	summary := &domain.NPSSummary{From: from, To: to}
+	err := r.db.QueryRow(ctx, query, from, to).Scan(
+		&summary.Responses,
+		&summary.Promoters,
+		&summary.Passives,
+		&summary.Detractors,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute NPS: %w", err)
+	}
+
+	return summary, nil
+}
+
+// ListLowScores returns responses at or below threshold, newest first.
+// Acknowledged alerts are only included when requested.
+func (r *SurveyRepository) ListLowScores(ctx context.Context, threshold int, includeAcknowledged bool, limit int) ([]domain.OrderSurvey, error) {
+	query := `
+		SELECT ` + surveyColumns + `
+		FROM order_surveys
+		WHERE score <= $1 AND ($2 OR alert_acknowledged_at IS NULL)
+		ORDER BY responded_at DESC
+		LIMIT $3
+	`
+	return r.querySurveys(ctx, query, threshold, includeAcknowledged, limit)
+}
+
+// AcknowledgeAlert marks a low-score alert as handled
+func (r *SurveyRepository) AcknowledgeAlert(ctx context.Context, id, adminID uuid.UUID) error {
+	query := `
+		UPDATE order_surveys
+		SET alert_acknowledged_at = NOW(), alert_acknowledged_by = $2
+		WHERE id = $1 AND responded_at IS NOT NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, adminID)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge survey alert: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
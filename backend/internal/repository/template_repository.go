@@ -0,0 +1,74 @@
+// Package repository implements message template overrides
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// TemplateRepository handles message template persistence
+type TemplateRepository struct {
+	db *database.Pool
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *database.Pool) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// GetOverrides returns every stored override. Channel is not stored and is
+// left empty.
+func (r *TemplateRepository) GetOverrides(ctx context.Context) ([]domain.MessageTemplate, error) {
+	rows, err := r.db.Query(ctx, `SELECT key, locale, subject, body, updated_by, updated_at FROM message_templates`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []domain.MessageTemplate{}
+	for rows.Next() {
+		var t domain.MessageTemplate
+		if err := rows.Scan(&t.Key, &t.Locale, &t.Subject, &t.Body, &t.UpdatedBy, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+// Set stores an override for one key and locale
+func (r *TemplateRepository) Set(ctx context.Context, t *domain.MessageTemplate, updatedBy uuid.UUID) error {
+	now := time.Now()
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO message_templates (key, locale, subject, body, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key, locale) DO UPDATE SET subject = $3, body = $4, updated_by = $5, updated_at = $6
+	`, t.Key, t.Locale, t.Subject, t.Body, updatedBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to save template: %w", mapPgError(err))
+	}
+
+	t.UpdatedBy = &updatedBy
+	t.UpdatedAt = &now
+	return nil
+}
+
+// Delete removes an override so the embedded default applies again.
+// Returns ErrNotFound if there was no override.
+func (r *TemplateRepository) Delete(ctx context.Context, key, locale string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM message_templates WHERE key = $1 AND locale = $2`, key, locale)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -6,24 +6,72 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 
 	"fooddelivery/internal/domain"
 	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/pii"
 )
 
-// Common repository errors
-var (
-	ErrNotFound      = errors.New("record not found")
-	ErrDuplicateKey  = errors.New("duplicate key violation")
-	ErrVersionConflict = errors.New("version conflict - record was modified")
+// userColumns is the column list matching scanUser
+const userColumns = `id, phone_number, phone_number_enc, name, email, email_enc, password_hash, email_verified, is_admin, is_rider, created_at, updated_at,
+	deactivated_at, deactivated_by, COALESCE(deactivation_reason, ''), COALESCE(locale, ''), COALESCE(detected_locale, '')`
+
+// Field names bound into PII ciphertexts, so a value can't be moved between columns
+const (
+	fieldUserPhone  = "users.phone_number"
+	fieldUserEmail  = "users.email"
+	fieldOTPPhone   = "otps.phone_number"
+	fieldOTPEmail   = "otps.email"
+	piiBackfillSize = 500
 )
 
+// ErrPIIKeyMissing is returned when encrypted PII is read without keys configured
+var ErrPIIKeyMissing = errors.New("encrypted PII found but no encryption key is configured")
+
+// scanUser scans a row selected with userColumns, decrypting PII
+func (r *UserRepository) scanUser(row pgx.Row) (*domain.User, error) {
+	user := &domain.User{}
+	var phone, phoneEnc, email, emailEnc *string
+	err := row.Scan(
+		&user.ID,
+		&phone,
+		&phoneEnc,
+		&user.Name,
+		&email,
+		&emailEnc,
+		&user.PasswordHash,
+		&user.EmailVerified,
+		&user.IsAdmin,
+		&user.IsRider,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.DeactivatedBy,
+		&user.DeactivationReason,
+		&user.Locale,
+		&user.DetectedLocale,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PhoneNumber, err = r.openPII(phone, phoneEnc, fieldUserPhone); err != nil {
+		return nil, err
+	}
+	if user.Email, err = r.openPII(email, emailEnc, fieldUserEmail); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // UserRepository handles user data persistence
 type UserRepository struct {
-	db *database.Pool
+	db  *database.Pool
+	pii *pii.Protector
 }
 
 // NewUserRepository creates a new user repository
@@ -31,31 +79,100 @@ func NewUserRepository(db *database.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// SetPIIProtector enables encryption of phone numbers and emails. Without
+// it values are stored in plaintext (local development only).
+func (r *UserRepository) SetPIIProtector(p *pii.Protector) {
+	r.pii = p
+}
+
+// sealedPII is how one PII value is written: either plaintext, or
+// ciphertext plus blind index
+type sealedPII struct {
+	plain *string
+	enc   *string
+	bidx  *string
+}
+
+// sealPII prepares a value for storage
+func (r *UserRepository) sealPII(value *string, field, indexDomain string) (sealedPII, error) {
+	if value == nil || r.pii == nil {
+		return sealedPII{plain: value}, nil
+	}
+
+	enc, err := r.pii.Encrypt(*value, field)
+	if err != nil {
+		return sealedPII{}, err
+	}
+	bidx := r.pii.BlindIndex(*value, indexDomain)
+	return sealedPII{enc: &enc, bidx: &bidx}, nil
+}
+
+// openPII returns the stored value, preferring ciphertext over any
+// not-yet-migrated plaintext
+func (r *UserRepository) openPII(plain, enc *string, field string) (string, error) {
+	if enc == nil {
+		if plain == nil {
+			return "", nil
+		}
+		return *plain, nil
+	}
+	if r.pii == nil {
+		return "", ErrPIIKeyMissing
+	}
+	value, err := r.pii.Decrypt(*enc, field)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+	return value, nil
+}
+
+// lookupIndex returns the blind index to search for, or nil when PII is
+// stored in plaintext
+func (r *UserRepository) lookupIndex(value, indexDomain string) *string {
+	if r.pii == nil {
+		return nil
+	}
+	bidx := r.pii.BlindIndex(value, indexDomain)
+	return &bidx
+}
+
 // Create inserts a new user into the database
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (id, phone_number, name, email, password_hash, email_verified, is_admin, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, phone_number, phone_number_enc, phone_number_bidx, name,
+			email, email_enc, email_bidx, password_hash, email_verified, is_admin, is_rider, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
+	phone, err := r.sealPII(&user.PhoneNumber, fieldUserPhone, pii.IndexPhone)
+	if err != nil {
+		return err
+	}
+	email, err := r.sealPII(&user.Email, fieldUserEmail, pii.IndexEmail)
+	if err != nil {
+		return err
+	}
+
 	user.ID = uuid.New()
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		user.ID,
-		user.PhoneNumber,
+		phone.plain,
+		phone.enc,
+		phone.bidx,
 		user.Name,
-		user.Email,
+		email.plain,
+		email.enc,
+		email.bidx,
 		user.PasswordHash,
 		user.EmailVerified,
 		user.IsAdmin,
+		user.IsRider,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
-			return ErrDuplicateKey
-		}
-		return fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to create user: %w", mapPgError(err))
 	}
 
 	return nil
@@ -64,24 +181,12 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 // GetByID retrieves a user by their UUID
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	query := `
-		SELECT id, phone_number, name, email, password_hash, email_verified, is_admin, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 	`
 
-	user := &domain.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.PhoneNumber,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.EmailVerified,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+	user, err := r.scanUser(r.db.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -95,24 +200,12 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 // GetByPhoneNumber retrieves a user by phone number
 func (r *UserRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*domain.User, error) {
 	query := `
-		SELECT id, phone_number, name, email, password_hash, email_verified, is_admin, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE phone_number = $1
+		WHERE phone_number_bidx = $1 OR phone_number = $2
 	`
 
-	user := &domain.User{}
-	err := r.db.QueryRow(ctx, query, phoneNumber).Scan(
-		&user.ID,
-		&user.PhoneNumber,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.EmailVerified,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+	user, err := r.scanUser(r.db.QueryRow(ctx, query, r.lookupIndex(phoneNumber, pii.IndexPhone), phoneNumber))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -126,24 +219,12 @@ func (r *UserRepository) GetByPhoneNumber(ctx context.Context, phoneNumber strin
 // GetByEmail retrieves a user by email address
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, phone_number, name, email, password_hash, email_verified, is_admin, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1
+		WHERE email_bidx = $1 OR email = $2
 	`
 
-	user := &domain.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.PhoneNumber,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.EmailVerified,
-		&user.IsAdmin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-
+	user, err := r.scanUser(r.db.QueryRow(ctx, query, r.lookupIndex(email, pii.IndexEmail), email))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -158,19 +239,27 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	query := `
 		UPDATE users
-		SET name = $2, email = $3, is_admin = $4, updated_at = NOW()
+		SET name = $2, email = $3, email_enc = $4, email_bidx = $5, is_admin = $6, is_rider = $7, updated_at = NOW()
 		WHERE id = $1
 	`
 
+	email, err := r.sealPII(&user.Email, fieldUserEmail, pii.IndexEmail)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.Exec(ctx, query,
 		user.ID,
 		user.Name,
-		user.Email,
+		email.plain,
+		email.enc,
+		email.bidx,
 		user.IsAdmin,
+		user.IsRider,
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		return fmt.Errorf("failed to update user: %w", mapPgError(err))
 	}
 
 	if result.RowsAffected() == 0 {
@@ -180,38 +269,127 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
-// isDuplicateKeyError checks if the error is a unique constraint violation
-func isDuplicateKeyError(err error) bool {
-	// PostgreSQL error code 23505 is unique_violation
-	return err != nil && (contains(err.Error(), "23505") || contains(err.Error(), "duplicate key"))
-}
+// EncryptPendingPII encrypts one batch of users whose phone or email is
+// still in plaintext or was encrypted under an older key, clearing the
+// plaintext. Returns how many users were rewritten; 0 means done.
+func (r *UserRepository) EncryptPendingPII(ctx context.Context) (int, error) {
+	if r.pii == nil {
+		return 0, ErrPIIKeyMissing
+	}
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
-}
+	updated := 0
+	err := execTx(ctx, r.db, func(tx pgx.Tx) error {
+		stalePrefix := r.pii.KeyID() + ":%"
+		rows, err := tx.Query(ctx, `
+			SELECT id, phone_number, phone_number_enc, email, email_enc
+			FROM users
+			WHERE phone_number IS NOT NULL OR email IS NOT NULL
+				OR phone_number_enc NOT LIKE $1 OR email_enc NOT LIKE $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		`, stalePrefix, piiBackfillSize)
+		if err != nil {
+			return fmt.Errorf("failed to query pending users: %w", err)
+		}
+
+		type pending struct {
+			id           uuid.UUID
+			phone, email string
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			var phone, phoneEnc, email, emailEnc *string
+			if err := rows.Scan(&p.id, &phone, &phoneEnc, &email, &emailEnc); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan pending user: %w", err)
+			}
+			if p.phone, err = r.openPII(phone, phoneEnc, fieldUserPhone); err != nil {
+				rows.Close()
+				return err
+			}
+			if p.email, err = r.openPII(email, emailEnc, fieldUserEmail); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read pending users: %w", err)
+		}
 
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+		for _, p := range batch {
+			phone, err := r.sealPII(&p.phone, fieldUserPhone, pii.IndexPhone)
+			if err != nil {
+				return err
+			}
+			email, err := r.sealPII(&p.email, fieldUserEmail, pii.IndexEmail)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, `
+				UPDATE users
+				SET phone_number = NULL, phone_number_enc = $2, phone_number_bidx = $3,
+					email = NULL, email_enc = $4, email_bidx = $5
+				WHERE id = $1
+			`, p.id, phone.enc, phone.bidx, email.enc, email.bidx)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt user %s: %w", p.id, err)
+			}
 		}
+
+		updated = len(batch)
+		return nil
+	})
+
+	return updated, err
+}
+
+// PurgeExpiredPlaintextOTPs deletes expired OTPs written before encryption
+// was enabled, so no plaintext contacts are left behind
+func (r *UserRepository) PurgeExpiredPlaintextOTPs(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM otps
+		WHERE expires_at < NOW() AND (phone_number IS NOT NULL OR email IS NOT NULL)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge plaintext OTPs: %w", err)
 	}
-	return false
+	return result.RowsAffected(), nil
 }
 
 // CreateOTP inserts a new OTP record
 func (r *UserRepository) CreateOTP(ctx context.Context, otp *domain.OTP) error {
 	query := `
-		INSERT INTO otps (id, user_id, phone_number, email, otp_code, purpose, expires_at, is_verified, attempts, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO otps (id, user_id, phone_number, phone_number_enc, email, email_enc, contact_bidx,
+			otp_code, purpose, expires_at, is_verified, attempts, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
+	phone, err := r.sealPII(otp.PhoneNumber, fieldOTPPhone, pii.IndexPhone)
+	if err != nil {
+		return err
+	}
+	email, err := r.sealPII(otp.Email, fieldOTPEmail, pii.IndexEmail)
+	if err != nil {
+		return err
+	}
+	contactIndex := phone.bidx
+	if contactIndex == nil {
+		contactIndex = email.bidx
+	}
+
 	otp.ID = uuid.New()
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		otp.ID,
 		otp.UserID,
-		otp.PhoneNumber,
-		otp.Email,
+		phone.plain,
+		phone.enc,
+		email.plain,
+		email.enc,
+		contactIndex,
 		otp.OTPCode,
 		otp.Purpose,
 		otp.ExpiresAt,
@@ -221,7 +399,7 @@ func (r *UserRepository) CreateOTP(ctx context.Context, otp *domain.OTP) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to create OTP: %w", err)
+		return fmt.Errorf("failed to create OTP: %w", mapPgError(err))
 	}
 
 	return nil
@@ -230,9 +408,10 @@ func (r *UserRepository) CreateOTP(ctx context.Context, otp *domain.OTP) error {
 // GetValidOTP retrieves a valid (not expired, not verified) OTP
 func (r *UserRepository) GetValidOTP(ctx context.Context, contact string, purpose domain.OTPPurpose) (*domain.OTP, error) {
 	query := `
-		SELECT id, user_id, phone_number, email, otp_code, purpose, expires_at, is_verified, verified_at, attempts, created_at
+		SELECT id, user_id, phone_number, phone_number_enc, email, email_enc, otp_code, purpose,
+			expires_at, is_verified, verified_at, attempts, created_at
 		FROM otps
-		WHERE (phone_number = $1 OR email = $1)
+		WHERE (phone_number = $1 OR email = $1 OR contact_bidx = ANY($3))
 		AND purpose = $2
 		AND is_verified = FALSE
 		AND expires_at > NOW()
@@ -241,12 +420,21 @@ func (r *UserRepository) GetValidOTP(ctx context.Context, contact string, purpos
 		LIMIT 1
 	`
 
+	// The contact may be a phone number or an email, so match either index
+	var indexes []string
+	if r.pii != nil {
+		indexes = []string{r.pii.BlindIndex(contact, pii.IndexPhone), r.pii.BlindIndex(contact, pii.IndexEmail)}
+	}
+
 	otp := &domain.OTP{}
-	err := r.db.QueryRow(ctx, query, contact, purpose).Scan(
+	var phone, phoneEnc, email, emailEnc *string
+	err := r.db.QueryRow(ctx, query, contact, purpose, indexes).Scan(
 		&otp.ID,
 		&otp.UserID,
-		&otp.PhoneNumber,
-		&otp.Email,
+		&phone,
+		&phoneEnc,
+		&email,
+		&emailEnc,
 		&otp.OTPCode,
 		&otp.Purpose,
 		&otp.ExpiresAt,
@@ -263,6 +451,21 @@ func (r *UserRepository) GetValidOTP(ctx context.Context, contact string, purpos
 		return nil, fmt.Errorf("failed to get OTP: %w", err)
 	}
 
+	if phone != nil || phoneEnc != nil {
+		value, err := r.openPII(phone, phoneEnc, fieldOTPPhone)
+		if err != nil {
+			return nil, err
+		}
+		otp.PhoneNumber = &value
+	}
+	if email != nil || emailEnc != nil {
+		value, err := r.openPII(email, emailEnc, fieldOTPEmail)
+		if err != nil {
+			return nil, err
+		}
+		otp.Email = &value
+	}
+
 	return otp, nil
 }
 
@@ -292,7 +495,7 @@ func (r *UserRepository) MarkOTPVerified(ctx context.Context, otpID uuid.UUID) e
 
 	_, err := r.db.Exec(ctx, query, otpID)
 	if err != nil {
-		return fmt.Errorf("failed to mark OTP as verified: %w", err)
+		return fmt.Errorf("failed to mark OTP as verified: %w", mapPgError(err))
 	}
 
 	return nil
@@ -320,7 +523,7 @@ func (r *UserRepository) CreateSession(ctx context.Context, session *domain.Sess
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
+		return fmt.Errorf("failed to create session: %w", mapPgError(err))
 	}
 
 	return nil
@@ -373,4 +576,332 @@ func (r *UserRepository) RevokeSession(ctx context.Context, tokenID string) erro
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// ListAdminIDs returns the IDs of every active admin
+func (r *UserRepository) ListAdminIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM users WHERE is_admin AND deactivated_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query admins: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Deactivate marks a user deactivated and revokes their sessions. by is the
+// admin responsible, nil when the user deactivated their own account.
+func (r *UserRepository) Deactivate(ctx context.Context, userID uuid.UUID, by *uuid.UUID, reason string, at time.Time) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users
+			SET deactivated_at = $2, deactivated_by = $3, deactivation_reason = NULLIF($4, ''), updated_at = NOW()
+			WHERE id = $1
+		`, userID, at, by, reason)
+		if err != nil {
+			return fmt.Errorf("failed to deactivate user: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE sessions
+			SET is_revoked = TRUE, revoked_at = NOW()
+			WHERE user_id = $1 AND NOT is_revoked
+		`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+}
+
+// Reactivate clears a user's deactivation
+func (r *UserRepository) Reactivate(ctx context.Context, userID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users
+		SET deactivated_at = NULL, deactivated_by = NULL, deactivation_reason = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetLocale stores the locale the user chose; empty clears it
+func (r *UserRepository) SetLocale(ctx context.Context, userID uuid.UUID, locale string) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users SET locale = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $1
+	`, userID, locale)
+	if err != nil {
+		return fmt.Errorf("failed to set locale: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetDetectedLocale stores the locale detected from the user's app,
+// reporting whether it changed
+func (r *UserRepository) SetDetectedLocale(ctx context.Context, userID uuid.UUID, locale string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users SET detected_locale = $2
+		WHERE id = $1 AND detected_locale IS DISTINCT FROM $2
+	`, userID, locale)
+	if err != nil {
+		return false, fmt.Errorf("failed to set detected locale: %w", mapPgError(err))
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// DeleteExpiredSessions deletes up to limit sessions that expired before
+// cutoff, returning how many were removed. Revoked sessions go once their
+// expiry passes too.
+func (r *UserRepository) DeleteExpiredSessions(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM sessions
+		WHERE id IN (SELECT id FROM sessions WHERE expires_at < $1 LIMIT $2)
+	`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpiredOTPs deletes up to limit OTPs that expired before cutoff,
+// returning how many were removed
+func (r *UserRepository) DeleteExpiredOTPs(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM otps
+		WHERE id IN (SELECT id FROM otps WHERE expires_at < $1 LIMIT $2)
+	`, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired OTPs: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// GetIdentity retrieves a linked external identity by provider subject
+func (r *UserRepository) GetIdentity(ctx context.Context, provider domain.IdentityProvider, subject string) (*domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, provider_subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND provider_subject = $2
+	`
+
+	identity := &domain.UserIdentity{}
+	err := r.db.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.ProviderSubject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// CreateIdentity links an external identity to a user
+func (r *UserRepository) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, provider_subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	identity.ID = uuid.New()
+	_, err := r.db.Exec(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Provider,
+		identity.ProviderSubject,
+		identity.Email,
+		identity.CreatedAt,
+		identity.LastLoginAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// TouchIdentity records a successful login through an external identity
+func (r *UserRepository) TouchIdentity(ctx context.Context, identityID uuid.UUID) error {
+	query := `
+		UPDATE user_identities
+		SET last_login_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, identityID)
+	if err != nil {
+		return fmt.Errorf("failed to update identity login time: %w", mapPgError(err))
+	}
+
+	return nil
+}
+
+// GetTOTP retrieves a user's TOTP enrollment
+func (r *UserRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*domain.UserTOTP, error) {
+	query := `
+		SELECT user_id, secret, is_enabled, enabled_at, last_used_step, created_at, updated_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	t := &domain.UserTOTP{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.IsEnabled,
+		&t.EnabledAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+
+	return t, nil
+}
+
+// SavePendingTOTP stores a new secret awaiting confirmation.
+// Re-enrolling replaces any pending secret but never an enabled one.
+func (r *UserRepository) SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, is_enabled, last_used_step, created_at, updated_at)
+		VALUES ($1, $2, FALSE, 0, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0
+		WHERE user_totp.is_enabled = FALSE
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save TOTP secret: %w", mapPgError(err))
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDuplicateKey
+	}
+
+	return nil
+}
+
+// EnableTOTP activates a confirmed enrollment and replaces recovery codes atomically
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		query := `
+			UPDATE user_totp
+			SET is_enabled = TRUE, enabled_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND is_enabled = FALSE
+		`
+
+		result, err := tx.Exec(ctx, query, userID, step)
+		if err != nil {
+			return fmt.Errorf("failed to enable TOTP: %w", mapPgError(err))
+		}
+		if result.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	})
+}
+
+// MarkTOTPStepUsed advances the replay guard; fails if the step was already used
+func (r *UserRepository) MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to update TOTP step: %w", mapPgError(err))
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates all existing recovery codes and stores new ones
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	return execTx(ctx, r.db, func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+// replaceRecoveryCodes swaps a user's recovery codes inside a transaction
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	query := `
+		INSERT INTO totp_recovery_codes (id, user_id, code_hash, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, query, uuid.New(), userID, hash); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", mapPgError(err))
+		}
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used.
+// Returns ErrNotFound if the code does not exist or was already used.
+func (r *UserRepository) ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE totp_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
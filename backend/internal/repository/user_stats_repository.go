@@ -0,0 +1,156 @@
+// Package repository implements per-customer order stats
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// UserSegmentFilter picks customers by their order stats; zero values
+// don't filter
+type UserSegmentFilter struct {
+	MinOrders       int
+	MinSpend        int64      // Paisa
+	LastOrderBefore *time.Time // Quiet since
+	LastOrderAfter  *time.Time // Ordered since
+	Limit           int
+	Offset          int
+}
+
+// userStatsColumns is the column list matching scanUserStats
+const userStatsColumns = `user_id, order_count, total_spend, first_order_at, last_order_at`
+
+// scanUserStats scans a row selected with userStatsColumns
+func scanUserStats(row pgx.Row) (*domain.UserOrderStats, error) {
+	s := &domain.UserOrderStats{}
+	err := row.Scan(&s.UserID, &s.OrderCount, &s.TotalSpend, &s.FirstOrderAt, &s.LastOrderAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetOrderStats returns a customer's order stats. Returns ErrNotFound if
+// they have no paid orders.
+func (r *UserRepository) GetOrderStats(ctx context.Context, userID uuid.UUID) (*domain.UserOrderStats, error) {
+	stats, err := scanUserStats(r.db.QueryRow(ctx, `SELECT `+userStatsColumns+` FROM user_order_stats WHERE user_id = $1`, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get order stats: %w", err)
+	}
+	return stats, nil
+}
+
+// ListOrderStats returns the stats of the customers matching filter, most
+// recently ordered first
+func (r *UserRepository) ListOrderStats(ctx context.Context, filter UserSegmentFilter) ([]domain.UserOrderStats, error) {
+	query := `
+		SELECT ` + userStatsColumns + `
+		FROM user_order_stats
+		WHERE order_count >= $1 AND total_spend >= $2
+			AND ($3::timestamptz IS NULL OR last_order_at < $3)
+			AND ($4::timestamptz IS NULL OR last_order_at >= $4)
+		ORDER BY last_order_at DESC, user_id
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.Query(ctx, query, filter.MinOrders, filter.MinSpend, filter.LastOrderBefore, filter.LastOrderAfter, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []domain.UserOrderStats
+	for rows.Next() {
+		s, err := scanUserStats(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order stats: %w", err)
+		}
+		stats = append(stats, *s)
+	}
+
+	return stats, rows.Err()
+}
+
+// addOrderToStats counts an order toward its customer's stats, in the
+// transaction that paid or imported it. Test orders and orders not in a
+// paid status are skipped.
+func addOrderToStats(ctx context.Context, q database.Querier, orderID uuid.UUID) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO user_order_stats (user_id, order_count, total_spend, first_order_at, last_order_at, updated_at)
+		SELECT user_id, 1, total_amount, created_at, created_at, NOW()
+		FROM orders
+		WHERE id = $1 AND status::text = ANY($2) AND NOT ($3 = ANY(tags))
+		ON CONFLICT (user_id) DO UPDATE
+		SET order_count = user_order_stats.order_count + 1,
+			total_spend = user_order_stats.total_spend + EXCLUDED.total_spend,
+			first_order_at = LEAST(user_order_stats.first_order_at, EXCLUDED.first_order_at),
+			last_order_at = GREATEST(user_order_stats.last_order_at, EXCLUDED.last_order_at),
+			updated_at = NOW()
+	`, orderID, paidStatuses(), domain.OrderTagTest)
+	if err != nil {
+		return fmt.Errorf("failed to update order stats: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// removeOrderFromStats takes a paid order that was just cancelled back out
+// of its customer's stats: what it still counted toward the spend after
+// processed refunds, and its date if it was the first or last order. A
+// customer left with no orders loses their row.
+func removeOrderFromStats(ctx context.Context, q database.Querier, orderID uuid.UUID) error {
+	_, err := q.Exec(ctx, `
+		WITH removed AS (
+			UPDATE user_order_stats s
+			SET order_count = s.order_count - 1,
+				total_spend = s.total_spend - o.total_amount + COALESCE((
+					SELECT SUM(amount) FROM refunds WHERE order_id = o.id AND status = $4
+				), 0),
+				first_order_at = COALESCE((
+					SELECT MIN(created_at) FROM orders
+					WHERE user_id = o.user_id AND status::text = ANY($2) AND NOT ($3 = ANY(tags))
+				), s.first_order_at),
+				last_order_at = COALESCE((
+					SELECT MAX(created_at) FROM orders
+					WHERE user_id = o.user_id AND status::text = ANY($2) AND NOT ($3 = ANY(tags))
+				), s.last_order_at),
+				updated_at = NOW()
+			FROM orders o
+			WHERE o.id = $1 AND s.user_id = o.user_id AND NOT ($3 = ANY(o.tags))
+			RETURNING s.user_id, s.order_count
+		)
+		DELETE FROM user_order_stats
+		WHERE user_id IN (SELECT user_id FROM removed WHERE order_count <= 0)
+	`, orderID, paidStatuses(), domain.OrderTagTest, domain.RefundProcessed)
+	if err != nil {
+		return fmt.Errorf("failed to update order stats: %w", mapPgError(err))
+	}
+	return nil
+}
+
+// subtractRefundFromStats takes a processed refund off the spend of the
+// customer whose order it was, if the order still counts (cancelled
+// orders were already taken out whole)
+func subtractRefundFromStats(ctx context.Context, q database.Querier, orderID uuid.UUID, amount int64) error {
+	_, err := q.Exec(ctx, `
+		UPDATE user_order_stats s
+		SET total_spend = s.total_spend - $2, updated_at = NOW()
+		FROM orders o
+		WHERE o.id = $1 AND s.user_id = o.user_id
+			AND o.status::text = ANY($3) AND NOT ($4 = ANY(o.tags))
+	`, orderID, amount, paidStatuses(), domain.OrderTagTest)
+	if err != nil {
+		return fmt.Errorf("failed to update order stats: %w", mapPgError(err))
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package repository implements webhook log data access and replay
+// bookkeeping
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+)
+
+// WebhookLogFilter selects webhook logs; zero values don't filter
+type WebhookLogFilter struct {
+	OrderID   *uuid.UUID
+	Status    domain.WebhookLogStatus
+	EventType string
+	Since     *time.Time // Received at or after
+	Until     *time.Time // Received before
+	Limit     int
+	Offset    int
+}
+
+// WebhookLogRepository handles the webhook audit trail
+type WebhookLogRepository struct {
+	db *database.Pool
+}
+
+// NewWebhookLogRepository creates a new webhook log repository
+func NewWebhookLogRepository(db *database.Pool) *WebhookLogRepository {
+	return &WebhookLogRepository{db: db}
+}
+
+// webhookLogColumns is the column list matching scanWebhookLog
+const webhookLogColumns = `id, source, event_type, payload, signature_valid, processed, processing_error,
+	order_id, attempts, next_attempt_at, failed_at, created_at, updated_at`
+
+// scanWebhookLog scans a row selected with webhookLogColumns
+func scanWebhookLog(row pgx.Row) (*domain.WebhookLog, error) {
+	l := &domain.WebhookLog{}
+	err := row.Scan(
+		&l.ID,
+		&l.Source,
+		&l.EventType,
+		&l.Payload,
+		&l.SignatureValid,
+		&l.Processed,
+		&l.ProcessingError,
+		&l.OrderID,
+		&l.Attempts,
+		&l.NextAttemptAt,
+		&l.FailedAt,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// scanWebhookLogs collects rows selected with webhookLogColumns
+func scanWebhookLogs(rows pgx.Rows) ([]domain.WebhookLog, error) {
+	defer rows.Close()
+
+	logs := []domain.WebhookLog{}
+	for rows.Next() {
+		l, err := scanWebhookLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook log: %w", err)
+		}
+		logs = append(logs, *l)
+	}
+
+	return logs, rows.Err()
+}
+
+// Create stores a webhook attempt for the audit trail. It counts as
+// processed when there is no processing error.
+func (r *WebhookLogRepository) Create(ctx context.Context, source, eventType string, payload []byte, signatureValid bool, orderID *uuid.UUID, processingError string) (uuid.UUID, error) {
+	query := `
+		INSERT INTO webhook_logs (id, source, event_type, payload, signature_valid, processed, processing_error, order_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	processed := processingError == ""
+	id := uuid.New()
+
+	_, err := r.db.Exec(ctx, query,
+		id,
+		source,
+		eventType,
+		payload,
+		signatureValid,
+		processed,
+		processingError,
+		orderID,
+		time.Now(),
+	)
+
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to log webhook: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetByID retrieves a webhook log
+func (r *WebhookLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookLog, error) {
+	l, err := scanWebhookLog(r.db.QueryRow(ctx, `SELECT `+webhookLogColumns+` FROM webhook_logs WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook log: %w", err)
+	}
+	return l, nil
+}
+
+// List returns the webhook logs matching f, newest first
+func (r *WebhookLogRepository) List(ctx context.Context, f WebhookLogFilter) ([]domain.WebhookLog, error) {
+	query := `
+		SELECT ` + webhookLogColumns + `
+		FROM webhook_logs
+		WHERE ($1::uuid IS NULL OR order_id = $1)
+		  AND ($2::text = '' OR event_type = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR created_at < $4)
+		  AND CASE $5::text
+			WHEN 'processed' THEN processed
+			WHEN 'unprocessed' THEN NOT processed
+			WHEN 'retrying' THEN next_attempt_at IS NOT NULL
+			WHEN 'failed' THEN failed_at IS NOT NULL
+			ELSE TRUE
+		  END
+		ORDER BY created_at DESC, id
+		LIMIT $6 OFFSET $7
+	`
+
+	rows, err := r.db.Query(ctx, query, f.OrderID, f.EventType, f.Since, f.Until, string(f.Status), f.Limit, f.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook logs: %w", err)
+	}
+	return scanWebhookLogs(rows)
+}
+
+// ListByOrder returns every webhook logged against an order, oldest first
+func (r *WebhookLogRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]domain.WebhookLog, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookLogColumns+`
+		FROM webhook_logs
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order webhook logs: %w", err)
+	}
+	return scanWebhookLogs(rows)
+}
+
+// ListUnprocessed returns up to limit webhooks that weren't processed and
+// aren't waiting for a replay, oldest first: the ones someone has to look at
+func (r *WebhookLogRepository) ListUnprocessed(ctx context.Context, limit int) ([]domain.WebhookLog, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookLogColumns+`
+		FROM webhook_logs
+		WHERE NOT processed AND next_attempt_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unprocessed webhook logs: %w", err)
+	}
+	return scanWebhookLogs(rows)
+}
+
+// ClaimDue returns up to limit webhooks due for a replay and counts the
+// attempt. Each is pushed lease into the future so another instance
+// doesn't replay it meanwhile; recording the outcome clears it.
+func (r *WebhookLogRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookLog, error) {
+	query := `
+		UPDATE webhook_logs
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM webhook_logs
+			WHERE next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookLogColumns
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhooks: %w", err)
+	}
+	return scanWebhookLogs(rows)
+}
+
+// Claim counts a manual replay of a webhook and leases it like ClaimDue,
+// whether or not a replay was scheduled
+func (r *WebhookLogRepository) Claim(ctx context.Context, id uuid.UUID, lease time.Duration) (*domain.WebhookLog, error) {
+	query := `
+		UPDATE webhook_logs
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + webhookLogColumns
+
+	l, err := scanWebhookLog(r.db.QueryRow(ctx, query, id, lease.Milliseconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to claim webhook: %w", err)
+	}
+	return l, nil
+}
+
+// RecordReplay stores the outcome of replaying a webhook on its original
+// log row. The order is kept if the replay couldn't find one; a webhook
+// that was given up on is revived if the replay succeeded.
+func (r *WebhookLogRepository) RecordReplay(ctx context.Context, id uuid.UUID, orderID *uuid.UUID, processingError string) error {
+	query := `
+		UPDATE webhook_logs
+		SET processed = ($3 = ''), processing_error = NULLIF($3, ''), order_id = COALESCE($2, order_id),
+			next_attempt_at = NULL, failed_at = CASE WHEN $3 = '' THEN NULL ELSE failed_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, id, orderID, processingError)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook replay: %w", mapPgError(err))
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ScheduleRetry schedules a failed webhook's next replay
+func (r *WebhookLogRepository) ScheduleRetry(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE webhook_logs SET next_attempt_at = $2, updated_at = NOW() WHERE id = $1`, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook retry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed gives up on a webhook whose replays ran out
+func (r *WebhookLogRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE webhook_logs SET next_attempt_at = NULL, failed_at = NOW(), updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package usecase implements customers' saved delivery addresses
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+)
+
+// Address errors
+var (
+	ErrAddressLabel     = errors.New("label must be 1-30 characters")
+	ErrAddressLine      = errors.New("line1 is required and address lines are at most 200 characters")
+	ErrAddressDetail    = errors.New("landmark, city and instructions are too long")
+	ErrAddressPostal    = errors.New("postal_code must be at most 10 characters")
+	ErrAddressLocation  = errors.New("address needs valid latitude and longitude")
+	ErrAddressNotFound  = errors.New("saved address not found")
+	ErrAddressLimitFull = errors.New("too many saved addresses; delete one first")
+)
+
+// maxSavedAddresses caps a customer's address book
+const maxSavedAddresses = 20
+
+// AddressUsecase manages customers' saved delivery addresses
+type AddressUsecase struct {
+	repo *repository.AddressRepository
+	log  *logger.Logger
+}
+
+// NewAddressUsecase creates a new address usecase
+func NewAddressUsecase(repo *repository.AddressRepository, log *logger.Logger) *AddressUsecase {
+	return &AddressUsecase{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// List returns a user's saved addresses, the default first
+func (u *AddressUsecase) List(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	return u.repo.ListByUser(ctx, userID)
+}
+
+// Get returns one of a user's saved addresses, or ErrAddressNotFound
+func (u *AddressUsecase) Get(ctx context.Context, userID, id uuid.UUID) (*domain.Address, error) {
+	address, err := u.repo.GetByID(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAddressNotFound
+	}
+	return address, err
+}
+
+// Create saves a new address for a user. Their first address becomes the
+// default.
+func (u *AddressUsecase) Create(ctx context.Context, userID uuid.UUID, address *domain.Address) (*domain.Address, error) {
+	if err := normalizeAddress(address); err != nil {
+		return nil, err
+	}
+	address.UserID = userID
+
+	if err := u.repo.Create(ctx, address, maxSavedAddresses); err != nil {
+		if errors.Is(err, repository.ErrAddressLimit) {
+			return nil, ErrAddressLimitFull
+		}
+		return nil, err
+	}
+
+	u.log.Info("Address saved", "user_id", userID.String(), "address_id", address.ID.String())
+	return address, nil
+}
+
+// Update replaces the details of one of a user's addresses. Orders already
+// placed keep the address as it was.
+func (u *AddressUsecase) Update(ctx context.Context, userID, id uuid.UUID, address *domain.Address) (*domain.Address, error) {
+	if err := normalizeAddress(address); err != nil {
+		return nil, err
+	}
+	address.ID = id
+	address.UserID = userID
+
+	if err := u.repo.Update(ctx, address); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	return address, nil
+}
+
+// SetDefault makes one of a user's addresses their default
+func (u *AddressUsecase) SetDefault(ctx context.Context, userID, id uuid.UUID) error {
+	err := u.repo.SetDefault(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAddressNotFound
+	}
+	return err
+}
+
+// Delete removes one of a user's addresses
+func (u *AddressUsecase) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := u.repo.Delete(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAddressNotFound
+	}
+	return err
+}
+
+// normalizeAddress trims an address's fields and checks them
+func normalizeAddress(a *domain.Address) error {
+	a.Label = strings.TrimSpace(a.Label)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.Landmark = strings.TrimSpace(a.Landmark)
+	a.City = strings.TrimSpace(a.City)
+	a.PostalCode = strings.ReplaceAll(strings.TrimSpace(a.PostalCode), " ", "")
+	a.Instructions = strings.TrimSpace(a.Instructions)
+
+	switch {
+	case a.Label == "" || len(a.Label) > 30:
+		return ErrAddressLabel
+	case a.Line1 == "" || len(a.Line1) > 200 || len(a.Line2) > 200:
+		return ErrAddressLine
+	case len(a.Landmark) > 100 || len(a.City) > 100 || len(a.Instructions) > 200:
+		return ErrAddressDetail
+	case len(a.PostalCode) > 10:
+		return ErrAddressPostal
+	case !(geo.Point{Latitude: a.Latitude, Longitude: a.Longitude}).Valid():
+		return ErrAddressLocation
+	}
+	return nil
+}
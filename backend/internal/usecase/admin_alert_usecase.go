@@ -0,0 +1,360 @@
+// Package usecase implements admin alerts with hourly and daily digests
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/slack"
+)
+
+// Alert kinds
+const (
+	AlertSurveyLowScore       = "survey.low_score"
+	AlertPaymentGatewayFailed = "payment.gateway_failing"
+	AlertPaymentMismatch      = "payment.amount_mismatch"
+	AlertRefundFailed         = "payment.refund_failed"
+	AlertWebhookFailed        = "payment.webhook_failed"
+	AlertOrderEventsStalled   = "order_events.stalled"
+	AlertOrderIssueEscalated  = "order.issue_escalated"
+	AlertStockLow             = "inventory.low_stock"
+	AlertStockOut             = "inventory.sold_out"
+)
+
+// DigestFrequency is how often non-critical alerts of a kind are sent
+type DigestFrequency string
+
+// Digest frequencies
+const (
+	DigestImmediate DigestFrequency = "immediate"
+	DigestHourly    DigestFrequency = "hourly"
+	DigestDaily     DigestFrequency = "daily"
+)
+
+// criticalAlertDedupe stops one incident from paging admins on every
+// occurrence; a critical alert of a kind is sent at most this often
+const criticalAlertDedupe = 15 * time.Minute
+
+// digestPreviewLines is how many alerts a digest lists before summarising
+const digestPreviewLines = 5
+
+// adminAlertsSent counts alerts delivered to admins, by kind and mode
+var adminAlertsSent = metrics.NewCounterVec(
+	"admin_alerts_sent_total",
+	"Alerts sent to admins, individually or in a digest",
+	"kind", "mode",
+)
+
+// AdminAlert is an event admins should hear about. Critical alerts are sent
+// straight away; others follow their kind's digest frequency.
+type AdminAlert struct {
+	Kind     string            `json:"kind"`
+	Critical bool              `json:"critical"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+	At       time.Time         `json:"at"`
+}
+
+// AdminAlertConfig sets digest frequencies per alert kind. Kinds without
+// a rule use DefaultFrequency; daily digests go out at DailyHour in
+// Location.
+type AdminAlertConfig struct {
+	Frequencies      map[string]DigestFrequency
+	DefaultFrequency DigestFrequency
+	DailyHour        int
+	Location         *time.Location
+}
+
+// ParseDigestRules parses rules like "survey.low_score=hourly,payment.refund=daily"
+func ParseDigestRules(spec string) (map[string]DigestFrequency, error) {
+	rules := map[string]DigestFrequency{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid digest rule %q: expected kind=frequency", part)
+		}
+		freq := DigestFrequency(strings.ToLower(strings.TrimSpace(value)))
+		switch freq {
+		case DigestImmediate, DigestHourly, DigestDaily:
+		default:
+			return nil, fmt.Errorf("invalid digest rule %q: frequency must be immediate, hourly or daily", part)
+		}
+		rules[strings.TrimSpace(kind)] = freq
+	}
+	return rules, nil
+}
+
+// DailyReport is a section of the daily digest, e.g. the stock summary.
+// It returns the lines to add, none to leave the section out.
+type DailyReport func(ctx context.Context) ([]string, error)
+
+// AdminAlertUsecase sends alerts to every active admin by push, and to a
+// Slack channel if one is set, batching non-critical ones into digests
+// held in Redis
+type AdminAlertUsecase struct {
+	userRepo    *repository.UserRepository
+	push        push.Sender
+	slack       *slack.Webhook
+	redisClient *redis.Client
+	cfg         AdminAlertConfig
+	reports     []namedReport
+	log         *logger.Logger
+}
+
+// namedReport is a daily digest section with the name it is logged under
+type namedReport struct {
+	name   string
+	report DailyReport
+}
+
+// NewAdminAlertUsecase creates a new admin alert usecase. Without Redis
+// every alert is sent immediately.
+func NewAdminAlertUsecase(userRepo *repository.UserRepository, pushSender push.Sender, redisClient *redis.Client, cfg AdminAlertConfig, log *logger.Logger) *AdminAlertUsecase {
+	if cfg.DefaultFrequency == "" {
+		cfg.DefaultFrequency = DigestHourly
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	return &AdminAlertUsecase{
+		userRepo:    userRepo,
+		push:        pushSender,
+		redisClient: redisClient,
+		cfg:         cfg,
+		log:         log,
+	}
+}
+
+// SetSlack also posts alerts and digests to a Slack channel
+func (u *AdminAlertUsecase) SetSlack(webhook *slack.Webhook) {
+	u.slack = webhook
+}
+
+// AddDailyReport adds a section to the daily digest, which is then sent
+// even on days without alerts. Sections appear in the order added.
+func (u *AdminAlertUsecase) AddDailyReport(name string, report DailyReport) {
+	u.reports = append(u.reports, namedReport{name: name, report: report})
+}
+
+// Raise sends or queues an alert. Failures are logged, never returned, so
+// alerting can't break the operation that raised it. Safe on a nil receiver.
+func (u *AdminAlertUsecase) Raise(ctx context.Context, alert AdminAlert) {
+	if u == nil {
+		return
+	}
+	if alert.At.IsZero() {
+		alert.At = time.Now()
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	freq := u.frequency(alert.Kind)
+	if alert.Critical || freq == DigestImmediate || u.redisClient == nil {
+		if alert.Critical && u.redisClient != nil {
+			first, err := u.redisClient.SetNXWithTTL(ctx, redis.AdminAlertDedupePrefix+alert.Kind, alert.At.Unix(), criticalAlertDedupe)
+			if err != nil {
+				u.log.Warn("Failed to check alert dedupe", "kind", alert.Kind, "error", err)
+			} else if !first {
+				return
+			}
+		}
+		mode := "immediate"
+		if alert.Critical {
+			mode = "critical"
+		}
+		u.send(ctx, push.Message{Title: alert.Title, Body: alert.Body, Data: alert.Data}, alert.Kind, mode, 1)
+		return
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		u.log.Error("Failed to encode alert", "kind", alert.Kind, "error", err)
+		return
+	}
+	if err := u.redisClient.RPush(ctx, redis.AdminAlertDigestPrefix+string(freq), data).Err(); err != nil {
+		// Better a lone alert than a lost one
+		u.log.Warn("Failed to queue alert for digest, sending now", "kind", alert.Kind, "error", err)
+		u.send(ctx, push.Message{Title: alert.Title, Body: alert.Body, Data: alert.Data}, alert.Kind, "immediate", 1)
+	}
+}
+
+// RunDigests sends due digests every interval until ctx is cancelled
+func (u *AdminAlertUsecase) RunDigests(ctx context.Context, interval time.Duration) {
+	if u.redisClient == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now().In(u.cfg.Location)
+			u.flushIfDue(ctx, DigestHourly, now.Format("2006010215"))
+			if now.Hour() >= u.cfg.DailyHour {
+				u.flushIfDue(ctx, DigestDaily, now.Format("20060102"))
+			}
+		}
+	}
+}
+
+// flushIfDue sends the queued digest once per period. The period marker
+// makes one instance send it; alerts queued while nobody flushed go out in
+// the next period's digest.
+func (u *AdminAlertUsecase) flushIfDue(ctx context.Context, freq DigestFrequency, period string) {
+	first, err := u.redisClient.SetNXWithTTL(ctx, redis.AdminAlertDigestSentPrefix+string(freq)+":"+period, 1, 48*time.Hour)
+	if err != nil {
+		u.log.Warn("Failed to mark digest period", "frequency", freq, "error", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	alerts, err := u.drain(ctx, freq)
+	if err != nil {
+		u.log.Error("Failed to read alert digest", "frequency", freq, "error", err)
+		return
+	}
+	var report []string
+	if freq == DigestDaily {
+		report = u.dailyReport(ctx)
+	}
+	if len(alerts) == 0 && len(report) == 0 {
+		return
+	}
+
+	msg := digestMessage(freq, alerts)
+	if len(report) > 0 {
+		if len(alerts) == 0 {
+			msg = push.Message{
+				Title: "Daily report",
+				Data:  map[string]string{"type": "admin_daily_report"},
+			}
+		} else {
+			msg.Body += "\n\n"
+		}
+		msg.Body += strings.Join(report, "\n")
+	}
+	u.send(ctx, msg, "digest", string(freq), len(alerts))
+}
+
+// dailyReport runs the daily digest sections; a failing one is logged and
+// left out
+func (u *AdminAlertUsecase) dailyReport(ctx context.Context) []string {
+	var lines []string
+	for _, r := range u.reports {
+		section, err := r.report(ctx)
+		if err != nil {
+			u.log.Error("Failed to build daily report section", "section", r.name, "error", err)
+			continue
+		}
+		lines = append(lines, section...)
+	}
+	return lines
+}
+
+// drain takes every queued alert of a frequency in one transaction, so
+// alerts raised meanwhile land in the next digest
+func (u *AdminAlertUsecase) drain(ctx context.Context, freq DigestFrequency) ([]AdminAlert, error) {
+	key := redis.AdminAlertDigestPrefix + string(freq)
+	pipe := u.redisClient.TxPipeline()
+	items := pipe.LRange(ctx, key, 0, -1)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]AdminAlert, 0, len(items.Val()))
+	for _, raw := range items.Val() {
+		var alert AdminAlert
+		if err := json.Unmarshal([]byte(raw), &alert); err != nil {
+			u.log.Warn("Dropping unreadable queued alert", "error", err)
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// send pushes msg to every active admin and posts it to Slack. count is
+// how many alerts it carries, for the metric.
+func (u *AdminAlertUsecase) send(ctx context.Context, msg push.Message, kind, mode string, count int) {
+	if u.slack != nil {
+		if err := u.slack.Post(ctx, "*"+msg.Title+"*\n"+msg.Body); err != nil {
+			u.log.Warn("Failed to post admin alert to Slack", "kind", kind, "error", err)
+		}
+	}
+
+	admins, err := u.userRepo.ListAdminIDs(ctx)
+	if err != nil {
+		u.log.Error("Failed to list admins for alert", "kind", kind, "error", err)
+		return
+	}
+
+	for _, adminID := range admins {
+		if err := u.push.Send(ctx, adminID, msg); err != nil {
+			u.log.Warn("Failed to send admin alert", "kind", kind, "admin_id", adminID.String(), "error", err)
+		}
+	}
+	adminAlertsSent.Add(float64(count), kind, mode)
+}
+
+// frequency returns the digest frequency for an alert kind
+func (u *AdminAlertUsecase) frequency(kind string) DigestFrequency {
+	if freq, ok := u.cfg.Frequencies[kind]; ok {
+		return freq
+	}
+	return u.cfg.DefaultFrequency
+}
+
+// digestMessage summarises queued alerts: counts per kind, then the
+// oldest few titles
+func digestMessage(freq DigestFrequency, alerts []AdminAlert) push.Message {
+	counts := make(map[string]int)
+	for _, a := range alerts {
+		counts[a.Kind]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s: %d", kind, counts[kind]))
+	}
+
+	var body strings.Builder
+	body.WriteString(strings.Join(parts, ", "))
+	for i, a := range alerts {
+		if i == digestPreviewLines {
+			fmt.Fprintf(&body, "\n…and %d more", len(alerts)-i)
+			break
+		}
+		body.WriteString("\n- " + a.Title)
+	}
+
+	return push.Message{
+		Title: fmt.Sprintf("%s alert digest (%d)", strings.ToUpper(string(freq[:1]))+string(freq[1:]), len(alerts)),
+		Body:  body.String(),
+		Data:  map[string]string{"type": "admin_alert_digest", "frequency": string(freq)},
+	}
+}
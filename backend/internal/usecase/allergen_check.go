@@ -0,0 +1,46 @@
+// Package usecase implements allergen acknowledgment at checkout
+package usecase
+
+import (
+	"fmt"
+
+	"fooddelivery/internal/domain"
+)
+
+// AllergenAckRequiredError is returned when the cart has severe allergens
+// the customer hasn't acknowledged. Resubmitting with
+// acknowledged_allergens listing every allergen in Warnings places the
+// order.
+type AllergenAckRequiredError struct {
+	Warnings []domain.AllergenWarning
+}
+
+func (e *AllergenAckRequiredError) Error() string {
+	return fmt.Sprintf("%d severe allergens need acknowledgment", len(e.Warnings))
+}
+
+// acknowledgeAllergens checks every severe allergen in the cart's warnings
+// was acknowledged, whether contained or a cross-contamination risk, and
+// returns them to record on the order
+func acknowledgeAllergens(warnings []domain.AllergenWarning, acknowledged []domain.Allergen) ([]domain.Allergen, error) {
+	acked := make(map[domain.Allergen]bool, len(acknowledged))
+	for _, a := range acknowledged {
+		acked[a] = true
+	}
+
+	var severe []domain.Allergen
+	var missing []domain.AllergenWarning
+	for _, w := range warnings {
+		if !w.Severe {
+			continue
+		}
+		severe = append(severe, w.Allergen)
+		if !acked[w.Allergen] {
+			missing = append(missing, w)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &AllergenAckRequiredError{Warnings: missing}
+	}
+	return severe, nil
+}
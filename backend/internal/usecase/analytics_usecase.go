@@ -0,0 +1,341 @@
+// Package usecase implements time-series order metrics for the ops dashboard
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// MaxTimeSeriesHours bounds the dashboard window
+const MaxTimeSeriesHours = 7 * 24
+
+// ErrInvalidWindow is returned for a window outside 1..MaxTimeSeriesHours
+var ErrInvalidWindow = errors.New("hours must be between 1 and 168")
+
+// TimeSeriesPoint is one bucket: T is the bucket start
+type TimeSeriesPoint struct {
+	T time.Time `json:"t"`
+	V float64   `json:"v"`
+}
+
+// TimeSeries is a chart-ready series with contiguous, zero-filled buckets
+type TimeSeries struct {
+	Name          string            `json:"name"`
+	Unit          string            `json:"unit"`
+	BucketSeconds int               `json:"bucket_seconds"`
+	Points        []TimeSeriesPoint `json:"points"`
+}
+
+// OrderTimeSeries is the ops dashboard payload
+type OrderTimeSeries struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Series      []TimeSeries `json:"series"`
+}
+
+// AnalyticsUsecase builds dashboard metrics from Postgres, cached in Redis
+type AnalyticsUsecase struct {
+	repo        *repository.AnalyticsRepository
+	menuRepo    *repository.MenuRepository
+	redisClient *redis.Client
+	location    *time.Location
+	log         *logger.Logger
+}
+
+// NewAnalyticsUsecase creates a new analytics usecase
+func NewAnalyticsUsecase(repo *repository.AnalyticsRepository, menuRepo *repository.MenuRepository, redisClient *redis.Client, log *logger.Logger) *AnalyticsUsecase {
+	return &AnalyticsUsecase{
+		repo:        repo,
+		menuRepo:    menuRepo,
+		redisClient: redisClient,
+		location:    time.UTC,
+		log:         log,
+	}
+}
+
+// SetLocation sets the business time zone that hours and days are counted
+// in. Defaults to UTC.
+func (u *AnalyticsUsecase) SetLocation(loc *time.Location) {
+	u.location = loc
+}
+
+// GetOrderTimeSeries returns orders per 15 minutes, paid revenue per hour
+// and payment failure rate per hour for the last hours hours. The window
+// ends at the current (partial) bucket so the latest point keeps moving.
+func (u *AnalyticsUsecase) GetOrderTimeSeries(ctx context.Context, hours int) (*OrderTimeSeries, error) {
+	if hours < 1 || hours > MaxTimeSeriesHours {
+		return nil, ErrInvalidWindow
+	}
+
+	cacheKey := fmt.Sprintf("%s%d", redis.OrderTimeSeriesPrefix, hours)
+	if u.redisClient != nil {
+		var cached OrderTimeSeries
+		found, err := u.redisClient.GetJSON(ctx, cacheKey, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read time-series cache", "error", err)
+		} else if found {
+			return &cached, nil
+		}
+	}
+
+	now := time.Now().In(u.location)
+	to := truncateLocal(now, time.Hour).Add(time.Hour)
+	from := to.Add(-time.Duration(hours) * time.Hour)
+
+	quarter, err := u.repo.GetOrderBuckets(ctx, from, to, 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	hourly, err := u.repo.GetOrderBuckets(ctx, from, to, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrderTimeSeries{
+		From:        from,
+		To:          to,
+		GeneratedAt: now,
+		Series: []TimeSeries{
+			fillSeries("orders", "count", from, to, 15*time.Minute, quarter, func(b repository.OrderBucket) float64 {
+				return float64(b.Orders)
+			}),
+			fillSeries("revenue", "paisa", from, to, time.Hour, hourly, func(b repository.OrderBucket) float64 {
+				return float64(b.Revenue)
+			}),
+			fillSeries("payment_failure_rate", "percent", from, to, time.Hour, hourly, func(b repository.OrderBucket) float64 {
+				if b.Orders == 0 {
+					return 0
+				}
+				return math.Round(float64(b.Failed)/float64(b.Orders)*1000) / 10
+			}),
+		},
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, cacheKey, result, redis.OrderTimeSeriesTTL); err != nil {
+			u.log.Warn("Failed to cache time series", "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// truncateLocal rounds t down to a multiple of d counted from local
+// midnight, so hours line up with the wall clock in half-hour offset zones
+func truncateLocal(t time.Time, d time.Duration) time.Time {
+	midnight := startOfDay(t)
+	return midnight.Add(t.Sub(midnight).Truncate(d))
+}
+
+// fillSeries lays buckets onto a contiguous grid so charts get a point for
+// every interval, including the empty ones
+func fillSeries(name, unit string, from, to time.Time, step time.Duration, buckets []repository.OrderBucket, value func(repository.OrderBucket) float64) TimeSeries {
+	byStart := make(map[int64]repository.OrderBucket, len(buckets))
+	for _, b := range buckets {
+		byStart[b.Start.Unix()] = b
+	}
+
+	series := TimeSeries{
+		Name:          name,
+		Unit:          unit,
+		BucketSeconds: int(step.Seconds()),
+		Points:        make([]TimeSeriesPoint, 0, int(to.Sub(from)/step)),
+	}
+	for t := from; t.Before(to); t = t.Add(step) {
+		series.Points = append(series.Points, TimeSeriesPoint{T: t, V: value(byStart[t.Unix()])})
+	}
+
+	return series
+}
+
+// MaxItemReportDays bounds the item price report window
+const MaxItemReportDays = 92
+
+// ErrInvalidReportDays is returned for a window outside 1..MaxItemReportDays
+var ErrInvalidReportDays = errors.New("days must be between 1 and 92")
+
+// ItemPriceReport shows how a menu item's price changes played out in sales.
+// BaselinePrice is the list price at the start of the window; the
+// price_change_impact series is revenue minus what the same units would have
+// earned at the baseline, i.e. the margin gained or given up by repricing.
+type ItemPriceReport struct {
+	MenuItemID    uuid.UUID                `json:"menu_item_id"`
+	Name          string                   `json:"name"`
+	From          time.Time                `json:"from"`
+	To            time.Time                `json:"to"`
+	BaselinePrice int64                    `json:"baseline_price"`
+	Changes       []domain.MenuPriceChange `json:"changes"`
+	Series        []TimeSeries             `json:"series"`
+}
+
+// GetItemPriceReport returns daily list price, units sold, revenue and price
+// change impact for a menu item over the last days days (business-local
+// days, the current one included)
+func (u *AnalyticsUsecase) GetItemPriceReport(ctx context.Context, itemID uuid.UUID, days int) (*ItemPriceReport, error) {
+	if days < 1 || days > MaxItemReportDays {
+		return nil, ErrInvalidReportDays
+	}
+
+	item, err := u.menuRepo.GetByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := u.menuRepo.GetPriceHistory(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	const day = 24 * time.Hour
+	to := startOfDay(time.Now().In(u.location)).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	sales, err := u.repo.GetItemDailySales(ctx, itemID, from, to, u.location)
+	if err != nil {
+		return nil, err
+	}
+	byStart := make(map[int64]repository.ItemSalesBucket, len(sales))
+	for _, b := range sales {
+		byStart[b.Start.Unix()] = b
+	}
+
+	baseline := listPriceAt(history, from)
+	if baseline == 0 && len(history) > 0 {
+		// Created during the window: compare against the launch price
+		baseline = history[0].NewPrice
+	}
+
+	report := &ItemPriceReport{
+		MenuItemID:    item.ID,
+		Name:          item.Name,
+		From:          from,
+		To:            to,
+		BaselinePrice: baseline,
+		Changes:       []domain.MenuPriceChange{},
+	}
+	for _, c := range history {
+		if !c.ChangedAt.Before(from) && c.ChangedAt.Before(to) {
+			report.Changes = append(report.Changes, c)
+		}
+	}
+
+	newSeries := func(name, unit string) TimeSeries {
+		return TimeSeries{Name: name, Unit: unit, BucketSeconds: int(day.Seconds()), Points: make([]TimeSeriesPoint, 0, days)}
+	}
+	price := newSeries("list_price", "paisa")
+	units := newSeries("units", "count")
+	revenue := newSeries("revenue", "paisa")
+	impact := newSeries("price_change_impact", "paisa")
+
+	for t := from; t.Before(to); t = t.AddDate(0, 0, 1) {
+		b := byStart[t.Unix()]
+		// The price the day closed at, so a mid-day change shows that day
+		price.Points = append(price.Points, TimeSeriesPoint{T: t, V: float64(listPriceAt(history, t.AddDate(0, 0, 1)))})
+		units.Points = append(units.Points, TimeSeriesPoint{T: t, V: float64(b.Units)})
+		revenue.Points = append(revenue.Points, TimeSeriesPoint{T: t, V: float64(b.Revenue)})
+		impact.Points = append(impact.Points, TimeSeriesPoint{T: t, V: float64(b.Revenue - int64(b.Units)*baseline)})
+	}
+	report.Series = []TimeSeries{price, units, revenue, impact}
+
+	return report, nil
+}
+
+// listPriceAt returns the price in effect just before t, or 0 if the item
+// did not exist yet. history must be oldest first.
+func listPriceAt(history []domain.MenuPriceChange, t time.Time) int64 {
+	var price int64
+	for _, c := range history {
+		if !c.ChangedAt.Before(t) {
+			break
+		}
+		price = c.NewPrice
+	}
+	return price
+}
+
+// ErrInvalidReportMonth is returned for a month that isn't YYYY-MM or
+// hasn't started yet
+var ErrInvalidReportMonth = errors.New("month must be a YYYY-MM that has started")
+
+// SustainabilityReport sums up a month of packing choices on paid orders
+// for marketing. Shares are fractions of Orders, 0 when there were none.
+type SustainabilityReport struct {
+	Month             string                   `json:"month"` // YYYY-MM, business time zone
+	From              time.Time                `json:"from"`
+	To                time.Time                `json:"to"`
+	Orders            int                      `json:"orders"`
+	NoCutleryOrders   int                      `json:"no_cutlery_orders"`
+	NoCutleryShare    float64                  `json:"no_cutlery_share"`
+	Packaging         map[domain.Packaging]int `json:"packaging"`
+	SustainableOrders int                      `json:"sustainable_orders"` // Skipped cutlery or chose non-standard packaging
+	SustainableShare  float64                  `json:"sustainable_share"`
+}
+
+// GetSustainabilityReport reports cutlery and packaging choices for a
+// calendar month in the business time zone; an empty month means the
+// current one, which is reported up to now
+func (u *AnalyticsUsecase) GetSustainabilityReport(ctx context.Context, month string) (*SustainabilityReport, error) {
+	now := time.Now().In(u.location)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, u.location)
+	if month != "" {
+		var err error
+		from, err = time.ParseInLocation("2006-01", month, u.location)
+		if err != nil || from.After(now) {
+			return nil, ErrInvalidReportMonth
+		}
+	}
+	to := from.AddDate(0, 1, 0)
+
+	counts, err := u.repo.GetPackagingCounts(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SustainabilityReport{
+		Month: from.Format("2006-01"),
+		From:  from,
+		To:    to,
+		Packaging: map[domain.Packaging]int{
+			domain.PackagingStandard:    0,
+			domain.PackagingMinimal:     0,
+			domain.PackagingPlasticFree: 0,
+		},
+	}
+	for _, c := range counts {
+		report.Orders += c.Orders
+		report.NoCutleryOrders += c.NoCutlery
+		report.Packaging[c.Packaging] += c.Orders
+		if c.Packaging == domain.PackagingStandard {
+			report.SustainableOrders += c.NoCutlery
+		} else {
+			report.SustainableOrders += c.Orders
+		}
+	}
+	if report.Orders > 0 {
+		report.NoCutleryShare = roundShare(report.NoCutleryOrders, report.Orders)
+		report.SustainableShare = roundShare(report.SustainableOrders, report.Orders)
+	}
+
+	return report, nil
+}
+
+// roundShare returns n/total to four decimal places
+func roundShare(n, total int) float64 {
+	return math.Round(float64(n)/float64(total)*10000) / 10000
+}
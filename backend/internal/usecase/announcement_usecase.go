@@ -0,0 +1,308 @@
+// Package usecase implements in-app announcement banners
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// Announcement errors
+var (
+	ErrAnnouncementNotFound    = errors.New("announcement not found")
+	ErrInvalidAnnouncement     = errors.New("title is required and must be at most 100 characters, body at most 500")
+	ErrInvalidAnnouncementURL  = errors.New("image_url must be an http(s) URL and deep_link a URL, each at most 500 characters")
+	ErrInvalidAnnouncementTime = errors.New("ends_at must be after starts_at")
+	ErrInvalidAudience         = errors.New("audience must be ALL, GUESTS, NEW_CUSTOMERS, RETURNING_CUSTOMERS or LAPSED_CUSTOMERS")
+	ErrTooManyAnnouncementIDs  = errors.New("too many announcement IDs in one report")
+)
+
+// Announcement limits
+const (
+	maxAnnouncementTitle  = 100
+	maxAnnouncementBody   = 500
+	maxAnnouncementURL    = 500
+	maxAnnouncementEvents = 50 // IDs of each kind per tracking report
+)
+
+// AnnouncementInput is what an admin sets on a banner. A zero StartsAt
+// means now; a nil EndsAt leaves it up until it's paused or deleted.
+type AnnouncementInput struct {
+	Title    string                      `json:"title"`
+	Body     string                      `json:"body"`
+	ImageURL string                      `json:"image_url"`
+	DeepLink string                      `json:"deep_link"`
+	Audience domain.AnnouncementAudience `json:"audience"`
+	Priority int                         `json:"priority"`
+	StartsAt time.Time                   `json:"starts_at"`
+	EndsAt   *time.Time                  `json:"ends_at"`
+	IsActive *bool                       `json:"is_active"` // Defaults to true
+}
+
+// AnnouncementUsecase manages in-app banners. Banners that are up or still
+// to come are cached in Redis, so the app's fetch doesn't hit the database;
+// the window and audience are applied per request.
+type AnnouncementUsecase struct {
+	repo        *repository.AnnouncementRepository
+	users       *UserUsecase
+	audit       *AuditUsecase
+	redisClient *redis.Client
+	location    *time.Location
+	log         *logger.Logger
+}
+
+// NewAnnouncementUsecase creates a new announcement usecase. users resolves
+// which customer segment a caller is in; impressions and clicks are counted
+// per day in location.
+func NewAnnouncementUsecase(repo *repository.AnnouncementRepository, users *UserUsecase, audit *AuditUsecase, redisClient *redis.Client, location *time.Location, log *logger.Logger) *AnnouncementUsecase {
+	return &AnnouncementUsecase{
+		repo:        repo,
+		users:       users,
+		audit:       audit,
+		redisClient: redisClient,
+		location:    location,
+		log:         log,
+	}
+}
+
+// validate checks in and fills in its defaults
+func (in *AnnouncementInput) validate(now time.Time) error {
+	in.Title = strings.TrimSpace(in.Title)
+	in.Body = strings.TrimSpace(in.Body)
+	in.ImageURL = strings.TrimSpace(in.ImageURL)
+	in.DeepLink = strings.TrimSpace(in.DeepLink)
+
+	if in.Title == "" || utf8.RuneCountInString(in.Title) > maxAnnouncementTitle ||
+		utf8.RuneCountInString(in.Body) > maxAnnouncementBody {
+		return ErrInvalidAnnouncement
+	}
+	if in.ImageURL != "" && !validURL(in.ImageURL, "http", "https") {
+		return ErrInvalidAnnouncementURL
+	}
+	if in.DeepLink != "" && !validURL(in.DeepLink) {
+		return ErrInvalidAnnouncementURL
+	}
+
+	if in.Audience == "" {
+		in.Audience = domain.AudienceAll
+	}
+	if !in.Audience.IsValid() {
+		return ErrInvalidAudience
+	}
+
+	if in.StartsAt.IsZero() {
+		in.StartsAt = now
+	}
+	if in.EndsAt != nil && !in.EndsAt.After(in.StartsAt) {
+		return ErrInvalidAnnouncementTime
+	}
+	return nil
+}
+
+// validURL reports whether raw is an absolute URL of at most
+// maxAnnouncementURL characters, with one of schemes if any are given
+func validURL(raw string, schemes ...string) bool {
+	if len(raw) > maxAnnouncementURL {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	if len(schemes) == 0 {
+		return true
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return u.Host != ""
+		}
+	}
+	return false
+}
+
+// apply copies a validated input onto a banner
+func (in *AnnouncementInput) apply(a *domain.Announcement) {
+	a.Title = in.Title
+	a.Body = in.Body
+	a.ImageURL = in.ImageURL
+	a.DeepLink = in.DeepLink
+	a.Audience = in.Audience
+	a.Priority = in.Priority
+	a.StartsAt = in.StartsAt
+	a.EndsAt = in.EndsAt
+	a.IsActive = in.IsActive == nil || *in.IsActive
+}
+
+// CreateAnnouncement adds a banner on an admin's behalf
+func (u *AnnouncementUsecase) CreateAnnouncement(ctx context.Context, adminID uuid.UUID, in AnnouncementInput) (*domain.Announcement, error) {
+	if err := in.validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	a := &domain.Announcement{CreatedBy: &adminID}
+	in.apply(a)
+	if err := u.repo.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.audit.Record(ctx, adminID, domain.AuditAnnouncementCreated, "announcement", a.ID.String(), map[string]interface{}{
+		"title":    a.Title,
+		"audience": a.Audience,
+	})
+	return a, nil
+}
+
+// UpdateAnnouncement replaces a banner's content, audience and window
+func (u *AnnouncementUsecase) UpdateAnnouncement(ctx context.Context, adminID, id uuid.UUID, in AnnouncementInput) (*domain.Announcement, error) {
+	if err := in.validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	a := &domain.Announcement{ID: id}
+	in.apply(a)
+	if err := u.repo.Update(ctx, a); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.audit.Record(ctx, adminID, domain.AuditAnnouncementUpdated, "announcement", a.ID.String(), map[string]interface{}{
+		"title":     a.Title,
+		"audience":  a.Audience,
+		"is_active": a.IsActive,
+	})
+	return a, nil
+}
+
+// DeleteAnnouncement removes a banner and its stats
+func (u *AnnouncementUsecase) DeleteAnnouncement(ctx context.Context, adminID, id uuid.UUID) error {
+	if err := u.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAnnouncementNotFound
+		}
+		return err
+	}
+	u.invalidate(ctx)
+
+	u.audit.Record(ctx, adminID, domain.AuditAnnouncementDeleted, "announcement", id.String(), nil)
+	return nil
+}
+
+// ListAnnouncements returns every banner with its impressions, clicks and
+// click rate counted from since (all time when zero), newest first
+func (u *AnnouncementUsecase) ListAnnouncements(ctx context.Context, since time.Time) ([]domain.AnnouncementWithStats, error) {
+	return u.repo.ListWithStats(ctx, since)
+}
+
+// ActiveAnnouncements returns the banners live now for the caller, highest
+// priority first. userID is nil for a guest.
+func (u *AnnouncementUsecase) ActiveAnnouncements(ctx context.Context, userID *uuid.UUID) ([]domain.Announcement, error) {
+	current, err := u.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	audiences, err := u.audiencesFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	live := []domain.Announcement{}
+	for i := range current {
+		if current[i].LiveAt(now) && audiences[current[i].Audience] {
+			live = append(live, current[i])
+		}
+	}
+	sort.SliceStable(live, func(i, j int) bool { return live[i].Priority > live[j].Priority })
+
+	return live, nil
+}
+
+// audiencesFor returns the audiences the caller belongs to
+func (u *AnnouncementUsecase) audiencesFor(ctx context.Context, userID *uuid.UUID) (map[domain.AnnouncementAudience]bool, error) {
+	audiences := map[domain.AnnouncementAudience]bool{domain.AudienceAll: true}
+	if userID == nil {
+		audiences[domain.AudienceGuests] = true
+		return audiences, nil
+	}
+
+	stats, err := u.users.OrderStats(ctx, *userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case stats.OrderCount == 0:
+		audiences[domain.AudienceNewCustomers] = true
+	case stats.ChurnRisk:
+		audiences[domain.AudienceLapsedCustomers] = true
+	default:
+		audiences[domain.AudienceReturningCustomers] = true
+	}
+	return audiences, nil
+}
+
+// current loads the banners that are up or still to come through the
+// Redis cache
+func (u *AnnouncementUsecase) current(ctx context.Context) ([]domain.Announcement, error) {
+	if u.redisClient != nil {
+		var cached []domain.Announcement
+		found, err := u.redisClient.GetJSON(ctx, redis.AnnouncementsCacheKey, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read announcements cache", "error", err)
+		} else if found {
+			return cached, nil
+		}
+	}
+
+	current, err := u.repo.ListCurrent(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, redis.AnnouncementsCacheKey, current, redis.AnnouncementsCacheTTL); err != nil {
+			u.log.Warn("Failed to cache announcements", "error", err)
+		}
+	}
+
+	return current, nil
+}
+
+func (u *AnnouncementUsecase) invalidate(ctx context.Context) {
+	if u.redisClient == nil {
+		return
+	}
+	if err := u.redisClient.DeleteKey(ctx, redis.AnnouncementsCacheKey); err != nil {
+		u.log.Warn("Failed to invalidate announcements cache", "error", err)
+	}
+}
+
+// TrackAnnouncements counts the impressions and clicks the app reports.
+// Unknown banners are ignored.
+func (u *AnnouncementUsecase) TrackAnnouncements(ctx context.Context, impressions, clicks []uuid.UUID) error {
+	if len(impressions) > maxAnnouncementEvents || len(clicks) > maxAnnouncementEvents {
+		return ErrTooManyAnnouncementIDs
+	}
+	if len(impressions) == 0 && len(clicks) == 0 {
+		return nil
+	}
+
+	now := time.Now().In(u.location)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return u.repo.RecordEvents(ctx, day, impressions, clicks)
+}
@@ -0,0 +1,368 @@
+// Package usecase implements partner API key issuance, authentication and metering
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// API key errors
+var (
+	ErrInvalidAPIKey     = errors.New("invalid or revoked API key")
+	ErrAPIKeyScope       = errors.New("API key lacks the required scope")
+	ErrAPIKeyRateLimited = errors.New("API key rate limit exceeded")
+	ErrInvalidScope      = errors.New("unknown API key scope")
+)
+
+const (
+	// apiKeyPrefix makes leaked keys easy to spot in logs and secret scanners
+	apiKeyPrefix = "crv_"
+
+	// defaultAPIKeyRateLimit is used when an admin doesn't specify one
+	defaultAPIKeyRateLimit = 60
+
+	// maxUsageDays bounds usage report ranges to the metering TTL
+	maxUsageDays = 35
+)
+
+// APIKeyUsecase handles partner API key business logic
+type APIKeyUsecase struct {
+	apiKeyRepo  *repository.APIKeyRepository
+	redisClient *redis.Client
+	log         *logger.Logger
+}
+
+// NewAPIKeyUsecase creates a new API key usecase
+func NewAPIKeyUsecase(apiKeyRepo *repository.APIKeyRepository, redisClient *redis.Client, log *logger.Logger) *APIKeyUsecase {
+	return &APIKeyUsecase{
+		apiKeyRepo:  apiKeyRepo,
+		redisClient: redisClient,
+		log:         log,
+	}
+}
+
+// IssueAPIKeyRequest contains the settings for a new key
+type IssueAPIKeyRequest struct {
+	Name               string               `json:"name"`
+	Scopes             []domain.APIKeyScope `json:"scopes"`
+	RateLimitPerMinute int                  `json:"rate_limit_per_minute"`
+}
+
+// IssuedAPIKey contains a key record plus the plaintext secret.
+// The plaintext is only ever returned here; we store a hash.
+type IssuedAPIKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// IssueKey creates a new partner API key
+func (u *APIKeyUsecase) IssueKey(ctx context.Context, req IssueAPIKeyRequest, createdBy uuid.UUID) (*IssuedAPIKey, error) {
+	key, plaintext, err := u.newKey(req, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	u.log.Info("API key issued", "api_key_id", key.ID.String(), "name", key.Name, "created_by", createdBy.String())
+
+	return &IssuedAPIKey{APIKey: *key, Key: plaintext}, nil
+}
+
+// RotateKey replaces a key with a new secret carrying the same name, scopes and limits
+func (u *APIKeyUsecase) RotateKey(ctx context.Context, id uuid.UUID, rotatedBy uuid.UUID) (*IssuedAPIKey, error) {
+	old, err := u.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !old.IsActive {
+		return nil, repository.ErrNotFound
+	}
+
+	key, plaintext, err := u.newKey(IssueAPIKeyRequest{
+		Name:               old.Name,
+		Scopes:             old.Scopes,
+		RateLimitPerMinute: old.RateLimitPerMinute,
+	}, rotatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.apiKeyRepo.Rotate(ctx, old.ID, key); err != nil {
+		return nil, err
+	}
+
+	u.evictCachedKey(ctx, old.KeyHash)
+	u.log.Info("API key rotated", "old_api_key_id", old.ID.String(), "api_key_id", key.ID.String())
+
+	return &IssuedAPIKey{APIKey: *key, Key: plaintext}, nil
+}
+
+// RevokeKey deactivates a key immediately
+func (u *APIKeyUsecase) RevokeKey(ctx context.Context, id uuid.UUID) error {
+	key, err := u.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.apiKeyRepo.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	u.evictCachedKey(ctx, key.KeyHash)
+	u.log.Info("API key revoked", "api_key_id", id.String())
+
+	return nil
+}
+
+// ListKeys returns all keys for the admin dashboard
+func (u *APIKeyUsecase) ListKeys(ctx context.Context) ([]domain.APIKey, error) {
+	keys, err := u.apiKeyRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RateLimitResult reports quota state for response headers
+type RateLimitResult struct {
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// Authenticate resolves a plaintext key to its record and consumes one
+// request from its per-endpoint quota for the current minute.
+// Active keys are cached briefly in Redis so the hot path skips Postgres;
+// revocation and rotation evict the cache entry. The cache lookup and the
+// quota increment are pipelined into one round trip, so the counter is
+// keyed by key hash rather than ID. Returns ErrAPIKeyRateLimited with the
+// key once the quota is spent. Redis errors fail open: partner traffic
+// shouldn't stop because the cache is degraded.
+func (u *APIKeyUsecase) Authenticate(ctx context.Context, plaintext, endpoint string) (*domain.APIKey, *RateLimitResult, error) {
+	if plaintext == "" {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	keyHash := hashAPIKey(plaintext)
+	cacheKey := redis.APIKeyCachePrefix + keyHash
+
+	now := time.Now()
+	window := now.Truncate(time.Minute)
+	rlKey := fmt.Sprintf("%s%s:%s:%d", redis.APIKeyRatePrefix, keyHash, endpoint, window.Unix())
+
+	var key *domain.APIKey
+	count := int64(-1)
+	if u.redisClient != nil {
+		pipe := u.redisClient.Pipeline()
+		get := pipe.Get(ctx, cacheKey)
+		incr := pipe.Incr(ctx, rlKey)
+		pipe.ExpireNX(ctx, rlKey, time.Minute)
+
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			u.log.Warn("Failed to read API key cache and rate limit", "error", err)
+		} else {
+			count = incr.Val()
+			if raw, err := get.Bytes(); err == nil {
+				var cached domain.APIKey
+				if err := u.redisClient.DecodeJSON(raw, &cached); err != nil {
+					u.log.Warn("Failed to decode cached API key", "error", err)
+				} else {
+					key = &cached
+				}
+			}
+		}
+	}
+
+	if key == nil {
+		var err error
+		if key, err = u.loadAPIKey(ctx, keyHash, cacheKey); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result := &RateLimitResult{
+		Limit:     key.RateLimitPerMinute,
+		Remaining: key.RateLimitPerMinute,
+		ResetAt:   window.Add(time.Minute),
+	}
+	if count < 0 {
+		return key, result, nil
+	}
+
+	result.Remaining = key.RateLimitPerMinute - int(count)
+	if result.Remaining < 0 {
+		result.Remaining = 0
+		return key, result, ErrAPIKeyRateLimited
+	}
+
+	return key, result, nil
+}
+
+// loadAPIKey reads an active key from Postgres and caches it
+func (u *APIKeyUsecase) loadAPIKey(ctx context.Context, keyHash, cacheKey string) (*domain.APIKey, error) {
+	key, err := u.apiKeyRepo.GetActiveByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, cacheKey, key, redis.APIKeyCacheTTL); err != nil {
+			u.log.Warn("Failed to cache API key", "error", err)
+		}
+	}
+
+	// last_used_at is informational; don't make partners wait on it
+	go func(id uuid.UUID) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := u.apiKeyRepo.TouchLastUsed(ctx, id); err != nil {
+			u.log.Warn("Failed to update API key last used", "error", err)
+		}
+	}(key.ID)
+
+	return key, nil
+}
+
+// RecordUsage meters a request against the key, bucketed per day and endpoint
+func (u *APIKeyUsecase) RecordUsage(ctx context.Context, keyID uuid.UUID, endpoint string, status int) {
+	if u.redisClient == nil {
+		return
+	}
+
+	day := time.Now().UTC().Format("20060102")
+	usageKey := u.usageKey(keyID, day)
+
+	pipe := u.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, usageKey, endpoint, 1)
+	if status >= 400 {
+		pipe.HIncrBy(ctx, usageKey, "errors", 1)
+	}
+	pipe.Expire(ctx, usageKey, redis.APIKeyUsageTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		u.log.Warn("Failed to record API key usage", "error", err)
+	}
+}
+
+// APIKeyDailyUsage holds request counts for one UTC day
+type APIKeyDailyUsage struct {
+	Date       string           `json:"date"`
+	Total      int64            `json:"total"`
+	Errors     int64            `json:"errors"`
+	ByEndpoint map[string]int64 `json:"by_endpoint"`
+}
+
+// GetUsage returns daily usage for the last `days` days, newest first
+func (u *APIKeyUsecase) GetUsage(ctx context.Context, keyID uuid.UUID, days int) ([]APIKeyDailyUsage, error) {
+	if _, err := u.apiKeyRepo.GetByID(ctx, keyID); err != nil {
+		return nil, err
+	}
+
+	if days <= 0 {
+		days = 7
+	}
+	if days > maxUsageDays {
+		days = maxUsageDays
+	}
+
+	usage := make([]APIKeyDailyUsage, 0, days)
+	if u.redisClient == nil {
+		return usage, nil
+	}
+
+	today := time.Now().UTC()
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, -i).Format("20060102")
+
+		counts, err := u.redisClient.HGetAll(ctx, u.usageKey(keyID, day)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key usage: %w", err)
+		}
+
+		entry := APIKeyDailyUsage{Date: day, ByEndpoint: make(map[string]int64)}
+		for field, raw := range counts {
+			n, _ := strconv.ParseInt(raw, 10, 64)
+			if field == "errors" {
+				entry.Errors = n
+				continue
+			}
+			entry.ByEndpoint[field] = n
+			entry.Total += n
+		}
+		usage = append(usage, entry)
+	}
+
+	return usage, nil
+}
+
+// usageKey builds the metering hash key for a key and day
+func (u *APIKeyUsecase) usageKey(keyID uuid.UUID, day string) string {
+	return redis.APIKeyUsagePrefix + keyID.String() + ":" + day
+}
+
+// newKey validates a request and builds a key record with a fresh secret
+func (u *APIKeyUsecase) newKey(req IssueAPIKeyRequest, createdBy uuid.UUID) (*domain.APIKey, string, error) {
+	for _, scope := range req.Scopes {
+		if !domain.ValidAPIKeyScopes[scope] {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	rateLimit := req.RateLimitPerMinute
+	if rateLimit <= 0 {
+		rateLimit = defaultAPIKeyRateLimit
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	return &domain.APIKey{
+		Name:               req.Name,
+		KeyPrefix:          plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:            hashAPIKey(plaintext),
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: rateLimit,
+		IsActive:           true,
+		CreatedBy:          &createdBy,
+		CreatedAt:          time.Now(),
+	}, plaintext, nil
+}
+
+// evictCachedKey drops a key from the auth cache after revocation
+func (u *APIKeyUsecase) evictCachedKey(ctx context.Context, keyHash string) {
+	if u.redisClient == nil {
+		return
+	}
+	if err := u.redisClient.DeleteKey(ctx, redis.APIKeyCachePrefix+keyHash); err != nil {
+		u.log.Warn("Failed to evict API key cache", "error", err)
+	}
+}
+
+// hashAPIKey hashes a plaintext key; keys are 256-bit random so a fast hash is sufficient
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
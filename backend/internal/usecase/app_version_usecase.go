@@ -0,0 +1,204 @@
+// Package usecase implements the minimum app version gate
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+)
+
+// App platforms sent in X-App-Platform
+const (
+	AppPlatformAndroid = "android"
+	AppPlatformIOS     = "ios"
+)
+
+// App version check outcomes recorded in metrics
+const (
+	appVersionResultOK          = "ok"
+	appVersionResultOutdated    = "outdated"
+	appVersionResultUnversioned = "unversioned"
+	appVersionResultInvalid     = "invalid"
+)
+
+// minVersionCacheTTL is how long minimum versions are kept in memory; the
+// gate runs on every request, so it doesn't read settings each time
+const minVersionCacheTTL = 30 * time.Second
+
+var appRequests = metrics.NewCounterVec(
+	"app_requests_total",
+	"API requests from the mobile apps by platform, major.minor version and gate outcome",
+	"platform", "version", "result",
+)
+
+// AppOutdatedError is returned for a build older than its platform's minimum
+type AppOutdatedError struct {
+	Platform   string
+	Version    string
+	MinVersion string
+	UpdateURL  string
+}
+
+func (e *AppOutdatedError) Error() string {
+	return fmt.Sprintf("%s app %s is older than the minimum %s", e.Platform, e.Version, e.MinVersion)
+}
+
+// AppVersionConfig holds where each platform's users get the update
+type AppVersionConfig struct {
+	UpdateURLs map[string]string // Store listing per platform
+}
+
+// AppVersionUsecase rejects requests from app builds older than the minimum
+// version set for their platform. Minimums are runtime settings, so a
+// breaking API change can be rolled out without a redeploy; an empty
+// minimum lets every build through.
+type AppVersionUsecase struct {
+	settings   *SettingsUsecase
+	updateURLs map[string]string
+	log        *logger.Logger
+
+	mu         sync.Mutex
+	minimums   map[string]appVersion
+	minimumRaw map[string]string
+	loadedAt   time.Time
+}
+
+// NewAppVersionUsecase creates a new app version usecase
+func NewAppVersionUsecase(settings *SettingsUsecase, cfg AppVersionConfig, log *logger.Logger) *AppVersionUsecase {
+	return &AppVersionUsecase{
+		settings:   settings,
+		updateURLs: cfg.UpdateURLs,
+		log:        log,
+	}
+}
+
+// Check records the build a request came from and returns an
+// *AppOutdatedError if it is below its platform's minimum. Requests without
+// a version (web, partners, webhooks) and unknown platforms are let through.
+func (u *AppVersionUsecase) Check(ctx context.Context, platform, version string) error {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	version = strings.TrimSpace(version)
+
+	if platform != AppPlatformAndroid && platform != AppPlatformIOS {
+		if version != "" {
+			appRequests.Inc("other", "", appVersionResultUnversioned)
+		}
+		return nil
+	}
+	if version == "" {
+		appRequests.Inc(platform, "", appVersionResultUnversioned)
+		return nil
+	}
+
+	v, err := parseAppVersion(version)
+	if err != nil {
+		// Fail open: a build we can't read is better served than locked out
+		appRequests.Inc(platform, "", appVersionResultInvalid)
+		return nil
+	}
+
+	minimum, minimumRaw, ok := u.minimum(ctx, platform)
+	if ok && v.less(minimum) {
+		appRequests.Inc(platform, v.label(), appVersionResultOutdated)
+		return &AppOutdatedError{
+			Platform:   platform,
+			Version:    version,
+			MinVersion: minimumRaw,
+			UpdateURL:  u.updateURLs[platform],
+		}
+	}
+
+	appRequests.Inc(platform, v.label(), appVersionResultOK)
+	return nil
+}
+
+// minimum returns the platform's minimum version, if one is set
+func (u *AppVersionUsecase) minimum(ctx context.Context, platform string) (appVersion, string, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.minimums == nil || time.Since(u.loadedAt) > minVersionCacheTTL {
+		u.minimums = make(map[string]appVersion, 2)
+		u.minimumRaw = make(map[string]string, 2)
+		for p, key := range map[string]string{
+			AppPlatformAndroid: SettingAppMinVersionAndroid,
+			AppPlatformIOS:     SettingAppMinVersionIOS,
+		} {
+			raw := strings.TrimSpace(u.settings.String(ctx, key))
+			if raw == "" {
+				continue
+			}
+			v, err := parseAppVersion(raw)
+			if err != nil {
+				u.log.Error("Minimum app version is malformed, not enforcing it", "key", key, "value", raw)
+				continue
+			}
+			u.minimums[p] = v
+			u.minimumRaw[p] = raw
+		}
+		u.loadedAt = time.Now()
+	}
+
+	v, ok := u.minimums[platform]
+	return v, u.minimumRaw[platform], ok
+}
+
+// ValidateAppVersion accepts "" (no minimum) or a version such as 2.4 or 2.4.1
+func ValidateAppVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	_, err := parseAppVersion(version)
+	return err
+}
+
+// appVersion is a major.minor.patch build version
+type appVersion [3]int
+
+var errInvalidAppVersion = errors.New("version must look like 2.4.1")
+
+// parseAppVersion reads major[.minor[.patch]], ignoring a build or
+// pre-release suffix ("2.4.1+87", "2.4.1-beta")
+func parseAppVersion(s string) (appVersion, error) {
+	var v appVersion
+	if i := strings.IndexAny(s, "+- "); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > len(v) {
+		return v, errInvalidAppVersion
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 99999 {
+			return v, errInvalidAppVersion
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v appVersion) less(other appVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// label is the metric label for the version, major.minor to keep the
+// number of series bounded
+func (v appVersion) label() string {
+	if v[0] > 999 || v[1] > 999 {
+		return "other"
+	}
+	return strconv.Itoa(v[0]) + "." + strconv.Itoa(v[1])
+}
@@ -0,0 +1,138 @@
+// Package usecase implements app attestation checks for abuse-prone endpoints
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"fooddelivery/pkg/attestation"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/redis"
+)
+
+// ErrAttestationFailed is returned in enforce mode when a request can't prove
+// it came from a genuine app build
+var ErrAttestationFailed = errors.New("app attestation failed")
+
+// Attestation check outcomes recorded in metrics
+const (
+	attestationResultPass    = "pass"
+	attestationResultFail    = "fail"
+	attestationResultMissing = "missing"
+	attestationResultError   = "error"
+)
+
+var attestationChecks = metrics.NewCounterVec(
+	"attestation_checks_total",
+	"App attestation checks by protected action, outcome and mode",
+	"action", "result", "mode",
+)
+
+// AttestationUsecase verifies attestation tokens according to the configured mode
+type AttestationUsecase struct {
+	verifier    attestation.Verifier
+	mode        attestation.Mode
+	redisClient *redis.Client
+	log         *logger.Logger
+}
+
+// NewAttestationUsecase creates a new attestation usecase.
+// A nil verifier forces ModeOff.
+func NewAttestationUsecase(verifier attestation.Verifier, mode attestation.Mode, redisClient *redis.Client, log *logger.Logger) *AttestationUsecase {
+	if verifier == nil {
+		mode = attestation.ModeOff
+	}
+	return &AttestationUsecase{
+		verifier:    verifier,
+		mode:        mode,
+		redisClient: redisClient,
+		log:         log,
+	}
+}
+
+// Mode returns the active attestation mode
+func (u *AttestationUsecase) Mode() attestation.Mode {
+	return u.mode
+}
+
+// Check verifies the token for a protected action. It only returns
+// ErrAttestationFailed in enforce mode; in monitor mode failures are
+// recorded but the request proceeds. Verifier outages fail open so a
+// Google-side incident can't take ordering down.
+func (u *AttestationUsecase) Check(ctx context.Context, action, token string) error {
+	if u.mode == attestation.ModeOff {
+		return nil
+	}
+
+	if token == "" {
+		attestationChecks.Inc(action, attestationResultMissing, string(u.mode))
+		return u.reject()
+	}
+
+	verdict, err := u.verify(ctx, token)
+	if err != nil {
+		attestationChecks.Inc(action, attestationResultError, string(u.mode))
+		u.log.Warn("Attestation verification unavailable", "action", action, "error", err)
+		return nil
+	}
+
+	if !verdict.Valid {
+		attestationChecks.Inc(action, attestationResultFail, string(u.mode))
+		u.log.Info("Attestation rejected", "action", action, "reason", verdict.Reason, "app_id", verdict.AppID, "mode", u.mode)
+		return u.reject()
+	}
+
+	attestationChecks.Inc(action, attestationResultPass, string(u.mode))
+	return nil
+}
+
+func (u *AttestationUsecase) reject() error {
+	if u.mode == attestation.ModeEnforce {
+		return ErrAttestationFailed
+	}
+	return nil
+}
+
+// verify consults the verdict cache before the verifier. Tokens are reused by
+// clients for their lifetime, so caching saves a signature check per request;
+// rejected tokens are cached too since bots tend to replay them.
+func (u *AttestationUsecase) verify(ctx context.Context, token string) (*attestation.Verdict, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := redis.AttestationVerdictPrefix + hex.EncodeToString(sum[:])
+
+	if u.redisClient != nil {
+		var cached attestation.Verdict
+		found, err := u.redisClient.GetJSON(ctx, cacheKey, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read attestation cache", "error", err)
+		} else if found {
+			return &cached, nil
+		}
+	}
+
+	verdict, err := u.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.redisClient != nil {
+		ttl := redis.AttestationRejectTTL
+		if verdict.Valid {
+			ttl = redis.AttestationVerdictTTL
+			if remaining := time.Until(verdict.ExpiresAt); remaining < ttl {
+				ttl = remaining
+			}
+		}
+		if ttl > 0 {
+			if err := u.redisClient.SetJSON(ctx, cacheKey, verdict, ttl); err != nil {
+				u.log.Warn("Failed to cache attestation verdict", "error", err)
+			}
+		}
+	}
+
+	return verdict, nil
+}
@@ -0,0 +1,132 @@
+// Package usecase implements the admin audit log and activity feed
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+const (
+	defaultActivityWindow = 24 * time.Hour
+	maxActivityWindow     = 31 * 24 * time.Hour
+	defaultActivityLimit  = 50
+	maxActivityLimit      = 200
+)
+
+// ErrInvalidActivityWindow is returned for a since older than 31 days
+var ErrInvalidActivityWindow = errors.New("since must be within the last 31 days")
+
+// ActivityQuery selects admin activity. A zero Since means the last 24 hours.
+type ActivityQuery struct {
+	Since   time.Time
+	ActorID *uuid.UUID
+	Actions []domain.AuditAction
+	Limit   int
+	Offset  int
+}
+
+// AdminActivity is one page of the activity feed with per-action totals for
+// the whole window, for shift handover
+type AdminActivity struct {
+	Since   time.Time                  `json:"since"`
+	Counts  map[domain.AuditAction]int `json:"counts"`
+	Entries []domain.AuditEntry        `json:"entries"`
+	Limit   int                        `json:"limit"`
+	Offset  int                        `json:"offset"`
+	HasMore bool                       `json:"has_more"`
+}
+
+// AuditUsecase records admin actions and serves the activity feed
+type AuditUsecase struct {
+	repo *repository.AuditRepository
+	log  *logger.Logger
+}
+
+// NewAuditUsecase creates a new audit usecase
+func NewAuditUsecase(repo *repository.AuditRepository, log *logger.Logger) *AuditUsecase {
+	return &AuditUsecase{repo: repo, log: log}
+}
+
+// Record appends an admin action to the audit log. Failures are logged, not
+// returned: the action itself has already succeeded. Safe to call on a nil
+// AuditUsecase, which records nothing.
+func (u *AuditUsecase) Record(ctx context.Context, actorID uuid.UUID, action domain.AuditAction, entityType, entityID string, details map[string]interface{}) {
+	if u == nil {
+		return
+	}
+
+	entry := &domain.AuditEntry{
+		ActorID:    &actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err != nil {
+			u.log.Warn("Failed to encode audit details", "action", action, "error", err)
+		} else {
+			entry.Details = data
+		}
+	}
+
+	if err := u.repo.Record(ctx, entry); err != nil {
+		u.log.Warn("Failed to record audit entry", "action", action, "entity_id", entityID, "error", err)
+	}
+}
+
+// Activity returns recent admin actions, newest first
+func (u *AuditUsecase) Activity(ctx context.Context, q ActivityQuery) (*AdminActivity, error) {
+	now := time.Now()
+	if q.Since.IsZero() {
+		q.Since = now.Add(-defaultActivityWindow)
+	}
+	if q.Since.Before(now.Add(-maxActivityWindow)) {
+		return nil, ErrInvalidActivityWindow
+	}
+	if q.Limit <= 0 || q.Limit > maxActivityLimit {
+		q.Limit = defaultActivityLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	filter := repository.AuditFilter{
+		Since:   q.Since,
+		ActorID: q.ActorID,
+		Actions: q.Actions,
+		Limit:   q.Limit + 1, // One extra row tells whether another page exists
+		Offset:  q.Offset,
+	}
+
+	entries, err := u.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := u.repo.CountByAction(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &AdminActivity{
+		Since:   q.Since,
+		Counts:  counts,
+		Entries: entries,
+		Limit:   q.Limit,
+		Offset:  q.Offset,
+	}
+	if len(entries) > q.Limit {
+		activity.Entries = entries[:q.Limit]
+		activity.HasMore = true
+	}
+
+	return activity, nil
+}
@@ -0,0 +1,327 @@
+// Package usecase implements multi-order delivery batching and route planning
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+)
+
+// Batching errors
+var (
+	ErrEmptyBatch        = errors.New("a batch needs at least one order")
+	ErrBatchTooLarge     = errors.New("too many orders for one batch")
+	ErrOrderNotBatchable = errors.New("order is not an accepted delivery order with a location")
+	ErrNoActiveBatch     = errors.New("rider has no active delivery batch")
+)
+
+// BatchConfig tunes grouping and ETA estimation
+type BatchConfig struct {
+	Origin         geo.Point     // Kitchen location; routes start here
+	RadiusMeters   float64       // Max distance between orders grouped together
+	MaxOrders      int           // Max orders one rider carries
+	RiderSpeedKmph float64       // Average road speed used for ETAs
+	StopDwell      time.Duration // Time spent handing over at each stop
+}
+
+// BatchUsecase groups ready orders and plans rider routes
+type BatchUsecase struct {
+	orderRepo    *repository.OrderRepository
+	batchRepo    *repository.DeliveryBatchRepository
+	orderUsecase *OrderUsecase
+	presence     *PresenceUsecase
+	cfg          BatchConfig
+	log          *logger.Logger
+}
+
+// NewBatchUsecase creates a new batch usecase
+func NewBatchUsecase(
+	orderRepo *repository.OrderRepository,
+	batchRepo *repository.DeliveryBatchRepository,
+	orderUsecase *OrderUsecase,
+	cfg BatchConfig,
+	log *logger.Logger,
+) *BatchUsecase {
+	return &BatchUsecase{
+		orderRepo:    orderRepo,
+		batchRepo:    batchRepo,
+		orderUsecase: orderUsecase,
+		cfg:          cfg,
+		log:          log,
+	}
+}
+
+// SetPresence suggests online riders for batches
+func (u *BatchUsecase) SetPresence(presence *PresenceUsecase) {
+	u.presence = presence
+}
+
+// BatchSuggestion is a proposed group of orders with its planned route and,
+// when one is free, an online rider to take it
+type BatchSuggestion struct {
+	OrderIDs            []uuid.UUID                `json:"order_ids"`
+	TotalDistanceMeters int                        `json:"total_distance_meters"`
+	Stops               []domain.DeliveryBatchStop `json:"stops"`
+	Rider               *Presence                  `json:"rider,omitempty"`
+}
+
+// SuggestBatches groups orders waiting for a rider. Oldest orders seed
+// groups so nobody waits behind newer orders, and each seed pulls in the
+// closest other orders within the configured radius.
+func (u *BatchUsecase) SuggestBatches(ctx context.Context) ([]BatchSuggestion, error) {
+	orders, err := u.orderRepo.GetReadyForDispatch(ctx, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	remaining := orders
+	var suggestions []BatchSuggestion
+
+	for len(remaining) > 0 {
+		seed := remaining[0]
+		seedPoint := orderPoint(&seed)
+
+		type candidate struct {
+			idx  int
+			dist float64
+		}
+		var nearby []candidate
+		for i := 1; i < len(remaining); i++ {
+			if d := geo.HaversineMeters(seedPoint, orderPoint(&remaining[i])); d <= u.cfg.RadiusMeters {
+				nearby = append(nearby, candidate{idx: i, dist: d})
+			}
+		}
+		sort.Slice(nearby, func(i, j int) bool { return nearby[i].dist < nearby[j].dist })
+
+		taken := map[int]bool{0: true}
+		group := []domain.Order{seed}
+		for _, c := range nearby {
+			if len(group) >= u.cfg.MaxOrders {
+				break
+			}
+			taken[c.idx] = true
+			group = append(group, remaining[c.idx])
+		}
+
+		stops, total := u.planRoute(group, now)
+		suggestion := BatchSuggestion{TotalDistanceMeters: total, Stops: stops}
+		for _, s := range stops {
+			suggestion.OrderIDs = append(suggestion.OrderIDs, s.OrderID)
+		}
+		suggestions = append(suggestions, suggestion)
+
+		next := remaining[:0:0]
+		for i := range remaining {
+			if !taken[i] {
+				next = append(next, remaining[i])
+			}
+		}
+		remaining = next
+	}
+
+	u.suggestRiders(ctx, suggestions)
+	return suggestions, nil
+}
+
+// suggestRiders gives the oldest batches the online riders without a trip
+// in progress, nearest to the kitchen first. Presence is advisory: if it
+// can't be read the batches are returned without riders.
+func (u *BatchUsecase) suggestRiders(ctx context.Context, suggestions []BatchSuggestion) {
+	if u.presence == nil || len(suggestions) == 0 {
+		return
+	}
+	online, err := u.presence.Online(ctx, PresenceRider)
+	if err != nil {
+		u.log.Warn("Failed to load online riders", "error", err)
+		return
+	}
+	busy, err := u.batchRepo.GetBusyRiders(ctx)
+	if err != nil {
+		u.log.Warn("Failed to load busy riders", "error", err)
+		return
+	}
+
+	free := online[:0]
+	for _, rider := range online {
+		if !busy[rider.UserID] {
+			free = append(free, rider)
+		}
+	}
+	nearestOnline(free, u.cfg.Origin)
+
+	for i := range suggestions {
+		if i == len(free) {
+			break
+		}
+		suggestions[i].Rider = &free[i]
+	}
+}
+
+// CreateBatch dispatches the orders to the rider as one trip and stores the
+// optimized route. Orders are validated up-front so a bad ID doesn't leave
+// the batch half dispatched.
+func (u *BatchUsecase) CreateBatch(ctx context.Context, riderID uuid.UUID, orderIDs []uuid.UUID, createdBy uuid.UUID) (*domain.DeliveryBatch, error) {
+	if len(orderIDs) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(orderIDs) > u.cfg.MaxOrders {
+		return nil, ErrBatchTooLarge
+	}
+
+	orders := make([]domain.Order, 0, len(orderIDs))
+	seen := make(map[uuid.UUID]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		order, err := u.orderRepo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, fmt.Errorf("%w: %s", ErrOrderNotBatchable, id)
+			}
+			return nil, err
+		}
+		if order.Status != domain.OrderStatusAccepted ||
+			order.FulfillmentType != domain.FulfillmentDelivery ||
+			order.DeliveryLocation == nil {
+			return nil, fmt.Errorf("%w: %s", ErrOrderNotBatchable, id)
+		}
+		orders = append(orders, *order)
+	}
+
+	for _, order := range orders {
+		if _, err := u.orderUsecase.DispatchOrder(ctx, order.ID, riderID); err != nil {
+			return nil, fmt.Errorf("failed to dispatch order %s: %w", order.ID, err)
+		}
+	}
+
+	stops, total := u.planRoute(orders, time.Now())
+	batch := &domain.DeliveryBatch{
+		RiderID:             riderID,
+		TotalDistanceMeters: total,
+		Stops:               stops,
+		CreatedBy:           &createdBy,
+	}
+
+	if err := u.batchRepo.Create(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to save delivery batch: %w", err)
+	}
+
+	u.log.Info("Delivery batch created",
+		"batch_id", batch.ID.String(),
+		"rider_id", riderID.String(),
+		"orders", len(stops),
+		"distance_m", total,
+	)
+
+	return batch, nil
+}
+
+// GetRiderRoute returns the rider's active batch. ETAs for stops not yet
+// delivered are re-projected from now so the rider app stays accurate when
+// the trip starts late.
+func (u *BatchUsecase) GetRiderRoute(ctx context.Context, riderID uuid.UUID) (*domain.DeliveryBatch, error) {
+	batch, err := u.batchRepo.GetActiveByRider(ctx, riderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNoActiveBatch
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	eta := now
+	started := false
+	for i := range batch.Stops {
+		stop := &batch.Stops[i]
+		if stop.Status != domain.OrderStatusOutForDelivery {
+			continue
+		}
+		if !started {
+			// The rider is somewhere on the leg to the first pending stop; keep
+			// the planned ETA unless the plan is already behind
+			started = true
+			if stop.EstimatedArrivalAt.After(now) {
+				eta = stop.EstimatedArrivalAt
+			} else {
+				eta = now.Add(u.travelTime(float64(stop.LegDistanceMeters)))
+			}
+		} else {
+			eta = eta.Add(u.cfg.StopDwell).Add(u.travelTime(float64(stop.LegDistanceMeters)))
+		}
+		stop.EstimatedArrivalAt = eta
+	}
+
+	return batch, nil
+}
+
+// planRoute orders stops nearest-neighbor from the kitchen and projects ETAs
+func (u *BatchUsecase) planRoute(orders []domain.Order, start time.Time) ([]domain.DeliveryBatchStop, int) {
+	points := make([]geo.Point, len(orders))
+	for i := range orders {
+		points[i] = orderPoint(&orders[i])
+	}
+
+	origin := u.cfg.Origin
+	if origin.IsZero() && len(points) > 0 {
+		origin = points[0]
+	}
+
+	sequence := geo.NearestNeighborRoute(origin, points, geo.HaversineMeters)
+
+	stops := make([]domain.DeliveryBatchStop, 0, len(sequence))
+	current := origin
+	eta := start
+	var total float64
+
+	for i, idx := range sequence {
+		leg := geo.HaversineMeters(current, points[idx])
+		total += leg
+		if i > 0 {
+			eta = eta.Add(u.cfg.StopDwell)
+		}
+		eta = eta.Add(u.travelTime(leg))
+
+		stops = append(stops, domain.DeliveryBatchStop{
+			OrderID:            orders[idx].ID,
+			Sequence:           i + 1,
+			Location:           *orders[idx].DeliveryLocation,
+			LegDistanceMeters:  int(math.Round(leg)),
+			EstimatedArrivalAt: eta,
+			Status:             orders[idx].Status,
+		})
+		current = points[idx]
+	}
+
+	return stops, int(math.Round(total))
+}
+
+// travelTime converts a distance to riding time at the configured speed
+func (u *BatchUsecase) travelTime(meters float64) time.Duration {
+	if u.cfg.RiderSpeedKmph <= 0 {
+		return 0
+	}
+	hours := meters / 1000 / u.cfg.RiderSpeedKmph
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// orderPoint returns an order's drop-off coordinates
+func orderPoint(order *domain.Order) geo.Point {
+	if order.DeliveryLocation == nil {
+		return geo.Point{}
+	}
+	return geo.Point{Latitude: order.DeliveryLocation.Latitude, Longitude: order.DeliveryLocation.Longitude}
+}
@@ -0,0 +1,276 @@
+// Package usecase implements kitchen capacity planning in 15-minute slots
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// SlotDuration is the kitchen planning granularity
+const SlotDuration = 15 * time.Minute
+
+const (
+	maxScheduleAhead        = 7 * 24 * time.Hour // Furthest a customer can schedule
+	nextAvailableSuggestion = 3                  // Slots offered when the requested one is full
+)
+
+// Capacity errors
+var (
+	ErrKitchenAtCapacity   = errors.New("kitchen is at capacity for the requested time")
+	ErrInvalidSlot         = errors.New("requested time is in the past or too far ahead")
+	ErrInvalidCapacityRule = errors.New("invalid capacity rule")
+)
+
+// SlotUnavailableError carries the next slots that can still take an order,
+// so the client can offer them or switch to scheduled ordering
+type SlotUnavailableError struct {
+	Requested     time.Time
+	NextAvailable []time.Time
+}
+
+// Error implements error
+func (e *SlotUnavailableError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrKitchenAtCapacity, e.Requested.Format(time.RFC3339))
+}
+
+// Unwrap lets errors.Is match ErrKitchenAtCapacity
+func (e *SlotUnavailableError) Unwrap() error {
+	return ErrKitchenAtCapacity
+}
+
+// SlotAvailability describes one kitchen slot
+type SlotAvailability struct {
+	Start     time.Time `json:"start"`
+	Capacity  int       `json:"capacity"` // -1 = unlimited
+	Booked    int       `json:"booked"`
+	Available bool      `json:"available"`
+}
+
+// CapacityUsecase books orders into kitchen slots
+type CapacityUsecase struct {
+	repo            *repository.CapacityRepository
+	defaultCapacity int // Orders per slot outside any rule; 0 = unlimited
+	settings        *SettingsUsecase
+	location        *time.Location
+	audit           *AuditUsecase
+	log             *logger.Logger
+}
+
+// NewCapacityUsecase creates a new capacity usecase. Rules are evaluated in
+// the kitchen's time zone.
+func NewCapacityUsecase(repo *repository.CapacityRepository, defaultCapacity int, location *time.Location, log *logger.Logger) *CapacityUsecase {
+	if location == nil {
+		location = time.Local
+	}
+	return &CapacityUsecase{
+		repo:            repo,
+		defaultCapacity: defaultCapacity,
+		location:        location,
+		log:             log,
+	}
+}
+
+// SetSettings lets admins change the default slot capacity at runtime
+func (u *CapacityUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// SlotStart truncates t to the start of its slot
+func SlotStart(t time.Time) time.Time {
+	return t.Truncate(SlotDuration)
+}
+
+// GetRules returns the configured capacity rules
+func (u *CapacityUsecase) GetRules(ctx context.Context) ([]domain.KitchenCapacityRule, error) {
+	return u.repo.ListRules(ctx)
+}
+
+// SetAuditLog records rule changes in the admin audit log
+func (u *CapacityUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// ReplaceRules validates and stores a new rule set
+func (u *CapacityUsecase) ReplaceRules(ctx context.Context, rules []domain.KitchenCapacityRule, adminID uuid.UUID) ([]domain.KitchenCapacityRule, error) {
+	for i := range rules {
+		rule := &rules[i]
+		start, err1 := parseClock(rule.StartTime)
+		end, err2 := parseClock(rule.EndTime)
+		if err1 != nil || err2 != nil || end <= start {
+			return nil, fmt.Errorf("%w: window %q-%q", ErrInvalidCapacityRule, rule.StartTime, rule.EndTime)
+		}
+		if rule.DayOfWeek != nil && (*rule.DayOfWeek < 0 || *rule.DayOfWeek > 6) {
+			return nil, fmt.Errorf("%w: day_of_week must be 0-6", ErrInvalidCapacityRule)
+		}
+		if rule.OrdersPerSlot < 0 {
+			return nil, fmt.Errorf("%w: orders_per_slot must not be negative", ErrInvalidCapacityRule)
+		}
+		rule.UpdatedBy = &adminID
+	}
+
+	if err := u.repo.ReplaceRules(ctx, rules); err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Kitchen capacity rules updated", "admin_id", adminID.String(), "rules", len(rules))
+	u.audit.Record(ctx, adminID, domain.AuditCapacityRulesReplaced, "capacity_rules", "kitchen", map[string]interface{}{
+		"rules": len(rules),
+	})
+	return rules, nil
+}
+
+// Book reserves a slot for a new order. With no requested time the current
+// slot is booked; when it's full the error lists the next open slots.
+// Returns the booked slot and whether it was a scheduled (future) booking.
+func (u *CapacityUsecase) Book(ctx context.Context, requested *time.Time) (time.Time, bool, error) {
+	now := time.Now()
+	current := SlotStart(now)
+
+	slot := current
+	if requested != nil {
+		slot = SlotStart(*requested)
+		if slot.Before(current) || slot.After(now.Add(maxScheduleAhead)) {
+			return time.Time{}, false, ErrInvalidSlot
+		}
+	}
+
+	rules, err := u.repo.ListRules(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	fallback := u.slotDefault(ctx)
+	capacity := u.capacityFor(rules, fallback, slot)
+	if capacity >= 0 {
+		ok, err := u.repo.BookSlot(ctx, slot, capacity)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if !ok {
+			next, err := u.nextAvailable(ctx, rules, fallback, slot.Add(SlotDuration), nextAvailableSuggestion)
+			if err != nil {
+				return time.Time{}, false, err
+			}
+			return time.Time{}, false, &SlotUnavailableError{Requested: slot, NextAvailable: next}
+		}
+	}
+
+	return slot, slot.After(current), nil
+}
+
+// Release returns a booking to its slot
+func (u *CapacityUsecase) Release(ctx context.Context, slot time.Time) {
+	if err := u.repo.ReleaseSlot(ctx, slot); err != nil {
+		u.log.Warn("Failed to release kitchen slot", "slot", slot, "error", err)
+	}
+}
+
+// GetAvailability lists count slots starting at the slot containing from
+func (u *CapacityUsecase) GetAvailability(ctx context.Context, from time.Time, count int) ([]SlotAvailability, error) {
+	rules, err := u.repo.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := SlotStart(from)
+	bookings, err := u.repo.GetBookings(ctx, start, start.Add(time.Duration(count)*SlotDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := u.slotDefault(ctx)
+	slots := make([]SlotAvailability, 0, count)
+	for i := 0; i < count; i++ {
+		slot := start.Add(time.Duration(i) * SlotDuration)
+		capacity := u.capacityFor(rules, fallback, slot)
+		booked := bookings[slot.UTC()]
+		slots = append(slots, SlotAvailability{
+			Start:     slot,
+			Capacity:  capacity,
+			Booked:    booked,
+			Available: capacity < 0 || booked < capacity,
+		})
+	}
+
+	return slots, nil
+}
+
+// nextAvailable finds up to n open slots at or after from, within a day
+func (u *CapacityUsecase) nextAvailable(ctx context.Context, rules []domain.KitchenCapacityRule, fallback int, from time.Time, n int) ([]time.Time, error) {
+	const lookahead = 24 * time.Hour / SlotDuration
+
+	bookings, err := u.repo.GetBookings(ctx, from, from.Add(lookahead*SlotDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	var next []time.Time
+	for i := 0; i < int(lookahead) && len(next) < n; i++ {
+		slot := from.Add(time.Duration(i) * SlotDuration)
+		capacity := u.capacityFor(rules, fallback, slot)
+		if capacity < 0 || bookings[slot.UTC()] < capacity {
+			next = append(next, slot)
+		}
+	}
+
+	return next, nil
+}
+
+// slotDefault returns the orders per slot outside any rule; 0 = unlimited
+func (u *CapacityUsecase) slotDefault(ctx context.Context) int {
+	if u.settings != nil {
+		return u.settings.Int(ctx, SettingKitchenSlotCapacity)
+	}
+	return u.defaultCapacity
+}
+
+// capacityFor returns the orders allowed in a slot, or -1 for unlimited.
+// A weekday-specific rule wins over an every-day rule; fallback applies
+// outside any rule.
+func (u *CapacityUsecase) capacityFor(rules []domain.KitchenCapacityRule, fallback int, slot time.Time) int {
+	local := slot.In(u.location)
+	minute := local.Hour()*60 + local.Minute()
+	weekday := int(local.Weekday())
+
+	capacity, specific, matched := 0, false, false
+	for _, rule := range rules {
+		start, _ := parseClock(rule.StartTime)
+		end, _ := parseClock(rule.EndTime)
+		if minute < start || minute >= end {
+			continue
+		}
+		if rule.DayOfWeek != nil {
+			if *rule.DayOfWeek != weekday {
+				continue
+			}
+			capacity, specific, matched = rule.OrdersPerSlot, true, true
+		} else if !specific {
+			capacity, matched = rule.OrdersPerSlot, true
+		}
+	}
+
+	if matched {
+		return capacity
+	}
+	if fallback <= 0 {
+		return -1
+	}
+	return fallback
+}
+
+// parseClock converts "HH:MM" to minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
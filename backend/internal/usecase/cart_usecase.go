@@ -0,0 +1,342 @@
+// Package usecase implements saved carts for guests and signed-in users
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// Cart errors
+var (
+	// ErrGuestSessionExpired is returned for a guest token we didn't issue or
+	// whose cart has expired
+	ErrGuestSessionExpired = errors.New("guest session not found or expired")
+	// ErrCartItemNotFound is returned when changing a line the cart doesn't have
+	ErrCartItemNotFound = errors.New("item is not in the cart")
+)
+
+// Cart limits; checkout applies its own checks on top
+const (
+	maxCartLines    = 50
+	maxCartQuantity = 50
+)
+
+// GuestSession identifies an anonymous browser's cart. The token is only
+// shown once; the cart is stored under its hash.
+type GuestSession struct {
+	Token     string    `json:"guest_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GuestCart is a guest's saved cart
+type GuestCart struct {
+	Items     []domain.CartItem `json:"items"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// storedCart is the Redis value of a guest cart
+type storedCart struct {
+	Items     []domain.CartItem `json:"items"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// CartUsecase keeps carts so guests can build one before they have an
+// account, and signed-in users find theirs on any device. Guest carts live
+// in Redis only; user carts are kept in Postgres behind a Redis cache.
+type CartUsecase struct {
+	redisClient *redis.Client
+	cartRepo    *repository.CartRepository
+	menuRepo    *repository.MenuRepository
+	log         *logger.Logger
+}
+
+// NewCartUsecase creates a new cart usecase
+func NewCartUsecase(redisClient *redis.Client, cartRepo *repository.CartRepository, menuRepo *repository.MenuRepository, log *logger.Logger) *CartUsecase {
+	return &CartUsecase{
+		redisClient: redisClient,
+		cartRepo:    cartRepo,
+		menuRepo:    menuRepo,
+		log:         log,
+	}
+}
+
+// StartGuestSession issues a guest token with an empty cart
+func (u *CartUsecase) StartGuestSession(ctx context.Context) (*GuestSession, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate guest token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := u.redisClient.SetJSON(ctx, guestCartKey(token), storedCart{Items: []domain.CartItem{}, UpdatedAt: time.Now()}, redis.GuestCartTTL); err != nil {
+		return nil, err
+	}
+
+	return &GuestSession{Token: token, ExpiresAt: time.Now().Add(redis.GuestCartTTL)}, nil
+}
+
+// GetGuestCart returns the guest's cart
+func (u *CartUsecase) GetGuestCart(ctx context.Context, token string) (*GuestCart, error) {
+	var cart storedCart
+	found, err := u.redisClient.GetAndExtendTTL(ctx, guestCartKey(token), &cart, redis.GuestCartTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrGuestSessionExpired
+	}
+	return &GuestCart{Items: cart.Items, ExpiresAt: time.Now().Add(redis.GuestCartTTL)}, nil
+}
+
+// SaveGuestCart replaces the guest's cart
+func (u *CartUsecase) SaveGuestCart(ctx context.Context, token string, items []domain.CartItem) (*GuestCart, error) {
+	key := guestCartKey(token)
+	exists, err := u.redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis exists failed: %w", err)
+	}
+	if exists == 0 {
+		return nil, ErrGuestSessionExpired
+	}
+
+	items, err = u.normalizeItems(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.redisClient.SetJSON(ctx, key, storedCart{Items: items, UpdatedAt: time.Now()}, redis.GuestCartTTL); err != nil {
+		return nil, err
+	}
+
+	return &GuestCart{Items: items, ExpiresAt: time.Now().Add(redis.GuestCartTTL)}, nil
+}
+
+// GetCart returns the user's saved cart, empty if they have none
+func (u *CartUsecase) GetCart(ctx context.Context, userID uuid.UUID) (*domain.Cart, error) {
+	items, err := u.cartRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Cart{UserID: userID, Items: items}, nil
+}
+
+// SaveCart replaces the user's saved cart
+func (u *CartUsecase) SaveCart(ctx context.Context, userID uuid.UUID, items []domain.CartItem) (*domain.Cart, error) {
+	items, err := u.normalizeItems(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	return u.update(ctx, userID, func([]domain.CartItem) ([]domain.CartItem, error) {
+		return items, nil
+	})
+}
+
+// AddItem adds an item to the user's cart, on top of any quantity of it
+// already there
+func (u *CartUsecase) AddItem(ctx context.Context, userID uuid.UUID, item domain.CartItem) (*domain.Cart, error) {
+	added, err := u.normalizeItems(ctx, []domain.CartItem{item})
+	if err != nil {
+		return nil, err
+	}
+	return u.update(ctx, userID, func(items []domain.CartItem) ([]domain.CartItem, error) {
+		return addCartItems(items, added, true)
+	})
+}
+
+// UpdateQuantity sets how many the cart holds of the line with line's item,
+// variant and modifiers; 0 removes it
+func (u *CartUsecase) UpdateQuantity(ctx context.Context, userID uuid.UUID, line domain.CartItem, quantity int) (*domain.Cart, error) {
+	if quantity < 0 || quantity > maxCartQuantity {
+		return nil, ErrInvalidCart
+	}
+	key := line.LineKey()
+	return u.update(ctx, userID, func(items []domain.CartItem) ([]domain.CartItem, error) {
+		for i := range items {
+			if items[i].LineKey() != key {
+				continue
+			}
+			if quantity == 0 {
+				return append(items[:i], items[i+1:]...), nil
+			}
+			items[i].Quantity = quantity
+			return items, nil
+		}
+		return nil, ErrCartItemNotFound
+	})
+}
+
+// RemoveItem takes the line with line's item, variant and modifiers out of
+// the user's cart. Removing a line the cart doesn't hold leaves it as is.
+func (u *CartUsecase) RemoveItem(ctx context.Context, userID uuid.UUID, line domain.CartItem) (*domain.Cart, error) {
+	key := line.LineKey()
+	return u.update(ctx, userID, func(items []domain.CartItem) ([]domain.CartItem, error) {
+		kept := items[:0]
+		for _, item := range items {
+			if item.LineKey() != key {
+				kept = append(kept, item)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// Clear empties the user's cart
+func (u *CartUsecase) Clear(ctx context.Context, userID uuid.UUID) error {
+	return u.cartRepo.Delete(ctx, userID)
+}
+
+// MergeGuestCart moves a guest's cart into the user's, adding quantities of
+// items in both, and ends the guest session. Merging an already merged or
+// expired guest cart leaves the user's cart as is. If the user's cart
+// can't be saved the guest cart is put back so the merge can be retried.
+func (u *CartUsecase) MergeGuestCart(ctx context.Context, userID uuid.UUID, token string) (*domain.Cart, error) {
+	key := guestCartKey(token)
+	var guest storedCart
+	found, err := u.redisClient.GetDelJSON(ctx, key, &guest)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return u.GetCart(ctx, userID)
+	}
+
+	cart, err := u.update(ctx, userID, func(items []domain.CartItem) ([]domain.CartItem, error) {
+		return addCartItems(items, guest.Items, false)
+	})
+	if err != nil {
+		if restoreErr := u.redisClient.SetJSON(context.WithoutCancel(ctx), key, guest, redis.GuestCartTTL); restoreErr != nil {
+			u.log.Error("Failed to restore guest cart after a failed merge", "user_id", userID.String(), "error", restoreErr)
+		}
+		return nil, err
+	}
+
+	u.log.Info("Guest cart merged", "user_id", userID.String())
+	return cart, nil
+}
+
+// update applies fn to the user's saved cart
+func (u *CartUsecase) update(ctx context.Context, userID uuid.UUID, fn func([]domain.CartItem) ([]domain.CartItem, error)) (*domain.Cart, error) {
+	items, err := u.cartRepo.Update(ctx, userID, fn)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Cart{UserID: userID, Items: items}, nil
+}
+
+// addCartItems adds lines to a cart, summing quantities of lines already in
+// it with the same item and options. The added line's displayed price wins,
+// being the one the customer saw last. With strict set, going over the cart
+// limits is an error; otherwise (merging a guest cart) quantities are
+// capped and extra lines dropped.
+func addCartItems(items, added []domain.CartItem, strict bool) ([]domain.CartItem, error) {
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[item.LineKey()] = i
+	}
+	for _, item := range added {
+		i, ok := index[item.LineKey()]
+		if !ok {
+			if len(items) >= maxCartLines {
+				if strict {
+					return nil, ErrInvalidCart
+				}
+				continue
+			}
+			index[item.LineKey()] = len(items)
+			items = append(items, item)
+			continue
+		}
+		quantity := items[i].Quantity + item.Quantity
+		if quantity > maxCartQuantity {
+			if strict {
+				return nil, ErrInvalidCart
+			}
+			quantity = maxCartQuantity
+		}
+		items[i].Quantity = quantity
+		if item.DisplayedPrice != nil {
+			items[i].DisplayedPrice = item.DisplayedPrice
+		}
+	}
+	return items, nil
+}
+
+// normalizeItems checks quantities, folds repeated lines into one and drops
+// nothing silently: unknown or unavailable items and options that don't fit
+// the item are an error
+func (u *CartUsecase) normalizeItems(ctx context.Context, items []domain.CartItem) ([]domain.CartItem, error) {
+	normalized := make([]domain.CartItem, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 || item.MenuItemID == uuid.Nil {
+			return nil, ErrInvalidCart
+		}
+		if i, ok := index[item.LineKey()]; ok {
+			normalized[i].Quantity += item.Quantity
+			if item.DisplayedPrice != nil {
+				normalized[i].DisplayedPrice = item.DisplayedPrice
+			}
+			continue
+		}
+		index[item.LineKey()] = len(normalized)
+		normalized = append(normalized, item)
+	}
+	if len(normalized) > maxCartLines {
+		return nil, ErrInvalidCart
+	}
+
+	ids := make([]uuid.UUID, 0, len(normalized))
+	seen := make(map[uuid.UUID]bool, len(normalized))
+	for _, item := range normalized {
+		if item.Quantity > maxCartQuantity {
+			return nil, ErrInvalidCart
+		}
+		if !seen[item.MenuItemID] {
+			seen[item.MenuItemID] = true
+			ids = append(ids, item.MenuItemID)
+		}
+	}
+	if len(ids) == 0 {
+		return normalized, nil
+	}
+
+	menuItems, err := u.menuRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch menu items: %w", err)
+	}
+	if len(menuItems) != len(ids) {
+		return nil, ErrItemNotAvailable
+	}
+	byID := make(map[uuid.UUID]*domain.MenuItem, len(menuItems))
+	for i := range menuItems {
+		if !menuItems[i].IsAvailable {
+			return nil, ErrItemNotAvailable
+		}
+		byID[menuItems[i].ID] = &menuItems[i]
+	}
+	for _, item := range normalized {
+		if _, err := orderLine(byID[item.MenuItemID], item); err != nil {
+			return nil, err
+		}
+	}
+
+	return normalized, nil
+}
+
+// guestCartKey keys a guest cart by the token's hash, so tokens can't be
+// read back out of Redis
+func guestCartKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return redis.GuestCartPrefix + hex.EncodeToString(sum[:])
+}
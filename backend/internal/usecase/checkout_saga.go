@@ -0,0 +1,228 @@
+// Package usecase implements order placement as a saga of undoable steps
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/logger"
+)
+
+// checkoutOrders is the order storage checkout writes to; satisfied by
+// *repository.OrderRepository
+type checkoutOrders interface {
+	Create(ctx context.Context, order *domain.Order) error
+	UpdateStatus(ctx context.Context, orderID uuid.UUID, newStatus domain.OrderStatus, expectedVersion int) error
+	SetRazorpayOrderID(ctx context.Context, orderID uuid.UUID, razorpayOrderID string, expectedVersion int) error
+}
+
+// checkoutStock takes a new order's items out of stock and gives them
+// back; satisfied by *InventoryUsecase
+type checkoutStock interface {
+	Reserve(ctx context.Context, order *domain.Order) ([]uuid.UUID, error)
+	Restore(ctx context.Context, order *domain.Order)
+	refreshMenu(ctx context.Context, changed []uuid.UUID)
+}
+
+// checkoutSlots books the kitchen and delivery slots of a new order
+type checkoutSlots interface {
+	// book reserves the order's slots, setting them on order. It leaves
+	// nothing booked when it fails.
+	book(ctx context.Context, order *domain.Order) error
+	// release gives the slots back; saved says whether the order holding
+	// them was inserted
+	release(ctx context.Context, order *domain.Order, saved bool)
+}
+
+// gatewayOrders creates the payment gateway's order for a new order and
+// returns its ID
+type gatewayOrders interface {
+	CreateOrder(ctx context.Context, order *domain.Order) (string, error)
+}
+
+// sagaStep is one step of a saga and the action that undoes it
+type sagaStep struct {
+	name       string
+	run        func(ctx context.Context) error
+	compensate func(ctx context.Context) error
+}
+
+// saga runs steps in order and remembers how to undo the completed ones.
+// A failing step must leave nothing behind itself.
+type saga struct {
+	log  *logger.Logger
+	done []sagaStep
+}
+
+// run runs a step, remembering its compensation if it succeeds
+func (s *saga) run(ctx context.Context, step sagaStep) error {
+	if err := step.run(ctx); err != nil {
+		return err
+	}
+	s.done = append(s.done, step)
+	return nil
+}
+
+// compensate undoes the completed steps, newest first. It runs even if ctx
+// was cancelled, and a failed compensation is logged so the rest still run.
+func (s *saga) compensate(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(s.done) - 1; i >= 0; i-- {
+		step := s.done[i]
+		if err := step.compensate(ctx); err != nil {
+			s.log.Error("Failed to compensate checkout step", "step", step.name, "error", err)
+		}
+	}
+	s.done = nil
+}
+
+// checkout places a priced order. Its slots are booked and the order is
+// inserted, then its stock is taken, then the gateway order is created and
+// stored on it. A failure undoes what the earlier steps did: stock is put
+// back, the order expires as PAYMENT_FAILED and its slots are released.
+type checkout struct {
+	orders  checkoutOrders
+	stock   checkoutStock // nil when stock isn't tracked
+	slots   checkoutSlots // nil when nothing is booked
+	gateway gatewayOrders
+	uow     database.UnitOfWork
+	record  func(ctx context.Context, event *domain.PaymentEvent)
+}
+
+// place runs the checkout for order, returning the gateway order ID. Stock
+// is taken after the insert because the reservation is recorded on the
+// order row.
+func (c *checkout) place(ctx context.Context, order *domain.Order, log *logger.Logger) (string, error) {
+	s := &saga{log: log}
+	saved := false
+
+	// Booking, insert and stock share the unit of work, so with one a
+	// failure here is undone by its rollback rather than by compensation
+	var soldOut []uuid.UUID
+	err := inTx(ctx, c.uow, func(ctx context.Context) error {
+		if c.slots != nil {
+			err := s.run(ctx, sagaStep{
+				name: "slots",
+				run:  func(ctx context.Context) error { return c.slots.book(ctx, order) },
+				compensate: func(ctx context.Context) error {
+					c.slots.release(ctx, order, saved)
+					return nil
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := s.run(ctx, sagaStep{
+			name: "order",
+			run: func(ctx context.Context) error {
+				if err := c.orders.Create(ctx, order); err != nil {
+					return fmt.Errorf("failed to create order: %w", err)
+				}
+				saved = true
+				return nil
+			},
+			compensate: func(ctx context.Context) error {
+				return c.orders.UpdateStatus(ctx, order.ID, domain.OrderStatusPaymentFailed, order.Version)
+			},
+		})
+		if err != nil || c.stock == nil {
+			return err
+		}
+
+		return s.run(ctx, sagaStep{
+			name: "stock",
+			run: func(ctx context.Context) error {
+				var err error
+				soldOut, err = c.stock.Reserve(ctx, order)
+				return err
+			},
+			compensate: func(ctx context.Context) error {
+				c.stock.Restore(ctx, order)
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		if c.uow == nil {
+			s.compensate(ctx)
+		}
+		return "", err
+	}
+	if c.stock != nil {
+		c.stock.refreshMenu(ctx, soldOut)
+	}
+
+	c.recordEvent(ctx, &domain.PaymentEvent{
+		OrderID:   &order.ID,
+		Source:    domain.PaymentSourceCheckout,
+		EventType: "order.created",
+		NewStatus: statusPtr(domain.OrderStatusPending),
+		Outcome:   domain.PaymentOutcomeApplied,
+		Amount:    &order.TotalAmount,
+	})
+
+	razorpayOrderID, err := c.gateway.CreateOrder(ctx, order)
+	if err != nil {
+		log.Error("Failed to create Razorpay order", "error", err)
+		s.compensate(ctx)
+		c.recordEvent(ctx, &domain.PaymentEvent{
+			OrderID:        &order.ID,
+			Source:         domain.PaymentSourceCheckout,
+			EventType:      "gateway_order.failed",
+			PreviousStatus: statusPtr(domain.OrderStatusPending),
+			NewStatus:      statusPtr(domain.OrderStatusPaymentFailed),
+			Outcome:        domain.PaymentOutcomeError,
+			Amount:         &order.TotalAmount,
+			Detail:         err.Error(),
+		})
+		return "", fmt.Errorf("failed to create payment order: %w", err)
+	}
+
+	if err := c.orders.SetRazorpayOrderID(ctx, order.ID, razorpayOrderID, order.Version); err != nil {
+		log.Error("Failed to update order with Razorpay ID", "error", err)
+		// Razorpay orders can't be cancelled; this one's ID is never handed
+		// out, so it lapses unpaid
+		s.compensate(ctx)
+		c.recordEvent(ctx, &domain.PaymentEvent{
+			OrderID:         &order.ID,
+			RazorpayOrderID: razorpayOrderID,
+			Source:          domain.PaymentSourceCheckout,
+			EventType:       "gateway_order.created",
+			PreviousStatus:  statusPtr(domain.OrderStatusPending),
+			NewStatus:       statusPtr(domain.OrderStatusPaymentFailed),
+			Outcome:         domain.PaymentOutcomeError,
+			Amount:          &order.TotalAmount,
+			Detail:          err.Error(),
+		})
+		return "", fmt.Errorf("failed to update order: %w", err)
+	}
+	order.RazorpayOrderID = razorpayOrderID
+	order.Status = domain.OrderStatusAwaitingPayment
+	order.Version++
+
+	c.recordEvent(ctx, &domain.PaymentEvent{
+		OrderID:         &order.ID,
+		RazorpayOrderID: razorpayOrderID,
+		Source:          domain.PaymentSourceCheckout,
+		EventType:       "gateway_order.created",
+		PreviousStatus:  statusPtr(domain.OrderStatusPending),
+		NewStatus:       statusPtr(domain.OrderStatusAwaitingPayment),
+		Outcome:         domain.PaymentOutcomeApplied,
+		Amount:          &order.TotalAmount,
+	})
+
+	return razorpayOrderID, nil
+}
+
+// recordEvent adds a checkout step to the order's payment history
+func (c *checkout) recordEvent(ctx context.Context, event *domain.PaymentEvent) {
+	if c.record != nil {
+		c.record(ctx, event)
+	}
+}
@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/logger"
+)
+
+// checkoutCalls records the steps and compensations a checkout ran, in order
+type checkoutCalls struct {
+	calls []string
+	fail  map[string]error // Step name -> error it fails with
+}
+
+func (c *checkoutCalls) call(name string) error {
+	c.calls = append(c.calls, name)
+	return c.fail[name]
+}
+
+type fakeCheckoutOrders struct{ *checkoutCalls }
+
+func (f fakeCheckoutOrders) Create(_ context.Context, order *domain.Order) error {
+	if err := f.call("create"); err != nil {
+		return err
+	}
+	order.ID = uuid.New()
+	order.Version = 1
+	return nil
+}
+
+func (f fakeCheckoutOrders) UpdateStatus(_ context.Context, _ uuid.UUID, status domain.OrderStatus, _ int) error {
+	return f.call("status:" + string(status))
+}
+
+func (f fakeCheckoutOrders) SetRazorpayOrderID(context.Context, uuid.UUID, string, int) error {
+	return f.call("attach")
+}
+
+type fakeCheckoutStock struct{ *checkoutCalls }
+
+func (f fakeCheckoutStock) Reserve(context.Context, *domain.Order) ([]uuid.UUID, error) {
+	return nil, f.call("reserve")
+}
+
+func (f fakeCheckoutStock) Restore(context.Context, *domain.Order) {
+	_ = f.call("restore")
+}
+
+func (f fakeCheckoutStock) refreshMenu(context.Context, []uuid.UUID) {}
+
+type fakeCheckoutSlots struct{ *checkoutCalls }
+
+func (f fakeCheckoutSlots) book(context.Context, *domain.Order) error {
+	return f.call("book")
+}
+
+func (f fakeCheckoutSlots) release(_ context.Context, _ *domain.Order, saved bool) {
+	if saved {
+		_ = f.call("release:order")
+	} else {
+		_ = f.call("release:booking")
+	}
+}
+
+type fakeGateway struct{ *checkoutCalls }
+
+func (f fakeGateway) CreateOrder(context.Context, *domain.Order) (string, error) {
+	if err := f.call("gateway"); err != nil {
+		return "", err
+	}
+	return "order_test", nil
+}
+
+// rollbackUnitOfWork runs fn directly; a real one would roll back on error
+type rollbackUnitOfWork struct{}
+
+func (rollbackUnitOfWork) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newTestCheckout(calls *checkoutCalls) *checkout {
+	return &checkout{
+		orders:  fakeCheckoutOrders{calls},
+		stock:   fakeCheckoutStock{calls},
+		slots:   fakeCheckoutSlots{calls},
+		gateway: fakeGateway{calls},
+	}
+}
+
+func TestCheckoutCompensatesEachFailurePoint(t *testing.T) {
+	errStep := errors.New("step failed")
+	expired := "status:" + string(domain.OrderStatusPaymentFailed)
+
+	tests := []struct {
+		name  string
+		fails string
+		want  []string
+	}{
+		{"slots full", "book", []string{"book"}},
+		{"insert fails", "create", []string{"book", "create", "release:booking"}},
+		{"sold out", "reserve", []string{"book", "create", "reserve", expired, "release:order"}},
+		{"gateway down", "gateway", []string{"book", "create", "reserve", "gateway", "restore", expired, "release:order"}},
+		{"gateway ID not stored", "attach", []string{"book", "create", "reserve", "gateway", "attach", "restore", expired, "release:order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &checkoutCalls{fail: map[string]error{tt.fails: errStep}}
+			order := &domain.Order{TotalAmount: 25000}
+
+			_, err := newTestCheckout(calls).place(context.Background(), order, logger.NewLogger())
+			if !errors.Is(err, errStep) {
+				t.Fatalf("err = %v, want %v", err, errStep)
+			}
+			if !reflect.DeepEqual(calls.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckoutKeepsCompensationGoingAfterOneFails(t *testing.T) {
+	calls := &checkoutCalls{fail: map[string]error{
+		"gateway": errors.New("gateway down"),
+		"status:" + string(domain.OrderStatusPaymentFailed): errors.New("order changed"),
+	}}
+
+	_, err := newTestCheckout(calls).place(context.Background(), &domain.Order{}, logger.NewLogger())
+	if err == nil {
+		t.Fatal("place succeeded with the gateway down")
+	}
+	if last := calls.calls[len(calls.calls)-1]; last != "release:order" {
+		t.Errorf("calls = %v, want the slots released after the failed expiry", calls.calls)
+	}
+}
+
+func TestCheckoutLeavesRollbackToUnitOfWork(t *testing.T) {
+	calls := &checkoutCalls{fail: map[string]error{"reserve": ErrItemSoldOut}}
+	c := newTestCheckout(calls)
+	c.uow = rollbackUnitOfWork{}
+
+	_, err := c.place(context.Background(), &domain.Order{}, logger.NewLogger())
+	if !errors.Is(err, ErrItemSoldOut) {
+		t.Fatalf("err = %v, want ErrItemSoldOut", err)
+	}
+	if want := []string{"book", "create", "reserve"}; !reflect.DeepEqual(calls.calls, want) {
+		t.Errorf("calls = %v, want %v with nothing compensated", calls.calls, want)
+	}
+}
+
+func TestCheckoutPlacesOrder(t *testing.T) {
+	calls := &checkoutCalls{}
+	var events []string
+	c := newTestCheckout(calls)
+	c.record = func(_ context.Context, event *domain.PaymentEvent) {
+		events = append(events, event.EventType)
+	}
+	order := &domain.Order{Status: domain.OrderStatusPending}
+
+	id, err := c.place(context.Background(), order, logger.NewLogger())
+	if err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	if id != "order_test" || order.RazorpayOrderID != id {
+		t.Errorf("gateway order = %q on the order, %q returned", order.RazorpayOrderID, id)
+	}
+	if order.Status != domain.OrderStatusAwaitingPayment || order.Version != 2 {
+		t.Errorf("order is %s at version %d, want %s at 2", order.Status, order.Version, domain.OrderStatusAwaitingPayment)
+	}
+	if want := []string{"book", "create", "reserve", "gateway", "attach"}; !reflect.DeepEqual(calls.calls, want) {
+		t.Errorf("calls = %v, want %v", calls.calls, want)
+	}
+	if want := []string{"order.created", "gateway_order.created"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,182 @@
+// Package usecase implements periodic cleanup of expired auth data, old
+// order chats and published order events
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/redis"
+)
+
+// cleanupBatchSize bounds each DELETE so a large backlog never holds locks
+// for long
+const cleanupBatchSize = 5000
+
+// cleanupReclaimed counts rows removed by each cleanup pass, by kind
+var cleanupReclaimed = metrics.NewCounterVec(
+	"cleanup_reclaimed_total",
+	"Expired records deleted by the cleanup job",
+	"kind",
+)
+
+// CleanupConfig sets how long expired records are kept
+type CleanupConfig struct {
+	SessionRetention time.Duration // After expiry, for login history and support
+	OTPRetention     time.Duration // After expiry
+	ChatRetention    time.Duration // After the message was sent
+	EventRetention   time.Duration // After the order event was published
+}
+
+// CleanupResult is what one pass removed
+type CleanupResult struct {
+	Sessions int64
+	OTPs     int64
+	Messages int64
+	Events   int64
+}
+
+// CleanupUsecase purges expired sessions and OTPs, and order chat messages
+// and published order events past their retention, on a schedule
+type CleanupUsecase struct {
+	userRepo    *repository.UserRepository
+	messageRepo *repository.OrderMessageRepository
+	eventRepo   *repository.OrderEventRepository
+	redisClient *redis.Client
+	cfg         CleanupConfig
+	log         *logger.Logger
+}
+
+// NewCleanupUsecase creates a new cleanup usecase
+func NewCleanupUsecase(userRepo *repository.UserRepository, redisClient *redis.Client, cfg CleanupConfig, log *logger.Logger) *CleanupUsecase {
+	return &CleanupUsecase{
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		cfg:         cfg,
+		log:         log,
+	}
+}
+
+// SetOrderMessages deletes order chat messages older than ChatRetention
+func (u *CleanupUsecase) SetOrderMessages(messageRepo *repository.OrderMessageRepository) {
+	u.messageRepo = messageRepo
+}
+
+// SetOrderEvents deletes order events published more than EventRetention
+// ago
+func (u *CleanupUsecase) SetOrderEvents(eventRepo *repository.OrderEventRepository) {
+	u.eventRepo = eventRepo
+}
+
+// Run cleans up every interval until ctx is cancelled. Only one instance
+// runs a pass at a time.
+func (u *CleanupUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.runLocked(ctx)
+		}
+	}
+}
+
+// runLocked runs one pass unless another instance is already running one
+func (u *CleanupUsecase) runLocked(ctx context.Context) {
+	if u.redisClient != nil {
+		lock, err := u.redisClient.TryLock(ctx, redis.CleanupLockKey, redis.CleanupLockTTL)
+		if err != nil {
+			u.log.Warn("Failed to take cleanup lock", "error", err)
+			return
+		}
+		if lock == nil {
+			return
+		}
+		defer func() {
+			if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+				u.log.Warn("Failed to release cleanup lock", "error", err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	result, err := u.Cleanup(ctx)
+	if err != nil {
+		u.log.Error("Cleanup failed", "error", err, "sessions", result.Sessions, "otps", result.OTPs,
+			"messages", result.Messages, "events", result.Events)
+		return
+	}
+	if result.Sessions > 0 || result.OTPs > 0 || result.Messages > 0 || result.Events > 0 {
+		u.log.Info("Cleanup finished",
+			"sessions", result.Sessions,
+			"otps", result.OTPs,
+			"messages", result.Messages,
+			"events", result.Events,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// Cleanup deletes sessions, OTPs, chat messages and published order events
+// past their retention, in batches.
+// On error the result holds what was deleted before it.
+func (u *CleanupUsecase) Cleanup(ctx context.Context) (CleanupResult, error) {
+	var result CleanupResult
+	now := time.Now()
+
+	n, err := drain(ctx, "sessions", func(ctx context.Context) (int64, error) {
+		return u.userRepo.DeleteExpiredSessions(ctx, now.Add(-u.cfg.SessionRetention), cleanupBatchSize)
+	})
+	result.Sessions = n
+	if err != nil {
+		return result, err
+	}
+
+	result.OTPs, err = drain(ctx, "otps", func(ctx context.Context) (int64, error) {
+		return u.userRepo.DeleteExpiredOTPs(ctx, now.Add(-u.cfg.OTPRetention), cleanupBatchSize)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if u.messageRepo != nil && u.cfg.ChatRetention > 0 {
+		result.Messages, err = drain(ctx, "order_messages", func(ctx context.Context) (int64, error) {
+			return u.messageRepo.DeleteOlderThan(ctx, now.Add(-u.cfg.ChatRetention), cleanupBatchSize)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if u.eventRepo != nil && u.cfg.EventRetention > 0 {
+		result.Events, err = drain(ctx, "order_events", func(ctx context.Context) (int64, error) {
+			return u.eventRepo.DeletePublishedBefore(ctx, now.Add(-u.cfg.EventRetention), cleanupBatchSize)
+		})
+	}
+	return result, err
+}
+
+// drain repeats a batched delete until a batch comes back short, counting
+// what it removes
+func drain(ctx context.Context, kind string, deleteBatch func(context.Context) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := deleteBatch(ctx)
+		if n > 0 {
+			total += n
+			cleanupReclaimed.Add(float64(n), kind)
+		}
+		if err != nil || n < cleanupBatchSize {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
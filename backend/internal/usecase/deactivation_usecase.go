@@ -0,0 +1,226 @@
+// Package usecase implements soft account deactivation and reactivation
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// Deactivation errors
+var (
+	ErrAccountDeactivated   = errors.New("account is deactivated")
+	ErrReactivationRequired = errors.New("account is deactivated, verify the OTP sent to reactivate it")
+	ErrDeactivationReason   = errors.New("a reason is required to deactivate an account")
+)
+
+// defaultReactivationWindow is how long a user can undo their own
+// deactivation by logging in again
+const defaultReactivationWindow = 30 * 24 * time.Hour
+
+// SetReactivationWindow sets how long after deactivating their own account
+// a user can reactivate it by logging in. Later logins are refused.
+func (u *UserUsecase) SetReactivationWindow(window time.Duration) {
+	u.reactivationWindow = window
+}
+
+// SetAuditLog records admin deactivations in the admin audit log
+func (u *UserUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// DeactivateAccount deactivates the caller's own account. Their data is
+// kept and sessions are revoked; logging in again within the reactivation
+// window starts the reactivation flow.
+func (u *UserUsecase) DeactivateAccount(ctx context.Context, userID uuid.UUID, reason string) error {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsDeactivated() {
+		return nil
+	}
+
+	if err := u.userRepo.Deactivate(ctx, userID, nil, strings.TrimSpace(reason), time.Now()); err != nil {
+		return fmt.Errorf("failed to deactivate account: %w", err)
+	}
+	u.users.Invalidate(ctx, userID)
+
+	u.log.Info("Account deactivated by user", "user_id", userID.String())
+
+	return nil
+}
+
+// AdminDeactivateUser deactivates an account with a reason. The user can't
+// reactivate it themselves; an admin has to.
+func (u *UserUsecase) AdminDeactivateUser(ctx context.Context, adminID, userID uuid.UUID, reason string) (*domain.User, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrDeactivationReason
+	}
+
+	if _, err := u.getUserOrNotFound(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Also replaces a self-deactivation, so the user can no longer undo it
+	if err := u.userRepo.Deactivate(ctx, userID, &adminID, reason, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to deactivate account: %w", err)
+	}
+	u.users.Invalidate(ctx, userID)
+
+	u.audit.Record(ctx, adminID, domain.AuditUserDeactivated, "user", userID.String(), map[string]interface{}{
+		"reason": reason,
+	})
+	u.log.Info("Account deactivated by admin", "user_id", userID.String(), "admin_id", adminID.String())
+
+	return u.getUserOrNotFound(ctx, userID)
+}
+
+// AdminReactivateUser reactivates any deactivated account
+func (u *UserUsecase) AdminReactivateUser(ctx context.Context, adminID, userID uuid.UUID) (*domain.User, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsDeactivated() {
+		return user, nil
+	}
+
+	if err := u.userRepo.Reactivate(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to reactivate account: %w", err)
+	}
+	u.users.Invalidate(ctx, userID)
+
+	u.audit.Record(ctx, adminID, domain.AuditUserReactivated, "user", userID.String(), nil)
+	u.log.Info("Account reactivated by admin", "user_id", userID.String(), "admin_id", adminID.String())
+
+	return u.getUserOrNotFound(ctx, userID)
+}
+
+// ReactivateRequest carries the OTP sent when a deactivated user logged in
+type ReactivateRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	OTP         string `json:"otp"`
+}
+
+// Reactivate verifies a reactivation OTP, reactivates the account and logs
+// the user in
+func (u *UserUsecase) Reactivate(ctx context.Context, req ReactivateRequest) (*LoginResponse, error) {
+	otp, err := u.userRepo.GetValidOTP(ctx, req.PhoneNumber, domain.OTPPurposeReactivate)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidOTP
+		}
+		return nil, fmt.Errorf("failed to get OTP: %w", err)
+	}
+
+	if otp.OTPCode != req.OTP {
+		if err := u.userRepo.IncrementOTPAttempts(ctx, otp.ID); err != nil {
+			u.log.Error("Failed to increment OTP attempts", "error", err)
+		}
+		return nil, ErrInvalidOTP
+	}
+
+	if err := u.userRepo.MarkOTPVerified(ctx, otp.ID); err != nil {
+		u.log.Error("Failed to mark OTP as verified", "error", err)
+	}
+
+	user, err := u.userRepo.GetByPhoneNumber(ctx, req.PhoneNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// An admin may have deactivated the account since the OTP was sent
+	if user.IsDeactivated() {
+		if !u.canSelfReactivate(user) {
+			return nil, ErrAccountDeactivated
+		}
+		if err := u.userRepo.Reactivate(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to reactivate account: %w", err)
+		}
+		u.users.Invalidate(ctx, user.ID)
+		user.DeactivatedAt, user.DeactivatedBy, user.DeactivationReason = nil, nil, ""
+
+		u.log.Info("Account reactivated by user", "user_id", user.ID.String())
+	}
+
+	tokens, err := u.issueSession(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		PhoneNumber:   user.PhoneNumber,
+		MFARequired:   u.requiresMFA(user),
+	}, nil
+}
+
+// checkActive stops deactivated accounts from logging in. Call it once the
+// login's credentials have been verified. A user within the window after
+// deactivating their own account is sent a reactivation OTP and gets
+// ErrReactivationRequired.
+func (u *UserUsecase) checkActive(ctx context.Context, user *domain.User) error {
+	if !user.IsDeactivated() {
+		return nil
+	}
+	if !u.canSelfReactivate(user) {
+		return ErrAccountDeactivated
+	}
+	if err := u.sendReactivationOTP(ctx, user); err != nil {
+		return err
+	}
+	return ErrReactivationRequired
+}
+
+// canSelfReactivate reports whether the user deactivated the account
+// themselves recently enough to undo it
+func (u *UserUsecase) canSelfReactivate(user *domain.User) bool {
+	return user.DeactivatedBy == nil && time.Since(*user.DeactivatedAt) < u.reactivationWindow
+}
+
+// sendReactivationOTP stores a reactivation OTP for the user's phone
+func (u *UserUsecase) sendReactivationOTP(ctx context.Context, user *domain.User) error {
+	if err := u.allowOTPRequest(ctx, user.PhoneNumber); err != nil {
+		return err
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return fmt.Errorf("failed to generate OTP: %w", err)
+	}
+
+	now := time.Now()
+	otp := &domain.OTP{
+		UserID:      &user.ID,
+		PhoneNumber: &user.PhoneNumber,
+		OTPCode:     code,
+		Purpose:     domain.OTPPurposeReactivate,
+		ExpiresAt:   now.Add(otpValidity),
+		CreatedAt:   now,
+	}
+	if err := u.userRepo.CreateOTP(ctx, otp); err != nil {
+		return fmt.Errorf("failed to store OTP: %w", err)
+	}
+
+	if err := u.deliverOTP(ctx, user.PhoneNumber, code, user.PreferredLocale()); err != nil {
+		return err
+	}
+	u.log.Info("Reactivation OTP sent", "user_id", user.ID.String())
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package usecase implements distance-based delivery fees
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/routing"
+)
+
+// ErrOutOfDeliveryRange is returned when the drop-off is too far by road
+// (or unreachable) from the kitchen
+var ErrOutOfDeliveryRange = errors.New("delivery address is outside the delivery range")
+
+// DeliveryFeeConfig prices a delivery by road distance from the kitchen.
+// Fees are in paisa; every started kilometre past BaseDistanceMeters adds PerKmFee.
+type DeliveryFeeConfig struct {
+	Origin             geo.Point
+	BaseFee            int64
+	BaseDistanceMeters int
+	PerKmFee           int64
+	MaxDistanceMeters  int
+}
+
+// DeliveryQuote is the fee charged for a delivery and the distance behind it
+type DeliveryQuote struct {
+	DistanceMeters int    `json:"distance_meters"`
+	Fee            int64  `json:"fee"` // Amount in paisa
+	Source         string `json:"source"`
+}
+
+// DeliveryFeeUsecase computes delivery fees from road distance
+type DeliveryFeeUsecase struct {
+	provider    routing.Provider
+	redisClient *redis.Client
+	cfg         DeliveryFeeConfig
+	settings    *SettingsUsecase
+	log         *logger.Logger
+}
+
+// NewDeliveryFeeUsecase creates a new delivery fee usecase
+func NewDeliveryFeeUsecase(provider routing.Provider, redisClient *redis.Client, cfg DeliveryFeeConfig, log *logger.Logger) *DeliveryFeeUsecase {
+	return &DeliveryFeeUsecase{
+		provider:    provider,
+		redisClient: redisClient,
+		cfg:         cfg,
+		log:         log,
+	}
+}
+
+// SetSettings lets admins change the tariff at runtime; without it the
+// configured tariff applies
+func (u *DeliveryFeeUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// Quote returns the delivery fee for a drop-off location
+func (u *DeliveryFeeUsecase) Quote(ctx context.Context, loc *domain.DeliveryLocation) (*DeliveryQuote, error) {
+	tariff := u.tariff(ctx)
+
+	route, err := u.route(ctx, geo.Point{Latitude: loc.Latitude, Longitude: loc.Longitude})
+	if err != nil {
+		if errors.Is(err, routing.ErrNoRoute) {
+			return nil, ErrOutOfDeliveryRange
+		}
+		return nil, err
+	}
+
+	if tariff.MaxDistanceMeters > 0 && route.DistanceMeters > tariff.MaxDistanceMeters {
+		return nil, ErrOutOfDeliveryRange
+	}
+
+	return &DeliveryQuote{
+		DistanceMeters: route.DistanceMeters,
+		Fee:            tariff.fee(route.DistanceMeters),
+		Source:         route.Source,
+	}, nil
+}
+
+// tariff returns the config with any admin overrides applied
+func (u *DeliveryFeeUsecase) tariff(ctx context.Context) DeliveryFeeConfig {
+	cfg := u.cfg
+	if u.settings != nil {
+		cfg.BaseFee = u.settings.Int64(ctx, SettingDeliveryBaseFee)
+		cfg.BaseDistanceMeters = u.settings.Int(ctx, SettingDeliveryBaseDistance)
+		cfg.PerKmFee = u.settings.Int64(ctx, SettingDeliveryPerKmFee)
+		cfg.MaxDistanceMeters = u.settings.Int(ctx, SettingDeliveryMaxDistance)
+	}
+	return cfg
+}
+
+// fee applies the tariff to a road distance
+func (cfg DeliveryFeeConfig) fee(distanceMeters int) int64 {
+	fee := cfg.BaseFee
+	if extra := distanceMeters - cfg.BaseDistanceMeters; extra > 0 {
+		startedKm := int64((extra + 999) / 1000)
+		fee += startedKm * cfg.PerKmFee
+	}
+	return fee
+}
+
+// route resolves the kitchen-to-destination route, cached per coordinate pair.
+// Coordinates are rounded to ~1 m so the same address always hits the cache.
+func (u *DeliveryFeeUsecase) route(ctx context.Context, dest geo.Point) (*routing.Route, error) {
+	key := fmt.Sprintf("%s%.5f,%.5f:%.5f,%.5f", redis.DistanceCachePrefix,
+		u.cfg.Origin.Latitude, u.cfg.Origin.Longitude, dest.Latitude, dest.Longitude)
+
+	if u.redisClient != nil {
+		var cached routing.Route
+		found, err := u.redisClient.GetJSON(ctx, key, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read distance cache", "error", err)
+		} else if found {
+			return &cached, nil
+		}
+	}
+
+	route, err := u.provider.Route(ctx, u.cfg.Origin, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Straight-line estimates are only cached briefly so the real road
+	// distance replaces them once the routing service is back
+	if u.redisClient != nil {
+		ttl := redis.DistanceCacheTTL
+		if route.Source == routing.SourceHaversine {
+			ttl = redis.DistanceEstimateTTL
+		}
+		if err := u.redisClient.SetJSON(ctx, key, route, ttl); err != nil {
+			u.log.Warn("Failed to cache distance", "error", err)
+		}
+	}
+
+	return route, nil
+}
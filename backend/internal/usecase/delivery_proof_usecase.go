@@ -0,0 +1,119 @@
+// Package usecase implements photo proof of delivery
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+)
+
+// Proof of delivery errors
+var (
+	ErrStorageNotConfigured = errors.New("storage service is not configured")
+	ErrInvalidProofPhoto    = errors.New("proof photo must be a JPEG, PNG or WebP image")
+	ErrProofPhotoTooLarge   = errors.New("proof photo is too large")
+	ErrProofNotAllowed      = errors.New("proof can only be uploaded while delivering or just after")
+)
+
+// MaxProofPhotoBytes bounds doorstep photos; the rider app downsizes before upload
+const MaxProofPhotoBytes = 3 << 20
+
+// proofPhotoTypes maps accepted sniffed content types to file extensions
+var proofPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// UploadDeliveryProof stores the rider's doorstep photo and attaches it to
+// the order. The content type is sniffed rather than trusted from the client.
+func (u *OrderUsecase) UploadDeliveryProof(ctx context.Context, orderID, riderID uuid.UUID, photo []byte) (*domain.DeliveryProof, error) {
+	if u.storage == nil {
+		return nil, ErrStorageNotConfigured
+	}
+	if len(photo) == 0 {
+		return nil, ErrInvalidProofPhoto
+	}
+	if len(photo) > MaxProofPhotoBytes {
+		return nil, ErrProofPhotoTooLarge
+	}
+
+	contentType := http.DetectContentType(photo)
+	ext, ok := proofPhotoTypes[contentType]
+	if !ok {
+		return nil, ErrInvalidProofPhoto
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RiderID == nil || *order.RiderID != riderID {
+		return nil, ErrNotAssignedRider
+	}
+	// Riders often snap the photo right after entering the OTP
+	if order.Status != domain.OrderStatusOutForDelivery && order.Status != domain.OrderStatusDelivered {
+		return nil, ErrProofNotAllowed
+	}
+
+	key := fmt.Sprintf("delivery-proofs/%s/%s%s", order.ID, uuid.New(), ext)
+	if err := u.storage.Put(ctx, key, bytes.NewReader(photo)); err != nil {
+		return nil, fmt.Errorf("failed to store proof photo: %w", err)
+	}
+
+	proof := &domain.DeliveryProof{
+		OrderID:     order.ID,
+		RiderID:     &riderID,
+		StorageKey:  key,
+		ContentType: contentType,
+		SizeBytes:   len(photo),
+	}
+
+	previousKey, err := u.orderRepo.SaveDeliveryProof(ctx, proof)
+	if err != nil {
+		// Don't leave an orphaned object behind
+		_ = u.storage.Delete(ctx, key)
+		return nil, err
+	}
+
+	if previousKey != "" {
+		if err := u.storage.Delete(ctx, previousKey); err != nil {
+			u.log.Warn("Failed to delete replaced proof photo", "key", previousKey, "error", err)
+		}
+	}
+
+	u.log.Info("Delivery proof uploaded",
+		"order_id", order.ID.String(),
+		"rider_id", riderID.String(),
+		"size_bytes", len(photo),
+	)
+
+	return proof, nil
+}
+
+// OpenDeliveryProof returns the proof photo for an order. Access control is
+// the caller's job (customer owning the order, or support/admin).
+func (u *OrderUsecase) OpenDeliveryProof(ctx context.Context, orderID uuid.UUID) (io.ReadCloser, *domain.DeliveryProof, error) {
+	if u.storage == nil {
+		return nil, nil, ErrStorageNotConfigured
+	}
+
+	proof, err := u.orderRepo.GetDeliveryProof(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := u.storage.Open(ctx, proof.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open proof photo: %w", err)
+	}
+
+	return body, proof, nil
+}
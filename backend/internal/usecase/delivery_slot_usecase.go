@@ -0,0 +1,275 @@
+// Package usecase implements capacity-limited delivery slots for scheduled
+// orders
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// DeliverySlotDuration is the length of a delivery window customers pick
+const DeliverySlotDuration = 30 * time.Minute
+
+// deliverySlotSweepBatch bounds the unpaid holds released per sweep
+const deliverySlotSweepBatch = 200
+
+// Delivery slot errors
+var (
+	ErrDeliverySlotFull    = errors.New("delivery slot is fully booked")
+	ErrInvalidDeliverySlot = errors.New("delivery slot is closed, too soon to prepare for or too far ahead")
+	ErrDeliverySlotPickup  = errors.New("delivery slots apply to delivery orders only")
+	ErrInvalidDeliveryDate = errors.New("date must be today or within the next 7 days")
+)
+
+// DeliverySlotFullError carries the next slots that can still take an
+// order, so the client can offer them instead
+type DeliverySlotFullError struct {
+	Requested     time.Time
+	NextAvailable []time.Time
+}
+
+// Error implements error
+func (e *DeliverySlotFullError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDeliverySlotFull, e.Requested.Format(time.RFC3339))
+}
+
+// Unwrap lets errors.Is match ErrDeliverySlotFull
+func (e *DeliverySlotFullError) Unwrap() error {
+	return ErrDeliverySlotFull
+}
+
+// DeliverySlot describes one delivery window
+type DeliverySlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Capacity  int       `json:"capacity"` // -1 = unlimited
+	Booked    int       `json:"booked"`
+	Available bool      `json:"available"`
+}
+
+// DeliverySlotUsecase books orders into delivery windows. Bookings are
+// held while an order awaits payment and given back on cancellation or
+// when the order is still unpaid after the hold period.
+type DeliverySlotUsecase struct {
+	repo            *repository.DeliverySlotRepository
+	defaultCapacity int // Orders per slot; 0 = unlimited
+	holdFor         time.Duration
+	settings        *SettingsUsecase
+	eta             *ETAUsecase
+	location        *time.Location
+	log             *logger.Logger
+}
+
+// NewDeliverySlotUsecase creates a new delivery slot usecase. Days and
+// opening hours are in the kitchen's time zone.
+func NewDeliverySlotUsecase(repo *repository.DeliverySlotRepository, defaultCapacity int, holdFor time.Duration, location *time.Location, log *logger.Logger) *DeliverySlotUsecase {
+	if location == nil {
+		location = time.Local
+	}
+	return &DeliverySlotUsecase{
+		repo:            repo,
+		defaultCapacity: defaultCapacity,
+		holdFor:         holdFor,
+		location:        location,
+		log:             log,
+	}
+}
+
+// SetSettings lets admins change slot capacity and opening hours at runtime
+func (u *DeliverySlotUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// SetETAUsecase hides slots that start before an order placed now could
+// arrive
+func (u *DeliverySlotUsecase) SetETAUsecase(eta *ETAUsecase) {
+	u.eta = eta
+}
+
+// DeliverySlotStart truncates t to the start of its delivery slot
+func DeliverySlotStart(t time.Time) time.Time {
+	return t.Truncate(DeliverySlotDuration)
+}
+
+// Book reserves the slot containing requested for a new order. earliest is
+// when the order could arrive if prepared now; slots ending before then are
+// rejected. When the slot is full the error lists the next open ones.
+func (u *DeliverySlotUsecase) Book(ctx context.Context, requested, earliest time.Time) (time.Time, error) {
+	slot := DeliverySlotStart(requested)
+	hours := u.openHours(ctx)
+	if !u.bookable(hours, slot, earliest) {
+		return time.Time{}, ErrInvalidDeliverySlot
+	}
+
+	capacity := u.slotCapacity(ctx)
+	limit := capacity
+	if limit < 0 {
+		// Still counted, so a capacity set later sees existing bookings
+		limit = math.MaxInt32
+	}
+
+	ok, err := u.repo.BookSlot(ctx, slot, limit)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !ok {
+		from := slot.Add(DeliverySlotDuration)
+		slots, err := u.list(ctx, from, from.Add(24*time.Hour), earliest)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var next []time.Time
+		for _, s := range slots {
+			if s.Available && len(next) < nextAvailableSuggestion {
+				next = append(next, s.Start)
+			}
+		}
+		return time.Time{}, &DeliverySlotFullError{Requested: slot, NextAvailable: next}
+	}
+
+	return slot, nil
+}
+
+// Release gives back an order's delivery slot, if it still holds one
+func (u *DeliverySlotUsecase) Release(ctx context.Context, orderID uuid.UUID) {
+	if _, err := u.repo.ReleaseOrderSlot(ctx, orderID); err != nil {
+		u.log.Warn("Failed to release delivery slot", "order_id", orderID.String(), "error", err)
+	}
+}
+
+// ReleaseBooking gives back a booking whose order was never saved
+func (u *DeliverySlotUsecase) ReleaseBooking(ctx context.Context, slot time.Time) {
+	if err := u.repo.ReleaseSlot(ctx, slot); err != nil {
+		u.log.Warn("Failed to release delivery slot", "slot", slot, "error", err)
+	}
+}
+
+// GetAvailability lists the delivery slots of a day (any time on it, in the
+// kitchen's time zone) that fall within opening hours
+func (u *DeliverySlotUsecase) GetAvailability(ctx context.Context, day time.Time) ([]DeliverySlot, error) {
+	local := day.In(u.location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, u.location)
+	end := start.AddDate(0, 0, 1)
+
+	now := time.Now()
+	if !end.After(now) || start.After(now.Add(maxScheduleAhead)) {
+		return nil, ErrInvalidDeliveryDate
+	}
+
+	return u.list(ctx, start, end, u.earliestArrival(ctx, now))
+}
+
+// RunExpiry releases the slots of orders left unpaid past the hold period,
+// every interval until ctx is cancelled
+func (u *DeliverySlotUsecase) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.expireHolds(ctx)
+		}
+	}
+}
+
+// expireHolds releases one batch of expired holds
+func (u *DeliverySlotUsecase) expireHolds(ctx context.Context) {
+	released, err := u.repo.ReleaseExpiredHolds(ctx, time.Now().Add(-u.holdFor), deliverySlotSweepBatch)
+	if err != nil {
+		u.log.Error("Failed to release expired delivery slot holds", "error", err)
+		return
+	}
+	if released > 0 {
+		u.log.Info("Released delivery slots of unpaid orders", "count", released)
+	}
+}
+
+// list describes the open-hours slots starting in [from, to)
+func (u *DeliverySlotUsecase) list(ctx context.Context, from, to, earliest time.Time) ([]DeliverySlot, error) {
+	from = DeliverySlotStart(from)
+	bookings, err := u.repo.GetBookings(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := u.openHours(ctx)
+	capacity := u.slotCapacity(ctx)
+	slots := []DeliverySlot{}
+	for slot := from; slot.Before(to); slot = slot.Add(DeliverySlotDuration) {
+		if hours != nil {
+			if _, open := hours.OpenFor(slot.In(u.location)); !open {
+				continue
+			}
+		}
+		booked := bookings[slot.UTC()]
+		slots = append(slots, DeliverySlot{
+			Start:     slot.In(u.location),
+			End:       slot.Add(DeliverySlotDuration).In(u.location),
+			Capacity:  capacity,
+			Booked:    booked,
+			Available: u.bookable(hours, slot, earliest) && (capacity < 0 || booked < capacity),
+		})
+	}
+
+	return slots, nil
+}
+
+// bookable reports whether an order could be delivered in slot: it ends
+// after the earliest possible arrival, is within the scheduling horizon and
+// starts during opening hours
+func (u *DeliverySlotUsecase) bookable(hours *OpenHours, slot, earliest time.Time) bool {
+	if !slot.Add(DeliverySlotDuration).After(earliest) || slot.After(time.Now().Add(maxScheduleAhead)) {
+		return false
+	}
+	if hours != nil {
+		if _, open := hours.OpenFor(slot.In(u.location)); !open {
+			return false
+		}
+	}
+	return true
+}
+
+// earliestArrival is when a delivery ordered at now could arrive at the
+// soonest, before travel time
+func (u *DeliverySlotUsecase) earliestArrival(ctx context.Context, now time.Time) time.Time {
+	if u.eta == nil {
+		return now
+	}
+	return u.eta.Estimate(ctx, domain.FulfillmentDelivery, nil, nil, now).EstimatedAt
+}
+
+// openHours returns the kitchen's opening hours, nil when unrestricted
+func (u *DeliverySlotUsecase) openHours(ctx context.Context) *OpenHours {
+	if u.settings == nil {
+		return nil
+	}
+	hours, err := ParseOpenHours(u.settings.String(ctx, SettingKitchenOpenHours))
+	if err != nil {
+		return nil
+	}
+	return hours
+}
+
+// slotCapacity returns the orders allowed per slot, or -1 for unlimited
+func (u *DeliverySlotUsecase) slotCapacity(ctx context.Context) int {
+	capacity := u.defaultCapacity
+	if u.settings != nil {
+		capacity = u.settings.Int(ctx, SettingDeliverySlotCapacity)
+	}
+	if capacity <= 0 {
+		return -1
+	}
+	return capacity
+}
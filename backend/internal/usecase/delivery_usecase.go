@@ -0,0 +1,207 @@
+// Package usecase implements rider dispatch and OTP-confirmed delivery hand-over
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// Delivery errors
+var (
+	ErrDispatchRequired    = errors.New("use dispatch to send an order out for delivery")
+	ErrDeliveryOTPRequired = errors.New("delivery orders can only be completed with the customer's OTP")
+	ErrNotDeliveryOrder    = errors.New("order is not a delivery order")
+	ErrNotRider            = errors.New("user is not a rider")
+	ErrNotOutForDelivery   = errors.New("order is not out for delivery")
+	ErrNotAssignedRider    = errors.New("order is assigned to another rider")
+	ErrInvalidDeliveryOTP  = errors.New("invalid delivery OTP")
+	ErrDeliveryOTPLocked   = errors.New("delivery OTP locked after too many attempts, contact support")
+	ErrOverrideReason      = errors.New("a reason is required to override delivery confirmation")
+)
+
+// maxDeliveryOTPAttempts locks the OTP so a rider can't brute-force it at the door
+const maxDeliveryOTPAttempts = 5
+
+// DispatchOrder assigns a rider to an accepted delivery order and generates
+// the hand-over OTP the customer will read out
+func (u *OrderUsecase) DispatchOrder(ctx context.Context, orderID, riderID uuid.UUID) (*domain.Order, error) {
+	rider, err := u.users.Get(ctx, riderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotRider
+		}
+		return nil, fmt.Errorf("failed to fetch rider: %w", err)
+	}
+	if !rider.IsRider {
+		return nil, ErrNotRider
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.FulfillmentType != domain.FulfillmentDelivery {
+		return nil, ErrNotDeliveryOrder
+	}
+	if !isValidStatusTransition(order.Status, domain.OrderStatusOutForDelivery) {
+		return nil, fmt.Errorf("invalid status transition from %s to %s", order.Status, domain.OrderStatusOutForDelivery)
+	}
+
+	otp, err := generateOTP()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delivery OTP: %w", err)
+	}
+
+	if err := u.orderRepo.Dispatch(ctx, order.ID, rider.ID, otp, order.Version); err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Order dispatched",
+		"order_id", order.ID.String(),
+		"rider_id", rider.ID.String(),
+		"gift", order.IsGift(),
+	)
+
+	dispatched, err := u.orderRepo.GetByID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	u.notifications.OutForDelivery(dispatched, rider)
+	return dispatched, nil
+}
+
+// DeliveryOTPResponse is shown to the customer while the rider is en route
+type DeliveryOTPResponse struct {
+	OrderID uuid.UUID `json:"order_id"`
+	OTP     string    `json:"otp"`
+}
+
+// GetDeliveryOTP returns the hand-over OTP for the customer's own order
+func (u *OrderUsecase) GetDeliveryOTP(ctx context.Context, orderID, userID uuid.UUID) (*DeliveryOTPResponse, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	if order.Status != domain.OrderStatusOutForDelivery {
+		return nil, ErrNotOutForDelivery
+	}
+
+	return &DeliveryOTPResponse{
+		OrderID: order.ID,
+		OTP:     order.DeliveryOTP,
+	}, nil
+}
+
+// GetRiderOrders lists the rider's active deliveries
+func (u *OrderUsecase) GetRiderOrders(ctx context.Context, riderID uuid.UUID) ([]domain.Order, error) {
+	orders, err := u.orderRepo.GetActiveByRider(ctx, riderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rider orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ConfirmDelivery marks the order DELIVERED when the rider enters the
+// customer's OTP. Wrong entries count towards a persistent lockout.
+func (u *OrderUsecase) ConfirmDelivery(ctx context.Context, orderID, riderID uuid.UUID, otp string) (*domain.Order, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.RiderID == nil || *order.RiderID != riderID {
+		return nil, ErrNotAssignedRider
+	}
+	if order.Status != domain.OrderStatusOutForDelivery {
+		return nil, ErrNotOutForDelivery
+	}
+	if order.DeliveryOTPAttempts >= maxDeliveryOTPAttempts {
+		return nil, ErrDeliveryOTPLocked
+	}
+
+	if subtle.ConstantTimeCompare([]byte(order.DeliveryOTP), []byte(otp)) != 1 {
+		attempts, err := u.orderRepo.IncrementDeliveryOTPAttempts(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		u.log.Warn("Delivery OTP mismatch",
+			"order_id", order.ID.String(),
+			"rider_id", riderID.String(),
+			"attempts", attempts,
+		)
+
+		if attempts >= maxDeliveryOTPAttempts {
+			return nil, ErrDeliveryOTPLocked
+		}
+		return nil, ErrInvalidDeliveryOTP
+	}
+
+	if err := u.orderRepo.CompleteDelivery(ctx, order.ID, domain.DeliveryConfirmedByOTP, nil, "", order.Version); err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Order delivered (OTP confirmed)",
+		"order_id", order.ID.String(),
+		"rider_id", riderID.String(),
+	)
+
+	return u.deliveredOrder(ctx, order.ID)
+}
+
+// OverrideDelivery lets an admin mark an out-for-delivery order DELIVERED
+// without the OTP (customer's phone died, OTP locked, etc.). The reason and
+// admin are recorded on the order for audits.
+func (u *OrderUsecase) OverrideDelivery(ctx context.Context, orderID, adminID uuid.UUID, reason string) (*domain.Order, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrOverrideReason
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.OrderStatusOutForDelivery {
+		return nil, ErrNotOutForDelivery
+	}
+
+	if err := u.orderRepo.CompleteDelivery(ctx, order.ID, domain.DeliveryConfirmedByOverride, &adminID, reason, order.Version); err != nil {
+		return nil, err
+	}
+
+	u.log.Warn("Delivery confirmed by admin override",
+		"order_id", order.ID.String(),
+		"admin_id", adminID.String(),
+		"reason", reason,
+		"otp_attempts", order.DeliveryOTPAttempts,
+	)
+	u.audit.Record(ctx, adminID, domain.AuditOrderDeliveryOverride, "order", order.ID.String(), map[string]interface{}{
+		"reason": reason,
+	})
+
+	return u.deliveredOrder(ctx, order.ID)
+}
+
+// deliveredOrder reloads an order that was just delivered and runs the
+// post-delivery follow-ups
+func (u *OrderUsecase) deliveredOrder(ctx context.Context, orderID uuid.UUID) (*domain.Order, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	u.afterDelivered(ctx, order)
+	return order, nil
+}
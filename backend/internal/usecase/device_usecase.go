@@ -0,0 +1,64 @@
+// Package usecase implements registering devices for push notifications
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Device errors
+var (
+	ErrInvalidDeviceToken = errors.New("token must be 1-4096 characters")
+	ErrInvalidPlatform    = errors.New("platform must be android, ios or web")
+)
+
+// Device limits
+const (
+	maxDeviceTokenLength = 4096
+	// maxDevicesPerUser drops a user's least recently seen devices, which
+	// are mostly reinstalls whose old token was never unregistered
+	maxDevicesPerUser = 10
+)
+
+// DeviceUsecase keeps the push tokens of the apps a user is signed in on
+type DeviceUsecase struct {
+	repo *repository.DeviceTokenRepository
+	log  *logger.Logger
+}
+
+// NewDeviceUsecase creates a new device usecase
+func NewDeviceUsecase(repo *repository.DeviceTokenRepository, log *logger.Logger) *DeviceUsecase {
+	return &DeviceUsecase{repo: repo, log: log}
+}
+
+// RegisterDevice saves the FCM token of the app the user is signed in on.
+// Apps call it at every launch, since FCM rotates tokens.
+func (u *DeviceUsecase) RegisterDevice(ctx context.Context, userID uuid.UUID, token string, platform domain.DevicePlatform) (*domain.DeviceToken, error) {
+	token = strings.TrimSpace(token)
+	if token == "" || len(token) > maxDeviceTokenLength {
+		return nil, ErrInvalidDeviceToken
+	}
+	platform = domain.DevicePlatform(strings.ToLower(string(platform)))
+	if !platform.IsValid() {
+		return nil, ErrInvalidPlatform
+	}
+
+	device := &domain.DeviceToken{Token: token, UserID: userID, Platform: platform}
+	if err := u.repo.Register(ctx, device, maxDevicesPerUser); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// UnregisterDevice stops pushes to a device, e.g. on sign-out. Returns
+// repository.ErrNotFound if the user has no such token.
+func (u *DeviceUsecase) UnregisterDevice(ctx context.Context, userID uuid.UUID, token string) error {
+	return u.repo.Unregister(ctx, userID, strings.TrimSpace(token))
+}
@@ -0,0 +1,222 @@
+// Package usecase implements queueing and sending transactional order
+// emails
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/email"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+)
+
+// emailBatch is how many due emails one sweep claims
+const emailBatch = 20
+
+// emailLease keeps other instances off an email being sent
+const emailLease = 5 * time.Minute
+
+// emailTimeFormat formats order times in emails
+const emailTimeFormat = "2 Jan 2006, 3:04 PM"
+
+// EmailUsecase emails customers about their orders: a confirmation once an
+// order is paid, a receipt once it is delivered or collected. Emails are
+// queued in the email outbox, a single insert, and sent by a background
+// worker, so the order flow never waits on the mail provider. Only users
+// with a verified address are emailed.
+type EmailUsecase struct {
+	outbox    *repository.EmailOutboxRepository
+	orderRepo *repository.OrderRepository
+	users     *UserCache
+	templates *TemplateUsecase
+	sender    email.Sender
+	policy    retry.Policy
+	location  *time.Location
+	wake      chan struct{}
+	log       *logger.Logger
+}
+
+// NewEmailUsecase creates a new email usecase. Times in emails are shown
+// in location.
+func NewEmailUsecase(outbox *repository.EmailOutboxRepository, orderRepo *repository.OrderRepository, users *UserCache, templates *TemplateUsecase, sender email.Sender, location *time.Location, log *logger.Logger) *EmailUsecase {
+	return &EmailUsecase{
+		outbox:    outbox,
+		orderRepo: orderRepo,
+		users:     users,
+		templates: templates,
+		sender:    sender,
+		policy:    retry.NoRetry,
+		location:  location,
+		wake:      make(chan struct{}, 1),
+		log:       log.WithFields(map[string]interface{}{"source": "email"}),
+	}
+}
+
+// SetRetryPolicy retries failed sends from the outbox, backing off per
+// policy until MaxAttempts is reached. Without it a failed email is given
+// up on at once.
+func (u *EmailUsecase) SetRetryPolicy(policy retry.Policy) {
+	u.policy = policy
+}
+
+// Enqueue queues the email templateKey for an order. Imported orders are
+// never emailed. Safe to call on a nil usecase.
+func (u *EmailUsecase) Enqueue(ctx context.Context, templateKey string, order *domain.Order) error {
+	if u == nil || order.IsImported() {
+		return nil
+	}
+	if err := u.outbox.Enqueue(ctx, templateKey, order.ID, order.UserID); err != nil {
+		return err
+	}
+	// Send now rather than at the next poll, if this instance's worker is idle
+	select {
+	case u.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run sends due emails every interval, and as soon as one is queued on
+// this instance, until ctx is cancelled
+func (u *EmailUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.wake:
+		}
+		u.sendDue(ctx)
+	}
+}
+
+// sendDue sends due emails until none are left
+func (u *EmailUsecase) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := u.outbox.ClaimDue(ctx, emailBatch, emailLease)
+		if err != nil {
+			u.log.Error("Failed to load due emails", "error", err)
+			return
+		}
+		for i := range due {
+			u.send(ctx, &due[i])
+		}
+		if len(due) < emailBatch {
+			return
+		}
+	}
+}
+
+// send sends one queued email and records the outcome: sent, retried
+// later with backoff, or given up on once attempts run out or the failure
+// is final
+func (u *EmailUsecase) send(ctx context.Context, queued *domain.QueuedEmail) {
+	log := u.log.WithFields(map[string]interface{}{
+		"email_id": queued.ID,
+		"template": queued.TemplateKey,
+		"order_id": queued.OrderID.String(),
+		"attempt":  queued.Attempts,
+	})
+
+	err := u.deliver(ctx, queued)
+	if err == nil {
+		if err := u.outbox.MarkSent(ctx, queued.ID); err != nil {
+			log.Error("Failed to mark email sent", "error", err)
+		}
+		return
+	}
+
+	retryable := u.policy.Retryable
+	if retryable == nil {
+		retryable = retry.DefaultClassifier
+	}
+	if queued.Attempts < u.policy.MaxAttempts && retryable(err) {
+		at := time.Now().Add(u.policy.Backoff(queued.Attempts))
+		if err := u.outbox.ScheduleRetry(ctx, queued.ID, at, err.Error()); err != nil {
+			log.Error("Failed to schedule email retry", "error", err)
+			return
+		}
+		log.Warn("Email failed, will retry", "next_attempt_at", at, "error", err)
+		return
+	}
+
+	if err := u.outbox.MarkFailed(ctx, queued.ID, err.Error()); err != nil {
+		log.Error("Failed to mark email failed", "error", err)
+		return
+	}
+	log.Error("Giving up on email", "error", err)
+}
+
+// deliver renders a queued email in the user's locale and sends it. A user
+// without a verified address has nothing to send.
+func (u *EmailUsecase) deliver(ctx context.Context, queued *domain.QueuedEmail) error {
+	user, err := u.users.Get(ctx, queued.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return retry.Permanent(err)
+		}
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.Email == "" || !user.EmailVerified || user.DeactivatedAt != nil {
+		return nil
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, queued.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return retry.Permanent(err)
+		}
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+
+	msg, err := u.templates.Render(ctx, queued.TemplateKey, user.PreferredLocale(), u.orderVariables(order, user))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	return u.sender.Send(ctx, email.Message{To: user.Email, Subject: msg.Subject, HTML: msg.Body})
+}
+
+// orderVariables fills in the order email templates
+func (u *EmailUsecase) orderVariables(order *domain.Order, user *domain.User) map[string]any {
+	items := make([]map[string]any, 0, len(order.Items))
+	for _, item := range order.Items {
+		name := item.Name
+		if item.VariantName != "" {
+			name += " (" + item.VariantName + ")"
+		}
+		items = append(items, map[string]any{
+			"name":     name,
+			"quantity": item.Quantity,
+			"amount":   formatRupees(item.Subtotal()),
+		})
+	}
+
+	deliveredAt := time.Now()
+	switch {
+	case order.DeliveredAt != nil:
+		deliveredAt = *order.DeliveredAt
+	case order.PickupVerifiedAt != nil:
+		deliveredAt = *order.PickupVerifiedAt
+	}
+	return map[string]any{
+		"customer_name":    user.Name,
+		"order_number":     orderNumber(order.ID),
+		"items":            items,
+		"subtotal":         formatRupees(order.Subtotal),
+		"packaging_charge": formatRupees(order.PackagingCharge),
+		"delivery_fee":     formatRupees(order.DeliveryFee),
+		"tax":              formatRupees(order.TaxAmount),
+		"total":            formatRupees(order.TotalAmount),
+		"placed_at":        order.CreatedAt.In(u.location).Format(emailTimeFormat),
+		"delivered_at":     deliveredAt.In(u.location).Format(emailTimeFormat),
+		"payment_id":       order.RazorpayPaymentID,
+	}
+}
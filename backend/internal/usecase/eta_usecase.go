@@ -0,0 +1,140 @@
+// Package usecase implements delivery ETA estimation
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/weather"
+)
+
+// ETAConfig tunes delivery time estimates
+type ETAConfig struct {
+	Origin         geo.Point     // Kitchen location
+	PrepTime       time.Duration // Kitchen preparation before dispatch
+	RiderSpeedKmph float64       // Average road speed
+}
+
+// ETAEstimate is the promised delivery (or ready-for-pickup) time and how
+// much of it came from weather
+type ETAEstimate struct {
+	EstimatedAt       time.Time         `json:"estimated_at"`
+	AdjustmentMinutes int               `json:"adjustment_minutes"`
+	WeatherCondition  weather.Condition `json:"weather_condition,omitempty"`
+}
+
+// ETAUsecase estimates when an order will arrive
+type ETAUsecase struct {
+	cfg         ETAConfig
+	weather     weather.Provider
+	adjustments weather.Adjustments
+	settings    *SettingsUsecase
+	redisClient *redis.Client
+	log         *logger.Logger
+}
+
+// NewETAUsecase creates a new ETA usecase
+func NewETAUsecase(cfg ETAConfig, redisClient *redis.Client, log *logger.Logger) *ETAUsecase {
+	return &ETAUsecase{
+		cfg:         cfg,
+		redisClient: redisClient,
+		log:         log,
+	}
+}
+
+// SetWeather enables weather-based adjustments
+func (u *ETAUsecase) SetWeather(provider weather.Provider, adjustments weather.Adjustments) {
+	u.weather = provider
+	u.adjustments = adjustments
+}
+
+// SetSettings lets admins change the kitchen prep time at runtime
+func (u *ETAUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// Estimate returns the ETA for an order whose preparation starts at
+// kitchenStart (now if earlier). Delivery orders add riding time for
+// distanceMeters (straight-line from the kitchen when the road distance is
+// unknown) plus any weather adjustment at the drop-off.
+func (u *ETAUsecase) Estimate(ctx context.Context, fulfillment domain.FulfillmentType, loc *domain.DeliveryLocation, distanceMeters *int, kitchenStart time.Time) *ETAEstimate {
+	start := time.Now()
+	if kitchenStart.After(start) {
+		start = kitchenStart
+	}
+
+	prepTime := u.cfg.PrepTime
+	if u.settings != nil {
+		prepTime = time.Duration(u.settings.Int(ctx, SettingKitchenPrepMinutes)) * time.Minute
+	}
+
+	eta := &ETAEstimate{EstimatedAt: start.Add(prepTime)}
+	if fulfillment != domain.FulfillmentDelivery || loc == nil {
+		return eta
+	}
+
+	dest := geo.Point{Latitude: loc.Latitude, Longitude: loc.Longitude}
+	meters := geo.HaversineMeters(u.cfg.Origin, dest)
+	if distanceMeters != nil {
+		meters = float64(*distanceMeters)
+	}
+	if u.cfg.RiderSpeedKmph > 0 {
+		eta.EstimatedAt = eta.EstimatedAt.Add(time.Duration(meters / 1000 / u.cfg.RiderSpeedKmph * float64(time.Hour)))
+	}
+
+	if obs := u.currentWeather(ctx, dest); obs != nil {
+		eta.WeatherCondition = obs.Condition
+		if extra, ok := u.adjustments[obs.Condition]; ok && extra > 0 {
+			eta.AdjustmentMinutes = int(extra / time.Minute)
+			eta.EstimatedAt = eta.EstimatedAt.Add(extra)
+		}
+	}
+
+	eta.EstimatedAt = eta.EstimatedAt.Truncate(time.Minute)
+	return eta
+}
+
+// currentWeather returns conditions for the area around p, cached per
+// ~1 km grid cell. Lookup failures return nil so ETAs never block checkout.
+func (u *ETAUsecase) currentWeather(ctx context.Context, p geo.Point) *weather.Observation {
+	if u.weather == nil {
+		return nil
+	}
+
+	// Two decimals is ~1.1 km of latitude - one area shares one lookup
+	area := geo.Point{
+		Latitude:  math.Round(p.Latitude*100) / 100,
+		Longitude: math.Round(p.Longitude*100) / 100,
+	}
+	key := fmt.Sprintf("%s%.2f,%.2f", redis.WeatherCachePrefix, area.Latitude, area.Longitude)
+
+	if u.redisClient != nil {
+		var cached weather.Observation
+		found, err := u.redisClient.GetJSON(ctx, key, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read weather cache", "error", err)
+		} else if found {
+			return &cached
+		}
+	}
+
+	obs, err := u.weather.Current(ctx, area)
+	if err != nil {
+		u.log.Warn("Weather lookup failed, ETA not adjusted", "error", err)
+		return nil
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, key, obs, redis.WeatherCacheTTL); err != nil {
+			u.log.Warn("Failed to cache weather", "error", err)
+		}
+	}
+
+	return obs
+}
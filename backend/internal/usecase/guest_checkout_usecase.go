@@ -0,0 +1,208 @@
+// Package usecase implements turning a guest into a registered customer at
+// checkout
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// Guest conversion errors
+var (
+	ErrInvalidPhone = errors.New("phone number must be 10 to 14 digits")
+	ErrNameRequired = errors.New("name is required to create an account")
+)
+
+// userPhonePattern matches the users table's phone number check
+var userPhonePattern = regexp.MustCompile(`^\+?[0-9]{10,14}$`)
+
+// SetCarts lets logins and guest conversion carry the guest's cart into
+// the account
+func (u *UserUsecase) SetCarts(carts *CartUsecase) {
+	u.carts = carts
+}
+
+// carryGuestCart merges a guest cart into the user's on login and returns
+// the result, or nil when there was no guest cart to carry. A failed merge
+// doesn't fail the login; the guest cart is kept so the app can retry with
+// POST /cart/merge.
+func (u *UserUsecase) carryGuestCart(ctx context.Context, userID uuid.UUID, guestToken string) *domain.Cart {
+	if guestToken == "" || u.carts == nil {
+		return nil
+	}
+	cart, err := u.carts.MergeGuestCart(ctx, userID, guestToken)
+	if err != nil {
+		u.log.Error("Failed to merge guest cart", "error", err, "user_id", userID.String())
+		return nil
+	}
+	return cart
+}
+
+// GuestOTPResponse tells the app whether to ask for a name and email
+// before verifying
+type GuestOTPResponse struct {
+	Message              string `json:"message"`
+	NewAccount           bool   `json:"new_account"`
+	ReactivationRequired bool   `json:"reactivation_required,omitempty"` // OTP is for POST /auth/reactivate
+}
+
+// SendGuestOTP sends a checkout OTP: a login OTP for a number that has an
+// account, otherwise a signup OTP
+func (u *UserUsecase) SendGuestOTP(ctx context.Context, req PhoneLoginRequest) (*GuestOTPResponse, error) {
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	if !userPhonePattern.MatchString(req.PhoneNumber) {
+		return nil, ErrInvalidPhone
+	}
+
+	resp, err := u.SendOTP(ctx, req)
+	if err == nil {
+		return &GuestOTPResponse{Message: resp.Message, ReactivationRequired: resp.ReactivationRequired}, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	if err := u.allowOTPRequest(ctx, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OTP: %w", err)
+	}
+
+	now := time.Now()
+	otp := &domain.OTP{
+		PhoneNumber: &req.PhoneNumber,
+		OTPCode:     code,
+		Purpose:     domain.OTPPurposeSignup,
+		ExpiresAt:   now.Add(otpValidity),
+		CreatedAt:   now,
+	}
+	if err := u.userRepo.CreateOTP(ctx, otp); err != nil {
+		return nil, fmt.Errorf("failed to store OTP: %w", err)
+	}
+
+	if err := u.deliverOTP(ctx, req.PhoneNumber, code, ""); err != nil {
+		return nil, err
+	}
+	u.log.Info("Signup OTP sent for guest checkout")
+
+	return &GuestOTPResponse{
+		Message:    "OTP sent to your phone number",
+		NewAccount: true,
+	}, nil
+}
+
+// ConvertGuestRequest verifies a guest checkout OTP. Name and email are
+// only needed when the number has no account yet.
+type ConvertGuestRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	OTP         string `json:"otp"`
+	Name        string `json:"name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	GuestToken  string `json:"guest_token,omitempty"`
+}
+
+// ConvertGuestResponse is the new session and the cart carried into it
+type ConvertGuestResponse struct {
+	*VerifyOTPResponse
+	NewAccount bool `json:"new_account"`
+}
+
+// ConvertGuest verifies the OTP from SendGuestOTP, logging in to the
+// number's account or creating one, and merges the guest's cart into it.
+// A failed merge doesn't fail the login; the guest cart is kept so the app
+// can retry with POST /cart/merge.
+func (u *UserUsecase) ConvertGuest(ctx context.Context, req ConvertGuestRequest) (*ConvertGuestResponse, error) {
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+
+	resp := &ConvertGuestResponse{}
+	_, err := u.userRepo.GetByPhoneNumber(ctx, req.PhoneNumber)
+	switch {
+	case err == nil:
+		resp.VerifyOTPResponse, err = u.VerifyOTP(ctx, VerifyOTPRequest{PhoneNumber: req.PhoneNumber, OTP: req.OTP, GuestToken: req.GuestToken})
+	case errors.Is(err, repository.ErrNotFound):
+		resp.VerifyOTPResponse, err = u.signUpGuest(ctx, req)
+		resp.NewAccount = true
+		if err == nil {
+			resp.Cart = u.carryGuestCart(ctx, resp.UserID, req.GuestToken)
+		}
+	default:
+		err = fmt.Errorf("failed to find user: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// signUpGuest checks the signup OTP and creates the account
+func (u *UserUsecase) signUpGuest(ctx context.Context, req ConvertGuestRequest) (*VerifyOTPResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	email, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || email.Name != "" {
+		return nil, ErrInvalidEmail
+	}
+
+	otp, err := u.userRepo.GetValidOTP(ctx, req.PhoneNumber, domain.OTPPurposeSignup)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidOTP
+		}
+		return nil, fmt.Errorf("failed to get OTP: %w", err)
+	}
+	if otp.OTPCode != req.OTP {
+		if err := u.userRepo.IncrementOTPAttempts(ctx, otp.ID); err != nil {
+			u.log.Error("Failed to increment OTP attempts", "error", err)
+		}
+		return nil, ErrInvalidOTP
+	}
+	if err := u.userRepo.MarkOTPVerified(ctx, otp.ID); err != nil {
+		u.log.Error("Failed to mark OTP as verified", "error", err)
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		PhoneNumber: req.PhoneNumber,
+		Name:        name,
+		Email:       strings.ToLower(email.Address),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	tokens, err := u.issueSession(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Guest signed up at checkout", "user_id", user.ID.String())
+
+	return &VerifyOTPResponse{
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		PhoneNumber:   user.PhoneNumber,
+	}, nil
+}
@@ -0,0 +1,267 @@
+// Package usecase implements stock tracking for menu items
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Inventory errors
+var (
+	ErrItemSoldOut           = errors.New("one or more items are sold out")
+	ErrInvalidStock          = errors.New("stock quantity must be between 0 and 100000")
+	ErrInvalidStockThreshold = errors.New("low stock threshold must be between 0 and 100000")
+)
+
+// maxStockQuantity caps a stock level, catching typos in the admin panel
+const maxStockQuantity = 100000
+
+// InventoryUsecase keeps stock levels in step with orders. Checkout takes
+// items out of stock with the order insert; cancellations and failed
+// payments put them back. Items running low or selling out alert admins,
+// and sold out items can be switched off until restocked.
+type InventoryUsecase struct {
+	inventory *repository.InventoryRepository
+	menu      *MenuUsecase
+	audit     *AuditUsecase
+	settings  *SettingsUsecase
+	alerts    *AdminAlertUsecase
+	log       *logger.Logger
+}
+
+// NewInventoryUsecase creates a new inventory usecase. menu has its cache
+// refreshed when an item sells out or comes back.
+func NewInventoryUsecase(inventory *repository.InventoryRepository, menu *MenuUsecase, audit *AuditUsecase, log *logger.Logger) *InventoryUsecase {
+	return &InventoryUsecase{
+		inventory: inventory,
+		menu:      menu,
+		audit:     audit,
+		log:       log,
+	}
+}
+
+// SetSettings lets admins change the default low-stock threshold and
+// whether sold out items are switched off at runtime. Without it only
+// sold out items alert and nothing is switched off.
+func (u *InventoryUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// SetAlerts alerts admins when items run low or sell out
+func (u *InventoryUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// validStock checks a stock level an admin entered; nil means untracked
+func validStock(quantity *int) error {
+	if quantity != nil && (*quantity < 0 || *quantity > maxStockQuantity) {
+		return ErrInvalidStock
+	}
+	return nil
+}
+
+// SetStock sets how many units of an item are left (admin only); nil stops
+// tracking it. Returns the updated item.
+func (u *InventoryUsecase) SetStock(ctx context.Context, itemID uuid.UUID, quantity *int, adminID uuid.UUID) (*domain.MenuItem, error) {
+	if err := validStock(quantity); err != nil {
+		return nil, err
+	}
+	if err := u.inventory.SetStock(ctx, itemID, quantity); err != nil {
+		return nil, err
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuStockUpdated, "menu_item", itemID.String(), map[string]interface{}{
+		"stock_quantity": quantity,
+	})
+
+	// The menu shows sold out items, so any change may show there. A
+	// restocked item that was switched off comes back now.
+	u.menu.invalidateCache(ctx)
+	if err := u.CheckStock(ctx); err != nil {
+		u.log.Warn("Failed to check stock levels", "error", err)
+	}
+	return u.menu.GetMenuItem(ctx, itemID)
+}
+
+// SetLowStockThreshold sets the stock level at or below which admins are
+// alerted about an item (admin only); nil uses the default
+func (u *InventoryUsecase) SetLowStockThreshold(ctx context.Context, itemID uuid.UUID, threshold *int, adminID uuid.UUID) error {
+	if threshold != nil && (*threshold < 0 || *threshold > maxStockQuantity) {
+		return ErrInvalidStockThreshold
+	}
+	if err := u.inventory.SetLowStockThreshold(ctx, itemID, threshold); err != nil {
+		return err
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuStockThreshold, "menu_item", itemID.String(), map[string]interface{}{
+		"low_stock_threshold": threshold,
+	})
+
+	if err := u.CheckStock(ctx); err != nil {
+		u.log.Warn("Failed to check stock levels", "error", err)
+	}
+	return nil
+}
+
+// StockSummary returns the tracked items' stock against their thresholds
+func (u *InventoryUsecase) StockSummary(ctx context.Context) (*domain.StockSummary, error) {
+	threshold, _ := u.stockSettings(ctx)
+	return u.inventory.StockSummary(ctx, threshold)
+}
+
+// StockReport is the stock section of the admins' daily digest: counts,
+// then the lowest items. Nothing when no item is tracked.
+func (u *InventoryUsecase) StockReport(ctx context.Context) ([]string, error) {
+	summary, err := u.StockSummary(ctx)
+	if err != nil || summary.Tracked == 0 {
+		return nil, err
+	}
+
+	lines := []string{fmt.Sprintf("Stock: %d items tracked, %d low, %d sold out", summary.Tracked, summary.Low, summary.Out)}
+	for i, item := range summary.Items {
+		if i == digestPreviewLines {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(summary.Items)-i))
+			break
+		}
+		if item.Level == domain.StockLevelOut {
+			lines = append(lines, "- "+item.Name+": sold out")
+		} else {
+			lines = append(lines, fmt.Sprintf("- %s: %d left", item.Name, item.StockQuantity))
+		}
+	}
+	return lines, nil
+}
+
+// CheckStock compares stock levels with their thresholds, alerting admins
+// about items that ran low or sold out since the last check and switching
+// sold out items off (and restocked ones back on). Checkout and stock
+// changes run it straight away; RunStockChecks catches the rest.
+func (u *InventoryUsecase) CheckStock(ctx context.Context) error {
+	threshold, disable := u.stockSettings(ctx)
+	changed, err := u.inventory.CheckStockLevels(ctx, threshold, disable)
+	if err != nil {
+		return err
+	}
+
+	switched := false
+	for _, c := range changed {
+		if c.Disabled || c.Reenabled {
+			switched = true
+			u.log.Info("Menu item availability switched by stock", "menu_item_id", c.MenuItemID.String(), "available", c.Reenabled)
+		}
+
+		data := map[string]string{"type": "stock_alert", "menu_item_id": c.MenuItemID.String()}
+		switch {
+		case c.Level == domain.StockLevelOut && c.Previous != domain.StockLevelOut:
+			body := c.Name + " (" + c.Category + ") sold out and shows as sold out until restocked."
+			if c.Disabled {
+				body = c.Name + " (" + c.Category + ") sold out and is off the menu until restocked."
+			}
+			u.alerts.Raise(ctx, AdminAlert{
+				Kind:  AlertStockOut,
+				Title: c.Name + " sold out",
+				Body:  body,
+				Data:  data,
+			})
+		case c.Level == domain.StockLevelLow && c.Previous == "":
+			left := 0
+			if c.StockQuantity != nil {
+				left = *c.StockQuantity
+			}
+			u.alerts.Raise(ctx, AdminAlert{
+				Kind:  AlertStockLow,
+				Title: c.Name + " is running low",
+				Body:  fmt.Sprintf("%d left of %s (%s); the alert level is %d.", left, c.Name, c.Category, c.Threshold),
+				Data:  data,
+			})
+		}
+	}
+
+	if switched {
+		u.menu.invalidateCache(ctx)
+	}
+	return nil
+}
+
+// RunStockChecks checks stock levels every interval until ctx is
+// cancelled
+func (u *InventoryUsecase) RunStockChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.CheckStock(ctx); err != nil {
+				u.log.Error("Failed to check stock levels", "error", err)
+			}
+		}
+	}
+}
+
+// stockSettings returns the default low-stock threshold and whether sold
+// out items are switched off
+func (u *InventoryUsecase) stockSettings(ctx context.Context) (int, bool) {
+	if u.settings == nil {
+		return 0, false
+	}
+	return u.settings.Int(ctx, SettingInventoryLowStockThreshold), u.settings.Bool(ctx, SettingInventoryDisableSoldOut)
+}
+
+// Reserve takes a new order's items out of stock. Call it inside the unit
+// of work that inserts the order, and refreshMenu with the result once it
+// commits. Returns ErrItemSoldOut if an item ran out since the cart was
+// priced.
+func (u *InventoryUsecase) Reserve(ctx context.Context, order *domain.Order) ([]uuid.UUID, error) {
+	soldOut, err := u.inventory.Decrement(ctx, order.ID, order.Items)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			return nil, ErrItemSoldOut
+		}
+		return nil, fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	return soldOut, nil
+}
+
+// Restore gives back the stock of an order that won't be fulfilled. Safe
+// to call more than once per order; failures are logged, not returned.
+func (u *InventoryUsecase) Restore(ctx context.Context, order *domain.Order) {
+	restocked, err := u.inventory.Restore(ctx, order.ID)
+	if err != nil {
+		u.log.Error("Failed to restore stock", "order_id", order.ID.String(), "error", err)
+		return
+	}
+	u.refreshMenu(ctx, restocked)
+}
+
+// Retake takes stock again for an order paid after a failed payment gave
+// its stock back. Failures are logged, not returned.
+func (u *InventoryUsecase) Retake(ctx context.Context, order *domain.Order) {
+	soldOut, err := u.inventory.Retake(ctx, order.ID)
+	if err != nil {
+		u.log.Error("Failed to retake stock", "order_id", order.ID.String(), "error", err)
+		return
+	}
+	u.refreshMenu(ctx, soldOut)
+}
+
+// refreshMenu updates the cached menu after items sold out or came back,
+// and switches them off or on now rather than at the next stock check
+func (u *InventoryUsecase) refreshMenu(ctx context.Context, changed []uuid.UUID) {
+	if len(changed) == 0 {
+		return
+	}
+	u.log.Info("Menu items changed stock state", "items", len(changed))
+	u.menu.invalidateCache(ctx)
+	if err := u.CheckStock(ctx); err != nil {
+		u.log.Warn("Failed to check stock levels", "error", err)
+	}
+}
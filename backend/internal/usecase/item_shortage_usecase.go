@@ -0,0 +1,406 @@
+// Package usecase implements the flow for items the kitchen runs out of
+// after an order was paid for
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/logger"
+)
+
+// Item shortage errors
+var (
+	ErrShortageNotAllowed   = errors.New("items can only be reported unavailable on paid orders still in the kitchen")
+	ErrInvalidShortage      = errors.New("invalid shortage: items must be lines of the order with quantities up to those ordered")
+	ErrShortagePending      = errors.New("order already has a shortage waiting for the customer")
+	ErrNoPendingShortage    = errors.New("order has no shortage waiting for an answer")
+	ErrInvalidShortageReply = errors.New("answer must be accept or cancel")
+)
+
+// ShortageReply is the customer's answer to a shortage
+type ShortageReply string
+
+const (
+	ShortageReplyAccept ShortageReply = "accept" // Refund the missing items, go ahead with the rest
+	ShortageReplyCancel ShortageReply = "cancel" // Cancel the order and refund it in full
+)
+
+// defaultShortageResponseWindow is how long the customer has to answer
+// before the kitchen goes ahead with the rest of the order
+const defaultShortageResponseWindow = 10 * time.Minute
+
+// shortageSweepBatch is how many overdue shortages one sweep resolves
+const shortageSweepBatch = 50
+
+// ItemShortageUsecase lets staff report items they can't make on a paid
+// order. The customer accepts a refund for them or cancels the order;
+// unanswered shortages are accepted once the response window passes.
+type ItemShortageUsecase struct {
+	shortageRepo   *repository.ItemShortageRepository
+	orderRepo      *repository.OrderRepository
+	payments       *PaymentUsecase
+	notifications  *NotificationUsecase
+	alerts         *AdminAlertUsecase
+	audit          *AuditUsecase
+	uow            database.UnitOfWork
+	responseWindow time.Duration
+	log            *logger.Logger
+}
+
+// NewItemShortageUsecase creates a new item shortage usecase
+func NewItemShortageUsecase(
+	shortageRepo *repository.ItemShortageRepository,
+	orderRepo *repository.OrderRepository,
+	payments *PaymentUsecase,
+	notifications *NotificationUsecase,
+	log *logger.Logger,
+) *ItemShortageUsecase {
+	return &ItemShortageUsecase{
+		shortageRepo:   shortageRepo,
+		orderRepo:      orderRepo,
+		payments:       payments,
+		notifications:  notifications,
+		responseWindow: defaultShortageResponseWindow,
+		log:            log,
+	}
+}
+
+// SetResponseWindow sets how long customers have to answer a shortage
+func (u *ItemShortageUsecase) SetResponseWindow(window time.Duration) {
+	u.responseWindow = window
+}
+
+// SetUnitOfWork cancels an order and closes its shortage in one transaction
+func (u *ItemShortageUsecase) SetUnitOfWork(uow database.UnitOfWork) {
+	u.uow = uow
+}
+
+// SetAlerts tells admins about refunds that failed and must be made by hand
+func (u *ItemShortageUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// SetAuditLog records reported shortages in the admin audit log
+func (u *ItemShortageUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// ShortItemRequest is one order line and how many of it can't be made
+type ShortItemRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// ReportShortageRequest lists the items the kitchen ran out of
+type ReportShortageRequest struct {
+	Items []ShortItemRequest `json:"items"`
+	Note  string             `json:"note"`
+}
+
+// ReportShortage records items missing from a paid order and asks the
+// customer what to do. When nothing on the order can be made it is
+// cancelled and refunded straight away.
+func (u *ItemShortageUsecase) ReportShortage(ctx context.Context, adminID, orderID uuid.UUID, req ReportShortageRequest) (*domain.ItemShortage, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !inKitchen(order.Status) || order.IsImported() {
+		return nil, ErrShortageNotAllowed
+	}
+
+	items, refund, everything, err := shortItems(order, req.Items)
+	if err != nil {
+		return nil, err
+	}
+	if everything {
+		// Nothing left to send, so the delivery fee goes back too
+		refund = order.TotalAmount
+	}
+
+	shortage := &domain.ItemShortage{
+		OrderID:      order.ID,
+		Items:        items,
+		RefundAmount: refund,
+		Note:         strings.TrimSpace(req.Note),
+		ReportedBy:   &adminID,
+		RespondBy:    time.Now().Add(u.responseWindow),
+	}
+	if err := u.shortageRepo.Create(ctx, shortage); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrShortagePending
+		}
+		return nil, err
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditItemShortageReported, "order", order.ID.String(), map[string]interface{}{
+		"items":         items,
+		"refund_amount": refund,
+		"note":          shortage.Note,
+	})
+	u.log.Info("Item shortage reported",
+		"order_id", order.ID.String(),
+		"shortage_id", shortage.ID.String(),
+		"refund_amount", refund,
+		"whole_order", everything,
+	)
+
+	if everything {
+		return u.resolve(ctx, order, shortage, domain.ItemShortageCancelled, nil)
+	}
+
+	u.notifications.ItemsUnavailable(order, shortage)
+	return shortage, nil
+}
+
+// Reply records the customer's answer to their order's pending shortage
+func (u *ItemShortageUsecase) Reply(ctx context.Context, userID, orderID uuid.UUID, reply ShortageReply) (*domain.ItemShortage, error) {
+	var status domain.ItemShortageStatus
+	switch reply {
+	case ShortageReplyAccept:
+		status = domain.ItemShortageAccepted
+	case ShortageReplyCancel:
+		status = domain.ItemShortageCancelled
+	default:
+		return nil, ErrInvalidShortageReply
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+
+	shortage, err := u.shortageRepo.GetPendingByOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNoPendingShortage
+		}
+		return nil, err
+	}
+
+	return u.resolve(ctx, order, shortage, status, &userID)
+}
+
+// GetShortages returns every shortage reported on an order, oldest first.
+// A non-nil userID must own the order.
+func (u *ItemShortageUsecase) GetShortages(ctx context.Context, orderID uuid.UUID, userID *uuid.UUID) ([]domain.ItemShortage, error) {
+	if userID != nil {
+		order, err := u.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.UserID != *userID {
+			return nil, repository.ErrNotFound
+		}
+	}
+	return u.shortageRepo.ListByOrder(ctx, orderID)
+}
+
+// RunExpiry accepts shortages nobody answered in time, every interval until
+// ctx is cancelled
+func (u *ItemShortageUsecase) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.expireDue(ctx)
+		}
+	}
+}
+
+// expireDue accepts one batch of overdue shortages
+func (u *ItemShortageUsecase) expireDue(ctx context.Context) {
+	due, err := u.shortageRepo.GetDue(ctx, time.Now(), shortageSweepBatch)
+	if err != nil {
+		u.log.Error("Failed to load overdue item shortages", "error", err)
+		return
+	}
+
+	for i := range due {
+		shortage := &due[i]
+		order, err := u.orderRepo.GetByID(ctx, shortage.OrderID)
+		if err != nil {
+			u.log.Error("Failed to load order for item shortage", "order_id", shortage.OrderID.String(), "error", err)
+			continue
+		}
+		if _, err := u.resolve(ctx, order, shortage, domain.ItemShortageAccepted, nil); err != nil && !errors.Is(err, ErrNoPendingShortage) {
+			u.log.Error("Failed to accept overdue item shortage", "order_id", order.ID.String(), "error", err)
+		}
+	}
+}
+
+// resolve closes a pending shortage and refunds the customer. Cancelling
+// also cancels the order, in the same transaction, as long as it hasn't
+// left the kitchen. resolvedBy is nil when resolved automatically.
+func (u *ItemShortageUsecase) resolve(ctx context.Context, order *domain.Order, shortage *domain.ItemShortage, status domain.ItemShortageStatus, resolvedBy *uuid.UUID) (*domain.ItemShortage, error) {
+	// Cancelled and refunded in full some other way; nothing left to refund
+	if order.Status == domain.OrderStatusCancelled {
+		if err := u.shortageRepo.Resolve(ctx, shortage.ID, domain.ItemShortageCancelled, resolvedBy); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return nil, ErrNoPendingShortage
+			}
+			return nil, err
+		}
+		now := time.Now()
+		shortage.Status, shortage.ResolvedAt, shortage.ResolvedBy = domain.ItemShortageCancelled, &now, resolvedBy
+		return shortage, nil
+	}
+
+	previous := order.Status
+	refund := shortage.RefundAmount
+	if status == domain.ItemShortageCancelled {
+		// Only what partial refunds haven't already returned
+		refund = order.TotalAmount
+		if refunded, err := u.payments.refunds.RefundedAmount(ctx, order.RazorpayPaymentID); err != nil {
+			u.log.Warn("Failed to total earlier refunds", "order_id", order.ID.String(), "error", err)
+		} else {
+			refund -= refunded
+		}
+	}
+
+	err := inTx(ctx, u.uow, func(ctx context.Context) error {
+		if err := u.shortageRepo.Resolve(ctx, shortage.ID, status, resolvedBy); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrNoPendingShortage
+			}
+			return err
+		}
+		if status != domain.ItemShortageCancelled {
+			return nil
+		}
+
+		if !inKitchen(order.Status) {
+			return ErrShortageNotAllowed
+		}
+		if err := u.orderRepo.Cancel(ctx, order.ID, order.Version, resolvedBy, "items unavailable"); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				// Moved on (dispatched, handed over) since it was loaded
+				return ErrShortageNotAllowed
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shortage.Status, shortage.ResolvedAt, shortage.ResolvedBy = status, &now, resolvedBy
+
+	eventType := "refund.items_unavailable"
+	newStatus := (*domain.OrderStatus)(nil)
+	if status == domain.ItemShortageCancelled {
+		eventType = "refund.order_cancelled"
+		newStatus = statusPtr(domain.OrderStatusCancelled)
+		order.Status = domain.OrderStatusCancelled
+		u.payments.releaseSlot(ctx, order)
+		u.payments.restoreStock(ctx, order)
+	}
+
+	u.log.Info("Item shortage resolved",
+		"order_id", order.ID.String(),
+		"shortage_id", shortage.ID.String(),
+		"status", status,
+		"automatic", resolvedBy == nil,
+	)
+
+	if refund <= 0 {
+		if status == domain.ItemShortageCancelled {
+			u.notifications.OrderCancelled(order, order.TotalAmount) // All of it was refunded already
+		}
+		return shortage, nil
+	}
+
+	// The shortage stays resolved if the refund fails; admins refund by hand
+	refundID, refundErr := u.payments.refund(ctx, order, refund, eventType, statusPtr(previous), newStatus)
+	if refundErr != nil {
+		shortage.RefundError = refundErr.Error()
+		u.log.Error("Shortage refund failed", "order_id", order.ID.String(), "amount", refund, "error", refundErr)
+		u.alerts.Raise(ctx, AdminAlert{
+			Kind:     AlertRefundFailed,
+			Critical: true,
+			Title:    "Refund failed for order " + orderNumber(order.ID),
+			Body:     fmt.Sprintf("%s could not be refunded automatically after an item shortage; refund it from the Razorpay dashboard.", formatRupees(refund)),
+			Data: map[string]string{
+				"type":     "refund_failed",
+				"order_id": order.ID.String(),
+			},
+		})
+	}
+	shortage.RazorpayRefundID = refundID
+	if err := u.shortageRepo.SetRefund(ctx, shortage.ID, refundID, shortage.RefundError); err != nil {
+		u.log.Warn("Failed to record shortage refund", "order_id", order.ID.String(), "error", err)
+	}
+
+	if status == domain.ItemShortageCancelled {
+		u.notifications.OrderCancelled(order, refund)
+	}
+
+	return shortage, nil
+}
+
+// inKitchen reports whether an order is paid but not yet handed over to a
+// rider or the customer
+func inKitchen(status domain.OrderStatus) bool {
+	return status == domain.OrderStatusPaid || status == domain.OrderStatusAccepted
+}
+
+// shortItems validates the requested lines against the order and prices
+// them at what the customer paid. everything is true when no item would be
+// left to send.
+func shortItems(order *domain.Order, requested []ShortItemRequest) ([]domain.ShortItem, int64, bool, error) {
+	if len(requested) == 0 {
+		return nil, 0, false, ErrInvalidShortage
+	}
+
+	lines := make(map[uuid.UUID]domain.OrderItem, len(order.Items))
+	orderedUnits := 0
+	for _, item := range order.Items {
+		lines[item.ID] = item
+		orderedUnits += item.Quantity
+	}
+
+	seen := make(map[uuid.UUID]bool, len(requested))
+	items := make([]domain.ShortItem, 0, len(requested))
+	var refund int64
+	shortUnits := 0
+	for _, r := range requested {
+		line, ok := lines[r.OrderItemID]
+		if !ok || seen[r.OrderItemID] || r.Quantity < 1 || r.Quantity > line.Quantity {
+			return nil, 0, false, ErrInvalidShortage
+		}
+		seen[r.OrderItemID] = true
+
+		amount := line.Price * int64(r.Quantity)
+		items = append(items, domain.ShortItem{
+			OrderItemID: line.ID,
+			Name:        line.Name,
+			Quantity:    r.Quantity,
+			Amount:      amount,
+		})
+		refund += amount
+		shortUnits += r.Quantity
+	}
+
+	// The GST paid on the missing items goes back with them
+	refund += order.TaxOn(refund)
+
+	return items, refund, shortUnits == orderedUnits, nil
+}
@@ -0,0 +1,215 @@
+// Package usecase implements masked calls from riders to customers
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/calls"
+	"fooddelivery/pkg/logger"
+)
+
+// Masked call errors
+var (
+	ErrCallUnavailable  = errors.New("calls are only available while the order is out for delivery")
+	ErrCallInProgress   = errors.New("a call about this order is already in progress")
+	ErrNoPhoneNumber    = errors.New("no phone number on file to call")
+	ErrCallFailed       = errors.New("call could not be placed")
+	ErrInvalidCallToken = errors.New("invalid call callback token")
+)
+
+// MaskedCallConfig configures bridged calls
+type MaskedCallConfig struct {
+	Provider       string        // Name recorded in the call log; "log" when empty
+	MaxDuration    time.Duration // Calls are cut off after this
+	AfterDelivery  time.Duration // Default for calls.after_delivery_minutes
+	CallbackURL    string        // Public URL of the status callback route, without the call ID; empty = no callbacks
+	CallbackSecret string        // Signs the callback URL of each call
+}
+
+// MaskedCallUsecase lets a rider call the customer of an order they are
+// delivering. The provider rings the rider and connects them to the
+// customer, or the gift recipient, with both seeing only the provider's
+// number. Every call is logged against the order.
+type MaskedCallUsecase struct {
+	callRepo  *repository.OrderCallRepository
+	orderRepo *repository.OrderRepository
+	users     *UserCache
+	bridger   calls.Bridger
+	settings  *SettingsUsecase
+	cfg       MaskedCallConfig
+	log       *logger.Logger
+}
+
+// NewMaskedCallUsecase creates a new masked call usecase
+func NewMaskedCallUsecase(
+	callRepo *repository.OrderCallRepository,
+	orderRepo *repository.OrderRepository,
+	users *UserCache,
+	bridger calls.Bridger,
+	cfg MaskedCallConfig,
+	log *logger.Logger,
+) *MaskedCallUsecase {
+	if cfg.Provider == "" {
+		cfg.Provider = "log"
+	}
+	return &MaskedCallUsecase{
+		callRepo:  callRepo,
+		orderRepo: orderRepo,
+		users:     users,
+		bridger:   bridger,
+		cfg:       cfg,
+		log:       log,
+	}
+}
+
+// SetSettings lets admins change how long after delivery riders can still
+// call
+func (u *MaskedCallUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// CallCustomer bridges a call from the order's rider to the customer. Only
+// one call per order can be open at a time.
+func (u *MaskedCallUsecase) CallCustomer(ctx context.Context, orderID, riderID uuid.UUID) (*domain.OrderCall, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RiderID == nil || *order.RiderID != riderID {
+		return nil, ErrNotAssignedRider
+	}
+	if !u.callable(ctx, order) {
+		return nil, ErrCallUnavailable
+	}
+
+	open, err := u.callRepo.HasOpenCall(ctx, orderID, time.Now().Add(-u.cfg.MaxDuration))
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, ErrCallInProgress
+	}
+
+	rider, err := u.users.Get(ctx, riderID)
+	if err != nil {
+		return nil, err
+	}
+	customerPhone, err := u.customerPhone(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if rider.PhoneNumber == "" || customerPhone == "" {
+		return nil, ErrNoPhoneNumber
+	}
+
+	call := &domain.OrderCall{
+		OrderID:  orderID,
+		RiderID:  riderID,
+		Provider: u.cfg.Provider,
+		Status:   string(calls.StatusInitiated),
+	}
+	if err := u.callRepo.Create(ctx, call); err != nil {
+		return nil, err
+	}
+
+	placed, err := u.bridger.Bridge(ctx, calls.BridgeRequest{
+		From:              rider.PhoneNumber,
+		To:                customerPhone,
+		MaxDuration:       u.cfg.MaxDuration,
+		StatusCallbackURL: u.callbackURL(call.ID),
+	})
+	if err != nil {
+		u.log.Error("Failed to place masked call", "order_id", orderID.String(), "call_id", call.ID.String(), "error", err)
+		if err := u.callRepo.UpdateStatus(ctx, call.ID, "", string(calls.StatusFailed), 0, true, err.Error()); err != nil {
+			u.log.Warn("Failed to log failed call", "call_id", call.ID.String(), "error", err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCallFailed, err)
+	}
+
+	call.ProviderCallID = placed.ProviderCallID
+	call.Status = string(placed.Status)
+	if err := u.callRepo.UpdateStatus(ctx, call.ID, placed.ProviderCallID, call.Status, 0, placed.Status.Ended(), ""); err != nil {
+		u.log.Warn("Failed to log placed call", "call_id", call.ID.String(), "error", err)
+	}
+
+	u.log.Info("Masked call placed", "order_id", orderID.String(), "call_id", call.ID.String(), "rider_id", riderID.String())
+	return call, nil
+}
+
+// HandleStatusCallback records the final status the provider posted for a
+// call. token must be the one put in the call's callback URL.
+func (u *MaskedCallUsecase) HandleStatusCallback(ctx context.Context, callID uuid.UUID, token string, form url.Values) error {
+	if u.cfg.CallbackSecret == "" || !hmac.Equal([]byte(token), []byte(u.callbackToken(callID))) {
+		return ErrInvalidCallToken
+	}
+
+	update, err := u.bridger.ParseStatusCallback(form)
+	if err != nil {
+		return err
+	}
+	return u.callRepo.UpdateStatus(ctx, callID, update.ProviderCallID, string(update.Status), update.Duration, update.Status.Ended(), "")
+}
+
+// GetOrderCalls returns the calls placed about an order
+func (u *MaskedCallUsecase) GetOrderCalls(ctx context.Context, orderID uuid.UUID) ([]domain.OrderCall, error) {
+	if _, err := u.orderRepo.GetByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return u.callRepo.ListByOrder(ctx, orderID)
+}
+
+// callable reports whether riders can call about order now: while it is out
+// for delivery and for calls.after_delivery_minutes after hand-over
+func (u *MaskedCallUsecase) callable(ctx context.Context, order *domain.Order) bool {
+	switch order.Status {
+	case domain.OrderStatusOutForDelivery:
+		return true
+	case domain.OrderStatusDelivered:
+		grace := u.cfg.AfterDelivery
+		if u.settings != nil {
+			grace = time.Duration(u.settings.Int(ctx, SettingCallsAfterDeliveryMinutes)) * time.Minute
+		}
+		return order.DeliveredAt != nil && time.Since(*order.DeliveredAt) < grace
+	}
+	return false
+}
+
+// customerPhone is who the rider hands the order to: the gift recipient or
+// the payer
+func (u *MaskedCallUsecase) customerPhone(ctx context.Context, order *domain.Order) (string, error) {
+	if order.IsGift() {
+		return order.Recipient.PhoneNumber, nil
+	}
+	payer, err := u.users.Get(ctx, order.UserID)
+	if err != nil {
+		return "", err
+	}
+	return payer.PhoneNumber, nil
+}
+
+// callbackURL is where the provider posts a call's status, or empty when
+// callbacks aren't configured
+func (u *MaskedCallUsecase) callbackURL(callID uuid.UUID) string {
+	if u.cfg.CallbackURL == "" || u.cfg.CallbackSecret == "" {
+		return ""
+	}
+	return u.cfg.CallbackURL + "/" + callID.String() + "?token=" + u.callbackToken(callID)
+}
+
+// callbackToken authenticates a status callback for callID
+func (u *MaskedCallUsecase) callbackToken(callID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(u.cfg.CallbackSecret))
+	mac.Write([]byte(callID.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
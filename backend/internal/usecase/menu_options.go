@@ -0,0 +1,145 @@
+// Package usecase implements menu item variants and modifiers and the
+// pricing of the choices made from them
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+)
+
+// Menu option errors
+var (
+	ErrInvalidItemOptions = errors.New("pick one of the item's variants if it has any, and only its modifiers, each at most once")
+	ErrInvalidMenuOptions = errors.New("up to 20 variants priced above 0 and 50 modifiers priced 0 or more, each with a unique name of at most 100 characters")
+)
+
+const (
+	// maxItemVariants and maxItemModifiers cap the options of one item
+	maxItemVariants  = 20
+	maxItemModifiers = 50
+)
+
+// orderLine prices one cart line from its menu item: the chosen variant's
+// price, or the item's if it has no variants, plus the chosen modifiers.
+// Returns ErrInvalidItemOptions if the choice doesn't fit the item and
+// ErrItemNotAvailable if a chosen option is switched off.
+func orderLine(menuItem *domain.MenuItem, line domain.CartItem) (domain.OrderItem, error) {
+	item := domain.OrderItem{
+		MenuItemID: menuItem.ID,
+		Name:       menuItem.Name,
+		Price:      menuItem.Price,
+		Quantity:   line.Quantity,
+		Category:   menuItem.Category,
+		ImageURL:   menuItem.ImageURL,
+	}
+
+	switch {
+	case line.VariantID != nil:
+		variant := menuItem.Variant(*line.VariantID)
+		if variant == nil {
+			return item, ErrInvalidItemOptions
+		}
+		if !variant.IsAvailable {
+			return item, ErrItemNotAvailable
+		}
+		item.VariantID = &variant.ID
+		item.VariantName = variant.Name
+		item.Price = variant.Price
+	case menuItem.HasVariants():
+		return item, ErrInvalidItemOptions
+	}
+
+	if len(line.ModifierIDs) > maxItemModifiers || hasDuplicateIDs(line.ModifierIDs) {
+		return item, ErrInvalidItemOptions
+	}
+	for _, id := range line.ModifierIDs {
+		modifier := menuItem.Modifier(id)
+		if modifier == nil {
+			return item, ErrInvalidItemOptions
+		}
+		if !modifier.IsAvailable {
+			return item, ErrItemNotAvailable
+		}
+		item.Price += modifier.Price
+		item.Modifiers = append(item.Modifiers, domain.OrderItemModifier{
+			ID:    modifier.ID,
+			Name:  modifier.Name,
+			Price: modifier.Price,
+		})
+	}
+
+	return item, nil
+}
+
+// SetOptions replaces a menu item's variants and modifiers (admin only).
+// Entries keep their ID when it is given, so carts holding them stay
+// valid; entries left out are removed. Returns the updated item, or
+// repository.ErrNotFound if the item or a given ID doesn't exist.
+func (u *MenuUsecase) SetOptions(ctx context.Context, itemID uuid.UUID, variants []domain.MenuItemVariant, modifiers []domain.MenuItemModifier, adminID uuid.UUID) (*domain.MenuItem, error) {
+	if len(variants) > maxItemVariants || len(modifiers) > maxItemModifiers {
+		return nil, ErrInvalidMenuOptions
+	}
+	names := make([]string, 0, len(variants))
+	ids := make([]uuid.UUID, 0, len(variants))
+	for i := range variants {
+		v := &variants[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Price <= 0 {
+			return nil, ErrInvalidMenuOptions
+		}
+		names = append(names, v.Name)
+		if v.ID != uuid.Nil {
+			ids = append(ids, v.ID)
+		}
+	}
+	if !validOptionNames(names) || hasDuplicateIDs(ids) {
+		return nil, ErrInvalidMenuOptions
+	}
+
+	names = names[:0]
+	ids = ids[:0]
+	for i := range modifiers {
+		m := &modifiers[i]
+		m.Name = strings.TrimSpace(m.Name)
+		if m.Price < 0 {
+			return nil, ErrInvalidMenuOptions
+		}
+		names = append(names, m.Name)
+		if m.ID != uuid.Nil {
+			ids = append(ids, m.ID)
+		}
+	}
+	if !validOptionNames(names) || hasDuplicateIDs(ids) {
+		return nil, ErrInvalidMenuOptions
+	}
+
+	if err := u.menuRepo.SetOptions(ctx, itemID, variants, modifiers); err != nil {
+		return nil, err
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuOptionsUpdated, "menu_item", itemID.String(), map[string]interface{}{
+		"variants":  len(variants),
+		"modifiers": len(modifiers),
+	})
+
+	u.invalidateCache(ctx)
+	return u.GetMenuItem(ctx, itemID)
+}
+
+// validOptionNames reports whether every name is set, at most 100
+// characters and distinct from the others ignoring case
+func validOptionNames(names []string) bool {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if name == "" || len(name) > 100 || seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
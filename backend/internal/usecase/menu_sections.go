@@ -0,0 +1,149 @@
+// Package usecase implements curated menu sections and menu display order
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+)
+
+// Menu section errors
+var (
+	ErrInvalidMenuSection     = errors.New("unknown menu section")
+	ErrMenuSectionItems       = errors.New("a section pins at most 20 items, each once")
+	ErrMenuSectionUnavailable = errors.New("only available menu items can be pinned")
+	ErrMenuOrderItems         = errors.New("item_ids must list each menu item at most once, up to 500")
+)
+
+const (
+	// maxSectionItems caps the items pinned to one section
+	maxSectionItems = 20
+	// maxReorderItems caps the items placed by one reorder request
+	maxReorderItems = 500
+	// autoBestsellers is how many top sellers fill an unpinned Bestsellers
+	// section, counted over bestsellerWindow
+	autoBestsellers  = 10
+	bestsellerWindow = 30 * 24 * time.Hour
+)
+
+// loadSections builds the curated sections from the available items, in
+// pinned order. An unpinned Bestsellers section falls back to recent top
+// sellers; other empty sections are left out. Sections are extras, so a
+// failure to load them is logged and the menu is served without them.
+func (u *MenuUsecase) loadSections(ctx context.Context, items []domain.MenuItem) []domain.MenuSection {
+	sections := []domain.MenuSection{}
+	if u.sections == nil {
+		return sections
+	}
+
+	pinned, err := u.sections.GetPinned(ctx)
+	if err != nil {
+		u.log.Warn("Failed to load menu sections", "error", err)
+		return sections
+	}
+
+	byID := make(map[uuid.UUID]domain.MenuItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	for _, key := range domain.MenuSectionKeys {
+		section := domain.MenuSection{Key: key, Title: key.Title(), Items: []domain.MenuItem{}}
+		for _, id := range pinned[key] {
+			if item, ok := byID[id]; ok {
+				section.Items = append(section.Items, item)
+			}
+		}
+
+		if len(section.Items) == 0 && key == domain.MenuSectionBestsellers {
+			ids, err := u.sections.GetTopSellers(ctx, time.Now().Add(-bestsellerWindow), autoBestsellers)
+			if err != nil {
+				u.log.Warn("Failed to compute bestsellers", "error", err)
+			}
+			for _, id := range ids {
+				if item, ok := byID[id]; ok {
+					section.Items = append(section.Items, item)
+				}
+			}
+			section.Automatic = true
+		}
+
+		if len(section.Items) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// SetSectionItems pins itemIDs to a section in that order, replacing what
+// was pinned. An empty list unpins the section; Bestsellers then goes back
+// to recent top sellers.
+func (u *MenuUsecase) SetSectionItems(ctx context.Context, key domain.MenuSectionKey, itemIDs []uuid.UUID, adminID uuid.UUID) error {
+	if !key.IsValid() || u.sections == nil {
+		return ErrInvalidMenuSection
+	}
+	if len(itemIDs) > maxSectionItems || hasDuplicateIDs(itemIDs) {
+		return ErrMenuSectionItems
+	}
+
+	if len(itemIDs) > 0 {
+		available, err := u.menuRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		ids := make(map[uuid.UUID]bool, len(available))
+		for _, item := range available {
+			ids[item.ID] = true
+		}
+		for _, id := range itemIDs {
+			if !ids[id] {
+				return ErrMenuSectionUnavailable
+			}
+		}
+	}
+
+	if err := u.sections.SetPinned(ctx, key, itemIDs, adminID); err != nil {
+		return err
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuSectionUpdated, "menu_section", string(key), map[string]interface{}{
+		"item_ids": itemIDs,
+	})
+
+	u.invalidateCache(ctx)
+	return nil
+}
+
+// ReorderMenu sets the display order of menu items within their categories
+// to their order in itemIDs. Items not listed keep their place; an unknown
+// ID returns repository.ErrNotFound.
+func (u *MenuUsecase) ReorderMenu(ctx context.Context, itemIDs []uuid.UUID, adminID uuid.UUID) error {
+	if len(itemIDs) == 0 || len(itemIDs) > maxReorderItems || hasDuplicateIDs(itemIDs) {
+		return ErrMenuOrderItems
+	}
+
+	if err := u.menuRepo.SetSortOrder(ctx, itemIDs); err != nil {
+		return err
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuReordered, "menu", "display_order", map[string]interface{}{
+		"items": len(itemIDs),
+	})
+
+	u.invalidateCache(ctx)
+	return nil
+}
+
+// hasDuplicateIDs reports whether any ID appears twice
+func hasDuplicateIDs(ids []uuid.UUID) bool {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
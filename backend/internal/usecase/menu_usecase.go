@@ -3,7 +3,10 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -13,15 +16,33 @@ import (
 	"fooddelivery/pkg/redis"
 )
 
+// Menu errors
+var (
+	ErrMenuAsOfFuture     = errors.New("menu as of a future time")
+	ErrInvalidIngredients = errors.New("ingredients need a name of at most 100 characters and known allergens, up to 50 per item")
+	ErrAvailabilityItems  = errors.New("item_ids must list each menu item at most once, up to 200")
+)
+
+// maxIngredients caps the ingredients listed on one menu item
+const maxIngredients = 50
+
+// maxAvailabilityItems caps the items switched by one availability request
+const maxAvailabilityItems = 200
+
+// menuWarmTimeout bounds a background re-warm after invalidation
+const menuWarmTimeout = 30 * time.Second
+
 // MenuUsecase handles menu-related business logic
 type MenuUsecase struct {
-	menuRepo    *repository.MenuRepository
+	menuRepo    MenuStore
 	redisClient *redis.Client
+	sections    *repository.MenuSectionRepository
+	audit       *AuditUsecase
 	log         *logger.Logger
 }
 
 // NewMenuUsecase creates a new menu usecase
-func NewMenuUsecase(menuRepo *repository.MenuRepository, redisClient *redis.Client, log *logger.Logger) *MenuUsecase {
+func NewMenuUsecase(menuRepo MenuStore, redisClient *redis.Client, log *logger.Logger) *MenuUsecase {
 	return &MenuUsecase{
 		menuRepo:    menuRepo,
 		redisClient: redisClient,
@@ -29,11 +50,22 @@ func NewMenuUsecase(menuRepo *repository.MenuRepository, redisClient *redis.Clie
 	}
 }
 
-// MenuResponse wraps menu items with metadata
+// SetAuditLog records menu edits in the admin audit log
+func (u *MenuUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// SetSectionRepository enables the curated sections shown above the menu
+func (u *MenuUsecase) SetSectionRepository(sections *repository.MenuSectionRepository) {
+	u.sections = sections
+}
+
+// MenuResponse wraps menu items with metadata. Items are in display order.
 type MenuResponse struct {
-	Items      []domain.MenuItem `json:"items"`
-	Categories []string          `json:"categories"`
-	CacheHit   bool              `json:"cache_hit"`
+	Items      []domain.MenuItem    `json:"items"`
+	Categories []string             `json:"categories"`
+	Sections   []domain.MenuSection `json:"sections"`
+	CacheHit   bool                 `json:"cache_hit"`
 }
 
 // GetMenu retrieves the full menu with Redis caching.
@@ -59,39 +91,115 @@ func (u *MenuUsecase) GetMenu(ctx context.Context) (*MenuResponse, error) {
 	u.log.Debug("Menu cache MISS, querying database")
 
 	// Step 2: Query database
+	response, byCategory, err := u.loadMenu(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Cache the response
+	u.cacheMenu(ctx, response, byCategory)
+
+	return response, nil
+}
+
+// loadMenu reads the available items and groups them by category
+func (u *MenuUsecase) loadMenu(ctx context.Context) (*MenuResponse, map[string][]domain.MenuItem, error) {
 	items, err := u.menuRepo.GetAll(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch menu: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch menu: %w", err)
+	}
+	for i := range items {
+		items[i].AllergenWarnings = items[i].ComputeAllergenWarnings()
 	}
 
 	// Extract unique categories
-	categorySet := make(map[string]struct{})
+	byCategory := make(map[string][]domain.MenuItem)
 	for _, item := range items {
-		categorySet[item.Category] = struct{}{}
+		byCategory[item.Category] = append(byCategory[item.Category], item)
 	}
 
-	categories := make([]string, 0, len(categorySet))
-	for cat := range categorySet {
+	categories := make([]string, 0, len(byCategory))
+	for cat := range byCategory {
 		categories = append(categories, cat)
 	}
 
 	response := &MenuResponse{
 		Items:      items,
 		Categories: categories,
+		Sections:   u.loadSections(ctx, items),
 		CacheHit:   false,
 	}
+	return response, byCategory, nil
+}
 
-	// Step 3: Cache the response
-	if u.redisClient != nil {
-		if err := u.redisClient.SetJSON(ctx, redis.MenuCacheKey, response, redis.MenuCacheTTL); err != nil {
-			u.log.Warn("Failed to cache menu", "error", err)
-			// Don't fail - cache is optimization
-		} else {
-			u.log.Debug("Menu cached successfully", "ttl", redis.MenuCacheTTL)
+// cacheMenu stores the full menu and each category's items in one round
+// trip, so every menu key expires together
+func (u *MenuUsecase) cacheMenu(ctx context.Context, response *MenuResponse, byCategory map[string][]domain.MenuItem) {
+	if u.redisClient == nil {
+		return
+	}
+
+	entries := make([]redis.JSONEntry, 0, len(byCategory)+1)
+	entries = append(entries, redis.JSONEntry{Key: redis.MenuCacheKey, Value: response, TTL: redis.MenuCacheTTL})
+	for cat, items := range byCategory {
+		entries = append(entries, redis.JSONEntry{Key: redis.MenuCategoryCachePrefix + cat, Value: items, TTL: redis.MenuCacheTTL})
+	}
+
+	if err := u.redisClient.SetJSONMany(ctx, entries...); err != nil {
+		u.log.Warn("Failed to cache menu", "error", err)
+		// Don't fail - cache is optimization
+	} else {
+		u.log.Debug("Menu cached successfully", "ttl", redis.MenuCacheTTL, "categories", len(byCategory))
+	}
+}
+
+// WarmCache fills the menu caches ahead of traffic, at startup and after
+// every invalidation. A Redis lock makes one instance do the work when
+// several start or invalidate at once; the others return immediately.
+func (u *MenuUsecase) WarmCache(ctx context.Context) error {
+	if u.redisClient == nil {
+		return nil
+	}
+
+	lock, err := u.redisClient.TryLock(ctx, redis.MenuWarmLockKey, redis.MenuWarmLockTTL)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		u.log.Debug("Menu cache warming already in progress elsewhere")
+		return nil
+	}
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			u.log.Warn("Failed to release menu warm lock", "error", err)
 		}
+	}()
+
+	start := time.Now()
+	response, byCategory, err := u.loadMenu(ctx)
+	if err != nil {
+		return err
 	}
+	u.cacheMenu(ctx, response, byCategory)
 
-	return response, nil
+	u.log.Info("Menu cache warmed",
+		"items", len(response.Items),
+		"categories", len(byCategory),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+// rewarm warms the caches in the background after an invalidation, so the
+// next customer request does not pay for the cold read
+func (u *MenuUsecase) rewarm() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), menuWarmTimeout)
+		defer cancel()
+		if err := u.WarmCache(ctx); err != nil {
+			u.log.Warn("Failed to re-warm menu cache", "error", err)
+		}
+	}()
 }
 
 // GetMenuItem retrieves a single menu item by ID
@@ -100,14 +208,25 @@ func (u *MenuUsecase) GetMenuItem(ctx context.Context, id uuid.UUID) (*domain.Me
 	if err != nil {
 		return nil, err
 	}
+	item.AllergenWarnings = item.ComputeAllergenWarnings()
 	return item, nil
 }
 
 // CreateMenuItem creates a new menu item (admin only)
-func (u *MenuUsecase) CreateMenuItem(ctx context.Context, item *domain.MenuItem) error {
-	if err := u.menuRepo.Create(ctx, item); err != nil {
+func (u *MenuUsecase) CreateMenuItem(ctx context.Context, item *domain.MenuItem, adminID uuid.UUID) error {
+	if err := normalizeIngredients(item); err != nil {
+		return err
+	}
+	if err := validStock(item.StockQuantity); err != nil {
+		return err
+	}
+	if err := u.menuRepo.Create(ctx, item, adminID); err != nil {
 		return fmt.Errorf("failed to create menu item: %w", err)
 	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuItemCreated, "menu_item", item.ID.String(), map[string]interface{}{
+		"name":  item.Name,
+		"price": item.Price,
+	})
 
 	// Invalidate cache after creation
 	u.invalidateCache(ctx)
@@ -115,11 +234,23 @@ func (u *MenuUsecase) CreateMenuItem(ctx context.Context, item *domain.MenuItem)
 	return nil
 }
 
-// UpdateMenuItem updates an existing menu item (admin only)
-func (u *MenuUsecase) UpdateMenuItem(ctx context.Context, item *domain.MenuItem) error {
-	if err := u.menuRepo.Update(ctx, item); err != nil {
+// UpdateMenuItem updates an existing menu item (admin only). item.Version
+// must be the version the admin edited; a stale one returns
+// repository.ErrVersionConflict. Price changes are kept in the item's
+// price history.
+func (u *MenuUsecase) UpdateMenuItem(ctx context.Context, item *domain.MenuItem, adminID uuid.UUID) error {
+	if err := normalizeIngredients(item); err != nil {
+		return err
+	}
+	if err := u.menuRepo.Update(ctx, item, adminID); err != nil {
 		return err
 	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuItemUpdated, "menu_item", item.ID.String(), map[string]interface{}{
+		"name":         item.Name,
+		"price":        item.Price,
+		"is_available": item.IsAvailable,
+		"version":      item.Version,
+	})
 
 	// Invalidate cache after update
 	u.invalidateCache(ctx)
@@ -127,11 +258,13 @@ func (u *MenuUsecase) UpdateMenuItem(ctx context.Context, item *domain.MenuItem)
 	return nil
 }
 
-// DeleteMenuItem soft-deletes a menu item (admin only)
-func (u *MenuUsecase) DeleteMenuItem(ctx context.Context, id uuid.UUID) error {
-	if err := u.menuRepo.Delete(ctx, id); err != nil {
+// DeleteMenuItem soft-deletes a menu item (admin only) if it is still at
+// expectedVersion
+func (u *MenuUsecase) DeleteMenuItem(ctx context.Context, id uuid.UUID, expectedVersion int, adminID uuid.UUID) error {
+	if err := u.menuRepo.Delete(ctx, id, expectedVersion, adminID); err != nil {
 		return err
 	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuItemDeleted, "menu_item", id.String(), nil)
 
 	// Invalidate cache after deletion
 	u.invalidateCache(ctx)
@@ -139,6 +272,75 @@ func (u *MenuUsecase) DeleteMenuItem(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// SetAvailability switches many items on or off at once, e.g. when an
+// ingredient runs out. All items change or none do; an unknown ID returns
+// repository.ErrNotFound. Returns the IDs of the items that changed.
+func (u *MenuUsecase) SetAvailability(ctx context.Context, itemIDs []uuid.UUID, available bool, adminID uuid.UUID) ([]uuid.UUID, error) {
+	if len(itemIDs) == 0 || len(itemIDs) > maxAvailabilityItems || hasDuplicateIDs(itemIDs) {
+		return nil, ErrAvailabilityItems
+	}
+
+	changed, err := u.menuRepo.SetAvailability(ctx, itemIDs, available, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return changed, nil
+	}
+	u.audit.Record(ctx, adminID, domain.AuditMenuAvailability, "menu", "availability", map[string]interface{}{
+		"is_available": available,
+		"item_ids":     changed,
+	})
+
+	u.invalidateCache(ctx)
+	return changed, nil
+}
+
+// normalizeIngredients trims and checks an item's ingredients and computes
+// its allergen warnings
+func normalizeIngredients(item *domain.MenuItem) error {
+	if len(item.Ingredients) > maxIngredients {
+		return ErrInvalidIngredients
+	}
+	for i := range item.Ingredients {
+		ing := &item.Ingredients[i]
+		ing.Name = strings.TrimSpace(ing.Name)
+		if ing.Name == "" || len(ing.Name) > 100 {
+			return ErrInvalidIngredients
+		}
+		for _, allergens := range [][]domain.Allergen{ing.Allergens, ing.MayContain} {
+			for _, a := range allergens {
+				if !a.IsValid() {
+					return ErrInvalidIngredients
+				}
+			}
+		}
+	}
+	item.AllergenWarnings = item.ComputeAllergenWarnings()
+	return nil
+}
+
+// GetMenuAsOf reconstructs the menu as it stood at a point in time, for
+// settling disputes about what was offered and at what price
+func (u *MenuUsecase) GetMenuAsOf(ctx context.Context, at time.Time) (*domain.MenuSnapshot, error) {
+	if at.After(time.Now()) {
+		return nil, ErrMenuAsOfFuture
+	}
+	items, err := u.menuRepo.GetMenuAsOf(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MenuSnapshot{AsOf: at, Items: items}, nil
+}
+
+// GetPriceHistory returns a menu item's price timeline, oldest first
+func (u *MenuUsecase) GetPriceHistory(ctx context.Context, id uuid.UUID) ([]domain.MenuPriceChange, error) {
+	if _, err := u.menuRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return u.menuRepo.GetPriceHistory(ctx, id)
+}
+
 // InvalidateMenuCache explicitly invalidates the menu cache.
 // Called by admin endpoint POST /admin/menu/invalidate-cache
 func (u *MenuUsecase) InvalidateMenuCache(ctx context.Context) error {
@@ -146,24 +348,53 @@ func (u *MenuUsecase) InvalidateMenuCache(ctx context.Context) error {
 	return nil
 }
 
-// invalidateCache removes the menu cache from Redis
+// invalidateCache removes the full and per-category menu caches from Redis,
+// then re-warms them
 func (u *MenuUsecase) invalidateCache(ctx context.Context) {
 	if u.redisClient == nil {
 		return
 	}
 
-	if err := u.redisClient.DeleteKey(ctx, redis.MenuCacheKey); err != nil {
+	// The cached menu lists the categories cached alongside it
+	keys := []string{redis.MenuCacheKey}
+	var cached MenuResponse
+	if found, err := u.redisClient.GetJSON(ctx, redis.MenuCacheKey, &cached); err == nil && found {
+		for _, cat := range cached.Categories {
+			keys = append(keys, redis.MenuCategoryCachePrefix+cat)
+		}
+	}
+
+	if err := u.redisClient.DeleteKeys(ctx, keys...); err != nil {
 		u.log.Warn("Failed to invalidate menu cache", "error", err)
 	} else {
 		u.log.Info("Menu cache invalidated")
 	}
+
+	u.rewarm()
 }
 
-// GetMenuByCategory retrieves menu items filtered by category
+// GetMenuByCategory retrieves available menu items in a category, cached
+// alongside the full menu
 func (u *MenuUsecase) GetMenuByCategory(ctx context.Context, category string) ([]domain.MenuItem, error) {
-	items, err := u.menuRepo.GetByCategory(ctx, category)
+	if u.redisClient != nil {
+		var cached []domain.MenuItem
+		found, err := u.redisClient.GetJSON(ctx, redis.MenuCategoryCachePrefix+category, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read menu category from cache", "error", err)
+		} else if found {
+			return cached, nil
+		}
+	}
+
+	response, byCategory, err := u.loadMenu(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch menu by category: %w", err)
+		return nil, err
+	}
+	u.cacheMenu(ctx, response, byCategory)
+
+	items := byCategory[category]
+	if items == nil {
+		items = []domain.MenuItem{}
 	}
 	return items, nil
 }
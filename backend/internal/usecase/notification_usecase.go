@@ -0,0 +1,266 @@
+// Package usecase implements order notifications routed to the payer or a
+// gift recipient
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/sms"
+)
+
+// notifyTimeout bounds a background notification, retries included
+const notifyTimeout = 30 * time.Second
+
+// chatPreviewLength is how many characters of a chat message a push shows
+const chatPreviewLength = 80
+
+// NotificationUsecase tells customers about their orders. Payment receipts
+// always go to the payer; delivery updates for gift orders go to the
+// recipient by SMS instead. Notifications are sent in the background and
+// failures are only logged, so they never hold up the order flow.
+type NotificationUsecase struct {
+	templates *TemplateUsecase
+	push      push.Sender
+	sms       sms.Sender
+	users     *UserCache
+	emails    *EmailUsecase
+	log       *logger.Logger
+}
+
+// NewNotificationUsecase creates a new notification usecase
+func NewNotificationUsecase(templates *TemplateUsecase, pushSender push.Sender, userRepo *repository.UserRepository, log *logger.Logger) *NotificationUsecase {
+	return &NotificationUsecase{
+		templates: templates,
+		push:      pushSender,
+		users:     NewUserCache(userRepo, nil, log),
+		log:       log,
+	}
+}
+
+// SetSMSSender enables text messages to gift recipients. Without it their
+// updates are logged and dropped.
+func (u *NotificationUsecase) SetSMSSender(sender sms.Sender) {
+	u.sms = sender
+}
+
+// SetEmails enables the order confirmation and receipt emails
+func (u *NotificationUsecase) SetEmails(emails *EmailUsecase) {
+	u.emails = emails
+}
+
+// SetUserCache shares a Redis-backed user cache for payer lookups
+func (u *NotificationUsecase) SetUserCache(cache *UserCache) {
+	u.users = cache
+}
+
+// PaymentReceived sends the payer a receipt for a paid order, and queues
+// the order confirmation email
+func (u *NotificationUsecase) PaymentReceived(order *domain.Order) {
+	if u == nil {
+		return
+	}
+	u.queueEmail(order, TemplateOrderConfirmation)
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.pushTemplate(ctx, order.UserID, TemplatePaymentReceipt, "payment_receipt", order.ID, map[string]any{
+			"order_number": orderNumber(order.ID),
+			"amount":       formatRupees(order.TotalAmount),
+		})
+	})
+}
+
+// OutForDelivery announces a dispatched order. Gift recipients get the
+// rider's contact and the hand-over OTP by SMS, since they don't have the
+// payer's app.
+func (u *NotificationUsecase) OutForDelivery(order *domain.Order, rider *domain.User) {
+	if u == nil {
+		return
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		if !order.IsGift() {
+			return u.pushTemplate(ctx, order.UserID, TemplateOrderOutForDelivery, "order_out_for_delivery", order.ID, map[string]any{
+				"order_number": orderNumber(order.ID),
+				"rider_name":   rider.Name,
+			})
+		}
+
+		senderName := "Someone"
+		if payer, err := u.users.Get(ctx, order.UserID); err == nil && payer.Name != "" {
+			senderName = payer.Name
+		}
+		return u.smsTemplate(ctx, order.Recipient.PhoneNumber, TemplateGiftOutForDelivery, order.ID, map[string]any{
+			"recipient_name": order.Recipient.Name,
+			"sender_name":    senderName,
+			"rider_name":     rider.Name,
+			"rider_phone":    rider.PhoneNumber,
+			"otp":            order.DeliveryOTP,
+		})
+	})
+}
+
+// OrderAccepted tells the payer the kitchen has started on their order
+func (u *NotificationUsecase) OrderAccepted(order *domain.Order) {
+	if u == nil {
+		return
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.pushTemplate(ctx, order.UserID, TemplateOrderAccepted, "order_accepted", order.ID, map[string]any{
+			"order_number": orderNumber(order.ID),
+		})
+	})
+}
+
+// Delivered queues the payer's receipt email and tells them an order was
+// handed over at the door; for a gift, that the recipient has it. Pickup
+// customers collected the order themselves and get no push.
+func (u *NotificationUsecase) Delivered(order *domain.Order) {
+	if u == nil {
+		return
+	}
+	u.queueEmail(order, TemplateOrderReceipt)
+	if order.FulfillmentType == domain.FulfillmentPickup {
+		return
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		if !order.IsGift() {
+			return u.pushTemplate(ctx, order.UserID, TemplateOrderDelivered, "order_delivered", order.ID, map[string]any{
+				"order_number": orderNumber(order.ID),
+			})
+		}
+		return u.pushTemplate(ctx, order.UserID, TemplateGiftDelivered, "gift_delivered", order.ID, map[string]any{
+			"order_number":   orderNumber(order.ID),
+			"recipient_name": order.Recipient.Name,
+		})
+	})
+}
+
+// ItemsUnavailable asks the payer to accept a refund for items the kitchen
+// ran out of, or cancel the order
+func (u *NotificationUsecase) ItemsUnavailable(order *domain.Order, shortage *domain.ItemShortage) {
+	if u == nil {
+		return
+	}
+	items := make([]string, 0, len(shortage.Items))
+	for _, item := range shortage.Items {
+		items = append(items, fmt.Sprintf("%d x %s", item.Quantity, item.Name))
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.pushTemplate(ctx, order.UserID, TemplateItemsUnavailable, "order_items_unavailable", order.ID, map[string]any{
+			"order_number":    orderNumber(order.ID),
+			"items":           strings.Join(items, ", "),
+			"refund":          formatRupees(shortage.RefundAmount),
+			"respond_minutes": int(math.Ceil(time.Until(shortage.RespondBy).Minutes())),
+		})
+	})
+}
+
+// OrderCancelled confirms a cancellation and its refund to the payer
+func (u *NotificationUsecase) OrderCancelled(order *domain.Order, refund int64) {
+	if u == nil {
+		return
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.pushTemplate(ctx, order.UserID, TemplateOrderCancelled, "order_cancelled", order.ID, map[string]any{
+			"order_number": orderNumber(order.ID),
+			"refund":       formatRupees(refund),
+		})
+	})
+}
+
+// ChatMessage tells recipientID about a new message in an order's chat
+func (u *NotificationUsecase) ChatMessage(order *domain.Order, recipientID uuid.UUID, message *domain.OrderMessage) {
+	if u == nil {
+		return
+	}
+	sender := map[domain.ChatRole]string{
+		domain.ChatRoleCustomer: "Customer",
+		domain.ChatRoleRider:    "Rider",
+		domain.ChatRoleSupport:  "Support",
+	}[message.SenderRole]
+	preview := message.Body
+	if runes := []rune(preview); len(runes) > chatPreviewLength {
+		preview = string(runes[:chatPreviewLength]) + "…"
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.pushTemplate(ctx, recipientID, TemplateOrderChatMessage, "order_chat_message", order.ID, map[string]any{
+			"order_number": orderNumber(order.ID),
+			"sender":       sender,
+			"preview":      preview,
+		})
+	})
+}
+
+// queueEmail queues an order email for the email worker
+func (u *NotificationUsecase) queueEmail(order *domain.Order, key string) {
+	if u.emails == nil {
+		return
+	}
+	u.background(order.ID, func(ctx context.Context) error {
+		return u.emails.Enqueue(ctx, key, order)
+	})
+}
+
+// background runs send detached from the request that triggered it
+func (u *NotificationUsecase) background(orderID uuid.UUID, send func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			u.log.Warn("Failed to send order notification", "order_id", orderID.String(), "error", err)
+		}
+	}()
+}
+
+// pushTemplate renders a push template in the user's language and sends it
+// to their devices
+func (u *NotificationUsecase) pushTemplate(ctx context.Context, userID uuid.UUID, key, kind string, orderID uuid.UUID, vars map[string]any) error {
+	msg, err := u.templates.RenderForUser(ctx, key, userID, vars)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", key, err)
+	}
+	return u.push.Send(ctx, userID, push.Message{
+		Title: msg.Subject,
+		Body:  msg.Body,
+		Data: map[string]string{
+			"type":     kind,
+			"order_id": orderID.String(),
+		},
+	})
+}
+
+// smsTemplate renders an SMS template in the restaurant locale and texts it
+// to phoneNumber, who need not have an account
+func (u *NotificationUsecase) smsTemplate(ctx context.Context, phoneNumber, key string, orderID uuid.UUID, vars map[string]any) error {
+	if u.sms == nil {
+		u.log.Warn("No SMS sender configured, recipient not notified", "order_id", orderID.String(), "template", key)
+		return nil
+	}
+	msg, err := u.templates.Render(ctx, key, "", vars)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", key, err)
+	}
+	return u.sms.Send(ctx, phoneNumber, msg.Body)
+}
+
+// orderNumber is the short order reference shown to customers
+func orderNumber(id uuid.UUID) string {
+	return id.String()[:8]
+}
+
+// formatRupees renders an amount in paisa as e.g. "₹245.00"
+func formatRupees(paisa int64) string {
+	sign := ""
+	if paisa < 0 {
+		sign, paisa = "-", -paisa
+	}
+	return fmt.Sprintf("%s₹%d.%02d", sign, paisa/100, paisa%100)
+}
@@ -0,0 +1,192 @@
+// Package usecase implements order cancellation and its refund
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Order cancellation errors
+var (
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+	ErrOrderCancelled      = errors.New("order was cancelled")
+	ErrCancellationReason  = errors.New("cancellation reason is too long")
+)
+
+// Cancellation limits
+const (
+	maxCancellationReasonLength = 500
+	cancelAttempts              = 3 // Reloads when the order changes mid-cancel
+)
+
+// CancelOrderResult is a cancelled order and what is refunded for it
+type CancelOrderResult struct {
+	Order         *domain.Order `json:"order"`
+	RefundAmount  int64         `json:"refund_amount"` // Paisa; 0 when nothing was paid
+	RefundID      string        `json:"refund_id,omitempty"`
+	RefundPending bool          `json:"refund_pending,omitempty"` // The automatic refund failed; support refunds by hand
+}
+
+// isCancellable reports whether an order can still be cancelled: until the
+// kitchen accepts it
+func isCancellable(status domain.OrderStatus) bool {
+	switch status {
+	case domain.OrderStatusPending, domain.OrderStatusAwaitingPayment, domain.OrderStatusPaid:
+		return true
+	}
+	return false
+}
+
+// CancelOrder cancels an order the kitchen hasn't accepted yet and refunds
+// it in full if it was paid. Customers can only cancel their own orders;
+// with asAdmin any order can be cancelled and the cancellation is audited.
+// A failed refund doesn't undo the cancellation; admins are alerted to
+// refund by hand.
+func (u *OrderUsecase) CancelOrder(ctx context.Context, actorID, orderID uuid.UUID, asAdmin bool, reason string) (*CancelOrderResult, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxCancellationReasonLength {
+		return nil, ErrCancellationReason
+	}
+
+	var order *domain.Order
+	for attempt := 0; ; attempt++ {
+		var err error
+		order, err = u.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if !asAdmin && order.UserID != actorID {
+			return nil, repository.ErrNotFound
+		}
+		if !isCancellable(order.Status) {
+			return nil, ErrOrderNotCancellable
+		}
+		// A captured payment under review is settled by an admin first
+		if order.PaymentReview != nil && order.PaymentReview.ResolvedAt == nil {
+			return nil, ErrOrderNotCancellable
+		}
+
+		err = u.orderRepo.Cancel(ctx, order.ID, order.Version, &actorID, reason)
+		if err == nil {
+			break
+		}
+		// Paid or accepted since it was loaded; look again
+		if !errors.Is(err, repository.ErrVersionConflict) || attempt+1 == cancelAttempts {
+			return nil, err
+		}
+	}
+
+	previous := order.Status
+	order.Status = domain.OrderStatusCancelled
+	order.Version++
+
+	result := &CancelOrderResult{Order: order}
+	if u.paymentUsecase != nil {
+		result.RefundAmount, result.RefundID, result.RefundPending = u.paymentUsecase.refundCancelledOrder(ctx, order, previous)
+	}
+
+	if asAdmin {
+		u.audit.Record(ctx, actorID, domain.AuditOrderCancelled, "order", order.ID.String(), map[string]interface{}{
+			"previous_status": previous,
+			"reason":          reason,
+			"refund_amount":   result.RefundAmount,
+		})
+	}
+	u.log.Info("Order cancelled",
+		"order_id", order.ID.String(),
+		"previous_status", previous,
+		"by_admin", asAdmin,
+		"refund_amount", result.RefundAmount,
+	)
+
+	if updated, err := u.orderRepo.GetByID(ctx, order.ID); err == nil {
+		result.Order = updated
+	}
+	return result, nil
+}
+
+// refundCancelledOrder gives back a cancelled order's kitchen slot and
+// stock and, if it was paid, refunds it in full and tells the payer. A
+// failed refund alerts admins and is reported as pending.
+func (u *PaymentUsecase) refundCancelledOrder(ctx context.Context, order *domain.Order, previous domain.OrderStatus) (amount int64, refundID string, pending bool) {
+	u.releaseSlot(ctx, order)
+	u.restoreStock(ctx, order)
+	if previous != domain.OrderStatusPaid {
+		return 0, "", false
+	}
+
+	// Only what partial refunds haven't already returned
+	amount = order.TotalAmount
+	if refunded, err := u.refunds.RefundedAmount(ctx, order.RazorpayPaymentID); err != nil {
+		u.log.Warn("Failed to total earlier refunds", "order_id", order.ID.String(), "error", err)
+	} else {
+		amount -= refunded
+	}
+	if amount <= 0 {
+		u.notifications.OrderCancelled(order, order.TotalAmount) // All of it was refunded already
+		return 0, "", false
+	}
+
+	refundID, err := u.refund(ctx, order, amount, "refund.order_cancelled", statusPtr(previous), statusPtr(domain.OrderStatusCancelled))
+	if err != nil {
+		u.log.Error("Cancellation refund failed", "order_id", order.ID.String(), "amount", amount, "error", err)
+		u.raiseRefundFailed(ctx, order.ID, amount, "after the order was cancelled")
+		pending = true
+	}
+
+	u.notifications.OrderCancelled(order, amount)
+	return amount, refundID, pending
+}
+
+// refundLateCapture refunds a payment captured after its order was
+// cancelled (the customer paid in a checkout left open). Redelivered
+// webhooks for the same payment don't refund it again.
+func (u *PaymentUsecase) refundLateCapture(ctx context.Context, order *domain.Order, paymentID string, amount int64, log *logger.Logger) error {
+	claimed, err := u.orderRepo.ClaimLateCapture(ctx, order.ID, paymentID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		log.Info("Late capture on cancelled order already handled")
+		return nil
+	}
+
+	order.RazorpayPaymentID = paymentID
+	refund := &domain.Refund{
+		OrderID:           order.ID,
+		RazorpayPaymentID: paymentID,
+		Amount:            amount,
+		Reason:            "refund.captured_after_cancel",
+	}
+	if err := u.issueRefund(ctx, order, refund, amount, refund.Reason, statusPtr(domain.OrderStatusCancelled), nil); err != nil {
+		log.Error("Late capture refund failed", "error", err)
+		u.raiseRefundFailed(ctx, order.ID, amount, "captured after the order was cancelled")
+		return nil
+	}
+
+	log.Info("Payment captured after cancellation refunded")
+	return nil
+}
+
+// raiseRefundFailed asks admins to refund an order from the Razorpay dashboard
+func (u *PaymentUsecase) raiseRefundFailed(ctx context.Context, orderID uuid.UUID, amount int64, when string) {
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertRefundFailed,
+		Critical: true,
+		Title:    "Refund failed for order " + orderNumber(orderID),
+		Body:     fmt.Sprintf("%s could not be refunded automatically %s; refund it from the Razorpay dashboard.", formatRupees(amount), when),
+		Data: map[string]string{
+			"type":     "refund_failed",
+			"order_id": orderID.String(),
+		},
+	})
+}
@@ -0,0 +1,167 @@
+// Package usecase implements the chat between an order's customer, its
+// rider and support
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Order chat errors
+var (
+	ErrChatClosed         = errors.New("chat is only open while the order is being prepared or delivered")
+	ErrInvalidChatMessage = errors.New("message must be 1-1000 characters")
+)
+
+const (
+	// maxChatMessageLength matches the order_messages body check
+	maxChatMessageLength = 1000
+
+	// chatPageSize bounds the messages one read returns
+	chatPageSize = 200
+)
+
+// chatPhoneNumber matches phone-number-like runs of digits, allowing the
+// spaces, dashes and dots people type between them
+var chatPhoneNumber = regexp.MustCompile(`\+?\d[\d\s.\-()]{8,}\d`)
+
+// OrderChatUsecase runs each order's chat. Customers and riders talk through
+// the app rather than by phone: senders are shown by role and numbers typed
+// into messages are masked, so neither side sees the other's number.
+type OrderChatUsecase struct {
+	messageRepo   *repository.OrderMessageRepository
+	orderRepo     *repository.OrderRepository
+	notifications *NotificationUsecase
+	log           *logger.Logger
+}
+
+// NewOrderChatUsecase creates a new order chat usecase
+func NewOrderChatUsecase(
+	messageRepo *repository.OrderMessageRepository,
+	orderRepo *repository.OrderRepository,
+	notifications *NotificationUsecase,
+	log *logger.Logger,
+) *OrderChatUsecase {
+	return &OrderChatUsecase{
+		messageRepo:   messageRepo,
+		orderRepo:     orderRepo,
+		notifications: notifications,
+		log:           log,
+	}
+}
+
+// SendChatRequest is a new chat message
+type SendChatRequest struct {
+	Body string `json:"body"`
+}
+
+// Send posts a message to an order's chat and notifies the other side.
+// Customers and riders can write while the order is being prepared or
+// delivered; support can write at any time.
+func (u *OrderChatUsecase) Send(ctx context.Context, orderID, senderID uuid.UUID, role domain.ChatRole, req SendChatRequest) (*domain.OrderMessage, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" || utf8.RuneCountInString(body) > maxChatMessageLength {
+		return nil, ErrInvalidChatMessage
+	}
+
+	order, err := u.authorize(ctx, orderID, senderID, role)
+	if err != nil {
+		return nil, err
+	}
+	if role != domain.ChatRoleSupport && !chatOpen(order.Status) {
+		return nil, ErrChatClosed
+	}
+
+	message := &domain.OrderMessage{
+		OrderID:    orderID,
+		SenderID:   senderID,
+		SenderRole: role,
+		Body:       maskPhoneNumbers(body),
+	}
+	if err := u.messageRepo.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	for _, recipientID := range chatRecipients(order, role) {
+		u.notifications.ChatMessage(order, recipientID, message)
+	}
+
+	u.log.Info("Order chat message sent", "order_id", orderID.String(), "message_id", message.ID.String(), "sender_role", string(role))
+	return message, nil
+}
+
+// List returns an order's chat, oldest first. With after set, only newer
+// messages are returned, so apps can poll with the last one they have.
+func (u *OrderChatUsecase) List(ctx context.Context, orderID, viewerID uuid.UUID, role domain.ChatRole, after *time.Time) ([]domain.OrderMessage, error) {
+	if _, err := u.authorize(ctx, orderID, viewerID, role); err != nil {
+		return nil, err
+	}
+	return u.messageRepo.List(ctx, orderID, after, chatPageSize)
+}
+
+// authorize loads the order and checks userID may use its chat as role.
+// Other customers' orders read as not found.
+func (u *OrderChatUsecase) authorize(ctx context.Context, orderID, userID uuid.UUID, role domain.ChatRole) (*domain.Order, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch role {
+	case domain.ChatRoleCustomer:
+		if order.UserID != userID {
+			return nil, repository.ErrNotFound
+		}
+	case domain.ChatRoleRider:
+		if order.RiderID == nil || *order.RiderID != userID {
+			return nil, ErrNotAssignedRider
+		}
+	}
+	return order, nil
+}
+
+// chatOpen reports whether customers and riders can still write about an
+// order in status
+func chatOpen(status domain.OrderStatus) bool {
+	return inKitchen(status) || status == domain.OrderStatusOutForDelivery
+}
+
+// chatRecipients lists who to notify about a message sent by role. Support
+// reads chats in the admin panel, so only customers and riders are pushed.
+func chatRecipients(order *domain.Order, role domain.ChatRole) []uuid.UUID {
+	var recipients []uuid.UUID
+	if role != domain.ChatRoleCustomer {
+		recipients = append(recipients, order.UserID)
+	}
+	if role != domain.ChatRoleRider && order.RiderID != nil && order.Status == domain.OrderStatusOutForDelivery {
+		recipients = append(recipients, *order.RiderID)
+	}
+	return recipients
+}
+
+// maskPhoneNumbers hides anything in body with 10 or more digits, so phone
+// numbers aren't passed on through the chat
+func maskPhoneNumbers(body string) string {
+	return chatPhoneNumber.ReplaceAllStringFunc(body, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < 10 {
+			return match
+		}
+		return "[number hidden]"
+	})
+}
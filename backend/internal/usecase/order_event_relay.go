@@ -0,0 +1,195 @@
+// Package usecase implements relaying the order events outbox to the
+// configured sink
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/events"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/retry"
+)
+
+// Relay batch sizes
+const (
+	orderEventBatch       = 100
+	orderEventBatchesPass = 20 // Batches per pass, so a pass fits in the lock's TTL
+)
+
+var (
+	orderEventsPublished = metrics.NewCounterVec(
+		"order_events_published_total",
+		"Order events relayed from the outbox, by outcome",
+		"result",
+	)
+	orderEventsPending = metrics.NewGaugeVec(
+		"order_events_pending",
+		"Order events in the outbox waiting to be published",
+	)
+)
+
+// OrderEventRelay publishes the order events outbox to a sink. Only one
+// instance relays at a time and a failed batch is retried before anything
+// after it, so events reach the sink in the order they were written,
+// at least once.
+type OrderEventRelay struct {
+	repo        *repository.OrderEventRepository
+	publisher   events.Publisher
+	live        events.Publisher
+	redisClient *redis.Client
+	policy      retry.Policy
+	alerts      *AdminAlertUsecase
+	log         *logger.Logger
+
+	failures    int // Consecutive failed batches
+	nextAttempt time.Time
+}
+
+// NewOrderEventRelay creates a relay. policy sets the backoff between
+// failed batches; after MaxAttempts of them in a row admins are alerted,
+// and the relay keeps retrying at MaxBackoff.
+func NewOrderEventRelay(repo *repository.OrderEventRepository, publisher events.Publisher, redisClient *redis.Client, policy retry.Policy, log *logger.Logger) *OrderEventRelay {
+	return &OrderEventRelay{
+		repo:        repo,
+		publisher:   publisher,
+		redisClient: redisClient,
+		policy:      policy,
+		log:         log.WithFields(map[string]interface{}{"source": "order_events"}),
+	}
+}
+
+// SetAlerts alerts admins when the sink keeps failing
+func (u *OrderEventRelay) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// SetLive also sends published events to a live feed, such as the channel
+// the WebSocket hubs listen on. It is best effort: a failure there is
+// logged and doesn't hold the outbox back.
+func (u *OrderEventRelay) SetLive(live events.Publisher) {
+	u.live = live
+}
+
+// Run relays pending events every interval until ctx is cancelled
+func (u *OrderEventRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Now().Before(u.nextAttempt) {
+				continue
+			}
+			u.relayLocked(ctx)
+		}
+	}
+}
+
+// relayLocked runs one pass unless another instance is already relaying
+func (u *OrderEventRelay) relayLocked(ctx context.Context) {
+	if u.redisClient != nil {
+		lock, err := u.redisClient.TryLock(ctx, redis.OrderEventRelayLockKey, redis.OrderEventRelayLockTTL)
+		if err != nil {
+			u.log.Warn("Failed to take order event relay lock", "error", err)
+			return
+		}
+		if lock == nil {
+			return
+		}
+		defer func() {
+			if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+				u.log.Warn("Failed to release order event relay lock", "error", err)
+			}
+		}()
+	}
+
+	for range orderEventBatchesPass {
+		n, err := u.relayBatch(ctx)
+		if err != nil {
+			u.backOff(ctx, err)
+			break
+		}
+		u.failures = 0
+		if n < orderEventBatch {
+			break
+		}
+	}
+
+	if pending, err := u.repo.CountUnpublished(ctx); err == nil {
+		orderEventsPending.Set(float64(pending))
+	}
+}
+
+// relayBatch publishes the oldest unpublished events and returns how many
+// there were
+func (u *OrderEventRelay) relayBatch(ctx context.Context) (int, error) {
+	pending, err := u.repo.ListUnpublished(ctx, orderEventBatch)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	batch := make([]events.Event, len(pending))
+	ids := make([]int64, len(pending))
+	for i, e := range pending {
+		ids[i] = e.ID
+		batch[i] = events.Event{
+			ID:         strconv.FormatInt(e.ID, 10),
+			Type:       string(e.Type),
+			Key:        e.OrderID.String(),
+			Payload:    e.Payload,
+			OccurredAt: e.CreatedAt,
+		}
+	}
+
+	if err := u.publisher.Publish(ctx, batch); err != nil {
+		orderEventsPublished.Add(float64(len(batch)), "failed")
+		if recordErr := u.repo.RecordFailure(ctx, ids, err.Error()); recordErr != nil {
+			u.log.Error("Failed to record order event failure", "error", recordErr)
+		}
+		return 0, err
+	}
+	orderEventsPublished.Add(float64(len(batch)), "published")
+
+	// Publishing again after this fails is safe: delivery is at least once
+	if err := u.repo.MarkPublished(ctx, ids); err != nil {
+		return 0, err
+	}
+
+	if u.live != nil {
+		if err := u.live.Publish(ctx, batch); err != nil {
+			u.log.Warn("Failed to send order events to the live feed", "error", err, "events", len(batch))
+		}
+	}
+	return len(batch), nil
+}
+
+// backOff delays the next pass after a failed batch and alerts admins
+// once the failures reach the policy's attempt limit
+func (u *OrderEventRelay) backOff(ctx context.Context, cause error) {
+	u.failures++
+	wait := u.policy.Backoff(u.failures)
+	u.nextAttempt = time.Now().Add(wait)
+	u.log.Error("Failed to relay order events", "error", cause, "failures", u.failures, "retry_in", wait.String())
+
+	if u.failures != u.policy.MaxAttempts {
+		return
+	}
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertOrderEventsStalled,
+		Critical: true,
+		Title:    "Order events are not being published",
+		Body:     fmt.Sprintf("Publishing order events failed %d times in a row; downstream systems are missing status updates: %v", u.failures, cause),
+		Data: map[string]string{
+			"type": "order_events_stalled",
+		},
+	})
+}
@@ -0,0 +1,55 @@
+// Package usecase implements streamed order exports for admins
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// maxExportRange bounds one export so a single request can't walk the
+// whole order history
+const maxExportRange = 92 * 24 * time.Hour
+
+var (
+	// ErrInvalidExportRange is returned when from/to are missing, reversed or too far apart
+	ErrInvalidExportRange = errors.New("export needs from before to, at most 92 days apart")
+	// ErrInvalidExportStatus is returned for an unknown status filter
+	ErrInvalidExportStatus = errors.New("unknown order status")
+)
+
+// exportableStatuses are the statuses an export can be filtered by
+var exportableStatuses = map[domain.OrderStatus]bool{
+	domain.OrderStatusPending:         true,
+	domain.OrderStatusAwaitingPayment: true,
+	domain.OrderStatusPaymentFailed:   true,
+	domain.OrderStatusPaid:            true,
+	domain.OrderStatusAccepted:        true,
+	domain.OrderStatusOutForDelivery:  true,
+	domain.OrderStatusDelivered:       true,
+	domain.OrderStatusCancelled:       true,
+}
+
+// ValidateExport checks an export filter before any output is written, so
+// bad requests still get a proper error status
+func (u *OrderUsecase) ValidateExport(filter repository.OrderExportFilter) error {
+	if filter.From.IsZero() || filter.To.IsZero() || !filter.From.Before(filter.To) || filter.To.Sub(filter.From) > maxExportRange {
+		return ErrInvalidExportRange
+	}
+	if filter.Status != "" && !exportableStatuses[filter.Status] {
+		return ErrInvalidExportStatus
+	}
+	return nil
+}
+
+// ExportOrders calls fn for every order matching filter, newest first,
+// without loading them all into memory
+func (u *OrderUsecase) ExportOrders(ctx context.Context, filter repository.OrderExportFilter, fn func(*domain.Order) error) error {
+	if err := u.ValidateExport(filter); err != nil {
+		return err
+	}
+	return u.orderRepo.StreamOrders(ctx, filter, fn)
+}
@@ -0,0 +1,474 @@
+// Package usecase implements historical order import from the legacy platform
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// MaxImportOrders bounds one import run; larger exports are split by the caller
+const MaxImportOrders = 5000
+
+var (
+	// ErrInvalidImport is returned when an export can't be parsed at all
+	ErrInvalidImport = errors.New("invalid import file")
+	// ErrImportTooLarge is returned for exports over MaxImportOrders orders
+	ErrImportTooLarge = errors.New("import exceeds maximum number of orders")
+	// ErrInvalidImportSource is returned for a missing or over-long source label
+	ErrInvalidImportSource = errors.New("import source is required (max 50 characters)")
+)
+
+// ImportCSVColumns is the header of a CSV export: one row per line item,
+// grouped into orders by legacy_order_id. Order-level columns are read from
+// the first row of each order.
+var ImportCSVColumns = []string{
+	"legacy_order_id", "customer_email", "customer_phone", "status", "fulfillment_type",
+	"placed_at", "delivered_at", "delivery_address", "delivery_fee",
+	"item_name", "item_price", "item_quantity",
+}
+
+// LegacyOrder is one order from a legacy export. Amounts are in paisa and
+// timestamps in RFC 3339.
+type LegacyOrder struct {
+	LegacyOrderID   string           `json:"legacy_order_id"`
+	CustomerEmail   string           `json:"customer_email"`
+	CustomerPhone   string           `json:"customer_phone"`
+	Status          string           `json:"status"`
+	FulfillmentType string           `json:"fulfillment_type"`
+	PlacedAt        time.Time        `json:"placed_at"`
+	DeliveredAt     *time.Time       `json:"delivered_at"`
+	DeliveryAddress string           `json:"delivery_address"`
+	DeliveryFee     int64            `json:"delivery_fee"`
+	Items           []LegacyLineItem `json:"items"`
+}
+
+// LegacyLineItem is one line of a legacy order
+type LegacyLineItem struct {
+	Name     string `json:"name"`
+	Price    int64  `json:"price"`
+	Quantity int    `json:"quantity"`
+}
+
+// ImportOptions controls an import run
+type ImportOptions struct {
+	// Source labels the export (e.g. "legacy-2023"); legacy order IDs are
+	// unique per source, so re-running the same export is safe
+	Source string
+
+	// Aliases maps legacy item names to current menu item names for items
+	// that were renamed
+	Aliases map[string]string
+
+	// DryRun validates and maps the export without writing anything
+	DryRun bool
+}
+
+// ImportSkip explains why a legacy order was not imported
+type ImportSkip struct {
+	LegacyOrderID string `json:"legacy_order_id"`
+	Reason        string `json:"reason"`
+}
+
+// ImportResult summarises an import run
+type ImportResult struct {
+	Source     string       `json:"source"`
+	DryRun     bool         `json:"dry_run"`
+	Total      int          `json:"total"`
+	Imported   int          `json:"imported"`
+	Duplicates int          `json:"duplicates"`
+	Skipped    []ImportSkip `json:"skipped"`
+
+	// Legacy item names with no menu match, with how many lines used them.
+	// These lines are still imported, without a menu item reference.
+	UnmatchedItems map[string]int `json:"unmatched_items"`
+}
+
+// OrderImportUsecase brings historical orders over from the legacy platform.
+// Imported orders are tagged so dispatch, batching, surveys and the live ops
+// dashboard never see them; they only appear in order history and reports.
+type OrderImportUsecase struct {
+	orderRepo *repository.OrderRepository
+	menuRepo  *repository.MenuRepository
+	userRepo  *repository.UserRepository
+	log       *logger.Logger
+}
+
+// NewOrderImportUsecase creates a new order import usecase
+func NewOrderImportUsecase(orderRepo *repository.OrderRepository, menuRepo *repository.MenuRepository, userRepo *repository.UserRepository, log *logger.Logger) *OrderImportUsecase {
+	return &OrderImportUsecase{
+		orderRepo: orderRepo,
+		menuRepo:  menuRepo,
+		userRepo:  userRepo,
+		log:       log,
+	}
+}
+
+// ParseLegacyJSON reads a JSON export: an array of LegacyOrder
+func ParseLegacyJSON(r io.Reader) ([]LegacyOrder, error) {
+	var orders []LegacyOrder
+	if err := json.NewDecoder(r).Decode(&orders); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if len(orders) > MaxImportOrders {
+		return nil, ErrImportTooLarge
+	}
+	return orders, nil
+}
+
+// ParseLegacyCSV reads a CSV export with an ImportCSVColumns header. Columns
+// may appear in any order; unknown columns are ignored.
+func ParseLegacyCSV(r io.Reader) ([]LegacyOrder, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header: %v", ErrInvalidImport, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"legacy_order_id", "placed_at", "item_name", "item_price", "item_quantity"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImport, required)
+		}
+	}
+
+	var orders []LegacyOrder
+	byID := make(map[string]int)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+		}
+
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		price, err := strconv.ParseInt(field("item_price"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid item_price", ErrInvalidImport, line)
+		}
+		quantity, err := strconv.Atoi(field("item_quantity"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid item_quantity", ErrInvalidImport, line)
+		}
+		item := LegacyLineItem{Name: field("item_name"), Price: price, Quantity: quantity}
+
+		id := field("legacy_order_id")
+		if i, ok := byID[id]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+			continue
+		}
+
+		order := LegacyOrder{
+			LegacyOrderID:   id,
+			CustomerEmail:   field("customer_email"),
+			CustomerPhone:   field("customer_phone"),
+			Status:          field("status"),
+			FulfillmentType: field("fulfillment_type"),
+			DeliveryAddress: field("delivery_address"),
+			Items:           []LegacyLineItem{item},
+		}
+		if order.PlacedAt, err = time.Parse(time.RFC3339, field("placed_at")); err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid placed_at", ErrInvalidImport, line)
+		}
+		if v := field("delivered_at"); v != "" {
+			deliveredAt, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid delivered_at", ErrInvalidImport, line)
+			}
+			order.DeliveredAt = &deliveredAt
+		}
+		if v := field("delivery_fee"); v != "" {
+			if order.DeliveryFee, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid delivery_fee", ErrInvalidImport, line)
+			}
+		}
+
+		if len(orders) == MaxImportOrders {
+			return nil, ErrImportTooLarge
+		}
+		byID[id] = len(orders)
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// Import validates legacy orders, maps their items onto the current menu and
+// stores them as imported history. Individual bad orders are skipped and
+// reported; only infrastructure failures abort the run.
+func (u *OrderImportUsecase) Import(ctx context.Context, orders []LegacyOrder, opts ImportOptions) (*ImportResult, error) {
+	source := strings.TrimSpace(opts.Source)
+	if source == "" || len(source) > 50 {
+		return nil, ErrInvalidImportSource
+	}
+	if len(orders) > MaxImportOrders {
+		return nil, ErrImportTooLarge
+	}
+
+	menu, err := u.menuRepo.GetAllIncludingUnavailable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load menu: %w", err)
+	}
+	matcher := newMenuMatcher(menu, opts.Aliases)
+
+	result := &ImportResult{
+		Source:         source,
+		DryRun:         opts.DryRun,
+		Total:          len(orders),
+		Skipped:        []ImportSkip{},
+		UnmatchedItems: make(map[string]int),
+	}
+	users := make(map[string]uuid.UUID)
+	seen := make(map[string]bool, len(orders))
+	importedAt := time.Now()
+
+	for _, legacy := range orders {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, ImportSkip{LegacyOrderID: legacy.LegacyOrderID, Reason: reason})
+		}
+
+		legacy.LegacyOrderID = strings.TrimSpace(legacy.LegacyOrderID)
+		if legacy.LegacyOrderID == "" || len(legacy.LegacyOrderID) > 100 {
+			skip("legacy_order_id is required (max 100 characters)")
+			continue
+		}
+		if seen[legacy.LegacyOrderID] {
+			skip("legacy_order_id appears more than once in this import")
+			continue
+		}
+		seen[legacy.LegacyOrderID] = true
+
+		order, reason := u.mapLegacyOrder(legacy, matcher, result.UnmatchedItems)
+		if reason != "" {
+			skip(reason)
+			continue
+		}
+
+		userID, reason, err := u.resolveCustomer(ctx, legacy, users)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			skip(reason)
+			continue
+		}
+		order.UserID = userID
+		order.ImportedAt = &importedAt
+		order.ImportSource = source
+		order.LegacyOrderID = legacy.LegacyOrderID
+
+		if opts.DryRun {
+			result.Imported++
+			continue
+		}
+
+		inserted, err := u.orderRepo.Import(ctx, order)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import order %s: %w", legacy.LegacyOrderID, err)
+		}
+		if inserted {
+			result.Imported++
+		} else {
+			result.Duplicates++
+		}
+	}
+
+	u.log.Info("Historical orders imported",
+		"source", source,
+		"dry_run", opts.DryRun,
+		"total", result.Total,
+		"imported", result.Imported,
+		"duplicates", result.Duplicates,
+		"skipped", len(result.Skipped),
+		"unmatched_items", len(result.UnmatchedItems),
+	)
+
+	return result, nil
+}
+
+// mapLegacyOrder converts a legacy order into an imported order, returning a
+// skip reason if it can't be imported
+func (u *OrderImportUsecase) mapLegacyOrder(legacy LegacyOrder, matcher *menuMatcher, unmatched map[string]int) (*domain.Order, string) {
+	// Only completed orders are history worth keeping; abandoned carts and
+	// failed payments on the old platform are dropped
+	switch strings.ToLower(strings.TrimSpace(legacy.Status)) {
+	case "", "delivered", "completed", "picked_up":
+	default:
+		return nil, fmt.Sprintf("status %q is not importable", legacy.Status)
+	}
+
+	fulfillment := domain.FulfillmentDelivery
+	switch strings.ToUpper(strings.TrimSpace(legacy.FulfillmentType)) {
+	case "", string(domain.FulfillmentDelivery):
+	case string(domain.FulfillmentPickup):
+		fulfillment = domain.FulfillmentPickup
+	default:
+		return nil, fmt.Sprintf("unknown fulfillment_type %q", legacy.FulfillmentType)
+	}
+
+	if legacy.PlacedAt.IsZero() || legacy.PlacedAt.After(time.Now()) {
+		return nil, "placed_at is missing or in the future"
+	}
+	if legacy.DeliveredAt != nil && legacy.DeliveredAt.Before(legacy.PlacedAt) {
+		return nil, "delivered_at is before placed_at"
+	}
+	if legacy.DeliveryFee < 0 {
+		return nil, "delivery_fee is negative"
+	}
+	if len(legacy.Items) == 0 {
+		return nil, "order has no items"
+	}
+
+	order := &domain.Order{
+		Status:          domain.OrderStatusDelivered,
+		FulfillmentType: fulfillment,
+		DeliveryFee:     legacy.DeliveryFee,
+		DeliveredAt:     legacy.DeliveredAt,
+		TotalAmount:     legacy.DeliveryFee,
+		CreatedAt:       legacy.PlacedAt,
+		UpdatedAt:       legacy.PlacedAt,
+	}
+	if address := strings.TrimSpace(legacy.DeliveryAddress); address != "" && fulfillment == domain.FulfillmentDelivery {
+		order.DeliveryLocation = &domain.DeliveryLocation{Address: address}
+	}
+	if order.DeliveredAt != nil {
+		order.UpdatedAt = *order.DeliveredAt
+	}
+
+	for _, line := range legacy.Items {
+		name := strings.TrimSpace(line.Name)
+		if name == "" || line.Price <= 0 || line.Quantity <= 0 {
+			return nil, "items need a name, a positive price and a positive quantity"
+		}
+
+		item := domain.OrderItem{Name: name, Price: line.Price, Quantity: line.Quantity}
+		if menuItem, ok := matcher.match(name); ok {
+			item.MenuItemID = menuItem.ID
+		} else {
+			unmatched[name]++
+		}
+
+		order.Items = append(order.Items, item)
+		order.Subtotal += item.Subtotal()
+		order.TotalAmount += item.Subtotal()
+	}
+
+	return order, ""
+}
+
+// resolveCustomer finds the migrated user for a legacy order by email, then
+// phone. Lookups are memoised per run since exports repeat customers heavily.
+func (u *OrderImportUsecase) resolveCustomer(ctx context.Context, legacy LegacyOrder, cache map[string]uuid.UUID) (uuid.UUID, string, error) {
+	lookups := []struct {
+		key    string
+		lookup func(context.Context, string) (*domain.User, error)
+	}{
+		{strings.ToLower(strings.TrimSpace(legacy.CustomerEmail)), u.userRepo.GetByEmail},
+		{strings.TrimSpace(legacy.CustomerPhone), u.userRepo.GetByPhoneNumber},
+	}
+
+	for _, l := range lookups {
+		if l.key == "" {
+			continue
+		}
+		if id, ok := cache[l.key]; ok {
+			if id == uuid.Nil {
+				continue
+			}
+			return id, "", nil
+		}
+
+		user, err := l.lookup(ctx, l.key)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				cache[l.key] = uuid.Nil
+				continue
+			}
+			return uuid.Nil, "", fmt.Errorf("failed to resolve customer: %w", err)
+		}
+		cache[l.key] = user.ID
+		return user.ID, "", nil
+	}
+
+	return uuid.Nil, "customer not found; migrate the user first", nil
+}
+
+// menuMatcher maps legacy item names onto current menu items by normalised
+// name, after applying any explicit renames
+type menuMatcher struct {
+	byName  map[string]domain.MenuItem
+	aliases map[string]string
+}
+
+func newMenuMatcher(menu []domain.MenuItem, aliases map[string]string) *menuMatcher {
+	m := &menuMatcher{
+		byName:  make(map[string]domain.MenuItem, len(menu)),
+		aliases: make(map[string]string, len(aliases)),
+	}
+	for _, item := range menu {
+		m.byName[normalizeItemName(item.Name)] = item
+	}
+	for from, to := range aliases {
+		m.aliases[normalizeItemName(from)] = normalizeItemName(to)
+	}
+	return m
+}
+
+func (m *menuMatcher) match(name string) (domain.MenuItem, bool) {
+	key := normalizeItemName(name)
+	if alias, ok := m.aliases[key]; ok {
+		key = alias
+	}
+	item, ok := m.byName[key]
+	return item, ok
+}
+
+// normalizeItemName lowercases a name and folds punctuation and repeated
+// spaces, so "Paneer Tikka (Half)" and "paneer  tikka-half" compare equal
+func normalizeItemName(name string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteRune(r)
+			space = false
+		case r == '\'' || r == '’':
+			// "Chef's Special" matches "Chefs Special"
+		default:
+			space = true
+		}
+	}
+	return sb.String()
+}
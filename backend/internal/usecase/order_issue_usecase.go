@@ -0,0 +1,388 @@
+// Package usecase implements self-service resolution of problems with
+// delivered orders
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Order issue errors
+var (
+	ErrIssueNotAllowed     = errors.New("problems can only be reported on delivered orders")
+	ErrIssueWindowClosed   = errors.New("the time to report a problem with this order has passed")
+	ErrIssueAlreadyFiled   = errors.New("a problem has already been reported for this order")
+	ErrInvalidIssueType    = errors.New("type must be MISSING_ITEMS, WRONG_ITEMS, COLD_FOOD or OTHER")
+	ErrInvalidIssueItems   = errors.New("items must be lines of the order with quantities up to those ordered")
+	ErrIssueDescription    = errors.New("a description of at most 1000 characters is required for other problems")
+	ErrIssueNotEscalated   = errors.New("issue is not waiting for staff")
+	ErrInvalidIssueRefund  = errors.New("refund amount must not be negative")
+	ErrInvalidIssueStatus  = errors.New("status must be AUTO_RESOLVED, ESCALATED or RESOLVED")
+	ErrIssueResolutionNote = errors.New("a note of at most 1000 characters is required")
+)
+
+// maxIssueTextLength bounds descriptions and resolution notes
+const maxIssueTextLength = 1000
+
+// issueAutoLimitPeriod is the rolling period the per-customer limit on
+// automatic remedies counts over
+const issueAutoLimitPeriod = 30 * 24 * time.Hour
+
+// defaultIssueListLimit is used when no valid limit is requested
+const defaultIssueListLimit = 50
+
+// Escalation reasons shown to staff
+const (
+	issueReasonOther        = "not covered by the automatic rules"
+	issueReasonOverLimit    = "remedy over the automatic refund limit"
+	issueReasonTooMany      = "customer reached the limit of automatic remedies"
+	issueReasonNoPayment    = "order has no online payment to refund"
+	issueReasonRefundFailed = "automatic refund failed"
+)
+
+// OrderIssueUsecase lets customers report a problem with a delivered order
+// and get a remedy without waiting for staff. Missing and wrong items are
+// refunded at what was paid for them, cold food a share of the subtotal.
+// Anything the rules don't cover, or that passes their limits, becomes a
+// ticket for staff. There is no wallet, so remedies are always refunds to
+// the original payment.
+type OrderIssueUsecase struct {
+	issueRepo *repository.OrderIssueRepository
+	orderRepo *repository.OrderRepository
+	payments  *PaymentUsecase
+	settings  *SettingsUsecase
+	alerts    *AdminAlertUsecase
+	audit     *AuditUsecase
+	log       *logger.Logger
+}
+
+// NewOrderIssueUsecase creates a new order issue usecase. settings holds
+// the window and compensation limits.
+func NewOrderIssueUsecase(
+	issueRepo *repository.OrderIssueRepository,
+	orderRepo *repository.OrderRepository,
+	payments *PaymentUsecase,
+	settings *SettingsUsecase,
+	log *logger.Logger,
+) *OrderIssueUsecase {
+	return &OrderIssueUsecase{
+		issueRepo: issueRepo,
+		orderRepo: orderRepo,
+		payments:  payments,
+		settings:  settings,
+		log:       log,
+	}
+}
+
+// SetAlerts tells admins about escalated issues and failed refunds
+func (u *OrderIssueUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// SetAuditLog records staff resolutions in the admin audit log
+func (u *OrderIssueUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// ReportIssueRequest is a problem the customer picked for their order
+type ReportIssueRequest struct {
+	Type        domain.OrderIssueType `json:"type"`
+	Items       []ShortItemRequest    `json:"items"` // Missing or wrong items only
+	Description string                `json:"description"`
+}
+
+// ResolveIssueRequest closes an escalated issue, refunding RefundAmount
+// paisa when it isn't zero
+type ResolveIssueRequest struct {
+	RefundAmount int64  `json:"refund_amount"`
+	Note         string `json:"note"`
+}
+
+// OrderIssueOptions tells the app whether a problem can still be reported
+// for an order, until when, and what was reported already
+type OrderIssueOptions struct {
+	CanReport  bool                    `json:"can_report"`
+	ReportBy   *time.Time              `json:"report_by,omitempty"`
+	IssueTypes []domain.OrderIssueType `json:"issue_types"`
+	Issues     []domain.OrderIssue     `json:"issues"`
+}
+
+// GetOptions returns the issue choices for one of the customer's orders
+func (u *OrderIssueUsecase) GetOptions(ctx context.Context, userID, orderID uuid.UUID) (*OrderIssueOptions, error) {
+	order, err := u.customerOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := u.issueRepo.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &OrderIssueOptions{
+		IssueTypes: []domain.OrderIssueType{domain.IssueMissingItems, domain.IssueWrongItems, domain.IssueColdFood, domain.IssueOther},
+		Issues:     issues,
+	}
+	if order.Status == domain.OrderStatusDelivered && order.DeliveredAt != nil && !order.IsImported() {
+		reportBy := u.reportBy(ctx, order)
+		options.ReportBy = &reportBy
+		options.CanReport = len(issues) == 0 && time.Now().Before(reportBy)
+	}
+	return options, nil
+}
+
+// ReportIssue records a problem with a delivered order. When the
+// compensation rules cover it the refund is made straight away and the
+// issue comes back AUTO_RESOLVED; otherwise it comes back ESCALATED and
+// admins are told.
+func (u *OrderIssueUsecase) ReportIssue(ctx context.Context, userID, orderID uuid.UUID, req ReportIssueRequest) (*domain.OrderIssue, error) {
+	if !req.Type.IsValid() {
+		return nil, ErrInvalidIssueType
+	}
+	description := strings.TrimSpace(req.Description)
+	if utf8.RuneCountInString(description) > maxIssueTextLength || (req.Type == domain.IssueOther && description == "") {
+		return nil, ErrIssueDescription
+	}
+
+	order, err := u.customerOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.OrderStatusDelivered || order.DeliveredAt == nil || order.IsImported() {
+		return nil, ErrIssueNotAllowed
+	}
+	if !time.Now().Before(u.reportBy(ctx, order)) {
+		return nil, ErrIssueWindowClosed
+	}
+
+	issue := &domain.OrderIssue{
+		OrderID:     order.ID,
+		UserID:      userID,
+		Type:        req.Type,
+		Description: description,
+	}
+	switch {
+	case req.Type.NeedsItems():
+		items, amount, _, err := shortItems(order, req.Items)
+		if err != nil {
+			return nil, ErrInvalidIssueItems
+		}
+		issue.Items, issue.RemedyAmount = items, amount
+	case req.Type == domain.IssueColdFood:
+		issue.RemedyAmount = order.Subtotal * int64(u.settings.Int(ctx, SettingIssueColdFoodPercent)) / 100
+	}
+
+	reason, err := u.escalationReason(ctx, order, issue)
+	if err != nil {
+		return nil, err
+	}
+	issue.Status, issue.EscalationReason = domain.IssueAutoResolved, reason
+	if reason != "" {
+		issue.Status = domain.IssueEscalated
+	} else {
+		issue.RefundAmount = issue.RemedyAmount
+	}
+
+	if err := u.issueRepo.Create(ctx, issue); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrIssueAlreadyFiled
+		}
+		return nil, err
+	}
+
+	u.log.Info("Order issue reported",
+		"order_id", order.ID.String(),
+		"issue_id", issue.ID.String(),
+		"type", issue.Type,
+		"status", issue.Status,
+		"remedy_amount", issue.RemedyAmount,
+	)
+
+	if issue.Status == domain.IssueAutoResolved {
+		u.autoRefund(ctx, order, issue)
+	}
+	if issue.Status == domain.IssueEscalated {
+		u.raiseEscalated(ctx, order, issue)
+	}
+
+	return issue, nil
+}
+
+// ListIssues returns issues for staff, newest first, only those with
+// status when it isn't empty
+func (u *OrderIssueUsecase) ListIssues(ctx context.Context, status domain.OrderIssueStatus, limit, offset int) ([]domain.OrderIssue, error) {
+	if status != "" && !status.IsValid() {
+		return nil, ErrInvalidIssueStatus
+	}
+	if limit <= 0 || limit > 100 {
+		limit = defaultIssueListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return u.issueRepo.List(ctx, status, limit, offset)
+}
+
+// ResolveIssue closes an escalated issue on an admin's behalf, refunding
+// what they decided. The issue stays resolved if the refund fails; the
+// error is recorded on it and admins refund by hand.
+func (u *OrderIssueUsecase) ResolveIssue(ctx context.Context, adminID, issueID uuid.UUID, req ResolveIssueRequest) (*domain.OrderIssue, error) {
+	if req.RefundAmount < 0 {
+		return nil, ErrInvalidIssueRefund
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" || utf8.RuneCountInString(note) > maxIssueTextLength {
+		return nil, ErrIssueResolutionNote
+	}
+
+	issue, err := u.issueRepo.GetByID(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue.Status != domain.IssueEscalated {
+		return nil, ErrIssueNotEscalated
+	}
+	order, err := u.orderRepo.GetByID(ctx, issue.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if req.RefundAmount > 0 && order.RazorpayPaymentID == "" {
+		return nil, ErrRefundNotAllowed
+	}
+
+	if err := u.issueRepo.Resolve(ctx, issue.ID, adminID, note); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, ErrIssueNotEscalated
+		}
+		return nil, err
+	}
+	now := time.Now()
+	issue.Status, issue.ResolvedAt, issue.ResolvedBy, issue.ResolutionNote = domain.IssueResolved, &now, &adminID, note
+
+	if req.RefundAmount > 0 {
+		refundID, refundErr := u.payments.refund(ctx, order, req.RefundAmount, "refund.order_issue", nil, nil)
+		issue.RazorpayRefundID, issue.RefundError, issue.RefundAmount = refundID, "", req.RefundAmount
+		if refundErr != nil {
+			issue.RefundError, issue.RefundAmount = refundErr.Error(), 0
+			u.log.Error("Order issue refund failed", "order_id", order.ID.String(), "issue_id", issue.ID.String(), "amount", req.RefundAmount, "error", refundErr)
+			u.raiseRefundFailed(ctx, order, req.RefundAmount)
+		}
+		if err := u.issueRepo.SetRefund(ctx, issue.ID, issue.RefundAmount, issue.RazorpayRefundID, issue.RefundError); err != nil {
+			u.log.Warn("Failed to record order issue refund", "issue_id", issue.ID.String(), "error", err)
+		}
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditOrderIssueResolved, "order", order.ID.String(), map[string]interface{}{
+		"issue_id":      issue.ID.String(),
+		"refund_amount": issue.RefundAmount,
+		"refund_error":  issue.RefundError,
+		"note":          note,
+	})
+	u.log.Info("Order issue resolved", "order_id", order.ID.String(), "issue_id", issue.ID.String(), "refund_amount", issue.RefundAmount)
+
+	return issue, nil
+}
+
+// customerOrder loads an order the customer owns
+func (u *OrderIssueUsecase) customerOrder(ctx context.Context, userID, orderID uuid.UUID) (*domain.Order, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	return order, nil
+}
+
+// reportBy is when the window to report a problem with a delivered order
+// closes
+func (u *OrderIssueUsecase) reportBy(ctx context.Context, order *domain.Order) time.Time {
+	return order.DeliveredAt.Add(time.Duration(u.settings.Int(ctx, SettingIssueWindowHours)) * time.Hour)
+}
+
+// escalationReason returns why the issue needs staff, or "" when the rules
+// can resolve it
+func (u *OrderIssueUsecase) escalationReason(ctx context.Context, order *domain.Order, issue *domain.OrderIssue) (string, error) {
+	switch {
+	case issue.Type == domain.IssueOther:
+		return issueReasonOther, nil
+	case order.RazorpayPaymentID == "":
+		return issueReasonNoPayment, nil
+	case issue.RemedyAmount > u.settings.Int64(ctx, SettingIssueMaxAutoRefund):
+		return issueReasonOverLimit, nil
+	}
+
+	used, err := u.issueRepo.CountAutoResolvedSince(ctx, issue.UserID, time.Now().Add(-issueAutoLimitPeriod))
+	if err != nil {
+		return "", err
+	}
+	if used >= u.settings.Int(ctx, SettingIssueAutoLimit) {
+		return issueReasonTooMany, nil
+	}
+	return "", nil
+}
+
+// autoRefund refunds an automatically resolved issue. A failed refund
+// hands the issue over to staff.
+func (u *OrderIssueUsecase) autoRefund(ctx context.Context, order *domain.Order, issue *domain.OrderIssue) {
+	if issue.RefundAmount == 0 {
+		return
+	}
+
+	refundID, refundErr := u.payments.refund(ctx, order, issue.RefundAmount, "refund.order_issue", nil, nil)
+	if refundErr == nil {
+		issue.RazorpayRefundID = refundID
+		if err := u.issueRepo.SetRefund(ctx, issue.ID, issue.RefundAmount, refundID, ""); err != nil {
+			u.log.Warn("Failed to record order issue refund", "issue_id", issue.ID.String(), "error", err)
+		}
+		return
+	}
+
+	u.log.Error("Order issue refund failed", "order_id", order.ID.String(), "issue_id", issue.ID.String(), "amount", issue.RefundAmount, "error", refundErr)
+	if err := u.issueRepo.Escalate(ctx, issue.ID, issueReasonRefundFailed, refundErr.Error()); err != nil {
+		u.log.Error("Failed to escalate order issue", "issue_id", issue.ID.String(), "error", err)
+		return
+	}
+	issue.Status, issue.EscalationReason, issue.RefundError = domain.IssueEscalated, issueReasonRefundFailed, refundErr.Error()
+	issue.RefundAmount, issue.ResolvedAt = 0, nil
+}
+
+// raiseEscalated tells admins an issue is waiting for them
+func (u *OrderIssueUsecase) raiseEscalated(ctx context.Context, order *domain.Order, issue *domain.OrderIssue) {
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:  AlertOrderIssueEscalated,
+		Title: "Problem reported with order " + orderNumber(order.ID),
+		Body:  fmt.Sprintf("%s (%s); the rules would refund %s.", issue.Type, issue.EscalationReason, formatRupees(issue.RemedyAmount)),
+		Data: map[string]string{
+			"type":     "order_issue_escalated",
+			"order_id": order.ID.String(),
+			"issue_id": issue.ID.String(),
+		},
+	})
+}
+
+// raiseRefundFailed tells admins a refund staff decided on must be made by
+// hand
+func (u *OrderIssueUsecase) raiseRefundFailed(ctx context.Context, order *domain.Order, amount int64) {
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertRefundFailed,
+		Critical: true,
+		Title:    "Refund failed for order " + orderNumber(order.ID),
+		Body:     fmt.Sprintf("%s could not be refunded for a reported problem; refund it from the Razorpay dashboard.", formatRupees(amount)),
+		Data: map[string]string{
+			"type":     "refund_failed",
+			"order_id": order.ID.String(),
+		},
+	})
+}
@@ -0,0 +1,202 @@
+// Package usecase implements the hub pushing live order updates to
+// connected clients
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/events"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/redis"
+)
+
+// liveBuffer is how many updates a subscriber can fall behind before it is
+// dropped; the client reconnects and reloads its orders
+const liveBuffer = 32
+
+// liveResubscribeDelay is the wait before listening again after the
+// channel subscription fails
+const liveResubscribeDelay = 5 * time.Second
+
+var liveSubscribers = metrics.NewGaugeVec(
+	"order_live_subscribers",
+	"Clients connected to live order updates, by audience",
+	"audience",
+)
+
+// LiveSubscription receives the order events one client may see. Messages
+// is closed when the subscriber falls too far behind or is unsubscribed.
+type LiveSubscription struct {
+	userID   uuid.UUID
+	admin    bool
+	orderID  uuid.UUID // Set for a single order's stream
+	messages chan []byte
+	closed   bool // Guarded by the hub's mutex
+}
+
+// audience labels subscriber metrics
+func (s *LiveSubscription) audience() string {
+	switch {
+	case s.orderID != uuid.Nil:
+		return "order"
+	case s.admin:
+		return "admin"
+	}
+	return "customer"
+}
+
+// wants reports whether the subscriber may see an event for the order
+func (s *LiveSubscription) wants(orderID, userID uuid.UUID) bool {
+	if s.orderID != uuid.Nil {
+		return s.orderID == orderID
+	}
+	return s.admin || s.userID == userID
+}
+
+// Messages returns the JSON-encoded events for the subscriber
+func (s *LiveSubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+// OrderLiveHub fans order events out to connected clients: customers get
+// events for their own orders, admins get every event. Events come from
+// the outbox relay over a Redis channel, so every instance's clients see
+// them whichever instance relayed them.
+type OrderLiveHub struct {
+	redisClient *redis.Client
+	log         *logger.Logger
+
+	mu     sync.Mutex
+	subs   map[*LiveSubscription]struct{}
+	counts map[string]int // Subscribers by audience, for the gauge
+}
+
+// NewOrderLiveHub creates a hub listening on the live order events channel
+func NewOrderLiveHub(redisClient *redis.Client, log *logger.Logger) *OrderLiveHub {
+	return &OrderLiveHub{
+		redisClient: redisClient,
+		log:         log.WithFields(map[string]interface{}{"source": "order_live"}),
+		subs:        make(map[*LiveSubscription]struct{}),
+		counts:      make(map[string]int),
+	}
+}
+
+// Subscribe registers a client: userID's orders, or all orders for admins
+func (h *OrderLiveHub) Subscribe(userID uuid.UUID, admin bool) *LiveSubscription {
+	return h.add(&LiveSubscription{userID: userID, admin: admin})
+}
+
+// SubscribeOrder registers a client following one order; the caller has
+// checked it may see the order
+func (h *OrderLiveHub) SubscribeOrder(orderID uuid.UUID) *LiveSubscription {
+	return h.add(&LiveSubscription{orderID: orderID})
+}
+
+// add registers a subscription
+func (h *OrderLiveHub) add(s *LiveSubscription) *LiveSubscription {
+	s.messages = make(chan []byte, liveBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[s] = struct{}{}
+	h.count(s, 1)
+	return s
+}
+
+// Unsubscribe removes a client; safe to call more than once
+func (h *OrderLiveHub) Unsubscribe(s *LiveSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(s)
+}
+
+// drop removes and closes a subscription; the caller holds the mutex
+func (h *OrderLiveHub) drop(s *LiveSubscription) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(h.subs, s)
+	close(s.messages)
+	h.count(s, -1)
+}
+
+// count updates the subscriber gauge; the caller holds the mutex
+func (h *OrderLiveHub) count(s *LiveSubscription, delta int) {
+	label := s.audience()
+	h.counts[label] += delta
+	liveSubscribers.Set(float64(h.counts[label]), label)
+}
+
+// Run listens for order events until ctx is cancelled, subscribing again
+// if the channel drops
+func (h *OrderLiveHub) Run(ctx context.Context) {
+	channel := h.redisClient.Channel(redis.OrderEventsLiveChannel)
+	for {
+		pubsub := h.redisClient.Subscribe(ctx, channel)
+		h.listen(ctx, pubsub.Channel())
+		if err := pubsub.Close(); err != nil {
+			h.log.Warn("Failed to close live order subscription", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(liveResubscribeDelay):
+		}
+	}
+}
+
+// listen dispatches messages until the subscription or ctx ends
+func (h *OrderLiveHub) listen(ctx context.Context, messages <-chan *redis.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				h.log.Warn("Live order subscription closed, resubscribing")
+				return
+			}
+			h.dispatch([]byte(msg.Payload))
+		}
+	}
+}
+
+// dispatch sends one event to the subscribers allowed to see it. A
+// subscriber whose buffer is full is dropped rather than slowing the rest.
+func (h *OrderLiveHub) dispatch(data []byte) {
+	var event events.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		h.log.Warn("Dropping undecodable live order event", "error", err)
+		return
+	}
+	var payload struct {
+		OrderID uuid.UUID `json:"order_id"`
+		UserID  uuid.UUID `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		h.log.Warn("Dropping live order event without an owner", "event_id", event.ID, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if !s.wants(payload.OrderID, payload.UserID) {
+			continue
+		}
+		select {
+		case s.messages <- data:
+		default:
+			h.log.Warn("Dropping slow live order subscriber", "user_id", s.userID.String(), "audience", s.audience())
+			h.drop(s)
+		}
+	}
+}
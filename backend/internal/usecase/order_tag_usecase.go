@@ -0,0 +1,289 @@
+// Package usecase implements tags and custom attributes on orders
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// Order tag and attribute errors
+var (
+	ErrInvalidOrderTag       = errors.New("tags must be 1-32 lowercase letters, digits, '-' or '_'")
+	ErrTooManyOrderTags      = errors.New("too many tags on the order")
+	ErrUnknownOrderAttribute = errors.New("order attribute is not defined")
+	ErrInvalidOrderAttribute = errors.New("order attribute value does not match its type")
+	ErrInvalidAttributeKey   = errors.New("attribute keys must be 1-50 lowercase letters, digits or '_', starting with a letter")
+	ErrAttributeTypeConflict = errors.New("order attribute is already defined with a different type")
+	ErrInvalidAttributeType  = errors.New("attribute type must be string, number or boolean")
+	ErrNoOrderLabelChanges   = errors.New("no tags or attributes to change")
+)
+
+// Order tag and attribute limits
+const (
+	maxOrderTags             = 20
+	maxOrderAttributeLength  = 200 // Characters in a string attribute value
+	orderTagChangesPerUpdate = 20
+)
+
+var (
+	orderTagPattern          = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	orderAttributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+)
+
+// OrderSearch filters the admin order listing. Attribute values are given
+// as text and read according to the attribute's type.
+type OrderSearch struct {
+	Tags       []string
+	Attributes map[string]string
+}
+
+// OrderTagsUpdate adds and removes tags on an order
+type OrderTagsUpdate struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// OrderAttributesUpdate sets and removes custom attributes on an order
+type OrderAttributesUpdate struct {
+	Set    map[string]interface{} `json:"set"`
+	Remove []string               `json:"remove"`
+}
+
+// UpdateOrderTags changes an order's tags on an admin's behalf and returns
+// the order's tags
+func (u *OrderUsecase) UpdateOrderTags(ctx context.Context, adminID, orderID uuid.UUID, req OrderTagsUpdate) ([]string, error) {
+	tags, err := u.updateTags(ctx, orderID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditOrderTagsUpdated, "order", orderID.String(), map[string]interface{}{
+		"added":   req.Add,
+		"removed": req.Remove,
+		"tags":    tags,
+	})
+	u.log.Info("Order tags updated", "order_id", orderID.String(), "admin_id", adminID.String())
+
+	return tags, nil
+}
+
+// TagOrder adds tags to an order for an automation rule, named by rule in
+// the log. Admins change tags with UpdateOrderTags.
+func (u *OrderUsecase) TagOrder(ctx context.Context, orderID uuid.UUID, rule string, tags ...string) error {
+	if _, err := u.updateTags(ctx, orderID, OrderTagsUpdate{Add: tags}); err != nil {
+		return err
+	}
+	u.log.Info("Order tagged by rule", "order_id", orderID.String(), "rule", rule, "tags", tags)
+	return nil
+}
+
+func (u *OrderUsecase) updateTags(ctx context.Context, orderID uuid.UUID, req OrderTagsUpdate) ([]string, error) {
+	add, err := normalizeOrderTags(req.Add)
+	if err != nil {
+		return nil, err
+	}
+	remove, err := normalizeOrderTags(req.Remove)
+	if err != nil {
+		return nil, err
+	}
+	if len(add)+len(remove) == 0 {
+		return nil, ErrNoOrderLabelChanges
+	}
+	if len(add)+len(remove) > orderTagChangesPerUpdate {
+		return nil, ErrTooManyOrderTags
+	}
+
+	tags, applied, err := u.orderRepo.UpdateTags(ctx, orderID, add, remove, maxOrderTags)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, ErrTooManyOrderTags
+	}
+	return tags, nil
+}
+
+// SetOrderAttributes changes an order's custom attributes on an admin's
+// behalf and returns the order's attributes. Every key must be defined and
+// every value must match the key's type.
+func (u *OrderUsecase) SetOrderAttributes(ctx context.Context, adminID, orderID uuid.UUID, req OrderAttributesUpdate) (map[string]interface{}, error) {
+	if len(req.Set)+len(req.Remove) == 0 {
+		return nil, ErrNoOrderLabelChanges
+	}
+
+	definitions, err := u.attributeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range req.Set {
+		valueType, ok := definitions[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOrderAttribute, key)
+		}
+		if err := checkAttributeValue(valueType, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	attributes, err := u.orderRepo.UpdateAttributes(ctx, orderID, req.Set, req.Remove)
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditOrderAttributesSet, "order", orderID.String(), map[string]interface{}{
+		"set":     req.Set,
+		"removed": req.Remove,
+	})
+	u.log.Info("Order attributes updated", "order_id", orderID.String(), "admin_id", adminID.String())
+
+	return attributes, nil
+}
+
+// GetOrderAttributeDefinitions lists the attributes that can be set on orders
+func (u *OrderUsecase) GetOrderAttributeDefinitions(ctx context.Context) ([]domain.OrderAttributeDefinition, error) {
+	definitions, err := u.orderRepo.GetAttributeDefinitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order attribute definitions: %w", err)
+	}
+	return definitions, nil
+}
+
+// DefineOrderAttribute adds an order attribute or updates its description.
+// An attribute's type can't be changed once defined.
+func (u *OrderUsecase) DefineOrderAttribute(ctx context.Context, adminID uuid.UUID, key string, valueType domain.OrderAttributeType, description string) (*domain.OrderAttributeDefinition, error) {
+	if !orderAttributeKeyPattern.MatchString(key) {
+		return nil, ErrInvalidAttributeKey
+	}
+	switch valueType {
+	case domain.OrderAttributeString, domain.OrderAttributeNumber, domain.OrderAttributeBoolean:
+	default:
+		return nil, ErrInvalidAttributeType
+	}
+	description = strings.TrimSpace(description)
+
+	definition := &domain.OrderAttributeDefinition{
+		Key:         key,
+		Type:        valueType,
+		Description: description,
+		CreatedBy:   &adminID,
+	}
+	if err := u.orderRepo.SaveAttributeDefinition(ctx, definition); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrAttributeTypeConflict
+		}
+		return nil, err
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditOrderAttributeDefined, "order_attribute", key, map[string]interface{}{
+		"type":        valueType,
+		"description": description,
+	})
+
+	return definition, nil
+}
+
+// orderSearchFilter turns an admin search into a repository filter, reading
+// each attribute value as its attribute's type
+func (u *OrderUsecase) orderSearchFilter(ctx context.Context, search OrderSearch) (repository.AdminOrderFilter, error) {
+	var filter repository.AdminOrderFilter
+
+	tags, err := normalizeOrderTags(search.Tags)
+	if err != nil {
+		return filter, err
+	}
+	filter.Tags = tags
+
+	if len(search.Attributes) == 0 {
+		return filter, nil
+	}
+	definitions, err := u.attributeTypes(ctx)
+	if err != nil {
+		return filter, err
+	}
+	filter.Attributes = make(map[string]interface{}, len(search.Attributes))
+	for key, raw := range search.Attributes {
+		valueType, ok := definitions[key]
+		if !ok {
+			return filter, fmt.Errorf("%w: %s", ErrUnknownOrderAttribute, key)
+		}
+		value, err := parseAttributeValue(valueType, raw)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %s", ErrInvalidOrderAttribute, key)
+		}
+		if err := checkAttributeValue(valueType, key, value); err != nil {
+			return filter, err
+		}
+		filter.Attributes[key] = value
+	}
+
+	return filter, nil
+}
+
+// attributeTypes maps each defined attribute to its type
+func (u *OrderUsecase) attributeTypes(ctx context.Context) (map[string]domain.OrderAttributeType, error) {
+	definitions, err := u.GetOrderAttributeDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]domain.OrderAttributeType, len(definitions))
+	for _, d := range definitions {
+		types[d.Key] = d.Type
+	}
+	return types, nil
+}
+
+// normalizeOrderTags lowercases and trims tags, dropping repeats
+func normalizeOrderTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !orderTagPattern.MatchString(tag) {
+			return nil, ErrInvalidOrderTag
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// checkAttributeValue checks a JSON-decoded value against its attribute's type
+func checkAttributeValue(valueType domain.OrderAttributeType, key string, value interface{}) error {
+	ok := false
+	switch v := value.(type) {
+	case string:
+		ok = valueType == domain.OrderAttributeString && utf8.RuneCountInString(v) <= maxOrderAttributeLength
+	case float64:
+		ok = valueType == domain.OrderAttributeNumber && !math.IsInf(v, 0) && !math.IsNaN(v)
+	case bool:
+		ok = valueType == domain.OrderAttributeBoolean
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidOrderAttribute, key)
+	}
+	return nil
+}
+
+// parseAttributeValue reads a search value given as text
+func parseAttributeValue(valueType domain.OrderAttributeType, raw string) (interface{}, error) {
+	switch valueType {
+	case domain.OrderAttributeNumber:
+		return strconv.ParseFloat(raw, 64)
+	case domain.OrderAttributeBoolean:
+		return strconv.ParseBool(raw)
+	default:
+		return raw, nil
+	}
+}
@@ -3,31 +3,97 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"fooddelivery/internal/domain"
 	"fooddelivery/internal/repository"
 	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/storage"
 )
 
 // OrderUsecase handles order-related business logic
 type OrderUsecase struct {
-	orderRepo      *repository.OrderRepository
-	paymentUsecase *PaymentUsecase
-	log            *logger.Logger
+	orderRepo        OrderStore
+	userRepo         UserReader
+	users            *UserCache
+	paymentUsecase   *PaymentUsecase
+	redisClient      *redis.Client
+	pickupSigningKey []byte
+	storage          storage.Store
+	surveyUsecase    *SurveyUsecase
+	notifications    *NotificationUsecase
+	audit            *AuditUsecase
+	stations         *PrepStationUsecase
+	log              *logger.Logger
 }
 
 // NewOrderUsecase creates a new order usecase
-func NewOrderUsecase(orderRepo *repository.OrderRepository, paymentUsecase *PaymentUsecase, log *logger.Logger) *OrderUsecase {
+func NewOrderUsecase(orderRepo OrderStore, userRepo UserReader, paymentUsecase *PaymentUsecase, log *logger.Logger) *OrderUsecase {
 	return &OrderUsecase{
 		orderRepo:      orderRepo,
+		userRepo:       userRepo,
+		users:          NewUserCache(userRepo, nil, log),
 		paymentUsecase: paymentUsecase,
 		log:            log,
 	}
 }
 
+// SetRedisClient sets the Redis client (for dependency injection)
+func (u *OrderUsecase) SetRedisClient(client *redis.Client) {
+	u.redisClient = client
+}
+
+// SetUserCache shares a Redis-backed user cache for rider lookups
+func (u *OrderUsecase) SetUserCache(cache *UserCache) {
+	u.users = cache
+}
+
+// SetPickupSigningKey sets the HMAC key used to sign pickup QR payloads
+func (u *OrderUsecase) SetPickupSigningKey(key []byte) {
+	u.pickupSigningKey = key
+}
+
+// SetStorage sets the object store used for delivery proof photos
+func (u *OrderUsecase) SetStorage(store storage.Store) {
+	u.storage = store
+}
+
+// SetSurveyUsecase enables post-delivery surveys
+func (u *OrderUsecase) SetSurveyUsecase(surveyUsecase *SurveyUsecase) {
+	u.surveyUsecase = surveyUsecase
+}
+
+// SetNotifications enables delivery updates to the customer or, for gift
+// orders, the recipient
+func (u *OrderUsecase) SetNotifications(notifications *NotificationUsecase) {
+	u.notifications = notifications
+}
+
+// SetStations routes accepted orders to kitchen prep stations
+func (u *OrderUsecase) SetStations(stations *PrepStationUsecase) {
+	u.stations = stations
+}
+
+// SetAuditLog records manual order transitions by staff in the admin audit log
+func (u *OrderUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// afterDelivered runs follow-ups once an order reaches DELIVERED
+func (u *OrderUsecase) afterDelivered(ctx context.Context, order *domain.Order) {
+	if u.surveyUsecase != nil {
+		u.surveyUsecase.Schedule(ctx, order)
+	}
+	u.notifications.Delivered(order)
+}
+
 // GetOrder retrieves an order by ID
 func (u *OrderUsecase) GetOrder(ctx context.Context, orderID uuid.UUID) (*domain.Order, error) {
 	order, err := u.orderRepo.GetByID(ctx, orderID)
@@ -37,17 +103,64 @@ func (u *OrderUsecase) GetOrder(ctx context.Context, orderID uuid.UUID) (*domain
 	return order, nil
 }
 
-// GetUserOrders retrieves all orders for a user
-func (u *OrderUsecase) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]domain.Order, error) {
-	orders, err := u.orderRepo.GetByUserID(ctx, userID)
+// Order listing errors
+var (
+	ErrInvalidOrderFilter = errors.New("invalid order filter")
+	ErrInvalidCursor      = errors.New("invalid page cursor")
+)
+
+// Order history page sizes
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// OrderPage is one page of an order listing
+type OrderPage struct {
+	Orders     []domain.Order
+	Limit      int
+	Offset     int
+	HasMore    bool
+	NextCursor string // Set by GetAllOrders when HasMore
+}
+
+// GetUserOrders retrieves a page of a user's orders, newest first
+func (u *OrderUsecase) GetUserOrders(ctx context.Context, userID uuid.UUID, filter repository.OrderListFilter, limit, offset int) (*OrderPage, error) {
+	if filter.Status != "" && !exportableStatuses[filter.Status] {
+		return nil, ErrInvalidOrderFilter
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidOrderFilter
+	}
+	if limit <= 0 {
+		limit = defaultOrderPageSize
+	}
+	limit = min(limit, maxOrderPageSize)
+	offset = max(offset, 0)
+
+	// One extra row tells whether another page follows
+	orders, err := u.orderRepo.GetByUserID(ctx, userID, filter, limit+1, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch user orders: %w", err)
 	}
-	return orders, nil
+
+	page := &OrderPage{Limit: limit, Offset: offset}
+	if len(orders) > limit {
+		orders, page.HasMore = orders[:limit], true
+	}
+	page.Orders = orders
+	return page, nil
 }
 
-// GetAllOrders retrieves all orders (admin only)
-func (u *OrderUsecase) GetAllOrders(ctx context.Context, limit, offset int) ([]domain.Order, error) {
+// GetAllOrders retrieves a page of all orders matching search (admin only).
+// A cursor from the previous page's NextCursor continues after it;
+// otherwise offset is used.
+func (u *OrderUsecase) GetAllOrders(ctx context.Context, search OrderSearch, limit, offset int, cursor string) (*OrderPage, error) {
+	filter, err := u.orderSearchFilter(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
 	if limit <= 0 {
 		limit = 50
 	}
@@ -55,16 +168,62 @@ func (u *OrderUsecase) GetAllOrders(ctx context.Context, limit, offset int) ([]d
 		limit = 100
 	}
 
-	orders, err := u.orderRepo.GetAllOrders(ctx, limit, offset)
+	var after *repository.OrderCursor
+	if cursor != "" {
+		if after, err = decodeOrderCursor(cursor); err != nil {
+			return nil, err
+		}
+		offset = 0
+	}
+	offset = max(offset, 0)
+
+	// One extra row tells whether another page follows
+	orders, err := u.orderRepo.GetAllOrders(ctx, filter, limit+1, offset, after)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch all orders: %w", err)
 	}
-	return orders, nil
+
+	page := &OrderPage{Limit: limit, Offset: offset}
+	if len(orders) > limit {
+		orders, page.HasMore = orders[:limit], true
+		last := orders[limit-1]
+		page.NextCursor = encodeOrderCursor(repository.OrderCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	page.Orders = orders
+	return page, nil
+}
+
+// encodeOrderCursor makes an opaque page token from a cursor
+func encodeOrderCursor(c repository.OrderCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeOrderCursor reads a token from encodeOrderCursor
+func decodeOrderCursor(token string) (*repository.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.OrderCursor{CreatedAt: createdAt, ID: orderID}, nil
 }
 
 // UpdateOrderStatus updates order status (admin only)
-// Valid transitions: PAID -> ACCEPTED -> DELIVERED
-func (u *OrderUsecase) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus domain.OrderStatus) error {
+// Valid transitions: PAID -> ACCEPTED; later steps go through dispatch,
+// delivery OTP or pickup code verification
+func (u *OrderUsecase) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus domain.OrderStatus, adminID uuid.UUID) error {
 	order, err := u.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		return err
@@ -75,6 +234,19 @@ func (u *OrderUsecase) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID,
 		return fmt.Errorf("invalid status transition from %s to %s", order.Status, newStatus)
 	}
 
+	// Hand-over must be confirmed: pickup code at the counter, OTP at the door
+	if newStatus == domain.OrderStatusDelivered {
+		if order.FulfillmentType == domain.FulfillmentPickup {
+			return ErrPickupCodeRequired
+		}
+		return ErrDeliveryOTPRequired
+	}
+
+	// Dispatch assigns a rider and generates the hand-over OTP
+	if newStatus == domain.OrderStatusOutForDelivery {
+		return ErrDispatchRequired
+	}
+
 	if err := u.orderRepo.UpdateStatus(ctx, orderID, newStatus, order.Version); err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
@@ -84,6 +256,27 @@ func (u *OrderUsecase) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID,
 		"old_status", order.Status,
 		"new_status", newStatus,
 	)
+	u.audit.Record(ctx, adminID, domain.AuditOrderStatusChanged, "order", orderID.String(), map[string]interface{}{
+		"old_status": order.Status,
+		"new_status": newStatus,
+	})
+
+	// Marked failed by hand: give back what checkout reserved, as the
+	// payment.failed webhook and cancellation do
+	if newStatus == domain.OrderStatusPaymentFailed && u.paymentUsecase != nil {
+		u.paymentUsecase.releaseSlot(ctx, order)
+		u.paymentUsecase.restoreStock(ctx, order)
+	}
+
+	if newStatus == domain.OrderStatusAccepted {
+		u.notifications.OrderAccepted(order)
+		// The order is accepted either way; admins can route it again by hand
+		if u.stations != nil {
+			if err := u.stations.Route(ctx, order); err != nil {
+				u.log.Error("Failed to route order to prep stations", "order_id", orderID.String(), "error", err)
+			}
+		}
+	}
 
 	return nil
 }
@@ -95,7 +288,8 @@ func isValidStatusTransition(current, next domain.OrderStatus) bool {
 		domain.OrderStatusAwaitingPayment: {domain.OrderStatusPaid, domain.OrderStatusPaymentFailed},
 		domain.OrderStatusPaymentFailed:   {domain.OrderStatusAwaitingPayment}, // Allow retry
 		domain.OrderStatusPaid:            {domain.OrderStatusAccepted},
-		domain.OrderStatusAccepted:        {domain.OrderStatusDelivered},
+		domain.OrderStatusAccepted:        {domain.OrderStatusOutForDelivery, domain.OrderStatusDelivered},
+		domain.OrderStatusOutForDelivery:  {domain.OrderStatusDelivered},
 	}
 
 	allowedNext, ok := validTransitions[current]
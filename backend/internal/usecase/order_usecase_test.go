@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// fakeOrderStore keeps orders in memory. Methods a test doesn't need fall
+// through to the nil embedded OrderStore and panic.
+type fakeOrderStore struct {
+	OrderStore
+	orders    map[uuid.UUID]*domain.Order
+	conflicts int // Cancel calls that fail with a version conflict first
+	cancels   int
+}
+
+func newFakeOrderStore(orders ...*domain.Order) *fakeOrderStore {
+	store := &fakeOrderStore{orders: map[uuid.UUID]*domain.Order{}}
+	for _, order := range orders {
+		store.orders[order.ID] = order
+	}
+	return store
+}
+
+func (s *fakeOrderStore) GetByID(_ context.Context, id uuid.UUID) (*domain.Order, error) {
+	order, ok := s.orders[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *order
+	return &copied, nil
+}
+
+func (s *fakeOrderStore) GetByUserID(_ context.Context, userID uuid.UUID, _ repository.OrderListFilter, limit, offset int) ([]domain.Order, error) {
+	var orders []domain.Order
+	for _, order := range s.orders {
+		if order.UserID == userID {
+			orders = append(orders, *order)
+		}
+	}
+	if offset >= len(orders) {
+		return nil, nil
+	}
+	orders = orders[offset:]
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (s *fakeOrderStore) Cancel(_ context.Context, orderID uuid.UUID, expectedVersion int, cancelledBy *uuid.UUID, reason string) error {
+	s.cancels++
+	order := s.orders[orderID]
+	if s.conflicts > 0 {
+		s.conflicts--
+		order.Version++ // Changed by someone else in the meantime
+		return repository.ErrVersionConflict
+	}
+	if order.Version != expectedVersion {
+		return repository.ErrVersionConflict
+	}
+	order.Status = domain.OrderStatusCancelled
+	order.Version++
+	order.Cancellation = &domain.OrderCancellation{CancelledBy: cancelledBy, Reason: reason}
+	return nil
+}
+
+// fakeUsers finds no users; order tests don't look any up
+type fakeUsers struct{}
+
+func (fakeUsers) GetByID(context.Context, uuid.UUID) (*domain.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func newTestOrderUsecase(store OrderStore) *OrderUsecase {
+	return NewOrderUsecase(store, fakeUsers{}, nil, logger.NewLogger())
+}
+
+func TestCancelOrderRetriesVersionConflicts(t *testing.T) {
+	customer := uuid.New()
+	order := &domain.Order{ID: uuid.New(), UserID: customer, Status: domain.OrderStatusAwaitingPayment, Version: 1}
+	store := newFakeOrderStore(order)
+	store.conflicts = cancelAttempts - 1
+
+	result, err := newTestOrderUsecase(store).CancelOrder(context.Background(), customer, order.ID, false, " changed my mind ")
+	if err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if store.cancels != cancelAttempts {
+		t.Errorf("Cancel called %d times, want %d", store.cancels, cancelAttempts)
+	}
+	if result.Order.Status != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want %s", result.Order.Status, domain.OrderStatusCancelled)
+	}
+	if got := store.orders[order.ID].Cancellation.Reason; got != "changed my mind" {
+		t.Errorf("reason = %q, want it trimmed", got)
+	}
+	if result.RefundAmount != 0 {
+		t.Errorf("refund = %d for an unpaid order", result.RefundAmount)
+	}
+}
+
+func TestCancelOrderGivesUpAfterRepeatedConflicts(t *testing.T) {
+	customer := uuid.New()
+	order := &domain.Order{ID: uuid.New(), UserID: customer, Status: domain.OrderStatusPending, Version: 1}
+	store := newFakeOrderStore(order)
+	store.conflicts = cancelAttempts
+
+	_, err := newTestOrderUsecase(store).CancelOrder(context.Background(), customer, order.ID, false, "")
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("err = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestCancelOrderRules(t *testing.T) {
+	customer := uuid.New()
+	tests := []struct {
+		name    string
+		status  domain.OrderStatus
+		actor   uuid.UUID
+		asAdmin bool
+		want    error
+	}{
+		{"other customer's order", domain.OrderStatusPaid, uuid.New(), false, repository.ErrNotFound},
+		{"admin cancels any order", domain.OrderStatusPending, uuid.New(), true, nil},
+		{"accepted by the kitchen", domain.OrderStatusAccepted, customer, false, ErrOrderNotCancellable},
+		{"already cancelled", domain.OrderStatusCancelled, customer, false, ErrOrderNotCancellable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &domain.Order{ID: uuid.New(), UserID: customer, Status: tt.status, Version: 1}
+			store := newFakeOrderStore(order)
+
+			_, err := newTestOrderUsecase(store).CancelOrder(context.Background(), tt.actor, order.ID, tt.asAdmin, "")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && store.cancels != 0 {
+				t.Errorf("order was cancelled despite %v", tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserOrdersPages(t *testing.T) {
+	customer := uuid.New()
+	store := newFakeOrderStore()
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		store.orders[id] = &domain.Order{ID: id, UserID: customer}
+	}
+	u := newTestOrderUsecase(store)
+
+	page, err := u.GetUserOrders(context.Background(), customer, repository.OrderListFilter{}, 2, 0)
+	if err != nil {
+		t.Fatalf("GetUserOrders: %v", err)
+	}
+	if len(page.Orders) != 2 || !page.HasMore {
+		t.Errorf("first page: %d orders, has_more %v; want 2, true", len(page.Orders), page.HasMore)
+	}
+
+	page, err = u.GetUserOrders(context.Background(), customer, repository.OrderListFilter{}, 2, 2)
+	if err != nil {
+		t.Fatalf("GetUserOrders: %v", err)
+	}
+	if len(page.Orders) != 1 || page.HasMore {
+		t.Errorf("last page: %d orders, has_more %v; want 1, false", len(page.Orders), page.HasMore)
+	}
+}
@@ -0,0 +1,43 @@
+// Package usecase implements texting OTPs to users
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fooddelivery/pkg/sms"
+	"fooddelivery/pkg/throttle"
+)
+
+// otpValidity is how long an OTP can be verified after it is sent
+const otpValidity = 10 * time.Minute
+
+// SetOTPSender enables texting OTPs, rendered from the login_otp template.
+// Without it OTPs are stored but never delivered.
+func (u *UserUsecase) SetOTPSender(sender sms.Sender, templates *TemplateUsecase) {
+	u.sms = sender
+	u.templates = templates
+}
+
+// deliverOTP texts an OTP to a phone number ahead of other queued texts, in
+// locale or, when empty, the restaurant locale. The code is never logged.
+func (u *UserUsecase) deliverOTP(ctx context.Context, phoneNumber, code, locale string) error {
+	if u.sms == nil || u.templates == nil {
+		u.log.Warn("No SMS sender configured, OTP not delivered", "to", sms.MaskNumber(phoneNumber))
+		return nil
+	}
+
+	msg, err := u.templates.Render(ctx, TemplateLoginOTP, locale, map[string]any{
+		"code":            code,
+		"expires_minutes": int(otpValidity / time.Minute),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render OTP message: %w", err)
+	}
+
+	if err := u.sms.Send(throttle.WithPriority(ctx, throttle.PriorityOTP), phoneNumber, msg.Body); err != nil {
+		return fmt.Errorf("failed to send OTP: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package usecase implements payment review of orders whose captured payment
+// doesn't match them
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/logger"
+)
+
+// Payment review errors
+var (
+	ErrPaymentMismatch = errors.New("captured payment does not match the order")
+	ErrResolutionNote  = errors.New("a note is required to resolve a payment review")
+)
+
+// paymentReviewListLimit caps the open review queue returned to admins
+const paymentReviewListLimit = 200
+
+// capturedPayment is the part of a Razorpay payment checked against the order
+type capturedPayment struct {
+	ID       string `json:"id"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	OrderID  string `json:"order_id"`
+	Status   string `json:"status"`
+}
+
+// SetAuditLog records resolved payment reviews in the admin audit log
+func (u *PaymentUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// paymentMismatch describes how a captured payment differs from the order
+// it was captured for, or returns "" when it matches
+func paymentMismatch(order *domain.Order, payment capturedPayment) string {
+	if payment.OrderID != order.RazorpayOrderID {
+		return fmt.Sprintf("payment %s belongs to Razorpay order %s, not %s", payment.ID, payment.OrderID, order.RazorpayOrderID)
+	}
+	if !strings.EqualFold(payment.Currency, "INR") {
+		return fmt.Sprintf("payment %s was captured in %s, the order is in INR", payment.ID, payment.Currency)
+	}
+	if payment.Amount != order.TotalAmount {
+		return fmt.Sprintf("payment %s captured %s, the order total is %s", payment.ID, formatRupees(payment.Amount), formatRupees(order.TotalAmount))
+	}
+	return ""
+}
+
+// flagPaymentMismatch holds the order for admin review and alerts admins. A
+// redelivered webhook for the same payment doesn't reopen a resolved review.
+func (u *PaymentUsecase) flagPaymentMismatch(ctx context.Context, order *domain.Order, reason string, log *logger.Logger) {
+	log.Warn("Captured payment does not match order, holding for review", "reason", reason)
+
+	if order.PaymentReview != nil && order.PaymentReview.Reason == reason {
+		return
+	}
+	if err := u.orderRepo.FlagPaymentReview(ctx, order.ID, reason); err != nil {
+		log.Error("Failed to flag order for payment review", "error", err)
+	}
+
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertPaymentMismatch,
+		Critical: true,
+		Title:    "Payment mismatch on order " + orderNumber(order.ID),
+		Body:     reason + ". The order was not marked paid and is waiting for review.",
+		Data: map[string]string{
+			"type":     "payment_review",
+			"order_id": order.ID.String(),
+		},
+	})
+}
+
+// fetchPayment loads a payment from Razorpay
+func (u *PaymentUsecase) fetchPayment(ctx context.Context, paymentID string) (*capturedPayment, error) {
+	client, _ := u.gateway()
+	var raw map[string]interface{}
+	err := u.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		raw, err = client.Payment.Fetch(paymentID, nil, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON for the typed fields (amount arrives as float64)
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var payment capturedPayment
+	if err := json.Unmarshal(data, &payment); err != nil {
+		return nil, fmt.Errorf("unexpected payment response: %w", err)
+	}
+	return &payment, nil
+}
+
+// GetOpenPaymentReviews returns orders held for payment review, oldest first
+func (u *PaymentUsecase) GetOpenPaymentReviews(ctx context.Context) ([]domain.Order, error) {
+	orders, err := u.orderRepo.GetOpenPaymentReviews(ctx, paymentReviewListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment reviews: %w", err)
+	}
+	return orders, nil
+}
+
+// ResolvePaymentReview closes an order's payment review once an admin has
+// settled it with Razorpay. The order's status is changed separately (to
+// PAID or PAYMENT_FAILED); the note records what was done.
+func (u *PaymentUsecase) ResolvePaymentReview(ctx context.Context, adminID, orderID uuid.UUID, note string) (*domain.Order, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, ErrResolutionNote
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.orderRepo.ResolvePaymentReview(ctx, orderID, adminID); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"note":   note,
+		"status": order.Status,
+	}
+	if order.PaymentReview != nil {
+		details["reason"] = order.PaymentReview.Reason
+	}
+	u.audit.Record(ctx, adminID, domain.AuditPaymentReviewResolved, "order", orderID.String(), details)
+	u.log.Info("Payment review resolved", "order_id", orderID.String(), "admin_id", adminID.String())
+
+	return u.orderRepo.GetByID(ctx, orderID)
+}
@@ -10,43 +10,86 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	razorpay "github.com/razorpay/razorpay-go"
+	rzperrors "github.com/razorpay/razorpay-go/errors"
 
 	"fooddelivery/internal/config"
 	"fooddelivery/internal/domain"
 	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/database"
+	"fooddelivery/pkg/geo"
 	"fooddelivery/pkg/logger"
 	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/retry"
 )
 
 // Payment-related errors
 var (
-	ErrInvalidCart        = errors.New("invalid cart: no items or invalid quantities")
-	ErrItemNotAvailable   = errors.New("one or more items are not available")
-	ErrPaymentFailed      = errors.New("payment verification failed")
-	ErrInvalidSignature   = errors.New("invalid webhook signature")
-	ErrOrderAlreadyPaid   = errors.New("order has already been paid")
-	ErrDuplicateRequest   = errors.New("duplicate request detected")
+	ErrInvalidCart             = errors.New("invalid cart: no items or invalid quantities")
+	ErrItemNotAvailable        = errors.New("one or more items are not available")
+	ErrPaymentFailed           = errors.New("payment verification failed")
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+	ErrOrderAlreadyPaid        = errors.New("order has already been paid")
+	ErrDuplicateRequest        = errors.New("duplicate request detected")
+	ErrInvalidFulfillment      = errors.New("invalid fulfillment type")
+	ErrInvalidDeliveryLocation = errors.New("delivery orders require an address and valid coordinates")
+	ErrInvalidRecipient        = errors.New("gift recipients need a name and phone number and delivery fulfillment")
+	ErrInvalidPackaging        = errors.New("packaging must be STANDARD, MINIMAL or PLASTIC_FREE")
 )
 
+// Gift recipient limits
+const maxRecipientNameLength = 100
+
+// recipientPhonePattern accepts an optional + and 10-15 digits
+var recipientPhonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
 // PaymentUsecase handles all payment-related business logic
 type PaymentUsecase struct {
-	orderRepo   *repository.OrderRepository
-	menuRepo    *repository.MenuRepository
-	razorpay    *razorpay.Client
-	redisClient *redis.Client
-	config      config.RazorpayConfig
-	log         *logger.Logger
+	orderRepo     *repository.OrderRepository
+	menuRepo      *repository.MenuRepository
+	paymentEvents *repository.PaymentEventRepository
+	refunds       *repository.RefundRepository
+	webhookLogs   *repository.WebhookLogRepository
+	redisClient   *redis.Client
+	deliveryFee   *DeliveryFeeUsecase
+	pricing       *PricingUsecase
+	inventory     *InventoryUsecase
+	eta           *ETAUsecase
+	capacity      *CapacityUsecase
+	deliverySlots *DeliverySlotUsecase
+	settings      *SettingsUsecase
+	notifications *NotificationUsecase
+	users         *UserCache
+	addresses     *AddressUsecase
+	alerts        *AdminAlertUsecase
+	audit         *AuditUsecase
+	uow           database.UnitOfWork
+	retry         retry.Policy
+	webhookRetry  retry.Policy // Replays of failed webhooks; off unless set
+
+	priceConfirmThreshold int64
+	log                   *logger.Logger
+
+	// Credentials can be rotated at runtime by the secrets manager
+	credsMu  sync.RWMutex
+	razorpay *razorpay.Client
+	config   config.RazorpayConfig
 }
 
 // NewPaymentUsecase creates a new payment usecase
 func NewPaymentUsecase(
 	orderRepo *repository.OrderRepository,
 	menuRepo *repository.MenuRepository,
+	paymentEvents *repository.PaymentEventRepository,
+	refunds *repository.RefundRepository,
+	webhookLogs *repository.WebhookLogRepository,
 	cfg config.RazorpayConfig,
 	log *logger.Logger,
 ) *PaymentUsecase {
@@ -54,35 +97,213 @@ func NewPaymentUsecase(
 	razorpayClient := razorpay.NewClient(cfg.KeyID, cfg.KeySecret)
 
 	return &PaymentUsecase{
-		orderRepo:   orderRepo,
-		menuRepo:    menuRepo,
-		razorpay:    razorpayClient,
-		config:      cfg,
-		log:         log,
+		orderRepo:     orderRepo,
+		menuRepo:      menuRepo,
+		paymentEvents: paymentEvents,
+		refunds:       refunds,
+		webhookLogs:   webhookLogs,
+		razorpay:      razorpayClient,
+		retry:         retry.NoRetry,
+		config:        cfg,
+		log:           log,
 	}
 }
 
+// SetRazorpayCredentials swaps the API key pair after a rotation. Orders
+// created under the old key are verified with the new secret, so rotate on
+// the Razorpay dashboard and in the secrets manager together.
+func (u *PaymentUsecase) SetRazorpayCredentials(keyID, keySecret string) {
+	u.credsMu.Lock()
+	defer u.credsMu.Unlock()
+	u.config.KeyID = keyID
+	u.config.KeySecret = keySecret
+	u.razorpay = razorpay.NewClient(keyID, keySecret)
+}
+
+// SetWebhookSecret swaps the webhook signing secret after a rotation
+func (u *PaymentUsecase) SetWebhookSecret(secret string) {
+	u.credsMu.Lock()
+	defer u.credsMu.Unlock()
+	u.config.WebhookSecret = secret
+}
+
+// gateway returns the current Razorpay client and credentials
+func (u *PaymentUsecase) gateway() (*razorpay.Client, config.RazorpayConfig) {
+	u.credsMu.RLock()
+	defer u.credsMu.RUnlock()
+	return u.razorpay, u.config
+}
+
 // SetRedisClient sets the Redis client (for dependency injection)
 func (u *PaymentUsecase) SetRedisClient(client *redis.Client) {
 	u.redisClient = client
 }
 
+// SetRetryPolicy sets how failed Razorpay API calls are retried. Client
+// errors (bad request) are never retried.
+func (u *PaymentUsecase) SetRetryPolicy(policy retry.Policy) {
+	policy.Retryable = razorpayRetryable
+	u.retry = policy
+}
+
+// gatewayFailureWindow and gatewayFailureThreshold decide when failed
+// gateway calls look like an outage rather than bad luck
+const (
+	gatewayFailureWindow    = 5 * time.Minute
+	gatewayFailureThreshold = 3
+)
+
+// recordGatewayFailure counts a failed gateway call and raises a critical
+// alert once failures within the window reach the threshold. Rejected
+// requests are our fault, not an outage, and aren't counted.
+func (u *PaymentUsecase) recordGatewayFailure(ctx context.Context, err error) {
+	if u.alerts == nil || u.redisClient == nil || !razorpayRetryable(err) {
+		return
+	}
+
+	failures, incrErr := u.redisClient.IncrWithTTL(ctx, redis.GatewayFailuresKey, gatewayFailureWindow)
+	if incrErr != nil {
+		u.log.Warn("Failed to count gateway failure", "error", incrErr)
+		return
+	}
+	if failures < gatewayFailureThreshold {
+		return
+	}
+
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertPaymentGatewayFailed,
+		Critical: true,
+		Title:    "Payments failing",
+		Body:     fmt.Sprintf("%d checkout attempts could not create a Razorpay order in the last %d minutes", failures, int(gatewayFailureWindow.Minutes())),
+		Data:     map[string]string{"type": "payment_outage"},
+	})
+}
+
+// razorpayRetryable retries gateway/server errors and network failures but
+// not requests Razorpay rejected as invalid
+func razorpayRetryable(err error) bool {
+	var badRequest *rzperrors.BadRequestError
+	if errors.As(err, &badRequest) {
+		return false
+	}
+	return retry.DefaultClassifier(err)
+}
+
+// SetDeliveryFeeUsecase enables distance-based delivery fees. Without it
+// delivery orders are charged no fee.
+func (u *PaymentUsecase) SetDeliveryFeeUsecase(deliveryFee *DeliveryFeeUsecase) {
+	u.deliveryFee = deliveryFee
+}
+
+// SetPricing adds the packaging charge and GST to orders. Without it
+// orders are charged the items and delivery fee only.
+func (u *PaymentUsecase) SetPricing(pricing *PricingUsecase) {
+	u.pricing = pricing
+}
+
+// SetInventory takes ordered items out of stock at checkout and puts them
+// back when an order is cancelled or its payment fails. Without it stock
+// isn't tracked.
+func (u *PaymentUsecase) SetInventory(inventory *InventoryUsecase) {
+	u.inventory = inventory
+}
+
+// SetUnitOfWork makes order creation atomic: the slot bookings, the order
+// insert and its stock commit or roll back together. Without it a failure
+// is undone step by step.
+func (u *PaymentUsecase) SetUnitOfWork(uow database.UnitOfWork) {
+	u.uow = uow
+}
+
+// SetETAUsecase enables promised delivery times on new orders
+func (u *PaymentUsecase) SetETAUsecase(eta *ETAUsecase) {
+	u.eta = eta
+}
+
+// SetCapacityUsecase enables kitchen slot booking at checkout
+func (u *PaymentUsecase) SetCapacityUsecase(capacity *CapacityUsecase) {
+	u.capacity = capacity
+}
+
+// SetDeliverySlots lets delivery orders book a 30-minute delivery window
+func (u *PaymentUsecase) SetDeliverySlots(slots *DeliverySlotUsecase) {
+	u.deliverySlots = slots
+}
+
+// SetNotifications sends payment receipts to the payer
+func (u *PaymentUsecase) SetNotifications(notifications *NotificationUsecase) {
+	u.notifications = notifications
+}
+
+// SetUserCache lets checkout refuse orders from deactivated accounts, whose
+// login tokens may not have expired yet
+func (u *PaymentUsecase) SetUserCache(cache *UserCache) {
+	u.users = cache
+}
+
+// SetAddresses lets checkout deliver to one of the customer's saved
+// addresses
+func (u *PaymentUsecase) SetAddresses(addresses *AddressUsecase) {
+	u.addresses = addresses
+}
+
+// SetAlerts pages admins when the payment gateway keeps failing or a
+// captured payment doesn't match its order
+func (u *PaymentUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// SetSettings lets checkout load runtime settings once per order, together
+// with the idempotency lookup
+func (u *PaymentUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
 // InitiateOrderRequest contains the data needed to create an order
 type InitiateOrderRequest struct {
-	UserID uuid.UUID            `json:"user_id"`
-	Items  []domain.CartItem    `json:"items"`
+	UserID           uuid.UUID                `json:"user_id"`
+	Items            []domain.CartItem        `json:"items"`
+	FulfillmentType  domain.FulfillmentType   `json:"fulfillment_type"`
+	DeliveryLocation *domain.DeliveryLocation `json:"delivery_location,omitempty"`
+	AddressID        *uuid.UUID               `json:"address_id,omitempty"` // Saved address; replaces DeliveryLocation
+	Recipient        *domain.OrderRecipient   `json:"recipient,omitempty"`  // Deliver to someone else
+	NoCutlery        bool                     `json:"no_cutlery,omitempty"`
+	Packaging        domain.Packaging         `json:"packaging,omitempty"`       // Defaults to STANDARD
+	ScheduledFor     *time.Time               `json:"scheduled_for,omitempty"`   // Book a later kitchen slot
+	DisplayedTotal   *int64                   `json:"displayed_total,omitempty"` // Total the app showed, in paisa
+	ConfirmedTotal   *int64                   `json:"confirmed_total,omitempty"` // Customer accepted this changed total
+
+	// Severe allergens in the cart the customer was warned of and accepts
+	Allergens []domain.Allergen `json:"acknowledged_allergens,omitempty"`
+
+	// Any time in the delivery window the customer picked; the kitchen
+	// slot is planned back from it unless ScheduledFor is set
+	DeliverySlot *time.Time `json:"delivery_slot,omitempty"`
 }
 
 // InitiateOrderResponse contains the Razorpay order details for client
 type InitiateOrderResponse struct {
-	ID              uuid.UUID `json:"id"`
-	RazorpayOrderID string    `json:"razorpay_order_id"`
-	KeyID           string    `json:"key_id"`
-	Amount          int64     `json:"amount"` // Amount in paisa
-	Currency        string    `json:"currency"`
-	Receipt         string    `json:"receipt"`
-	Name            string    `json:"name"`
-	Description     string    `json:"description"`
+	ID              uuid.UUID        `json:"id"`
+	RazorpayOrderID string           `json:"razorpay_order_id"`
+	KeyID           string           `json:"key_id"`
+	Amount          int64            `json:"amount"`       // Amount in paisa
+	DeliveryFee     int64            `json:"delivery_fee"` // Included in Amount
+	Subtotal        int64            `json:"subtotal"`     // Items, included in Amount
+	PackagingCharge int64            `json:"packaging_charge"`
+	TaxAmount       int64            `json:"tax_amount"` // GST on items and packaging
+	EstimatedAt     *time.Time       `json:"estimated_delivery_at,omitempty"`
+	KitchenSlotAt   *time.Time       `json:"kitchen_slot_at,omitempty"`
+	PriceAdjustment *PriceAdjustment `json:"price_adjustment,omitempty"` // Prices differed from what the app displayed
+	Currency        string           `json:"currency"`
+	Receipt         string           `json:"receipt"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+
+	// Allergens in the cart, for the confirmation screen
+	Allergens []domain.AllergenWarning `json:"allergen_warnings,omitempty"`
+
+	// Start of the booked delivery window
+	DeliverySlotAt *time.Time `json:"delivery_slot_at,omitempty"`
 }
 
 // InitiateOrder creates a new order and Razorpay payment order.
@@ -92,25 +313,43 @@ func (u *PaymentUsecase) InitiateOrder(ctx context.Context, req InitiateOrderReq
 		"user_id": req.UserID.String(),
 	})
 
-	// Validate cart
-	if len(req.Items) == 0 {
-		return nil, ErrInvalidCart
+	address, err := u.savedAddress(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCart(&req); err != nil {
+		return nil, err
 	}
 
-	for _, item := range req.Items {
-		if item.Quantity <= 0 {
-			return nil, ErrInvalidCart
+	var user *domain.User
+	if u.users != nil {
+		var err error
+		user, err = u.users.Get(ctx, req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user: %w", err)
+		}
+		if user.IsDeactivated() {
+			return nil, ErrAccountDeactivated
 		}
 	}
 
 	// Generate cart hash for idempotency check
 	// Same cart contents within 1 minute = same order
-	cartHash := u.generateCartHash(req.UserID, req.Items)
+	cartHash := u.generateCartHash(req)
 	idempotencyKey := redis.IdempotencyPrefix + cartHash
 
-	// Check for existing order with same cart (idempotency)
-	if u.redisClient != nil {
-		var existingResponse InitiateOrderResponse
+	// Check for existing order with same cart (idempotency). With settings
+	// configured the lookup shares one MGET with the settings overrides, which
+	// fee, ETA and slot calculations below then read from ctx.
+	var existingResponse InitiateOrderResponse
+	if u.settings != nil {
+		var found []bool
+		ctx, found = u.settings.Preload(ctx, []string{idempotencyKey}, []any{&existingResponse})
+		if found[0] {
+			log.Info("Returning cached order (idempotent request)", "razorpay_order_id", existingResponse.RazorpayOrderID)
+			return &existingResponse, nil
+		}
+	} else if u.redisClient != nil {
 		found, err := u.redisClient.GetJSON(ctx, idempotencyKey, &existingResponse)
 		if err != nil {
 			log.Warn("Failed to check idempotency cache", "error", err)
@@ -121,98 +360,97 @@ func (u *PaymentUsecase) InitiateOrder(ctx context.Context, req InitiateOrderReq
 		}
 	}
 
-	// Extract menu item IDs
-	menuItemIDs := make([]uuid.UUID, len(req.Items))
-	quantityMap := make(map[uuid.UUID]int)
-	for i, item := range req.Items {
-		menuItemIDs[i] = item.MenuItemID
-		quantityMap[item.MenuItemID] = item.Quantity
-	}
-
-	// Fetch menu items from database (NEVER trust client prices)
-	menuItems, err := u.menuRepo.GetByIDs(ctx, menuItemIDs)
+	price, err := u.priceCart(ctx, req, log)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch menu items: %w", err)
+		return nil, err
 	}
+	totalAmount := price.Total
 
-	// Validate all items exist and are available
-	if len(menuItems) != len(req.Items) {
-		return nil, ErrItemNotAvailable
+	// Severe allergens must be acknowledged before the order is placed
+	acknowledged, err := acknowledgeAllergens(price.Allergens, req.Allergens)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate total server-side (critical for security)
-	var totalAmount int64
-	orderItems := make([]domain.OrderItem, 0, len(menuItems))
-
-	for _, menuItem := range menuItems {
-		if !menuItem.IsAvailable {
-			return nil, ErrItemNotAvailable
+	// Never silently charge noticeably more than the app displayed; the
+	// customer confirms the exact new total
+	adjustment := u.comparePrices(ctx, req, price)
+	if adjustment != nil {
+		log.Warn("Cart priced differently than displayed",
+			"displayed_total", adjustment.DisplayedTotal,
+			"current_total", adjustment.CurrentTotal,
+			"changed_items", len(adjustment.Lines),
+		)
+		if adjustment.RequiresConfirmation &&
+			(req.ConfirmedTotal == nil || *req.ConfirmedTotal != adjustment.CurrentTotal) {
+			return nil, &PriceChangedError{Adjustment: adjustment}
 		}
-
-		quantity := quantityMap[menuItem.ID]
-		itemTotal := menuItem.Price * int64(quantity)
-		totalAmount += itemTotal
-
-		orderItems = append(orderItems, domain.OrderItem{
-			MenuItemID: menuItem.ID,
-			Name:       menuItem.Name,
-			Price:      menuItem.Price,
-			Quantity:   quantity,
-		})
 	}
+	deliveryFee := price.DeliveryFee
 
 	// Create order in database with PENDING status
 	order := &domain.Order{
-		UserID:      req.UserID,
-		Status:      domain.OrderStatusPending,
-		TotalAmount: totalAmount,
-		Items:       orderItems,
+		UserID:                 req.UserID,
+		Status:                 domain.OrderStatusPending,
+		TotalAmount:            totalAmount,
+		Items:                  price.Items,
+		FulfillmentType:        req.FulfillmentType,
+		DeliveryLocation:       req.DeliveryLocation,
+		DeliveryAddress:        address,
+		Recipient:              req.Recipient,
+		Preferences:            domain.OrderPreferences{NoCutlery: req.NoCutlery, Packaging: req.Packaging},
+		AcknowledgedAllergens:  acknowledged,
+		DeliveryFee:            deliveryFee,
+		DeliveryDistanceMeters: price.DeliveryDistanceMeters,
+		Subtotal:               price.Subtotal,
+		PackagingCharge:        price.PackagingCharge,
+		TaxAmount:              price.TaxAmount,
+		TaxRateBasisPoints:     price.TaxRateBasisPoints,
+	}
+
+	// Staff and sandbox orders are tagged as tests so analytics skip them
+	_, creds := u.gateway()
+	if rule := u.testOrderRule(ctx, user, creds.KeyID); rule != "" {
+		order.Tags = []string{domain.OrderTagTest}
+		log.Info("Order detected as a test order", "rule", rule)
 	}
 
-	if err := u.orderRepo.Create(ctx, order); err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+	// Pickup orders get the code the customer shows at the counter
+	if order.FulfillmentType == domain.FulfillmentPickup {
+		code, err := generateOTP()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate pickup code: %w", err)
+		}
+		order.PickupCode = code
 	}
 
 	log = log.WithFields(map[string]interface{}{
-		"order_id": order.ID.String(),
-		"amount":   totalAmount,
+		"amount": totalAmount,
 	})
 
-	// Create Razorpay order
-	razorpayData := map[string]interface{}{
-		"amount":          totalAmount, // Already in paisa
-		"currency":        "INR",
-		"receipt":         order.ID.String(),
-		"payment_capture": 1, // Auto-capture payment
-		"notes": map[string]interface{}{
-			"order_id": order.ID.String(),
-			"user_id":  req.UserID.String(),
-		},
-	}
-
-	razorpayOrder, err := u.razorpay.Order.Create(razorpayData, nil)
+	// Slots, insert, stock and gateway order are placed together; a failed
+	// step undoes the earlier ones
+	razorpayOrderID, err := u.checkout(req).place(ctx, order, log)
 	if err != nil {
-		log.Error("Failed to create Razorpay order", "error", err)
-		// Mark order as failed
-		_ = u.orderRepo.UpdateStatus(ctx, order.ID, domain.OrderStatusPaymentFailed, order.Version)
-		return nil, fmt.Errorf("failed to create payment order: %w", err)
+		return nil, err
 	}
 
-	razorpayOrderID := razorpayOrder["id"].(string)
-
-	// Update order with Razorpay order ID
-	if err := u.orderRepo.SetRazorpayOrderID(ctx, order.ID, razorpayOrderID, order.Version); err != nil {
-		log.Error("Failed to update order with Razorpay ID", "error", err)
-		return nil, fmt.Errorf("failed to update order: %w", err)
-	}
-
-	log.Info("Order created successfully", "razorpay_order_id", razorpayOrderID)
+	log.Info("Order created successfully", "order_id", order.ID.String(), "razorpay_order_id", razorpayOrderID)
 
 	response := &InitiateOrderResponse{
 		ID:              order.ID,
 		RazorpayOrderID: razorpayOrderID,
-		KeyID:           u.config.KeyID,
+		KeyID:           creds.KeyID,
 		Amount:          totalAmount,
+		DeliveryFee:     deliveryFee,
+		Subtotal:        price.Subtotal,
+		PackagingCharge: price.PackagingCharge,
+		TaxAmount:       price.TaxAmount,
+		EstimatedAt:     order.EstimatedDeliveryAt,
+		KitchenSlotAt:   order.KitchenSlotAt,
+		PriceAdjustment: adjustment,
+		Allergens:       price.Allergens,
+		DeliverySlotAt:  order.DeliverySlotAt,
 		Currency:        "INR",
 		Receipt:         order.ID.String(),
 		Name:            "Food Delivery",
@@ -230,6 +468,271 @@ func (u *PaymentUsecase) InitiateOrder(ctx context.Context, req InitiateOrderReq
 	return response, nil
 }
 
+// OrderQuote is what a cart would be charged at checkout right now. Prices
+// come from the same calculation as InitiateOrder; nothing is created and
+// no kitchen slot is held.
+type OrderQuote struct {
+	Items                  []QuoteLine `json:"items"`
+	Subtotal               int64       `json:"subtotal"`         // Items only, in paisa
+	DeliveryFee            int64       `json:"delivery_fee"`     // Included in Total
+	PackagingCharge        int64       `json:"packaging_charge"` // Included in Total
+	TaxAmount              int64       `json:"tax_amount"`       // GST on items and packaging, included in Total
+	TaxRateBasisPoints     int         `json:"tax_rate_basis_points"`
+	DeliveryDistanceMeters *int        `json:"delivery_distance_meters,omitempty"`
+	Total                  int64       `json:"total"` // Amount checkout will charge, in paisa
+	Currency               string      `json:"currency"`
+	EstimatedAt            *time.Time  `json:"estimated_delivery_at,omitempty"`
+
+	// Allergens in the cart; checkout needs the severe ones acknowledged
+	Allergens []domain.AllergenWarning `json:"allergen_warnings,omitempty"`
+}
+
+// QuoteLine is one priced cart item
+type QuoteLine struct {
+	MenuItemID  uuid.UUID                  `json:"menu_item_id"`
+	Name        string                     `json:"name"`
+	VariantID   *uuid.UUID                 `json:"variant_id,omitempty"`
+	VariantName string                     `json:"variant_name,omitempty"`
+	Modifiers   []domain.OrderItemModifier `json:"modifiers,omitempty"`
+	Price       int64                      `json:"price"` // Unit price in paisa, with the variant and modifiers
+	Quantity    int                        `json:"quantity"`
+	Subtotal    int64                      `json:"subtotal"`
+}
+
+// Quote prices a cart exactly as InitiateOrder would, without creating an
+// order or booking kitchen capacity, so the cart screen can show the final
+// charge. Settings changes or a menu edit between quote and checkout can
+// still change the amount.
+func (u *PaymentUsecase) Quote(ctx context.Context, req InitiateOrderRequest) (*OrderQuote, error) {
+	if _, err := u.savedAddress(ctx, &req); err != nil {
+		return nil, err
+	}
+	if err := validateCart(&req); err != nil {
+		return nil, err
+	}
+
+	log := u.log.WithFields(map[string]interface{}{
+		"user_id": req.UserID.String(),
+	})
+
+	if u.settings != nil {
+		ctx, _ = u.settings.Preload(ctx, nil, nil)
+	}
+
+	price, err := u.priceCart(ctx, req, log)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &OrderQuote{
+		Items:                  make([]QuoteLine, 0, len(price.Items)),
+		Subtotal:               price.Subtotal,
+		DeliveryFee:            price.DeliveryFee,
+		PackagingCharge:        price.PackagingCharge,
+		TaxAmount:              price.TaxAmount,
+		TaxRateBasisPoints:     price.TaxRateBasisPoints,
+		DeliveryDistanceMeters: price.DeliveryDistanceMeters,
+		Total:                  price.Total,
+		Currency:               "INR",
+		Allergens:              price.Allergens,
+	}
+	for _, item := range price.Items {
+		quote.Items = append(quote.Items, QuoteLine{
+			MenuItemID:  item.MenuItemID,
+			Name:        item.Name,
+			VariantID:   item.VariantID,
+			VariantName: item.VariantName,
+			Modifiers:   item.Modifiers,
+			Price:       item.Price,
+			Quantity:    item.Quantity,
+			Subtotal:    item.Subtotal(),
+		})
+	}
+
+	// The estimate assumes the requested (or current) slot has room; only
+	// checkout checks capacity
+	if u.eta != nil {
+		var kitchenStart time.Time
+		if req.ScheduledFor != nil {
+			kitchenStart = SlotStart(*req.ScheduledFor)
+		}
+		estimate := u.eta.Estimate(ctx, req.FulfillmentType, req.DeliveryLocation, price.DeliveryDistanceMeters, kitchenStart)
+		quote.EstimatedAt = &estimate.EstimatedAt
+		if req.DeliverySlot != nil {
+			if slot := DeliverySlotStart(*req.DeliverySlot); quote.EstimatedAt.Before(slot) {
+				quote.EstimatedAt = &slot
+			}
+		}
+	}
+
+	return quote, nil
+}
+
+// savedAddress loads the saved address a delivery checkout picked, if any,
+// and makes it the request's delivery location
+func (u *PaymentUsecase) savedAddress(ctx context.Context, req *InitiateOrderRequest) (*domain.Address, error) {
+	if req.AddressID == nil || req.FulfillmentType == domain.FulfillmentPickup {
+		return nil, nil
+	}
+	if u.addresses == nil {
+		return nil, ErrAddressNotFound
+	}
+
+	address, err := u.addresses.Get(ctx, req.UserID, *req.AddressID)
+	if err != nil {
+		return nil, err
+	}
+	req.DeliveryLocation = address.Location()
+	return address, nil
+}
+
+// validateCart checks a checkout request and applies defaults: fulfillment
+// falls back to delivery and pickup orders drop any delivery location
+func validateCart(req *InitiateOrderRequest) error {
+	if len(req.Items) == 0 {
+		return ErrInvalidCart
+	}
+
+	lines := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return ErrInvalidCart
+		}
+		// The same item with the same options belongs on one line
+		if lines[item.LineKey()] {
+			return ErrInvalidCart
+		}
+		lines[item.LineKey()] = true
+	}
+
+	if req.FulfillmentType == "" {
+		req.FulfillmentType = domain.FulfillmentDelivery
+	}
+	if !req.FulfillmentType.IsValid() {
+		return ErrInvalidFulfillment
+	}
+
+	// Delivery orders need a drop-off point for dispatch and routing
+	if req.FulfillmentType == domain.FulfillmentDelivery {
+		loc := req.DeliveryLocation
+		if loc == nil || strings.TrimSpace(loc.Address) == "" ||
+			!(geo.Point{Latitude: loc.Latitude, Longitude: loc.Longitude}).Valid() {
+			return ErrInvalidDeliveryLocation
+		}
+	} else {
+		req.DeliveryLocation = nil
+	}
+
+	// Gift orders: the rider and the SMS updates need a reachable recipient
+	if r := req.Recipient; r != nil {
+		r.Name = strings.TrimSpace(r.Name)
+		r.PhoneNumber = strings.ReplaceAll(strings.TrimSpace(r.PhoneNumber), " ", "")
+		if req.FulfillmentType != domain.FulfillmentDelivery ||
+			r.Name == "" || len(r.Name) > maxRecipientNameLength ||
+			!recipientPhonePattern.MatchString(r.PhoneNumber) {
+			return ErrInvalidRecipient
+		}
+	}
+
+	if req.DeliverySlot != nil && req.FulfillmentType != domain.FulfillmentDelivery {
+		return ErrDeliverySlotPickup
+	}
+
+	if req.Packaging == "" {
+		req.Packaging = domain.PackagingStandard
+	}
+	if !req.Packaging.IsValid() {
+		return ErrInvalidPackaging
+	}
+
+	return nil
+}
+
+// cartPrice is the server-side price of a validated cart
+type cartPrice struct {
+	Items                  []domain.OrderItem
+	Subtotal               int64
+	DeliveryFee            int64
+	DeliveryDistanceMeters *int
+	OrderCharges
+	Total     int64
+	Allergens []domain.AllergenWarning
+}
+
+// priceCart computes a cart's charge from current menu prices, the
+// delivery tariff, the packaging charge and GST. Checkout and quotes share
+// it so they can't disagree.
+func (u *PaymentUsecase) priceCart(ctx context.Context, req InitiateOrderRequest, log *logger.Logger) (*cartPrice, error) {
+	// Extract menu item IDs; an item can be on several lines with
+	// different variants or modifiers
+	menuItemIDs := make([]uuid.UUID, 0, len(req.Items))
+	quantityMap := make(map[uuid.UUID]int)
+	for _, item := range req.Items {
+		if _, ok := quantityMap[item.MenuItemID]; !ok {
+			menuItemIDs = append(menuItemIDs, item.MenuItemID)
+		}
+		quantityMap[item.MenuItemID] += item.Quantity
+	}
+
+	// Fetch menu items from database (NEVER trust client prices)
+	menuItems, err := u.menuRepo.GetByIDs(ctx, menuItemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch menu items: %w", err)
+	}
+
+	// Validate all items exist and are available
+	if len(menuItems) != len(menuItemIDs) {
+		return nil, ErrItemNotAvailable
+	}
+	byID := make(map[uuid.UUID]*domain.MenuItem, len(menuItems))
+	for i := range menuItems {
+		menuItem := &menuItems[i]
+		if !menuItem.IsAvailable {
+			return nil, ErrItemNotAvailable
+		}
+		if !menuItem.InStock(quantityMap[menuItem.ID]) {
+			return nil, ErrItemSoldOut
+		}
+		byID[menuItem.ID] = menuItem
+	}
+
+	// Calculate total server-side (critical for security), one order line
+	// per cart line in cart order
+	price := &cartPrice{Items: make([]domain.OrderItem, 0, len(req.Items))}
+
+	for _, line := range req.Items {
+		item, err := orderLine(byID[line.MenuItemID], line)
+		if err != nil {
+			return nil, err
+		}
+		price.Subtotal += item.Subtotal()
+		price.Items = append(price.Items, item)
+	}
+
+	price.Allergens = domain.CartAllergenWarnings(menuItems)
+
+	// Price delivery by road distance from the kitchen
+	if req.FulfillmentType == domain.FulfillmentDelivery && u.deliveryFee != nil {
+		quote, err := u.deliveryFee.Quote(ctx, req.DeliveryLocation)
+		if err != nil {
+			if errors.Is(err, ErrOutOfDeliveryRange) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to compute delivery fee: %w", err)
+		}
+		price.DeliveryFee = quote.Fee
+		price.DeliveryDistanceMeters = &quote.DistanceMeters
+		log.Info("Delivery fee computed", "distance_meters", quote.DistanceMeters, "fee", quote.Fee, "source", quote.Source)
+	}
+
+	if u.pricing != nil {
+		price.OrderCharges = u.pricing.Charges(ctx, price.Subtotal)
+	}
+
+	price.Total = price.Subtotal + price.DeliveryFee + price.PackagingCharge + price.TaxAmount
+	return price, nil
+}
+
 // VerifyPaymentRequest contains the payment verification data from client
 type VerifyPaymentRequest struct {
 	OrderID           uuid.UUID `json:"order_id"`
@@ -240,10 +743,10 @@ type VerifyPaymentRequest struct {
 
 // VerifyPaymentResponse contains the verification result
 type VerifyPaymentResponse struct {
-	Success bool           `json:"success"`
-	OrderID uuid.UUID      `json:"order_id"`
-	Status  string         `json:"status"`
-	Message string         `json:"message"`
+	Success bool      `json:"success"`
+	OrderID uuid.UUID `json:"order_id"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
 }
 
 // VerifyPayment verifies the payment signature and updates order status.
@@ -251,8 +754,8 @@ type VerifyPaymentResponse struct {
 // This is a secondary verification - webhook is the primary source of truth.
 func (u *PaymentUsecase) VerifyPayment(ctx context.Context, req VerifyPaymentRequest) (*VerifyPaymentResponse, error) {
 	log := u.log.WithFields(map[string]interface{}{
-		"order_id":           req.OrderID.String(),
-		"razorpay_order_id":  req.RazorpayOrderID,
+		"order_id":            req.OrderID.String(),
+		"razorpay_order_id":   req.RazorpayOrderID,
 		"razorpay_payment_id": req.RazorpayPaymentID,
 	})
 
@@ -265,9 +768,22 @@ func (u *PaymentUsecase) VerifyPayment(ctx context.Context, req VerifyPaymentReq
 		return nil, fmt.Errorf("failed to fetch order: %w", err)
 	}
 
+	// Payments for cancelled orders are refunded when their webhook arrives.
+	// Checked first, as isPaidStatus counts cancelled orders as paid.
+	if order.Status == domain.OrderStatusCancelled {
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeRejected, nil, "order cancelled"))
+		return &VerifyPaymentResponse{
+			Success: false,
+			OrderID: order.ID,
+			Status:  string(order.Status),
+			Message: "Order was cancelled; any payment will be refunded",
+		}, ErrOrderCancelled
+	}
+
 	// Check if already paid (idempotent success)
-	if order.Status == domain.OrderStatusPaid || order.Status == domain.OrderStatusAccepted || order.Status == domain.OrderStatusDelivered {
+	if isPaidStatus(order.Status) {
 		log.Info("Order already paid, returning success")
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeNoChange, nil, "already paid"))
 		return &VerifyPaymentResponse{
 			Success: true,
 			OrderID: order.ID,
@@ -279,10 +795,12 @@ func (u *PaymentUsecase) VerifyPayment(ctx context.Context, req VerifyPaymentReq
 	// Verify Razorpay signature
 	// Signature = HMAC_SHA256(razorpay_order_id + "|" + razorpay_payment_id, key_secret)
 	data := req.RazorpayOrderID + "|" + req.RazorpayPaymentID
-	expectedSignature := u.generateHMAC(data, u.config.KeySecret)
+	_, creds := u.gateway()
+	expectedSignature := u.generateHMAC(data, creds.KeySecret)
 
 	if !hmac.Equal([]byte(req.RazorpaySignature), []byte(expectedSignature)) {
 		log.Warn("Invalid payment signature")
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeRejected, nil, "invalid signature"))
 		return &VerifyPaymentResponse{
 			Success: false,
 			OrderID: order.ID,
@@ -291,26 +809,62 @@ func (u *PaymentUsecase) VerifyPayment(ctx context.Context, req VerifyPaymentReq
 		}, ErrInvalidSignature
 	}
 
+	// The signature only proves the client's order/payment pair; a payment
+	// for another (cheaper) Razorpay order must not pay for this one
+	if req.RazorpayOrderID != order.RazorpayOrderID {
+		log.Warn("Payment verification for a different Razorpay order")
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeRejected, nil, "razorpay order mismatch"))
+		return &VerifyPaymentResponse{
+			Success: false,
+			OrderID: order.ID,
+			Status:  string(order.Status),
+			Message: "Payment does not match this order",
+		}, ErrPaymentMismatch
+	}
+
+	// Check what was actually captured before marking the order paid
+	payment, err := u.fetchPayment(ctx, req.RazorpayPaymentID)
+	if err != nil {
+		log.Error("Failed to fetch payment from Razorpay", "error", err)
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeError, nil, "fetch payment: "+err.Error()))
+		return nil, fmt.Errorf("failed to fetch payment: %w", err)
+	}
+	if mismatch := paymentMismatch(order, *payment); mismatch != "" {
+		u.flagPaymentMismatch(ctx, order, mismatch, log)
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeRejected, nil, mismatch))
+		return &VerifyPaymentResponse{
+			Success: false,
+			OrderID: order.ID,
+			Status:  string(order.Status),
+			Message: "Payment does not match this order and is being reviewed",
+		}, ErrPaymentMismatch
+	}
+
 	// Update order status to PAID
 	err = u.orderRepo.UpdatePaymentStatus(ctx, order.ID, domain.OrderStatusPaid, req.RazorpayPaymentID, order.Version)
 	if err != nil {
-		if errors.Is(err, repository.ErrVersionConflict) {
+		if lostPaymentRace(err) {
 			// Concurrent update - fetch latest status
-			order, _ = u.orderRepo.GetByID(ctx, req.OrderID)
-			if order != nil && order.Status == domain.OrderStatusPaid {
+			latest, _ := u.orderRepo.GetByID(ctx, req.OrderID)
+			if latest != nil && latest.Status == domain.OrderStatusPaid {
+				u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeNoChange, nil, "paid concurrently"))
 				return &VerifyPaymentResponse{
 					Success: true,
-					OrderID: order.ID,
-					Status:  string(order.Status),
+					OrderID: latest.ID,
+					Status:  string(latest.Status),
 					Message: "Payment verified",
 				}, nil
 			}
 		}
 		log.Error("Failed to update payment status", "error", err)
+		u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeError, nil, err.Error()))
 		return nil, fmt.Errorf("failed to update payment status: %w", err)
 	}
 
 	log.Info("Payment verified successfully")
+	u.recordPaymentEvent(ctx, verifyEvent(order, req, domain.PaymentOutcomeApplied, statusPtr(domain.OrderStatusPaid), ""))
+	u.retakeStock(ctx, order)
+	u.notifications.PaymentReceived(order)
 
 	return &VerifyPaymentResponse{
 		Success: true,
@@ -334,15 +888,15 @@ type WebhookPayload struct {
 type PaymentEntity struct {
 	Payment struct {
 		Entity struct {
-			ID            string `json:"id"`
-			Amount        int64  `json:"amount"`
-			Currency      string `json:"currency"`
-			Status        string `json:"status"`
-			OrderID       string `json:"order_id"`
-			Method        string `json:"method"`
-			Captured      bool   `json:"captured"`
-			ErrorCode     string `json:"error_code,omitempty"`
-			ErrorDesc     string `json:"error_description,omitempty"`
+			ID        string `json:"id"`
+			Amount    int64  `json:"amount"`
+			Currency  string `json:"currency"`
+			Status    string `json:"status"`
+			OrderID   string `json:"order_id"`
+			Method    string `json:"method"`
+			Captured  bool   `json:"captured"`
+			ErrorCode string `json:"error_code,omitempty"`
+			ErrorDesc string `json:"error_description,omitempty"`
 		} `json:"entity"`
 	} `json:"payment"`
 }
@@ -357,7 +911,8 @@ func (u *PaymentUsecase) HandleWebhook(ctx context.Context, payload []byte, sign
 
 	// Verify webhook signature using HMAC SHA256
 	// This prevents attackers from sending fake webhook events
-	expectedSignature := u.generateHMAC(string(payload), u.config.WebhookSecret)
+	_, creds := u.gateway()
+	expectedSignature := u.generateHMAC(string(payload), creds.WebhookSecret)
 	signatureValid := hmac.Equal([]byte(signature), []byte(expectedSignature))
 
 	// Parse webhook payload
@@ -365,7 +920,7 @@ func (u *PaymentUsecase) HandleWebhook(ctx context.Context, payload []byte, sign
 	if err := json.Unmarshal(payload, &webhookData); err != nil {
 		log.Error("Failed to parse webhook payload", "error", err)
 		// Still log the attempt
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", "parse_error", payload, signatureValid, nil, err.Error())
+		_, _ = u.logWebhook(ctx, "parse_error", payload, signatureValid, nil, err.Error())
 		return fmt.Errorf("invalid webhook payload: %w", err)
 	}
 
@@ -381,22 +936,39 @@ func (u *PaymentUsecase) HandleWebhook(ctx context.Context, payload []byte, sign
 
 	if !signatureValid {
 		log.Warn("Invalid webhook signature")
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, false, nil, "invalid signature")
+		_, _ = u.logWebhook(ctx, webhookData.Event, payload, false, nil, "invalid signature")
 		return ErrInvalidSignature
 	}
 
 	log.Info("Processing webhook event")
 	log.Debug("Incoming webhook payload", "payload", string(payload))
 
-	// Handle different event types
+	// A failure the handler returns is transient; schedule a replay rather
+	// than rely on Razorpay redelivering it
+	attempt := &webhookAttempt{}
+	err := u.dispatchWebhook(withWebhookAttempt(ctx, attempt), webhookData, payload, log)
+	if err != nil {
+		if attempt.logID == uuid.Nil {
+			attempt.logID, _ = u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
+		}
+		u.scheduleWebhookRetry(ctx, attempt.logID, webhookData.Event, 1, err, log)
+	}
+	return err
+}
+
+// dispatchWebhook runs a verified webhook through the handler for its
+// event type
+func (u *PaymentUsecase) dispatchWebhook(ctx context.Context, webhookData WebhookPayload, payload []byte, log *logger.Logger) error {
 	switch webhookData.Event {
 	case "payment.captured":
 		return u.handlePaymentCaptured(ctx, webhookData, payload, log)
 	case "payment.failed":
 		return u.handlePaymentFailed(ctx, webhookData, payload, log)
+	case "refund.processed", "refund.failed":
+		return u.handleRefundSettled(ctx, webhookData, payload, log)
 	default:
 		log.Info("Unhandled webhook event type")
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, "")
+		_, _ = u.logWebhook(ctx, webhookData.Event, payload, true, nil, "")
 		return nil
 	}
 }
@@ -406,7 +978,7 @@ func (u *PaymentUsecase) handlePaymentCaptured(ctx context.Context, webhookData
 	var paymentData PaymentEntity
 	if err := json.Unmarshal(webhookData.Payload, &paymentData); err != nil {
 		log.Error("Failed to parse payment entity", "error", err)
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, err.Error())
+		_, _ = u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
 		return fmt.Errorf("invalid payment entity: %w", err)
 	}
 
@@ -417,49 +989,114 @@ func (u *PaymentUsecase) handlePaymentCaptured(ctx context.Context, webhookData
 		"amount":            payment.Amount,
 	})
 
+	event := &domain.PaymentEvent{
+		RazorpayOrderID:   payment.OrderID,
+		RazorpayPaymentID: payment.ID,
+		Source:            domain.PaymentSourceWebhook,
+		EventType:         webhookData.Event,
+		Amount:            &payment.Amount,
+	}
+
 	// Find order by Razorpay order ID
 	order, err := u.orderRepo.GetByRazorpayOrderID(ctx, payment.OrderID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
+			// Money was captured for an order we don't know - exactly the case
+			// support needs to find later by Razorpay IDs
 			log.Warn("Order not found for webhook")
-			_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, "order not found")
+			logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, nil, "order not found")
+			u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeRejected, "order not found")
 			return nil // Don't return error - might be from different system
 		}
 		log.Error("Failed to find order", "error", err)
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, err.Error())
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
 		return err
 	}
 
 	log = log.WithFields(map[string]interface{}{
 		"order_id": order.ID.String(),
 	})
+	event.OrderID = &order.ID
+	event.PreviousStatus = statusPtr(order.Status)
+
+	// A payment completed after the order was cancelled never revives it;
+	// the payer gets the money back
+	if order.Status == domain.OrderStatusCancelled {
+		return u.capturedAfterCancel(ctx, order, event, webhookData.Event, payload, log)
+	}
+
+	// Never mark paid on a captured amount that differs from the order
+	// total; the order waits for an admin instead. Returning nil stops
+	// Razorpay redelivering the event.
+	captured := capturedPayment{
+		ID:       payment.ID,
+		Amount:   payment.Amount,
+		Currency: payment.Currency,
+		OrderID:  payment.OrderID,
+		Status:   payment.Status,
+	}
+	if mismatch := paymentMismatch(order, captured); mismatch != "" {
+		u.flagPaymentMismatch(ctx, order, mismatch, log)
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, "payment mismatch")
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeRejected, mismatch)
+		return nil
+	}
 
 	// Update order status using serializable transaction
 	err = u.orderRepo.UpdatePaymentStatus(ctx, order.ID, domain.OrderStatusPaid, payment.ID, order.Version)
 	if err != nil {
-		if errors.Is(err, repository.ErrVersionConflict) {
+		if lostPaymentRace(err) {
+			// Cancelled while the payment was being captured
+			if latest, err := u.orderRepo.GetByID(ctx, order.ID); err == nil && latest.Status == domain.OrderStatusCancelled {
+				return u.capturedAfterCancel(ctx, latest, event, webhookData.Event, payload, log)
+			}
 			// Already processed by another request (client verification)
 			log.Info("Order already processed (version conflict - idempotent)")
-			_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, &order.ID, "")
+			logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, "")
+			u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeNoChange, "order modified concurrently")
 			return nil
 		}
 		log.Error("Failed to update order status", "error", err)
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, &order.ID, err.Error())
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
 		return err
 	}
 
 	log.Info("Payment captured successfully via webhook")
-	_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, &order.ID, "")
+	logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, "")
+	if isPaidStatus(order.Status) {
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeNoChange, "already paid")
+	} else {
+		event.NewStatus = statusPtr(domain.OrderStatusPaid)
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeApplied, "")
+		u.retakeStock(ctx, order)
+		u.notifications.PaymentReceived(order)
+	}
 
 	return nil
 }
 
+// capturedAfterCancel refunds a payment captured for a cancelled order and
+// logs the webhook
+func (u *PaymentUsecase) capturedAfterCancel(ctx context.Context, order *domain.Order, event *domain.PaymentEvent, webhookEvent string, payload []byte, log *logger.Logger) error {
+	log.Warn("Payment captured for a cancelled order")
+	if err := u.refundLateCapture(ctx, order, event.RazorpayPaymentID, *event.Amount, log); err != nil {
+		logID, _ := u.logWebhook(ctx, webhookEvent, payload, true, &order.ID, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
+		return err
+	}
+	logID, _ := u.logWebhook(ctx, webhookEvent, payload, true, &order.ID, "order cancelled")
+	u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeRejected, "order cancelled, payment refunded")
+	return nil
+}
+
 // handlePaymentFailed processes failed payment webhooks
 func (u *PaymentUsecase) handlePaymentFailed(ctx context.Context, webhookData WebhookPayload, payload []byte, log *logger.Logger) error {
 	var paymentData PaymentEntity
 	if err := json.Unmarshal(webhookData.Payload, &paymentData); err != nil {
 		log.Error("Failed to parse payment entity", "error", err)
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, err.Error())
+		_, _ = u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
 		return nil // Don't fail on parse errors for failed payments
 	}
 
@@ -471,46 +1108,336 @@ func (u *PaymentUsecase) handlePaymentFailed(ctx context.Context, webhookData We
 		"error_desc":        payment.ErrorDesc,
 	})
 
+	event := &domain.PaymentEvent{
+		RazorpayOrderID:   payment.OrderID,
+		RazorpayPaymentID: payment.ID,
+		Source:            domain.PaymentSourceWebhook,
+		EventType:         webhookData.Event,
+		Amount:            &payment.Amount,
+	}
+	gatewayError := strings.TrimSpace(payment.ErrorCode + " " + payment.ErrorDesc)
+
 	// Find order
 	order, err := u.orderRepo.GetByRazorpayOrderID(ctx, payment.OrderID)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			log.Warn("Order not found for failed payment webhook")
-			_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, nil, "order not found")
+			logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, nil, "order not found")
+			u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeRejected, "order not found")
 			return nil
 		}
 		return err
 	}
+	event.OrderID = &order.ID
+	event.PreviousStatus = statusPtr(order.Status)
+
+	// Failed attempts are reported even when a later one was captured, and
+	// webhooks arrive out of order: only an order still waiting for payment
+	// fails, and only then is its stock given back
+	if order.Status != domain.OrderStatusPending && order.Status != domain.OrderStatusAwaitingPayment {
+		log.Info("Ignoring payment failure for an order not awaiting payment", "status", order.Status)
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, "")
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeNoChange, "order is "+string(order.Status)+"; "+gatewayError)
+		return nil
+	}
 
 	// Update order status to PAYMENT_FAILED
 	err = u.orderRepo.UpdateStatus(ctx, order.ID, domain.OrderStatusPaymentFailed, order.Version)
 	if err != nil && !errors.Is(err, repository.ErrVersionConflict) {
 		log.Error("Failed to update order status to failed", "error", err)
-		_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, &order.ID, err.Error())
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
 		return err
 	}
 
 	log.Info("Payment failure recorded")
-	_ = u.orderRepo.LogWebhook(ctx, "razorpay", webhookData.Event, payload, true, &order.ID, "")
+	logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &order.ID, "")
+	if err != nil {
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeNoChange, "order modified concurrently; "+gatewayError)
+	} else {
+		event.NewStatus = statusPtr(domain.OrderStatusPaymentFailed)
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeApplied, gatewayError)
+		u.restoreStock(ctx, order)
+	}
+
+	return nil
+}
+
+// GetPaymentEvents returns the payment audit trail for an order
+func (u *PaymentUsecase) GetPaymentEvents(ctx context.Context, orderID uuid.UUID) ([]domain.PaymentEvent, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return u.paymentEvents.ListByOrder(ctx, order.ID, order.RazorpayOrderID)
+}
+
+// recordPaymentEvent appends to the payment audit trail. Audit failures are
+// logged, never surfaced - they must not change the payment outcome.
+func (u *PaymentUsecase) recordPaymentEvent(ctx context.Context, event *domain.PaymentEvent) {
+	if u.paymentEvents == nil {
+		return
+	}
+	if err := u.paymentEvents.Record(ctx, event); err != nil {
+		u.log.Warn("Failed to record payment event", "event_type", event.EventType, "error", err)
+	}
+}
+
+// recordWebhookEvent finalizes a webhook-driven event with its outcome and
+// the webhook_logs row holding the raw payload
+func (u *PaymentUsecase) recordWebhookEvent(ctx context.Context, event *domain.PaymentEvent, webhookLogID uuid.UUID, outcome domain.PaymentEventOutcome, detail string) {
+	if webhookLogID != uuid.Nil {
+		event.WebhookLogID = &webhookLogID
+	}
+	event.Outcome = outcome
+	event.Detail = detail
+	u.recordPaymentEvent(ctx, event)
+}
+
+// verifyEvent builds a client-verify audit event for an order
+func verifyEvent(order *domain.Order, req VerifyPaymentRequest, outcome domain.PaymentEventOutcome, newStatus *domain.OrderStatus, detail string) *domain.PaymentEvent {
+	return &domain.PaymentEvent{
+		OrderID:           &order.ID,
+		RazorpayOrderID:   req.RazorpayOrderID,
+		RazorpayPaymentID: req.RazorpayPaymentID,
+		Source:            domain.PaymentSourceClientVerify,
+		EventType:         "payment.verify",
+		PreviousStatus:    statusPtr(order.Status),
+		NewStatus:         newStatus,
+		Outcome:           outcome,
+		Amount:            &order.TotalAmount,
+		Detail:            detail,
+	}
+}
+
+// isPaidStatus reports whether an order is past payment. Cancelled orders
+// count too, though only those with a payment ID were ever paid for, so
+// callers that care check for cancellation or the payment ID as well.
+func isPaidStatus(status domain.OrderStatus) bool {
+	return status == domain.OrderStatusPaid || status == domain.OrderStatusAccepted ||
+		status == domain.OrderStatusOutForDelivery || status == domain.OrderStatusDelivered ||
+		status == domain.OrderStatusCancelled
+}
+
+// lostPaymentRace reports whether UpdatePaymentStatus failed because a
+// concurrent update got to the order first: a stale version, or the
+// serializable transaction being aborted in favour of the other one
+func lostPaymentRace(err error) bool {
+	return errors.Is(err, repository.ErrVersionConflict) || errors.Is(err, repository.ErrSerializationFailure)
+}
+
+// statusPtr returns a pointer to a status value
+func statusPtr(status domain.OrderStatus) *domain.OrderStatus {
+	return &status
+}
+
+// refund returns amount paisa of an order's captured payment to the
+// customer, returning the Razorpay refund ID. See issueRefund.
+func (u *PaymentUsecase) refund(ctx context.Context, order *domain.Order, amount int64, eventType string, previousStatus, newStatus *domain.OrderStatus) (string, error) {
+	refund := &domain.Refund{
+		OrderID:           order.ID,
+		RazorpayPaymentID: order.RazorpayPaymentID,
+		Amount:            amount,
+		Reason:            eventType,
+	}
+	if err := u.issueRefund(ctx, order, refund, order.TotalAmount, eventType, previousStatus, newStatus); err != nil {
+		return "", err
+	}
+	return refund.RazorpayRefundID, nil
+}
+
+// checkout wires the checkout saga for req to the usecase's stores and
+// gateway
+func (u *PaymentUsecase) checkout(req InitiateOrderRequest) *checkout {
+	c := &checkout{
+		orders:  u.orderRepo,
+		slots:   &checkoutBooking{u: u, req: req},
+		gateway: razorpayOrders{u: u},
+		uow:     u.uow,
+		record:  u.recordPaymentEvent,
+	}
+	if u.inventory != nil {
+		c.stock = u.inventory
+	}
+	return c
+}
+
+// checkoutBooking books the delivery window and kitchen slot a checkout
+// asked for, and promises an arrival time from them
+type checkoutBooking struct {
+	u   *PaymentUsecase
+	req InitiateOrderRequest
+}
+
+func (b *checkoutBooking) book(ctx context.Context, order *domain.Order) (err error) {
+	u := b.u
+	defer func() {
+		// Without a unit of work nothing rolls a half-done booking back
+		if err != nil && u.uow == nil {
+			b.release(ctx, order, false)
+		}
+	}()
+
+	// Book the delivery window first; the kitchen slot is planned from it
+	kitchenAt := b.req.ScheduledFor
+	if b.req.DeliverySlot != nil && u.deliverySlots != nil {
+		lead := time.Duration(0)
+		earliest := time.Now()
+		if u.eta != nil {
+			earliest = u.eta.Estimate(ctx, order.FulfillmentType, order.DeliveryLocation, order.DeliveryDistanceMeters, time.Time{}).EstimatedAt
+			lead = time.Until(earliest)
+		}
+		slot, err := u.deliverySlots.Book(ctx, *b.req.DeliverySlot, earliest)
+		if err != nil {
+			return err
+		}
+		order.DeliverySlotAt = &slot
+		if kitchenAt == nil {
+			// Start cooking so the order arrives as the window opens
+			if start := slot.Add(-lead); start.After(time.Now()) {
+				kitchenAt = &start
+			}
+		}
+	}
+
+	// Book kitchen capacity; a full slot surfaces the next open ones
+	var kitchenStart time.Time
+	if u.capacity != nil {
+		slot, scheduled, err := u.capacity.Book(ctx, kitchenAt)
+		if err != nil {
+			return err
+		}
+		order.KitchenSlotAt = &slot
+		order.IsScheduled = scheduled
+		kitchenStart = slot
+	}
 
+	// Promise an arrival time from the booked slot, weather included
+	if u.eta != nil {
+		estimate := u.eta.Estimate(ctx, order.FulfillmentType, order.DeliveryLocation, order.DeliveryDistanceMeters, kitchenStart)
+		order.EstimatedDeliveryAt = &estimate.EstimatedAt
+		order.ETAAdjustmentMinutes = estimate.AdjustmentMinutes
+		order.ETAWeatherCondition = string(estimate.WeatherCondition)
+		if order.DeliverySlotAt != nil && order.EstimatedDeliveryAt.Before(*order.DeliverySlotAt) {
+			order.EstimatedDeliveryAt = order.DeliverySlotAt
+		}
+	}
 	return nil
 }
 
+// release gives back the slots; a saved order's delivery window is
+// released through the order so the expiry job doesn't release it again
+func (b *checkoutBooking) release(ctx context.Context, order *domain.Order, saved bool) {
+	u := b.u
+	if u.capacity != nil && order.KitchenSlotAt != nil {
+		u.capacity.Release(ctx, *order.KitchenSlotAt)
+	}
+	if u.deliverySlots == nil || order.DeliverySlotAt == nil {
+		return
+	}
+	if saved {
+		u.deliverySlots.Release(ctx, order.ID)
+	} else {
+		u.deliverySlots.ReleaseBooking(ctx, *order.DeliverySlotAt)
+	}
+}
+
+// razorpayOrders creates Razorpay orders under the usecase's retry policy
+type razorpayOrders struct {
+	u *PaymentUsecase
+}
+
+// CreateOrder creates the Razorpay order for a new order. The receipt ties
+// retries to one order; a duplicate Razorpay order from a retried timeout
+// is never paid because its ID is not stored.
+func (g razorpayOrders) CreateOrder(ctx context.Context, order *domain.Order) (string, error) {
+	data := map[string]interface{}{
+		"amount":          order.TotalAmount, // Already in paisa
+		"currency":        "INR",
+		"receipt":         order.ID.String(),
+		"payment_capture": 1, // Auto-capture payment
+		"notes": map[string]interface{}{
+			"order_id": order.ID.String(),
+			"user_id":  order.UserID.String(),
+		},
+	}
+
+	client, _ := g.u.gateway()
+	var created map[string]interface{}
+	err := g.u.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = client.Order.Create(data, nil)
+		return err
+	})
+	if err != nil {
+		g.u.recordGatewayFailure(ctx, err)
+		return "", err
+	}
+
+	id, _ := created["id"].(string)
+	if id == "" {
+		return "", errors.New("razorpay order response has no id")
+	}
+	return id, nil
+}
+
+// releaseSlot gives back the kitchen and delivery slots of an order that
+// won't proceed
+func (u *PaymentUsecase) releaseSlot(ctx context.Context, order *domain.Order) {
+	if u.capacity != nil && order.KitchenSlotAt != nil {
+		u.capacity.Release(ctx, *order.KitchenSlotAt)
+	}
+	if u.deliverySlots != nil && order.DeliverySlotAt != nil && order.ID != uuid.Nil {
+		u.deliverySlots.Release(ctx, order.ID)
+	}
+}
+
+// restoreStock puts back the stock of an order that won't proceed
+func (u *PaymentUsecase) restoreStock(ctx context.Context, order *domain.Order) {
+	if u.inventory != nil {
+		u.inventory.Restore(ctx, order)
+	}
+}
+
+// retakeStock takes stock again for a newly paid order whose stock a
+// failed payment attempt gave back
+func (u *PaymentUsecase) retakeStock(ctx context.Context, order *domain.Order) {
+	if u.inventory != nil {
+		u.inventory.Retake(ctx, order)
+	}
+}
+
 // generateCartHash creates a deterministic hash for cart contents
 // Used for idempotency detection
-func (u *PaymentUsecase) generateCartHash(userID uuid.UUID, items []domain.CartItem) string {
-	// Sort items by ID for deterministic ordering
-	sortedItems := make([]domain.CartItem, len(items))
-	copy(sortedItems, items)
+func (u *PaymentUsecase) generateCartHash(req InitiateOrderRequest) string {
+	// Sort lines by item and options for deterministic ordering
+	sortedItems := make([]domain.CartItem, len(req.Items))
+	copy(sortedItems, req.Items)
 	sort.Slice(sortedItems, func(i, j int) bool {
-		return sortedItems[i].MenuItemID.String() < sortedItems[j].MenuItemID.String()
+		return sortedItems[i].LineKey() < sortedItems[j].LineKey()
 	})
 
 	// Build hash input
 	var sb strings.Builder
-	sb.WriteString(userID.String())
+	sb.WriteString(req.UserID.String())
+	sb.WriteString(":" + string(req.FulfillmentType))
+	if req.DeliveryLocation != nil {
+		sb.WriteString(fmt.Sprintf(":%.6f,%.6f", req.DeliveryLocation.Latitude, req.DeliveryLocation.Longitude))
+	}
+	if req.ScheduledFor != nil {
+		sb.WriteString(":" + SlotStart(*req.ScheduledFor).UTC().Format(time.RFC3339))
+	}
+	if req.DeliverySlot != nil {
+		sb.WriteString(":slot:" + DeliverySlotStart(*req.DeliverySlot).UTC().Format(time.RFC3339))
+	}
+	if req.Recipient != nil {
+		sb.WriteString(":gift:" + req.Recipient.PhoneNumber)
+	}
+	if req.NoCutlery || req.Packaging != domain.PackagingStandard {
+		sb.WriteString(fmt.Sprintf(":pack:%s:%t", req.Packaging, req.NoCutlery))
+	}
 	for _, item := range sortedItems {
-		sb.WriteString(fmt.Sprintf(":%s:%d", item.MenuItemID.String(), item.Quantity))
+		sb.WriteString(fmt.Sprintf(":%s:%d", item.LineKey(), item.Quantity))
 	}
 
 	// Generate SHA256 hash
@@ -0,0 +1,167 @@
+// Package usecase implements pickup code issuance and counter verification
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/redis"
+)
+
+// Pickup errors
+var (
+	ErrNotPickupOrder     = errors.New("order is not a pickup order")
+	ErrPickupNotReady     = errors.New("order is not ready for pickup")
+	ErrInvalidPickupCode  = errors.New("invalid pickup code")
+	ErrPickupCodeRequired = errors.New("pickup orders can only be completed by verifying the pickup code")
+)
+
+const (
+	// pickupQRPrefix marks QR payloads so the staff app can reject foreign codes
+	pickupQRPrefix = "CRVPICKUP"
+
+	// maxPickupAttempts bounds guesses at a 6-digit code per order
+	maxPickupAttempts = 5
+)
+
+// PickupCodeResponse is shown to the customer at the counter
+type PickupCodeResponse struct {
+	OrderID   uuid.UUID `json:"order_id"`
+	Code      string    `json:"code"`
+	QRPayload string    `json:"qr_payload"`
+}
+
+// GetPickupCode returns the pickup code and signed QR payload for the
+// customer's own pickup order
+func (u *OrderUsecase) GetPickupCode(ctx context.Context, orderID, userID uuid.UUID) (*PickupCodeResponse, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	if order.FulfillmentType != domain.FulfillmentPickup {
+		return nil, ErrNotPickupOrder
+	}
+	if order.Status != domain.OrderStatusPaid && order.Status != domain.OrderStatusAccepted {
+		return nil, ErrPickupNotReady
+	}
+
+	return &PickupCodeResponse{
+		OrderID:   order.ID,
+		Code:      order.PickupCode,
+		QRPayload: u.signPickupPayload(order.ID, order.PickupCode),
+	}, nil
+}
+
+// VerifyPickupRequest is submitted by counter staff, either by scanning the
+// QR payload or by typing the order ID and numeric code
+type VerifyPickupRequest struct {
+	QRPayload string    `json:"qr_payload"`
+	OrderID   uuid.UUID `json:"order_id"`
+	Code      string    `json:"code"`
+}
+
+// VerifyPickup validates a pickup code and hands the order over (DELIVERED)
+func (u *OrderUsecase) VerifyPickup(ctx context.Context, req VerifyPickupRequest, staffID uuid.UUID) (*domain.Order, error) {
+	orderID, code := req.OrderID, req.Code
+	if req.QRPayload != "" {
+		var err error
+		orderID, code, err = u.parsePickupPayload(req.QRPayload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if orderID == uuid.Nil || code == "" {
+		return nil, ErrInvalidPickupCode
+	}
+
+	attemptsKey := redis.PickupAttemptsPrefix + orderID.String()
+	if u.redisClient != nil {
+		attempts, err := u.redisClient.IncrWithTTL(ctx, attemptsKey, redis.PickupAttemptsTTL)
+		if err != nil {
+			u.log.Warn("Failed to track pickup attempts", "error", err)
+		} else if attempts > maxPickupAttempts {
+			return nil, ErrTooManyAttempts
+		}
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidPickupCode
+		}
+		return nil, err
+	}
+
+	if order.FulfillmentType != domain.FulfillmentPickup {
+		return nil, ErrNotPickupOrder
+	}
+	if subtle.ConstantTimeCompare([]byte(order.PickupCode), []byte(code)) != 1 {
+		u.log.Warn("Pickup code mismatch", "order_id", orderID.String(), "staff_id", staffID.String())
+		return nil, ErrInvalidPickupCode
+	}
+	if order.Status != domain.OrderStatusAccepted {
+		return nil, ErrPickupNotReady
+	}
+
+	if err := u.orderRepo.CompletePickup(ctx, order.ID, staffID, order.Version); err != nil {
+		return nil, fmt.Errorf("failed to complete pickup: %w", err)
+	}
+
+	if u.redisClient != nil {
+		_ = u.redisClient.DeleteKey(ctx, attemptsKey)
+	}
+
+	u.log.Info("Pickup order handed over",
+		"order_id", order.ID.String(),
+		"staff_id", staffID.String(),
+	)
+
+	return u.deliveredOrder(ctx, order.ID)
+}
+
+// signPickupPayload builds "CRVPICKUP.<order_id>.<code>.<sig>"; the signature
+// stops a forged QR from pairing a guessed code with someone else's order
+func (u *OrderUsecase) signPickupPayload(orderID uuid.UUID, code string) string {
+	return strings.Join([]string{pickupQRPrefix, orderID.String(), code, u.pickupSignature(orderID, code)}, ".")
+}
+
+// parsePickupPayload verifies a scanned QR payload and extracts its contents
+func (u *OrderUsecase) parsePickupPayload(payload string) (uuid.UUID, string, error) {
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 || parts[0] != pickupQRPrefix {
+		return uuid.Nil, "", ErrInvalidPickupCode
+	}
+
+	orderID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidPickupCode
+	}
+
+	expected := u.pickupSignature(orderID, parts[2])
+	if !hmac.Equal([]byte(parts[3]), []byte(expected)) {
+		return uuid.Nil, "", ErrInvalidPickupCode
+	}
+
+	return orderID, parts[2], nil
+}
+
+func (u *OrderUsecase) pickupSignature(orderID uuid.UUID, code string) string {
+	mac := hmac.New(sha256.New, u.pickupSigningKey)
+	mac.Write([]byte(orderID.String() + ":" + code))
+	// 128 bits keeps the QR small while remaining unforgeable
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
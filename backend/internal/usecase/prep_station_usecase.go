@@ -0,0 +1,333 @@
+// Package usecase implements routing accepted orders to kitchen prep
+// stations
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Prep station errors
+var (
+	ErrStationNotFound      = errors.New("prep station not found")
+	ErrInvalidStation       = errors.New("station name must be 1-50 characters")
+	ErrInvalidStationCat    = errors.New("categories must be distinct and 1-100 characters")
+	ErrStationNameTaken     = errors.New("a station with that name exists, or another station is already the default")
+	ErrStationCategoryTaken = errors.New("a category is already prepared at another station")
+	ErrStationBusy          = errors.New("station has open tickets; finish them before deleting it")
+	ErrTicketNotFound       = errors.New("station ticket not found")
+	ErrTicketNotQueued      = errors.New("ticket is already being prepared or ready")
+	ErrTicketAlreadyReady   = errors.New("ticket is already ready")
+	ErrOrderNotInKitchen    = errors.New("only accepted orders are routed to stations")
+)
+
+// Station input limits
+const (
+	maxStationNameLength     = 50
+	maxStationCategoryLength = 100
+)
+
+// StationInput is a station's settings as an admin submits them
+type StationInput struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+	IsDefault  bool     `json:"is_default"`
+	SortOrder  int      `json:"sort_order"`
+}
+
+// PrepStationUsecase maps menu categories to kitchen stations and, when an
+// order is accepted, splits its items into one ticket per station. Each
+// station works through its own queue; an order is ready for the pass once
+// every station has marked its ticket ready.
+type PrepStationUsecase struct {
+	repo      *repository.PrepStationRepository
+	orderRepo *repository.OrderRepository
+	audit     *AuditUsecase
+	log       *logger.Logger
+}
+
+// NewPrepStationUsecase creates a new prep station usecase
+func NewPrepStationUsecase(repo *repository.PrepStationRepository, orderRepo *repository.OrderRepository, log *logger.Logger) *PrepStationUsecase {
+	return &PrepStationUsecase{
+		repo:      repo,
+		orderRepo: orderRepo,
+		log:       log,
+	}
+}
+
+// SetAuditLog records station changes in the admin audit log
+func (u *PrepStationUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// ListStations returns every station with its categories, in display order
+func (u *PrepStationUsecase) ListStations(ctx context.Context) ([]domain.PrepStation, error) {
+	return u.repo.ListStations(ctx)
+}
+
+// CreateStation adds a station
+func (u *PrepStationUsecase) CreateStation(ctx context.Context, adminID uuid.UUID, in StationInput) (*domain.PrepStation, error) {
+	station, err := in.station()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.CreateStation(ctx, station); err != nil {
+		return nil, stationRepoError(err)
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditPrepStationCreated, "prep_station", station.ID.String(), map[string]interface{}{
+		"name":       station.Name,
+		"categories": station.Categories,
+		"is_default": station.IsDefault,
+	})
+	return station, nil
+}
+
+// UpdateStation replaces a station's settings and categories. Tickets
+// already routed stay where they are.
+func (u *PrepStationUsecase) UpdateStation(ctx context.Context, adminID, id uuid.UUID, in StationInput) (*domain.PrepStation, error) {
+	station, err := in.station()
+	if err != nil {
+		return nil, err
+	}
+	station.ID = id
+	if err := u.repo.UpdateStation(ctx, station); err != nil {
+		return nil, stationRepoError(err)
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditPrepStationUpdated, "prep_station", station.ID.String(), map[string]interface{}{
+		"name":       station.Name,
+		"categories": station.Categories,
+		"is_default": station.IsDefault,
+	})
+	return station, nil
+}
+
+// DeleteStation removes a station that has no open tickets
+func (u *PrepStationUsecase) DeleteStation(ctx context.Context, adminID, id uuid.UUID) error {
+	if err := u.repo.DeleteStation(ctx, id); err != nil {
+		return stationRepoError(err)
+	}
+	u.audit.Record(ctx, adminID, domain.AuditPrepStationDeleted, "prep_station", id.String(), nil)
+	return nil
+}
+
+// Route splits an accepted order's items into station tickets. Items whose
+// category no station claims go to the default station, or stay with the
+// pass when there is none. Routing an order again adds nothing.
+func (u *PrepStationUsecase) Route(ctx context.Context, order *domain.Order) error {
+	stations, err := u.repo.ListStations(ctx)
+	if err != nil || len(stations) == 0 {
+		return err
+	}
+
+	byCategory := make(map[string]uuid.UUID)
+	var fallback *uuid.UUID
+	for i := range stations {
+		for _, category := range stations[i].Categories {
+			byCategory[category] = stations[i].ID
+		}
+		if stations[i].IsDefault {
+			fallback = &stations[i].ID
+		}
+	}
+
+	tickets := []domain.StationTicket{}
+	index := make(map[uuid.UUID]int)
+	for _, item := range order.Items {
+		stationID, ok := byCategory[item.Category]
+		if !ok {
+			if fallback == nil {
+				continue
+			}
+			stationID = *fallback
+		}
+		i, ok := index[stationID]
+		if !ok {
+			i = len(tickets)
+			index[stationID] = i
+			tickets = append(tickets, domain.StationTicket{OrderID: order.ID, StationID: stationID})
+		}
+		tickets[i].Items = append(tickets[i].Items, domain.StationItem{
+			OrderItemID: item.ID,
+			Name:        item.Name,
+			VariantName: item.VariantName,
+			Modifiers:   item.Modifiers,
+			Quantity:    item.Quantity,
+		})
+	}
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	if err := u.repo.CreateTickets(ctx, tickets); err != nil {
+		return err
+	}
+	u.log.Info("Order routed to prep stations", "order_id", order.ID.String(), "stations", len(tickets))
+	return nil
+}
+
+// RouteOrder routes an accepted order by ID, for orders accepted before
+// stations were set up or whose routing failed
+func (u *PrepStationUsecase) RouteOrder(ctx context.Context, orderID uuid.UUID) (*domain.OrderReadiness, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.OrderStatusAccepted {
+		return nil, ErrOrderNotInKitchen
+	}
+	if err := u.Route(ctx, order); err != nil {
+		return nil, err
+	}
+	return u.OrderReadiness(ctx, orderID)
+}
+
+// StationQueue returns a station's open tickets, oldest first: its KDS
+// view
+func (u *PrepStationUsecase) StationQueue(ctx context.Context, stationID uuid.UUID) ([]domain.StationTicket, error) {
+	if _, err := u.repo.GetStation(ctx, stationID); err != nil {
+		return nil, stationRepoError(err)
+	}
+	return u.repo.ListOpenTickets(ctx, stationID)
+}
+
+// StartTicket marks a queued ticket as being prepared
+func (u *PrepStationUsecase) StartTicket(ctx context.Context, ticketID uuid.UUID) (*domain.StationTicket, error) {
+	if err := u.repo.StartTicket(ctx, ticketID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if _, getErr := u.repo.GetTicket(ctx, ticketID); errors.Is(getErr, repository.ErrNotFound) {
+				return nil, ErrTicketNotFound
+			}
+			return nil, ErrTicketNotQueued
+		}
+		return nil, err
+	}
+	return u.repo.GetTicket(ctx, ticketID)
+}
+
+// ReadyTicket marks a station's part of an order ready and returns the
+// order's readiness across stations
+func (u *PrepStationUsecase) ReadyTicket(ctx context.Context, adminID, ticketID uuid.UUID) (*domain.OrderReadiness, error) {
+	ticket, err := u.repo.GetTicket(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, err
+	}
+	if err := u.repo.ReadyTicket(ctx, ticketID, adminID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, ErrTicketAlreadyReady
+		}
+		return nil, err
+	}
+
+	readiness, err := u.OrderReadiness(ctx, ticket.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if readiness.Ready {
+		u.log.Info("Order ready at every station", "order_id", ticket.OrderID.String(), "stations", len(readiness.Tickets))
+	}
+	return readiness, nil
+}
+
+// OrderReadiness returns an order's tickets and whether all are ready
+func (u *PrepStationUsecase) OrderReadiness(ctx context.Context, orderID uuid.UUID) (*domain.OrderReadiness, error) {
+	tickets, err := u.repo.ListOrderTickets(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return readiness(orderID, tickets), nil
+}
+
+// KitchenReadiness returns the readiness of every order in the kitchen
+// that was routed to stations, oldest order first: the expo view
+func (u *PrepStationUsecase) KitchenReadiness(ctx context.Context) ([]domain.OrderReadiness, error) {
+	tickets, err := u.repo.ListKitchenTickets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []domain.OrderReadiness{}
+	for start := 0; start < len(tickets); {
+		end := start
+		for end < len(tickets) && tickets[end].OrderID == tickets[start].OrderID {
+			end++
+		}
+		orders = append(orders, *readiness(tickets[start].OrderID, tickets[start:end]))
+		start = end
+	}
+	return orders, nil
+}
+
+// readiness summarises an order's tickets. An order nothing was routed for
+// has no tickets and isn't ready.
+func readiness(orderID uuid.UUID, tickets []domain.StationTicket) *domain.OrderReadiness {
+	r := &domain.OrderReadiness{OrderID: orderID, Tickets: tickets, Ready: len(tickets) > 0}
+	var last time.Time
+	for _, t := range tickets {
+		if t.Status != domain.TicketReady || t.ReadyAt == nil {
+			r.Ready = false
+			continue
+		}
+		if t.ReadyAt.After(last) {
+			last = *t.ReadyAt
+		}
+	}
+	if r.Ready {
+		r.ReadyAt = &last
+	}
+	return r
+}
+
+// station validates the input and builds the station it describes
+func (in StationInput) station() (*domain.PrepStation, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxStationNameLength {
+		return nil, ErrInvalidStation
+	}
+
+	seen := make(map[string]bool, len(in.Categories))
+	categories := make([]string, 0, len(in.Categories))
+	for _, c := range in.Categories {
+		c = strings.TrimSpace(c)
+		if c == "" || utf8.RuneCountInString(c) > maxStationCategoryLength || seen[c] {
+			return nil, ErrInvalidStationCat
+		}
+		seen[c] = true
+		categories = append(categories, c)
+	}
+
+	return &domain.PrepStation{
+		Name:       name,
+		Categories: categories,
+		IsDefault:  in.IsDefault,
+		SortOrder:  in.SortOrder,
+	}, nil
+}
+
+// stationRepoError maps repository errors to station errors
+func stationRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrStationNotFound
+	case errors.Is(err, repository.ErrStationCategoryTaken):
+		return ErrStationCategoryTaken
+	case errors.Is(err, repository.ErrStationBusy):
+		return ErrStationBusy
+	case errors.Is(err, repository.ErrDuplicateKey):
+		return ErrStationNameTaken
+	}
+	return err
+}
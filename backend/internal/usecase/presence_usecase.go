@@ -0,0 +1,305 @@
+// Package usecase implements presence of rider apps and kitchen screens
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// SettingKitchenOpenHours is when the kitchen is open, "HH:MM-HH:MM" in
+// the business time zone; empty disables the kitchen screen check
+const SettingKitchenOpenHours = "kitchen.open_hours"
+
+// AlertKitchenScreensOffline is raised when no kitchen screen is connected
+// while the kitchen is open
+const AlertKitchenScreensOffline = "kitchen.screens_offline"
+
+// kitchenScreenGrace gives staff time to start the screens after opening
+const kitchenScreenGrace = 10 * time.Minute
+
+// Presence errors
+var (
+	ErrInvalidScreenID = errors.New("screen_id must be 1-64 letters, digits, '-' or '_'")
+	ErrInvalidLocation = errors.New("invalid coordinates")
+)
+
+// screenIDPattern is what a kitchen screen may call itself
+var screenIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// PresenceKind is the kind of client sending heartbeats
+type PresenceKind string
+
+// Presence kinds
+const (
+	PresenceRider         PresenceKind = "rider"
+	PresenceKitchenScreen PresenceKind = "kitchen_screen"
+)
+
+// Presence is a client seen recently. ID is the rider's user ID or the
+// kitchen screen's own ID; UserID is who the client is signed in as.
+type Presence struct {
+	Kind     PresenceKind `json:"kind"`
+	ID       string       `json:"id"`
+	UserID   uuid.UUID    `json:"user_id"`
+	Name     string       `json:"name,omitempty"`
+	Location *geo.Point   `json:"location,omitempty"`
+	LastSeen time.Time    `json:"last_seen"`
+}
+
+// PresenceUsecase tracks which rider apps and kitchen screens are online.
+// Each heartbeat stores the client under a key expiring after TTL, and
+// scores it by time in a per-kind index so listing needs no key scan.
+type PresenceUsecase struct {
+	redisClient *redis.Client
+	users       *UserCache
+	settings    *SettingsUsecase
+	alerts      *AdminAlertUsecase
+	ttl         time.Duration
+	location    *time.Location
+	log         *logger.Logger
+}
+
+// NewPresenceUsecase creates a new presence usecase. Clients missing
+// heartbeats for ttl are offline.
+func NewPresenceUsecase(redisClient *redis.Client, ttl time.Duration, location *time.Location, log *logger.Logger) *PresenceUsecase {
+	return &PresenceUsecase{
+		redisClient: redisClient,
+		ttl:         ttl,
+		location:    location,
+		log:         log,
+	}
+}
+
+// SetUserCache names riders after their account
+func (u *PresenceUsecase) SetUserCache(users *UserCache) {
+	u.users = users
+}
+
+// SetSettings reads the kitchen's open hours from runtime settings
+func (u *PresenceUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// SetAlerts alerts admins when no kitchen screen is connected during open hours
+func (u *PresenceUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// RiderHeartbeat marks a rider's app online, with its position if known
+func (u *PresenceUsecase) RiderHeartbeat(ctx context.Context, riderID uuid.UUID, location *geo.Point) error {
+	if location != nil && !location.Valid() {
+		return ErrInvalidLocation
+	}
+
+	presence := Presence{
+		Kind:     PresenceRider,
+		ID:       riderID.String(),
+		UserID:   riderID,
+		Location: location,
+		LastSeen: time.Now(),
+	}
+	if u.users != nil {
+		if user, err := u.users.Get(ctx, riderID); err == nil {
+			presence.Name = user.Name
+		}
+	}
+	return u.heartbeat(ctx, presence)
+}
+
+// KitchenScreenHeartbeat marks a kitchen display online. Screens choose
+// their own ID so several can run under one staff account.
+func (u *PresenceUsecase) KitchenScreenHeartbeat(ctx context.Context, userID uuid.UUID, screenID, name string) error {
+	if !screenIDPattern.MatchString(screenID) {
+		return ErrInvalidScreenID
+	}
+	name = strings.TrimSpace(name)
+	if len(name) > 100 {
+		name = name[:100]
+	}
+
+	return u.heartbeat(ctx, Presence{
+		Kind:     PresenceKitchenScreen,
+		ID:       screenID,
+		UserID:   userID,
+		Name:     name,
+		LastSeen: time.Now(),
+	})
+}
+
+// GoOffline removes a client straight away, e.g. when a rider ends a shift
+func (u *PresenceUsecase) GoOffline(ctx context.Context, kind PresenceKind, id string) error {
+	return u.redisClient.DeleteIndexed(ctx, presenceKey(kind, id), presenceIndexKey(kind), id)
+}
+
+// Online lists the clients of a kind seen within the TTL, most recent first
+func (u *PresenceUsecase) Online(ctx context.Context, kind PresenceKind) ([]Presence, error) {
+	ids, err := u.redisClient.IndexedSince(ctx, presenceIndexKey(kind), time.Now().Add(-u.ttl))
+	if err != nil {
+		return nil, err
+	}
+
+	online := []Presence{}
+	if len(ids) == 0 {
+		return online, nil
+	}
+
+	keys := make([]string, len(ids))
+	entries := make([]Presence, len(ids))
+	targets := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = presenceKey(kind, id)
+		targets[i] = &entries[i]
+	}
+	found, err := u.redisClient.MGetJSON(ctx, keys, targets)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if found[i] {
+			online = append(online, entries[i])
+		}
+	}
+	return online, nil
+}
+
+// RunKitchenWatch checks every interval that a kitchen screen is connected
+// while the kitchen is open, raising a critical alert when none is. Alerts
+// repeat at most every criticalAlertDedupe while the screens stay offline.
+func (u *PresenceUsecase) RunKitchenWatch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.checkKitchenScreens(ctx, time.Now())
+		}
+	}
+}
+
+// checkKitchenScreens raises an alert if the kitchen has been open for the
+// grace period and no screen is online
+func (u *PresenceUsecase) checkKitchenScreens(ctx context.Context, now time.Time) {
+	if u.settings == nil {
+		return
+	}
+	hours, err := ParseOpenHours(u.settings.String(ctx, SettingKitchenOpenHours))
+	if err != nil || hours == nil {
+		return
+	}
+	openFor, open := hours.OpenFor(now.In(u.location))
+	if !open || openFor < kitchenScreenGrace {
+		return
+	}
+
+	screens, err := u.Online(ctx, PresenceKitchenScreen)
+	if err != nil {
+		u.log.Warn("Failed to check kitchen screens", "error", err)
+		return
+	}
+	if len(screens) > 0 {
+		return
+	}
+
+	u.log.Warn("No kitchen screen online during open hours")
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertKitchenScreensOffline,
+		Critical: true,
+		Title:    "No kitchen screen connected",
+		Body:     "The kitchen is open but no kitchen display has checked in; new orders may go unseen.",
+		Data: map[string]string{
+			"type": "kitchen_screens_offline",
+		},
+	})
+}
+
+// heartbeat stores a client's presence and refreshes its index entry
+func (u *PresenceUsecase) heartbeat(ctx context.Context, presence Presence) error {
+	return u.redisClient.SetJSONIndexed(ctx, presenceKey(presence.Kind, presence.ID), presence, u.ttl,
+		presenceIndexKey(presence.Kind), presence.ID, presence.LastSeen)
+}
+
+func presenceKey(kind PresenceKind, id string) string {
+	return redis.PresencePrefix + string(kind) + ":" + id
+}
+
+func presenceIndexKey(kind PresenceKind) string {
+	return redis.PresenceIndexPrefix + string(kind)
+}
+
+// OpenHours is a daily opening window in minutes after midnight. End
+// before start runs past midnight.
+type OpenHours struct {
+	Start int
+	End   int
+}
+
+// ParseOpenHours parses "HH:MM-HH:MM"; an empty value returns nil
+func ParseOpenHours(value string) (*OpenHours, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("%q is not HH:MM-HH:MM", value)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not HH:MM-HH:MM", value)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil || end == start {
+		return nil, fmt.Errorf("%q is not HH:MM-HH:MM", value)
+	}
+	return &OpenHours{Start: start, End: end}, nil
+}
+
+// ValidateOpenHours checks a value for SettingKitchenOpenHours
+func ValidateOpenHours(value string) error {
+	_, err := ParseOpenHours(value)
+	return err
+}
+
+// OpenFor reports whether local falls in the window and how long it has
+// been open
+func (h *OpenHours) OpenFor(local time.Time) (time.Duration, bool) {
+	minute := local.Hour()*60 + local.Minute()
+	since := minute - h.Start
+	if since < 0 {
+		since += 24 * 60
+	}
+	length := h.End - h.Start
+	if length < 0 {
+		length += 24 * 60
+	}
+	if since >= length {
+		return 0, false
+	}
+	return time.Duration(since) * time.Minute, true
+}
+
+// nearestOnline orders riders by distance from origin; riders that haven't
+// shared a location go last, most recently seen first
+func nearestOnline(riders []Presence, origin geo.Point) {
+	sort.SliceStable(riders, func(i, j int) bool {
+		a, b := riders[i].Location, riders[j].Location
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return geo.HaversineMeters(origin, *a) < geo.HaversineMeters(origin, *b)
+	})
+}
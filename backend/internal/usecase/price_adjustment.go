@@ -0,0 +1,115 @@
+// Package usecase implements checkout price change detection
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+)
+
+// PriceAdjustment reports where the server's price for a cart differs from
+// what the app displayed, e.g. after a menu edit the app's cache missed.
+// Difference is current minus displayed; positive means the customer pays
+// more than they saw.
+type PriceAdjustment struct {
+	Lines                []PriceAdjustmentLine `json:"lines,omitempty"`
+	DisplayedTotal       int64                 `json:"displayed_total"`
+	CurrentTotal         int64                 `json:"current_total"`
+	Difference           int64                 `json:"difference"`
+	RequiresConfirmation bool                  `json:"requires_confirmation"`
+}
+
+// PriceAdjustmentLine is one item whose unit price changed
+type PriceAdjustmentLine struct {
+	MenuItemID     uuid.UUID `json:"menu_item_id"`
+	Name           string    `json:"name"`
+	VariantName    string    `json:"variant_name,omitempty"`
+	Quantity       int       `json:"quantity"`
+	DisplayedPrice int64     `json:"displayed_price"`
+	CurrentPrice   int64     `json:"current_price"`
+	Difference     int64     `json:"difference"` // For the whole line
+}
+
+// PriceChangedError is returned when the cart costs more than the app
+// displayed by more than the confirmation threshold. Resubmitting with
+// confirmed_total set to Adjustment.CurrentTotal places the order.
+type PriceChangedError struct {
+	Adjustment *PriceAdjustment
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("cart total changed by %d paisa, confirmation required", e.Adjustment.Difference)
+}
+
+// SetPriceConfirmThreshold sets how much more than the displayed total
+// (paisa) checkout charges without asking the customer to confirm. The
+// runtime setting overrides it when settings are configured.
+func (u *PaymentUsecase) SetPriceConfirmThreshold(paisa int64) {
+	u.priceConfirmThreshold = paisa
+}
+
+// confirmThreshold returns the effective confirmation threshold
+func (u *PaymentUsecase) confirmThreshold(ctx context.Context) int64 {
+	if u.settings != nil {
+		return u.settings.Int64(ctx, SettingCheckoutPriceConfirmThreshold)
+	}
+	return u.priceConfirmThreshold
+}
+
+// comparePrices builds the adjustment report for a priced cart against the
+// prices the app displayed. Returns nil when the app sent no prices or they
+// all match. Without a displayed total, items the app sent no price for are
+// assumed to match, and so is the delivery fee.
+func (u *PaymentUsecase) comparePrices(ctx context.Context, req InitiateOrderRequest, price *cartPrice) *PriceAdjustment {
+	shownAny := false
+	for _, item := range req.Items {
+		shownAny = shownAny || item.DisplayedPrice != nil
+	}
+	if !shownAny && req.DisplayedTotal == nil {
+		return nil
+	}
+
+	adj := &PriceAdjustment{CurrentTotal: price.Total}
+	var lineDifference int64
+	// price.Items has one line per cart line, in cart order
+	for i, item := range price.Items {
+		if req.Items[i].DisplayedPrice == nil {
+			continue
+		}
+		shown := *req.Items[i].DisplayedPrice
+		if shown == item.Price {
+			continue
+		}
+		diff := (item.Price - shown) * int64(item.Quantity)
+		lineDifference += diff
+		adj.Lines = append(adj.Lines, PriceAdjustmentLine{
+			MenuItemID:     item.MenuItemID,
+			Name:           item.Name,
+			VariantName:    item.VariantName,
+			Quantity:       item.Quantity,
+			DisplayedPrice: shown,
+			CurrentPrice:   item.Price,
+			Difference:     diff,
+		})
+	}
+
+	if req.DisplayedTotal != nil {
+		adj.DisplayedTotal = *req.DisplayedTotal
+	} else {
+		// GST follows the item prices, so it differs too
+		subtotal := price.Subtotal - lineDifference
+		tax := domain.TaxAmount(subtotal+price.PackagingCharge, price.TaxRateBasisPoints)
+		adj.DisplayedTotal = subtotal + price.DeliveryFee + price.PackagingCharge + tax
+	}
+	adj.Difference = adj.CurrentTotal - adj.DisplayedTotal
+	if adj.Difference == 0 && len(adj.Lines) == 0 {
+		return nil
+	}
+
+	// Only increases need consent; a cheaper cart is charged as is
+	adj.RequiresConfirmation = adj.Difference > u.confirmThreshold(ctx)
+	return adj
+}
@@ -0,0 +1,55 @@
+// Package usecase implements the charges added to an order's items
+package usecase
+
+import (
+	"context"
+
+	"fooddelivery/internal/domain"
+)
+
+// PricingConfig holds the default order charges. Amounts are in paisa.
+type PricingConfig struct {
+	PackagingCharge    int64 // Per order, delivery and pickup alike
+	GSTRateBasisPoints int   // GST on items and packaging; 500 = 5%
+}
+
+// OrderCharges is what an order costs on top of its items and delivery
+type OrderCharges struct {
+	PackagingCharge    int64
+	TaxAmount          int64
+	TaxRateBasisPoints int
+}
+
+// PricingUsecase computes the packaging charge and GST of an order. The
+// delivery fee is priced separately by DeliveryFeeUsecase and carries no
+// GST here.
+type PricingUsecase struct {
+	cfg      PricingConfig
+	settings *SettingsUsecase
+}
+
+// NewPricingUsecase creates a new pricing usecase
+func NewPricingUsecase(cfg PricingConfig) *PricingUsecase {
+	return &PricingUsecase{cfg: cfg}
+}
+
+// SetSettings lets admins change the packaging charge and GST rate at
+// runtime; without it the configured values apply
+func (u *PricingUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// Charges prices an order whose items come to subtotal
+func (u *PricingUsecase) Charges(ctx context.Context, subtotal int64) OrderCharges {
+	cfg := u.cfg
+	if u.settings != nil {
+		cfg.PackagingCharge = u.settings.Int64(ctx, SettingPricingPackagingCharge)
+		cfg.GSTRateBasisPoints = u.settings.Int(ctx, SettingPricingGSTRate)
+	}
+
+	return OrderCharges{
+		PackagingCharge:    cfg.PackagingCharge,
+		TaxAmount:          domain.TaxAmount(subtotal+cfg.PackagingCharge, cfg.GSTRateBasisPoints),
+		TaxRateBasisPoints: cfg.GSTRateBasisPoints,
+	}
+}
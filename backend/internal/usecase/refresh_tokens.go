@@ -0,0 +1,163 @@
+// Package usecase implements refresh tokens and logout
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/redis"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired, used or revoked
+// refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// SessionTokens are what a login hands the client: a short-lived access
+// token and, with Redis configured, a refresh token to renew it
+type SessionTokens struct {
+	Token            string     `json:"token"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+}
+
+// refreshSession is stored in Redis under the hash of a refresh token
+type refreshSession struct {
+	UserID uuid.UUID `json:"user_id"`
+	MFA    bool      `json:"mfa,omitempty"` // Carried over so admins don't re-verify on every refresh
+}
+
+// issueRefreshToken adds a new refresh token to tokens. Refresh tokens are
+// opaque random strings; only their hash is stored.
+func (u *UserUsecase) issueRefreshToken(ctx context.Context, userID uuid.UUID, mfaVerified bool, tokens *SessionTokens) error {
+	if u.redisClient == nil {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	session := refreshSession{UserID: userID, MFA: mfaVerified}
+	if err := u.redisClient.SetJSON(ctx, refreshTokenKey(refreshToken), session, u.refreshExpiry); err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(u.refreshExpiry)
+	tokens.RefreshToken = refreshToken
+	tokens.RefreshExpiresAt = &expiresAt
+	return nil
+}
+
+// RefreshRequest contains a refresh token from an earlier login or refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token works once, and roles are reloaded so
+// changes apply without logging in again.
+func (u *UserUsecase) Refresh(ctx context.Context, req RefreshRequest) (*SessionTokens, error) {
+	if u.redisClient == nil || req.RefreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var session refreshSession
+	found, err := u.redisClient.GetDelJSON(ctx, refreshTokenKey(req.RefreshToken), &session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+	if !found {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := u.getUserOrNotFound(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if user.IsDeactivated() {
+		return nil, ErrAccountDeactivated
+	}
+
+	tokens, err := u.issueSession(ctx, user, session.MFA)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Session refreshed", "user_id", user.ID.String())
+	return tokens, nil
+}
+
+// Logout revokes the refresh token, if given, and the access token the
+// request was made with. The access token stays blacklisted until it would
+// have expired.
+func (u *UserUsecase) Logout(ctx context.Context, claims *JWTClaims, refreshToken string) error {
+	if claims.TokenID != "" {
+		if err := u.userRepo.RevokeSession(ctx, claims.TokenID); err != nil {
+			u.log.Warn("Failed to mark session revoked", "user_id", claims.UserID.String(), "error", err)
+		}
+	}
+	if u.redisClient == nil {
+		return nil
+	}
+
+	if refreshToken != "" {
+		// Only the owner can revoke a refresh token
+		key := refreshTokenKey(refreshToken)
+		var session refreshSession
+		found, err := u.redisClient.GetJSON(ctx, key, &session)
+		if err != nil {
+			return fmt.Errorf("failed to load refresh token: %w", err)
+		}
+		if found && session.UserID == claims.UserID {
+			if err := u.redisClient.DeleteKey(ctx, key); err != nil {
+				return fmt.Errorf("failed to revoke refresh token: %w", err)
+			}
+		}
+	}
+
+	if claims.TokenID != "" && claims.ExpiresAt != nil {
+		if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+			if err := u.redisClient.SetJSON(ctx, redis.RevokedTokenPrefix+claims.TokenID, true, ttl); err != nil {
+				return fmt.Errorf("failed to revoke access token: %w", err)
+			}
+		}
+	}
+
+	u.log.Info("User logged out", "user_id", claims.UserID.String())
+	return nil
+}
+
+// IsTokenRevoked reports whether an access token was revoked by logout.
+// Redis errors fail open: the token is still signed and unexpired.
+func (u *UserUsecase) IsTokenRevoked(ctx context.Context, claims *JWTClaims) bool {
+	if u.redisClient == nil || claims.TokenID == "" {
+		return false
+	}
+
+	revoked, err := u.redisClient.KeyExists(ctx, redis.RevokedTokenPrefix+claims.TokenID)
+	if err != nil {
+		u.log.Warn("Failed to check token revocation", "error", err)
+		return false
+	}
+	return revoked
+}
+
+// refreshTokenKey is the Redis key of a refresh token, by its hash
+func refreshTokenKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return redis.RefreshTokenPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,300 @@
+// Package usecase implements refunds of captured payments
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+)
+
+// Refund errors
+var (
+	ErrRefundNotAllowed    = errors.New("order has no captured payment to refund")
+	ErrInvalidRefundAmount = errors.New("refund amount must be positive")
+	ErrRefundReason        = errors.New("a refund reason of at most 500 characters is required")
+	ErrNothingToRefund     = errors.New("order has been refunded in full")
+	ErrRefundRejected      = errors.New("razorpay rejected the refund")
+)
+
+// maxRefundReasonLength bounds an admin's refund reason
+const maxRefundReasonLength = 500
+
+// RefundEntity represents the refund data in refund webhooks
+type RefundEntity struct {
+	Refund struct {
+		Entity struct {
+			ID        string          `json:"id"`
+			Amount    int64           `json:"amount"`
+			PaymentID string          `json:"payment_id"`
+			Status    string          `json:"status"`
+			Notes     json.RawMessage `json:"notes"` // An empty array when there are none
+		} `json:"entity"`
+	} `json:"refund"`
+}
+
+// refundStatus maps a Razorpay refund status to ours
+func refundStatus(gatewayStatus string) domain.RefundStatus {
+	switch gatewayStatus {
+	case "processed":
+		return domain.RefundProcessed
+	case "failed":
+		return domain.RefundFailed
+	}
+	return domain.RefundPending
+}
+
+// issueRefund sends a refund to Razorpay and records it in the refunds
+// table and the payment audit trail. It is stored first, refused with
+// repository.ErrRefundExceedsPayment if the payment's refunds would pass
+// captured. On success refund carries Razorpay's ID and status. Refunds
+// are not retried: an attempt that timed out may still have gone through,
+// and a second would refund twice.
+func (u *PaymentUsecase) issueRefund(ctx context.Context, order *domain.Order, refund *domain.Refund, captured int64, eventType string, previousStatus, newStatus *domain.OrderStatus) error {
+	event := &domain.PaymentEvent{
+		OrderID:           &order.ID,
+		RazorpayOrderID:   order.RazorpayOrderID,
+		RazorpayPaymentID: refund.RazorpayPaymentID,
+		Source:            domain.PaymentSourceRefund,
+		EventType:         eventType,
+		PreviousStatus:    previousStatus,
+		NewStatus:         newStatus,
+		Amount:            &refund.Amount,
+	}
+
+	if refund.RazorpayPaymentID == "" {
+		event.Outcome, event.Detail = domain.PaymentOutcomeError, ErrRefundNotAllowed.Error()
+		u.recordPaymentEvent(ctx, event)
+		return ErrRefundNotAllowed
+	}
+
+	if err := u.refunds.Create(ctx, refund, captured); err != nil {
+		event.Outcome, event.Detail = domain.PaymentOutcomeRejected, err.Error()
+		if !errors.Is(err, repository.ErrRefundExceedsPayment) {
+			event.Outcome = domain.PaymentOutcomeError
+		}
+		u.recordPaymentEvent(ctx, event)
+		return err
+	}
+
+	client, _ := u.gateway()
+	resp, err := client.Payment.Refund(refund.RazorpayPaymentID, int(refund.Amount), map[string]interface{}{
+		"notes": map[string]interface{}{
+			"order_id":  order.ID.String(),
+			"refund_id": refund.ID.String(),
+			"reason":    eventType,
+		},
+	}, nil)
+	if err != nil {
+		if _, _, settleErr := u.refunds.Settle(ctx, refund.ID, "", domain.RefundFailed, err.Error()); settleErr != nil {
+			u.log.Warn("Failed to record refund failure", "refund_id", refund.ID.String(), "error", settleErr)
+		}
+		refund.Status, refund.FailureReason = domain.RefundFailed, err.Error()
+		event.Outcome, event.Detail = domain.PaymentOutcomeError, err.Error()
+		u.recordPaymentEvent(ctx, event)
+		return fmt.Errorf("%w: %v", ErrRefundRejected, err)
+	}
+
+	razorpayRefundID, _ := resp["id"].(string)
+	gatewayStatus, _ := resp["status"].(string)
+	if settled, _, err := u.refunds.Settle(ctx, refund.ID, razorpayRefundID, refundStatus(gatewayStatus), ""); err != nil {
+		// The money is on its way; the webhook fills the record in later
+		u.log.Warn("Failed to record issued refund", "refund_id", refund.ID.String(), "razorpay_refund_id", razorpayRefundID, "error", err)
+		refund.RazorpayRefundID = razorpayRefundID
+	} else {
+		*refund = *settled
+	}
+
+	event.Outcome, event.Detail = domain.PaymentOutcomeApplied, "refund "+razorpayRefundID
+	u.recordPaymentEvent(ctx, event)
+	u.log.Info("Payment refunded", "order_id", order.ID.String(), "refund_id", razorpayRefundID, "amount", refund.Amount)
+
+	return nil
+}
+
+// InitiateRefund refunds all or part of a paid order on an admin's behalf.
+// An amount of 0 refunds whatever earlier refunds haven't returned. The
+// order's status doesn't change; cancel it separately if it won't go ahead.
+func (u *PaymentUsecase) InitiateRefund(ctx context.Context, adminID, orderID uuid.UUID, amount int64, reason string) (*domain.Refund, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxRefundReasonLength {
+		return nil, ErrRefundReason
+	}
+	if amount < 0 {
+		return nil, ErrInvalidRefundAmount
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.RazorpayPaymentID == "" || !isPaidStatus(order.Status) {
+		return nil, ErrRefundNotAllowed
+	}
+
+	if amount == 0 {
+		refunded, err := u.refunds.RefundedAmount(ctx, order.RazorpayPaymentID)
+		if err != nil {
+			return nil, err
+		}
+		amount = order.TotalAmount - refunded
+		if amount <= 0 {
+			return nil, ErrNothingToRefund
+		}
+	}
+
+	refund := &domain.Refund{
+		OrderID:           order.ID,
+		RazorpayPaymentID: order.RazorpayPaymentID,
+		Amount:            amount,
+		Reason:            reason,
+		InitiatedBy:       &adminID,
+	}
+	if err := u.issueRefund(ctx, order, refund, order.TotalAmount, "refund.admin", nil, nil); err != nil {
+		return nil, err
+	}
+
+	u.audit.Record(ctx, adminID, domain.AuditRefundInitiated, "order", order.ID.String(), map[string]interface{}{
+		"refund_id":          refund.ID.String(),
+		"razorpay_refund_id": refund.RazorpayRefundID,
+		"amount":             refund.Amount,
+		"reason":             reason,
+	})
+
+	return refund, nil
+}
+
+// GetRefundStatus returns a refund, asking Razorpay for the latest status
+// while it is pending in case its webhook was missed
+func (u *PaymentUsecase) GetRefundStatus(ctx context.Context, refundID uuid.UUID) (*domain.Refund, error) {
+	refund, err := u.refunds.GetByID(ctx, refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != domain.RefundPending || refund.RazorpayRefundID == "" {
+		return refund, nil
+	}
+
+	client, _ := u.gateway()
+	resp, err := client.Refund.Fetch(refund.RazorpayRefundID, nil, nil)
+	if err != nil {
+		u.log.Warn("Failed to fetch refund status", "refund_id", refund.ID.String(), "razorpay_refund_id", refund.RazorpayRefundID, "error", err)
+		return refund, nil
+	}
+	gatewayStatus, _ := resp["status"].(string)
+	status := refundStatus(gatewayStatus)
+	if status == domain.RefundPending {
+		return refund, nil
+	}
+
+	settled, changed, err := u.refunds.Settle(ctx, refund.ID, refund.RazorpayRefundID, status, refundFailureReason(status))
+	if err != nil {
+		return nil, err
+	}
+	if changed && settled.Status == domain.RefundFailed {
+		u.raiseRefundFailed(ctx, settled.OrderID, settled.Amount, "after Razorpay failed it")
+	}
+	return settled, nil
+}
+
+// ListRefunds returns an order's refunds oldest first
+func (u *PaymentUsecase) ListRefunds(ctx context.Context, orderID uuid.UUID) ([]domain.Refund, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return u.refunds.ListByOrder(ctx, order.ID)
+}
+
+// handleRefundSettled records Razorpay's refund.processed and refund.failed
+// webhooks against the refund they settle. Refunds issued outside the app
+// (e.g. from the Razorpay dashboard) are logged and otherwise ignored.
+func (u *PaymentUsecase) handleRefundSettled(ctx context.Context, webhookData WebhookPayload, payload []byte, log *logger.Logger) error {
+	var refundData RefundEntity
+	if err := json.Unmarshal(webhookData.Payload, &refundData); err != nil {
+		log.Error("Failed to parse refund entity", "error", err)
+		_, _ = u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
+		return nil // Redelivery won't parse either
+	}
+
+	entity := refundData.Refund.Entity
+	log = log.WithFields(map[string]interface{}{
+		"razorpay_refund_id": entity.ID,
+		"payment_id":         entity.PaymentID,
+		"amount":             entity.Amount,
+	})
+
+	event := &domain.PaymentEvent{
+		RazorpayPaymentID: entity.PaymentID,
+		Source:            domain.PaymentSourceWebhook,
+		EventType:         webhookData.Event,
+		Amount:            &entity.Amount,
+	}
+
+	refund, err := u.refunds.GetByRazorpayRefundID(ctx, entity.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The webhook can beat the refund call's response; our ID is in the notes
+		var notes struct {
+			RefundID string `json:"refund_id"`
+		}
+		_ = json.Unmarshal(entity.Notes, &notes)
+		if id, parseErr := uuid.Parse(notes.RefundID); parseErr == nil {
+			refund, err = u.refunds.GetByID(ctx, id)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Warn("Refund not found for webhook")
+			logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, nil, "refund not found")
+			u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeRejected, "refund not issued by this system")
+			return nil
+		}
+		log.Error("Failed to find refund", "error", err)
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, nil, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
+		return err
+	}
+	event.OrderID = &refund.OrderID
+
+	status := refundStatus(entity.Status)
+	if webhookData.Event == "refund.failed" {
+		status = domain.RefundFailed
+	}
+	settled, changed, err := u.refunds.Settle(ctx, refund.ID, entity.ID, status, refundFailureReason(status))
+	if err != nil {
+		log.Error("Failed to settle refund", "error", err)
+		logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &refund.OrderID, err.Error())
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeError, err.Error())
+		return err
+	}
+
+	logID, _ := u.logWebhook(ctx, webhookData.Event, payload, true, &refund.OrderID, "")
+	if !changed {
+		u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeNoChange, "refund already "+strings.ToLower(string(settled.Status)))
+		return nil
+	}
+
+	log.Info("Refund settled", "order_id", settled.OrderID.String(), "status", settled.Status)
+	u.recordWebhookEvent(ctx, event, logID, domain.PaymentOutcomeApplied, "refund "+strings.ToLower(string(settled.Status)))
+	if settled.Status == domain.RefundFailed {
+		u.raiseRefundFailed(ctx, settled.OrderID, settled.Amount, "after Razorpay failed it")
+	}
+
+	return nil
+}
+
+// refundFailureReason explains a failed refund; Razorpay doesn't say why
+func refundFailureReason(status domain.RefundStatus) string {
+	if status == domain.RefundFailed {
+		return "failed at Razorpay"
+	}
+	return ""
+}
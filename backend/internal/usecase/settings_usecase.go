@@ -0,0 +1,438 @@
+// Package usecase implements runtime-editable settings
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+)
+
+// Setting keys read by other usecases
+const (
+	SettingDeliveryBaseFee      = "delivery.base_fee"
+	SettingDeliveryBaseDistance = "delivery.base_distance_meters"
+	SettingDeliveryPerKmFee     = "delivery.per_km_fee"
+	SettingDeliveryMaxDistance  = "delivery.max_distance_meters"
+	SettingDeliverySlotCapacity = "delivery.slot_capacity"
+	SettingKitchenPrepMinutes   = "kitchen.prep_minutes"
+	SettingKitchenSlotCapacity  = "kitchen.slot_capacity"
+	SettingSurveyAlertThreshold = "survey.alert_threshold"
+
+	SettingCheckoutPriceConfirmThreshold = "checkout.price_confirm_threshold"
+
+	SettingPricingPackagingCharge = "pricing.packaging_charge"
+	SettingPricingGSTRate         = "pricing.gst_rate_basis_points"
+
+	SettingCallsAfterDeliveryMinutes = "calls.after_delivery_minutes"
+
+	SettingChurnMinOrders = "churn.min_orders"
+	SettingChurnQuietDays = "churn.quiet_days"
+
+	SettingIssueWindowHours     = "issues.window_hours"
+	SettingIssueMaxAutoRefund   = "issues.max_auto_refund"
+	SettingIssueColdFoodPercent = "issues.cold_food_percent"
+	SettingIssueAutoLimit       = "issues.auto_resolve_limit"
+
+	SettingInventoryLowStockThreshold = "inventory.low_stock_threshold"
+	SettingInventoryDisableSoldOut    = "inventory.disable_sold_out"
+
+	SettingAppMinVersionAndroid = "app.min_version.android"
+	SettingAppMinVersionIOS     = "app.min_version.ios"
+)
+
+// defaultSettingHistoryLimit is used when no valid limit is requested
+const defaultSettingHistoryLimit = 50
+
+// preloadedSettingsKey carries overrides attached by Preload
+type preloadedSettingsKey struct{}
+
+var (
+	// ErrUnknownSetting is returned for a key no definition exists for
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrInvalidSettingValue is returned when a value fails type or range checks
+	ErrInvalidSettingValue = errors.New("invalid setting value")
+	// ErrSettingNotOverridden is returned when resetting a setting already at its default
+	ErrSettingNotOverridden = errors.New("setting is not overridden")
+)
+
+// SettingDefinition declares a setting: its type, the default from the
+// environment and the accepted range for numeric settings
+type SettingDefinition struct {
+	Key         string
+	Type        domain.SettingType
+	Description string
+	Default     any
+	Min         *float64
+	Max         *float64
+	Validate    func(value any) error // Extra check on the decoded value, if set
+}
+
+// IntSetting defines an integer setting bounded to [min, max]
+func IntSetting(key, description string, def, min, max int) SettingDefinition {
+	lo, hi := float64(min), float64(max)
+	return SettingDefinition{Key: key, Type: domain.SettingTypeInt, Description: description, Default: def, Min: &lo, Max: &hi}
+}
+
+// BoolSetting defines a boolean setting
+func BoolSetting(key, description string, def bool) SettingDefinition {
+	return SettingDefinition{Key: key, Type: domain.SettingTypeBool, Description: description, Default: def}
+}
+
+// StringSetting defines a string setting; validate, if not nil, rejects
+// malformed values
+func StringSetting(key, description, def string, validate func(string) error) SettingDefinition {
+	setting := SettingDefinition{Key: key, Type: domain.SettingTypeString, Description: description, Default: def}
+	if validate != nil {
+		setting.Validate = func(value any) error { return validate(value.(string)) }
+	}
+	return setting
+}
+
+// SettingsUsecase serves typed settings: admin overrides stored in Postgres
+// and cached in Redis, falling back to environment defaults. Reads never
+// fail; if overrides can't be loaded the defaults are used.
+type SettingsUsecase struct {
+	repo        *repository.SettingsRepository
+	redisClient *redis.Client
+	defs        map[string]SettingDefinition
+	order       []string
+	audit       *AuditUsecase
+	log         *logger.Logger
+}
+
+// NewSettingsUsecase creates a settings usecase serving the given definitions
+func NewSettingsUsecase(repo *repository.SettingsRepository, redisClient *redis.Client, defs []SettingDefinition, log *logger.Logger) *SettingsUsecase {
+	u := &SettingsUsecase{
+		repo:        repo,
+		redisClient: redisClient,
+		defs:        make(map[string]SettingDefinition, len(defs)),
+		log:         log,
+	}
+	for _, def := range defs {
+		if _, dup := u.defs[def.Key]; dup {
+			panic(fmt.Sprintf("duplicate setting: %s", def.Key))
+		}
+		u.defs[def.Key] = def
+		u.order = append(u.order, def.Key)
+	}
+	return u
+}
+
+// GetAll returns every setting with its effective value
+func (u *SettingsUsecase) GetAll(ctx context.Context) ([]domain.Setting, error) {
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]domain.Setting, 0, len(u.order))
+	for _, key := range u.order {
+		settings = append(settings, u.effective(u.defs[key], overrides))
+	}
+	return settings, nil
+}
+
+// SetAuditLog records setting changes in the admin audit log
+func (u *SettingsUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// Get returns one setting with its effective value
+func (u *SettingsUsecase) Get(ctx context.Context, key string) (*domain.Setting, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	setting := u.effective(def, overrides)
+	return &setting, nil
+}
+
+// Update validates and stores an override, effective immediately
+func (u *SettingsUsecase) Update(ctx context.Context, key string, value json.RawMessage, adminID uuid.UUID) (*domain.Setting, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	normalized, err := validateSetting(def, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Set(ctx, key, normalized, adminID); err != nil {
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.log.Info("Setting updated", "key", key, "value", string(normalized), "admin_id", adminID.String())
+	u.audit.Record(ctx, adminID, domain.AuditSettingUpdated, "setting", key, map[string]interface{}{
+		"value": normalized,
+	})
+
+	return u.Get(ctx, key)
+}
+
+// Reset removes an override so the environment default applies again
+func (u *SettingsUsecase) Reset(ctx context.Context, key string, adminID uuid.UUID) (*domain.Setting, error) {
+	if _, ok := u.defs[key]; !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	if err := u.repo.Delete(ctx, key, adminID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrSettingNotOverridden
+		}
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.log.Info("Setting reset to default", "key", key, "admin_id", adminID.String())
+	u.audit.Record(ctx, adminID, domain.AuditSettingReset, "setting", key, nil)
+
+	return u.Get(ctx, key)
+}
+
+// History returns a setting's change history, newest first
+func (u *SettingsUsecase) History(ctx context.Context, key string, limit int) ([]domain.SettingChange, error) {
+	if _, ok := u.defs[key]; !ok {
+		return nil, ErrUnknownSetting
+	}
+	if limit <= 0 || limit > 200 {
+		limit = defaultSettingHistoryLimit
+	}
+	return u.repo.ListChanges(ctx, key, limit)
+}
+
+// Int returns the effective value of an integer setting
+func (u *SettingsUsecase) Int(ctx context.Context, key string) int {
+	var v int
+	u.value(ctx, key, &v)
+	return v
+}
+
+// Int64 returns the effective value of an integer setting as int64
+func (u *SettingsUsecase) Int64(ctx context.Context, key string) int64 {
+	var v int64
+	u.value(ctx, key, &v)
+	return v
+}
+
+// Float returns the effective value of a float setting
+func (u *SettingsUsecase) Float(ctx context.Context, key string) float64 {
+	var v float64
+	u.value(ctx, key, &v)
+	return v
+}
+
+// Bool returns the effective value of a boolean setting
+func (u *SettingsUsecase) Bool(ctx context.Context, key string) bool {
+	var v bool
+	u.value(ctx, key, &v)
+	return v
+}
+
+// String returns the effective value of a string setting
+func (u *SettingsUsecase) String(ctx context.Context, key string) string {
+	var v string
+	u.value(ctx, key, &v)
+	return v
+}
+
+// Preload loads the overrides once and returns a context carrying them, so
+// every setting read made with it skips Redis. Paths that read several
+// settings per request (checkout) should call it first. extraKeys are
+// fetched in the same MGET into extraTargets; found reports which were
+// present. On Redis errors the original context is returned with nothing
+// found, and reads fall back to their usual lookups.
+func (u *SettingsUsecase) Preload(ctx context.Context, extraKeys []string, extraTargets []any) (context.Context, []bool) {
+	found := make([]bool, len(extraKeys))
+
+	var overrides map[string]domain.Setting
+	if u.redisClient != nil {
+		keys := append([]string{redis.SettingsCacheKey}, extraKeys...)
+		targets := append([]any{&overrides}, extraTargets...)
+		hits, err := u.redisClient.MGetJSON(ctx, keys, targets)
+		if err != nil {
+			u.log.Warn("Failed to preload settings", "error", err)
+			return ctx, found
+		}
+		copy(found, hits[1:])
+		if !hits[0] {
+			overrides = nil
+		}
+	}
+
+	if overrides == nil {
+		var err error
+		if overrides, err = u.overrides(ctx); err != nil {
+			u.log.Warn("Failed to preload settings", "error", err)
+			return ctx, found
+		}
+	}
+
+	return context.WithValue(ctx, preloadedSettingsKey{}, overrides), found
+}
+
+// value decodes the effective value of key into target, using the default
+// when overrides are unavailable
+func (u *SettingsUsecase) value(ctx context.Context, key string, target any) {
+	def, ok := u.defs[key]
+	if !ok {
+		u.log.Error("Read of undefined setting", "key", key)
+		return
+	}
+
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		u.log.Warn("Failed to load settings, using default", "key", key, "error", err)
+		overrides = nil
+	}
+
+	setting := u.effective(def, overrides)
+	if err := json.Unmarshal(setting.Value, target); err != nil {
+		u.log.Error("Stored setting does not match its type, using default", "key", key, "error", err)
+		_ = json.Unmarshal(setting.Default, target)
+	}
+}
+
+// effective merges a definition with its override, if any
+func (u *SettingsUsecase) effective(def SettingDefinition, overrides map[string]domain.Setting) domain.Setting {
+	defaultValue, _ := json.Marshal(def.Default)
+
+	setting := domain.Setting{
+		Key:         def.Key,
+		Type:        def.Type,
+		Description: def.Description,
+		Value:       defaultValue,
+		Default:     defaultValue,
+		Min:         def.Min,
+		Max:         def.Max,
+	}
+
+	if override, ok := overrides[def.Key]; ok {
+		setting.Value = override.Value
+		setting.IsOverridden = true
+		setting.UpdatedBy = override.UpdatedBy
+		setting.UpdatedAt = override.UpdatedAt
+	}
+
+	return setting
+}
+
+// overrides loads stored overrides: preloaded on ctx, else through the
+// Redis cache
+func (u *SettingsUsecase) overrides(ctx context.Context) (map[string]domain.Setting, error) {
+	if preloaded, ok := ctx.Value(preloadedSettingsKey{}).(map[string]domain.Setting); ok {
+		return preloaded, nil
+	}
+
+	if u.redisClient != nil {
+		var cached map[string]domain.Setting
+		found, err := u.redisClient.GetJSON(ctx, redis.SettingsCacheKey, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read settings cache", "error", err)
+		} else if found {
+			return cached, nil
+		}
+	}
+
+	overrides, err := u.repo.GetOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, redis.SettingsCacheKey, overrides, redis.SettingsCacheTTL); err != nil {
+			u.log.Warn("Failed to cache settings", "error", err)
+		}
+	}
+
+	return overrides, nil
+}
+
+func (u *SettingsUsecase) invalidate(ctx context.Context) {
+	if u.redisClient == nil {
+		return
+	}
+	if err := u.redisClient.DeleteKey(ctx, redis.SettingsCacheKey); err != nil {
+		u.log.Warn("Failed to invalidate settings cache", "error", err)
+	}
+}
+
+// validateSetting checks a value against its definition and returns it in
+// canonical JSON form
+func validateSetting(def SettingDefinition, raw json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettingValue, err)
+	}
+
+	var normalized any
+	switch def.Type {
+	case domain.SettingTypeInt, domain.SettingTypeFloat:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidSettingValue, def.Key)
+		}
+		f, err := n.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidSettingValue, def.Key)
+		}
+		if def.Type == domain.SettingTypeInt {
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a whole number", ErrInvalidSettingValue, def.Key)
+			}
+			normalized = i
+		} else {
+			normalized = f
+		}
+		if def.Min != nil && f < *def.Min {
+			return nil, fmt.Errorf("%w: %s must be at least %v", ErrInvalidSettingValue, def.Key, *def.Min)
+		}
+		if def.Max != nil && f > *def.Max {
+			return nil, fmt.Errorf("%w: %s must be at most %v", ErrInvalidSettingValue, def.Key, *def.Max)
+		}
+	case domain.SettingTypeBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidSettingValue, def.Key)
+		}
+		normalized = b
+	case domain.SettingTypeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidSettingValue, def.Key)
+		}
+		normalized = s
+	default:
+		return nil, fmt.Errorf("%w: %s has unsupported type %s", ErrInvalidSettingValue, def.Key, def.Type)
+	}
+
+	if def.Validate != nil {
+		if err := def.Validate(normalized); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSettingValue, def.Key, err)
+		}
+	}
+
+	return json.Marshal(normalized)
+}
@@ -0,0 +1,105 @@
+// Package usecase defines the storage the core usecases depend on, so unit
+// tests can replace the Postgres repositories with fakes
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// OrderStore is the order storage OrderUsecase uses; satisfied by
+// *repository.OrderRepository
+type OrderStore interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID, filter repository.OrderListFilter, limit, offset int) ([]domain.Order, error)
+	GetAllOrders(ctx context.Context, filter repository.AdminOrderFilter, limit, offset int, after *repository.OrderCursor) ([]domain.Order, error)
+	StreamOrders(ctx context.Context, filter repository.OrderExportFilter, fn func(*domain.Order) error) error
+	UpdateStatus(ctx context.Context, orderID uuid.UUID, newStatus domain.OrderStatus, expectedVersion int) error
+	Cancel(ctx context.Context, orderID uuid.UUID, expectedVersion int, cancelledBy *uuid.UUID, reason string) error
+
+	// Fulfilment
+	CompletePickup(ctx context.Context, orderID, verifiedBy uuid.UUID, expectedVersion int) error
+	Dispatch(ctx context.Context, orderID, riderID uuid.UUID, otp string, expectedVersion int) error
+	IncrementDeliveryOTPAttempts(ctx context.Context, orderID uuid.UUID) (int, error)
+	CompleteDelivery(ctx context.Context, orderID uuid.UUID, confirmation domain.DeliveryConfirmation, overrideBy *uuid.UUID, reason string, expectedVersion int) error
+	GetActiveByRider(ctx context.Context, riderID uuid.UUID) ([]domain.Order, error)
+	SaveDeliveryProof(ctx context.Context, proof *domain.DeliveryProof) (string, error)
+	GetDeliveryProof(ctx context.Context, orderID uuid.UUID) (*domain.DeliveryProof, error)
+
+	// Tags and attributes
+	UpdateTags(ctx context.Context, orderID uuid.UUID, add, remove []string, maxTags int) (tags []string, applied bool, err error)
+	UpdateAttributes(ctx context.Context, orderID uuid.UUID, set map[string]interface{}, remove []string) (map[string]interface{}, error)
+	GetAttributeDefinitions(ctx context.Context) ([]domain.OrderAttributeDefinition, error)
+	SaveAttributeDefinition(ctx context.Context, d *domain.OrderAttributeDefinition) error
+}
+
+// MenuStore is the menu storage MenuUsecase uses; satisfied by
+// *repository.MenuRepository
+type MenuStore interface {
+	GetAll(ctx context.Context) ([]domain.MenuItem, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.MenuItem, error)
+	Create(ctx context.Context, item *domain.MenuItem, createdBy uuid.UUID) error
+	Update(ctx context.Context, item *domain.MenuItem, changedBy uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, expectedVersion int, deletedBy uuid.UUID) error
+	GetPriceHistory(ctx context.Context, itemID uuid.UUID) ([]domain.MenuPriceChange, error)
+	GetMenuAsOf(ctx context.Context, at time.Time) ([]domain.MenuItemAsOf, error)
+	SetSortOrder(ctx context.Context, itemIDs []uuid.UUID) error
+	SetAvailability(ctx context.Context, itemIDs []uuid.UUID, available bool, changedBy uuid.UUID) ([]uuid.UUID, error)
+	SetOptions(ctx context.Context, itemID uuid.UUID, variants []domain.MenuItemVariant, modifiers []domain.MenuItemModifier) error
+}
+
+// UserReader loads users by ID, all UserCache needs
+type UserReader interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+}
+
+// UserStore is the user storage UserUsecase uses; satisfied by
+// *repository.UserRepository
+type UserStore interface {
+	UserReader
+	GetByPhoneNumber(ctx context.Context, phoneNumber string) (*domain.User, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	Create(ctx context.Context, user *domain.User) error
+	Update(ctx context.Context, user *domain.User) error
+	Deactivate(ctx context.Context, userID uuid.UUID, by *uuid.UUID, reason string, at time.Time) error
+	Reactivate(ctx context.Context, userID uuid.UUID) error
+	SetLocale(ctx context.Context, userID uuid.UUID, locale string) error
+	SetDetectedLocale(ctx context.Context, userID uuid.UUID, locale string) (bool, error)
+
+	// OTPs and sessions
+	CreateOTP(ctx context.Context, otp *domain.OTP) error
+	GetValidOTP(ctx context.Context, contact string, purpose domain.OTPPurpose) (*domain.OTP, error)
+	IncrementOTPAttempts(ctx context.Context, otpID uuid.UUID) error
+	MarkOTPVerified(ctx context.Context, otpID uuid.UUID) error
+	CreateSession(ctx context.Context, session *domain.Session) error
+	RevokeSession(ctx context.Context, tokenID string) error
+
+	// Social sign-in identities
+	GetIdentity(ctx context.Context, provider domain.IdentityProvider, subject string) (*domain.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error
+	TouchIdentity(ctx context.Context, identityID uuid.UUID) error
+
+	// Two-factor authentication
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*domain.UserTOTP, error)
+	SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	ConsumeRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+
+	// Order stats
+	GetOrderStats(ctx context.Context, userID uuid.UUID) (*domain.UserOrderStats, error)
+	ListOrderStats(ctx context.Context, filter repository.UserSegmentFilter) ([]domain.UserOrderStats, error)
+}
+
+// The repositories must keep satisfying the stores
+var (
+	_ OrderStore = (*repository.OrderRepository)(nil)
+	_ MenuStore  = (*repository.MenuRepository)(nil)
+	_ UserStore  = (*repository.UserRepository)(nil)
+)
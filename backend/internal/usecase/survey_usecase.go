@@ -0,0 +1,235 @@
+// Package usecase implements post-delivery NPS surveys
+package usecase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/throttle"
+)
+
+// Survey errors
+var (
+	ErrSurveyNotFound      = errors.New("no survey for this order")
+	ErrSurveyAnswered      = errors.New("survey has already been answered")
+	ErrInvalidSurveyScore  = errors.New("score must be between 0 and 10")
+	ErrSurveyCommentLength = errors.New("comment is too long")
+)
+
+const (
+	maxSurveyComment   = 1000
+	surveyOpenFor      = 7 * 24 * time.Hour // Unanswered surveys stop showing after a week
+	surveyDispatchSize = 100
+)
+
+var surveyLowScores = metrics.NewCounterVec(
+	"survey_low_scores_total",
+	"Survey responses at or below the admin alert threshold",
+)
+
+// SurveyConfig tunes survey timing and alerting
+type SurveyConfig struct {
+	Delay          time.Duration // Wait after delivery before asking
+	AlertThreshold int           // Scores at or below this alert admins
+}
+
+// SurveyUsecase schedules surveys, collects responses and reports NPS
+type SurveyUsecase struct {
+	repo      *repository.SurveyRepository
+	sender    push.Sender
+	templates *TemplateUsecase
+	cfg       SurveyConfig
+	settings  *SettingsUsecase
+	alerts    *AdminAlertUsecase
+	log       *logger.Logger
+}
+
+// NewSurveyUsecase creates a new survey usecase
+func NewSurveyUsecase(repo *repository.SurveyRepository, sender push.Sender, templates *TemplateUsecase, cfg SurveyConfig, log *logger.Logger) *SurveyUsecase {
+	return &SurveyUsecase{
+		repo:      repo,
+		sender:    sender,
+		templates: templates,
+		cfg:       cfg,
+		log:       log,
+	}
+}
+
+// Schedule queues a survey for a delivered order. Failures are logged only;
+// a missing survey must never fail the delivery itself.
+func (u *SurveyUsecase) Schedule(ctx context.Context, order *domain.Order) {
+	if order.Status != domain.OrderStatusDelivered {
+		return
+	}
+
+	survey := &domain.OrderSurvey{
+		OrderID:   order.ID,
+		UserID:    order.UserID,
+		SendAfter: time.Now().Add(u.cfg.Delay),
+	}
+	if err := u.repo.Create(ctx, survey); err != nil {
+		u.log.Warn("Failed to schedule survey", "order_id", order.ID.String(), "error", err)
+	}
+}
+
+// RunDispatcher pushes due surveys every interval until ctx is cancelled
+func (u *SurveyUsecase) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.dispatchDue(ctx)
+		}
+	}
+}
+
+// dispatchDue sends one batch of due survey pushes
+func (u *SurveyUsecase) dispatchDue(ctx context.Context) {
+	surveys, err := u.repo.GetDue(ctx, surveyDispatchSize)
+	if err != nil {
+		u.log.Error("Failed to load due surveys", "error", err)
+		return
+	}
+
+	for _, survey := range surveys {
+		text, err := u.templates.RenderForUser(ctx, TemplateOrderSurvey, survey.UserID, map[string]any{
+			"order_id": survey.OrderID.String(),
+		})
+		if err != nil {
+			u.log.Error("Failed to render survey push", "order_id", survey.OrderID.String(), "error", err)
+			continue
+		}
+
+		msg := push.Message{
+			Title: text.Subject,
+			Body:  text.Body,
+			Data: map[string]string{
+				"type":     "order_survey",
+				"order_id": survey.OrderID.String(),
+			},
+		}
+
+		// Surveys yield to order updates and OTPs when the provider is busy
+		if err := u.sender.Send(throttle.WithPriority(ctx, throttle.PriorityMarketing), survey.UserID, msg); err != nil {
+			// Left unsent so the next tick retries
+			u.log.Warn("Failed to send survey push", "order_id", survey.OrderID.String(), "error", err)
+			continue
+		}
+
+		if err := u.repo.MarkSent(ctx, survey.ID); err != nil {
+			u.log.Warn("Failed to mark survey sent", "order_id", survey.OrderID.String(), "error", err)
+		}
+	}
+}
+
+// GetOpenSurveys returns the user's unanswered surveys from the past week
+func (u *SurveyUsecase) GetOpenSurveys(ctx context.Context, userID uuid.UUID) ([]domain.OrderSurvey, error) {
+	return u.repo.GetOpenByUser(ctx, userID, time.Now().Add(-surveyOpenFor))
+}
+
+// Respond records the customer's score for their order
+func (u *SurveyUsecase) Respond(ctx context.Context, orderID, userID uuid.UUID, score int, comment string) (*domain.OrderSurvey, error) {
+	if score < 0 || score > 10 {
+		return nil, ErrInvalidSurveyScore
+	}
+	comment = strings.TrimSpace(comment)
+	if len(comment) > maxSurveyComment {
+		return nil, ErrSurveyCommentLength
+	}
+
+	survey, err := u.repo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrSurveyNotFound
+		}
+		return nil, err
+	}
+	if survey.UserID != userID {
+		return nil, ErrSurveyNotFound
+	}
+
+	ok, err := u.repo.Respond(ctx, survey.ID, score, comment)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSurveyAnswered
+	}
+
+	if score <= u.alertThreshold(ctx) {
+		surveyLowScores.Inc()
+		u.log.Warn("Low survey score",
+			"order_id", orderID.String(),
+			"user_id", userID.String(),
+			"score", score,
+			"comment", comment,
+		)
+		u.alerts.Raise(ctx, AdminAlert{
+			Kind:  AlertSurveyLowScore,
+			Title: "Order #" + orderNumber(orderID) + " scored " + strconv.Itoa(score) + "/10",
+			Body:  comment,
+			Data:  map[string]string{"type": "survey_alert", "order_id": orderID.String()},
+		})
+	}
+
+	return u.repo.GetByOrderID(ctx, orderID)
+}
+
+// GetNPS returns the NPS over the last days days
+func (u *SurveyUsecase) GetNPS(ctx context.Context, days int) (*domain.NPSSummary, error) {
+	to := time.Now()
+	summary, err := u.repo.GetNPS(ctx, to.AddDate(0, 0, -days), to)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.Responses > 0 {
+		raw := float64(summary.Promoters-summary.Detractors) / float64(summary.Responses) * 100
+		summary.Score = math.Round(raw*10) / 10
+	}
+
+	return summary, nil
+}
+
+// ListAlerts returns low-score responses for admins to follow up on
+func (u *SurveyUsecase) ListAlerts(ctx context.Context, includeAcknowledged bool, limit int) ([]domain.OrderSurvey, error) {
+	return u.repo.ListLowScores(ctx, u.alertThreshold(ctx), includeAcknowledged, limit)
+}
+
+// SetSettings lets admins change the alert threshold at runtime
+func (u *SurveyUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// SetAlerts notifies admins of low scores, batched per the digest rules
+func (u *SurveyUsecase) SetAlerts(alerts *AdminAlertUsecase) {
+	u.alerts = alerts
+}
+
+// alertThreshold returns the score at or below which admins are alerted
+func (u *SurveyUsecase) alertThreshold(ctx context.Context) int {
+	if u.settings != nil {
+		return u.settings.Int(ctx, SettingSurveyAlertThreshold)
+	}
+	return u.cfg.AlertThreshold
+}
+
+// AcknowledgeAlert marks a low-score alert handled
+func (u *SurveyUsecase) AcknowledgeAlert(ctx context.Context, surveyID, adminID uuid.UUID) error {
+	return u.repo.AcknowledgeAlert(ctx, surveyID, adminID)
+}
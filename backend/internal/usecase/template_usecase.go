@@ -0,0 +1,627 @@
+// Package usecase implements message templates with admin overrides
+package usecase
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/email"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/push"
+	"fooddelivery/pkg/redis"
+)
+
+//go:embed templates/defaults.json
+var defaultTemplatesJSON []byte
+
+// DefaultLocale is used when no variant exists for the requested locale or
+// the restaurant locale
+const DefaultLocale = "en"
+
+// Template keys sent by other usecases
+const (
+	TemplateOrderSurvey         = "order_survey"
+	TemplateLoginOTP            = "login_otp"
+	TemplatePaymentReceipt      = "payment_receipt"
+	TemplateOrderAccepted       = "order_accepted"
+	TemplateOrderOutForDelivery = "order_out_for_delivery"
+	TemplateGiftOutForDelivery  = "gift_out_for_delivery"
+	TemplateOrderDelivered      = "order_delivered"
+	TemplateGiftDelivered       = "gift_delivered"
+	TemplateItemsUnavailable    = "order_items_unavailable"
+	TemplateOrderCancelled      = "order_cancelled"
+	TemplateOrderChatMessage    = "order_chat_message"
+	TemplateOrderConfirmation   = "order_confirmation"
+	TemplateOrderReceipt        = "order_receipt"
+)
+
+var (
+	// ErrUnknownTemplate is returned for a key no definition exists for
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrInvalidTemplate is returned when a template fails to parse or render
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrInvalidLocale is returned for a locale that is not like "en" or "hi-IN"
+	ErrInvalidLocale = errors.New("invalid locale")
+	// ErrTemplateNotOverridden is returned when resetting a template already at its default
+	ErrTemplateNotOverridden = errors.New("template is not overridden")
+	// ErrNoTemplateSender is returned when test-sending on a channel with no sender
+	ErrNoTemplateSender = errors.New("no sender configured for this channel")
+)
+
+var localePattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{2}))?$`)
+
+// TemplateText is the editable part of a template
+type TemplateText struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// TemplateDefinition declares a template: its channel, the variables it may
+// use (with sample values for previews and validation) and the embedded
+// default text per locale
+type TemplateDefinition struct {
+	Key         string                  `json:"key"`
+	Channel     domain.TemplateChannel  `json:"channel"`
+	Description string                  `json:"description"`
+	Variables   map[string]any          `json:"variables"`
+	Locales     map[string]TemplateText `json:"locales"`
+}
+
+// TemplateInfo describes a template and its effective text in every locale
+// that has a default or an override
+type TemplateInfo struct {
+	Key         string                   `json:"key"`
+	Channel     domain.TemplateChannel   `json:"channel"`
+	Description string                   `json:"description"`
+	Variables   []string                 `json:"variables"`
+	Templates   []domain.MessageTemplate `json:"templates"`
+}
+
+// RenderedMessage is a template filled in with variables
+type RenderedMessage struct {
+	Key     string                 `json:"key"`
+	Channel domain.TemplateChannel `json:"channel"`
+	Locale  string                 `json:"locale"`
+	Subject string                 `json:"subject,omitempty"`
+	Body    string                 `json:"body"`
+}
+
+// TemplateUsecase renders SMS, push, email and invoice text from templates:
+// admin overrides stored in Postgres and cached in Redis, falling back to
+// the defaults embedded in the binary. Rendering never depends on the
+// database being up; if overrides can't be loaded the defaults are used.
+//
+// Messages are rendered in the recipient's locale when a template exists
+// for it, else in the restaurant locale, else in DefaultLocale.
+type TemplateUsecase struct {
+	repo           *repository.TemplateRepository
+	redisClient    *redis.Client
+	defs           map[string]TemplateDefinition
+	order          []string
+	fallbackLocale string
+	users          *UserCache
+	pushSender     push.Sender
+	emailSender    email.Sender
+	audit          *AuditUsecase
+	log            *logger.Logger
+}
+
+// NewTemplateUsecase creates a template usecase from the embedded defaults.
+// Returns an error if a default does not render with its sample variables.
+func NewTemplateUsecase(repo *repository.TemplateRepository, redisClient *redis.Client, log *logger.Logger) (*TemplateUsecase, error) {
+	var defs []TemplateDefinition
+	if err := json.Unmarshal(defaultTemplatesJSON, &defs); err != nil {
+		return nil, fmt.Errorf("failed to parse default templates: %w", err)
+	}
+
+	u := &TemplateUsecase{
+		repo:           repo,
+		redisClient:    redisClient,
+		defs:           make(map[string]TemplateDefinition, len(defs)),
+		fallbackLocale: DefaultLocale,
+		log:            log,
+	}
+	for _, def := range defs {
+		if _, ok := def.Locales[DefaultLocale]; !ok {
+			return nil, fmt.Errorf("template %s has no %s default", def.Key, DefaultLocale)
+		}
+		for locale, text := range def.Locales {
+			if _, err := renderText(def, text, def.Variables); err != nil {
+				return nil, fmt.Errorf("default template %s/%s: %w", def.Key, locale, err)
+			}
+		}
+		u.defs[def.Key] = def
+		u.order = append(u.order, def.Key)
+	}
+
+	return u, nil
+}
+
+// SetFallbackLocale sets the restaurant locale, used for recipients
+// without a locale of their own or without templates in it
+func (u *TemplateUsecase) SetFallbackLocale(locale string) error {
+	normalized, err := normalizeLocale(locale)
+	if err != nil {
+		return err
+	}
+	u.fallbackLocale = normalized
+	return nil
+}
+
+// SetUserCache lets RenderForUser look up users' locales
+func (u *TemplateUsecase) SetUserCache(users *UserCache) {
+	u.users = users
+}
+
+// SetPushSender enables test sends of push templates
+func (u *TemplateUsecase) SetPushSender(sender push.Sender) {
+	u.pushSender = sender
+}
+
+// SetEmailSender lets email templates be test-sent to the admin's address
+func (u *TemplateUsecase) SetEmailSender(sender email.Sender) {
+	u.emailSender = sender
+}
+
+// SetAuditLog records template edits in the admin audit log
+func (u *TemplateUsecase) SetAuditLog(audit *AuditUsecase) {
+	u.audit = audit
+}
+
+// List returns every template with its effective text per locale
+func (u *TemplateUsecase) List(ctx context.Context) ([]TemplateInfo, error) {
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]TemplateInfo, 0, len(u.order))
+	for _, key := range u.order {
+		def := u.defs[key]
+
+		locales := make(map[string]struct{}, len(def.Locales))
+		for locale := range def.Locales {
+			locales[locale] = struct{}{}
+		}
+		for _, o := range overrides {
+			if o.Key == key {
+				locales[o.Locale] = struct{}{}
+			}
+		}
+		sorted := make([]string, 0, len(locales))
+		for locale := range locales {
+			sorted = append(sorted, locale)
+		}
+		sort.Strings(sorted)
+
+		info := TemplateInfo{
+			Key:         key,
+			Channel:     def.Channel,
+			Description: def.Description,
+			Variables:   variableNames(def),
+			Templates:   make([]domain.MessageTemplate, 0, len(sorted)),
+		}
+		for _, locale := range sorted {
+			if t, ok := u.lookup(def, overrides, locale); ok {
+				info.Templates = append(info.Templates, *t)
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Get returns the template used for key in locale, after locale fallback
+func (u *TemplateUsecase) Get(ctx context.Context, key, locale string) (*domain.MessageTemplate, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return u.resolve(def, overrides, locale), nil
+}
+
+// Update validates and stores an override, effective immediately. The
+// subject is dropped for SMS, which has none.
+func (u *TemplateUsecase) Update(ctx context.Context, key, locale string, text TemplateText, adminID uuid.UUID) (*domain.MessageTemplate, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err = validateTemplate(def, text)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &domain.MessageTemplate{
+		Key:     key,
+		Channel: def.Channel,
+		Locale:  locale,
+		Subject: text.Subject,
+		Body:    text.Body,
+	}
+	if err := u.repo.Set(ctx, t, adminID); err != nil {
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.log.Info("Template updated", "key", key, "locale", locale, "admin_id", adminID.String())
+	u.audit.Record(ctx, adminID, domain.AuditTemplateUpdated, "template", key+"/"+locale, nil)
+
+	return t, nil
+}
+
+// Reset removes an override so the embedded default (or the fallback
+// locale) applies again
+func (u *TemplateUsecase) Reset(ctx context.Context, key, locale string, adminID uuid.UUID) (*domain.MessageTemplate, error) {
+	if _, ok := u.defs[key]; !ok {
+		return nil, ErrUnknownTemplate
+	}
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Delete(ctx, key, locale); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTemplateNotOverridden
+		}
+		return nil, err
+	}
+	u.invalidate(ctx)
+
+	u.log.Info("Template reset to default", "key", key, "locale", locale, "admin_id", adminID.String())
+	u.audit.Record(ctx, adminID, domain.AuditTemplateReset, "template", key+"/"+locale, nil)
+
+	return u.Get(ctx, key, locale)
+}
+
+// Render fills in the template for key in the best matching locale; an
+// empty or invalid locale gets the restaurant locale. Falls back to the
+// embedded defaults if overrides can't be loaded.
+func (u *TemplateUsecase) Render(ctx context.Context, key, locale string, vars map[string]any) (*RenderedMessage, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	if normalized, err := normalizeLocale(locale); err == nil && locale != "" {
+		locale = normalized
+	} else {
+		locale = u.fallbackLocale
+	}
+
+	overrides, err := u.overrides(ctx)
+	if err != nil {
+		u.log.Warn("Failed to load templates, using defaults", "key", key, "error", err)
+		overrides = nil
+	}
+
+	t := u.resolve(def, overrides, locale)
+	msg, err := renderText(def, TemplateText{Subject: t.Subject, Body: t.Body}, vars)
+	if err != nil && !t.IsDefault {
+		// A bad override must not stop the message; the default still works
+		u.log.Error("Template override failed to render, using default", "key", key, "locale", t.Locale, "error", err)
+		t = u.resolve(def, nil, locale)
+		msg, err = renderText(def, TemplateText{Subject: t.Subject, Body: t.Body}, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Locale = t.Locale
+	return msg, nil
+}
+
+// RenderForUser renders the template for key in the user's preferred
+// locale. Users that can't be loaded get the restaurant locale.
+func (u *TemplateUsecase) RenderForUser(ctx context.Context, key string, userID uuid.UUID, vars map[string]any) (*RenderedMessage, error) {
+	locale := ""
+	if u.users != nil {
+		user, err := u.users.Get(ctx, userID)
+		if err != nil {
+			u.log.Warn("Failed to load user locale, using restaurant locale", "user_id", userID.String(), "error", err)
+		} else {
+			locale = user.PreferredLocale()
+		}
+	}
+	return u.Render(ctx, key, locale, vars)
+}
+
+// Preview renders a stored template, or draft if given, with the sample
+// variables overlaid by vars
+func (u *TemplateUsecase) Preview(ctx context.Context, key, locale string, draft *TemplateText, vars map[string]any) (*RenderedMessage, error) {
+	def, ok := u.defs[key]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	locale, err := normalizeLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	text := draft
+	if text == nil {
+		t, err := u.Get(ctx, key, locale)
+		if err != nil {
+			return nil, err
+		}
+		locale = t.Locale
+		text = &TemplateText{Subject: t.Subject, Body: t.Body}
+	}
+
+	msg, err := renderText(def, *text, sampleVariables(def, vars))
+	if err != nil {
+		return nil, err
+	}
+	msg.Locale = locale
+	return msg, nil
+}
+
+// TestSend renders a template like Preview and sends it to the admin's own
+// devices, or their email address for email templates. Only channels with
+// a configured sender can be test-sent.
+func (u *TemplateUsecase) TestSend(ctx context.Context, key, locale string, draft *TemplateText, vars map[string]any, adminID uuid.UUID) (*RenderedMessage, error) {
+	msg, err := u.Preview(ctx, key, locale, draft, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Channel {
+	case domain.TemplateChannelPush:
+		if u.pushSender == nil {
+			return nil, ErrNoTemplateSender
+		}
+		err = u.pushSender.Send(ctx, adminID, push.Message{
+			Title: msg.Subject,
+			Body:  msg.Body,
+			Data:  map[string]string{"type": "template_test", "template": key},
+		})
+	case domain.TemplateChannelEmail:
+		if u.emailSender == nil || u.users == nil {
+			return nil, ErrNoTemplateSender
+		}
+		admin, err := u.users.Get(ctx, adminID)
+		if err != nil {
+			return nil, err
+		}
+		if admin.Email == "" {
+			return nil, ErrNoTemplateSender
+		}
+		err = u.emailSender.Send(ctx, email.Message{To: admin.Email, Subject: msg.Subject, HTML: msg.Body})
+	default:
+		return nil, ErrNoTemplateSender
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send test message: %w", err)
+	}
+
+	u.log.Info("Template test sent", "key", key, "locale", msg.Locale, "admin_id", adminID.String())
+	return msg, nil
+}
+
+// resolve picks the template for locale: an override or default for the
+// exact locale, then for its language, then the same for the restaurant
+// locale, then for DefaultLocale
+func (u *TemplateUsecase) resolve(def TemplateDefinition, overrides []domain.MessageTemplate, locale string) *domain.MessageTemplate {
+	var candidates []string
+	for _, l := range []string{locale, u.fallbackLocale} {
+		candidates = append(candidates, l)
+		if lang, _, found := strings.Cut(l, "-"); found {
+			candidates = append(candidates, lang)
+		}
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, candidate := range candidates {
+		if t, ok := u.lookup(def, overrides, candidate); ok {
+			return t
+		}
+	}
+
+	// Unreachable: every definition has a DefaultLocale default
+	text := def.Locales[DefaultLocale]
+	return &domain.MessageTemplate{Key: def.Key, Channel: def.Channel, Locale: DefaultLocale, Subject: text.Subject, Body: text.Body, IsDefault: true}
+}
+
+// lookup returns the override or default for exactly locale
+func (u *TemplateUsecase) lookup(def TemplateDefinition, overrides []domain.MessageTemplate, locale string) (*domain.MessageTemplate, bool) {
+	for _, o := range overrides {
+		if o.Key == def.Key && o.Locale == locale {
+			o.Channel = def.Channel
+			return &o, true
+		}
+	}
+	if text, ok := def.Locales[locale]; ok {
+		return &domain.MessageTemplate{Key: def.Key, Channel: def.Channel, Locale: locale, Subject: text.Subject, Body: text.Body, IsDefault: true}, true
+	}
+	return nil, false
+}
+
+// overrides returns the stored overrides, cached in Redis
+func (u *TemplateUsecase) overrides(ctx context.Context) ([]domain.MessageTemplate, error) {
+	if u.redisClient != nil {
+		var cached []domain.MessageTemplate
+		found, err := u.redisClient.GetJSON(ctx, redis.TemplatesCacheKey, &cached)
+		if err != nil {
+			u.log.Warn("Failed to read templates cache", "error", err)
+		} else if found {
+			return cached, nil
+		}
+	}
+
+	overrides, err := u.repo.GetOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.redisClient != nil {
+		if err := u.redisClient.SetJSON(ctx, redis.TemplatesCacheKey, overrides, redis.TemplatesCacheTTL); err != nil {
+			u.log.Warn("Failed to cache templates", "error", err)
+		}
+	}
+
+	return overrides, nil
+}
+
+func (u *TemplateUsecase) invalidate(ctx context.Context) {
+	if u.redisClient == nil {
+		return
+	}
+	if err := u.redisClient.DeleteKey(ctx, redis.TemplatesCacheKey); err != nil {
+		u.log.Warn("Failed to invalidate templates cache", "error", err)
+	}
+}
+
+// validateTemplate checks that text renders with the definition's sample
+// variables, so a typo in a variable name is caught when saving
+func validateTemplate(def TemplateDefinition, text TemplateText) (TemplateText, error) {
+	text.Subject = strings.TrimSpace(text.Subject)
+	text.Body = strings.TrimSpace(text.Body)
+	if def.Channel == domain.TemplateChannelSMS {
+		text.Subject = ""
+	}
+
+	if text.Body == "" {
+		return text, fmt.Errorf("%w: body is required", ErrInvalidTemplate)
+	}
+	if text.Subject == "" && (def.Channel == domain.TemplateChannelPush || def.Channel == domain.TemplateChannelEmail) {
+		return text, fmt.Errorf("%w: subject is required for %s", ErrInvalidTemplate, def.Channel)
+	}
+
+	if _, err := renderText(def, text, def.Variables); err != nil {
+		return text, err
+	}
+	return text, nil
+}
+
+// renderText executes subject and body with vars. A variable the template
+// uses but vars lacks is an error rather than "<no value>". Email bodies
+// are HTML, so their variables are escaped.
+func renderText(def TemplateDefinition, text TemplateText, vars map[string]any) (*RenderedMessage, error) {
+	execute := func(name, src string) (string, error) {
+		if src == "" {
+			return "", nil
+		}
+		tmpl, err := parseTemplate(def.Key+"."+name, src, def.Channel == domain.TemplateChannelEmail && name == "body")
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		return buf.String(), nil
+	}
+
+	subject, err := execute("subject", text.Subject)
+	if err != nil {
+		return nil, err
+	}
+	body, err := execute("body", text.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderedMessage{Key: def.Key, Channel: def.Channel, Subject: subject, Body: body}, nil
+}
+
+// parseTemplate parses src as text, or as HTML with contextual escaping
+func parseTemplate(name, src string, html bool) (interface {
+	Execute(w io.Writer, data any) error
+}, error) {
+	if html {
+		return htmltemplate.New(name).Option("missingkey=error").Parse(src)
+	}
+	return template.New(name).Option("missingkey=error").Parse(src)
+}
+
+// sampleVariables overlays vars on the definition's sample values
+func sampleVariables(def TemplateDefinition, vars map[string]any) map[string]any {
+	merged := make(map[string]any, len(def.Variables)+len(vars))
+	for k, v := range def.Variables {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return merged
+}
+
+func variableNames(def TemplateDefinition) []string {
+	names := make([]string, 0, len(def.Variables))
+	for name := range def.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// localeFromAcceptLanguage returns the preferred valid locale of an
+// Accept-Language header such as "hi-IN,hi;q=0.9,en;q=0.8", or empty if it
+// names none
+func localeFromAcceptLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		locale, err := normalizeLocale(strings.TrimSpace(tag))
+		if err != nil || tag == "" || q <= bestQ {
+			continue
+		}
+		best, bestQ = locale, q
+	}
+	return best
+}
+
+// normalizeLocale canonicalizes "hi_in" or "HI-in" to "hi-IN"; empty means
+// DefaultLocale
+func normalizeLocale(raw string) (string, error) {
+	if raw == "" {
+		return DefaultLocale, nil
+	}
+	m := localePattern.FindStringSubmatch(raw)
+	if m == nil {
+		return "", ErrInvalidLocale
+	}
+	locale := strings.ToLower(m[1])
+	if m[2] != "" {
+		locale += "-" + strings.ToUpper(m[2])
+	}
+	return locale, nil
+}
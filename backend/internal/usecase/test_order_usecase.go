@@ -0,0 +1,80 @@
+// Package usecase implements detection of test orders placed by staff
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+)
+
+// SettingTestOrderStaffPhones lists the phone numbers whose orders are tests
+const SettingTestOrderStaffPhones = "test_orders.staff_phones"
+
+// Test order detection rules, named in the order's log line
+const (
+	testOrderRuleStaffPhone     = "staff_phone"
+	testOrderRuleSandboxGateway = "sandbox_gateway"
+)
+
+// sandboxKeyPrefix starts Razorpay key IDs in test mode; payments through
+// them never move money
+const sandboxKeyPrefix = "rzp_test_"
+
+// ParseStaffPhones splits a comma-separated phone list into the last ten
+// digits of each number, so +91 and local forms of a number match
+func ParseStaffPhones(list string) ([]string, error) {
+	var phones []string
+	for _, phone := range strings.Split(list, ",") {
+		phone = strings.TrimSpace(phone)
+		if phone == "" {
+			continue
+		}
+		if !userPhonePattern.MatchString(phone) {
+			return nil, fmt.Errorf("%q is not a phone number", phone)
+		}
+		phones = append(phones, phone[len(phone)-10:])
+	}
+	return phones, nil
+}
+
+// ValidateStaffPhones checks a value for SettingTestOrderStaffPhones
+func ValidateStaffPhones(list string) error {
+	_, err := ParseStaffPhones(list)
+	return err
+}
+
+// testOrderRule names the rule that makes a new order a test, or returns ""
+// for a customer order. Coupons would be a rule too once checkout has them.
+func (u *PaymentUsecase) testOrderRule(ctx context.Context, user *domain.User, keyID string) string {
+	if strings.HasPrefix(keyID, sandboxKeyPrefix) {
+		return testOrderRuleSandboxGateway
+	}
+	if user == nil || u.settings == nil {
+		return ""
+	}
+
+	phones, err := ParseStaffPhones(u.settings.String(ctx, SettingTestOrderStaffPhones))
+	if err != nil || len(user.PhoneNumber) < 10 {
+		return ""
+	}
+	for _, phone := range phones {
+		if user.PhoneNumber[len(user.PhoneNumber)-10:] == phone {
+			return testOrderRuleStaffPhone
+		}
+	}
+	return ""
+}
+
+// SetTestOrder reclassifies an order as a test or a customer order on an
+// admin's behalf, overriding detection. Returns the order's tags.
+func (u *OrderUsecase) SetTestOrder(ctx context.Context, adminID, orderID uuid.UUID, test bool) ([]string, error) {
+	update := OrderTagsUpdate{Remove: []string{domain.OrderTagTest}}
+	if test {
+		update = OrderTagsUpdate{Add: []string{domain.OrderTagTest}}
+	}
+	return u.UpdateOrderTags(ctx, adminID, orderID, update)
+}
@@ -0,0 +1,348 @@
+// Package usecase implements TOTP two-factor authentication for admin accounts
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/totp"
+)
+
+// Two-factor errors
+var (
+	ErrMFANotAllowed     = errors.New("two-factor enrollment is only available for admin accounts")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrTooManyAttempts   = errors.New("too many attempts, try again later")
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued per enrollment
+	recoveryCodeCount = 10
+
+	// maxMFAAttempts is the number of failed codes allowed per MFAAttemptsTTL window
+	maxMFAAttempts = 5
+
+	// totpSkew allows one 30s step of clock drift on the authenticator device
+	totpSkew = 1
+)
+
+// requiresMFA reports whether a user must complete TOTP before admin access
+func (u *UserUsecase) requiresMFA(user *domain.User) bool {
+	return u.adminMFARequired && user.IsAdmin
+}
+
+// MFARequired reports whether admin two-factor enforcement is enabled
+func (u *UserUsecase) MFARequired() bool {
+	return u.adminMFARequired
+}
+
+// TOTPStatusResponse describes a user's two-factor state
+type TOTPStatusResponse struct {
+	Required bool `json:"required"`
+	Enrolled bool `json:"enrolled"`
+}
+
+// GetTOTPStatus returns whether the user has an active authenticator
+func (u *UserUsecase) GetTOTPStatus(ctx context.Context, userID uuid.UUID) (*TOTPStatusResponse, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrolled := false
+	t, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+	if t != nil {
+		enrolled = t.IsEnabled
+	}
+
+	return &TOTPStatusResponse{
+		Required: u.requiresMFA(user),
+		Enrolled: enrolled,
+	}, nil
+}
+
+// TOTPEnrollmentResponse contains the provisioning data rendered as a QR code
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// BeginTOTPEnrollment generates a new secret pending confirmation.
+// The secret only becomes active once ConfirmTOTPEnrollment sees a valid code,
+// so a half-finished enrollment can never lock an admin out.
+func (u *UserUsecase) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*TOTPEnrollmentResponse, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsAdmin {
+		return nil, ErrMFANotAllowed
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.SavePendingTOTP(ctx, userID, secret); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	u.log.Info("TOTP enrollment started", "user_id", userID.String())
+
+	return &TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(u.totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// MFATokenResponse contains an upgraded (second-factor verified) token
+type MFATokenResponse struct {
+	SessionTokens
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // Only returned once, at enrollment
+}
+
+// ConfirmTOTPEnrollment activates the pending secret with a first valid code
+// and returns one-time recovery codes alongside an MFA-verified token
+func (u *UserUsecase) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) (*MFATokenResponse, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+	if t.IsEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := u.checkMFAAttempts(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		u.recordMFAFailure(ctx, userID)
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+
+	tokens, err := u.issueSession(ctx, user, true)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("TOTP enrollment completed", "user_id", userID.String())
+
+	return &MFATokenResponse{
+		SessionTokens: *tokens,
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// VerifyMFARequest contains either an authenticator code or a recovery code
+type VerifyMFARequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// VerifyMFA completes the second factor after login and returns an
+// MFA-verified token that unlocks admin routes
+func (u *UserUsecase) VerifyMFA(ctx context.Context, userID uuid.UUID, req VerifyMFARequest) (*MFATokenResponse, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+	if !t.IsEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := u.checkMFAAttempts(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if req.RecoveryCode != "" {
+		err = u.userRepo.ConsumeRecoveryCode(ctx, userID, hashRecoveryCode(req.RecoveryCode))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				u.recordMFAFailure(ctx, userID)
+				return nil, ErrInvalidMFACode
+			}
+			return nil, fmt.Errorf("failed to consume recovery code: %w", err)
+		}
+		u.log.Warn("Recovery code used for two-factor login", "user_id", userID.String())
+	} else {
+		step, ok := totp.Validate(t.Secret, req.Code, time.Now(), totpSkew)
+		if !ok {
+			u.recordMFAFailure(ctx, userID)
+			return nil, ErrInvalidMFACode
+		}
+
+		// Reject a code that was already used, even within its validity window
+		if err := u.userRepo.MarkTOTPStepUsed(ctx, userID, step); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return nil, ErrInvalidMFACode
+			}
+			return nil, fmt.Errorf("failed to record TOTP step: %w", err)
+		}
+	}
+
+	tokens, err := u.issueSession(ctx, user, true)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("Two-factor verification succeeded", "user_id", userID.String())
+
+	return &MFATokenResponse{SessionTokens: *tokens}, nil
+}
+
+// RegenerateRecoveryCodes invalidates all recovery codes and issues new ones
+func (u *UserUsecase) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	t, err := u.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("failed to get TOTP enrollment: %w", err)
+	}
+	if !t.IsEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	u.log.Info("Recovery codes regenerated", "user_id", userID.String())
+
+	return codes, nil
+}
+
+// getUserOrNotFound maps repository not-found to ErrUserNotFound
+func (u *UserUsecase) getUserOrNotFound(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	user, err := u.users.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return user, nil
+}
+
+// checkMFAAttempts blocks verification once the failure budget is spent.
+// A 6-digit code is brute-forceable without this.
+func (u *UserUsecase) checkMFAAttempts(ctx context.Context, userID uuid.UUID) error {
+	if u.redisClient == nil {
+		return nil
+	}
+
+	attempts, err := u.redisClient.Get(ctx, redis.MFAAttemptsPrefix+userID.String()).Int()
+	if err != nil {
+		// Missing key means no failures yet; Redis errors fail open
+		return nil
+	}
+
+	if attempts >= maxMFAAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// recordMFAFailure counts a failed verification attempt
+func (u *UserUsecase) recordMFAFailure(ctx context.Context, userID uuid.UUID) {
+	if u.redisClient == nil {
+		return
+	}
+
+	if _, err := u.redisClient.IncrWithTTL(ctx, redis.MFAAttemptsPrefix+userID.String(), redis.MFAAttemptsTTL); err != nil {
+		u.log.Warn("Failed to record MFA failure", "error", err)
+	}
+}
+
+// recoveryAlphabet avoids visually ambiguous characters (0/o, 1/l)
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCodes returns display codes (xxxxx-xxxxx) and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		var sb strings.Builder
+		for j, b := range buf {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+
+		codes[i] = sb.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalizes user input before hashing so that case and
+// dashes typed by the admin don't matter
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,16 @@
+package usecase
+
+import (
+	"context"
+
+	"fooddelivery/pkg/database"
+)
+
+// inTx runs fn as one unit of work. Without a UnitOfWork (e.g. in tools that
+// wire only some dependencies) fn runs without a transaction.
+func inTx(ctx context.Context, uow database.UnitOfWork, fn func(ctx context.Context) error) error {
+	if uow == nil {
+		return fn(ctx)
+	}
+	return uow.InTx(ctx, fn)
+}
@@ -0,0 +1,190 @@
+// Package usecase implements cached user lookups by ID
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/pii"
+	"fooddelivery/pkg/redis"
+)
+
+// Fields authenticated with the phone number and email in Redis copies, so
+// they can't be swapped with the database columns
+const (
+	fieldCachedUserPhone = "user_cache.phone_number"
+	fieldCachedUserEmail = "user_cache.email"
+)
+
+// UserCache serves user records by ID from the request context first, then
+// a short-lived Redis copy, then Postgres. Redis copies never carry
+// PasswordHash (it isn't serialized); password checks load by email and
+// don't go through here. Phone numbers and emails are encrypted in Redis
+// the same way they are in Postgres.
+type UserCache struct {
+	userRepo    UserReader
+	redisClient *redis.Client
+	pii         *pii.Protector
+	log         *logger.Logger
+}
+
+// NewUserCache creates a user cache. With a nil redisClient only the
+// request-scoped layer is used; the Redis layer also needs SetPIIProtector.
+func NewUserCache(userRepo UserReader, redisClient *redis.Client, log *logger.Logger) *UserCache {
+	return &UserCache{
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		log:         log,
+	}
+}
+
+// SetPIIProtector enables the Redis layer, which stores phone numbers and
+// emails encrypted with p. Without it users are never written to Redis.
+func (c *UserCache) SetPIIProtector(p *pii.Protector) {
+	c.pii = p
+}
+
+// cachedUser is a user as stored in Redis, with its phone number and email
+// replaced by ciphertext
+type cachedUser struct {
+	User        domain.User `json:"user"`
+	PhoneNumber string      `json:"phone_number_enc,omitempty"`
+	Email       string      `json:"email_enc,omitempty"`
+}
+
+type requestUsersKey struct{}
+
+// requestUsers holds the users loaded while serving one request
+type requestUsers struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]domain.User
+}
+
+// WithUserCache returns a context that remembers users looked up with it,
+// so middleware and handlers loading the same user share one read. Attach
+// it once per request.
+func WithUserCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestUsersKey{}, &requestUsers{users: make(map[uuid.UUID]domain.User)})
+}
+
+// Get returns the user with the given ID, or repository.ErrNotFound. Each
+// call returns its own copy, so callers may modify it.
+func (c *UserCache) Get(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	scoped, _ := ctx.Value(requestUsersKey{}).(*requestUsers)
+	if scoped != nil {
+		scoped.mu.Lock()
+		user, ok := scoped.users[id]
+		scoped.mu.Unlock()
+		if ok {
+			return &user, nil
+		}
+	}
+
+	user, err := c.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scoped != nil {
+		scoped.mu.Lock()
+		scoped.users[id] = *user
+		scoped.mu.Unlock()
+	}
+	return user, nil
+}
+
+// load reads through Redis to Postgres. Missing users aren't cached, so a
+// user created right after a miss is found.
+func (c *UserCache) load(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	key := redis.UserCachePrefix + id.String()
+	shared := c.redisClient != nil && c.pii != nil
+
+	if shared {
+		var cached cachedUser
+		found, err := c.redisClient.GetJSON(ctx, key, &cached)
+		if err != nil {
+			c.log.Warn("Failed to read user from cache", "error", err)
+		} else if found && cached.User.ID == id {
+			user, err := c.open(&cached)
+			if err == nil {
+				return user, nil
+			}
+			c.log.Warn("Failed to decrypt cached user", "user_id", id.String(), "error", err)
+		}
+	}
+
+	user, err := c.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if shared {
+		cached, err := c.seal(user)
+		if err == nil {
+			err = c.redisClient.SetJSON(ctx, key, cached, redis.UserCacheTTL)
+		}
+		if err != nil {
+			c.log.Warn("Failed to cache user", "error", err)
+		}
+	}
+	return user, nil
+}
+
+// seal prepares a user for Redis, encrypting its phone number and email
+func (c *UserCache) seal(user *domain.User) (*cachedUser, error) {
+	cached := &cachedUser{User: *user}
+	cached.User.PhoneNumber, cached.User.Email = "", ""
+
+	var err error
+	if user.PhoneNumber != "" {
+		if cached.PhoneNumber, err = c.pii.Encrypt(user.PhoneNumber, fieldCachedUserPhone); err != nil {
+			return nil, fmt.Errorf("failed to encrypt phone number: %w", err)
+		}
+	}
+	if user.Email != "" {
+		if cached.Email, err = c.pii.Encrypt(user.Email, fieldCachedUserEmail); err != nil {
+			return nil, fmt.Errorf("failed to encrypt email: %w", err)
+		}
+	}
+	return cached, nil
+}
+
+// open restores a user read from Redis
+func (c *UserCache) open(cached *cachedUser) (*domain.User, error) {
+	user := cached.User
+
+	var err error
+	if cached.PhoneNumber != "" {
+		if user.PhoneNumber, err = c.pii.Decrypt(cached.PhoneNumber, fieldCachedUserPhone); err != nil {
+			return nil, fmt.Errorf("failed to decrypt phone number: %w", err)
+		}
+	}
+	if cached.Email != "" {
+		if user.Email, err = c.pii.Decrypt(cached.Email, fieldCachedUserEmail); err != nil {
+			return nil, fmt.Errorf("failed to decrypt email: %w", err)
+		}
+	}
+	return &user, nil
+}
+
+// Invalidate drops a user from both layers after it changes. Other requests
+// already holding the user keep their copy until they finish.
+func (c *UserCache) Invalidate(ctx context.Context, id uuid.UUID) {
+	if scoped, _ := ctx.Value(requestUsersKey{}).(*requestUsers); scoped != nil {
+		scoped.mu.Lock()
+		delete(scoped.users, id)
+		scoped.mu.Unlock()
+	}
+
+	if c.redisClient == nil {
+		return
+	}
+	if err := c.redisClient.DeleteKey(context.WithoutCancel(ctx), redis.UserCachePrefix+id.String()); err != nil {
+		c.log.Warn("Failed to invalidate user cache", "user_id", id.String(), "error", err)
+	}
+}
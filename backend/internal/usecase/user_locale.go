@@ -0,0 +1,71 @@
+// Package usecase implements users' message language
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/repository"
+)
+
+// LocaleSettings is the language a user's messages are sent in
+type LocaleSettings struct {
+	Locale         string `json:"locale"`                    // Chosen by the user; empty to follow the app
+	DetectedLocale string `json:"detected_locale,omitempty"` // From the app's language at the last login
+}
+
+// GetLocale returns the user's message language settings
+func (u *UserUsecase) GetLocale(ctx context.Context, userID uuid.UUID) (*LocaleSettings, error) {
+	user, err := u.users.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &LocaleSettings{Locale: user.Locale, DetectedLocale: user.DetectedLocale}, nil
+}
+
+// SetLocale overrides the language the user's messages are sent in. An
+// empty locale removes the override, so the detected one applies again.
+func (u *UserUsecase) SetLocale(ctx context.Context, userID uuid.UUID, locale string) (*LocaleSettings, error) {
+	if locale != "" {
+		normalized, err := normalizeLocale(locale)
+		if err != nil {
+			return nil, err
+		}
+		locale = normalized
+	}
+
+	if err := u.userRepo.SetLocale(ctx, userID, locale); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	u.users.Invalidate(ctx, userID)
+
+	u.log.Info("User locale set", "user_id", userID.String(), "locale", locale)
+	return u.GetLocale(ctx, userID)
+}
+
+// DetectLocale remembers the language of the user's app from the
+// Accept-Language header of a login. It is best effort; failures are
+// logged, not returned.
+func (u *UserUsecase) DetectLocale(ctx context.Context, userID uuid.UUID, acceptLanguage string) {
+	locale := localeFromAcceptLanguage(acceptLanguage)
+	if locale == "" {
+		return
+	}
+
+	changed, err := u.userRepo.SetDetectedLocale(ctx, userID, locale)
+	if err != nil {
+		u.log.Warn("Failed to store detected locale", "user_id", userID.String(), "error", err)
+		return
+	}
+	if changed {
+		u.users.Invalidate(ctx, userID)
+	}
+}
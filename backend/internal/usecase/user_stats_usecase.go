@@ -0,0 +1,147 @@
+// Package usecase implements per-customer order stats, churn flags and
+// customer segments for admins
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+)
+
+// Churn defaults, used until SetSettings is called
+const (
+	defaultChurnMinOrders = 3  // Orders that make a customer a regular
+	defaultChurnQuietDays = 30 // Days without an order before a regular is at risk
+)
+
+// Segment page sizes
+const (
+	defaultSegmentLimit = 50
+	maxSegmentLimit     = 200
+)
+
+// SetSettings lets admins change the churn thresholds at runtime
+func (u *UserUsecase) SetSettings(settings *SettingsUsecase) {
+	u.settings = settings
+}
+
+// UserSegment selects customers by their order stats. Zero values don't
+// filter; ChurnRisk adds the churn thresholds on top of the rest.
+type UserSegment struct {
+	ChurnRisk        bool
+	MinOrders        int
+	MinSpend         int64 // Paisa
+	InactiveDays     int   // No order for at least this many days
+	ActiveWithinDays int   // Ordered within this many days
+	Limit            int
+	Offset           int
+}
+
+// churnThresholds returns how many orders make a regular and how many quiet
+// days put one at risk
+func (u *UserUsecase) churnThresholds(ctx context.Context) (minOrders, quietDays int) {
+	if u.settings == nil {
+		return defaultChurnMinOrders, defaultChurnQuietDays
+	}
+	return u.settings.Int(ctx, SettingChurnMinOrders), u.settings.Int(ctx, SettingChurnQuietDays)
+}
+
+// deriveStats fills in the days since the last order and the churn flag
+func deriveStats(stats *domain.UserOrderStats, now time.Time, minOrders, quietDays int) {
+	if stats.LastOrderAt == nil {
+		return
+	}
+	days := int(now.Sub(*stats.LastOrderAt) / (24 * time.Hour))
+	stats.DaysSinceLastOrder = &days
+	stats.ChurnRisk = stats.OrderCount >= minOrders && days >= quietDays
+}
+
+// OrderStats returns a customer's order stats with the churn flag; a
+// customer who never paid for an order has zero stats
+func (u *UserUsecase) OrderStats(ctx context.Context, userID uuid.UUID) (*domain.UserOrderStats, error) {
+	stats, err := u.userRepo.GetOrderStats(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		stats = &domain.UserOrderStats{UserID: userID}
+	}
+
+	minOrders, quietDays := u.churnThresholds(ctx)
+	deriveStats(stats, time.Now(), minOrders, quietDays)
+	return stats, nil
+}
+
+// GetUserDetail returns a customer with their order stats
+func (u *UserUsecase) GetUserDetail(ctx context.Context, userID uuid.UUID) (*domain.UserWithStats, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	stats, err := u.OrderStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserWithStats{User: *user, Stats: *stats}, nil
+}
+
+// ListUserSegment returns the customers in a segment with their stats, most
+// recently ordered first
+func (u *UserUsecase) ListUserSegment(ctx context.Context, seg UserSegment) ([]domain.UserWithStats, error) {
+	if seg.Limit <= 0 || seg.Limit > maxSegmentLimit {
+		seg.Limit = defaultSegmentLimit
+	}
+	if seg.Offset < 0 {
+		seg.Offset = 0
+	}
+
+	now := time.Now()
+	minOrders, quietDays := u.churnThresholds(ctx)
+
+	filter := repository.UserSegmentFilter{
+		MinOrders: seg.MinOrders,
+		MinSpend:  seg.MinSpend,
+		Limit:     seg.Limit,
+		Offset:    seg.Offset,
+	}
+	inactiveDays := seg.InactiveDays
+	if seg.ChurnRisk {
+		filter.MinOrders = max(filter.MinOrders, minOrders)
+		inactiveDays = max(inactiveDays, quietDays)
+	}
+	if inactiveDays > 0 {
+		before := now.AddDate(0, 0, -inactiveDays)
+		filter.LastOrderBefore = &before
+	}
+	if seg.ActiveWithinDays > 0 {
+		after := now.AddDate(0, 0, -seg.ActiveWithinDays)
+		filter.LastOrderAfter = &after
+	}
+
+	stats, err := u.userRepo.ListOrderStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]domain.UserWithStats, 0, len(stats))
+	for i := range stats {
+		user, err := u.users.Get(ctx, stats[i].UserID)
+		if err != nil {
+			return nil, err
+		}
+		deriveStats(&stats[i], now, minOrders, quietDays)
+		members = append(members, domain.UserWithStats{User: *user, Stats: stats[i]})
+	}
+
+	return members, nil
+}
@@ -4,9 +4,12 @@ package usecase
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,42 +18,119 @@ import (
 
 	"fooddelivery/internal/domain"
 	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/googleauth"
 	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/redis"
+	"fooddelivery/pkg/sms"
 )
 
 // User-related errors
 var (
-	ErrUserExists       = errors.New("user with this email or phone already exists")
-	ErrUserNotFound     = errors.New("user not found")
-	ErrInvalidOTP       = errors.New("invalid or expired OTP")
-	ErrUnauthorized     = errors.New("unauthorized")
-	ErrInvalidPassword  = errors.New("invalid password")
-	ErrWeakPassword     = errors.New("password must be at least 8 characters")
-	ErrInvalidEmail     = errors.New("invalid email address")
+	ErrUserExists          = errors.New("user with this email or phone already exists")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidOTP          = errors.New("invalid or expired OTP")
+	ErrOTPRateLimited      = errors.New("too many OTP requests for this number, try again later")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrInvalidPassword     = errors.New("invalid password")
+	ErrWeakPassword        = errors.New("password must be at least 8 characters")
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrPhoneRequired       = errors.New("phone number is required to create an account")
+	ErrEmailUnverified     = errors.New("email address is not verified by the identity provider")
+	ErrSocialLoginDisabled = errors.New("social login is not configured")
+	ErrInvalidIDToken      = errors.New("invalid identity token")
 )
 
 // UserUsecase handles user-related business logic
 type UserUsecase struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
-	jwtExpiry time.Duration
-	log       *logger.Logger
+	userRepo         UserStore
+	users            *UserCache
+	redisClient      *redis.Client
+	jwtMu            sync.RWMutex
+	jwtSecret        string
+	jwtPrevSecret    string    // Still accepted after a rotation...
+	jwtPrevUntil     time.Time // ...until tokens signed with it have expired
+	jwtExpiry        time.Duration
+	refreshExpiry    time.Duration
+	googleVerifier   *googleauth.Verifier
+	adminMFARequired bool
+	totpIssuer       string
+
+	reactivationWindow time.Duration
+	audit              *AuditUsecase
+	carts              *CartUsecase
+	sms                sms.Sender
+	templates          *TemplateUsecase
+	settings           *SettingsUsecase
+	log                *logger.Logger
 }
 
 // NewUserUsecase creates a new user usecase
-func NewUserUsecase(userRepo *repository.UserRepository, log *logger.Logger) *UserUsecase {
+func NewUserUsecase(userRepo UserStore, log *logger.Logger) *UserUsecase {
 	return &UserUsecase{
-		userRepo:  userRepo,
-		jwtSecret: "", // Set via SetJWTConfig
-		jwtExpiry: 24 * time.Hour,
-		log:       log,
+		userRepo:         userRepo,
+		users:            NewUserCache(userRepo, nil, log),
+		jwtSecret:        "", // Set via SetJWTConfig
+		jwtExpiry:        15 * time.Minute,
+		refreshExpiry:    30 * 24 * time.Hour,
+		adminMFARequired: true, // Admin 2FA is on unless disabled via SetMFAConfig
+		totpIssuer:       "Crave Delivery",
+
+		reactivationWindow: defaultReactivationWindow,
+		log:                log,
+	}
+}
+
+// SetRedisClient sets the Redis client (used for auth rate limiting)
+func (u *UserUsecase) SetRedisClient(client *redis.Client) {
+	u.redisClient = client
+}
+
+// SetUserCache shares a Redis-backed user cache. Without it lookups are
+// cached only for the request.
+func (u *UserUsecase) SetUserCache(cache *UserCache) {
+	u.users = cache
+}
+
+// SetMFAConfig configures admin two-factor enforcement and the issuer
+// name shown in authenticator apps
+func (u *UserUsecase) SetMFAConfig(adminRequired bool, issuer string) {
+	u.adminMFARequired = adminRequired
+	if issuer != "" {
+		u.totpIssuer = issuer
 	}
 }
 
-// SetJWTConfig sets JWT configuration
-func (u *UserUsecase) SetJWTConfig(secret string, expiryHours int) {
+// SetJWTConfig sets the signing secret and the access and refresh token
+// lifetimes
+func (u *UserUsecase) SetJWTConfig(secret string, accessTTL, refreshTTL time.Duration) {
+	u.jwtSecret = secret
+	u.jwtExpiry = accessTTL
+	u.refreshExpiry = refreshTTL
+}
+
+// RotateJWTSecret signs new tokens with secret. Tokens signed with the
+// previous secret stay valid until they would have expired anyway.
+func (u *UserUsecase) RotateJWTSecret(secret string) {
+	u.jwtMu.Lock()
+	defer u.jwtMu.Unlock()
+	if secret == u.jwtSecret {
+		return
+	}
+	u.jwtPrevSecret = u.jwtSecret
+	u.jwtPrevUntil = time.Now().Add(u.jwtExpiry)
 	u.jwtSecret = secret
-	u.jwtExpiry = time.Duration(expiryHours) * time.Hour
+}
+
+// signingSecret returns the secret new tokens are signed with
+func (u *UserUsecase) signingSecret() []byte {
+	u.jwtMu.RLock()
+	defer u.jwtMu.RUnlock()
+	return []byte(u.jwtSecret)
+}
+
+// SetGoogleVerifier enables Google Sign-In (nil keeps it disabled)
+func (u *UserUsecase) SetGoogleVerifier(v *googleauth.Verifier) {
+	u.googleVerifier = v
 }
 
 // RegisterRequest contains registration data
@@ -63,8 +143,8 @@ type RegisterRequest struct {
 
 // RegisterResponse contains registration result
 type RegisterResponse struct {
+	SessionTokens
 	UserID      uuid.UUID `json:"user_id"`
-	Token       string    `json:"token"`
 	Name        string    `json:"name"`
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phone_number"`
@@ -121,39 +201,41 @@ func (u *UserUsecase) Register(ctx context.Context, req RegisterRequest) (*Regis
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Generate JWT token
-	expiresAt := time.Now().Add(u.jwtExpiry)
-	token, err := u.generateJWT(user, expiresAt)
+	tokens, err := u.issueSession(ctx, user, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	u.log.Info("User registered", "user_id", user.ID.String(), "email", req.Email)
 
 	return &RegisterResponse{
-		UserID:      user.ID,
-		Token:       token,
-		Name:        user.Name,
-		Email:       user.Email,
-		PhoneNumber: user.PhoneNumber,
-		Message:     "Registration successful",
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		PhoneNumber:   user.PhoneNumber,
+		Message:       "Registration successful",
 	}, nil
 }
 
 // EmailLoginRequest contains email/password login data
 type EmailLoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	GuestToken string `json:"guest_token,omitempty"` // Guest cart to carry into the account
 }
 
 // LoginResponse contains login result with JWT token
 type LoginResponse struct {
-	Token       string    `json:"token"`
+	SessionTokens
 	UserID      uuid.UUID `json:"user_id"`
 	Name        string    `json:"name"`
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phone_number"`
-	ExpiresAt   time.Time `json:"expires_at"`
+	MFARequired bool      `json:"mfa_required,omitempty"` // Admin must complete TOTP before admin routes unlock
+
+	// The user's saved cart, when a guest cart was carried into it
+	Cart *domain.Cart `json:"cart,omitempty"`
 }
 
 // EmailLogin performs email/password authentication
@@ -171,38 +253,24 @@ func (u *UserUsecase) EmailLogin(ctx context.Context, req EmailLoginRequest) (*L
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		return nil, ErrInvalidPassword
 	}
-
-	// Generate JWT token
-	expiresAt := time.Now().Add(u.jwtExpiry)
-	tokenID := uuid.New().String()
-	token, err := u.generateJWTWithID(user, expiresAt, tokenID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
-	}
-
-	// Create session record
-	session := &domain.Session{
-		UserID:         user.ID,
-		TokenID:        tokenID,
-		ExpiresAt:      expiresAt,
-		IsRevoked:      false,
-		LastActivityAt: time.Now(),
-		CreatedAt:      time.Now(),
+	if err := u.checkActive(ctx, user); err != nil {
+		return nil, err
 	}
 
-	if err := u.userRepo.CreateSession(ctx, session); err != nil {
-		u.log.Error("Failed to create session", "error", err)
-		// Don't fail login if session creation fails
+	tokens, err := u.issueSession(ctx, user, false)
+	if err != nil {
+		return nil, err
 	}
 
 	u.log.Info("User logged in via email", "user_id", user.ID.String())
 
 	return &LoginResponse{
-		Token:     token,
-		UserID:    user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		ExpiresAt: expiresAt,
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		MFARequired:   u.requiresMFA(user),
+		Cart:          u.carryGuestCart(ctx, user.ID, req.GuestToken),
 	}, nil
 }
 
@@ -210,16 +278,20 @@ func (u *UserUsecase) EmailLogin(ctx context.Context, req EmailLoginRequest) (*L
 type VerifyOTPRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	OTP         string `json:"otp"`
+	GuestToken  string `json:"guest_token,omitempty"` // Guest cart to carry into the account
 }
 
 // VerifyOTPResponse contains verification result with JWT token
 type VerifyOTPResponse struct {
-	Token       string    `json:"token"`
+	SessionTokens
 	UserID      uuid.UUID `json:"user_id"`
 	Name        string    `json:"name"`
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phone_number"`
-	ExpiresAt   time.Time `json:"expires_at"`
+	MFARequired bool      `json:"mfa_required,omitempty"`
+
+	// The user's saved cart, when a guest cart was carried into it
+	Cart *domain.Cart `json:"cart,omitempty"`
 }
 
 // VerifyOTP verifies OTP and returns JWT token
@@ -255,16 +327,203 @@ func (u *UserUsecase) VerifyOTP(ctx context.Context, req VerifyOTPRequest) (*Ver
 		}
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
+	if err := u.checkActive(ctx, user); err != nil {
+		return nil, err
+	}
 
 	// Generate JWT token with session tracking
+	tokens, err := u.issueSession(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("User logged in via OTP", "user_id", user.ID.String())
+
+	return &VerifyOTPResponse{
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		MFARequired:   u.requiresMFA(user),
+		Cart:          u.carryGuestCart(ctx, user.ID, req.GuestToken),
+	}, nil
+}
+
+// GoogleLoginRequest contains a Google ID token from the client SDK.
+// PhoneNumber is only needed the first time, when an account is created,
+// because deliveries require a reachable phone.
+type GoogleLoginRequest struct {
+	IDToken     string `json:"id_token"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Name        string `json:"name,omitempty"`
+	GuestToken  string `json:"guest_token,omitempty"` // Guest cart to carry into the account
+}
+
+// GoogleLogin authenticates with a Google ID token.
+// Resolution order:
+// 1. Identity already linked -> log in as the linked user
+// 2. Verified email matches an existing account -> link and log in
+// 3. No account -> create one (phone number required) and link
+func (u *UserUsecase) GoogleLogin(ctx context.Context, req GoogleLoginRequest) (*LoginResponse, error) {
+	if u.googleVerifier == nil {
+		return nil, ErrSocialLoginDisabled
+	}
+
+	claims, err := u.googleVerifier.Verify(ctx, req.IDToken)
+	if err != nil {
+		if errors.Is(err, googleauth.ErrNotConfigured) {
+			return nil, ErrSocialLoginDisabled
+		}
+		if errors.Is(err, googleauth.ErrInvalidToken) || errors.Is(err, googleauth.ErrInvalidAudience) {
+			return nil, ErrInvalidIDToken
+		}
+		return nil, fmt.Errorf("failed to verify google token: %w", err)
+	}
+
+	user, err := u.resolveGoogleUser(ctx, claims, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkActive(ctx, user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.issueSession(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	u.log.Info("User logged in via Google", "user_id", user.ID.String())
+
+	return &LoginResponse{
+		SessionTokens: *tokens,
+		UserID:        user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		PhoneNumber:   user.PhoneNumber,
+		MFARequired:   u.requiresMFA(user),
+		Cart:          u.carryGuestCart(ctx, user.ID, req.GuestToken),
+	}, nil
+}
+
+// resolveGoogleUser finds, links or creates the local user for a Google identity
+func (u *UserUsecase) resolveGoogleUser(ctx context.Context, claims *googleauth.Claims, req GoogleLoginRequest) (*domain.User, error) {
+	identity, err := u.userRepo.GetIdentity(ctx, domain.IdentityProviderGoogle, claims.Subject)
+	if err == nil {
+		if err := u.userRepo.TouchIdentity(ctx, identity.ID); err != nil {
+			u.log.Warn("Failed to update identity login time", "error", err)
+		}
+		return u.users.Get(ctx, identity.UserID)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+
+	// Linking by email is only safe when Google vouches for the address,
+	// otherwise anyone could claim an existing customer's email.
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, ErrEmailUnverified
+	}
+
+	user, err := u.userRepo.GetByEmail(ctx, claims.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check existing email: %w", err)
+	}
+
+	if user == nil {
+		user, err = u.createGoogleUser(ctx, claims, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	email := claims.Email
+	identity = &domain.UserIdentity{
+		UserID:          user.ID,
+		Provider:        domain.IdentityProviderGoogle,
+		ProviderSubject: claims.Subject,
+		Email:           &email,
+		CreatedAt:       now,
+		LastLoginAt:     now,
+	}
+
+	if err := u.userRepo.CreateIdentity(ctx, identity); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			// The account is already linked to a different Google account
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	u.log.Info("Google identity linked", "user_id", user.ID.String())
+
+	return user, nil
+}
+
+// createGoogleUser creates a passwordless account from Google profile data
+func (u *UserUsecase) createGoogleUser(ctx context.Context, claims *googleauth.Claims, req GoogleLoginRequest) (*domain.User, error) {
+	if req.PhoneNumber == "" {
+		return nil, ErrPhoneRequired
+	}
+
+	existingPhone, err := u.userRepo.GetByPhoneNumber(ctx, req.PhoneNumber)
+	if err == nil && existingPhone != nil {
+		return nil, ErrUserExists
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check existing phone: %w", err)
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = req.Name
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		PhoneNumber:   req.PhoneNumber,
+		Name:          name,
+		Email:         claims.Email,
+		EmailVerified: true,
+		IsAdmin:       false,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	u.log.Info("User registered via Google", "user_id", user.ID.String())
+
+	return user, nil
+}
+
+// JWTClaims contains JWT payload
+type JWTClaims struct {
+	UserID  uuid.UUID `json:"user_id"`
+	IsAdmin bool      `json:"is_admin"`
+	IsRider bool      `json:"is_rider,omitempty"`
+	TokenID string    `json:"jti,omitempty"`
+	MFA     bool      `json:"mfa,omitempty"` // Second factor verified for this session
+	jwt.RegisteredClaims
+}
+
+// issueSession generates a JWT with a token ID, records the session and
+// issues a refresh token for it. Session persistence failures are logged
+// but never block login.
+func (u *UserUsecase) issueSession(ctx context.Context, user *domain.User, mfaVerified bool) (*SessionTokens, error) {
 	expiresAt := time.Now().Add(u.jwtExpiry)
 	tokenID := uuid.New().String()
-	token, err := u.generateJWTWithID(user, expiresAt, tokenID)
+	token, err := u.generateJWTWithID(user, expiresAt, tokenID, mfaVerified)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	// Create session record
 	session := &domain.Session{
 		UserID:         user.ID,
 		TokenID:        tokenID,
@@ -278,47 +537,44 @@ func (u *UserUsecase) VerifyOTP(ctx context.Context, req VerifyOTPRequest) (*Ver
 		u.log.Error("Failed to create session", "error", err)
 	}
 
-	u.log.Info("User logged in via OTP", "user_id", user.ID.String())
-
-	return &VerifyOTPResponse{
-		Token:     token,
-		UserID:    user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		ExpiresAt: expiresAt,
-	}, nil
+	tokens := &SessionTokens{Token: token, ExpiresAt: expiresAt}
+	if err := u.issueRefreshToken(ctx, user.ID, mfaVerified, tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
 }
 
-// JWTClaims contains JWT payload
-type JWTClaims struct {
-	UserID  uuid.UUID `json:"user_id"`
-	IsAdmin bool      `json:"is_admin"`
-	TokenID string    `json:"jti,omitempty"`
-	jwt.RegisteredClaims
-}
+// SetRiderStatus grants or revokes the rider role (admin only).
+// Takes effect on the user's next login since roles travel in the JWT.
+func (u *UserUsecase) SetRiderStatus(ctx context.Context, userID uuid.UUID, isRider bool) (*domain.User, error) {
+	user, err := u.getUserOrNotFound(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
-// generateJWT creates a new JWT token
-func (u *UserUsecase) generateJWT(user *domain.User, expiresAt time.Time) (string, error) {
-	claims := JWTClaims{
-		UserID:  user.ID,
-		IsAdmin: user.IsAdmin,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   user.ID.String(),
-		},
+	if user.IsRider == isRider {
+		return user, nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(u.jwtSecret))
+	user.IsRider = isRider
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update rider status: %w", err)
+	}
+	u.users.Invalidate(ctx, userID)
+
+	u.log.Info("Rider status updated", "user_id", userID.String(), "is_rider", isRider)
+
+	return user, nil
 }
 
 // generateJWTWithID creates a new JWT token with token ID for session tracking
-func (u *UserUsecase) generateJWTWithID(user *domain.User, expiresAt time.Time, tokenID string) (string, error) {
+func (u *UserUsecase) generateJWTWithID(user *domain.User, expiresAt time.Time, tokenID string, mfaVerified bool) (string, error) {
 	claims := JWTClaims{
 		UserID:  user.ID,
 		IsAdmin: user.IsAdmin,
+		IsRider: user.IsRider,
 		TokenID: tokenID,
+		MFA:     mfaVerified,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -328,7 +584,7 @@ func (u *UserUsecase) generateJWTWithID(user *domain.User, expiresAt time.Time,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(u.jwtSecret))
+	return token.SignedString(u.signingSecret())
 }
 
 // generateOTP generates a 6-digit OTP
@@ -341,6 +597,30 @@ func generateOTP() (string, error) {
 	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
+// maxOTPRequests is the number of OTPs sent to one phone number per
+// OTPRequestsTTL window
+const maxOTPRequests = 5
+
+// allowOTPRequest counts an OTP about to be sent to a phone number and
+// returns ErrOTPRateLimited once the window's budget is spent. Numbers are
+// hashed so they aren't stored in Redis; Redis errors fail open.
+func (u *UserUsecase) allowOTPRequest(ctx context.Context, phone string) error {
+	if u.redisClient == nil {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(phone))
+	count, err := u.redisClient.IncrWithTTL(ctx, redis.OTPRequestsPrefix+hex.EncodeToString(sum[:]), redis.OTPRequestsTTL)
+	if err != nil {
+		u.log.Warn("Failed to track OTP requests", "error", err)
+		return nil
+	}
+	if count > maxOTPRequests {
+		return ErrOTPRateLimited
+	}
+	return nil
+}
+
 // PhoneLoginRequest contains phone-based OTP login request
 type PhoneLoginRequest struct {
 	PhoneNumber string `json:"phone_number"`
@@ -348,7 +628,8 @@ type PhoneLoginRequest struct {
 
 // SendOTPResponse contains OTP send result
 type SendOTPResponse struct {
-	Message string `json:"message"`
+	Message              string `json:"message"`
+	ReactivationRequired bool   `json:"reactivation_required,omitempty"` // OTP is for POST /auth/reactivate
 }
 
 // SendOTP generates and sends OTP to phone number
@@ -362,6 +643,21 @@ func (u *UserUsecase) SendOTP(ctx context.Context, req PhoneLoginRequest) (*Send
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
+	// Deactivated accounts get a reactivation OTP, if they can have one
+	if user.IsDeactivated() {
+		if err := u.checkActive(ctx, user); !errors.Is(err, ErrReactivationRequired) {
+			return nil, err
+		}
+		return &SendOTPResponse{
+			Message:              "Account is deactivated, OTP sent to reactivate it",
+			ReactivationRequired: true,
+		}, nil
+	}
+
+	if err := u.allowOTPRequest(ctx, req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
 	// Generate OTP
 	otpCode, err := generateOTP()
 	if err != nil {
@@ -374,7 +670,7 @@ func (u *UserUsecase) SendOTP(ctx context.Context, req PhoneLoginRequest) (*Send
 		PhoneNumber: &req.PhoneNumber,
 		OTPCode:     otpCode,
 		Purpose:     domain.OTPPurposeLogin,
-		ExpiresAt:   time.Now().Add(10 * time.Minute),
+		ExpiresAt:   time.Now().Add(otpValidity),
 		IsVerified:  false,
 		Attempts:    0,
 		CreatedAt:   time.Now(),
@@ -384,8 +680,10 @@ func (u *UserUsecase) SendOTP(ctx context.Context, req PhoneLoginRequest) (*Send
 		return nil, fmt.Errorf("failed to store OTP: %w", err)
 	}
 
-	// In production: Send OTP via SMS service (Twilio, AWS SNS, etc.)
-	u.log.Info("OTP generated", "user_id", user.ID.String(), "phone", req.PhoneNumber, "otp", otpCode)
+	if err := u.deliverOTP(ctx, req.PhoneNumber, otpCode, user.PreferredLocale()); err != nil {
+		return nil, err
+	}
+	u.log.Info("Login OTP sent", "user_id", user.ID.String())
 
 	return &SendOTPResponse{
 		Message: "OTP sent to your phone number",
@@ -394,13 +692,26 @@ func (u *UserUsecase) SendOTP(ctx context.Context, req PhoneLoginRequest) (*Send
 
 // ValidateToken validates JWT token and returns claims
 func (u *UserUsecase) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(u.jwtSecret), nil
-	})
+	u.jwtMu.RLock()
+	current, previous := u.jwtSecret, u.jwtPrevSecret
+	if time.Now().After(u.jwtPrevUntil) {
+		previous = ""
+	}
+	u.jwtMu.RUnlock()
+
+	parse := func(secret string) (*jwt.Token, error) {
+		return jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(secret), nil
+		})
+	}
 
+	token, err := parse(current)
+	if err != nil && previous != "" && errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		token, err = parse(previous)
+	}
 	if err != nil {
 		return nil, ErrUnauthorized
 	}
@@ -414,9 +725,9 @@ func (u *UserUsecase) ValidateToken(tokenString string) (*JWTClaims, error) {
 
 // GetUser retrieves user by ID
 func (u *UserUsecase) GetUser(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
-	user, err := u.userRepo.GetByID(ctx, userID)
+	user, err := u.users.Get(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
-}
\ No newline at end of file
+}
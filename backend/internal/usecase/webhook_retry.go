@@ -0,0 +1,255 @@
+// Package usecase implements replaying Razorpay webhooks whose processing
+// failed, automatically and by admins
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/internal/domain"
+	"fooddelivery/internal/repository"
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+)
+
+// webhookReplayBatch is how many due webhooks one sweep replays
+const webhookReplayBatch = 20
+
+// webhookReplayLease keeps other instances off a webhook being replayed
+const webhookReplayLease = 5 * time.Minute
+
+// Webhook log page sizes
+const (
+	defaultWebhookLogLimit = 50
+	maxWebhookLogLimit     = 200
+)
+
+// Webhook log errors
+var (
+	ErrWebhookLogNotFound   = errors.New("webhook log not found")
+	ErrWebhookNotReplayable = errors.New("webhook has an invalid signature or payload and can't be replayed")
+)
+
+// webhookAttempt follows a webhook through its handler: the log row the
+// handler wrote, and when replaying, the row being replayed
+type webhookAttempt struct {
+	replayOf uuid.UUID
+	logID    uuid.UUID
+}
+
+type webhookAttemptKey struct{}
+
+// withWebhookAttempt attaches attempt to ctx for logWebhook
+func withWebhookAttempt(ctx context.Context, attempt *webhookAttempt) context.Context {
+	return context.WithValue(ctx, webhookAttemptKey{}, attempt)
+}
+
+// SetWebhookRetryPolicy enables replaying webhooks whose handler failed,
+// backing off per policy until MaxAttempts (counting the delivery) is
+// reached. Without it failed webhooks are only logged.
+func (u *PaymentUsecase) SetWebhookRetryPolicy(policy retry.Policy) {
+	u.webhookRetry = policy
+}
+
+// logWebhook records a webhook's outcome. A replay updates the row it
+// replays instead of adding one.
+func (u *PaymentUsecase) logWebhook(ctx context.Context, eventType string, payload []byte, signatureValid bool, orderID *uuid.UUID, processingError string) (uuid.UUID, error) {
+	attempt, _ := ctx.Value(webhookAttemptKey{}).(*webhookAttempt)
+	if attempt != nil && attempt.replayOf != uuid.Nil {
+		attempt.logID = attempt.replayOf
+		return attempt.replayOf, u.webhookLogs.RecordReplay(ctx, attempt.replayOf, orderID, processingError)
+	}
+
+	id, err := u.webhookLogs.Create(ctx, "razorpay", eventType, payload, signatureValid, orderID, processingError)
+	if attempt != nil {
+		attempt.logID = id
+	}
+	return id, err
+}
+
+// scheduleWebhookRetry schedules the next replay of a webhook that failed
+// its attempts-th attempt, or gives up on it once they run out
+func (u *PaymentUsecase) scheduleWebhookRetry(ctx context.Context, logID uuid.UUID, eventType string, attempts int, cause error, log *logger.Logger) {
+	if logID == uuid.Nil || u.webhookRetry.MaxAttempts <= 1 {
+		return
+	}
+
+	if attempts < u.webhookRetry.MaxAttempts {
+		at := time.Now().Add(u.webhookRetry.Backoff(attempts))
+		if err := u.webhookLogs.ScheduleRetry(ctx, logID, at); err != nil {
+			log.Error("Failed to schedule webhook retry", "error", err)
+			return
+		}
+		log.Info("Webhook scheduled for retry", "attempts", attempts, "next_attempt_at", at)
+		return
+	}
+
+	if err := u.webhookLogs.MarkFailed(ctx, logID); err != nil {
+		log.Error("Failed to mark webhook failed", "error", err)
+		return
+	}
+	log.Error("Giving up on webhook", "attempts", attempts, "error", cause)
+	u.alerts.Raise(ctx, AdminAlert{
+		Kind:     AlertWebhookFailed,
+		Critical: true,
+		Title:    "Payment webhook failed",
+		Body:     fmt.Sprintf("A Razorpay %s webhook failed %d times and won't be retried: %v", eventType, attempts, cause),
+		Data: map[string]string{
+			"type":           "webhook_failed",
+			"webhook_log_id": logID.String(),
+		},
+	})
+}
+
+// RunWebhookRetries replays due webhooks every interval until ctx is
+// cancelled
+func (u *PaymentUsecase) RunWebhookRetries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.replayDue(ctx)
+		}
+	}
+}
+
+// replayDue replays one batch of due webhooks
+func (u *PaymentUsecase) replayDue(ctx context.Context) {
+	due, err := u.webhookLogs.ClaimDue(ctx, webhookReplayBatch, webhookReplayLease)
+	if err != nil {
+		u.log.Error("Failed to load due webhooks", "error", err)
+		return
+	}
+
+	for i := range due {
+		u.replayWebhook(ctx, &due[i])
+	}
+}
+
+// replayWebhook runs a logged webhook through its handler again. Its
+// signature was checked when it arrived.
+func (u *PaymentUsecase) replayWebhook(ctx context.Context, wl *domain.WebhookLog) {
+	log := u.log.WithFields(map[string]interface{}{
+		"source":         "webhook_replay",
+		"webhook_log_id": wl.ID.String(),
+		"event":          wl.EventType,
+		"attempt":        wl.Attempts,
+	})
+
+	var webhookData WebhookPayload
+	if err := json.Unmarshal(wl.Payload, &webhookData); err != nil {
+		// Can't get better on a retry
+		_ = u.webhookLogs.RecordReplay(ctx, wl.ID, nil, err.Error())
+		u.scheduleWebhookRetry(ctx, wl.ID, wl.EventType, u.webhookRetry.MaxAttempts, err, log)
+		return
+	}
+
+	attempt := &webhookAttempt{replayOf: wl.ID}
+	err := u.dispatchWebhook(withWebhookAttempt(ctx, attempt), webhookData, wl.Payload, log)
+	if err != nil {
+		if attempt.logID == uuid.Nil {
+			_ = u.webhookLogs.RecordReplay(ctx, wl.ID, nil, err.Error())
+		}
+		u.scheduleWebhookRetry(ctx, wl.ID, wl.EventType, wl.Attempts, err, log)
+		return
+	}
+
+	// Handlers log every outcome; this covers one that didn't
+	if attempt.logID == uuid.Nil {
+		if err := u.webhookLogs.RecordReplay(ctx, wl.ID, nil, ""); err != nil {
+			log.Error("Failed to record webhook replay", "error", err)
+		}
+	}
+	log.Info("Webhook replayed")
+}
+
+// ListWebhookLogs returns the webhook logs matching f, newest first
+func (u *PaymentUsecase) ListWebhookLogs(ctx context.Context, f repository.WebhookLogFilter) ([]domain.WebhookLog, error) {
+	if f.Limit <= 0 || f.Limit > maxWebhookLogLimit {
+		f.Limit = defaultWebhookLogLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return u.webhookLogs.List(ctx, f)
+}
+
+// GetWebhookLog returns one webhook log with its payload
+func (u *PaymentUsecase) GetWebhookLog(ctx context.Context, id uuid.UUID) (*domain.WebhookLog, error) {
+	wl, err := u.webhookLogs.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWebhookLogNotFound
+	}
+	return wl, err
+}
+
+// GetOrderWebhookLogs returns the webhooks logged against an order, oldest
+// first
+func (u *PaymentUsecase) GetOrderWebhookLogs(ctx context.Context, orderID uuid.UUID) ([]domain.WebhookLog, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return u.webhookLogs.ListByOrder(ctx, order.ID)
+}
+
+// ReplayWebhook runs a logged webhook through its handler again now, for
+// an admin unsticking a payment. Handlers are idempotent, so replaying one
+// that was processed changes nothing. A failed replay of a webhook that
+// wasn't given up on stays scheduled for automatic retries. Returns the
+// log with the replay's outcome.
+func (u *PaymentUsecase) ReplayWebhook(ctx context.Context, adminID, id uuid.UUID) (*domain.WebhookLog, error) {
+	wl, err := u.GetWebhookLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var webhookData WebhookPayload
+	if !wl.SignatureValid || json.Unmarshal(wl.Payload, &webhookData) != nil {
+		return nil, ErrWebhookNotReplayable
+	}
+
+	if wl, err = u.webhookLogs.Claim(ctx, id, webhookReplayLease); err != nil {
+		return nil, err
+	}
+	log := u.log.WithFields(map[string]interface{}{
+		"source":         "webhook_replay",
+		"webhook_log_id": wl.ID.String(),
+		"event":          wl.EventType,
+		"admin_id":       adminID.String(),
+	})
+
+	attempt := &webhookAttempt{replayOf: wl.ID}
+	replayErr := u.dispatchWebhook(withWebhookAttempt(ctx, attempt), webhookData, wl.Payload, log)
+	if replayErr != nil {
+		log.Warn("Manual webhook replay failed", "error", replayErr)
+		if attempt.logID == uuid.Nil {
+			if err := u.webhookLogs.RecordReplay(ctx, wl.ID, nil, replayErr.Error()); err != nil {
+				return nil, err
+			}
+		}
+		if wl.FailedAt == nil {
+			u.scheduleWebhookRetry(ctx, wl.ID, wl.EventType, wl.Attempts, replayErr, log)
+		}
+	} else if attempt.logID == uuid.Nil {
+		if err := u.webhookLogs.RecordReplay(ctx, wl.ID, nil, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	details := map[string]interface{}{"event_type": wl.EventType}
+	if replayErr != nil {
+		details["error"] = replayErr.Error()
+	}
+	u.audit.Record(ctx, adminID, domain.AuditWebhookReplayed, "webhook_log", wl.ID.String(), details)
+
+	return u.webhookLogs.GetByID(ctx, id)
+}
@@ -0,0 +1,42 @@
+-- Migration: 004_user_identities
+-- Description: Linked third-party identities (Google Sign-In) for social login
+-- Date: 2024-02-01
+
+-- ============================================================================
+-- USER_IDENTITIES TABLE
+-- ============================================================================
+
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- Local account the identity is linked to
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Identity provider (e.g., 'google')
+    provider VARCHAR(20) NOT NULL,
+
+    -- Stable provider-side user identifier ("sub" claim for Google)
+    -- Emails can change on the provider side, the subject never does
+    provider_subject VARCHAR(255) NOT NULL,
+
+    -- Email reported by the provider at link time (informational)
+    email VARCHAR(255),
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Constraints
+    CONSTRAINT user_identities_provider_subject_unique UNIQUE (provider, provider_subject),
+    CONSTRAINT user_identities_user_provider_unique UNIQUE (user_id, provider)
+);
+
+-- Index for listing identities linked to a user
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- ============================================================================
+-- COMMENTS
+-- ============================================================================
+
+COMMENT ON TABLE user_identities IS 'Third-party login identities linked to local user accounts';
+COMMENT ON COLUMN user_identities.provider_subject IS 'Provider user ID (Google "sub" claim), stable across email changes';
@@ -0,0 +1,60 @@
+-- Migration: 005_admin_totp
+-- Description: TOTP two-factor authentication for admin accounts
+-- Date: 2024-02-05
+
+-- ============================================================================
+-- USER_TOTP TABLE (one authenticator per user)
+-- ============================================================================
+
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Base32 shared secret provisioned to the authenticator app
+    secret VARCHAR(64) NOT NULL,
+
+    -- Enrollment is pending until the first code is confirmed
+    is_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+
+    -- Last accepted time step; codes at or before it are rejected as replays
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Trigger for user_totp table
+CREATE TRIGGER trigger_user_totp_updated_at
+    BEFORE UPDATE ON user_totp
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- TOTP_RECOVERY_CODES TABLE (single-use backup codes)
+-- ============================================================================
+
+CREATE TABLE totp_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- SHA-256 of the code; plaintext is shown once at generation
+    code_hash VARCHAR(64) NOT NULL,
+
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT totp_recovery_codes_hash_unique UNIQUE (user_id, code_hash)
+);
+
+-- Index for looking up a user's unused codes
+CREATE INDEX idx_totp_recovery_codes_user_id ON totp_recovery_codes(user_id) WHERE used_at IS NULL;
+
+-- ============================================================================
+-- COMMENTS
+-- ============================================================================
+
+COMMENT ON TABLE user_totp IS 'TOTP authenticator enrollment, required for admin accounts';
+COMMENT ON COLUMN user_totp.last_used_step IS 'Last accepted 30s time step, prevents code replay';
+COMMENT ON TABLE totp_recovery_codes IS 'Hashed single-use recovery codes for lost authenticators';
@@ -0,0 +1,52 @@
+-- Migration: 006_api_keys
+-- Description: API keys for server-to-server partner integrations (POS, analytics)
+-- Date: 2024-02-10
+
+-- ============================================================================
+-- API_KEYS TABLE
+-- ============================================================================
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- Human-readable partner/integration name
+    name VARCHAR(100) NOT NULL,
+
+    -- First characters of the key, shown in the admin UI to identify it
+    key_prefix VARCHAR(16) NOT NULL,
+
+    -- SHA-256 of the full key; plaintext is only returned at issuance
+    key_hash VARCHAR(64) NOT NULL,
+
+    -- Permission scopes (e.g., 'menu:read', 'orders:read')
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Requests allowed per minute per endpoint
+    rate_limit_per_minute INT NOT NULL DEFAULT 60,
+
+    -- Lifecycle
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    rotated_from UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+
+    -- Timestamps
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- Constraints
+    CONSTRAINT api_keys_key_hash_unique UNIQUE (key_hash),
+    CONSTRAINT api_keys_name_not_empty CHECK (LENGTH(TRIM(name)) > 0),
+    CONSTRAINT api_keys_rate_limit_positive CHECK (rate_limit_per_minute > 0)
+);
+
+-- Index for listing active keys in the admin dashboard
+CREATE INDEX idx_api_keys_active ON api_keys(is_active) WHERE is_active = TRUE;
+
+-- ============================================================================
+-- COMMENTS
+-- ============================================================================
+
+COMMENT ON TABLE api_keys IS 'Partner API keys with scoped permissions and per-key rate limits';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 of the key. Plaintext is never stored.';
+COMMENT ON COLUMN api_keys.rotated_from IS 'Previous key this one replaced on rotation';
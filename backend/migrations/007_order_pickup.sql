@@ -0,0 +1,29 @@
+-- Migration: 007_order_pickup
+-- Description: Counter pickup orders verified with a customer-held pickup code
+-- Date: 2024-02-14
+
+-- ============================================================================
+-- FULFILLMENT TYPE
+-- ============================================================================
+
+CREATE TYPE fulfillment_type AS ENUM (
+    'DELIVERY',  -- Rider delivers to the customer's address
+    'PICKUP'     -- Customer collects the order at the counter
+);
+
+ALTER TABLE orders
+    ADD COLUMN fulfillment_type fulfillment_type NOT NULL DEFAULT 'DELIVERY',
+
+    -- 6-digit code shown to the customer (numeric entry or inside a signed QR)
+    ADD COLUMN pickup_code VARCHAR(6),
+
+    -- Staff member who verified the code and handed the order over
+    ADD COLUMN pickup_verified_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN pickup_verified_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    ADD CONSTRAINT orders_pickup_code_required
+        CHECK (fulfillment_type <> 'PICKUP' OR pickup_code IS NOT NULL);
+
+-- Counter staff look up ready pickup orders
+CREATE INDEX idx_orders_pickup_ready ON orders(created_at)
+    WHERE fulfillment_type = 'PICKUP' AND status = 'ACCEPTED';
@@ -0,0 +1,42 @@
+-- Migration: 008_delivery_otp
+-- Description: Rider dispatch and OTP-confirmed doorstep hand-over
+-- Date: 2024-02-18
+
+-- ============================================================================
+-- ORDER STATUS
+-- ============================================================================
+
+-- Rider has picked the order up and is on the way
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'OUT_FOR_DELIVERY' AFTER 'ACCEPTED';
+
+-- ============================================================================
+-- RIDERS
+-- ============================================================================
+
+-- Riders are regular accounts flagged by an admin; they use the rider app routes
+ALTER TABLE users ADD COLUMN is_rider BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- ============================================================================
+-- DELIVERY HAND-OVER
+-- ============================================================================
+
+ALTER TABLE orders
+    ADD COLUMN rider_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN dispatched_at TIMESTAMP WITH TIME ZONE,
+
+    -- Generated at dispatch, shown to the customer, entered by the rider
+    ADD COLUMN delivery_otp VARCHAR(6),
+    -- Wrong entries; the OTP locks after the limit and needs an admin override
+    ADD COLUMN delivery_otp_attempts INTEGER NOT NULL DEFAULT 0,
+
+    ADD COLUMN delivered_at TIMESTAMP WITH TIME ZONE,
+    -- How the hand-over was confirmed: OTP, PICKUP_CODE or ADMIN_OVERRIDE
+    ADD COLUMN delivery_confirmation VARCHAR(20),
+    ADD COLUMN delivery_override_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN delivery_override_reason TEXT,
+
+    ADD CONSTRAINT orders_delivery_otp_attempts_non_negative CHECK (delivery_otp_attempts >= 0);
+
+-- Rider app lists its active deliveries
+CREATE INDEX idx_orders_rider_active ON orders(rider_id, dispatched_at)
+    WHERE status = 'OUT_FOR_DELIVERY';
@@ -0,0 +1,25 @@
+-- Migration: 009_delivery_proof
+-- Description: Photo proof of delivery uploaded by riders at the doorstep
+-- Date: 2024-02-21
+
+-- ============================================================================
+-- DELIVERY_PROOFS TABLE
+-- ============================================================================
+
+CREATE TABLE delivery_proofs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- One proof per order; a re-upload replaces the previous photo
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    rider_id UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    -- Object key in the storage service (never a public URL)
+    storage_key TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes INTEGER NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT delivery_proofs_order_unique UNIQUE (order_id),
+    CONSTRAINT delivery_proofs_size_positive CHECK (size_bytes > 0)
+);
@@ -0,0 +1,61 @@
+-- Migration: 010_delivery_batches
+-- Description: Delivery locations on orders and multi-order rider batches
+-- Date: 2024-02-25
+
+-- ============================================================================
+-- DELIVERY LOCATION
+-- ============================================================================
+
+-- Captured at checkout for delivery orders; pickup orders leave these NULL
+ALTER TABLE orders
+    ADD COLUMN delivery_address TEXT,
+    ADD COLUMN delivery_latitude DOUBLE PRECISION,
+    ADD COLUMN delivery_longitude DOUBLE PRECISION,
+
+    ADD CONSTRAINT orders_delivery_coordinates_valid CHECK (
+        (delivery_latitude IS NULL AND delivery_longitude IS NULL) OR
+        (delivery_latitude BETWEEN -90 AND 90 AND delivery_longitude BETWEEN -180 AND 180)
+    );
+
+-- ============================================================================
+-- DELIVERY_BATCHES TABLE
+-- ============================================================================
+
+CREATE TABLE delivery_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    rider_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+
+    -- Planned route length from the kitchen through every stop
+    total_distance_meters INTEGER NOT NULL,
+
+    -- Admin who created the batch
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_delivery_batches_rider ON delivery_batches(rider_id, created_at DESC);
+
+-- ============================================================================
+-- DELIVERY_BATCH_STOPS TABLE
+-- ============================================================================
+
+CREATE TABLE delivery_batch_stops (
+    batch_id UUID NOT NULL REFERENCES delivery_batches(id) ON DELETE CASCADE,
+
+    -- An order can only ride in one batch
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+
+    -- 1-based position in the optimized visit sequence
+    sequence INTEGER NOT NULL,
+
+    -- Distance from the previous stop (or the kitchen for the first stop)
+    leg_distance_meters INTEGER NOT NULL,
+
+    estimated_arrival_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (batch_id, sequence),
+    CONSTRAINT delivery_batch_stops_order_unique UNIQUE (order_id),
+    CONSTRAINT delivery_batch_stops_sequence_positive CHECK (sequence > 0)
+);
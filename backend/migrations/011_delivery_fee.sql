@@ -0,0 +1,18 @@
+-- Migration: 011_delivery_fee
+-- Description: Distance-based delivery fee stored on orders
+-- Date: 2024-02-27
+
+-- ============================================================================
+-- DELIVERY FEE
+-- ============================================================================
+
+-- The fee is part of total_amount; the road distance it was priced on is kept
+-- for support and fee audits. Pickup orders have no fee and no distance.
+ALTER TABLE orders
+    ADD COLUMN delivery_fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN delivery_distance_meters INTEGER,
+
+    ADD CONSTRAINT orders_delivery_fee_non_negative CHECK (delivery_fee >= 0),
+    ADD CONSTRAINT orders_delivery_distance_non_negative CHECK (
+        delivery_distance_meters IS NULL OR delivery_distance_meters >= 0
+    );
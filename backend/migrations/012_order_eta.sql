@@ -0,0 +1,19 @@
+-- Migration: 012_order_eta
+-- Description: Promised delivery time with weather adjustment on orders
+-- Date: 2024-03-01
+
+-- ============================================================================
+-- ORDER ETA
+-- ============================================================================
+
+-- eta_adjustment_minutes is the part of the ETA added for weather; the
+-- condition that triggered it is kept for analytics
+ALTER TABLE orders
+    ADD COLUMN estimated_delivery_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN eta_adjustment_minutes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN eta_weather_condition VARCHAR(20),
+
+    ADD CONSTRAINT orders_eta_adjustment_non_negative CHECK (eta_adjustment_minutes >= 0);
+
+CREATE INDEX idx_orders_eta_weather ON orders(eta_weather_condition, created_at)
+    WHERE eta_weather_condition IS NOT NULL;
@@ -0,0 +1,55 @@
+-- Migration: 013_kitchen_capacity
+-- Description: Kitchen throughput per 15-minute slot and slot bookings
+-- Date: 2024-03-04
+
+-- ============================================================================
+-- KITCHEN_CAPACITY_RULES TABLE
+-- ============================================================================
+
+-- Orders the kitchen can take per 15-minute slot within a time window.
+-- A rule with a day_of_week overrides an every-day rule (NULL day) for the
+-- same window; slots not covered by any rule use the configured default.
+CREATE TABLE kitchen_capacity_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- 0 = Sunday ... 6 = Saturday; NULL applies to every day
+    day_of_week SMALLINT,
+
+    -- Kitchen local time, end exclusive
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+
+    -- 0 closes the window for ordering
+    orders_per_slot INTEGER NOT NULL,
+
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT kitchen_capacity_day_valid CHECK (day_of_week IS NULL OR day_of_week BETWEEN 0 AND 6),
+    CONSTRAINT kitchen_capacity_window_valid CHECK (end_time > start_time),
+    CONSTRAINT kitchen_capacity_non_negative CHECK (orders_per_slot >= 0)
+);
+
+-- ============================================================================
+-- KITCHEN_SLOT_BOOKINGS TABLE
+-- ============================================================================
+
+-- One row per slot with orders booked; incremented atomically at checkout
+CREATE TABLE kitchen_slot_bookings (
+    slot_start TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    booked INTEGER NOT NULL DEFAULT 0,
+
+    CONSTRAINT kitchen_slot_bookings_non_negative CHECK (booked >= 0)
+);
+
+-- ============================================================================
+-- ORDER SLOT
+-- ============================================================================
+
+-- The kitchen slot an order was booked into; is_scheduled marks orders the
+-- customer placed for a later slot
+ALTER TABLE orders
+    ADD COLUMN kitchen_slot_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN is_scheduled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_orders_kitchen_slot ON orders(kitchen_slot_at) WHERE kitchen_slot_at IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Migration: 014_order_surveys
+-- Description: Post-delivery NPS surveys
+-- Date: 2024-03-07
+
+-- ============================================================================
+-- ORDER_SURVEYS TABLE
+-- ============================================================================
+
+-- One survey per delivered order. The push is sent once send_after passes;
+-- the customer answers with a 0-10 score and an optional comment.
+CREATE TABLE order_surveys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    send_after TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE,
+
+    score SMALLINT,
+    comment TEXT,
+    responded_at TIMESTAMP WITH TIME ZONE,
+
+    -- Low scores raise an admin alert until acknowledged
+    alert_acknowledged_at TIMESTAMP WITH TIME ZONE,
+    alert_acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT order_surveys_score_range CHECK (score IS NULL OR score BETWEEN 0 AND 10),
+    CONSTRAINT order_surveys_response_complete CHECK ((score IS NULL) = (responded_at IS NULL))
+);
+
+-- Dispatcher scans for surveys due to be pushed
+CREATE INDEX idx_order_surveys_due ON order_surveys(send_after) WHERE sent_at IS NULL;
+
+-- Rolling NPS over recent responses
+CREATE INDEX idx_order_surveys_responded ON order_surveys(responded_at) WHERE responded_at IS NOT NULL;
+
+CREATE INDEX idx_order_surveys_user ON order_surveys(user_id, created_at DESC);
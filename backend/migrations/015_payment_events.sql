@@ -0,0 +1,47 @@
+-- Migration: 015_payment_events
+-- Description: Structured audit trail of payment state changes
+-- Date: 2024-03-11
+
+-- ============================================================================
+-- PAYMENT_EVENTS TABLE
+-- ============================================================================
+
+-- Every payment-related transition attempt, whether or not it changed the
+-- order. Used to debug "money taken but order not paid" complaints.
+CREATE TABLE payment_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- NULL when a gateway event could not be matched to an order; the
+    -- Razorpay IDs still tie it back
+    order_id UUID REFERENCES orders(id) ON DELETE CASCADE,
+    razorpay_order_id VARCHAR(100),
+    razorpay_payment_id VARCHAR(100),
+
+    -- Who drove the transition: CHECKOUT, WEBHOOK, CLIENT_VERIFY, RECONCILIATION
+    source VARCHAR(20) NOT NULL,
+
+    -- What happened, e.g. 'order.created', 'payment.captured', 'signature.invalid'
+    event_type VARCHAR(100) NOT NULL,
+
+    previous_status order_status,
+    new_status order_status,
+
+    -- APPLIED (status changed), NO_CHANGE (duplicate/late), REJECTED, ERROR
+    outcome VARCHAR(20) NOT NULL,
+
+    amount BIGINT,
+    detail TEXT,
+
+    -- The raw gateway payload lives in webhook_logs; no FK so the audit row
+    -- never blocks on webhook logging
+    webhook_log_id UUID,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT payment_events_source_valid CHECK (source IN ('CHECKOUT', 'WEBHOOK', 'CLIENT_VERIFY', 'RECONCILIATION')),
+    CONSTRAINT payment_events_outcome_valid CHECK (outcome IN ('APPLIED', 'NO_CHANGE', 'REJECTED', 'ERROR'))
+);
+
+CREATE INDEX idx_payment_events_order ON payment_events(order_id, created_at) WHERE order_id IS NOT NULL;
+CREATE INDEX idx_payment_events_razorpay_order ON payment_events(razorpay_order_id) WHERE razorpay_order_id IS NOT NULL;
+CREATE INDEX idx_payment_events_razorpay_payment ON payment_events(razorpay_payment_id) WHERE razorpay_payment_id IS NOT NULL;
@@ -0,0 +1,41 @@
+-- Migration: 016_order_import
+-- Description: Historical orders imported from the legacy platform
+-- Date: 2024-03-11
+
+-- ============================================================================
+-- ORDERS: IMPORT TAGGING
+-- ============================================================================
+
+-- Imported orders exist for order history and analytics only. They are never
+-- dispatched, batched, surveyed or counted on the live ops dashboard.
+ALTER TABLE orders
+    -- Set only on imported orders; NULL means the order was placed here
+    ADD COLUMN imported_at TIMESTAMP WITH TIME ZONE,
+
+    -- Export the order came from (e.g. 'legacy-2023') and its ID there
+    ADD COLUMN import_source VARCHAR(50),
+    ADD COLUMN legacy_order_id VARCHAR(100),
+
+    ADD CONSTRAINT orders_import_fields_complete CHECK (
+        (imported_at IS NULL AND import_source IS NULL AND legacy_order_id IS NULL)
+        OR (imported_at IS NOT NULL AND import_source IS NOT NULL AND legacy_order_id IS NOT NULL)
+    );
+
+-- Re-running an import skips orders already brought over
+CREATE UNIQUE INDEX idx_orders_legacy_order ON orders(import_source, legacy_order_id)
+    WHERE legacy_order_id IS NOT NULL;
+
+-- Legacy pickup orders had no hand-over code
+ALTER TABLE orders
+    DROP CONSTRAINT orders_pickup_code_required,
+    ADD CONSTRAINT orders_pickup_code_required
+        CHECK (fulfillment_type <> 'PICKUP' OR pickup_code IS NOT NULL OR imported_at IS NOT NULL);
+
+-- ============================================================================
+-- ORDER_ITEMS: UNMATCHED LEGACY ITEMS
+-- ============================================================================
+
+-- Legacy items that no longer match a menu item keep their name and price
+-- snapshot with no menu item reference
+ALTER TABLE order_items
+    ALTER COLUMN menu_item_id DROP NOT NULL;
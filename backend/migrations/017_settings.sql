@@ -0,0 +1,37 @@
+-- Migration: 017_settings
+-- Description: Runtime-editable settings with change history
+-- Date: 2024-03-11
+
+-- ============================================================================
+-- SETTINGS TABLE
+-- ============================================================================
+
+-- Admin overrides of tunable settings. Settings without a row use the
+-- default from the environment; deleting a row restores the default.
+-- Keys and types are defined in code, so unknown keys are never written.
+CREATE TABLE settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value JSONB NOT NULL,
+
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- ============================================================================
+-- SETTING_CHANGES TABLE
+-- ============================================================================
+
+-- Every override and reset, for "who changed the delivery fee and when"
+CREATE TABLE setting_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key VARCHAR(100) NOT NULL,
+
+    -- NULL old_value: override created; NULL new_value: reset to default
+    old_value JSONB,
+    new_value JSONB,
+
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_setting_changes_key ON setting_changes(key, changed_at DESC);
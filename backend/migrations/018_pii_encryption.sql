@@ -0,0 +1,50 @@
+-- Migration: 018_pii_encryption
+-- Description: Encrypted phone/email columns with blind indexes for lookups
+-- Date: 2024-03-11
+
+-- Ciphertext is "<key id>:<base64>" (AES-256-GCM, written by the API).
+-- Blind indexes are hex HMAC-SHA256 digests used for exact-match lookups
+-- and uniqueness. The plaintext columns stay nullable until existing rows
+-- have been encrypted with `go run ./cmd/encryptpii`; a later migration
+-- drops them.
+
+-- ============================================================================
+-- USERS
+-- ============================================================================
+
+ALTER TABLE users
+    ADD COLUMN phone_number_enc TEXT,
+    ADD COLUMN phone_number_bidx CHAR(64),
+    ADD COLUMN email_enc TEXT,
+    ADD COLUMN email_bidx CHAR(64),
+
+    ALTER COLUMN phone_number DROP NOT NULL,
+    ALTER COLUMN email DROP NOT NULL,
+
+    ADD CONSTRAINT users_phone_number_present
+        CHECK (phone_number IS NOT NULL OR phone_number_enc IS NOT NULL),
+    ADD CONSTRAINT users_email_present
+        CHECK (email IS NOT NULL OR email_enc IS NOT NULL),
+    ADD CONSTRAINT users_phone_number_indexed
+        CHECK ((phone_number_enc IS NULL) = (phone_number_bidx IS NULL)),
+    ADD CONSTRAINT users_email_indexed
+        CHECK ((email_enc IS NULL) = (email_bidx IS NULL));
+
+-- Login lookups and uniqueness move to the blind indexes
+CREATE UNIQUE INDEX idx_users_phone_number_bidx ON users(phone_number_bidx) WHERE phone_number_bidx IS NOT NULL;
+CREATE UNIQUE INDEX idx_users_email_bidx ON users(email_bidx) WHERE email_bidx IS NOT NULL;
+
+-- ============================================================================
+-- OTPS
+-- ============================================================================
+
+ALTER TABLE otps
+    ADD COLUMN phone_number_enc TEXT,
+    ADD COLUMN email_enc TEXT,
+    ADD COLUMN contact_bidx CHAR(64),
+
+    DROP CONSTRAINT otps_contact_check,
+    ADD CONSTRAINT otps_contact_check
+        CHECK (num_nonnulls(phone_number, email, phone_number_enc, email_enc) = 1);
+
+CREATE INDEX idx_otps_contact_bidx ON otps(contact_bidx) WHERE contact_bidx IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Migration: 019_menu_item_version
+-- Description: Optimistic locking for menu item edits
+-- Date: 2024-03-12
+
+-- ============================================================================
+-- MENU ITEM VERSION
+-- ============================================================================
+
+-- Admin updates and deletes must send the version they read; a stale
+-- version means someone else edited the item first and is rejected with
+-- 409 instead of silently overwriting their change.
+ALTER TABLE menu_items
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN menu_items.version IS 'Optimistic locking version. Increment on every update.';
@@ -0,0 +1,27 @@
+-- Migration: 020_menu_price_history
+-- Description: Price change history for menu items
+-- Date: 2024-03-12
+
+-- ============================================================================
+-- MENU_PRICE_HISTORY TABLE
+-- ============================================================================
+
+-- One row per price change, written with the menu item update, so finance
+-- can see what an item cost on any date. old_price is NULL for the row
+-- written when the item was created.
+CREATE TABLE menu_price_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    menu_item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+
+    old_price INTEGER,
+    new_price INTEGER NOT NULL,
+
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_menu_price_history_item ON menu_price_history(menu_item_id, changed_at);
+
+-- Existing items start their timeline at their current price
+INSERT INTO menu_price_history (menu_item_id, old_price, new_price, changed_at)
+SELECT id, NULL, price, created_at FROM menu_items;
@@ -0,0 +1,26 @@
+-- Migration: 021_admin_audit_log
+-- Description: Audit log of staff actions for the admin activity feed
+-- Date: 2024-03-13
+
+-- ============================================================================
+-- ADMIN_AUDIT_LOG TABLE
+-- ============================================================================
+
+-- One row per admin action (menu edits, manual order transitions, setting
+-- changes, ...). Written best-effort after the action succeeds; details
+-- holds action-specific context such as old and new status.
+CREATE TABLE admin_audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(100) NOT NULL,
+    details JSONB,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Shift-handover feed: newest first, optionally for one admin
+CREATE INDEX idx_admin_audit_log_created_at ON admin_audit_log(created_at DESC);
+CREATE INDEX idx_admin_audit_log_actor ON admin_audit_log(actor_id, created_at DESC);
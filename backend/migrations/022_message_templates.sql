@@ -0,0 +1,24 @@
+-- Migration: 022_message_templates
+-- Description: Admin-editable SMS/push/email/invoice message templates
+-- Date: 2024-03-14
+
+-- ============================================================================
+-- MESSAGE_TEMPLATES TABLE
+-- ============================================================================
+
+-- Overrides of the templates embedded in the binary, per locale. Templates
+-- without a row use the embedded default; deleting a row restores it.
+-- Keys and their variables are defined in code, so unknown keys are never
+-- written. subject is the push title or email subject; SMS leaves it empty.
+CREATE TABLE message_templates (
+    key VARCHAR(100) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (key, locale)
+);
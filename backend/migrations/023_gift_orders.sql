@@ -0,0 +1,19 @@
+-- Migration: 023_gift_orders
+-- Description: Recipient contact for orders delivered to someone other than the payer
+-- Date: 2024-03-14
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- Gift orders carry the recipient's name and phone. The rider calls the
+-- recipient and delivery updates (including the hand-over OTP) go to them
+-- by SMS; payment receipts still go to the paying user.
+ALTER TABLE orders
+    ADD COLUMN recipient_name VARCHAR(100),
+    ADD COLUMN recipient_phone VARCHAR(20),
+
+    ADD CONSTRAINT orders_recipient_complete
+        CHECK ((recipient_name IS NULL) = (recipient_phone IS NULL)),
+    ADD CONSTRAINT orders_recipient_delivery_only
+        CHECK (recipient_name IS NULL OR fulfillment_type = 'DELIVERY');
@@ -0,0 +1,20 @@
+-- Migration: 024_order_item_snapshot
+-- Description: Snapshot menu category and image on order items
+-- Date: 2024-03-14
+
+-- ============================================================================
+-- ORDER_ITEMS
+-- ============================================================================
+
+-- Copied from the menu item at checkout so order history renders as it
+-- looked when ordered, even after the item is edited or deleted. NULL for
+-- older and imported items.
+ALTER TABLE order_items
+    ADD COLUMN category VARCHAR(50),
+    ADD COLUMN image_url TEXT;
+
+-- Backfill from the current menu; the best available approximation
+UPDATE order_items oi
+SET category = mi.category, image_url = mi.image_url
+FROM menu_items mi
+WHERE oi.menu_item_id = mi.id;
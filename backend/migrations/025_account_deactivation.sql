@@ -0,0 +1,25 @@
+-- Migration: 025_account_deactivation
+-- Description: Soft deactivation of user accounts, by the user or an admin
+-- Date: 2024-03-15
+
+-- ============================================================================
+-- USERS
+-- ============================================================================
+
+-- Deactivated accounts keep all their data but can't log in or order.
+-- deactivated_by is NULL when the user deactivated their own account; only
+-- those can be reactivated by the user (with an OTP, within the window the
+-- API is configured with). Admin deactivations record a reason.
+ALTER TABLE users
+    ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN deactivated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN deactivation_reason TEXT,
+
+    ADD CONSTRAINT users_deactivation_complete
+        CHECK (deactivated_at IS NOT NULL OR (deactivated_by IS NULL AND deactivation_reason IS NULL));
+
+-- ============================================================================
+-- OTPS
+-- ============================================================================
+
+COMMENT ON COLUMN otps.purpose IS 'Purpose: login, signup, password_reset, email_verify, reactivate';
@@ -0,0 +1,20 @@
+-- Migration: 026_payment_review
+-- Description: Flag orders whose captured payment doesn't match the order for admin review
+-- Date: 2024-03-18
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- Set when Razorpay reports a captured amount or currency that differs from
+-- the order's total. The order is not marked PAID; an admin checks it with
+-- Razorpay, settles the status (or refunds) and resolves the flag.
+ALTER TABLE orders
+    ADD COLUMN payment_review_reason TEXT,
+    ADD COLUMN payment_review_flagged_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN payment_review_resolved_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN payment_review_resolved_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Open reviews, for the admin queue
+CREATE INDEX idx_orders_payment_review_open ON orders(payment_review_flagged_at)
+    WHERE payment_review_flagged_at IS NOT NULL AND payment_review_resolved_at IS NULL;
@@ -0,0 +1,60 @@
+-- Migration: 027_item_shortages
+-- Description: Items the kitchen ran out of after payment, resolved by the customer
+-- Date: 2024-03-19
+
+-- ============================================================================
+-- ORDER STATUS
+-- ============================================================================
+
+-- Orders the customer cancelled (e.g. after a shortage); the payment is
+-- refunded in full
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'CANCELLED';
+
+-- ============================================================================
+-- ORDER_ITEM_SHORTAGES TABLE
+-- ============================================================================
+
+-- Staff report items missing from a paid order; the customer accepts a
+-- refund for them or cancels the order. Unanswered shortages are accepted
+-- when respond_by passes. At most one shortage per order is open at a time.
+CREATE TABLE order_item_shortages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+
+    -- PENDING, ACCEPTED (refund for the missing items) or CANCELLED (full refund)
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+
+    -- [{order_item_id, name, quantity, amount}] with amounts in paisa
+    items JSONB NOT NULL,
+    refund_amount BIGINT NOT NULL,
+    note TEXT,
+
+    reported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    respond_by TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    -- resolved_by is NULL when the shortage was resolved automatically
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    -- Set once the refund was issued, or why it failed (refunded by hand)
+    razorpay_refund_id VARCHAR(100),
+    refund_error TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT order_item_shortages_status_valid CHECK (status IN ('PENDING', 'ACCEPTED', 'CANCELLED')),
+    CONSTRAINT order_item_shortages_refund_positive CHECK (refund_amount > 0)
+);
+
+CREATE INDEX idx_order_item_shortages_order ON order_item_shortages(order_id, created_at);
+CREATE UNIQUE INDEX idx_order_item_shortages_open ON order_item_shortages(order_id) WHERE status = 'PENDING';
+CREATE INDEX idx_order_item_shortages_due ON order_item_shortages(respond_by) WHERE status = 'PENDING';
+
+-- ============================================================================
+-- PAYMENT_EVENTS
+-- ============================================================================
+
+-- Refunds are part of an order's payment audit trail
+ALTER TABLE payment_events DROP CONSTRAINT payment_events_source_valid;
+ALTER TABLE payment_events ADD CONSTRAINT payment_events_source_valid
+    CHECK (source IN ('CHECKOUT', 'WEBHOOK', 'CLIENT_VERIFY', 'RECONCILIATION', 'REFUND'));
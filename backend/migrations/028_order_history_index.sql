@@ -0,0 +1,11 @@
+-- Migration: 028_order_history_index
+-- Description: Index for paging a customer's order history newest first
+-- Date: 2024-03-20
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- GET /orders pages by (created_at, id) within one user; idx_orders_user_id
+-- alone made long-time customers' history sort every order they ever placed
+CREATE INDEX idx_orders_user_created ON orders(user_id, created_at DESC, id DESC);
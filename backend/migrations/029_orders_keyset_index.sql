@@ -0,0 +1,12 @@
+-- Migration: 029_orders_keyset_index
+-- Description: Index for keyset pagination of the admin order listing
+-- Date: 2024-03-21
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- GET /admin/orders pages by (created_at, id) < cursor; idx_orders_created_at
+-- can't serve the id tie-break, so each page re-sorted every order that
+-- shared a timestamp. The export stream pages the same way.
+CREATE INDEX idx_orders_created_id ON orders(created_at DESC, id DESC);
@@ -0,0 +1,33 @@
+-- Migration: 030_order_tags
+-- Description: Free-form tags and typed custom attributes on orders
+-- Date: 2024-03-22
+
+-- ============================================================================
+-- ORDER TAGS AND ATTRIBUTES
+-- ============================================================================
+
+-- Tags are lowercase labels (e.g. vip, influencer, test-order) set by admins
+-- and automation rules. Orders tagged test-order are left out of analytics.
+-- Attributes map a defined key to a string, number or boolean value.
+ALTER TABLE orders
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Admin search filters with containment (tags @> / attributes @>)
+CREATE INDEX idx_orders_tags ON orders USING GIN (tags);
+CREATE INDEX idx_orders_attributes ON orders USING GIN (attributes jsonb_path_ops);
+
+-- ============================================================================
+-- ORDER_ATTRIBUTE_DEFINITIONS TABLE
+-- ============================================================================
+
+-- Attributes must be defined before they are set, so every order agrees on
+-- a key's type. A key's type can't change once defined.
+CREATE TABLE order_attribute_definitions (
+    key VARCHAR(50) PRIMARY KEY CHECK (key ~ '^[a-z][a-z0-9_]*$'),
+    value_type VARCHAR(10) NOT NULL CHECK (value_type IN ('string', 'number', 'boolean')),
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+-- Migration: 031_order_cancellation
+-- Description: Who cancelled an order, when and why
+-- Date: 2024-03-23
+
+-- ============================================================================
+-- ORDER CANCELLATION
+-- ============================================================================
+
+-- Set when an order moves to CANCELLED. cancelled_by is NULL for
+-- cancellations made by the system (e.g. a shortage nobody could fill).
+-- Paid orders are refunded in full; the refund is in payment_events.
+ALTER TABLE orders
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancelled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN cancellation_reason TEXT;
@@ -0,0 +1,33 @@
+-- Migration: 032_menu_item_history
+-- Description: Full menu item snapshots for point-in-time menu lookups
+-- Date: 2024-03-24
+
+-- ============================================================================
+-- MENU_ITEM_HISTORY TABLE
+-- ============================================================================
+
+-- One row per create, update or removal of a menu item, holding the item as
+-- it stood afterwards. Prices go back further in menu_price_history, which
+-- stays the source for what an item cost; this table adds the name,
+-- category and availability.
+CREATE TABLE menu_item_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    menu_item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+
+    name VARCHAR(200) NOT NULL,
+    description TEXT,
+    price INTEGER NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    image_url TEXT,
+    is_available BOOLEAN NOT NULL,
+
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_menu_item_history_item ON menu_item_history(menu_item_id, changed_at);
+
+-- Start every existing item's history now. Earlier details weren't
+-- recorded, so lookups before this point use the item's current details.
+INSERT INTO menu_item_history (menu_item_id, name, description, price, category, image_url, is_available)
+SELECT id, name, description, price, category, image_url, is_available FROM menu_items;
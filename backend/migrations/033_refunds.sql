@@ -0,0 +1,50 @@
+-- Migration: 033_refunds
+-- Description: Refunds against captured payments and their settlement status
+-- Date: 2024-03-25
+
+-- ============================================================================
+-- REFUNDS TABLE
+-- ============================================================================
+
+-- Every refund issued through Razorpay, automatic (cancellations, item
+-- shortages) or by an admin. A row is written before the gateway is called
+-- so concurrent refunds can't together exceed the captured amount; it stays
+-- PENDING until Razorpay's refund.processed or refund.failed webhook.
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    razorpay_payment_id VARCHAR(100) NOT NULL,
+
+    -- NULL until Razorpay accepts the refund; stays NULL if the call failed
+    razorpay_refund_id VARCHAR(100) UNIQUE,
+
+    amount BIGINT NOT NULL,
+
+    -- PENDING, PROCESSED or FAILED
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+
+    -- Why the refund was issued, e.g. 'refund.order_cancelled' or an
+    -- admin's note, and why Razorpay rejected or failed it
+    reason TEXT NOT NULL,
+    failure_reason TEXT,
+
+    -- NULL for automatic refunds
+    initiated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT refunds_status_valid CHECK (status IN ('PENDING', 'PROCESSED', 'FAILED')),
+    CONSTRAINT refunds_amount_positive CHECK (amount > 0)
+);
+
+CREATE INDEX idx_refunds_order ON refunds(order_id, created_at);
+CREATE INDEX idx_refunds_payment ON refunds(razorpay_payment_id) WHERE status <> 'FAILED';
+
+-- Carry over refunds already issued, from the payment audit trail. Their
+-- settlement wasn't tracked; Razorpay accepted them, so count them processed.
+INSERT INTO refunds (order_id, razorpay_payment_id, razorpay_refund_id, amount, status, reason, created_at, updated_at)
+SELECT order_id, razorpay_payment_id, NULLIF(substring(detail FROM 8), ''), amount, 'PROCESSED', event_type, created_at, created_at
+FROM payment_events
+WHERE source = 'REFUND' AND outcome = 'APPLIED'
+    AND order_id IS NOT NULL AND razorpay_payment_id IS NOT NULL AND amount > 0;
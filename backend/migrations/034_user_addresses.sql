@@ -0,0 +1,57 @@
+-- Migration: 034_user_addresses
+-- Description: Saved delivery addresses and the address snapshot on orders
+-- Date: 2024-03-26
+
+-- ============================================================================
+-- USER_ADDRESSES TABLE
+-- ============================================================================
+
+-- A customer's address book. Checkout can pick one of these instead of
+-- sending a free-form drop-off point; the order keeps its own copy, so
+-- editing or deleting an address never changes past orders.
+CREATE TABLE user_addresses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- What the customer calls it, e.g. 'Home' or 'Work'
+    label VARCHAR(30) NOT NULL,
+
+    line1 VARCHAR(200) NOT NULL,
+    line2 VARCHAR(200),
+    landmark VARCHAR(100),
+    city VARCHAR(100),
+    postal_code VARCHAR(10),
+
+    -- Drop-off point used for fees, ETAs and routing
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+
+    -- Shown to the rider, e.g. 'Ring twice, dog is friendly'
+    instructions VARCHAR(200),
+
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_addresses_user ON user_addresses(user_id, created_at);
+
+-- At most one default address per customer
+CREATE UNIQUE INDEX idx_user_addresses_default ON user_addresses(user_id) WHERE is_default;
+
+-- Trigger for user_addresses table
+CREATE TRIGGER trigger_user_addresses_updated_at
+    BEFORE UPDATE ON user_addresses
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- The saved address as it was at checkout, for orders placed with one.
+-- delivery_address/latitude/longitude stay the routing point for all
+-- delivery orders.
+ALTER TABLE orders
+    ADD COLUMN delivery_address_snapshot JSONB;
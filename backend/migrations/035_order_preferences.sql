@@ -0,0 +1,15 @@
+-- Migration: 035_order_preferences
+-- Description: Cutlery and packaging preferences chosen at checkout
+-- Date: 2024-03-27
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- Shown to the kitchen when packing and counted in the monthly
+-- sustainability report. Orders placed before this default to the standard
+-- packaging with cutlery, which is what they got.
+ALTER TABLE orders
+    ADD COLUMN no_cutlery BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN packaging VARCHAR(20) NOT NULL DEFAULT 'STANDARD',
+    ADD CONSTRAINT orders_packaging_valid CHECK (packaging IN ('STANDARD', 'MINIMAL', 'PLASTIC_FREE'));
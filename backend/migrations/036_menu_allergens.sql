@@ -0,0 +1,21 @@
+-- Migration: 036_menu_allergens
+-- Description: Structured ingredients with allergens on menu items, and the allergens a customer acknowledged at checkout
+-- Date: 2024-03-28
+
+-- ============================================================================
+-- MENU_ITEMS
+-- ============================================================================
+
+-- [{"name": "Cashew paste", "allergens": ["tree_nuts"], "may_contain": ["peanuts"]}]
+-- may_contain covers cross-contamination from shared equipment or the
+-- supplier's facility. Allergen warnings are computed from these.
+ALTER TABLE menu_items
+    ADD COLUMN ingredients JSONB NOT NULL DEFAULT '[]';
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- Severe allergens in the cart the customer confirmed they were warned of
+ALTER TABLE orders
+    ADD COLUMN acknowledged_allergens TEXT[];
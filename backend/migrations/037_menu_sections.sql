@@ -0,0 +1,33 @@
+-- Migration: 037_menu_sections
+-- Description: Admin display order for menu items and curated menu sections
+-- Date: 2024-03-29
+
+-- ============================================================================
+-- MENU_ITEMS
+-- ============================================================================
+
+-- Position within the item's category; ties (and new items, at 0) fall back
+-- to name order
+ALTER TABLE menu_items
+    ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+
+DROP INDEX IF EXISTS idx_menu_items_category_available;
+CREATE INDEX idx_menu_items_category_available ON menu_items(category, is_available, sort_order);
+
+-- ============================================================================
+-- MENU_SECTION_ITEMS
+-- ============================================================================
+
+-- Items an admin pinned to a section shown above the categories, in order.
+-- BESTSELLERS falls back to recent top sellers while nothing is pinned.
+CREATE TABLE menu_section_items (
+    section VARCHAR(30) NOT NULL CHECK (section IN ('CHEFS_SPECIALS', 'BESTSELLERS')),
+    menu_item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    pinned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (section, menu_item_id)
+);
+
+CREATE INDEX idx_menu_section_items_position ON menu_section_items(section, position);
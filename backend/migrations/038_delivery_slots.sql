@@ -0,0 +1,31 @@
+-- Migration: 038_delivery_slots
+-- Description: Capacity-limited 30-minute delivery slots customers book at checkout
+-- Date: 2024-03-30
+
+-- ============================================================================
+-- DELIVERY_SLOT_BOOKINGS TABLE
+-- ============================================================================
+
+-- One row per delivery slot with orders booked; incremented atomically in
+-- the order-creation transaction, decremented when a booking is released
+CREATE TABLE delivery_slot_bookings (
+    slot_start TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    booked INTEGER NOT NULL DEFAULT 0,
+
+    CONSTRAINT delivery_slot_bookings_non_negative CHECK (booked >= 0)
+);
+
+-- ============================================================================
+-- ORDER DELIVERY SLOT
+-- ============================================================================
+
+-- The delivery slot the customer chose. delivery_slot_released_at is set
+-- once the booking is given back (cancellation, or an unpaid order's hold
+-- expiring), so it is never released twice.
+ALTER TABLE orders
+    ADD COLUMN delivery_slot_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN delivery_slot_released_at TIMESTAMP WITH TIME ZONE;
+
+-- Unpaid orders still holding a slot, for the expiry sweep
+CREATE INDEX idx_orders_delivery_slot_held ON orders(created_at)
+    WHERE delivery_slot_at IS NOT NULL AND delivery_slot_released_at IS NULL;
@@ -0,0 +1,27 @@
+-- Migration: 039_order_chat
+-- Description: Per-order chat between the customer, the rider and support
+-- Date: 2024-03-31
+
+-- ============================================================================
+-- ORDER_MESSAGES TABLE
+-- ============================================================================
+
+-- One row per chat message. Phone numbers are masked before a body is
+-- stored; messages are deleted after the retention period by the cleanup job.
+CREATE TABLE order_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id),
+    sender_role VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT order_messages_sender_role_check CHECK (sender_role IN ('CUSTOMER', 'RIDER', 'SUPPORT')),
+    CONSTRAINT order_messages_body_length CHECK (char_length(body) BETWEEN 1 AND 1000)
+);
+
+-- A conversation in order, and messages after a polling cursor
+CREATE INDEX idx_order_messages_order ON order_messages(order_id, created_at, id);
+
+-- Retention sweep
+CREATE INDEX idx_order_messages_created ON order_messages(created_at);
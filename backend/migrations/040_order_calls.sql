@@ -0,0 +1,29 @@
+-- Migration: 040_order_calls
+-- Description: Log of masked calls bridged between riders and customers
+-- Date: 2024-04-01
+
+-- ============================================================================
+-- ORDER_CALLS TABLE
+-- ============================================================================
+
+-- One row per call a rider placed about an order. Neither party's number is
+-- stored; the provider only ever shows them its own number.
+CREATE TABLE order_calls (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    rider_id UUID NOT NULL REFERENCES users(id),
+    provider VARCHAR(20) NOT NULL,
+    provider_call_id VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'INITIATED',
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT order_calls_status_check CHECK (status IN (
+        'INITIATED', 'RINGING', 'IN_PROGRESS', 'COMPLETED', 'BUSY', 'NO_ANSWER', 'FAILED', 'CANCELED'
+    ))
+);
+
+-- Call log of an order, and the call still open on it
+CREATE INDEX idx_order_calls_order ON order_calls(order_id, created_at);
@@ -0,0 +1,15 @@
+-- Migration: 041_user_carts
+-- Description: Durable copy of signed-in users' carts behind the Redis cache
+-- Date: 2024-04-02
+
+-- ============================================================================
+-- USER_CARTS TABLE
+-- ============================================================================
+
+-- One row per user with a saved cart. Redis caches it for reads; this table
+-- keeps carts through Redis restarts and evictions.
+CREATE TABLE user_carts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    items JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
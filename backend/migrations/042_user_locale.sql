@@ -0,0 +1,13 @@
+-- Migration: 042_user_locale
+-- Description: Per-user language for SMS, push and email templates
+-- Date: 2024-04-03
+
+-- ============================================================================
+-- USERS
+-- ============================================================================
+
+-- locale is chosen by the user and always wins; detected_locale is taken
+-- from the app's Accept-Language at login. Both are like "hi" or "hi-IN".
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(10),
+    ADD COLUMN detected_locale VARCHAR(10);
@@ -0,0 +1,26 @@
+-- Migration: 043_order_charges
+-- Description: Subtotal, packaging charge and GST breakdown of order totals
+-- Date: 2024-04-04
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- total_amount is now subtotal + delivery_fee + packaging_charge + tax_amount.
+-- tax_rate_basis_points keeps the GST rate the order was charged at
+-- (500 = 5%), so partial refunds return the GST actually paid.
+ALTER TABLE orders
+    ADD COLUMN subtotal BIGINT,
+    ADD COLUMN packaging_charge BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_rate_basis_points INTEGER NOT NULL DEFAULT 0;
+
+-- Existing orders were items plus the delivery fee
+UPDATE orders SET subtotal = total_amount - delivery_fee;
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal SET NOT NULL,
+    ADD CONSTRAINT orders_charges_non_negative
+        CHECK (subtotal >= 0 AND packaging_charge >= 0 AND tax_amount >= 0 AND tax_rate_basis_points >= 0),
+    ADD CONSTRAINT orders_total_matches_charges
+        CHECK (total_amount = subtotal + delivery_fee + packaging_charge + tax_amount);
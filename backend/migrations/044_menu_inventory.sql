@@ -0,0 +1,25 @@
+-- Migration: 044_menu_inventory
+-- Description: Stock tracking for menu items, reserved at checkout
+-- Date: 2024-04-05
+
+-- ============================================================================
+-- MENU ITEMS
+-- ============================================================================
+
+-- NULL means the item isn't stock-tracked and never sells out. 0 is sold
+-- out: still listed (unlike is_available = FALSE) but not orderable.
+ALTER TABLE menu_items
+    ADD COLUMN stock_quantity INTEGER,
+    ADD CONSTRAINT menu_items_stock_quantity_check CHECK (stock_quantity >= 0);
+
+-- ============================================================================
+-- ORDERS
+-- ============================================================================
+
+-- RESERVED once checkout took the order's items out of stock, RELEASED once
+-- they were given back (cancelled, payment failed), so a cancellation racing
+-- a payment failure restores stock once, and a payment that succeeds on a
+-- retry takes it again. NULL when no item in the order is tracked.
+ALTER TABLE orders
+    ADD COLUMN stock_status VARCHAR(10)
+        CHECK (stock_status IN ('RESERVED', 'RELEASED'));
@@ -0,0 +1,51 @@
+-- Migration: 045_menu_variants
+-- Description: Menu item variants (sizes) and modifiers (add-ons), snapshotted on order items
+-- Date: 2024-04-06
+
+-- ============================================================================
+-- MENU_ITEM_VARIANTS TABLE
+-- ============================================================================
+
+-- Sizes or portions of an item, each with its own price. An item with
+-- available variants is ordered as one of them; its own price is unused.
+CREATE TABLE menu_item_variants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    menu_item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    price BIGINT NOT NULL CHECK (price > 0),
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_menu_item_variants_item ON menu_item_variants(menu_item_id, sort_order);
+
+-- ============================================================================
+-- MENU_ITEM_MODIFIERS TABLE
+-- ============================================================================
+
+-- Add-ons chosen on top of an item (or its variant), each at most once per
+-- line; the price is added to the unit price
+CREATE TABLE menu_item_modifiers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    menu_item_id UUID NOT NULL REFERENCES menu_items(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    price BIGINT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    is_available BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_menu_item_modifiers_item ON menu_item_modifiers(menu_item_id, sort_order);
+
+-- ============================================================================
+-- ORDER_ITEMS
+-- ============================================================================
+
+-- What was chosen, as it was named and priced at checkout; no foreign keys,
+-- so removing a variant or modifier from the menu leaves orders intact.
+-- price stays the full unit price including the variant and modifiers.
+ALTER TABLE order_items
+    ADD COLUMN variant_id UUID,
+    ADD COLUMN variant_name VARCHAR(100),
+    ADD COLUMN modifiers JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,33 @@
+-- Migration: 046_user_order_stats
+-- Description: Per-customer order count, spend and recency for churn flags and segments
+-- Date: 2024-04-07
+
+-- ============================================================================
+-- USER_ORDER_STATS TABLE
+-- ============================================================================
+
+-- One row per customer with a paid order, updated in the transaction that
+-- marks an order paid (or imports one) and when a refund is processed.
+-- Test orders are never counted. total_spend is net of processed refunds.
+CREATE TABLE user_order_stats (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    order_count INTEGER NOT NULL CHECK (order_count >= 0),
+    total_spend BIGINT NOT NULL,
+    first_order_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_order_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Segments filter on recency first
+CREATE INDEX idx_user_order_stats_last_order ON user_order_stats(last_order_at);
+
+-- Backfill from the orders paid so far
+INSERT INTO user_order_stats (user_id, order_count, total_spend, first_order_at, last_order_at)
+SELECT o.user_id, COUNT(*), SUM(o.total_amount) - COALESCE(SUM(r.refunded), 0), MIN(o.created_at), MAX(o.created_at)
+FROM orders o
+LEFT JOIN (
+    SELECT order_id, SUM(amount) AS refunded FROM refunds WHERE status = 'PROCESSED' GROUP BY order_id
+) r ON r.order_id = o.id
+WHERE o.status IN ('PAID', 'ACCEPTED', 'OUT_FOR_DELIVERY', 'DELIVERED')
+    AND NOT ('test-order' = ANY(o.tags))
+GROUP BY o.user_id;
@@ -0,0 +1,31 @@
+-- Migration: 047_webhook_retries
+-- Description: Replay of webhooks whose processing failed, with backoff
+-- Date: 2024-04-08
+
+-- ============================================================================
+-- WEBHOOK_LOGS
+-- ============================================================================
+
+-- A webhook whose handler failed (database error, gateway timeout) is
+-- scheduled for replay at next_attempt_at. Each replay updates the same
+-- row; once replays run out it is given up on at failed_at. Rows logged
+-- with a final outcome (order not found, payment mismatch) are never
+-- scheduled.
+ALTER TABLE webhook_logs
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN next_attempt_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN failed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE;
+
+-- The retry worker polls for due rows
+CREATE INDEX idx_webhook_logs_next_attempt ON webhook_logs(next_attempt_at)
+    WHERE next_attempt_at IS NOT NULL;
+
+-- Failures from the last week that aren't final outcomes get replayed
+UPDATE webhook_logs
+SET next_attempt_at = NOW()
+WHERE NOT processed
+    AND signature_valid
+    AND event_type IN ('payment.captured', 'payment.failed', 'refund.processed', 'refund.failed')
+    AND processing_error NOT IN ('order not found', 'refund not found', 'payment mismatch', 'order cancelled')
+    AND created_at > NOW() - INTERVAL '7 days';
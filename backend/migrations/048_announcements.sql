@@ -0,0 +1,49 @@
+-- Migration: 048_announcements
+-- Description: In-app banners with a validity window and audience, and their impressions and clicks
+-- Date: 2024-04-09
+
+-- ============================================================================
+-- ANNOUNCEMENTS TABLE
+-- ============================================================================
+
+-- A banner is shown between starts_at and ends_at (open-ended when NULL)
+-- to its audience, highest priority first. Admins pause one by clearing
+-- is_active.
+CREATE TABLE announcements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(100) NOT NULL,
+    body VARCHAR(500),
+    image_url VARCHAR(500),
+    deep_link VARCHAR(500),
+    audience VARCHAR(30) NOT NULL DEFAULT 'ALL'
+        CHECK (audience IN ('ALL', 'GUESTS', 'NEW_CUSTOMERS', 'RETURNING_CUSTOMERS', 'LAPSED_CUSTOMERS')),
+    priority INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- The app's fetch loads what is live or still to come
+CREATE INDEX idx_announcements_live ON announcements(ends_at) WHERE is_active;
+
+CREATE TRIGGER update_announcements_updated_at
+    BEFORE UPDATE ON announcements
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- ANNOUNCEMENT_STATS TABLE
+-- ============================================================================
+
+-- Impressions and clicks reported by the app, counted per day
+CREATE TABLE announcement_stats (
+    announcement_id UUID NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    impressions BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (announcement_id, day)
+);
@@ -0,0 +1,31 @@
+-- Migration: 049_order_events
+-- Description: Outbox of order events, written with each status change and relayed to a sink
+-- Date: 2024-04-10
+
+-- ============================================================================
+-- ORDER_EVENTS TABLE
+-- ============================================================================
+
+-- One row per order created or status change, inserted in the transaction
+-- that made the change, so an event exists exactly when the change
+-- committed. The relay publishes rows in id order and stamps published_at;
+-- failed publishes count attempts and keep the last error. Published rows
+-- are deleted by the cleanup job after a retention period.
+CREATE TABLE order_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- The relay reads what is left to publish
+CREATE INDEX idx_order_events_unpublished ON order_events(id) WHERE published_at IS NULL;
+
+-- Cleanup deletes published rows by age
+CREATE INDEX idx_order_events_published ON order_events(published_at) WHERE published_at IS NOT NULL;
+
+CREATE INDEX idx_order_events_order ON order_events(order_id);
@@ -0,0 +1,46 @@
+-- Migration: 050_order_issues
+-- Description: Problems customers report after delivery, resolved automatically within limits or escalated to staff
+-- Date: 2024-04-11
+
+-- ============================================================================
+-- ORDER_ISSUES TABLE
+-- ============================================================================
+
+-- A customer reports one issue per delivered order, within a window after
+-- delivery. Issues the compensation rules cover are AUTO_RESOLVED with a
+-- partial refund; the rest (over the limits, "other", failed refunds) are
+-- ESCALATED for staff, who close them as RESOLVED with or without a refund.
+CREATE TABLE order_issues (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issue_type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+
+    -- [{order_item_id, name, quantity, amount}] for missing and wrong items
+    items JSONB NOT NULL DEFAULT '[]',
+    description TEXT,
+
+    -- What the rules work out, and what was refunded (paisa)
+    remedy_amount BIGINT NOT NULL DEFAULT 0,
+    refund_amount BIGINT NOT NULL DEFAULT 0,
+    razorpay_refund_id VARCHAR(100),
+    refund_error TEXT,
+
+    escalation_reason TEXT,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL when resolved automatically
+    resolution_note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT order_issues_type_valid CHECK (issue_type IN ('MISSING_ITEMS', 'WRONG_ITEMS', 'COLD_FOOD', 'OTHER')),
+    CONSTRAINT order_issues_status_valid CHECK (status IN ('AUTO_RESOLVED', 'ESCALATED', 'RESOLVED')),
+    CONSTRAINT order_issues_amounts_valid CHECK (remedy_amount >= 0 AND refund_amount >= 0)
+);
+
+CREATE UNIQUE INDEX idx_order_issues_order ON order_issues(order_id);
+
+-- The staff queue, and the per-customer monthly cap on automatic remedies
+CREATE INDEX idx_order_issues_escalated ON order_issues(created_at) WHERE status = 'ESCALATED';
+CREATE INDEX idx_order_issues_user ON order_issues(user_id, created_at);
@@ -0,0 +1,58 @@
+-- Migration: 051_prep_stations
+-- Description: Kitchen prep stations fed by menu category, with per-station tickets for accepted orders
+-- Date: 2024-04-12
+
+-- ============================================================================
+-- PREP_STATIONS TABLE
+-- ============================================================================
+
+-- A station (grill, fryer, beverages) prepares the menu categories mapped
+-- to it. Items in unmapped categories go to the default station, if one is
+-- set, and are otherwise left to the pass.
+CREATE TABLE prep_stations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(50) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_prep_stations_name ON prep_stations(LOWER(name));
+CREATE UNIQUE INDEX idx_prep_stations_default ON prep_stations(is_default) WHERE is_default;
+
+-- Each category is prepared at one station at most
+CREATE TABLE prep_station_categories (
+    category VARCHAR(100) PRIMARY KEY,
+    station_id UUID NOT NULL REFERENCES prep_stations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_prep_station_categories_station ON prep_station_categories(station_id);
+
+-- ============================================================================
+-- STATION_TICKETS TABLE
+-- ============================================================================
+
+-- When an order is accepted its items are split into one ticket per
+-- station. Stations move their tickets QUEUED -> PREPARING -> READY; the
+-- order is ready for the pass once every ticket is.
+CREATE TABLE station_tickets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    station_id UUID NOT NULL REFERENCES prep_stations(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'QUEUED',
+
+    -- [{order_item_id, name, variant_name, modifiers, quantity}]
+    items JSONB NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    ready_at TIMESTAMP WITH TIME ZONE,
+    ready_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    CONSTRAINT station_tickets_status_valid CHECK (status IN ('QUEUED', 'PREPARING', 'READY')),
+    CONSTRAINT station_tickets_order_station UNIQUE (order_id, station_id)
+);
+
+-- Station queues list open tickets oldest first
+CREATE INDEX idx_station_tickets_open ON station_tickets(station_id, created_at) WHERE status <> 'READY';
@@ -0,0 +1,21 @@
+-- Migration: 052_stock_alerts
+-- Description: Low-stock thresholds per menu item, with alert state and automatic disabling at zero
+-- Date: 2024-04-13
+
+-- ============================================================================
+-- MENU ITEMS
+-- ============================================================================
+
+-- low_stock_threshold: admins are alerted once stock is at or below it;
+-- NULL uses the inventory.low_stock_threshold setting.
+-- stock_alert: the last level admins were alerted about (LOW or OUT), so
+-- each drop alerts once; cleared when the item is restocked.
+-- stock_disabled: is_available was switched off because the item sold
+-- out, and is switched back on when it is restocked. An admin setting the
+-- availability by hand clears it.
+ALTER TABLE menu_items
+    ADD COLUMN low_stock_threshold INTEGER,
+    ADD COLUMN stock_alert VARCHAR(3),
+    ADD COLUMN stock_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD CONSTRAINT menu_items_low_stock_threshold_check CHECK (low_stock_threshold >= 0),
+    ADD CONSTRAINT menu_items_stock_alert_check CHECK (stock_alert IN ('LOW', 'OUT'));
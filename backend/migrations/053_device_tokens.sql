@@ -0,0 +1,20 @@
+-- Migration: 053_device_tokens
+-- Description: FCM registration tokens of users' devices, for push notifications
+-- Date: 2024-04-14
+
+-- ============================================================================
+-- DEVICE_TOKENS TABLE
+-- ============================================================================
+
+-- A token belongs to one app install. It moves to whoever registers it
+-- last (another account signed in on the device), and is deleted when the
+-- app unregisters it or FCM reports it unregistered.
+CREATE TABLE device_tokens (
+    token VARCHAR(4096) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('android', 'ios', 'web')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_tokens_user ON device_tokens(user_id, last_seen_at DESC);
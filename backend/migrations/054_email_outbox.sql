@@ -0,0 +1,31 @@
+-- Migration: 054_email_outbox
+-- Description: Queue of transactional emails sent by a background worker
+-- Date: 2024-04-15
+
+-- ============================================================================
+-- EMAIL_OUTBOX TABLE
+-- ============================================================================
+
+-- One row per email owed for an order (confirmation, receipt), queued
+-- where the order changed and sent by the email worker, so checkout never
+-- waits on the mail provider. The email is rendered when it is sent. A
+-- failed send is retried at next_attempt_at; once attempts run out, or
+-- the failure is final, the row is given up on at failed_at.
+CREATE TABLE email_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    template_key VARCHAR(100) NOT NULL,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    -- An order gets each email once, however often its hook fires
+    CONSTRAINT uq_email_outbox_order_template UNIQUE (order_id, template_key)
+);
+
+-- The worker polls for due rows
+CREATE INDEX idx_email_outbox_next_attempt ON email_outbox(next_attempt_at)
+    WHERE next_attempt_at IS NOT NULL;
@@ -0,0 +1,177 @@
+package attestation
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Firebase App Check endpoints
+const (
+	AppCheckJWKSURL     = "https://firebaseappcheck.googleapis.com/v1/jwks"
+	appCheckIssuerBase  = "https://firebaseappcheck.googleapis.com/"
+	defaultAppCheckKeys = 6 * time.Hour
+)
+
+// AppCheckVerifier validates Firebase App Check tokens for a project.
+// App IDs restrict which registered apps (Android/iOS) are accepted.
+type AppCheckVerifier struct {
+	projectNumber string
+	appIDs        map[string]bool
+	jwksURL       string
+	httpClient    *http.Client
+
+	mu         sync.RWMutex
+	keys       map[string]*rsa.PublicKey
+	keysExpiry time.Time
+}
+
+// NewAppCheckVerifier creates a verifier for the given Firebase project number.
+// An empty appIDs list accepts any app registered in the project.
+func NewAppCheckVerifier(projectNumber string, appIDs []string) *AppCheckVerifier {
+	allowed := make(map[string]bool, len(appIDs))
+	for _, id := range appIDs {
+		allowed[id] = true
+	}
+
+	return &AppCheckVerifier{
+		projectNumber: projectNumber,
+		appIDs:        allowed,
+		jwksURL:       AppCheckJWKSURL,
+		httpClient:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify implements Verifier
+func (v *AppCheckVerifier) Verify(ctx context.Context, token string) (*Verdict, error) {
+	if token == "" {
+		return &Verdict{Reason: ErrMissingToken.Error()}, nil
+	}
+
+	var keyErr error
+	claims := &jwt.RegisteredClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			keyErr = err
+		}
+		return key, err
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(appCheckIssuerBase+v.projectNumber),
+		jwt.WithAudience("projects/"+v.projectNumber),
+	)
+	if keyErr != nil && !errors.Is(keyErr, errUnknownKey) {
+		return nil, keyErr
+	}
+	if err != nil || !parsed.Valid {
+		return &Verdict{Reason: ErrInvalidToken.Error()}, nil
+	}
+
+	// The subject is the Firebase app ID of the attested app
+	if len(v.appIDs) > 0 && !v.appIDs[claims.Subject] {
+		return &Verdict{AppID: claims.Subject, Reason: ErrUnknownApp.Error()}, nil
+	}
+
+	return &Verdict{
+		Valid:     true,
+		AppID:     claims.Subject,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// key returns the public key for kid, refreshing the cached key set when it
+// is stale or the kid is unknown
+func (v *AppCheckVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Now().Before(v.keysExpiry)
+	v.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := v.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// refreshKeys downloads the current App Check signing keys
+func (v *AppCheckVerifier) refreshKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build jwks request: %w", err)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch app check jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("app check jwks returned status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode app check jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return fmt.Errorf("failed to parse key %s: %w", k.Kid, err)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return fmt.Errorf("failed to parse key %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.keysExpiry = time.Now().Add(defaultAppCheckKeys)
+	v.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,62 @@
+// Package attestation verifies that requests originate from a genuine,
+// untampered build of our mobile apps (Play Integrity on Android, App Attest
+// on iOS). Platform verdicts are exchanged by the client for a short-lived
+// Firebase App Check token, which is what the API verifies.
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HeaderName carries the attestation token on protected requests
+const HeaderName = "X-Firebase-AppCheck"
+
+// Mode controls how attestation failures are handled
+type Mode string
+
+const (
+	// ModeOff skips attestation entirely
+	ModeOff Mode = "off"
+	// ModeMonitor verifies and records metrics but never blocks a request
+	ModeMonitor Mode = "monitor"
+	// ModeEnforce rejects requests without a valid attestation
+	ModeEnforce Mode = "enforce"
+)
+
+// ParseMode converts a config value to a Mode
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ModeOff, ModeMonitor, ModeEnforce:
+		return m, nil
+	case "":
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("unknown attestation mode %q", s)
+	}
+}
+
+// Verification errors
+var (
+	ErrMissingToken = errors.New("attestation token missing")
+	ErrInvalidToken = errors.New("invalid attestation token")
+	ErrUnknownApp   = errors.New("attestation issued for unknown app")
+)
+
+// Verdict is the outcome of verifying an attestation token
+type Verdict struct {
+	Valid     bool      `json:"valid"`
+	AppID     string    `json:"app_id,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Verifier checks an attestation token. Implementations return a verdict
+// for tokens that are well-formed but rejected, and an error only when
+// verification itself could not be performed (e.g. key fetch failure).
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Verdict, error)
+}
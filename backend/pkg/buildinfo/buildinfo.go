@@ -0,0 +1,80 @@
+// Package buildinfo exposes the version the binary was built from. Values
+// are stamped at build time:
+//
+//	go build -ldflags "-X fooddelivery/pkg/buildinfo.Version=v1.4.0 -X fooddelivery/pkg/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// When not stamped, the VCS details Go embeds in module builds are used.
+package buildinfo
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Set via -ldflags -X
+var (
+	Version   = ""
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a dirty tree
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info, read once
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			info.GoVersion = bi.GoVersion
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = s.Value
+					}
+				case "vcs.modified":
+					info.Modified = s.Value == "true"
+				}
+			}
+		}
+
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		if info.Commit == "" {
+			info.Commit = "unknown"
+		}
+	})
+	return info
+}
+
+// Release identifies the build for error reports and logs: the version,
+// suffixed with the short commit when known
+func Release() string {
+	i := Get()
+	if i.Commit == "unknown" {
+		return i.Version
+	}
+	commit := i.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return i.Version + "+" + commit
+}
@@ -0,0 +1,124 @@
+// Package calls bridges phone calls between two people through a
+// call-masking provider, so each only sees the provider's number.
+package calls
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/sms"
+)
+
+// Status is where a bridged call is in its lifecycle
+type Status string
+
+const (
+	StatusInitiated  Status = "INITIATED"
+	StatusRinging    Status = "RINGING"
+	StatusInProgress Status = "IN_PROGRESS"
+	StatusCompleted  Status = "COMPLETED"
+	StatusBusy       Status = "BUSY"
+	StatusNoAnswer   Status = "NO_ANSWER"
+	StatusFailed     Status = "FAILED"
+	StatusCanceled   Status = "CANCELED"
+)
+
+// Ended reports whether a call in status s is over
+func (s Status) Ended() bool {
+	switch s {
+	case StatusCompleted, StatusBusy, StatusNoAnswer, StatusFailed, StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// ErrInvalidCallback is returned for status callbacks missing the call
+// ID or status
+var ErrInvalidCallback = errors.New("invalid call status callback")
+
+// BridgeRequest asks for From to be rung and, once they answer, connected
+// to To
+type BridgeRequest struct {
+	From              string
+	To                string
+	MaxDuration       time.Duration // Call is cut off after this; 0 = provider default
+	StatusCallbackURL string        // Where the provider posts the final status; optional
+}
+
+// Call is a bridged call as the provider reported it
+type Call struct {
+	ProviderCallID string
+	Status         Status
+}
+
+// StatusUpdate is a call's status from a provider callback
+type StatusUpdate struct {
+	ProviderCallID string
+	Status         Status
+	Duration       time.Duration // Time the two were connected
+}
+
+// Bridger places masked calls
+type Bridger interface {
+	Bridge(ctx context.Context, req BridgeRequest) (*Call, error)
+	ParseStatusCallback(form url.Values) (*StatusUpdate, error)
+}
+
+// LogBridger records that a call would have been placed instead of placing
+// it. Used until a provider is configured.
+type LogBridger struct {
+	log *logger.Logger
+}
+
+// NewLogBridger creates a bridger that only logs
+func NewLogBridger(log *logger.Logger) *LogBridger {
+	return &LogBridger{log: log}
+}
+
+// Bridge implements Bridger
+func (b *LogBridger) Bridge(_ context.Context, req BridgeRequest) (*Call, error) {
+	b.log.Info("Masked call (log only)", "from", sms.MaskNumber(req.From), "to", sms.MaskNumber(req.To))
+	return &Call{Status: StatusCompleted}, nil
+}
+
+// ParseStatusCallback implements Bridger; nothing calls back without a
+// provider
+func (b *LogBridger) ParseStatusCallback(url.Values) (*StatusUpdate, error) {
+	return nil, ErrInvalidCallback
+}
+
+// parseSeconds reads a whole number of seconds, 0 if missing or invalid
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// providerStatus maps the lower-case statuses Exotel and Twilio share
+func providerStatus(status string) (Status, bool) {
+	switch status {
+	case "queued", "initiated":
+		return StatusInitiated, true
+	case "ringing":
+		return StatusRinging, true
+	case "in-progress":
+		return StatusInProgress, true
+	case "completed":
+		return StatusCompleted, true
+	case "busy":
+		return StatusBusy, true
+	case "no-answer":
+		return StatusNoAnswer, true
+	case "failed":
+		return StatusFailed, true
+	case "canceled":
+		return StatusCanceled, true
+	}
+	return "", false
+}
@@ -0,0 +1,118 @@
+package calls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+	"fooddelivery/pkg/sms"
+)
+
+// ExotelConfig holds Exotel credentials. CallerID is the ExoPhone both
+// sides see the call coming from.
+type ExotelConfig struct {
+	AccountSID         string
+	APIKey             string
+	APIToken           string
+	CallerID           string
+	DefaultCountryCode string // Added to 10-digit numbers, e.g. "91"
+	BaseURL            string // Defaults to https://api.exotel.com; the Singapore cluster is https://api.in.exotel.com
+}
+
+// ExotelBridger bridges calls with Exotel's connect two numbers API
+type ExotelBridger struct {
+	cfg        ExotelConfig
+	httpClient *http.Client
+}
+
+// NewExotelBridger creates an Exotel bridger
+func NewExotelBridger(cfg ExotelConfig, timeout time.Duration) *ExotelBridger {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.exotel.com"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &ExotelBridger{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// exotelResponse is the connect API's answer
+type exotelResponse struct {
+	Call struct {
+		Sid    string `json:"Sid"`
+		Status string `json:"Status"`
+	} `json:"Call"`
+	RestException struct {
+		Message string `json:"Message"`
+	} `json:"RestException"`
+}
+
+// Bridge implements Bridger. Exotel rings From, then dials To when they
+// pick up.
+func (b *ExotelBridger) Bridge(ctx context.Context, req BridgeRequest) (*Call, error) {
+	form := url.Values{}
+	form.Set("From", sms.E164(req.From, b.cfg.DefaultCountryCode))
+	form.Set("To", sms.E164(req.To, b.cfg.DefaultCountryCode))
+	form.Set("CallerId", b.cfg.CallerID)
+	if req.MaxDuration > 0 {
+		form.Set("TimeLimit", strconv.Itoa(int(req.MaxDuration.Seconds())))
+	}
+	if req.StatusCallbackURL != "" {
+		form.Set("StatusCallback", req.StatusCallbackURL)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/Accounts/%s/Calls/connect.json", b.cfg.BaseURL, url.PathEscape(b.cfg.AccountSID))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(b.cfg.APIKey, b.cfg.APIToken)
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("exotel request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result exotelResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &retry.StatusError{Service: "exotel", StatusCode: resp.StatusCode}
+		if decodeErr == nil && result.RestException.Message != "" {
+			return nil, fmt.Errorf("%w: %s", statusErr, result.RestException.Message)
+		}
+		return nil, statusErr
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode exotel response: %w", decodeErr)
+	}
+
+	status, ok := providerStatus(result.Call.Status)
+	if !ok {
+		status = StatusInitiated
+	}
+	return &Call{ProviderCallID: result.Call.Sid, Status: status}, nil
+}
+
+// ParseStatusCallback implements Bridger for Exotel's StatusCallback, which
+// is posted once the call ends
+func (b *ExotelBridger) ParseStatusCallback(form url.Values) (*StatusUpdate, error) {
+	status, ok := providerStatus(form.Get("Status"))
+	if !ok || form.Get("CallSid") == "" {
+		return nil, ErrInvalidCallback
+	}
+	return &StatusUpdate{
+		ProviderCallID: form.Get("CallSid"),
+		Status:         status,
+		Duration:       parseSeconds(form.Get("ConversationDuration")),
+	}, nil
+}
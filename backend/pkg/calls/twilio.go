@@ -0,0 +1,124 @@
+package calls
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+	"fooddelivery/pkg/sms"
+)
+
+// TwilioConfig holds Twilio credentials. CallerID is a voice-capable
+// Twilio number both sides see the call coming from.
+type TwilioConfig struct {
+	AccountSID         string
+	AuthToken          string
+	CallerID           string
+	DefaultCountryCode string // Added to 10-digit numbers, e.g. "91"
+	BaseURL            string // Defaults to https://api.twilio.com
+}
+
+// TwilioBridger bridges calls with Twilio's Calls API: it calls From and,
+// once they answer, dials To from the Twilio number
+type TwilioBridger struct {
+	cfg        TwilioConfig
+	httpClient *http.Client
+}
+
+// NewTwilioBridger creates a Twilio bridger
+func NewTwilioBridger(cfg TwilioConfig, timeout time.Duration) *TwilioBridger {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.twilio.com"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &TwilioBridger{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// twilioCall is the Calls API's answer
+type twilioCall struct {
+	Sid     string `json:"sid"`
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Bridge implements Bridger
+func (b *TwilioBridger) Bridge(ctx context.Context, req BridgeRequest) (*Call, error) {
+	to := sms.E164(req.To, b.cfg.DefaultCountryCode)
+	var twiml strings.Builder
+	twiml.WriteString(`<Response><Dial callerId="`)
+	xml.EscapeText(&twiml, []byte(b.cfg.CallerID))
+	twiml.WriteString(`"`)
+	if req.MaxDuration > 0 {
+		twiml.WriteString(` timeLimit="` + strconv.Itoa(int(req.MaxDuration.Seconds())) + `"`)
+	}
+	twiml.WriteString(`><Number>`)
+	xml.EscapeText(&twiml, []byte(to))
+	twiml.WriteString(`</Number></Dial></Response>`)
+
+	form := url.Values{}
+	form.Set("To", sms.E164(req.From, b.cfg.DefaultCountryCode))
+	form.Set("From", b.cfg.CallerID)
+	form.Set("Twiml", twiml.String())
+	if req.StatusCallbackURL != "" {
+		form.Set("StatusCallback", req.StatusCallbackURL)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Calls.json", b.cfg.BaseURL, url.PathEscape(b.cfg.AccountSID))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(b.cfg.AccountSID, b.cfg.AuthToken)
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := b.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result twilioCall
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &retry.StatusError{Service: "twilio", StatusCode: resp.StatusCode}
+		if decodeErr == nil && result.Code != 0 {
+			return nil, fmt.Errorf("%w: error %d: %s", statusErr, result.Code, result.Message)
+		}
+		return nil, statusErr
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode twilio response: %w", decodeErr)
+	}
+
+	status, ok := providerStatus(result.Status)
+	if !ok {
+		status = StatusInitiated
+	}
+	return &Call{ProviderCallID: result.Sid, Status: status}, nil
+}
+
+// ParseStatusCallback implements Bridger for Twilio's StatusCallback, which
+// is posted when the call completes
+func (b *TwilioBridger) ParseStatusCallback(form url.Values) (*StatusUpdate, error) {
+	status, ok := providerStatus(form.Get("CallStatus"))
+	if !ok || form.Get("CallSid") == "" {
+		return nil, ErrInvalidCallback
+	}
+	return &StatusUpdate{
+		ProviderCallID: form.Get("CallSid"),
+		Status:         status,
+		Duration:       parseSeconds(form.Get("CallDuration")),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+)
+
+// Health check timing
+const (
+	healthCheckInterval = 30 * time.Second
+	healthPingTimeout   = 5 * time.Second
+)
+
+// reconnectBackoff spaces reconnection attempts during an outage
+var reconnectBackoff = retry.Policy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// RunHealthChecker pings the database periodically and, on failure, retries
+// with exponential backoff until it answers again, keeping IsHealthy up to
+// date. pgxpool re-dials connections on its own; this tracks availability
+// for health endpoints. Runs until ctx is cancelled; meant to be run as a
+// supervised worker.
+func (p *Pool) RunHealthChecker(ctx context.Context) error {
+	m := &healthMonitor{
+		ping:       p.Pool.Ping,
+		setHealthy: p.setHealthy,
+		interval:   healthCheckInterval,
+		backoff:    reconnectBackoff,
+		after:      time.After,
+		log:        p.log,
+	}
+	return m.run(ctx)
+}
+
+func (p *Pool) setHealthy(healthy bool) {
+	p.mu.Lock()
+	p.isHealthy = healthy
+	p.mu.Unlock()
+}
+
+// healthMonitor holds the health check loop, with its dependencies
+// injectable for tests
+type healthMonitor struct {
+	ping       func(ctx context.Context) error
+	setHealthy func(healthy bool)
+	interval   time.Duration
+	backoff    retry.Policy
+	after      func(d time.Duration) <-chan time.Time
+	log        *logger.Logger
+}
+
+// run checks health every interval until ctx is cancelled
+func (m *healthMonitor) run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.after(m.interval):
+		}
+
+		if err := m.pingOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			m.setHealthy(false)
+			m.log.Error("Database health check failed", "error", err)
+
+			if err := m.reconnect(ctx); err != nil {
+				return nil // cancelled during the outage
+			}
+			continue
+		}
+		m.setHealthy(true)
+	}
+}
+
+// reconnect retries the ping with exponential backoff until it succeeds,
+// marking the pool healthy again. Returns ctx.Err() if cancelled first.
+func (m *healthMonitor) reconnect(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		wait := m.backoff.Backoff(attempt)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.after(wait):
+		}
+
+		if err := m.pingOnce(ctx); err == nil {
+			m.setHealthy(true)
+			m.log.Info("Database connection restored", "attempts", attempt)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		m.log.Warn("Database reconnection attempt failed",
+			"attempt", attempt,
+			"next_retry_in", m.backoff.Backoff(attempt+1).String())
+	}
+}
+
+func (m *healthMonitor) pingOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	return m.ping(ctx)
+}
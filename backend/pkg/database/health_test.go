@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+)
+
+// fakeClock fires every wait immediately and records the requested durations
+type fakeClock struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func newTestMonitor(ping func(ctx context.Context) error, clock *fakeClock) (*healthMonitor, *[]bool) {
+	var states []bool
+	return &healthMonitor{
+		ping:       ping,
+		setHealthy: func(h bool) { states = append(states, h) },
+		interval:   30 * time.Second,
+		backoff:    retry.Policy{InitialBackoff: time.Second, MaxBackoff: 8 * time.Second},
+		after:      clock.after,
+		log:        logger.NewLogger(),
+	}, &states
+}
+
+func TestReconnectBacksOffUntilPingSucceeds(t *testing.T) {
+	failures := 5
+	calls := 0
+	ping := func(context.Context) error {
+		calls++
+		if calls <= failures {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	clock := &fakeClock{}
+	m, states := newTestMonitor(ping, clock)
+
+	if err := m.reconnect(context.Background()); err != nil {
+		t.Fatalf("reconnect returned %v, want nil", err)
+	}
+
+	if calls != failures+1 {
+		t.Errorf("ping called %d times, want %d", calls, failures+1)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second, 8 * time.Second}
+	if len(clock.waits) != len(want) {
+		t.Fatalf("waited %v, want %v", clock.waits, want)
+	}
+	for i := range want {
+		if clock.waits[i] != want[i] {
+			t.Errorf("wait %d = %v, want %v", i+1, clock.waits[i], want[i])
+		}
+	}
+
+	if len(*states) != 1 || !(*states)[0] {
+		t.Errorf("health states = %v, want [true]", *states)
+	}
+}
+
+func TestReconnectStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	ping := func(context.Context) error {
+		calls++
+		if calls == 3 {
+			cancel()
+		}
+		return errors.New("connection refused")
+	}
+
+	m, states := newTestMonitor(ping, &fakeClock{})
+
+	done := make(chan error, 1)
+	go func() { done <- m.reconnect(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("reconnect returned %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reconnect did not return after cancellation")
+	}
+
+	if len(*states) != 0 {
+		t.Errorf("health states = %v, want none", *states)
+	}
+}
+
+func TestRunMarksUnhealthyThenRecovers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// healthy, down for two reconnect attempts, back up, then stop
+	results := []error{nil, errors.New("down"), errors.New("down"), errors.New("down"), nil}
+	calls := 0
+	ping := func(context.Context) error {
+		if calls >= len(results) {
+			cancel()
+			return nil
+		}
+		err := results[calls]
+		calls++
+		return err
+	}
+
+	m, states := newTestMonitor(ping, &fakeClock{})
+
+	done := make(chan error, 1)
+	go func() { done <- m.run(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run returned %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not return after cancellation")
+	}
+
+	want := []bool{true, false, true}
+	got := *states
+	if len(got) < len(want) {
+		t.Fatalf("health states = %v, want prefix %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("health states = %v, want prefix %v", got, want)
+		}
+	}
+}
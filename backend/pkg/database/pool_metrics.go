@@ -0,0 +1,116 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"fooddelivery/pkg/metrics"
+)
+
+// RegisterMetrics exposes pool statistics on r, read at scrape time
+func (p *Pool) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("db_pool_acquired_conns", "Connections currently checked out of the pool",
+		func() float64 { return float64(p.Stat().AcquiredConns()) })
+	r.NewGaugeFunc("db_pool_idle_conns", "Idle connections in the pool",
+		func() float64 { return float64(p.Stat().IdleConns()) })
+	r.NewGaugeFunc("db_pool_constructing_conns", "Connections being established",
+		func() float64 { return float64(p.Stat().ConstructingConns()) })
+	r.NewGaugeFunc("db_pool_total_conns", "Total connections in the pool",
+		func() float64 { return float64(p.Stat().TotalConns()) })
+	r.NewGaugeFunc("db_pool_max_conns", "Maximum pool size",
+		func() float64 { return float64(p.Stat().MaxConns()) })
+	r.NewCounterFunc("db_pool_acquires_total", "Successful connection acquires",
+		func() float64 { return float64(p.Stat().AcquireCount()) })
+	r.NewCounterFunc("db_pool_acquire_wait_seconds_total", "Total time spent acquiring connections",
+		func() float64 { return p.Stat().AcquireDuration().Seconds() })
+	r.NewCounterFunc("db_pool_empty_acquires_total", "Acquires that had to wait for a connection",
+		func() float64 { return float64(p.Stat().EmptyAcquireCount()) })
+	r.NewCounterFunc("db_pool_canceled_acquires_total", "Acquires cancelled before a connection was available",
+		func() float64 { return float64(p.Stat().CanceledAcquireCount()) })
+}
+
+// WatchdogConfig tunes the pool saturation watchdog
+type WatchdogConfig struct {
+	Interval time.Duration // how often stats are sampled
+	// Threshold is the mean acquire wait over an interval that counts as
+	// saturation
+	Threshold time.Duration
+	// DumpCooldown limits goroutine dumps while saturation persists
+	DumpCooldown time.Duration
+}
+
+var poolSaturationWarnings = metrics.NewCounterVec(
+	"db_pool_saturation_warnings_total",
+	"Intervals where mean connection acquire wait exceeded the watchdog threshold",
+)
+
+// RunWatchdog samples pool stats every interval and warns when the mean
+// acquire wait exceeds the threshold, attaching a goroutine dump (at most
+// once per cooldown) so whatever is holding connections can be found.
+// Blocks until ctx is cancelled.
+func (p *Pool) RunWatchdog(ctx context.Context, cfg WatchdogConfig) {
+	if cfg.Interval <= 0 || cfg.Threshold <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	prev := p.Stat()
+	prevCount, prevWait := prev.AcquireCount(), prev.AcquireDuration()
+	prevCanceled := prev.CanceledAcquireCount()
+	var lastDump time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stat := p.Stat()
+		count, wait, canceled := stat.AcquireCount(), stat.AcquireDuration(), stat.CanceledAcquireCount()
+		acquires := count - prevCount
+		waited := wait - prevWait
+		newlyCanceled := canceled - prevCanceled
+		prevCount, prevWait, prevCanceled = count, wait, canceled
+
+		var meanWait time.Duration
+		if acquires > 0 {
+			meanWait = waited / time.Duration(acquires)
+		}
+		if meanWait < cfg.Threshold && newlyCanceled == 0 {
+			continue
+		}
+
+		poolSaturationWarnings.Inc()
+		args := []any{
+			"mean_acquire_wait", meanWait.String(),
+			"threshold", cfg.Threshold.String(),
+			"acquires", acquires,
+			"canceled_acquires", newlyCanceled,
+			"acquired_conns", stat.AcquiredConns(),
+			"idle_conns", stat.IdleConns(),
+			"constructing_conns", stat.ConstructingConns(),
+			"max_conns", stat.MaxConns(),
+			"goroutines", runtime.NumGoroutine(),
+		}
+		if time.Since(lastDump) >= cfg.DumpCooldown {
+			lastDump = time.Now()
+			args = append(args, "goroutine_dump", goroutineDump())
+		}
+		p.log.Warn("Database pool saturated", args...)
+	}
+}
+
+// goroutineDump returns all goroutine stacks, identical stacks grouped
+func goroutineDump() string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return "goroutine dump failed: " + err.Error()
+	}
+	return buf.String()
+}
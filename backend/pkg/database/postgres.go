@@ -60,6 +60,10 @@ func createPool(ctx context.Context, connStr string, log *logger.Logger) (*Pool,
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
 	}
 
+	// Sessions run in UTC so timestamps read and date math in SQL never
+	// depend on the server's zone; local days are converted explicitly
+	config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
 	// Pool configuration optimized for 50-500 concurrent users
 	// MaxConns = expected_connections * 1.5 for headroom
 	config.MaxConns = 50
@@ -113,68 +117,9 @@ func createPool(ctx context.Context, connStr string, log *logger.Logger) (*Pool,
 		isHealthy: true,
 	}
 
-	// Start background health checker with auto-reconnect
-	go p.healthChecker(ctx)
-
 	return p, nil
 }
 
-// healthChecker runs periodic health checks and attempts reconnection on failure.
-// Uses exponential backoff to avoid overwhelming the database during outages.
-func (p *Pool) healthChecker(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	backoff := time.Second
-	maxBackoff := 30 * time.Second
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			err := p.Pool.Ping(ctx)
-			if err != nil {
-				p.mu.Lock()
-				p.isHealthy = false
-				p.mu.Unlock()
-
-				p.log.Error("Database health check failed", "error", err)
-
-				// Attempt reconnection with exponential backoff
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(backoff):
-						if err := p.Pool.Ping(ctx); err == nil {
-							p.mu.Lock()
-							p.isHealthy = true
-							p.mu.Unlock()
-							p.log.Info("Database connection restored")
-							backoff = time.Second // Reset backoff
-							break
-						}
-
-						p.log.Warn("Database reconnection attempt failed",
-							"next_retry_in", backoff.String())
-
-						// Exponential backoff with cap
-						backoff *= 2
-						if backoff > maxBackoff {
-							backoff = maxBackoff
-						}
-					}
-				}
-			} else {
-				p.mu.Lock()
-				p.isHealthy = true
-				p.mu.Unlock()
-			}
-		}
-	}
-}
-
 // IsHealthy returns current health status of the database connection.
 // Used by health check endpoints and circuit breakers.
 func (p *Pool) IsHealthy() bool {
@@ -186,7 +131,12 @@ func (p *Pool) IsHealthy() bool {
 // ExecTx executes a function within a database transaction.
 // Automatically handles commit/rollback based on error return.
 // Uses serializable isolation for critical operations like payments.
+// Inside a unit of work (see InTx) fn runs in that transaction instead.
 func (p *Pool) ExecTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return fn(tx)
+	}
+
 	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{
 		IsoLevel: pgx.Serializable,
 	})
@@ -216,7 +166,12 @@ func (p *Pool) ExecTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
 
 // ExecTxWithIsolation executes a function within a transaction with specified isolation level.
 // Use ReadCommitted for read-heavy operations, Serializable for payment processing.
+// Inside a unit of work fn joins that transaction at its isolation level.
 func (p *Pool) ExecTxWithIsolation(ctx context.Context, isoLevel pgx.TxIsoLevel, fn func(tx pgx.Tx) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return fn(tx)
+	}
+
 	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{
 		IsoLevel: isoLevel,
 	})
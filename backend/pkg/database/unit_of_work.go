@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UnitOfWork groups repository calls from a usecase into one transaction.
+// Repositories need no changes: the transaction travels in the context, and
+// Pool's Exec, Query, QueryRow and ExecTx use it when present.
+type UnitOfWork interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// txFromContext returns the transaction started by InTx, if any
+func txFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// InTx runs fn in a Read Committed transaction carried by the ctx passed to
+// fn. It commits when fn returns nil and rolls back on an error or panic.
+// Calls nested inside an existing unit of work join it, so only the
+// outermost InTx commits.
+func (p *Pool) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		// Rollback uses a fresh context so a cancelled request still
+		// returns its connection cleanly
+		if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			p.log.Error("Failed to rollback transaction", "error", rbErr)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	return nil
+}
+
+// Querier returns the transaction in ctx, or the pool outside a unit of work
+func (p *Pool) Querier(ctx context.Context) Querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return p.Pool
+}
+
+// Exec runs sql in ctx's transaction, if any
+func (p *Pool) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return p.Querier(ctx).Exec(ctx, sql, arguments...)
+}
+
+// Query runs sql in ctx's transaction, if any
+func (p *Pool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return p.Querier(ctx).Query(ctx, sql, args...)
+}
+
+// QueryRow runs sql in ctx's transaction, if any
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return p.Querier(ctx).QueryRow(ctx, sql, args...)
+}
@@ -0,0 +1,59 @@
+// Package email delivers transactional emails (order confirmations and
+// receipts) through a pluggable provider.
+package email
+
+import (
+	"context"
+	"strings"
+
+	"fooddelivery/pkg/logger"
+)
+
+// Message is one email. HTML is the body; Text is the plain-text
+// alternative for clients that don't show HTML and may be empty.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Sender delivers an email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Address is the sender shown to recipients
+type Address struct {
+	Email string
+	Name  string // Optional display name
+}
+
+// LogSender records that an email would have been sent instead of sending
+// it. Used until a provider is configured.
+type LogSender struct {
+	log *logger.Logger
+}
+
+// NewLogSender creates a sender that only logs
+func NewLogSender(log *logger.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send implements Sender
+func (s *LogSender) Send(_ context.Context, msg Message) error {
+	s.log.Info("Email (log only)", "to", MaskAddress(msg.To), "subject", msg.Subject, "length", len(msg.HTML))
+	return nil
+}
+
+// MaskAddress hides most of the local part of an email address, for logs
+func MaskAddress(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return "****"
+	}
+	if at <= 1 {
+		return "*" + address[at:]
+	}
+	return address[:1] + strings.Repeat("*", at-1) + address[at:]
+}
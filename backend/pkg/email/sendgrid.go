@@ -0,0 +1,115 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// SendGridConfig holds SendGrid credentials. From must be a verified
+// sender or on an authenticated domain.
+type SendGridConfig struct {
+	APIKey  string
+	From    Address
+	BaseURL string // Defaults to https://api.sendgrid.com
+}
+
+// SendGridSender sends with SendGrid's v3 Mail Send API
+type SendGridSender struct {
+	cfg        SendGridConfig
+	httpClient *http.Client
+}
+
+// NewSendGridSender creates a SendGrid sender
+func NewSendGridSender(cfg SendGridConfig, timeout time.Duration) *SendGridSender {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.sendgrid.com"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &SendGridSender{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// sendGridError is the body of a failed SendGrid request
+type sendGridError struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Send implements Sender. Client errors (bad address, unverified sender)
+// are permanent; rate limits and server errors can be retried.
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	body := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: s.cfg.From.Email, Name: s.cfg.From.Name},
+		Subject:          msg.Subject,
+	}
+	// SendGrid wants text/plain before text/html
+	if msg.Text != "" {
+		body.Content = append(body.Content, sendGridContent{Type: "text/plain", Value: msg.Text})
+	}
+	body.Content = append(body.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	statusErr := &retry.StatusError{Service: "sendgrid", StatusCode: resp.StatusCode}
+	var apiErr sendGridError
+	if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && len(apiErr.Errors) > 0 {
+		err = fmt.Errorf("%w: %s", statusErr, apiErr.Errors[0].Message)
+	} else {
+		err = statusErr
+	}
+	if !retry.RetryableStatus(resp.StatusCode) {
+		return retry.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/retry"
+)
+
+// SMTPConfig holds an SMTP relay's address and credentials. Port 465 uses
+// implicit TLS; other ports upgrade with STARTTLS when the server offers
+// it. Credentials are only sent over TLS.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     Address
+}
+
+// SMTPSender sends through an SMTP relay, one connection per email
+type SMTPSender struct {
+	cfg     SMTPConfig
+	timeout time.Duration
+}
+
+// NewSMTPSender creates an SMTP sender
+func NewSMTPSender(cfg SMTPConfig, timeout time.Duration) *SMTPSender {
+	return &SMTPSender{cfg: cfg, timeout: timeout}
+}
+
+// Send implements Sender. Rejections with a 5xx reply (unknown mailbox,
+// refused sender) are permanent; connection failures and 4xx replies can be
+// retried.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	body, err := s.compose(msg)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var conn net.Conn
+	if s.cfg.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp connect failed: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("smtp connect failed: %w", err)
+	}
+	// net/smtp doesn't take a context; closing the connection aborts it
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return smtpError(err)
+	}
+	defer client.Close()
+
+	if s.cfg.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return smtpError(err)
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted
+		// connection to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return smtpError(err)
+		}
+	}
+
+	if err := client.Mail(s.cfg.From.Email); err != nil {
+		return smtpError(err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return smtpError(err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError(err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return smtpError(err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(err)
+	}
+	return smtpError(client.Quit())
+}
+
+// compose builds the MIME message: HTML, with the plain-text alternative
+// first when there is one
+func (s *SMTPSender) compose(msg Message) ([]byte, error) {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	from := mail.Address{Name: s.cfg.From.Name, Address: s.cfg.From.Email}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.New(), s.cfg.Host)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	write := func(contentType, content string) error {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(content)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+	if msg.Text != "" {
+		if err := write("text/plain", msg.Text); err != nil {
+			return nil, err
+		}
+	}
+	if err := write("text/html", msg.HTML); err != nil {
+		return nil, err
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// smtpError marks permanent SMTP rejections so they aren't retried
+func smtpError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return retry.Permanent(fmt.Errorf("smtp rejected: %w", err))
+	}
+	return fmt.Errorf("smtp failed: %w", err)
+}
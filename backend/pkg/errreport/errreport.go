@@ -0,0 +1,180 @@
+// Package errreport sends panics and server errors to an error tracking
+// service. Reports are queued and sent in the background so a slow or
+// unreachable tracker never delays requests.
+package errreport
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"fooddelivery/pkg/logger"
+)
+
+// Level is the severity of a report
+type Level string
+
+const (
+	LevelError Level = "error"
+	LevelFatal Level = "fatal" // panics
+)
+
+// Request is the HTTP context of a report
+type Request struct {
+	Method    string
+	URL       string
+	RequestID string
+	ClientIP  string
+	UserAgent string
+	Status    int
+}
+
+// Event is one error report
+type Event struct {
+	Level       Level
+	Message     string
+	ErrorType   string
+	Stack       string
+	Request     *Request
+	UserID      string
+	Tags        map[string]string
+	Time        time.Time
+	Release     string
+	Environment string
+}
+
+// Reporter captures error events
+type Reporter interface {
+	// Report queues an event; it never blocks
+	Report(event Event)
+	// Close sends queued events, waiting until ctx is done
+	Close(ctx context.Context) error
+}
+
+// Transport delivers an event to a tracker (Sentry, Rollbar, ...)
+type Transport interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// Options configure a Client
+type Options struct {
+	Release     string
+	Environment string
+	// SampleRate is the fraction of LevelError events sent (0..1). Panics
+	// are always sent.
+	SampleRate float64
+	QueueSize  int
+	Timeout    time.Duration // per send
+}
+
+// Client is a Reporter that samples events and sends them through a
+// Transport from a background worker
+type Client struct {
+	transport Transport
+	opts      Options
+	queue     chan Event
+	done      chan struct{}
+	log       *logger.Logger
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	closed bool
+}
+
+// NewClient starts a client sending through transport
+func NewClient(transport Transport, opts Options, log *logger.Logger) *Client {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	c := &Client{
+		transport: transport,
+		opts:      opts,
+		queue:     make(chan Event, opts.QueueSize),
+		done:      make(chan struct{}),
+		log:       log,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	go c.run()
+	return c
+}
+
+// Report implements Reporter. Events are dropped when sampled out or when
+// the queue is full.
+func (c *Client) Report(event Event) {
+	if event.Level == "" {
+		event.Level = LevelError
+	}
+	if event.Level == LevelError && !c.sample() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Release = c.opts.Release
+	event.Environment = c.opts.Environment
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.queue <- event:
+	default:
+		c.log.Warn("Error report queue full, dropping event", "message", event.Message)
+	}
+}
+
+// Close implements Reporter
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	for event := range c.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+		if err := c.transport.Send(ctx, event); err != nil {
+			c.log.Warn("Failed to send error report", "error", err)
+		}
+		cancel()
+	}
+}
+
+func (c *Client) sample() bool {
+	if c.opts.SampleRate >= 1 {
+		return true
+	}
+	if c.opts.SampleRate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rand.Float64() < c.opts.SampleRate
+}
+
+// Nop discards every event; used when no tracker is configured
+type Nop struct{}
+
+// Report implements Reporter
+func (Nop) Report(Event) {}
+
+// Close implements Reporter
+func (Nop) Close(context.Context) error { return nil }
@@ -0,0 +1,199 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDSN is returned for a malformed Sentry DSN
+var ErrInvalidDSN = errors.New("invalid Sentry DSN")
+
+// sentryClientName identifies this integration in the auth header
+const sentryClientName = "crave-delivery/1.0"
+
+// SentryTransport sends events to Sentry's envelope endpoint
+type SentryTransport struct {
+	dsn        string
+	publicKey  string
+	endpoint   string
+	serverName string
+	httpClient *http.Client
+}
+
+// NewSentryTransport parses a DSN of the form
+// https://<public key>@<host>/<project id>
+func NewSentryTransport(dsn string, timeout time.Duration) (*SentryTransport, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, ErrInvalidDSN
+	}
+
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+	if projectID == "" {
+		return nil, ErrInvalidDSN
+	}
+	prefix := ""
+	if idx >= 0 {
+		prefix = "/" + path[:idx]
+	}
+
+	serverName, _ := os.Hostname()
+
+	return &SentryTransport{
+		dsn:        dsn,
+		publicKey:  u.User.Username(),
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, projectID),
+		serverName: serverName,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// sentryEvent is the subset of the Sentry event payload we populate
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       Level             `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     *sentryMessage    `json:"message,omitempty"`
+	Exception   *sentryExceptions `json:"exception,omitempty"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryRequest struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type sentryUser struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// Send implements Transport
+func (t *SentryTransport) Send(ctx context.Context, event Event) error {
+	payload := t.buildEvent(event)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": payload.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+		"dsn":      t.dsn,
+	})
+	itemHeader, _ := json.Marshal(map[string]any{"type": "event", "length": len(body)})
+
+	var envelope bytes.Buffer
+	envelope.Write(header)
+	envelope.WriteByte('\n')
+	envelope.Write(itemHeader)
+	envelope.WriteByte('\n')
+	envelope.Write(body)
+	envelope.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, &envelope)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=%s", t.publicKey, sentryClientName))
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *SentryTransport) buildEvent(event Event) sentryEvent {
+	payload := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   event.Time.UTC().Format(time.RFC3339Nano),
+		Level:       event.Level,
+		Platform:    "go",
+		Release:     event.Release,
+		Environment: event.Environment,
+		ServerName:  t.serverName,
+		Tags:        make(map[string]string, len(event.Tags)+2),
+	}
+	for k, v := range event.Tags {
+		payload.Tags[k] = v
+	}
+
+	if event.ErrorType != "" {
+		payload.Exception = &sentryExceptions{Values: []sentryException{{Type: event.ErrorType, Value: event.Message}}}
+	} else {
+		payload.Message = &sentryMessage{Formatted: event.Message}
+	}
+
+	if event.Stack != "" {
+		payload.Extra = map[string]any{"stack_trace": event.Stack}
+	}
+
+	if event.UserID != "" || (event.Request != nil && event.Request.ClientIP != "") {
+		payload.User = &sentryUser{ID: event.UserID}
+		if event.Request != nil {
+			payload.User.IPAddress = event.Request.ClientIP
+		}
+	}
+
+	if r := event.Request; r != nil {
+		payload.Request = &sentryRequest{
+			Method:  r.Method,
+			URL:     r.URL,
+			Headers: map[string]string{"User-Agent": r.UserAgent},
+		}
+		payload.Tags["request_id"] = r.RequestID
+		if r.Status != 0 {
+			payload.Tags["status_code"] = fmt.Sprint(r.Status)
+		}
+	}
+
+	return payload
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
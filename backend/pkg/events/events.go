@@ -0,0 +1,47 @@
+// Package events publishes domain events (order status changes) to an
+// external sink through a pluggable publisher.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"fooddelivery/pkg/logger"
+)
+
+// Event is one domain event. ID is unique and increasing per source, so
+// consumers can drop duplicates: delivery is at least once. Key groups
+// events that must stay in order, e.g. an order ID.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Key        string          `json:"key"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Publisher delivers a batch of events in order. An error means none of
+// the batch can be assumed delivered.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// LogPublisher records events instead of sending them anywhere. Used until
+// a sink is configured.
+type LogPublisher struct {
+	log *logger.Logger
+}
+
+// NewLogPublisher creates a publisher that only logs
+func NewLogPublisher(log *logger.Logger) *LogPublisher {
+	return &LogPublisher{log: log}
+}
+
+// Publish implements Publisher
+func (p *LogPublisher) Publish(_ context.Context, events []Event) error {
+	for _, e := range events {
+		p.log.Info("Event (log only)", "event_id", e.ID, "type", e.Type, "key", e.Key, "payload", string(e.Payload))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// KafkaConfig points at a Kafka REST Proxy (Confluent REST API v2), which
+// produces to the cluster on our behalf
+type KafkaConfig struct {
+	RESTURL  string // e.g. https://kafka-rest.internal:8082
+	Topic    string
+	Username string // Basic auth, if the proxy requires it
+	Password string
+}
+
+// KafkaPublisher produces events to a Kafka topic through the REST Proxy.
+// Events are keyed by Event.Key, so one order's events land on one
+// partition and stay in order.
+type KafkaPublisher struct {
+	cfg        KafkaConfig
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewKafkaPublisher creates a Kafka publisher
+func NewKafkaPublisher(cfg KafkaConfig, timeout time.Duration) *KafkaPublisher {
+	return &KafkaPublisher{
+		cfg:        cfg,
+		endpoint:   strings.TrimRight(cfg.RESTURL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// kafkaProduceRequest is the REST Proxy produce request body
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// kafkaProduceResponse has one offset per record; a record that failed
+// carries an error code
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish implements Publisher. The batch is produced in one request.
+// Rejected requests (bad topic, auth) are permanent; rate limits and
+// server errors can be retried.
+func (p *KafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, e := range events {
+		records[i] = kafkaRecord{Key: e.Key, Value: e}
+	}
+	body, err := json.Marshal(kafkaProduceRequest{Records: records})
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.cfg.Username != "" {
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka rest request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := &retry.StatusError{Service: "kafka-rest", StatusCode: resp.StatusCode}
+		if !retry.RetryableStatus(resp.StatusCode) {
+			return retry.Permanent(err)
+		}
+		return err
+	}
+
+	var result kafkaProduceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode kafka rest response: %w", err)
+	}
+	for _, o := range result.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected an event (code %d): %s", *o.ErrorCode, o.Error)
+		}
+	}
+	return nil
+}
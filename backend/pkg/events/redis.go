@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"fooddelivery/pkg/redis"
+)
+
+// RedisStreamPublisher appends events to a Redis stream, trimmed to about
+// MaxLen entries. Consumers read it with XREAD or a consumer group.
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamPublisher creates a publisher writing to stream; maxLen 0
+// never trims it
+func NewRedisStreamPublisher(client *redis.Client, stream string, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{client: client, stream: stream, maxLen: maxLen}
+}
+
+// Publish implements Publisher. The batch is sent in one pipeline.
+func (p *RedisStreamPublisher) Publish(ctx context.Context, events []Event) error {
+	pipe := p.client.Pipeline()
+	for _, e := range events {
+		pipe.XAdd(ctx, &goredis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: p.maxLen > 0,
+			Values: map[string]interface{}{
+				"id":          e.ID,
+				"type":        e.Type,
+				"key":         e.Key,
+				"payload":     string(e.Payload),
+				"occurred_at": e.OccurredAt.UTC().Format(time.RFC3339Nano),
+			},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add events to stream %s: %w", p.stream, err)
+	}
+	return nil
+}
+
+// RedisChannelPublisher publishes each event as JSON on a Redis pub/sub
+// channel. Subscribers only see events sent while they are listening, so
+// it suits live updates, not a durable sink.
+type RedisChannelPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisChannelPublisher creates a publisher for channel, in the client's
+// namespace
+func NewRedisChannelPublisher(client *redis.Client, channel string) *RedisChannelPublisher {
+	return &RedisChannelPublisher{client: client, channel: client.Channel(channel)}
+}
+
+// Publish implements Publisher. The batch is sent in one pipeline.
+func (p *RedisChannelPublisher) Publish(ctx context.Context, events []Event) error {
+	pipe := p.client.Pipeline()
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", e.ID, err)
+		}
+		pipe.Publish(ctx, p.channel, data)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish events to channel %s: %w", p.channel, err)
+	}
+	return nil
+}
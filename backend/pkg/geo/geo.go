@@ -0,0 +1,73 @@
+// Package geo provides coordinate math used for delivery routing:
+// great-circle distances and a nearest-neighbor visit ordering.
+package geo
+
+import (
+	"math"
+)
+
+// earthRadiusMeters is the mean Earth radius
+const earthRadiusMeters = 6371000.0
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// IsZero reports whether the point is unset
+func (p Point) IsZero() bool {
+	return p.Latitude == 0 && p.Longitude == 0
+}
+
+// Valid reports whether the coordinates are within WGS84 bounds
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180 && !p.IsZero()
+}
+
+// HaversineMeters returns the great-circle distance between two points
+func HaversineMeters(a, b Point) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * math.Pi / 180
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// DistanceFunc returns the travel distance in meters between two points
+type DistanceFunc func(a, b Point) float64
+
+// NearestNeighborRoute orders stops by repeatedly visiting the closest
+// unvisited stop, starting from origin. It returns indexes into stops.
+// Good enough for the handful of stops a rider carries; not an optimal TSP.
+func NearestNeighborRoute(origin Point, stops []Point, distance DistanceFunc) []int {
+	if distance == nil {
+		distance = HaversineMeters
+	}
+
+	visited := make([]bool, len(stops))
+	order := make([]int, 0, len(stops))
+	current := origin
+
+	for len(order) < len(stops) {
+		best := -1
+		bestDist := math.MaxFloat64
+		for i, p := range stops {
+			if visited[i] {
+				continue
+			}
+			if d := distance(current, p); d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		visited[best] = true
+		order = append(order, best)
+		current = stops[best]
+	}
+
+	return order
+}
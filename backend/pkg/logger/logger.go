@@ -13,11 +13,13 @@ type Logger struct {
 	*slog.Logger
 }
 
-func Init() {
+// Init sets up the global logger. attrs (key-value pairs) are attached to
+// every record, e.g. the build version.
+func Init(attrs ...any) {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	})
-	Log = &Logger{slog.New(handler)}
+	Log = &Logger{slog.New(handler).With(attrs...)}
 }
 
 // NewLogger creates a new logger instance (useful for fallbacks)
@@ -0,0 +1,219 @@
+// Package metrics provides a minimal in-process metrics registry exposed in
+// the Prometheus text format. It intentionally supports only what the API
+// needs (labelled counters and gauges, and gauges/counters read at scrape
+// time) so we avoid pulling in a full client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds named metric families
+type Registry struct {
+	mu       sync.RWMutex
+	counters map[string]*CounterVec
+	funcs    map[string]*funcMetric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		counters: make(map[string]*CounterVec),
+		funcs:    make(map[string]*funcMetric),
+	}
+}
+
+// funcMetric is a single unlabelled series whose value is read on scrape,
+// for stats owned by another component (connection pools)
+type funcMetric struct {
+	name string
+	help string
+	kind string // gauge or counter
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn() at scrape time.
+// Registering the same name again replaces fn.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.registerFunc(name, help, "gauge", fn)
+}
+
+// NewCounterFunc registers a counter read from fn() at scrape time; fn must
+// be monotonic (e.g. a cumulative pool statistic)
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.registerFunc(name, help, "counter", fn)
+}
+
+func (r *Registry) registerFunc(name, help, kind string, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[name] = &funcMetric{name: name, help: help, kind: kind, fn: fn}
+}
+
+// Default is the process-wide registry served on /metrics
+var Default = NewRegistry()
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	name       string
+	help       string
+	kind       string // counter, or gauge for a GaugeVec
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec registers a counter family. Registering the same name twice
+// returns the existing family so package-level vars stay idempotent.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return r.newVec(name, help, "counter", labelNames)
+}
+
+func (r *Registry) newVec(name, help, kind string, labelNames []string) *CounterVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.counters[name]; ok {
+		return existing
+	}
+
+	cv := &CounterVec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		values:     make(map[string]*counterValue),
+	}
+	r.counters[name] = cv
+	return cv
+}
+
+// NewCounterVec registers a counter family on the default registry
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labelNames...)
+}
+
+// GaugeVec is a value partitioned by labels that can go up and down
+type GaugeVec struct {
+	cv *CounterVec
+}
+
+// NewGaugeVec registers a gauge family. Registering the same name twice
+// returns the existing family.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{cv: r.newVec(name, help, "gauge", labelNames)}
+}
+
+// NewGaugeVec registers a gauge family on the default registry
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labelNames...)
+}
+
+// Set sets the series identified by labelValues to value
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.cv.update(labelValues, func(v *counterValue) { v.value = value })
+}
+
+// Inc adds one to the series identified by labelValues
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+// Add increases the series identified by labelValues by delta
+func (cv *CounterVec) Add(delta float64, labelValues ...string) {
+	cv.update(labelValues, func(v *counterValue) { v.value += delta })
+}
+
+// update applies fn to the series identified by labelValues, creating it
+func (cv *CounterVec) update(labelValues []string, fn func(v *counterValue)) {
+	if len(labelValues) != len(cv.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d labels, got %d", cv.name, len(cv.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	v, ok := cv.values[key]
+	if !ok {
+		v = &counterValue{labels: append([]string(nil), labelValues...)}
+		cv.values[key] = v
+	}
+	fn(v)
+}
+
+// WriteTo renders every registered family in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.counters)+len(r.funcs))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		r.mu.RLock()
+		cv := r.counters[name]
+		fm := r.funcs[name]
+		r.mu.RUnlock()
+		if cv != nil {
+			cv.write(&sb)
+		} else if fm != nil {
+			fm.write(&sb)
+		}
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (cv *CounterVec) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", cv.name, cv.help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", cv.name, cv.kind)
+
+	cv.mu.Lock()
+	keys := make([]string, 0, len(cv.values))
+	for k := range cv.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := cv.values[k]
+		sb.WriteString(cv.name)
+		if len(cv.labelNames) > 0 {
+			sb.WriteByte('{')
+			for i, ln := range cv.labelNames {
+				if i > 0 {
+					sb.WriteByte(',')
+				}
+				fmt.Fprintf(sb, "%s=%q", ln, v.labels[i])
+			}
+			sb.WriteByte('}')
+		}
+		fmt.Fprintf(sb, " %g\n", v.value)
+	}
+	cv.mu.Unlock()
+}
+
+func (fm *funcMetric) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", fm.name, fm.help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", fm.name, fm.kind)
+	fmt.Fprintf(sb, "%s %g\n", fm.name, fm.fn())
+}
@@ -0,0 +1,164 @@
+// Package netguard provides transport-level access controls: IP allowlists
+// with trusted-proxy aware client IP resolution, and TLS server settings
+// for mutual TLS.
+package netguard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// ErrInvalidEntry is returned for allowlist entries that are neither an IP
+// nor a CIDR range
+var ErrInvalidEntry = errors.New("invalid IP or CIDR")
+
+// IPList is a set of IP addresses and CIDR ranges
+type IPList struct {
+	prefixes []netip.Prefix
+}
+
+// ParseIPList parses IPs ("10.0.0.5") and CIDR ranges ("10.0.0.0/8").
+// An empty list matches nothing.
+func ParseIPList(entries []string) (*IPList, error) {
+	list := &IPList{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidEntry, entry)
+			}
+			list.prefixes = append(list.prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidEntry, entry)
+		}
+		addr = addr.Unmap()
+		list.prefixes = append(list.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return list, nil
+}
+
+// Len returns the number of entries
+func (l *IPList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.prefixes)
+}
+
+// Contains reports whether addr is in the list
+func (l *IPList) Contains(addr netip.Addr) bool {
+	if l == nil || !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP resolves the originating client address. X-Forwarded-For is only
+// honored when the direct peer is a trusted proxy, and is read right to
+// left so a client can't spoof its address by prepending entries: the
+// first hop that isn't a trusted proxy is the client.
+func ClientIP(remote netip.Addr, forwardedFor string, trustedProxies *IPList) netip.Addr {
+	remote = remote.Unmap()
+	if forwardedFor == "" || !trustedProxies.Contains(remote) {
+		return remote
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop can't be attributed; stop at the last good one
+			break
+		}
+		client = addr.Unmap()
+		if !trustedProxies.Contains(client) {
+			break
+		}
+	}
+	return client
+}
+
+// Client certificate policies for TLS termination
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional" // verified if presented; routes decide whether to require one
+	ClientAuthRequire  = "require"  // the handshake fails without a valid certificate
+)
+
+// TLSConfig describes the server certificate and client verification
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   string
+}
+
+// ServerConfig builds a tls.Config for the server. Client certificates are
+// verified against ClientCAFile, which is required unless ClientAuth is none.
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch c.ClientAuth {
+	case "", ClientAuthNone:
+		return tlsConfig, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q (want none, optional or require)", c.ClientAuth)
+	}
+
+	if c.ClientCAFile == "" {
+		return nil, fmt.Errorf("a client CA file is required for client auth %q", c.ClientAuth)
+	}
+	pem, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", c.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+
+	return tlsConfig, nil
+}
+
+// VerifiedClient returns the subject common name of a verified client
+// certificate, if the connection presented one
+func VerifiedClient(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, true
+}
@@ -0,0 +1,168 @@
+// Package pii encrypts personal data for storage (AES-256-GCM) and derives
+// blind indexes (HMAC-SHA256) so encrypted values can still be looked up by
+// exact match. Several keys can be loaded at once so the data key can be
+// rotated: new values use the first key, older values stay readable.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the required length of data and index keys (AES-256)
+const KeySize = 32
+
+var (
+	// ErrInvalidKey is returned for malformed key material
+	ErrInvalidKey = errors.New("pii: invalid key")
+	// ErrUnknownKey is returned when a value was encrypted under a key that isn't loaded
+	ErrUnknownKey = errors.New("pii: unknown key id")
+	// ErrMalformedCiphertext is returned for values that aren't ciphertext from this package
+	ErrMalformedCiphertext = errors.New("pii: malformed ciphertext")
+)
+
+// Index domains keep blind indexes of different kinds of value apart, so a
+// phone index can never match an email index
+const (
+	IndexPhone = "phone"
+	IndexEmail = "email"
+)
+
+// Key is a named data key; the ID is stored with each ciphertext
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys reads "id:base64key,id:base64key". The first key encrypts new
+// values; the rest are kept for reading values written before a rotation.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%w: expected id:base64key", ErrInvalidKey)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s is not base64", ErrInvalidKey, id)
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return keys, nil
+}
+
+// Protector encrypts, decrypts and indexes PII values
+type Protector struct {
+	aeads     map[string]cipher.AEAD
+	currentID string
+	indexKey  []byte
+}
+
+// NewProtector creates a protector from data keys (first is current) and a
+// separate blind index key
+func NewProtector(keys []Key, indexKey []byte) (*Protector, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one data key is required", ErrInvalidKey)
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("%w: blind index key must be %d bytes", ErrInvalidKey, KeySize)
+	}
+
+	p := &Protector{
+		aeads:     make(map[string]cipher.AEAD, len(keys)),
+		currentID: keys[0].ID,
+		indexKey:  indexKey,
+	}
+	for _, key := range keys {
+		if len(key.Secret) != KeySize {
+			return nil, fmt.Errorf("%w: key %s must be %d bytes", ErrInvalidKey, key.ID, KeySize)
+		}
+		if strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("%w: key id %s must not contain ':'", ErrInvalidKey, key.ID)
+		}
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		p.aeads[key.ID] = aead
+	}
+
+	return p, nil
+}
+
+// Encrypt seals value under the current key. The field name (e.g.
+// "users.email") is authenticated, so a ciphertext copied into another
+// column fails to decrypt. Output format: "<key id>:<base64(nonce|sealed)>".
+func (p *Protector) Encrypt(value, field string) (string, error) {
+	aead := p.aeads[p.currentID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("pii: failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return p.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt for the same field
+func (p *Protector) Decrypt(ciphertext, field string) (string, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return "", ErrMalformedCiphertext
+	}
+	aead, ok := p.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", ErrMalformedCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("%w: authentication failed", ErrMalformedCiphertext)
+	}
+	return string(plaintext), nil
+}
+
+// KeyID returns the ID of the key new values are encrypted under
+func (p *Protector) KeyID() string {
+	return p.currentID
+}
+
+// NeedsReencrypt reports whether a ciphertext was written under an older key
+func (p *Protector) NeedsReencrypt(ciphertext string) bool {
+	id, _, _ := strings.Cut(ciphertext, ":")
+	return id != p.currentID
+}
+
+// BlindIndex returns a deterministic, keyed digest of value for equality
+// lookups. It reveals nothing about the value without the index key, but
+// equal values in the same domain always produce the same index.
+func (p *Protector) BlindIndex(value, domain string) string {
+	mac := hmac.New(sha256.New, p.indexKey)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
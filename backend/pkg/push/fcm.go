@@ -0,0 +1,287 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/retry"
+)
+
+// fcmScope is the OAuth scope for sending with the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmTokenLeeway renews the access token this long before it expires
+const fcmTokenLeeway = time.Minute
+
+// TokenStore holds the FCM registration tokens of users' devices
+type TokenStore interface {
+	DeviceTokens(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// RemoveDeviceToken forgets a token FCM says is no longer valid (app
+	// uninstalled, token rotated)
+	RemoveDeviceToken(ctx context.Context, token string) error
+}
+
+// FCMConfig configures Firebase Cloud Messaging. CredentialsFile is a
+// service account key (JSON) with permission to send messages; ProjectID
+// defaults to the key's project.
+type FCMConfig struct {
+	CredentialsFile string
+	ProjectID       string
+	BaseURL         string // Defaults to https://fcm.googleapis.com
+}
+
+// serviceAccount is the subset of a service account key file we read
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMSender sends to every device a user registered, through the FCM HTTP
+// v1 API. It signs in as the service account with a self-signed JWT and
+// caches the access token until shortly before it expires.
+type FCMSender struct {
+	projectID   string
+	baseURL     string
+	clientEmail string
+	privateKey  *rsa.PrivateKey
+	tokenURI    string
+	tokens      TokenStore
+	httpClient  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMSender reads the service account key and creates a sender for the
+// devices in tokens
+func NewFCMSender(cfg FCMConfig, tokens TokenStore, timeout time.Duration) (*FCMSender, error) {
+	raw, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, errors.New("FCM credentials must be a service account key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	if projectID == "" {
+		return nil, errors.New("FCM project ID is not set and not in the credentials")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://fcm.googleapis.com"
+	}
+
+	return &FCMSender{
+		projectID:   projectID,
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		clientEmail: account.ClientEmail,
+		privateKey:  key,
+		tokenURI:    account.TokenURI,
+		tokens:      tokens,
+		httpClient:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// fcmRequest is the body of a messages:send call
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fcmError is the body of a failed FCM request
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Send implements Sender. Tokens FCM reports as unregistered are removed.
+// A user without devices is not an error. The message counts as sent if
+// any device took it, so a retry never repeats it on the others; otherwise
+// the first failure is returned.
+func (s *FCMSender) Send(ctx context.Context, userID uuid.UUID, msg Message) error {
+	tokens, err := s.tokens.DeviceTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load device tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	delivered := false
+	for _, token := range tokens {
+		err := s.send(ctx, accessToken, token, msg)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, errUnregistered):
+			if err := s.tokens.RemoveDeviceToken(ctx, token); err != nil {
+				firstErr = errors.Join(firstErr, fmt.Errorf("failed to remove device token: %w", err))
+			}
+		case firstErr == nil:
+			firstErr = err
+		}
+	}
+	if delivered {
+		return nil
+	}
+	return firstErr
+}
+
+// errUnregistered marks a token FCM no longer delivers to
+var errUnregistered = retry.Permanent(errors.New("device token is no longer registered"))
+
+// send delivers to one device
+func (s *FCMSender) send(ctx context.Context, accessToken, token string, msg Message) error {
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	}})
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", s.baseURL, url.PathEscape(s.projectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apiErr fcmError
+	_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+	for _, d := range apiErr.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return errUnregistered
+		}
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		s.expire()
+	}
+	statusErr := &retry.StatusError{Service: "fcm", StatusCode: resp.StatusCode}
+	if apiErr.Error.Message != "" {
+		return fmt.Errorf("%w: %s", statusErr, apiErr.Error.Message)
+	}
+	return statusErr
+}
+
+// token returns a cached access token, signing in again when it is about
+// to expire
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": fcmScope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.privateKey)
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("failed to sign FCM assertion: %w", err))
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &retry.StatusError{Service: "fcm token", StatusCode: resp.StatusCode}
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode fcm token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("fcm token response has no access token")
+	}
+
+	s.accessToken = body.AccessToken
+	s.expiresAt = now.Add(time.Duration(body.ExpiresIn)*time.Second - fcmTokenLeeway)
+	return s.accessToken, nil
+}
+
+// expire drops the cached access token after FCM rejected it
+func (s *FCMSender) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+}
@@ -0,0 +1,79 @@
+// Package push delivers notifications to a user's devices.
+package push
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+	"fooddelivery/pkg/throttle"
+)
+
+// Message is a notification shown on the device. Data is passed to the app
+// for deep-linking (e.g. the order a survey is about).
+type Message struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Sender sends a message to every device registered for a user
+type Sender interface {
+	Send(ctx context.Context, userID uuid.UUID, msg Message) error
+}
+
+// LogSender records messages instead of sending them. Used until a push
+// provider is configured so scheduled notifications still flow end to end.
+type LogSender struct {
+	log *logger.Logger
+}
+
+// NewLogSender creates a sender that only logs
+func NewLogSender(log *logger.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send implements Sender
+func (s *LogSender) Send(_ context.Context, userID uuid.UUID, msg Message) error {
+	s.log.Info("Push notification (log only)", "user_id", userID.String(), "title", msg.Title, "data", msg.Data)
+	return nil
+}
+
+// RetryingSender retries a sender according to a policy
+type RetryingSender struct {
+	sender Sender
+	policy retry.Policy
+}
+
+// NewRetryingSender wraps sender with a retry policy
+func NewRetryingSender(sender Sender, policy retry.Policy) *RetryingSender {
+	return &RetryingSender{sender: sender, policy: policy}
+}
+
+// Send implements Sender
+func (s *RetryingSender) Send(ctx context.Context, userID uuid.UUID, msg Message) error {
+	return s.policy.Do(ctx, func(ctx context.Context) error {
+		return s.sender.Send(ctx, userID, msg)
+	})
+}
+
+// ThrottledSender paces a sender to the provider's rate limit, sending
+// higher priority messages first (see throttle.WithPriority)
+type ThrottledSender struct {
+	sender    Sender
+	throttler *throttle.Throttler
+}
+
+// NewThrottledSender wraps sender with a throttler
+func NewThrottledSender(sender Sender, throttler *throttle.Throttler) *ThrottledSender {
+	return &ThrottledSender{sender: sender, throttler: throttler}
+}
+
+// Send implements Sender
+func (s *ThrottledSender) Send(ctx context.Context, userID uuid.UUID, msg Message) error {
+	return s.throttler.Do(ctx, func(ctx context.Context) error {
+		return s.sender.Send(ctx, userID, msg)
+	})
+}
@@ -4,19 +4,25 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/metrics"
 )
 
+// Nil is the error for a missing key, re-exported for callers using
+// pipelines directly
+const Nil = redis.Nil
+
 // Client wraps redis.Client with additional functionality
 type Client struct {
 	*redis.Client
-	log *logger.Logger
+	log         *logger.Logger
+	prefix      string // Key namespace, see SetNamespace
+	compressMin int    // See SetCompression; 0 stores values as plain JSON
 }
 
 // NewClient creates a new Redis client with the given connection URL.
@@ -48,20 +54,71 @@ func NewClient(url string, log *logger.Logger) (*Client, error) {
 
 	log.Info("Redis connection established")
 
-	return &Client{
+	c := &Client{
 		Client: client,
 		log:    log,
-	}, nil
+	}
+	client.AddHook(namespaceHook{client: c})
+	return c, nil
 }
 
 // Cache keys constants
 const (
-	MenuCacheKey       = "app:menu:all"
-	MenuCacheTTL       = 1 * time.Hour
-	IdempotencyPrefix  = "app:idempotency:"
-	IdempotencyTTL     = 1 * time.Minute
-	SessionPrefix      = "app:session:"
-	SessionTTL         = 24 * time.Hour
+	MenuCacheKey               = "app:menu:all"
+	MenuCategoryCachePrefix    = "app:menu:category:"
+	MenuWarmLockKey            = "app:lock:menu-warm"
+	MenuWarmLockTTL            = 30 * time.Second
+	CleanupLockKey             = "app:lock:cleanup"
+	CleanupLockTTL             = 10 * time.Minute
+	OrderEventRelayLockKey     = "app:lock:order-events"
+	OrderEventRelayLockTTL     = 2 * time.Minute
+	OrderEventsLiveChannel     = "app:order-events:live" // Pub/sub, see Channel
+	MenuCacheTTL               = 1 * time.Hour
+	IdempotencyPrefix          = "app:idempotency:"
+	IdempotencyTTL             = 1 * time.Minute
+	SessionPrefix              = "app:session:"
+	SessionTTL                 = 24 * time.Hour
+	MFAAttemptsPrefix          = "app:mfa:attempts:"
+	MFAAttemptsTTL             = 15 * time.Minute
+	APIKeyCachePrefix          = "app:apikey:hash:"
+	APIKeyCacheTTL             = 5 * time.Minute
+	APIKeyRatePrefix           = "app:apikey:rl:"
+	APIKeyUsagePrefix          = "app:apikey:usage:"
+	APIKeyUsageTTL             = 35 * 24 * time.Hour
+	AttestationVerdictPrefix   = "app:attestation:verdict:"
+	AttestationVerdictTTL      = 10 * time.Minute
+	AttestationRejectTTL       = 1 * time.Minute
+	PickupAttemptsPrefix       = "app:pickup:attempts:"
+	PickupAttemptsTTL          = 15 * time.Minute
+	OTPRequestsPrefix          = "app:otp:requests:"
+	OTPRequestsTTL             = 1 * time.Hour
+	RefreshTokenPrefix         = "app:auth:refresh:"
+	RevokedTokenPrefix         = "app:auth:revoked:"
+	DistanceCachePrefix        = "app:distance:"
+	DistanceCacheTTL           = 7 * 24 * time.Hour
+	DistanceEstimateTTL        = 10 * time.Minute
+	WeatherCachePrefix         = "app:weather:"
+	WeatherCacheTTL            = 10 * time.Minute
+	OrderTimeSeriesPrefix      = "app:analytics:orders:"
+	OrderTimeSeriesTTL         = 1 * time.Minute
+	SettingsCacheKey           = "app:settings:overrides"
+	SettingsCacheTTL           = 5 * time.Minute
+	TemplatesCacheKey          = "app:templates:overrides"
+	TemplatesCacheTTL          = 5 * time.Minute
+	AnnouncementsCacheKey      = "app:announcements:current"
+	AnnouncementsCacheTTL      = 5 * time.Minute
+	UserCachePrefix            = "app:user:"
+	UserCacheTTL               = 1 * time.Minute
+	AdminAlertDigestPrefix     = "app:alerts:digest:"
+	AdminAlertDigestSentPrefix = "app:alerts:digest-sent:"
+	AdminAlertDedupePrefix     = "app:alerts:dedupe:"
+	GatewayFailuresKey         = "app:payments:gateway-failures"
+	GuestCartPrefix            = "app:cart:guest:"
+	GuestCartTTL               = 7 * 24 * time.Hour
+	UserCartPrefix             = "app:cart:user:"
+	UserCartTTL                = 30 * 24 * time.Hour
+	PresencePrefix             = "app:presence:"
+	PresenceIndexPrefix        = "app:presence-index:"
 )
 
 // GetJSON retrieves a JSON value from Redis and unmarshals it into the target.
@@ -75,8 +132,8 @@ func (c *Client) GetJSON(ctx context.Context, key string, target interface{}) (b
 		return false, fmt.Errorf("redis get failed: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(val), target); err != nil {
-		return false, fmt.Errorf("failed to unmarshal cached value: %w", err)
+	if err := c.DecodeJSON([]byte(val), target); err != nil {
+		return false, err
 	}
 
 	return true, nil
@@ -84,9 +141,9 @@ func (c *Client) GetJSON(ctx context.Context, key string, target interface{}) (b
 
 // SetJSON marshals the value to JSON and stores it in Redis with TTL.
 func (c *Client) SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	data, err := json.Marshal(value)
+	data, err := c.encodeJSON(key, value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return err
 	}
 
 	if err := c.Set(ctx, key, data, ttl).Err(); err != nil {
@@ -105,13 +162,40 @@ func (c *Client) DeleteKey(ctx context.Context, key string) error {
 	return nil
 }
 
+// GetDelJSON atomically retrieves and removes a JSON value, so only one
+// caller can consume it. Returns false if the key doesn't exist.
+func (c *Client) GetDelJSON(ctx context.Context, key string, target interface{}) (bool, error) {
+	val, err := c.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("redis getdel failed: %w", err)
+	}
+
+	if err := c.DecodeJSON([]byte(val), target); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// KeyExists reports whether a key is present
+func (c *Client) KeyExists(ctx context.Context, key string) (bool, error) {
+	n, err := c.Exists(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis exists failed: %w", err)
+	}
+	return n > 0, nil
+}
+
 // SetNXWithTTL sets a key only if it doesn't exist (for idempotency).
 // Returns true if the key was set (first request), false if it already exists.
 // This is the foundation for preventing duplicate order creation.
 func (c *Client) SetNXWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
-	data, err := json.Marshal(value)
+	data, err := c.encodeJSON(key, value)
 	if err != nil {
-		return false, fmt.Errorf("failed to marshal value: %w", err)
+		return false, err
 	}
 
 	// SetNX is atomic - only one concurrent request will succeed
@@ -144,9 +228,112 @@ func (c *Client) GetAndExtendTTL(ctx context.Context, key string, target interfa
 		return false, fmt.Errorf("redis get failed: %w", err)
 	}
 
-	if err := json.Unmarshal([]byte(val), target); err != nil {
-		return false, fmt.Errorf("failed to unmarshal cached value: %w", err)
+	if err := c.DecodeJSON([]byte(val), target); err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
+
+// IncrWithTTL increments a counter and sets its TTL on first increment.
+// Used for fixed-window rate limiting (e.g., failed verification attempts).
+func (c *Client) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := c.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis incr failed: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// MGetJSON fetches several JSON values in one round trip. targets[i]
+// receives keys[i]; the result reports which keys were found. A value that
+// fails to unmarshal is treated as a miss so one bad entry doesn't fail the
+// whole batch.
+func (c *Client) MGetJSON(ctx context.Context, keys []string, targets []interface{}) ([]bool, error) {
+	if len(keys) != len(targets) {
+		return nil, fmt.Errorf("redis mget: %d keys but %d targets", len(keys), len(targets))
+	}
+	found := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	vals, err := c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis mget failed: %w", err)
+	}
+
+	for i, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue // nil: cache miss
+		}
+		if err := c.DecodeJSON([]byte(s), targets[i]); err != nil {
+			c.log.Warn("Failed to unmarshal cached value", "key", keys[i], "error", err)
+			continue
+		}
+		found[i] = true
+	}
+
+	return found, nil
+}
+
+// JSONEntry is one value for SetJSONMany
+type JSONEntry struct {
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+// SetJSONMany stores several JSON values with their TTLs in one pipelined
+// round trip
+func (c *Client) SetJSONMany(ctx context.Context, entries ...JSONEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	pipe := c.Pipeline()
+	for _, e := range entries {
+		data, err := c.encodeJSON(e.Key, e.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode value for %s: %w", e.Key, err)
+		}
+		pipe.Set(ctx, e.Key, data, e.TTL)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis pipeline set failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteKeys removes several keys with a single DEL
+func (c *Client) DeleteKeys(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := c.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis delete failed: %w", err)
+	}
+	return nil
+}
+
+// RegisterMetrics exposes connection pool statistics on r, read at scrape time
+func (c *Client) RegisterMetrics(r *metrics.Registry) {
+	r.NewCounterFunc("redis_pool_hits_total", "Times a free connection was found in the pool",
+		func() float64 { return float64(c.PoolStats().Hits) })
+	r.NewCounterFunc("redis_pool_misses_total", "Times no free connection was found and one was dialed",
+		func() float64 { return float64(c.PoolStats().Misses) })
+	r.NewCounterFunc("redis_pool_timeouts_total", "Times waiting for a connection timed out",
+		func() float64 { return float64(c.PoolStats().Timeouts) })
+	r.NewGaugeFunc("redis_pool_total_conns", "Total connections in the pool",
+		func() float64 { return float64(c.PoolStats().TotalConns) })
+	r.NewGaugeFunc("redis_pool_idle_conns", "Idle connections in the pool",
+		func() float64 { return float64(c.PoolStats().IdleConns) })
+	r.NewCounterFunc("redis_pool_stale_conns_total", "Stale connections removed from the pool",
+		func() float64 { return float64(c.PoolStats().StaleConns) })
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"fooddelivery/pkg/metrics"
+)
+
+// zstdMagic starts every zstd frame. JSON never starts with 0x28 ('('), so
+// compressed and plain values can be told apart without a header and
+// values written before compression was enabled stay readable.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// maxDecodedSize bounds a decompressed value, so a corrupt or hostile
+// entry can't exhaust memory
+const maxDecodedSize = 64 << 20
+
+var (
+	jsonWrites = metrics.NewCounterVec("redis_json_writes_total",
+		"JSON values written to Redis, by key family", "family", "compressed")
+	jsonBytes = metrics.NewCounterVec("redis_json_bytes_total",
+		"Serialized size of JSON values written to Redis before compression, by key family", "family")
+	jsonStoredBytes = metrics.NewCounterVec("redis_json_stored_bytes_total",
+		"Bytes of JSON values stored in Redis after compression, by key family", "family")
+	jsonLastSize = metrics.NewGaugeVec("redis_json_last_size_bytes",
+		"Serialized size of the last JSON value written, by key family", "family")
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// codecs creates the shared zstd encoder and decoder on first use; both are
+// safe for concurrent EncodeAll/DecodeAll calls
+func codecs() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize))
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// SetCompression zstd-compresses JSON values of at least minBytes when they
+// are written; 0 disables it. Reads always handle both forms, so enable it
+// only once every instance runs a version that can read compressed values.
+func (c *Client) SetCompression(minBytes int) {
+	c.compressMin = minBytes
+}
+
+// encodeJSON marshals value for key, compressing it past the threshold,
+// and records its size
+func (c *Client) encodeJSON(key string, value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	family := keyFamily(key)
+	jsonBytes.Add(float64(len(data)), family)
+	jsonLastSize.Set(float64(len(data)), family)
+
+	compressed := "false"
+	if c.compressMin > 0 && len(data) >= c.compressMin {
+		enc, _, err := codecs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create compressor: %w", err)
+		}
+		data = enc.EncodeAll(data, make([]byte, 0, len(data)/4))
+		compressed = "true"
+	}
+	jsonWrites.Inc(family, compressed)
+	jsonStoredBytes.Add(float64(len(data)), family)
+
+	return data, nil
+}
+
+// DecodeJSON unmarshals a value written by the JSON helpers into target,
+// decompressing it if needed. For callers reading values with raw commands
+// or pipelines.
+func (c *Client) DecodeJSON(data []byte, target interface{}) error {
+	if bytes.HasPrefix(data, zstdMagic) {
+		_, dec, err := codecs()
+		if err != nil {
+			return fmt.Errorf("failed to create decompressor: %w", err)
+		}
+		if data, err = dec.DecodeAll(data, nil); err != nil {
+			return fmt.Errorf("failed to decompress cached value: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to unmarshal cached value: %w", err)
+	}
+	return nil
+}
+
+// keyFamily labels metrics by the key's second segment, e.g. "menu" for
+// app:menu:all, so IDs in keys don't become label values
+func keyFamily(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) < 3 {
+		return "other"
+	}
+	return parts[1]
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SetJSONIndexed stores a JSON value with a TTL and records member in the
+// sorted set index, scored by at, in one round trip. The index lets
+// recently written keys be listed without scanning the keyspace.
+func (c *Client) SetJSONIndexed(ctx context.Context, key string, value interface{}, ttl time.Duration, index, member string, at time.Time) error {
+	data, err := c.encodeJSON(key, value)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.Pipeline()
+	pipe.Set(ctx, key, data, ttl)
+	pipe.ZAdd(ctx, index, redis.Z{Score: float64(at.UnixMilli()), Member: member})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis indexed set failed: %w", err)
+	}
+	return nil
+}
+
+// IndexedSince returns the members of index recorded at or after since,
+// newest first, and drops older ones
+func (c *Client) IndexedSince(ctx context.Context, index string, since time.Time) ([]string, error) {
+	pipe := c.Pipeline()
+	pipe.ZRemRangeByScore(ctx, index, "-inf", "("+strconv.FormatInt(since.UnixMilli(), 10))
+	members := pipe.ZRevRange(ctx, index, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("redis index read failed: %w", err)
+	}
+	return members.Val(), nil
+}
+
+// DeleteIndexed removes a key written with SetJSONIndexed and its index entry
+func (c *Client) DeleteIndexed(ctx context.Context, key, index, member string) error {
+	pipe := c.Pipeline()
+	pipe.Del(ctx, key)
+	pipe.ZRem(ctx, index, member)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis indexed delete failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// releaseScript deletes the lock only if it still holds our token, so an
+// instance whose lock expired cannot release another instance's lock
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock is a held distributed lock. It expires on its own after its TTL, so
+// a crashed holder never blocks other instances for long.
+type Lock struct {
+	client *Client
+	key    string
+	token  string
+}
+
+// TryLock takes the lock at key for ttl without waiting. Returns nil and no
+// error if another instance holds it.
+func (c *Client) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	ok, err := c.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis lock failed: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	return &Lock{client: c, key: key, token: token}, nil
+}
+
+// Release frees the lock if it is still ours
+func (l *Lock) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("redis unlock failed: %w", err)
+	}
+	return nil
+}
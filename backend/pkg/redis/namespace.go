@@ -0,0 +1,183 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Commands whose arguments after the name are all keys
+var allKeyCommands = map[string]bool{
+	"del": true, "unlink": true, "exists": true, "mget": true, "touch": true, "watch": true,
+}
+
+// Commands whose first two arguments are keys
+var twoKeyCommands = map[string]bool{
+	"rename": true, "renamenx": true, "rpoplpush": true, "lmove": true, "blmove": true, "smove": true, "copy": true,
+}
+
+// Commands without a key argument, left untouched. SCAN is here because
+// its first argument is a cursor; a MATCH pattern is not namespaced.
+var keylessCommands = map[string]bool{
+	"ping": true, "echo": true, "auth": true, "hello": true, "select": true, "client": true,
+	"info": true, "time": true, "dbsize": true, "command": true, "config": true,
+	"multi": true, "exec": true, "discard": true, "unwatch": true,
+	"script": true, "function": true, "scan": true, "flushdb": true, "flushall": true,
+	"publish": true, "spublish": true, "quit": true,
+}
+
+type skipNamespaceKey struct{}
+
+// withoutNamespace marks ctx so commands run with it address raw keys, for
+// moving keys written before the namespace was set
+func withoutNamespace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipNamespaceKey{}, true)
+}
+
+// SetNamespace prefixes every key the client touches with "ns:", so
+// environments sharing one Redis instance cannot read or overwrite each
+// other's keys. Applies to helpers, raw commands, pipelines and scripts
+// alike. Call before the client is used; an empty namespace disables it.
+func (c *Client) SetNamespace(ns string) {
+	if ns == "" {
+		c.prefix = ""
+		return
+	}
+	c.prefix = strings.TrimSuffix(ns, ":") + ":"
+}
+
+// Namespace returns the key prefix, empty when none is set
+func (c *Client) Namespace() string {
+	return c.prefix
+}
+
+// namespaceHook rewrites key arguments in place before commands are sent
+type namespaceHook struct {
+	client *Client
+}
+
+func (h namespaceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h namespaceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.active(ctx) {
+			prefixKeys(cmd.Args(), h.client.prefix)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h namespaceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if h.active(ctx) {
+			for _, cmd := range cmds {
+				prefixKeys(cmd.Args(), h.client.prefix)
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (h namespaceHook) active(ctx context.Context) bool {
+	return h.client.prefix != "" && ctx.Value(skipNamespaceKey{}) == nil
+}
+
+// prefixKeys prefixes the key positions of a command's arguments. Commands
+// not listed above are assumed to take a single key as first argument,
+// which holds for every string, hash, list, set and sorted set command the
+// app uses.
+func prefixKeys(args []interface{}, prefix string) {
+	if len(args) < 2 {
+		return
+	}
+	name, _ := args[0].(string)
+	name = strings.ToLower(name)
+
+	switch {
+	case keylessCommands[name]:
+	case allKeyCommands[name]:
+		for i := 1; i < len(args); i++ {
+			args[i] = prefixKey(args[i], prefix)
+		}
+	case twoKeyCommands[name]:
+		for i := 1; i < len(args) && i <= 2; i++ {
+			args[i] = prefixKey(args[i], prefix)
+		}
+	case name == "mset" || name == "msetnx":
+		for i := 1; i < len(args); i += 2 {
+			args[i] = prefixKey(args[i], prefix)
+		}
+	case name == "eval" || name == "evalsha" || name == "eval_ro" || name == "evalsha_ro" || name == "fcall" || name == "fcall_ro":
+		// name, script or sha, numkeys, keys..., args...
+		if len(args) < 3 {
+			return
+		}
+		n, err := strconv.Atoi(fmt.Sprint(args[2]))
+		if err != nil {
+			return
+		}
+		for i := 3; i < len(args) && i < 3+n; i++ {
+			args[i] = prefixKey(args[i], prefix)
+		}
+	default:
+		args[1] = prefixKey(args[1], prefix)
+	}
+}
+
+func prefixKey(arg interface{}, prefix string) interface{} {
+	if key, ok := arg.(string); ok {
+		return prefix + key
+	}
+	return arg
+}
+
+// MigrateNamespace moves keys matching pattern that were written before
+// the namespace was set into it, keeping their values and TTLs. A key the
+// namespace already holds is left alone, since the app has written a newer
+// value there. Returns how many keys were moved and skipped. Safe to
+// re-run and to run while the API is serving traffic.
+func (c *Client) MigrateNamespace(ctx context.Context, pattern string, dryRun bool) (moved, skipped int, err error) {
+	if c.prefix == "" {
+		return 0, 0, fmt.Errorf("no namespace set")
+	}
+	raw := withoutNamespace(ctx)
+
+	var cursor uint64
+	for {
+		keys, next, err := c.Scan(raw, cursor, pattern, 500).Result()
+		if err != nil {
+			return moved, skipped, fmt.Errorf("redis scan failed: %w", err)
+		}
+		for _, key := range keys {
+			// Already namespaced keys can match a broad pattern
+			if strings.HasPrefix(key, c.prefix) {
+				continue
+			}
+			if dryRun {
+				moved++
+				continue
+			}
+			ok, err := c.RenameNX(raw, key, c.prefix+key).Result()
+			if err != nil && strings.Contains(err.Error(), "no such key") {
+				continue // Expired since the scan
+			}
+			if err != nil {
+				return moved, skipped, fmt.Errorf("redis renamenx failed: %w", err)
+			}
+			if ok {
+				moved++
+			} else {
+				skipped++
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return moved, skipped, nil
+		}
+	}
+}
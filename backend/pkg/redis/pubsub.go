@@ -0,0 +1,13 @@
+package redis
+
+import "github.com/redis/go-redis/v9"
+
+// Message is a pub/sub message, re-exported for subscribers
+type Message = redis.Message
+
+// Channel returns a pub/sub channel name in the client's namespace.
+// Channels are not keys, so the namespace hook leaves PUBLISH and SUBSCRIBE
+// alone; publishers and subscribers both name channels through here.
+func (c *Client) Channel(name string) string {
+	return c.prefix + name
+}
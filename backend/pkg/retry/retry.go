@@ -0,0 +1,138 @@
+// Package retry runs calls to external integrations with a per-integration
+// policy: attempt limit, exponential backoff with jitter, and a classifier
+// deciding which errors are worth retrying.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Classifier reports whether err is transient and the call may be retried
+type Classifier func(err error) bool
+
+// Policy describes how one integration retries
+type Policy struct {
+	MaxAttempts    int           // Total attempts including the first; <= 1 disables retries
+	InitialBackoff time.Duration // Wait before the second attempt
+	MaxBackoff     time.Duration // Cap on any single wait
+	Multiplier     float64       // Backoff growth per attempt (default 2)
+	Jitter         float64       // 0..1 fraction of each wait randomized away
+	Retryable      Classifier    // Defaults to DefaultClassifier
+}
+
+// NoRetry is a policy that makes exactly one attempt
+var NoRetry = Policy{MaxAttempts: 1}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so no policy retries it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// StatusError is an HTTP response an integration treated as a failure
+type StatusError struct {
+	Service    string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d", e.Service, e.StatusCode)
+}
+
+// RetryableStatus reports whether an HTTP status is worth retrying:
+// rate limiting, timeouts and server-side errors
+func RetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+// DefaultClassifier retries network errors, timeouts and retryable HTTP
+// statuses. Permanent errors and caller cancellation are never retried;
+// unknown errors are retried.
+func DefaultClassifier(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		return RetryableStatus(status.StatusCode)
+	}
+
+	// Network errors, deadline exceeded and anything unclassified
+	return true
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the
+// attempts run out, or ctx is done. The last error is returned.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	classify := p.Retryable
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt == attempts || !classify(err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.Backoff(attempt)):
+		}
+	}
+
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return perm.err
+	}
+	return err
+}
+
+// Backoff returns the wait after the given (1-based) failed attempt
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		wait -= wait * jitter * rand.Float64()
+	}
+
+	return time.Duration(wait)
+}
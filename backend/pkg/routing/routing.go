@@ -0,0 +1,181 @@
+// Package routing resolves road distances between coordinates using an
+// external routing service, falling back to straight-line estimates.
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/retry"
+)
+
+// Route sources reported on results
+const (
+	SourceOSRM      = "osrm"
+	SourceHaversine = "haversine"
+)
+
+// haversineRoadFactor inflates straight-line distance to approximate the
+// extra length of a road route through a city grid
+const haversineRoadFactor = 1.3
+
+// ErrNoRoute is returned when the provider finds no drivable route
+var ErrNoRoute = errors.New("no route between points")
+
+// Route is the road distance and travel time between two points
+type Route struct {
+	DistanceMeters  int    `json:"distance_meters"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Source          string `json:"source"`
+}
+
+// Provider computes a route between two points
+type Provider interface {
+	Route(ctx context.Context, from, to geo.Point) (*Route, error)
+}
+
+// OSRMProvider queries an OSRM-compatible /route/v1 endpoint
+type OSRMProvider struct {
+	baseURL    string
+	profile    string
+	httpClient *http.Client
+	retry      retry.Policy
+}
+
+// NewOSRMProvider creates a provider for the OSRM server at baseURL
+// (e.g. "https://router.project-osrm.org")
+func NewOSRMProvider(baseURL string, timeout time.Duration) *OSRMProvider {
+	return &OSRMProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		profile:    "driving",
+		httpClient: &http.Client{Timeout: timeout},
+		retry:      retry.NoRetry,
+	}
+}
+
+// SetRetryPolicy sets how failed routing calls are retried
+func (p *OSRMProvider) SetRetryPolicy(policy retry.Policy) {
+	p.retry = policy
+}
+
+// osrmResponse is the subset of the OSRM route response we read
+type osrmResponse struct {
+	Code   string `json:"code"`
+	Routes []struct {
+		Distance float64 `json:"distance"`
+		Duration float64 `json:"duration"`
+	} `json:"routes"`
+}
+
+// Route implements Provider
+func (p *OSRMProvider) Route(ctx context.Context, from, to geo.Point) (*Route, error) {
+	var route *Route
+	err := p.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		route, err = p.route(ctx, from, to)
+		return err
+	})
+	return route, err
+}
+
+// route makes a single routing request
+func (p *OSRMProvider) route(ctx context.Context, from, to geo.Point) (*Route, error) {
+	// OSRM takes coordinates as lon,lat
+	url := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=false",
+		p.baseURL, p.profile, from.Longitude, from.Latitude, to.Longitude, to.Latitude)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("routing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body osrmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &retry.StatusError{Service: "routing service", StatusCode: resp.StatusCode}
+		}
+		return nil, retry.Permanent(fmt.Errorf("failed to decode routing response: %w", err))
+	}
+
+	// OSRM reports NoRoute with a 400, so check the code before the status
+	if body.Code == "NoRoute" || (body.Code == "Ok" && len(body.Routes) == 0) {
+		return nil, retry.Permanent(ErrNoRoute)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &retry.StatusError{Service: "routing service", StatusCode: resp.StatusCode}
+	}
+	if body.Code != "Ok" {
+		return nil, retry.Permanent(fmt.Errorf("routing service returned code %s", body.Code))
+	}
+
+	return &Route{
+		DistanceMeters:  int(body.Routes[0].Distance),
+		DurationSeconds: int(body.Routes[0].Duration),
+		Source:          SourceOSRM,
+	}, nil
+}
+
+// HaversineProvider estimates road distance from the great-circle distance.
+// Duration assumes an average speed in km/h.
+type HaversineProvider struct {
+	speedKmph float64
+}
+
+// NewHaversineProvider creates an offline estimator
+func NewHaversineProvider(speedKmph float64) *HaversineProvider {
+	if speedKmph <= 0 {
+		speedKmph = 20
+	}
+	return &HaversineProvider{speedKmph: speedKmph}
+}
+
+// Route implements Provider
+func (p *HaversineProvider) Route(_ context.Context, from, to geo.Point) (*Route, error) {
+	meters := geo.HaversineMeters(from, to) * haversineRoadFactor
+	seconds := meters / (p.speedKmph * 1000 / 3600)
+
+	return &Route{
+		DistanceMeters:  int(meters),
+		DurationSeconds: int(seconds),
+		Source:          SourceHaversine,
+	}, nil
+}
+
+// FallbackProvider tries the primary provider and uses the fallback when
+// it errors, so an outage at the routing service never blocks checkout.
+// ErrNoRoute is a real answer and is returned as-is.
+type FallbackProvider struct {
+	primary  Provider
+	fallback Provider
+	onError  func(err error)
+}
+
+// NewFallbackProvider chains two providers. onError, if set, is called with
+// the primary provider's error before falling back.
+func NewFallbackProvider(primary, fallback Provider, onError func(err error)) *FallbackProvider {
+	return &FallbackProvider{primary: primary, fallback: fallback, onError: onError}
+}
+
+// Route implements Provider
+func (p *FallbackProvider) Route(ctx context.Context, from, to geo.Point) (*Route, error) {
+	route, err := p.primary.Route(ctx, from, to)
+	if err == nil || errors.Is(err, ErrNoRoute) {
+		return route, err
+	}
+	if p.onError != nil {
+		p.onError(err)
+	}
+	return p.fallback.Route(ctx, from, to)
+}
@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// AWSCredentials are static IAM credentials, usually from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and (for temporary credentials) AWS_SESSION_TOKEN
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSSource reads a secret from AWS Secrets Manager. The secret's
+// SecretString must be a JSON object of string values.
+type AWSSource struct {
+	region     string
+	secretID   string
+	creds      AWSCredentials
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewAWSSource creates a source for secretID (name or ARN) in region
+func NewAWSSource(region, secretID string, creds AWSCredentials, timeout time.Duration) *AWSSource {
+	return &AWSSource{
+		region:     region,
+		secretID:   secretID,
+		creds:      creds,
+		endpoint:   fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Source
+func (s *AWSSource) Name() string {
+	return "aws-secrets-manager"
+}
+
+// Fetch implements Source using the GetSecretValue API
+func (s *AWSSource) Fetch(ctx context.Context) (map[string]string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": s.secretID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	s.sign(req, payload, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &retry.StatusError{Service: "aws secrets manager", StatusCode: resp.StatusCode}
+	}
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(body.SecretString), &raw); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object: %w", s.secretID, err)
+	}
+
+	return stringValues(raw), nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header
+func (s *AWSSource) sign(req *http.Request, payload []byte, now time.Time) {
+	const service = "secretsmanager"
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.creds.SessionToken)
+	}
+
+	// Canonical headers must be lowercase and sorted by name
+	headers := [][2]string{
+		{"content-type", req.Header.Get("Content-Type")},
+		{"host", req.URL.Host},
+		{"x-amz-date", amzDate},
+	}
+	if s.creds.SessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", s.creds.SessionToken})
+	}
+	headers = append(headers, [2]string{"x-amz-target", req.Header.Get("X-Amz-Target")})
+
+	var canonicalHeaders string
+	names := make([]string, 0, len(headers))
+	for _, h := range headers {
+		canonicalHeaders += h[0] + ":" + h[1] + "\n"
+		names = append(names, h[0])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := req.Method + "\n/\n\n" + canonicalHeaders + "\n" + signedHeaders + "\n" + payloadHash
+	scope := date + "/" + s.region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.creds.SecretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,144 @@
+// Package secrets loads runtime credentials from a secrets manager (HashiCorp
+// Vault or AWS Secrets Manager) instead of environment variables, and keeps
+// them fresh so rotated values reach the running process.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotLoaded is returned when reading refresh state before the first load
+var ErrNotLoaded = errors.New("secrets: not loaded")
+
+// Source fetches the full set of secrets, keyed by environment variable name
+// (e.g. "JWT_SECRET")
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// Manager holds the latest secrets from a Source and notifies subscribers
+// when a value changes
+type Manager struct {
+	source Source
+
+	mu        sync.RWMutex
+	values    map[string]string
+	loadedAt  time.Time
+	listeners map[string][]func(value string)
+}
+
+// NewManager creates a manager for source. Call Load before use.
+func NewManager(source Source) *Manager {
+	return &Manager{
+		source:    source,
+		listeners: make(map[string][]func(string)),
+	}
+}
+
+// Source returns the name of the backing secrets manager
+func (m *Manager) Source() string {
+	return m.source.Name()
+}
+
+// Load fetches secrets for the first time
+func (m *Manager) Load(ctx context.Context) error {
+	values, err := m.source.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("secrets: failed to load from %s: %w", m.source.Name(), err)
+	}
+
+	m.mu.Lock()
+	m.values = values
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// Lookup returns a secret; ok is false if the secrets manager doesn't hold it
+func (m *Manager) Lookup(key string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// OnChange registers fn to be called with the new value whenever key
+// changes on refresh. Callbacks run on the refresh goroutine.
+func (m *Manager) OnChange(key string, fn func(value string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners[key] = append(m.listeners[key], fn)
+}
+
+// Refresh re-fetches secrets and notifies listeners of changed keys.
+// Returns the keys that changed. Keys removed upstream keep their last value
+// so a bad edit can't blank a credential in a running process.
+func (m *Manager) Refresh(ctx context.Context) ([]string, error) {
+	values, err := m.source.Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: failed to refresh from %s: %w", m.source.Name(), err)
+	}
+
+	m.mu.Lock()
+	if m.values == nil {
+		m.mu.Unlock()
+		return nil, ErrNotLoaded
+	}
+
+	var changed []string
+	var notify []func()
+	for key, value := range values {
+		if old, ok := m.values[key]; ok && old == value {
+			continue
+		}
+		m.values[key] = value
+		changed = append(changed, key)
+		for _, fn := range m.listeners[key] {
+			fn, value := fn, value
+			notify = append(notify, func() { fn(value) })
+		}
+	}
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+
+	for _, fn := range notify {
+		fn()
+	}
+	return changed, nil
+}
+
+// Run refreshes every interval until ctx is cancelled. onRefresh is called
+// after every attempt with the changed keys or the error.
+func (m *Manager) Run(ctx context.Context, interval time.Duration, onRefresh func(changed []string, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := m.Refresh(ctx)
+			if onRefresh != nil {
+				onRefresh(changed, err)
+			}
+		}
+	}
+}
+
+// stringValues converts a decoded JSON object to string values, skipping
+// non-string entries
+func stringValues(raw map[string]any) map[string]string {
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		if s, ok := v.(string); ok {
+			values[key] = s
+		}
+	}
+	return values
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// VaultSource reads a KV version 2 secret from HashiCorp Vault. Every string
+// field of the secret becomes one value.
+type VaultSource struct {
+	addr       string
+	token      string
+	namespace  string
+	path       string
+	httpClient *http.Client
+}
+
+// NewVaultSource creates a source for the secret at path, given as the KV v2
+// API path (e.g. "secret/data/crave-delivery")
+func NewVaultSource(addr, token, namespace, path string, timeout time.Duration) *VaultSource {
+	return &VaultSource{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		namespace:  namespace,
+		path:       strings.Trim(path, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Source
+func (s *VaultSource) Name() string {
+	return "vault"
+}
+
+// vaultKVResponse is the subset of a KV v2 read response we use
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+}
+
+// Fetch implements Source
+func (s *VaultSource) Fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.addr+"/v1/"+s.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &retry.StatusError{Service: "vault", StatusCode: resp.StatusCode}
+	}
+
+	var body vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	return stringValues(body.Data.Data), nil
+}
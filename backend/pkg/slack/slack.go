@@ -0,0 +1,67 @@
+// Package slack posts messages to a Slack channel through an incoming
+// webhook.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// Webhook posts to the channel an incoming webhook URL was created for
+type Webhook struct {
+	url        string
+	httpClient *http.Client
+	retry      retry.Policy
+}
+
+// NewWebhook creates a client for the incoming webhook at url
+// (https://hooks.slack.com/services/...)
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+		retry:      retry.NoRetry,
+	}
+}
+
+// SetRetryPolicy sets how failed posts are retried
+func (w *Webhook) SetRetryPolicy(policy retry.Policy) {
+	w.retry = policy
+}
+
+// Post sends text, which may use Slack's mrkdwn formatting
+func (w *Webhook) Post(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return w.retry.Do(ctx, func(ctx context.Context) error {
+		return w.post(ctx, body)
+	})
+}
+
+// post makes a single webhook request
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &retry.StatusError{Service: "slack", StatusCode: resp.StatusCode}
+	}
+	return nil
+}
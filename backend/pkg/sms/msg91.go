@@ -0,0 +1,120 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// MSG91Config holds MSG91 credentials. Indian carriers only deliver texts
+// matching a DLT-registered template, so DLTTemplateID is needed there.
+type MSG91Config struct {
+	AuthKey            string
+	SenderID           string // 6-character DLT header, e.g. "CRAVEF"
+	DLTTemplateID      string
+	Route              string // "4" (transactional) by default
+	DefaultCountryCode string // Added to 10-digit numbers, e.g. "91"
+	BaseURL            string // Defaults to https://api.msg91.com
+}
+
+// MSG91Sender sends texts with MSG91's send SMS API
+type MSG91Sender struct {
+	cfg        MSG91Config
+	httpClient *http.Client
+}
+
+// NewMSG91Sender creates an MSG91 sender
+func NewMSG91Sender(cfg MSG91Config, timeout time.Duration) *MSG91Sender {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.msg91.com"
+	}
+	if cfg.Route == "" {
+		cfg.Route = "4"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &MSG91Sender{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// msg91Request is the send SMS request body
+type msg91Request struct {
+	Sender        string         `json:"sender"`
+	Route         string         `json:"route"`
+	DLTTemplateID string         `json:"DLT_TE_ID,omitempty"`
+	SMS           []msg91Message `json:"sms"`
+}
+
+type msg91Message struct {
+	Message string   `json:"message"`
+	To      []string `json:"to"`
+}
+
+// msg91Response reports success or failure; MSG91 can answer 200 with
+// type "error"
+type msg91Response struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Send implements Sender. Rejected requests (bad key, number or template)
+// are permanent; rate limits and server errors can be retried.
+func (s *MSG91Sender) Send(ctx context.Context, phoneNumber, body string) error {
+	payload, err := json.Marshal(msg91Request{
+		Sender:        s.cfg.SenderID,
+		Route:         s.cfg.Route,
+		DLTTemplateID: s.cfg.DLTTemplateID,
+		SMS: []msg91Message{{
+			Message: body,
+			To:      []string{strings.TrimPrefix(E164(phoneNumber, s.cfg.DefaultCountryCode), "+")},
+		}},
+	})
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/api/v2/sendsms", bytes.NewReader(payload))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.Header.Set("authkey", s.cfg.AuthKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("msg91 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result msg91Response
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &retry.StatusError{Service: "msg91", StatusCode: resp.StatusCode}
+		if decodeErr == nil && result.Message != "" {
+			err = fmt.Errorf("%w: %s", statusErr, result.Message)
+		} else {
+			err = statusErr
+		}
+		if !retry.RetryableStatus(resp.StatusCode) {
+			return retry.Permanent(err)
+		}
+		return err
+	}
+
+	if decodeErr != nil {
+		return retry.Permanent(fmt.Errorf("failed to decode msg91 response: %w", decodeErr))
+	}
+	if result.Type != "success" {
+		return retry.Permanent(errors.New("msg91 rejected the message: " + result.Message))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package sms delivers text messages (login OTPs, gift order updates)
+// through a pluggable provider.
+package sms
+
+import (
+	"context"
+	"strings"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+	"fooddelivery/pkg/throttle"
+)
+
+// Sender delivers a text message to a phone number
+type Sender interface {
+	Send(ctx context.Context, phoneNumber, body string) error
+}
+
+// LogSender records that a text would have been sent instead of sending
+// it. Used until a provider is configured. The body is not logged: it may
+// hold an OTP.
+type LogSender struct {
+	log *logger.Logger
+}
+
+// NewLogSender creates a sender that only logs
+func NewLogSender(log *logger.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send implements Sender
+func (s *LogSender) Send(_ context.Context, phoneNumber, body string) error {
+	s.log.Info("SMS (log only)", "to", MaskNumber(phoneNumber), "length", len(body))
+	return nil
+}
+
+// RetryingSender retries a sender according to a policy
+type RetryingSender struct {
+	sender Sender
+	policy retry.Policy
+}
+
+// NewRetryingSender wraps sender with a retry policy
+func NewRetryingSender(sender Sender, policy retry.Policy) *RetryingSender {
+	return &RetryingSender{sender: sender, policy: policy}
+}
+
+// Send implements Sender
+func (s *RetryingSender) Send(ctx context.Context, phoneNumber, body string) error {
+	return s.policy.Do(ctx, func(ctx context.Context) error {
+		return s.sender.Send(ctx, phoneNumber, body)
+	})
+}
+
+// ThrottledSender paces a sender to the provider's rate limit, sending
+// higher priority texts first (see throttle.WithPriority)
+type ThrottledSender struct {
+	sender    Sender
+	throttler *throttle.Throttler
+}
+
+// NewThrottledSender wraps sender with a throttler
+func NewThrottledSender(sender Sender, throttler *throttle.Throttler) *ThrottledSender {
+	return &ThrottledSender{sender: sender, throttler: throttler}
+}
+
+// Send implements Sender
+func (s *ThrottledSender) Send(ctx context.Context, phoneNumber, body string) error {
+	return s.throttler.Do(ctx, func(ctx context.Context) error {
+		return s.sender.Send(ctx, phoneNumber, body)
+	})
+}
+
+// MaskNumber hides all but the last four digits of a phone number, for logs
+func MaskNumber(phoneNumber string) string {
+	if len(phoneNumber) <= 4 {
+		return "****"
+	}
+	return strings.Repeat("*", len(phoneNumber)-4) + phoneNumber[len(phoneNumber)-4:]
+}
+
+// E164 formats a stored phone number for providers that want it with a
+// country code: numbers already starting with "+" are kept, a bare
+// 10-digit national number gets defaultCountryCode (e.g. "91"), and
+// anything else is assumed to include its country code.
+func E164(phoneNumber, defaultCountryCode string) string {
+	phoneNumber = strings.TrimSpace(phoneNumber)
+	switch {
+	case strings.HasPrefix(phoneNumber, "+"):
+		return phoneNumber
+	case len(phoneNumber) == 10:
+		return "+" + defaultCountryCode + phoneNumber
+	default:
+		return "+" + phoneNumber
+	}
+}
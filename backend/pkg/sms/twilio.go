@@ -0,0 +1,90 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/retry"
+)
+
+// TwilioConfig holds Twilio credentials. From is a sending number in E.164
+// form or a Messaging Service SID ("MG...").
+type TwilioConfig struct {
+	AccountSID         string
+	AuthToken          string
+	From               string
+	DefaultCountryCode string // Added to 10-digit numbers, e.g. "91"
+	BaseURL            string // Defaults to https://api.twilio.com
+}
+
+// TwilioSender sends texts with Twilio's Messages API
+type TwilioSender struct {
+	cfg        TwilioConfig
+	httpClient *http.Client
+}
+
+// NewTwilioSender creates a Twilio sender
+func NewTwilioSender(cfg TwilioConfig, timeout time.Duration) *TwilioSender {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.twilio.com"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &TwilioSender{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// twilioError is the body of a failed Twilio request
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send implements Sender. Client errors (bad number, unverified sender) are
+// permanent; rate limits and server errors can be retried.
+func (s *TwilioSender) Send(ctx context.Context, phoneNumber, body string) error {
+	form := url.Values{}
+	form.Set("To", E164(phoneNumber, s.cfg.DefaultCountryCode))
+	form.Set("Body", body)
+	if strings.HasPrefix(s.cfg.From, "MG") {
+		form.Set("MessagingServiceSid", s.cfg.From)
+	} else {
+		form.Set("From", s.cfg.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.cfg.BaseURL, url.PathEscape(s.cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return retry.Permanent(err)
+	}
+	req.SetBasicAuth(s.cfg.AccountSID, s.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	statusErr := &retry.StatusError{Service: "twilio", StatusCode: resp.StatusCode}
+	var apiErr twilioError
+	if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Code != 0 {
+		err = fmt.Errorf("%w: error %d: %s", statusErr, apiErr.Code, apiErr.Message)
+	} else {
+		err = statusErr
+	}
+	if !retry.RetryableStatus(resp.StatusCode) {
+		return retry.Permanent(err)
+	}
+	return err
+}
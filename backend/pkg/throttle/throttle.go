@@ -0,0 +1,266 @@
+// Package throttle paces sends to rate-limited notification providers (push,
+// SMS). Each provider gets a token bucket and a queue ordered by priority, so
+// during a marketing blast OTPs and order updates still go out first.
+package throttle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"fooddelivery/pkg/metrics"
+)
+
+// Priority orders queued sends; higher goes first
+type Priority int
+
+// Priorities, lowest first
+const (
+	PriorityMarketing Priority = iota
+	PriorityTransactional
+	PriorityOTP
+)
+
+// String returns the priority's metric label
+func (p Priority) String() string {
+	switch p {
+	case PriorityMarketing:
+		return "marketing"
+	case PriorityOTP:
+		return "otp"
+	default:
+		return "transactional"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority marks sends made with ctx
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set on ctx, PriorityTransactional if none
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= PriorityMarketing && p <= PriorityOTP {
+		return p
+	}
+	return PriorityTransactional
+}
+
+// ErrQueueFull is returned when a send is rejected, or evicted by a higher
+// priority one, because the provider's queue is full
+var ErrQueueFull = errors.New("notification queue is full")
+
+var (
+	sendsTotal = metrics.NewCounterVec(
+		"notification_sends_total",
+		"Throttled notification sends by provider, priority and result",
+		"provider", "priority", "result",
+	)
+	queueWaitSeconds = metrics.NewCounterVec(
+		"notification_queue_wait_seconds_total",
+		"Time sends spent queued for a rate limit token",
+		"provider", "priority",
+	)
+	sendSeconds = metrics.NewCounterVec(
+		"notification_send_seconds_total",
+		"Time spent in provider calls; divide by notification_sends_total for latency",
+		"provider", "priority",
+	)
+)
+
+// Config is one provider's limits
+type Config struct {
+	Rate      float64 // Sends per second; <= 0 disables throttling
+	Burst     int     // Sends allowed at once after an idle spell (default 1)
+	QueueSize int     // Sends waiting at most (default 1000)
+}
+
+// job is one queued send
+type job struct {
+	ctx      context.Context
+	priority Priority
+	send     func(ctx context.Context) error
+	queuedAt time.Time
+	done     chan error
+}
+
+// Throttler queues sends to one provider and releases them at its rate.
+// Sends start no faster than Rate; they run concurrently once started, so a
+// slow provider call doesn't hold up the next one.
+type Throttler struct {
+	name string
+	cfg  Config
+
+	mu     sync.Mutex
+	queues [PriorityOTP + 1][]*job
+	queued int
+	wake   chan struct{}
+}
+
+// New creates a throttler for the named provider and registers its queue
+// depth gauge (<name>_queue_depth) on the default registry. Start it with Run.
+func New(name string, cfg Config) *Throttler {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1000
+	}
+	t := &Throttler{
+		name: name,
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+	}
+	metrics.Default.NewGaugeFunc(name+"_queue_depth", "Sends to "+name+" waiting for a rate limit token",
+		func() float64 { return float64(t.Len()) })
+	return t
+}
+
+// Len returns the number of queued sends
+func (t *Throttler) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.queued
+}
+
+// Do queues send at ctx's priority and waits for it to run, returning its
+// error. It gives up when ctx is done; a queued send whose ctx is done is
+// dropped without using a token.
+func (t *Throttler) Do(ctx context.Context, send func(ctx context.Context) error) error {
+	priority := PriorityFrom(ctx)
+	if t.cfg.Rate <= 0 {
+		return t.run(ctx, priority, send, 0)
+	}
+
+	j := &job{ctx: ctx, priority: priority, send: send, queuedAt: time.Now(), done: make(chan error, 1)}
+	if err := t.enqueue(j); err != nil {
+		sendsTotal.Inc(t.name, priority.String(), "rejected")
+		return err
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds j, evicting the newest send of the lowest queued priority
+// below j's when the queue is full
+func (t *Throttler) enqueue(j *job) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.queued >= t.cfg.QueueSize {
+		evicted := false
+		for p := PriorityMarketing; p < j.priority; p++ {
+			if n := len(t.queues[p]); n > 0 {
+				victim := t.queues[p][n-1]
+				t.queues[p] = t.queues[p][:n-1]
+				t.queued--
+				victim.done <- ErrQueueFull
+				sendsTotal.Inc(t.name, p.String(), "evicted")
+				evicted = true
+				break
+			}
+		}
+		if !evicted {
+			return ErrQueueFull
+		}
+	}
+
+	t.queues[j.priority] = append(t.queues[j.priority], j)
+	t.queued++
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// next removes the oldest send of the highest queued priority, skipping
+// sends whose callers gave up. It returns nil when the queue is empty.
+func (t *Throttler) next() *job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for p := PriorityOTP; p >= PriorityMarketing; p-- {
+		for len(t.queues[p]) > 0 {
+			j := t.queues[p][0]
+			t.queues[p][0] = nil
+			t.queues[p] = t.queues[p][1:]
+			t.queued--
+			if j.ctx.Err() != nil {
+				sendsTotal.Inc(t.name, p.String(), "abandoned")
+				continue
+			}
+			return j
+		}
+	}
+	return nil
+}
+
+// Run releases queued sends until ctx is cancelled. Run it once per
+// throttler; without it throttled sends wait until their callers give up.
+func (t *Throttler) Run(ctx context.Context) {
+	if t.cfg.Rate <= 0 {
+		return
+	}
+
+	interval := time.Duration(float64(time.Second) / t.cfg.Rate)
+	tokens := float64(t.cfg.Burst)
+	last := time.Now()
+
+	for {
+		// Wait for a token first, so the send taken after it is the highest
+		// priority one queued by then
+		now := time.Now()
+		tokens = min(float64(t.cfg.Burst), tokens+now.Sub(last).Seconds()*t.cfg.Rate)
+		last = now
+		if tokens < 1 {
+			wait := time.Duration((1 - tokens) * float64(interval))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		j := t.next()
+		if j == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.wake:
+			}
+			continue
+		}
+
+		tokens--
+		go func() {
+			j.done <- t.run(j.ctx, j.priority, j.send, time.Since(j.queuedAt))
+		}()
+	}
+}
+
+// run performs one send and records it
+func (t *Throttler) run(ctx context.Context, priority Priority, send func(ctx context.Context) error, waited time.Duration) error {
+	label := priority.String()
+	queueWaitSeconds.Add(waited.Seconds(), t.name, label)
+
+	start := time.Now()
+	err := send(ctx)
+	sendSeconds.Add(time.Since(start).Seconds(), t.name, label)
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	sendsTotal.Inc(t.name, label, result)
+	return err
+}
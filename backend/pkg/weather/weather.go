@@ -0,0 +1,160 @@
+// Package weather looks up current conditions for a location so delivery
+// ETAs can account for rain and storms.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"fooddelivery/pkg/geo"
+	"fooddelivery/pkg/retry"
+)
+
+// Condition is a coarse weather category that ETA rules are keyed on
+type Condition string
+
+// Weather conditions
+const (
+	ConditionClear     Condition = "clear"
+	ConditionFog       Condition = "fog"
+	ConditionRain      Condition = "rain"
+	ConditionHeavyRain Condition = "heavy_rain"
+	ConditionSnow      Condition = "snow"
+	ConditionStorm     Condition = "storm"
+)
+
+// Observation is the current weather at a location
+type Observation struct {
+	Condition       Condition `json:"condition"`
+	PrecipitationMM float64   `json:"precipitation_mm"`
+	TemperatureC    float64   `json:"temperature_c"`
+	ObservedAt      time.Time `json:"observed_at"`
+}
+
+// Provider returns current weather for a point
+type Provider interface {
+	Current(ctx context.Context, p geo.Point) (*Observation, error)
+}
+
+// OpenMeteoProvider reads current conditions from the Open-Meteo forecast API
+type OpenMeteoProvider struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      retry.Policy
+}
+
+// NewOpenMeteoProvider creates a provider for the API at baseURL
+// (e.g. "https://api.open-meteo.com")
+func NewOpenMeteoProvider(baseURL string, timeout time.Duration) *OpenMeteoProvider {
+	return &OpenMeteoProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+		retry:      retry.NoRetry,
+	}
+}
+
+// SetRetryPolicy sets how failed weather lookups are retried
+func (p *OpenMeteoProvider) SetRetryPolicy(policy retry.Policy) {
+	p.retry = policy
+}
+
+// openMeteoResponse is the subset of the forecast response we read
+type openMeteoResponse struct {
+	Current struct {
+		WeatherCode   int     `json:"weather_code"`
+		Precipitation float64 `json:"precipitation"`
+		Temperature   float64 `json:"temperature_2m"`
+	} `json:"current"`
+}
+
+// Current implements Provider
+func (p *OpenMeteoProvider) Current(ctx context.Context, pt geo.Point) (*Observation, error) {
+	var obs *Observation
+	err := p.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		obs, err = p.current(ctx, pt)
+		return err
+	})
+	return obs, err
+}
+
+// current makes a single weather request
+func (p *OpenMeteoProvider) current(ctx context.Context, pt geo.Point) (*Observation, error) {
+	url := fmt.Sprintf("%s/v1/forecast?latitude=%.4f&longitude=%.4f&current=weather_code,precipitation,temperature_2m",
+		p.baseURL, pt.Latitude, pt.Longitude)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("weather request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &retry.StatusError{Service: "weather service", StatusCode: resp.StatusCode}
+	}
+
+	var body openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, retry.Permanent(fmt.Errorf("failed to decode weather response: %w", err))
+	}
+
+	return &Observation{
+		Condition:       conditionFromWMO(body.Current.WeatherCode, body.Current.Precipitation),
+		PrecipitationMM: body.Current.Precipitation,
+		TemperatureC:    body.Current.Temperature,
+		ObservedAt:      time.Now(),
+	}, nil
+}
+
+// conditionFromWMO maps WMO weather interpretation codes to a Condition.
+// Heavy precipitation upgrades rain regardless of the reported code.
+func conditionFromWMO(code int, precipitationMM float64) Condition {
+	switch {
+	case code >= 95:
+		return ConditionStorm
+	case code == 65 || code == 67 || code == 82 || precipitationMM >= 7.5:
+		return ConditionHeavyRain
+	case (code >= 51 && code <= 67) || (code >= 80 && code <= 82):
+		return ConditionRain
+	case (code >= 71 && code <= 77) || code == 85 || code == 86:
+		return ConditionSnow
+	case code == 45 || code == 48:
+		return ConditionFog
+	default:
+		return ConditionClear
+	}
+}
+
+// Adjustments maps a condition to extra delivery time
+type Adjustments map[Condition]time.Duration
+
+// ParseAdjustments parses rules like "rain=10,heavy_rain=20,storm=30"
+// where values are minutes added to the ETA
+func ParseAdjustments(spec string) (Adjustments, error) {
+	rules := Adjustments{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid ETA rule %q: expected condition=minutes", part)
+		}
+		var minutes int
+		if _, err := fmt.Sscanf(strings.TrimSpace(value), "%d", &minutes); err != nil || minutes < 0 {
+			return nil, fmt.Errorf("invalid ETA rule %q: minutes must be a non-negative integer", part)
+		}
+		rules[Condition(strings.ToLower(strings.TrimSpace(name)))] = time.Duration(minutes) * time.Minute
+	}
+	return rules, nil
+}
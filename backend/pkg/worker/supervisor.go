@@ -0,0 +1,165 @@
+// Package worker supervises long-running background tasks (health checks,
+// dispatchers, refreshers): each runs in its own goroutine, is restarted
+// with backoff if it fails or panics, and stops when the supervisor does.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"fooddelivery/pkg/logger"
+	"fooddelivery/pkg/retry"
+)
+
+// Func is a background task. It should run until ctx is cancelled; returning
+// nil earlier means the task is done, returning an error restarts it.
+type Func func(ctx context.Context) error
+
+// ErrAlreadyStarted is returned when adding workers after Start
+var ErrAlreadyStarted = errors.New("supervisor already started")
+
+// defaultRestart backs off restarts of a failing worker
+var defaultRestart = retry.Policy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Jitter:         0.2,
+}
+
+// stableAfter resets the restart backoff once a worker has run this long
+const stableAfter = time.Minute
+
+type task struct {
+	name string
+	fn   Func
+}
+
+// Supervisor owns a set of background workers
+type Supervisor struct {
+	log     *logger.Logger
+	restart retry.Policy
+
+	mu      sync.Mutex
+	tasks   []task
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewSupervisor creates a supervisor with no workers
+func NewSupervisor(log *logger.Logger) *Supervisor {
+	return &Supervisor{log: log, restart: defaultRestart}
+}
+
+// Add registers a worker; all workers start together on Start
+func (s *Supervisor) Add(name string, fn Func) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrAlreadyStarted
+	}
+	s.tasks = append(s.tasks, task{name: name, fn: fn})
+	return nil
+}
+
+// Start launches every worker. Workers stop when ctx is cancelled or Stop
+// is called.
+func (s *Supervisor) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.supervise(ctx, t)
+	}
+	s.log.Info("Background workers started", "count", len(s.tasks))
+}
+
+// Stop cancels every worker and waits for them to return, or for ctx to
+// expire
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.log.Info("Background workers stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers still running: %w", ctx.Err())
+	}
+}
+
+// supervise runs one worker, restarting it until it finishes or ctx ends
+func (s *Supervisor) supervise(ctx context.Context, t task) {
+	defer s.wg.Done()
+
+	failures := 0
+	for {
+		started := time.Now()
+		err := runProtected(ctx, t.fn)
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			s.log.Info("Background worker finished", "worker", t.name)
+			return
+		}
+
+		if time.Since(started) >= stableAfter {
+			failures = 0
+		}
+		failures++
+		wait := s.restart.Backoff(failures)
+		s.log.Error("Background worker failed, restarting",
+			"worker", t.name,
+			"error", err,
+			"restart_in", wait.String(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runProtected converts a panic in fn into an error
+func runProtected(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(ctx)
+}
+
+// Forever adapts a task that runs until ctx is cancelled and has no error
+// to report. It is still restarted if it panics.
+func Forever(fn func(ctx context.Context)) Func {
+	return func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	}
+}